@@ -0,0 +1,58 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// ExportFilesystem writes the directory tree at path in the sandbox to w
+// as a tar stream, with entry names relative to path and file modes
+// preserved. If path is a file, the archive holds just that file under
+// its base name. Together with ImportFilesystem it snapshots a workspace
+// after a run or moves it to another sandbox:
+//
+//	var snapshot bytes.Buffer
+//	sb.ExportFilesystem(ctx, "/workspace", &snapshot)
+//	other.ImportFilesystem(ctx, "/workspace", &snapshot)
+//
+// Only providers with SupportsArchive (Docker, gVisor, nsjail, Wasmer)
+// implement it; others return ErrCapabilityNotSupported.
+func (s *sandbox) ExportFilesystem(ctx context.Context, path string, w io.Writer) error {
+	archiver, err := s.archiver("exportFilesystem")
+	if err != nil {
+		return err
+	}
+	if err := archiver.ExportTar(ctx, path, w); err != nil {
+		return NewError("exportFilesystem", s.providerName, s.instance.ID(), s.redact(err))
+	}
+	return nil
+}
+
+// ImportFilesystem extracts a tar stream into the directory at path in the
+// sandbox, creating it if needed and overwriting files already there.
+// Entries are relative to path, as ExportFilesystem writes them.
+func (s *sandbox) ImportFilesystem(ctx context.Context, path string, r io.Reader) error {
+	archiver, err := s.archiver("importFilesystem")
+	if err != nil {
+		return err
+	}
+	if err := archiver.ImportTar(ctx, path, r); err != nil {
+		return NewError("importFilesystem", s.providerName, s.instance.ID(), s.redact(err))
+	}
+	return nil
+}
+
+// archiver returns the sandbox file system as an fs.Archiver.
+func (s *sandbox) archiver(op string) (fs.Archiver, error) {
+	if err := s.checkActive(op); err != nil {
+		return nil, err
+	}
+	archiver, ok := s.instance.FileSystem().(fs.Archiver)
+	if !ok {
+		return nil, NewError(op, s.providerName, s.instance.ID(), fmt.Errorf("filesystem archives: %w", ErrCapabilityNotSupported))
+	}
+	return archiver, nil
+}
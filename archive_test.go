@@ -0,0 +1,76 @@
+package sindoq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// dirFileSystem archives paths under a host directory.
+type dirFileSystem struct {
+	fs.FileSystem
+	root string
+}
+
+func (d *dirFileSystem) ExportTar(ctx context.Context, dir string, w io.Writer) error {
+	return fs.WriteTar(ctx, filepath.Join(d.root, dir), w)
+}
+
+func (d *dirFileSystem) ImportTar(ctx context.Context, dir string, r io.Reader) error {
+	return fs.ExtractTar(ctx, filepath.Join(d.root, dir), r)
+}
+
+func TestSandboxFilesystemRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := &dirFileSystem{root: t.TempDir()}
+	workspace := filepath.Join(src.root, "workspace")
+	if err := os.MkdirAll(filepath.Join(workspace, "out"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "out", "result.txt"), []byte("42\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workspace, "run.sh"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sb := setupTailProvider(t, src)
+	var snapshot bytes.Buffer
+	if err := sb.ExportFilesystem(ctx, "/workspace", &snapshot); err != nil {
+		t.Fatalf("ExportFilesystem() error = %v", err)
+	}
+	if err := sb.ImportFilesystem(ctx, "/restored", &snapshot); err != nil {
+		t.Fatalf("ImportFilesystem() error = %v", err)
+	}
+
+	for name, want := range map[string]os.FileMode{"out/result.txt": 0640, "run.sh": 0755} {
+		path := filepath.Join(src.root, "restored", filepath.FromSlash(name))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("%s not imported: %v", name, err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s mode = %v, want %v", name, info.Mode().Perm(), want)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(src.root, "restored", "out", "result.txt")); string(data) != "42\n" {
+		t.Errorf("result.txt = %q", data)
+	}
+}
+
+func TestSandboxFilesystemArchiveUnsupported(t *testing.T) {
+	sb := setupTailProvider(t, &memFileSystem{files: map[string][]byte{}})
+
+	if err := sb.ExportFilesystem(context.Background(), "/workspace", io.Discard); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExportFilesystem() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if err := sb.ImportFilesystem(context.Background(), "/workspace", &bytes.Buffer{}); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ImportFilesystem() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
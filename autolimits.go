@@ -0,0 +1,67 @@
+package sindoq
+
+import (
+	"github.com/happyhackingspace/sindoq/internal/cgroup"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// autoLimitMemoryShare is the share of the cgroup memory limit that
+// WithAutoResourceLimits leaves to sandboxes; the rest is headroom for the
+// process creating them.
+const autoLimitMemoryShare = 0.8
+
+// readCgroupLimits reads the limits of the current process. Tests replace
+// it.
+var readCgroupLimits = cgroup.Read
+
+// ProcessResourceLimits returns the memory and CPU limits that the cgroup
+// of the current process imposes, under cgroup v1 or v2. Zero fields are
+// unlimited, as everywhere on hosts without cgroups.
+func ProcessResourceLimits() (ResourceConfig, error) {
+	limits, err := readCgroupLimits()
+	if err != nil {
+		return ResourceConfig{}, err
+	}
+	return ResourceConfig{MemoryMB: limits.MemoryMB, CPUs: limits.CPUs}, nil
+}
+
+// applyAutoResourceLimits caps cfg.Resources to the process's cgroup
+// limits, minus headroom, logging each clamp.
+func applyAutoResourceLimits(cfg *Config) error {
+	limits, err := ProcessResourceLimits()
+	if err != nil {
+		return err
+	}
+
+	if limits.MemoryMB > 0 {
+		available := int(float64(limits.MemoryMB) * autoLimitMemoryShare)
+		if cfg.Resources.MemoryMB == 0 || cfg.Resources.MemoryMB > available {
+			if cfg.Logger != nil {
+				cfg.Logger.Warn("memory request clamped to cgroup limit", "requestedMB", cfg.Resources.MemoryMB, "limitMB", limits.MemoryMB, "grantedMB", available)
+			}
+			cfg.Resources.MemoryMB = available
+		}
+	}
+	if limits.CPUs > 0 && (cfg.Resources.CPUs == 0 || cfg.Resources.CPUs > limits.CPUs) {
+		if cfg.Logger != nil {
+			cfg.Logger.Warn("CPU request clamped to cgroup limit", "requested", cfg.Resources.CPUs, "limit", limits.CPUs)
+		}
+		cfg.Resources.CPUs = limits.CPUs
+	}
+	return nil
+}
+
+// applyMemoryMinimum raises cfg.Resources.MemoryMB to the minimum of the
+// runtime language, logging the change, so compilers such as javac are not
+// killed under the default limit. Unlimited memory is left alone.
+func applyMemoryMinimum(cfg *Config) {
+	language, _ := langdetect.ParseRuntimeSpec(cfg.Runtime)
+	info, ok := langdetect.GetRuntimeInfo(language)
+	if !ok || cfg.Resources.MemoryMB == 0 || cfg.Resources.MemoryMB >= info.MinMemoryMB {
+		return
+	}
+	if cfg.Logger != nil {
+		cfg.Logger.Warn("memory request raised to language minimum", "language", info.Language, "requestedMB", cfg.Resources.MemoryMB, "grantedMB", info.MinMemoryMB)
+	}
+	cfg.Resources.MemoryMB = info.MinMemoryMB
+}
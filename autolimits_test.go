@@ -0,0 +1,218 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/cgroup"
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// mockCgroup makes readCgroupLimits read a cgroup v2 hierarchy with the
+// given memory.max and cpu.max.
+func mockCgroup(t *testing.T, memoryMax, cpuMax string) {
+	t.Helper()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"proc/self/cgroup":                 "0::/\n",
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+		"sys/fs/cgroup/memory.max":         memoryMax + "\n",
+		"sys/fs/cgroup/cpu.max":            cpuMax + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	orig := readCgroupLimits
+	readCgroupLimits = func() (cgroup.Limits, error) { return cgroup.ReadFrom(root) }
+	t.Cleanup(func() { readCgroupLimits = orig })
+}
+
+func TestApplyAutoResourceLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		memoryMax string
+		cpuMax    string
+		request   ResourceConfig
+		want      ResourceConfig
+		warns     int
+	}{
+		{
+			name:      "clamped",
+			memoryMax: "1073741824",
+			cpuMax:    "100000 100000",
+			request:   ResourceConfig{MemoryMB: 2048, CPUs: 4},
+			want:      ResourceConfig{MemoryMB: 819, CPUs: 1},
+			warns:     2,
+		},
+		{
+			name:      "within limits",
+			memoryMax: "1073741824",
+			cpuMax:    "200000 100000",
+			request:   ResourceConfig{MemoryMB: 512, CPUs: 1},
+			want:      ResourceConfig{MemoryMB: 512, CPUs: 1},
+		},
+		{
+			name:      "unlimited",
+			memoryMax: "max",
+			cpuMax:    "max 100000",
+			request:   ResourceConfig{MemoryMB: 8192, CPUs: 16},
+			want:      ResourceConfig{MemoryMB: 8192, CPUs: 16},
+		},
+		{
+			name:      "unset request takes the limit",
+			memoryMax: "536870912",
+			cpuMax:    "50000 100000",
+			want:      ResourceConfig{MemoryMB: 409, CPUs: 0.5},
+			warns:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCgroup(t, tt.memoryMax, tt.cpuMax)
+			logger := &warnLogger{}
+			cfg := DefaultConfig()
+			cfg.Resources = tt.request
+			cfg.Logger = logger
+
+			if err := applyAutoResourceLimits(cfg); err != nil {
+				t.Fatalf("applyAutoResourceLimits() error = %v", err)
+			}
+			if cfg.Resources != tt.want {
+				t.Errorf("Resources = %+v, want %+v", cfg.Resources, tt.want)
+			}
+			if len(logger.warns) != tt.warns {
+				t.Errorf("warnings = %q, want %d", logger.warns, tt.warns)
+			}
+		})
+	}
+}
+
+func TestCreateAutoResourceLimits(t *testing.T) {
+	mockCgroup(t, "1073741824", "max 100000")
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithResources(ResourceConfig{MemoryMB: 4096, CPUs: 2}), WithAutoResourceLimits())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if mp.createOpts.Resources.MemoryMB != 819 || mp.createOpts.Resources.CPUs != 2 {
+		t.Errorf("Resources = %+v, want 819 MB and 2 CPUs", mp.createOpts.Resources)
+	}
+}
+
+func TestCreateAutoResourceLimitsError(t *testing.T) {
+	orig := readCgroupLimits
+	readCgroupLimits = func() (cgroup.Limits, error) { return cgroup.Limits{}, errors.New("permission denied") }
+	defer func() { readCgroupLimits = orig }()
+
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+	if _, err := Create(context.Background(), WithProvider("mock"), WithAutoResourceLimits()); err == nil {
+		t.Error("Create() should fail when the cgroup limits cannot be read")
+	}
+}
+
+func TestApplyMemoryMinimum(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  string
+		memoryMB int
+		want     int
+		warns    int
+	}{
+		{name: "java default raised", runtime: "Java", memoryMB: 512, want: 1024, warns: 1},
+		{name: "pinned version", runtime: "Java@21", memoryMB: 512, want: 1024, warns: 1},
+		{name: "alias", runtime: "rust", memoryMB: 256, want: 1024, warns: 1},
+		{name: "enough already", runtime: "Java", memoryMB: 2048, want: 2048},
+		{name: "unlimited", runtime: "Java", memoryMB: 0, want: 0},
+		{name: "no minimum", runtime: "Python", memoryMB: 128, want: 128},
+		{name: "no runtime", memoryMB: 512, want: 512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &warnLogger{}
+			cfg := DefaultConfig()
+			cfg.Runtime = tt.runtime
+			cfg.Resources.MemoryMB = tt.memoryMB
+			cfg.Logger = logger
+
+			applyMemoryMinimum(cfg)
+			if cfg.Resources.MemoryMB != tt.want {
+				t.Errorf("MemoryMB = %d, want %d", cfg.Resources.MemoryMB, tt.want)
+			}
+			if len(logger.warns) != tt.warns {
+				t.Errorf("warnings = %q, want %d", logger.warns, tt.warns)
+			}
+		})
+	}
+}
+
+func TestCreateMemoryMinimum(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithRuntime("Java"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	java, _ := GetRuntimeInfo("Java")
+	if got := mp.createOpts.Resources.MemoryMB; got != java.MinMemoryMB || got <= DefaultConfig().Resources.MemoryMB {
+		t.Errorf("MemoryMB = %d, want Java's minimum %d above the default", got, java.MinMemoryMB)
+	}
+
+	sb, err = Create(ctx, WithProvider("mock"), WithRuntime("Java"), WithSkipMemoryMinimum())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+	if got := mp.createOpts.Resources.MemoryMB; got != 512 {
+		t.Errorf("MemoryMB = %d with WithSkipMemoryMinimum, want 512", got)
+	}
+}
+
+func TestCreateMemoryMinimumWithinCgroup(t *testing.T) {
+	mockCgroup(t, "1073741824", "max 100000")
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithRuntime("Java"), WithAutoResourceLimits())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if got := mp.createOpts.Resources.MemoryMB; got != 819 {
+		t.Errorf("MemoryMB = %d, want the cgroup cap of 819", got)
+	}
+}
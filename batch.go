@@ -0,0 +1,181 @@
+package sindoq
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// BatchItem is one program run by Sandbox.ExecuteBatchStream.
+type BatchItem struct {
+	// Language of the item's code (auto-detected if empty).
+	Language string
+
+	// Code to execute.
+	Code string
+
+	// Options apply to this item only.
+	Options []ExecuteOption
+}
+
+// LabeledStreamEvent is a stream event from one item of a batch run by
+// Sandbox.ExecuteBatchEvents.
+type LabeledStreamEvent struct {
+	// ItemIndex is the index of the item in the batch.
+	ItemIndex int
+
+	*executor.StreamEvent
+}
+
+// BatchResult is the outcome of one BatchItem. Err is set when the item
+// could not be executed; Result is nil in that case.
+type BatchResult struct {
+	Result *executor.ExecutionResult
+	Err    error
+}
+
+// ExecuteBatchStream runs items with up to concurrency executions at a time
+// and calls cb with each item's index and result as soon as it finishes, so
+// results can be persisted incrementally. Calls to cb are serialized. A
+// concurrency below 1 runs items one at a time.
+//
+// Items run concurrently each get an ephemeral workdir (see
+// WithEphemeralWorkdir), so their code files and outputs do not collide;
+// an item's own WithPersistentWorkdir opts out. On providers without
+// ephemeral workdirs items run one at a time.
+//
+// Cancelling ctx stops dispatching new items; items already running finish
+// and are reported. If items were left undispatched it returns ctx.Err(),
+// and the batch can be resumed by passing the items whose indexes were not
+// reported.
+func (s *sandbox) ExecuteBatchStream(ctx context.Context, items []BatchItem, concurrency int, cb func(index int, r *BatchResult)) error {
+	if err := s.checkActive("executeBatch"); err != nil {
+		return err
+	}
+
+	concurrency, isolate := s.batchConcurrency(concurrency)
+	var cbMu sync.Mutex
+	return runBatch(ctx, items, concurrency, func(runCtx context.Context, index int, item BatchItem) {
+		result, err := s.Execute(runCtx, item.Code, item.options(isolate)...)
+
+		cbMu.Lock()
+		defer cbMu.Unlock()
+		cb(index, &BatchResult{Result: result, Err: err})
+	})
+}
+
+// ExecuteBatchEvents runs items like ExecuteBatchStream, in their own
+// workdirs when concurrent, but streams their output, multiplexing every
+// item's stream events onto one channel labeled with the item index. Events
+// of one item arrive in the order its stream produced them; events of
+// different items interleave. An item that fails without a complete or
+// error event gets a final StreamError event.
+//
+// The channel is closed once every dispatched item has finished, and the
+// caller must drain it. Cancelling ctx stops dispatching new items, which
+// then produce no events.
+func (s *sandbox) ExecuteBatchEvents(ctx context.Context, items []BatchItem, concurrency int) (<-chan LabeledStreamEvent, error) {
+	if err := s.checkActive("executeBatch"); err != nil {
+		return nil, err
+	}
+
+	concurrency, isolate := s.batchConcurrency(concurrency)
+	events := make(chan LabeledStreamEvent, concurrency)
+	go func() {
+		defer close(events)
+		runBatch(ctx, items, concurrency, func(runCtx context.Context, index int, item BatchItem) {
+			var ended atomic.Bool
+			err := s.ExecuteStream(runCtx, item.Code, func(e *executor.StreamEvent) error {
+				if e.Type == executor.StreamComplete || e.Type == executor.StreamError {
+					ended.Store(true)
+				}
+				events <- LabeledStreamEvent{ItemIndex: index, StreamEvent: e}
+				return nil
+			}, item.options(isolate)...)
+			if err != nil && !ended.Load() {
+				events <- LabeledStreamEvent{ItemIndex: index, StreamEvent: &executor.StreamEvent{
+					Type:      executor.StreamError,
+					Error:     err,
+					Timestamp: time.Now(),
+				}}
+			}
+		})
+	}()
+	return events, nil
+}
+
+// batchConcurrency returns how many items of a batch may run at once and
+// whether each then needs its own workdir. Concurrent runs would share the
+// working directory and the code file the provider writes there, so they
+// need ephemeral workdirs; without them items run one at a time.
+func (s *sandbox) batchConcurrency(concurrency int) (int, bool) {
+	if concurrency <= 1 {
+		return 1, false
+	}
+	if !s.capabilities.SupportsEphemeralWorkDir {
+		return 1, false
+	}
+	return concurrency, true
+}
+
+// options returns the item's execute options, led by its language and,
+// when isolate is set, an ephemeral workdir.
+func (item BatchItem) options(isolate bool) []ExecuteOption {
+	opts := make([]ExecuteOption, 0, len(item.Options)+2)
+	if item.Language != "" {
+		opts = append(opts, WithLanguage(item.Language))
+	}
+	if isolate {
+		opts = append(opts, WithEphemeralWorkdir())
+	}
+	return append(opts, item.Options...)
+}
+
+// runBatch calls run for each item with up to concurrency calls at a time
+// and waits for them. A concurrency below 1 runs items one at a time.
+// Cancelling ctx stops dispatching; run receives a context that is not
+// cancelled with it. It returns ctx.Err() if items were left undispatched.
+func runBatch(ctx context.Context, items []BatchItem, concurrency int, run func(runCtx context.Context, index int, item BatchItem)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// In-flight items must not be cut short when ctx is cancelled.
+	runCtx := context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	dispatched := 0
+dispatch:
+	for i := range items {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case sem <- struct{}{}:
+		}
+		// Prefer stopping over dispatching when both are ready.
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+
+		dispatched++
+		wg.Add(1)
+		go func(index int, item BatchItem) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			run(runCtx, index, item)
+		}(i, items[i])
+	}
+
+	wg.Wait()
+	if dispatched < len(items) {
+		return ctx.Err()
+	}
+	return nil
+}
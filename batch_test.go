@@ -0,0 +1,299 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// batchCapabilities are those of a provider that can run batch items
+// concurrently.
+var batchCapabilities = provider.Capabilities{
+	SupportsStreaming:        true,
+	SupportsEphemeralWorkDir: true,
+	SupportedLanguages:       []string{"Python"},
+}
+
+// setupBatchProvider registers a mock whose executions echo their code
+// after calling run, which may block or record concurrency.
+func setupBatchProvider(t *testing.T, run func()) func() {
+	t.Helper()
+	mp := &mockProvider{
+		name: "mock",
+		caps: &batchCapabilities,
+		instance: &mockInstance{
+			id:     "test-instance-123",
+			status: provider.StatusRunning,
+			execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+				run()
+				return &executor.ExecutionResult{Stdout: code, Language: opts.Language}
+			},
+		},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	return func() { factory.Unregister("mock") }
+}
+
+func TestSandboxExecuteBatchStream(t *testing.T) {
+	var running, peak atomic.Int32
+	cleanup := setupBatchProvider(t, func() {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	items := make([]BatchItem, 100)
+	for i := range items {
+		items[i] = BatchItem{Language: "Python", Code: fmt.Sprintf("print(%d)", i)}
+	}
+
+	// The callback is serialized, so it may update state without locking.
+	seen := make(map[int]bool)
+	err = sb.ExecuteBatchStream(ctx, items, 4, func(index int, r *BatchResult) {
+		if seen[index] {
+			t.Errorf("index %d reported twice", index)
+		}
+		seen[index] = true
+		if r.Err != nil {
+			t.Errorf("item %d error = %v", index, r.Err)
+			return
+		}
+		if r.Result.Stdout != items[index].Code {
+			t.Errorf("item %d Stdout = %q, want %q", index, r.Result.Stdout, items[index].Code)
+		}
+	})
+	if err != nil {
+		t.Fatalf("ExecuteBatchStream() error = %v", err)
+	}
+
+	if len(seen) != len(items) {
+		t.Errorf("got %d results, want %d", len(seen), len(items))
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("peak concurrency = %d, want at most 4", p)
+	}
+}
+
+func TestSandboxExecuteBatchStreamWorkdirs(t *testing.T) {
+	for _, supported := range []bool{true, false} {
+		t.Run(fmt.Sprintf("ephemeral workdirs supported=%v", supported), func(t *testing.T) {
+			caps := batchCapabilities
+			caps.SupportsEphemeralWorkDir = supported
+			caps.SupportedLanguages = []string{"Python", "C"}
+			var running, peak, ephemeral atomic.Int32
+			mp := &mockProvider{
+				name: "mock",
+				caps: &caps,
+				instance: &mockInstance{
+					id:     "test-instance-123",
+					status: provider.StatusRunning,
+					execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+						if opts.EphemeralWorkDir {
+							ephemeral.Add(1)
+						}
+						n := running.Add(1)
+						for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+						}
+						time.Sleep(5 * time.Millisecond)
+						running.Add(-1)
+						return &executor.ExecutionResult{}
+					},
+				},
+			}
+			factory.Register("mock", func(config any) (provider.Provider, error) {
+				return mp, nil
+			})
+			defer factory.Unregister("mock")
+
+			ctx := context.Background()
+			sb, err := Create(ctx, WithProvider("mock"))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			// Compiled items share the isolation: their binaries go into
+			// the run's workdir.
+			items := make([]BatchItem, 8)
+			for i := range items {
+				items[i] = BatchItem{Language: "Python", Code: "print(1)"}
+				if i%2 == 1 {
+					items[i] = BatchItem{Language: "C", Code: "int main(void) { return 0; }"}
+				}
+			}
+			if err := sb.ExecuteBatchStream(ctx, items, 4, func(int, *BatchResult) {}); err != nil {
+				t.Fatalf("ExecuteBatchStream() error = %v", err)
+			}
+
+			if supported {
+				if n := ephemeral.Load(); n != int32(len(items)) {
+					t.Errorf("%d of %d items ran in an ephemeral workdir, want all", n, len(items))
+				}
+			} else if p := peak.Load(); p != 1 || ephemeral.Load() != 0 {
+				t.Errorf("peak concurrency = %d, ephemeral runs = %d, want items run one at a time in the shared workdir", p, ephemeral.Load())
+			}
+		})
+	}
+}
+
+func TestSandboxExecuteBatchStreamCancel(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	cleanup := setupBatchProvider(t, func() {
+		started <- struct{}{}
+		<-release
+	})
+	defer cleanup()
+
+	sb, err := Create(context.Background(), WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(context.Background())
+
+	items := make([]BatchItem, 10)
+	for i := range items {
+		items[i] = BatchItem{Language: "Python", Code: fmt.Sprintf("print(%d)", i)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// Cancel once both workers are busy, then let them finish.
+		<-started
+		<-started
+		cancel()
+		close(release)
+	}()
+
+	var reported []int
+	err = sb.ExecuteBatchStream(ctx, items, 2, func(index int, r *BatchResult) {
+		if r.Err != nil {
+			t.Errorf("in-flight item %d error = %v", index, r.Err)
+		}
+		reported = append(reported, index)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteBatchStream() error = %v, want context.Canceled", err)
+	}
+	if len(reported) != 2 {
+		t.Errorf("reported %v, want the 2 in-flight items", reported)
+	}
+}
+
+func TestSandboxExecuteBatchStreamStopped(t *testing.T) {
+	cleanup := setupBatchProvider(t, func() {})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sb.Stop(ctx)
+
+	err = sb.ExecuteBatchStream(ctx, []BatchItem{{Code: "x"}}, 1, func(int, *BatchResult) {
+		t.Error("callback should not run on a stopped sandbox")
+	})
+	if !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("ExecuteBatchStream() error = %v, want ErrSandboxStopped", err)
+	}
+}
+
+func TestSandboxExecuteBatchEvents(t *testing.T) {
+	mp := &mockProvider{
+		name: "mock",
+		caps: &batchCapabilities,
+		instance: &mockInstance{
+			id:     "test-instance-123",
+			status: provider.StatusRunning,
+			streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+				for i := range 5 {
+					handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: fmt.Sprint(i)})
+				}
+				return handler(&executor.StreamEvent{Type: executor.StreamComplete})
+			},
+		},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	items := make([]BatchItem, 20)
+	for i := range items {
+		items[i] = BatchItem{Language: "Python", Code: "print(1)"}
+	}
+	items[7].Language = "COBOL"
+
+	events, err := sb.ExecuteBatchEvents(ctx, items, 4)
+	if err != nil {
+		t.Fatalf("ExecuteBatchEvents() error = %v", err)
+	}
+	got := make(map[int][]string)
+	for e := range events {
+		label := string(e.Type) + ":" + e.Data
+		if e.Type == executor.StreamError && e.Error == nil {
+			t.Errorf("item %d error event without Error", e.ItemIndex)
+		}
+		got[e.ItemIndex] = append(got[e.ItemIndex], label)
+	}
+
+	want := []string{"start:", "stdout:0", "stdout:1", "stdout:2", "stdout:3", "stdout:4", "complete:"}
+	for i := range items {
+		if i == 7 {
+			if fmt.Sprint(got[i]) != "[error:]" {
+				t.Errorf("item 7 events = %v, want one error event", got[i])
+			}
+			continue
+		}
+		if fmt.Sprint(got[i]) != fmt.Sprint(want) {
+			t.Errorf("item %d events = %v, want %v", i, got[i], want)
+		}
+	}
+}
+
+func TestSandboxExecuteBatchEventsStopped(t *testing.T) {
+	cleanup := setupBatchProvider(t, func() {})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sb.Stop(ctx)
+
+	if _, err := sb.ExecuteBatchEvents(ctx, []BatchItem{{Code: "x"}}, 1); !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("ExecuteBatchEvents() error = %v, want ErrSandboxStopped", err)
+	}
+}
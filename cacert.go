@@ -0,0 +1,95 @@
+package sindoq
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// caBundleName is the file custom CA certificates are written to in the
+// sandbox's working directory.
+const caBundleName = ".sindoq-ca-certificates.crt"
+
+// caBundlePath returns where the CA bundle lives for a sandbox working in
+// workDir.
+func caBundlePath(workDir string) string {
+	return path.Join(workDir, caBundleName)
+}
+
+// caTrustEnv lists the variables pointed at the CA bundle so common
+// runtimes pick it up: OpenSSL-based tools (Python ssl, curl, Ruby, PHP, Go)
+// read SSL_CERT_FILE, requests reads REQUESTS_CA_BUNDLE and Node.js reads
+// NODE_EXTRA_CA_CERTS.
+var caTrustEnv = []string{"SSL_CERT_FILE", "REQUESTS_CA_BUNDLE", "NODE_EXTRA_CA_CERTS"}
+
+// systemCABundles are well-known trust store locations in sandbox images.
+// The first one found is prepended to the custom bundle so public hosts
+// stay trusted when SSL_CERT_FILE replaces the default store.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/cert.pem",
+}
+
+// hasCACerts reports whether custom CA certificates are configured.
+func (c *Config) hasCACerts() bool {
+	return len(c.CACerts) > 0 || len(c.CACertFiles) > 0
+}
+
+// caBundle concatenates the configured CA certificates into a PEM bundle,
+// rejecting input that contains no certificates.
+func (c *Config) caBundle() ([]byte, error) {
+	var buf bytes.Buffer
+	add := func(name string, pem []byte) error {
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no PEM certificates found: %w", name, ErrInvalidConfiguration)
+		}
+		buf.Write(bytes.TrimSpace(pem))
+		buf.WriteByte('\n')
+		return nil
+	}
+
+	for i, pem := range c.CACerts {
+		if err := add(fmt.Sprintf("CA certificate %d", i), pem); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range c.CACertFiles {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		if err := add(path, pem); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// installCACerts writes the CA bundle into workDir of the instance,
+// prefixed with the image's system trust store when one can be read.
+func installCACerts(ctx context.Context, instance provider.Instance, workDir string, bundle []byte) error {
+	fsys := instance.FileSystem()
+	if fsys == nil {
+		return fmt.Errorf("install CA certificates: provider has no file system: %w", ErrInvalidConfiguration)
+	}
+
+	for _, path := range systemCABundles {
+		system, err := fsys.Read(ctx, path)
+		if err != nil || !x509.NewCertPool().AppendCertsFromPEM(system) {
+			continue
+		}
+		bundle = append(append(bytes.TrimSpace(system), '\n'), bundle...)
+		break
+	}
+
+	if err := fsys.Write(ctx, caBundlePath(workDir), bundle); err != nil {
+		return fmt.Errorf("install CA certificates: %w", err)
+	}
+	return nil
+}
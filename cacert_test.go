@@ -0,0 +1,194 @@
+package sindoq
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// memFileSystem stores files written to it in memory.
+type memFileSystem struct {
+	fs.FileSystem
+	files map[string][]byte
+}
+
+func (m *memFileSystem) Read(ctx context.Context, path string) ([]byte, error) {
+	data, ok := m.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memFileSystem) Write(ctx context.Context, path string, data []byte) error {
+	m.files[path] = data
+	return nil
+}
+
+func (m *memFileSystem) Delete(ctx context.Context, path string) error {
+	for p := range m.files {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(m.files, p)
+		}
+	}
+	return nil
+}
+
+func (m *memFileSystem) List(ctx context.Context, dir string) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	for p := range m.files {
+		rest, ok := strings.CutPrefix(p, dir+"/")
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rest, "/")
+		if !slices.ContainsFunc(infos, func(fi fs.FileInfo) bool { return fi.Name == name }) {
+			infos = append(infos, fs.FileInfo{Name: name, Path: dir + "/" + name, IsDir: isDir})
+		}
+	}
+	return infos, nil
+}
+
+func (m *memFileSystem) Exists(ctx context.Context, path string) (bool, error) {
+	_, ok := m.files[path]
+	return ok, nil
+}
+
+// testCACert returns a PEM-encoded self-signed CA certificate.
+func testCACert(t *testing.T, name string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey error: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestSandboxCACerts(t *testing.T) {
+	system := testCACert(t, "system root")
+	memfs := &memFileSystem{files: map[string][]byte{
+		"/etc/ssl/certs/ca-certificates.crt": system,
+	}}
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys:   memfs,
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	inline := testCACert(t, "inline CA")
+	file := testCACert(t, "file CA")
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithCACert(inline), WithCACertFile(path))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	bundlePath := "/workspace/" + caBundleName
+	bundle, ok := memfs.files[bundlePath]
+	if !ok {
+		t.Fatalf("CA bundle not written to %s", bundlePath)
+	}
+	for name, cert := range map[string][]byte{"system": system, "inline": inline, "file": file} {
+		if !strings.Contains(string(bundle), strings.TrimSpace(string(cert))) {
+			t.Errorf("CA bundle missing %s certificate", name)
+		}
+	}
+
+	if _, err := sb.Execute(ctx, `print(1)`, WithLanguage("Python")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, k := range []string{"SSL_CERT_FILE", "REQUESTS_CA_BUNDLE", "NODE_EXTRA_CA_CERTS"} {
+		if got := mp.instance.lastOpts.Env[k]; got != bundlePath {
+			t.Errorf("Env[%s] = %q, want %q", k, got, bundlePath)
+		}
+		if got, ok := mp.createOpts.Environment[k]; ok {
+			t.Errorf("CreateOptions.Environment[%s] = %q, want it set per execution only", k, got)
+		}
+	}
+}
+
+func TestSandboxCACertsWorkDir(t *testing.T) {
+	memfs := &memFileSystem{files: map[string][]byte{}}
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys:   memfs,
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithSandboxWorkDir("/srv/app"), WithCACert(testCACert(t, "inline CA")))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	bundlePath := "/srv/app/" + caBundleName
+	if _, ok := memfs.files[bundlePath]; !ok {
+		t.Fatalf("CA bundle not written to %s", bundlePath)
+	}
+	if _, err := sb.Execute(ctx, `print(1)`, WithLanguage("Python")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.Env["SSL_CERT_FILE"]; got != bundlePath {
+		t.Errorf("Env[SSL_CERT_FILE] = %q, want %q", got, bundlePath)
+	}
+}
+
+func TestSandboxCACertsInvalid(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	_, err := Create(ctx, WithProvider("mock"), WithCACert([]byte("not a certificate")))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Create() error = %v, want ErrInvalidConfiguration", err)
+	}
+
+	_, err = Create(ctx, WithProvider("mock"), WithCACertFile(filepath.Join(t.TempDir(), "missing.pem")))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Create() error = %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/happyhackingspace/sindoq"
+)
+
+// doctorValidateTimeout bounds each provider's Validate call.
+const doctorValidateTimeout = 5 * time.Second
+
+// doctorBinaries are the host tools used by the local providers.
+var doctorBinaries = []string{"docker", "podman", "runsc", "nsjail", "wasmer", "firecracker", "kubectl"}
+
+// runDoctor reports which providers and host binaries are usable and runs
+// a self-test of each language on the given provider. It returns an error
+// if any self-test fails.
+func runDoctor(ctx context.Context, w io.Writer, providerName string, languages []string, opts ...sindoq.Option) error {
+	fmt.Fprintln(w, "Providers:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PROVIDER\tSTATUS\tDETAILS")
+
+	providers := sindoq.ListProviders()
+	sort.Strings(providers)
+	for _, name := range providers {
+		vctx, cancel := context.WithTimeout(ctx, doctorValidateTimeout)
+		err := sindoq.ValidateProvider(vctx, name)
+		cancel()
+
+		if err != nil {
+			fmt.Fprintf(tw, "  %s\tunavailable\t%s\n", name, firstLine(err.Error()))
+		} else {
+			fmt.Fprintf(tw, "  %s\tavailable\t\n", name)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Binaries:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, bin := range doctorBinaries {
+		if path, err := exec.LookPath(bin); err == nil {
+			fmt.Fprintf(tw, "  %s\t%s\n", bin, path)
+		} else {
+			fmt.Fprintf(tw, "  %s\tnot found\n", bin)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Self-test (%s):\n", providerName)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var failed []string
+	for _, language := range languages {
+		result, err := sindoq.SelfTest(ctx, providerName, language, opts...)
+		if err != nil {
+			failed = append(failed, language)
+			fmt.Fprintf(tw, "  %s\tFAILED\t%s\n", language, firstLine(err.Error()))
+			if result.Stderr != "" {
+				fmt.Fprintf(tw, "  \t\tstderr: %s\n", firstLine(strings.TrimSpace(result.Stderr)))
+			}
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\tOK\t%s\n", result.Language, result.Duration.Round(time.Millisecond))
+	}
+	tw.Flush()
+	if len(failed) > 0 {
+		return fmt.Errorf("self-test on %s failed for %s", providerName, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// firstLine trims multi-line error messages to their first line.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -17,6 +17,7 @@ import (
 	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
 	_ "github.com/happyhackingspace/sindoq/internal/provider/e2b"
 	_ "github.com/happyhackingspace/sindoq/internal/provider/kubernetes"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/lambda"
 	_ "github.com/happyhackingspace/sindoq/internal/provider/podman"
 	_ "github.com/happyhackingspace/sindoq/internal/provider/vercel"
 	_ "github.com/happyhackingspace/sindoq/internal/provider/wasmer"
@@ -48,14 +49,18 @@ func reorderArgs() {
 }
 
 func main() {
-	provider := flag.String("provider", "docker", "Provider to use (docker, podman, wasmer, nsjail, gvisor, firecracker, kubernetes, vercel, e2b)")
+	provider := flag.String("provider", "docker", "Provider to use (docker, podman, wasmer, nsjail, gvisor, firecracker, kubernetes, vercel, e2b, lambda)")
 	language := flag.String("lang", "", "Language (auto-detected if not specified)")
+	strictLang := flag.Bool("strict-lang", false, "Fail when the language cannot be detected instead of assuming Python")
 	timeout := flag.Duration("timeout", 5*time.Minute, "Execution timeout")
 	stream := flag.Bool("stream", false, "Stream output in real-time")
 	jsonFormat := flag.Bool("json", false, "Output in JSON format")
+	jsonRequest := flag.Bool("json-request", false, "Read a JSON execution request from stdin and write a JSON response")
 	file := flag.String("file", "", "Execute code from file")
 	detect := flag.Bool("detect", false, "Only detect language, don't execute")
 	listLangs := flag.Bool("list-languages", false, "List supported languages")
+	doctor := flag.Bool("doctor", false, "Check which providers are usable and self-test the -lang languages (default Python)")
+	stdio := flag.Bool("stdio", false, "With serve, read JSON-lines requests from stdin and write responses to stdout")
 	version := flag.Bool("version", false, "Show version")
 
 	reorderArgs()
@@ -66,7 +71,10 @@ func main() {
 Usage:
   sindoq [flags] [code]
   sindoq [flags] -file <filename>
+  sindoq doctor [-lang python,go]
+  sindoq serve -stdio
   echo "print('hello')" | sindoq [flags]
+  echo '{"code": "print(1)"}' | sindoq -json-request
 
 Flags:
 `)
@@ -83,6 +91,8 @@ Examples:
 Environment Variables:
   VERCEL_TOKEN     - Vercel API token
   E2B_API_KEY      - E2B API key
+  LAMBDA_FUNCTION  - Lambda runner function name or ARN (AWS_REGION and
+                     credentials come from the usual AWS_* variables)
 `)
 	}
 
@@ -98,68 +108,137 @@ Environment Variables:
 		return
 	}
 
-	code, err := getCode(flag.Args(), *file)
+	if *doctor || (flag.NArg() == 1 && flag.Arg(0) == "doctor") {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		languages := []string{"Python"}
+		if *language != "" {
+			languages = strings.Split(*language, ",")
+		}
+		if err := runDoctor(ctx, os.Stdout, *provider, languages, providerOptions(*provider)...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() == 1 && flag.Arg(0) == "serve" {
+		if !*stdio {
+			fmt.Fprintln(os.Stderr, "Error: serve requires -stdio")
+			os.Exit(1)
+		}
+		opts := append(providerOptions(*provider), sindoq.WithFallbackLanguage("Python"), sindoq.WithTimeout(*timeout))
+		if *strictLang {
+			opts = append(opts, sindoq.WithStrictLanguage())
+		}
+		if err := sindoq.Serve(context.Background(), os.Stdin, os.Stdout, opts...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *jsonRequest {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if err := executeJSONRequest(ctx, os.Stdin, os.Stdout, *provider); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+
+	code, given, err := getCode(flag.Args(), *file)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if code == "" {
+	if !given {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if strings.TrimSpace(code) == "" {
+		fmt.Fprintf(os.Stderr, "Error: %v: the input has nothing to run\n", sindoq.ErrEmptyCode)
+		os.Exit(1)
+	}
 
 	if *detect {
-		detectLanguage(code)
+		detectLanguage(code, *jsonFormat)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
 	defer cancel()
 
-	if err := executeCode(ctx, code, *provider, *language, *stream, *jsonFormat); err != nil {
+	if err := executeCode(ctx, code, *provider, *language, *strictLang, *stream, *jsonFormat); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func getCode(args []string, filename string) (string, error) {
+// getCode returns the code from the file, the arguments or piped stdin,
+// in that order. given is false when there is no source at all, as
+// opposed to a source that is empty.
+func getCode(args []string, filename string) (code string, given bool, err error) {
 	if filename != "" {
 		data, err := os.ReadFile(filename)
 		if err != nil {
-			return "", fmt.Errorf("reading file: %w", err)
+			return "", true, fmt.Errorf("reading file: %w", err)
 		}
-		return string(data), nil
+		return string(data), true, nil
 	}
 
 	if len(args) > 0 {
-		return strings.Join(args, " "), nil
+		return strings.Join(args, " "), true, nil
 	}
 
 	stat, _ := os.Stdin.Stat()
 	if (stat.Mode() & os.ModeCharDevice) == 0 {
 		data, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return "", fmt.Errorf("reading stdin: %w", err)
+			return "", true, fmt.Errorf("reading stdin: %w", err)
 		}
-		return strings.TrimSpace(string(data)), nil
+		return strings.TrimSpace(string(data)), true, nil
 	}
 
-	return "", nil
+	return "", false, nil
 }
 
-func detectLanguage(code string) {
+func detectLanguage(code string, jsonFormat bool) {
 	detector := langdetect.New()
 	result := detector.Detect(code, langdetect.DefaultDetectOptions())
 
+	if jsonFormat {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+		return
+	}
+
 	fmt.Printf("Language:   %s\n", result.Language)
 	fmt.Printf("Confidence: %.2f\n", result.Confidence)
 	fmt.Printf("Method:     %s\n", result.Method)
+	for _, sig := range result.Signals {
+		fmt.Printf("Signal:     %-10s %s (%.2f)\n", sig.Method, sig.Language, sig.Confidence)
+	}
 
 	if info, ok := langdetect.GetRuntimeInfo(result.Language); ok {
 		fmt.Printf("Runtime:    %s\n", info.Runtime)
 		fmt.Printf("Extension:  %s\n", info.FileExt)
 		fmt.Printf("Docker:     %s\n", info.DockerImage)
+
+		profile := info.Profile()
+		switch {
+		case profile.IsInterpreted:
+			fmt.Println("Build:      interpreted")
+		case profile.NeedsCompilation:
+			fmt.Printf("Build:      compiled (~%v)\n", profile.TypicalCompileTime)
+		default:
+			fmt.Printf("Build:      built by the run command (~%v)\n", profile.TypicalCompileTime)
+		}
+		if profile.MinMemoryMB > 0 {
+			fmt.Printf("Memory:     at least %d MB\n", profile.MinMemoryMB)
+		}
 	}
 }
 
@@ -175,21 +254,10 @@ func listLanguages() {
 	}
 }
 
-func executeCode(ctx context.Context, code, providerName, language string, stream, jsonFormat bool) error {
-	if language == "" {
-		detector := langdetect.New()
-		result := detector.Detect(code, langdetect.DefaultDetectOptions())
-		if result.Language != "" {
-			language = result.Language
-		} else {
-			language = "Python"
-		}
-	}
-
-	opts := []sindoq.Option{
-		sindoq.WithProvider(providerName),
-		sindoq.WithRuntime(language),
-	}
+// providerOptions selects the provider and configures it from the
+// environment.
+func providerOptions(providerName string) []sindoq.Option {
+	opts := []sindoq.Option{sindoq.WithProvider(providerName)}
 
 	switch providerName {
 	case "vercel":
@@ -200,6 +268,51 @@ func executeCode(ctx context.Context, code, providerName, language string, strea
 		if key := os.Getenv("E2B_API_KEY"); key != "" {
 			opts = append(opts, sindoq.WithE2BConfig(sindoq.E2BConfig{APIKey: key}))
 		}
+	case "lambda":
+		if fn := os.Getenv("LAMBDA_FUNCTION"); fn != "" {
+			opts = append(opts, sindoq.WithLambdaConfig(sindoq.LambdaConfig{FunctionName: fn}))
+		}
+	}
+
+	return opts
+}
+
+// executeJSONRequest runs the sindoq.ExecuteRequest read from r and writes
+// a sindoq.ExecuteResponse to w. The request's provider takes precedence
+// over providerName. It returns the request or execution error, which is
+// also reported in the response.
+func executeJSONRequest(ctx context.Context, r io.Reader, w io.Writer, providerName string) error {
+	req, err := sindoq.ParseExecuteRequest(r)
+	if err != nil {
+		sindoq.ExecuteToJSON(w, nil, err)
+		return err
+	}
+
+	if req.Provider != "" {
+		providerName = req.Provider
+	}
+	result, err := req.Execute(ctx, providerOptions(providerName)...)
+	if werr := sindoq.ExecuteToJSON(w, result, err); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// executeCode runs code, assuming Python when its language is neither
+// given nor detected unless strict is set.
+func executeCode(ctx context.Context, code, providerName, language string, strict, stream, jsonFormat bool) error {
+	opts := append(providerOptions(providerName), sindoq.WithFallbackLanguage("Python"))
+	if strict {
+		opts = append(opts, sindoq.WithStrictLanguage())
+	}
+
+	// Pick the sandbox runtime up front; an undetected language leaves the
+	// provider's default, and Execute settles the language.
+	if language == "" {
+		language = langdetect.New().Detect(code, langdetect.DefaultDetectOptions()).Language
+	}
+	if language != "" {
+		opts = append(opts, sindoq.WithRuntime(language))
 	}
 
 	sb, err := sindoq.Create(ctx, opts...)
@@ -215,24 +328,18 @@ func executeCode(ctx context.Context, code, providerName, language string, strea
 
 	start := time.Now()
 
+	var result *executor.ExecutionResult
 	if stream && !jsonFormat {
-		return sb.ExecuteStream(ctx, code, func(e *executor.StreamEvent) error {
-			switch e.Type {
-			case executor.StreamStdout:
-				fmt.Print(e.Data)
-			case executor.StreamStderr:
-				fmt.Fprint(os.Stderr, e.Data)
-			case executor.StreamError:
-				return e.Error
-			}
-			return nil
-		}, execOpts...)
+		result, err = sb.ExecuteTo(ctx, code, os.Stdout, os.Stderr, execOpts...)
+	} else {
+		result, err = sb.Execute(ctx, code, execOpts...)
 	}
-
-	result, err := sb.Execute(ctx, code, execOpts...)
 	if err != nil {
 		return err
 	}
+	if result.Language != "" {
+		language = result.Language
+	}
 
 	if jsonFormat {
 		output := struct {
@@ -254,7 +361,7 @@ func executeCode(ctx context.Context, code, providerName, language string, strea
 		if err := enc.Encode(output); err != nil {
 			return err
 		}
-	} else {
+	} else if !stream {
 		if result.Stdout != "" {
 			fmt.Print(result.Stdout)
 		}
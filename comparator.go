@@ -0,0 +1,97 @@
+package sindoq
+
+import (
+	"slices"
+	"strings"
+)
+
+// OutputComparator decides whether a program's output is correct, for
+// ExecuteExpect and Judge. Compare returns whether actual matches
+// expected and, when it does not, an optional diff explaining why.
+type OutputComparator interface {
+	Compare(expected, actual string) (match bool, diff string)
+}
+
+// ComparatorFunc adapts a function to an OutputComparator.
+type ComparatorFunc func(expected, actual string) (match bool, diff string)
+
+// Compare calls f.
+func (f ComparatorFunc) Compare(expected, actual string) (bool, string) {
+	return f(expected, actual)
+}
+
+// WithComparator replaces the built-in comparison with cmp, such as one of
+// ExactComparator, TrimmedComparator, WhitespaceComparator,
+// NumericComparator and LineSetComparator, or a custom one. The
+// normalization options, such as WithTrimTrailingSpace, then have no
+// effect; WithCombinedOutput still selects what is compared.
+func WithComparator(cmp OutputComparator) ExpectOption {
+	return func(c *ExpectConfig) {
+		c.Comparator = cmp
+	}
+}
+
+// expectComparator compares output with the settings of an ExpectConfig.
+type expectComparator ExpectConfig
+
+func (c *expectComparator) Compare(expected, actual string) (bool, string) {
+	return compareOutput(expected, actual, (*ExpectConfig)(c))
+}
+
+// ExactComparator matches output byte for byte, final newline included.
+// It is what ExecuteExpect does without options.
+func ExactComparator() OutputComparator {
+	return &expectComparator{}
+}
+
+// TrimmedComparator ignores whitespace at the end of lines and blank
+// lines at the end of the output, as WithTrimTrailingSpace does.
+func TrimmedComparator() OutputComparator {
+	return &expectComparator{TrimTrailingSpace: true}
+}
+
+// WhitespaceComparator ignores how much whitespace separates words and
+// whether it leads or trails a line, and skips blank lines, so "1  2"
+// matches "1 2". Line breaks still matter.
+func WhitespaceComparator() OutputComparator {
+	return &expectComparator{TrimTrailingSpace: true, IgnoreBlankLines: true, collapseSpace: true}
+}
+
+// NumericComparator compares lines word by word, letting numbers differ
+// by up to tol, so "0.30000000000000004" matches "0.3". Whitespace is
+// handled as by WhitespaceComparator.
+func NumericComparator(tol float64) OutputComparator {
+	return &expectComparator{TrimTrailingSpace: true, IgnoreBlankLines: true, collapseSpace: true, FloatTolerance: tol}
+}
+
+// LineSetComparator matches output with the same lines in any order, for
+// programs whose output order is unspecified, such as the keys of a hash
+// map. Repeated lines must be repeated as often, trailing whitespace and
+// blank lines are ignored, and the diff is between the sorted lines.
+func LineSetComparator() OutputComparator {
+	return lineSetComparator{}
+}
+
+type lineSetComparator struct{}
+
+func (lineSetComparator) Compare(expected, actual string) (bool, string) {
+	cfg := &ExpectConfig{TrimTrailingSpace: true, IgnoreBlankLines: true}
+	a, b := sortedLines(expected, cfg), sortedLines(actual, cfg)
+	eq := func(x, y outputLine) bool { return x.key == y.key }
+	if slices.EqualFunc(a, b, eq) {
+		return true, ""
+	}
+	return false, unifiedDiff(a, b, eq)
+}
+
+// sortedLines splits output into lines sorted by their normalized form
+// and numbered in that order.
+func sortedLines(output string, cfg *ExpectConfig) []outputLine {
+	lines := splitOutput(output, cfg)
+	slices.SortStableFunc(lines, func(x, y outputLine) int { return strings.Compare(x.key, y.key) })
+	for i := range lines {
+		lines[i].num = i + 1
+		lines[i].noEOL = false
+	}
+	return lines
+}
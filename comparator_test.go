@@ -0,0 +1,96 @@
+package sindoq
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestBuiltinComparators(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmp      OutputComparator
+		expected string
+		actual   string
+		want     bool
+	}{
+		{"exact", ExactComparator(), "1\n2\n", "1\n2\n", true},
+		{"exact trailing space", ExactComparator(), "1\n2\n", "1 \n2\n", false},
+		{"exact final newline", ExactComparator(), "1\n", "1", false},
+		{"trimmed", TrimmedComparator(), "1\n2\n", "1  \n2\t\n\n", true},
+		{"trimmed leading space", TrimmedComparator(), "1\n", " 1\n", false},
+		{"whitespace", WhitespaceComparator(), "1 2 3\nfoo\n", "  1\t2   3 \n\nfoo", true},
+		{"whitespace line breaks", WhitespaceComparator(), "1 2\n", "1\n2\n", false},
+		{"whitespace words", WhitespaceComparator(), "a b\n", "ab\n", false},
+		{"numeric", NumericComparator(1e-6), "x 0.3\n", "x  0.30000000000000004", true},
+		{"numeric beyond tolerance", NumericComparator(1e-6), "0.3\n", "0.31\n", false},
+		{"numeric text differs", NumericComparator(1), "x 1\n", "y 1\n", false},
+		{"line set", LineSetComparator(), "a\nb\nc\n", "c\na \n\nb", true},
+		{"line set count", LineSetComparator(), "a\na\nb\n", "a\nb\nb\n", false},
+		{"line set missing", LineSetComparator(), "a\nb\n", "b\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, diff := tt.cmp.Compare(tt.expected, tt.actual)
+			if match != tt.want {
+				t.Errorf("match = %v, want %v\n%s", match, tt.want, diff)
+			}
+			if match != (diff == "") {
+				t.Errorf("diff = %q for match = %v", diff, match)
+			}
+		})
+	}
+}
+
+func TestLineSetComparatorDiff(t *testing.T) {
+	_, diff := LineSetComparator().Compare("b\na\n", "c\nb\n")
+	want := "--- expected\n+++ actual\n@@ -1,2 +1,2 @@\n-a\n b\n+c\n"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestWithComparator(t *testing.T) {
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{Stdout: "ANSWER: 42\n"}
+	})
+	ctx := context.Background()
+
+	// A custom comparator that only checks the answer is present.
+	var got [2]string
+	contains := ComparatorFunc(func(expected, actual string) (bool, string) {
+		got = [2]string{expected, actual}
+		if strings.Contains(actual, expected) {
+			return true, ""
+		}
+		return false, "missing " + expected
+	})
+
+	er, err := sb.ExecuteExpect(ctx, "print('ANSWER: 42')", "42", WithComparator(contains), WithExecuteOptions(WithLanguage("Python")))
+	if err != nil {
+		t.Fatalf("ExecuteExpect() error = %v", err)
+	}
+	if !er.Match || got != [2]string{"42", "ANSWER: 42\n"} {
+		t.Errorf("Match = %v, comparator got %q", er.Match, got)
+	}
+
+	// The comparator replaces the normalization options.
+	er, err = sb.ExecuteExpect(ctx, "print('ANSWER: 42')", "answer: 42", WithIgnoreCase(), WithComparator(contains), WithExecuteOptions(WithLanguage("Python")))
+	if err != nil {
+		t.Fatalf("ExecuteExpect() error = %v", err)
+	}
+	if er.Match || er.Diff != "missing answer: 42" {
+		t.Errorf("Match = %v, Diff = %q, want the comparator's mismatch", er.Match, er.Diff)
+	}
+
+	jr, err := sb.Judge(ctx, "print('ANSWER: 42')", []JudgeCase{{Expected: "42"}, {Expected: "43"}},
+		WithJudgeExpect(WithComparator(contains), WithExecuteOptions(WithLanguage("Python"))))
+	if err != nil {
+		t.Fatalf("Judge() error = %v", err)
+	}
+	if jr.Passed != 1 || jr.Cases[1].Verdict != VerdictWrongAnswer || jr.Cases[1].Diff != "missing 43" {
+		t.Errorf("Judge() = %+v, want the second case wrong with the comparator's diff", jr)
+	}
+}
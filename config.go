@@ -1,11 +1,20 @@
 package sindoq
 
 import (
+	"fmt"
 	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/happyhackingspace/sindoq/internal/provider"
 	"github.com/happyhackingspace/sindoq/pkg/event"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
 )
 
 // Config holds sandbox configuration.
@@ -13,15 +22,26 @@ type Config struct {
 	// Provider specifies which provider to use.
 	Provider string
 
-	// ProviderConfig holds provider-specific configuration.
+	// ProviderRouting maps canonical language names to the provider that
+	// runs them, overriding Provider. See WithProviderRouting.
+	ProviderRouting map[string]string
+
+	// ProviderConfig holds provider-specific configuration, such as the
+	// DockerConfig set by WithDockerConfig. Create converts it to the
+	// provider's own config; zero fields keep the provider's defaults.
 	ProviderConfig any
 
 	// DefaultTimeout for execution.
 	DefaultTimeout time.Duration
 
-	// DefaultLanguage when detection fails.
+	// DefaultLanguage is the fallback used when detection finds no
+	// language. Set it with WithFallbackLanguage.
 	DefaultLanguage string
 
+	// StrictLanguage makes undetectable code fail with
+	// ErrLanguageDetectionFailed even when DefaultLanguage is set.
+	StrictLanguage bool
+
 	// Runtime specifies the language runtime for sandbox creation (e.g., "Python", "JavaScript").
 	// This determines which Docker image or runtime environment to use.
 	Runtime string
@@ -29,20 +49,124 @@ type Config struct {
 	// Image specifies a specific container/VM image to use (overrides Runtime).
 	Image string
 
+	// Polyglot marks Image as providing runtimes for several languages.
+	Polyglot bool
+
+	// WorkDir is the sandbox's working directory and the default for
+	// executions. Empty uses the provider's default, falling back to
+	// /tmp/sindoq on images that cannot use it. See WithSandboxWorkDir.
+	WorkDir string
+
+	// FileLifetime decides whether files left in WorkDir persist between
+	// executions. See WithFileLifetimePolicy.
+	FileLifetime FileLifetimePolicy
+
 	// Resources configuration.
 	Resources ResourceConfig
 
+	// AutoResourceLimits caps Resources to the cgroup limits of the
+	// process creating the sandbox.
+	AutoResourceLimits bool
+
+	// SkipMemoryMinimum keeps Resources.MemoryMB even when it is below the
+	// minimum the Runtime language needs. See WithSkipMemoryMinimum.
+	SkipMemoryMinimum bool
+
 	// Logger for debug output.
 	Logger Logger
 
 	// EventHandler for global events.
 	EventHandler event.EventHandler
 
+	// AuditCode includes the executed code in execution.started events,
+	// which otherwise carry only its hash.
+	AuditCode bool
+
 	// AutoDetectLanguage enables automatic language detection.
 	AutoDetectLanguage bool
 
 	// InternetAccess controls network access from sandbox.
 	InternetAccess bool
+
+	// Ports lists sandbox ports to make reachable from the host through
+	// Network().PublishPort.
+	Ports []int
+
+	// Ulimits sets resource limits by name for the whole sandbox.
+	Ulimits map[string]executor.Ulimit
+
+	// ReadonlyRootfs mounts the sandbox root filesystem read-only.
+	ReadonlyRootfs bool
+
+	// CapDrop lists Linux capabilities removed from the sandbox.
+	CapDrop []string
+
+	// CapAdd lists Linux capabilities granted to the sandbox.
+	CapAdd []string
+
+	// SecurityProfile names the preset set by WithSecurityProfile.
+	SecurityProfile string
+
+	// InterpreterReuse keeps warm interpreters running between executions.
+	InterpreterReuse bool
+
+	// RespectEntrypoint runs the image's own entrypoint instead of
+	// replacing it.
+	RespectEntrypoint bool
+
+	// DeterministicID derives the sandbox ID from its configuration and
+	// reuses a running sandbox with that ID. See WithDeterministicID.
+	DeterministicID bool
+
+	// InterpreterPaths maps languages to the interpreter that replaces the
+	// first element of their run command.
+	InterpreterPaths map[string]string
+
+	// Tracer, when set, wraps sandbox operations in OpenTelemetry spans.
+	Tracer trace.Tracer
+
+	// ProviderEnv holds environment variables applied to every execution.
+	ProviderEnv map[string]string
+
+	// Secrets holds environment variables applied to every execution whose
+	// values are redacted from returned errors and events.
+	Secrets map[string]string
+
+	// SecretFiles maps file names to contents written under
+	// executor.SecretsDir for every execution and removed afterwards.
+	SecretFiles map[string][]byte
+
+	// CACerts holds PEM-encoded CA certificates trusted inside the sandbox.
+	CACerts [][]byte
+
+	// CACertFiles lists host paths of PEM-encoded CA certificates trusted
+	// inside the sandbox. They are read when the sandbox is created.
+	CACertFiles []string
+
+	// Recording receives a JSON ExecutionRecord for every execution.
+	Recording io.Writer
+
+	// RedactPatterns are regular expressions whose matches are replaced
+	// with "***" in program output.
+	RedactPatterns []string
+
+	// ResultRetention is how many ExecuteAsync results are kept for
+	// GetResult, and ResultRetentionAge how long; zero age keeps them
+	// until evicted by count.
+	ResultRetention    int
+	ResultRetentionAge time.Duration
+
+	// MaxLifetime, if positive, stops the sandbox that long after it was
+	// created, however busy it is.
+	MaxLifetime time.Duration
+
+	// ParseDirectives applies a leading "sindoq:" comment in the code to
+	// each execution. See WithParseDirectives.
+	ParseDirectives bool
+
+	// DirectiveMaxTimeout is the longest timeout a directive may set; zero
+	// means DefaultTimeout.
+	DirectiveMaxTimeout time.Duration
 }
 
 // DefaultConfig returns sensible defaults.
@@ -55,24 +179,30 @@ func DefaultConfig() *Config {
 		Resources: ResourceConfig{
 			MemoryMB: 512,
 			CPUs:     1,
-			DiskMB:   1024,
 		},
+		ResultRetention:    DefaultResultRetention,
+		ResultRetentionAge: DefaultResultRetentionAge,
 	}
 }
 
 // Option configures a sandbox.
 type Option func(*Config)
 
-// WithProvider sets the provider.
+// WithProvider sets the provider. A config set earlier with another
+// provider's With*Config option is dropped.
 func WithProvider(providerName string) Option {
 	return func(c *Config) {
-		c.Provider = providerName
+		c.setProvider(providerName)
 	}
 }
 
 // WithRuntime sets the language runtime for sandbox creation.
 // This determines which Docker image or runtime environment to use.
 // Examples: "Python", "JavaScript", "Go", "Rust"
+//
+// A version may be pinned with "@", e.g. "Python@3.11" or "Node@18", which
+// selects the matching image on Docker and gVisor. Providers without
+// versioned runtimes reject pinned versions when the sandbox is created.
 func WithRuntime(runtime string) Option {
 	return func(c *Config) {
 		c.Runtime = runtime
@@ -87,6 +217,19 @@ func WithImage(image string) Option {
 	}
 }
 
+// WithPolyglotImage runs every execution in image, which provides runtimes
+// for several languages. Each execution uses the run command of its
+// detected (or given) language rather than switching images, so Python and
+// Node can share one container and its files. Docker and gVisor check that
+// the image has a language's runtime before its first run; other providers
+// ignore it.
+func WithPolyglotImage(image string) Option {
+	return func(c *Config) {
+		c.Image = image
+		c.Polyglot = true
+	}
+}
+
 // WithDockerConfig configures Docker provider.
 func WithDockerConfig(cfg DockerConfig) Option {
 	return func(c *Config) {
@@ -111,6 +254,14 @@ func WithE2BConfig(cfg E2BConfig) Option {
 	}
 }
 
+// WithLambdaConfig configures AWS Lambda provider.
+func WithLambdaConfig(cfg LambdaConfig) Option {
+	return func(c *Config) {
+		c.Provider = "lambda"
+		c.ProviderConfig = cfg
+	}
+}
+
 // WithKubernetesConfig configures Kubernetes provider.
 func WithKubernetesConfig(cfg KubernetesConfig) Option {
 	return func(c *Config) {
@@ -143,6 +294,18 @@ func WithGVisorConfig(cfg GVisorConfig) Option {
 	}
 }
 
+// WithGVisorFallback selects the gVisor provider and lets it fall back to
+// Docker's default runtime, with a warning, when runsc is not configured in
+// Docker. The fallback does not provide gVisor's isolation.
+func WithGVisorFallback() Option {
+	return func(c *Config) {
+		cfg, _ := c.ProviderConfig.(GVisorConfig)
+		cfg.Fallback = true
+		c.Provider = "gvisor"
+		c.ProviderConfig = cfg
+	}
+}
+
 // WithNsjailConfig configures nsjail provider.
 func WithNsjailConfig(cfg NsjailConfig) Option {
 	return func(c *Config) {
@@ -166,6 +329,47 @@ func WithTimeout(d time.Duration) Option {
 	}
 }
 
+// WithMaxLifetime stops the sandbox d after it is created, whether or not
+// it is in use, as a ceiling for long-lived sandboxes in a service.
+// Executions running at that moment are canceled and fail with
+// ErrMaxLifetimeExceeded; later ones fail with ErrSandboxStopped. The
+// sandbox emits EventSandboxStopped with event.SandboxStoppedData whose
+// Reason is event.StopReasonMaxLifetime.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(c *Config) {
+		c.MaxLifetime = d
+	}
+}
+
+// WithParseDirectives lets code set its own execution options with a
+// directive comment on its first line (after any shebang), in the
+// language's comment syntax:
+//
+//	# sindoq: timeout=10s memory=256MB stdin-file=input.txt
+//	// sindoq: language=c timeout=2s
+//
+// The keys are timeout, memory, language and stdin-file. Timeouts above
+// DirectiveMaxTimeout (by default the sandbox's timeout) and memory above
+// the sandbox's are clamped, with a warning. Memory lowers the run's memory
+// cgroup limit; only providers with SupportsExecutionMemoryLimit (nsjail)
+// can apply it, and elsewhere the execution fails with
+// ErrCapabilityNotSupported. stdin-file is read from the
+// sandbox, relative to the working directory. Options passed to Execute
+// override directives, and a malformed directive fails the execution.
+func WithParseDirectives() Option {
+	return func(c *Config) {
+		c.ParseDirectives = true
+	}
+}
+
+// WithDirectiveMaxTimeout sets the longest timeout a directive comment may
+// request. See WithParseDirectives.
+func WithDirectiveMaxTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.DirectiveMaxTimeout = d
+	}
+}
+
 // WithResources sets resource limits.
 func WithResources(r ResourceConfig) Option {
 	return func(c *Config) {
@@ -173,6 +377,124 @@ func WithResources(r ResourceConfig) Option {
 	}
 }
 
+// WithAutoResourceLimits caps the requested memory and CPUs to what the
+// cgroup of the current process allows, leaving headroom for the process
+// itself, so a sindoq running in a limited container does not request
+// sandboxes its host cannot fit. Clamped requests are logged as warnings.
+func WithAutoResourceLimits() Option {
+	return func(c *Config) {
+		c.AutoResourceLimits = true
+	}
+}
+
+// WithSandboxWorkDir sets the working directory the sandbox is created
+// with and executions run in unless WithWorkDir overrides it. Docker and
+// gVisor create it if missing; Create fails if the image's user cannot
+// write to it. Without it, providers use their default, /workspace for
+// most, and move to /tmp/sindoq on images whose user cannot create that.
+func WithSandboxWorkDir(dir string) Option {
+	return func(c *Config) {
+		c.WorkDir = dir
+	}
+}
+
+// WithSkipMemoryMinimum creates the sandbox with exactly the requested
+// memory. By default a request below the MinMemoryMB of the WithRuntime
+// language, e.g. 512 MB for Java, whose compiler needs more, is raised to
+// that minimum with a logged warning.
+func WithSkipMemoryMinimum() Option {
+	return func(c *Config) {
+		c.SkipMemoryMinimum = true
+	}
+}
+
+// WithProviderEnv sets environment variables for every execution in the
+// sandbox. Docker and gVisor also apply them at container creation.
+// Per-call WithEnv values take precedence; see WithSecrets.
+func WithProviderEnv(env map[string]string) Option {
+	return func(c *Config) {
+		c.ProviderEnv = env
+	}
+}
+
+// WithSecrets injects secret environment variables into every execution.
+// They take precedence over WithProviderEnv but not over per-call WithEnv.
+// Secret values are replaced with "[REDACTED]" in errors returned by the
+// sandbox and in error events, and with "***" in program output.
+func WithSecrets(secrets map[string]string) Option {
+	return func(c *Config) {
+		c.Secrets = secrets
+	}
+}
+
+// WithSecretFiles delivers secrets as files instead of environment
+// variables, which other processes can read through /proc. Each name must be
+// a plain file name; the file appears as executor.SecretsDir+"/"+name with
+// mode 0400 for the duration of each execution and is removed once no
+// overlapping execution still uses it, including after a timeout. Contents
+// are redacted like WithSecrets values and are never recorded or reported as
+// file changes.
+//
+// Docker and gVisor keep the files on a tmpfs, so they never reach disk and
+// vanish with the container. nsjail writes them to its host scratch
+// directory with restrictive permissions and binds it read-only. Other
+// providers fail Create with ErrCapabilityNotSupported.
+func WithSecretFiles(files map[string][]byte) Option {
+	return func(c *Config) {
+		c.SecretFiles = files
+	}
+}
+
+// WithCACert adds PEM-encoded CA certificates to the sandbox trust store.
+// The certificates are written to a bundle in the working directory, and
+// SSL_CERT_FILE, REQUESTS_CA_BUNDLE and NODE_EXTRA_CA_CERTS point at it in
+// the environment of each execution; RunCommand does not set them. The
+// image's system roots are kept in the bundle when they can be read.
+func WithCACert(pem []byte) Option {
+	return func(c *Config) {
+		c.CACerts = append(c.CACerts, pem)
+	}
+}
+
+// WithCACertFile is like WithCACert but reads the certificates from a host
+// file when the sandbox is created.
+func WithCACertFile(path string) Option {
+	return func(c *Config) {
+		c.CACertFiles = append(c.CACertFiles, path)
+	}
+}
+
+// WithRecording writes every execution in the sandbox to w as a JSON
+// ExecutionRecord, one per line. Environment values and secrets are redacted.
+// Use ReadRecords and testutil.NewReplayProvider to replay them.
+func WithRecording(w io.Writer) Option {
+	return func(c *Config) {
+		c.Recording = w
+	}
+}
+
+// WithResultRetention keeps the last n ExecuteAsync results, each for at
+// most maxAge after it finished, for GetResult. A maxAge of zero keeps
+// results until n newer ones evict them; n of zero disables retention.
+// The default is DefaultResultRetention results for
+// DefaultResultRetentionAge.
+func WithResultRetention(n int, maxAge time.Duration) Option {
+	return func(c *Config) {
+		c.ResultRetention = n
+		c.ResultRetentionAge = maxAge
+	}
+}
+
+// WithRedactPatterns replaces matches of the given regular expressions with
+// "***" in the stdout and stderr of results, streamed events and commands.
+// WithSecrets values are always redacted from output. Invalid patterns fail
+// sandbox creation.
+func WithRedactPatterns(patterns []string) Option {
+	return func(c *Config) {
+		c.RedactPatterns = append(c.RedactPatterns, patterns...)
+	}
+}
+
 // WithLogger sets a custom logger.
 func WithLogger(l Logger) Option {
 	return func(c *Config) {
@@ -187,6 +509,34 @@ func WithEventHandler(h event.EventHandler) Option {
 	}
 }
 
+// WithFallbackLanguage sets the language used when detection finds none,
+// e.g. for a snippet too short to recognize. Without a fallback, such code
+// fails with ErrLanguageDetectionFailed. It does not affect code whose
+// language is given or detected.
+func WithFallbackLanguage(language string) Option {
+	return func(c *Config) {
+		c.DefaultLanguage = language
+	}
+}
+
+// WithStrictLanguage makes code whose language is neither given nor
+// detected fail with ErrLanguageDetectionFailed instead of running as the
+// fallback language.
+func WithStrictLanguage() Option {
+	return func(c *Config) {
+		c.StrictLanguage = true
+	}
+}
+
+// WithAuditCode includes the executed code in execution.started events
+// for audit trails. By default they carry only its SHA-256 hash, so
+// sensitive code is not logged by event handlers.
+func WithAuditCode() Option {
+	return func(c *Config) {
+		c.AuditCode = true
+	}
+}
+
 // WithAutoDetect enables automatic language detection.
 func WithAutoDetect() Option {
 	return func(c *Config) {
@@ -201,22 +551,199 @@ func WithInternetAccess() Option {
 	}
 }
 
+// WithPorts reserves host bindings for ports so that Network().PublishPort
+// can return a localhost URL for them. Docker and gVisor only bind ports at
+// creation time and require WithInternetAccess; other providers ignore it.
+func WithPorts(ports ...int) Option {
+	return func(c *Config) {
+		c.Ports = append(c.Ports, ports...)
+	}
+}
+
+// WithUlimits sets resource limits by name for every process in the
+// sandbox, e.g. raising "stack" for deeply recursive programs or "nofile"
+// for programs that open many files. Values use setrlimit units (bytes for
+// sizes). Docker and gVisor map them to container ulimits; nsjail passes
+// the soft limits as --rlimit_* flags. Other providers ignore them.
+func WithUlimits(limits map[string]executor.Ulimit) Option {
+	return func(c *Config) {
+		c.Ulimits = limits
+	}
+}
+
+// WithReadonlyRootfs mounts the container root filesystem read-only for
+// untrusted code. The working directory and /tmp stay writable, and /root
+// and /var/tmp are tmpfs so compilers and package managers can cache.
+// Executions must use the sandbox working directory. Docker and gVisor
+// support it; other providers ignore it.
+func WithReadonlyRootfs() Option {
+	return func(c *Config) {
+		c.ReadonlyRootfs = true
+	}
+}
+
+// WithDropCapabilities removes Linux capabilities, e.g. "NET_RAW", from
+// the sandbox. Use DropAll for untrusted code. Docker and gVisor map them
+// to the container's cap-drop list; nsjail already runs code without
+// capabilities, and other providers ignore them.
+func WithDropCapabilities(caps []string) Option {
+	return func(c *Config) {
+		c.CapDrop = append(c.CapDrop, caps...)
+	}
+}
+
+// WithAddCapabilities grants Linux capabilities, e.g. "NET_BIND_SERVICE",
+// to the sandbox. They are added after WithDropCapabilities removes its
+// list, so DropAll can be combined with the few a program needs. Docker
+// and gVisor support it; other providers ignore it.
+func WithAddCapabilities(caps []string) Option {
+	return func(c *Config) {
+		c.CapAdd = append(c.CapAdd, caps...)
+	}
+}
+
+// DropAll returns the capability list that removes every capability,
+// recommended for untrusted code:
+//
+//	sindoq.WithDropCapabilities(sindoq.DropAll())
+func DropAll() []string {
+	return []string{"ALL"}
+}
+
+// WithInterpreterReuse runs Python and JavaScript executions in a
+// long-lived interpreter server started by the first execution, instead of a
+// new process per run. Only the Docker provider supports it. Globals are
+// reset between runs, but imported modules and other process state persist.
+func WithInterpreterReuse() Option {
+	return func(c *Config) {
+		c.InterpreterReuse = true
+	}
+}
+
+// WithRespectEntrypoint keeps the image's entrypoint instead of replacing
+// it, for images whose entrypoint sets up the environment code needs, such
+// as activating a virtualenv or exporting variables. The entrypoint runs
+// once when the sandbox starts, and every execution inherits the
+// environment it exported. Only the Docker provider supports it; on others
+// Create fails with ErrCapabilityNotSupported.
+//
+// The entrypoint must end by exec'ing its arguments, as most do, so the
+// container keeps running.
+func WithRespectEntrypoint() Option {
+	return func(c *Config) {
+		c.RespectEntrypoint = true
+	}
+}
+
+// WithInterpreterPath runs language with the interpreter at path instead of
+// its runtime's default, e.g. "/usr/local/bin/python3.11" rather than
+// python3 from PATH in a custom image. Only the first element of the run
+// command is replaced; its flags are kept. Languages that run a compiled
+// binary have no interpreter to replace and fail Create with
+// ErrInvalidConfiguration. Only providers with SupportsInterpreterPath
+// (Docker, gVisor, nsjail, Firecracker) honor it; Execute and ExecuteStream
+// fail with ErrCapabilityNotSupported on others.
+func WithInterpreterPath(language, path string) Option {
+	return func(c *Config) {
+		if info, ok := langdetect.GetRuntimeInfo(language); ok {
+			language = info.Language
+		}
+		if c.InterpreterPaths == nil {
+			c.InterpreterPaths = make(map[string]string)
+		}
+		c.InterpreterPaths[language] = path
+	}
+}
+
+// checkInterpreterPaths validates InterpreterPaths.
+func (c *Config) checkInterpreterPaths() error {
+	for language, path := range c.InterpreterPaths {
+		info, ok := langdetect.GetRuntimeInfo(language)
+		if !ok {
+			return fmt.Errorf("interpreter path for %s: %w", language, ErrLanguageNotSupported)
+		}
+		if path == "" {
+			return fmt.Errorf("interpreter path for %s is empty: %w", language, ErrInvalidConfiguration)
+		}
+		if len(info.RunCommand) == 0 || slices.ContainsFunc(info.CompileCmd, func(arg string) bool {
+			return strings.Contains(arg, info.RunCommand[0])
+		}) {
+			return fmt.Errorf("interpreter path for %s: it runs a compiled binary: %w", language, ErrInvalidConfiguration)
+		}
+	}
+	return nil
+}
+
+// interpreterPath returns the interpreter configured for language, or "".
+func (c *Config) interpreterPath(language string) string {
+	if info, ok := langdetect.GetRuntimeInfo(language); ok {
+		language = info.Language
+	}
+	return c.InterpreterPaths[language]
+}
+
+// WithTracer wraps Create, Execute, ExecuteStream and Stop in spans from
+// tracer, nested under the span in the caller's context. Spans carry the
+// provider and sandbox ID, executions add the language, exit code and
+// duration, and failed operations record their error. Operations built on
+// Execute, such as RunTests or Judge, get one execute span per run.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *Config) {
+		c.Tracer = tracer
+	}
+}
+
 // ResourceConfig defines resource limits.
 type ResourceConfig struct {
 	MemoryMB int
 	CPUs     float64
-	DiskMB   int
+
+	// CPUMode selects a hard CPUs quota, the default, or a relative
+	// CPUShares weight that lets the sandbox use idle cores. Docker, gVisor
+	// and nsjail honor it; other providers always apply their quota.
+	CPUMode   CPUMode
+	CPUShares int
+
+	// DiskMB limits what the sandbox can write to disk. Zero means no
+	// limit; providers that cannot enforce one fail to create the sandbox
+	// with ErrDiskLimitUnsupported.
+	DiskMB int
+
+	// MaxPids limits processes and threads in the sandbox. Zero uses the
+	// provider default (256 for Docker and gVisor); negative removes it.
+	MaxPids int
+
+	// CPUSet pins the sandbox to host cores, such as "0-3,6". It must lie
+	// within the provider's CPUSet when one is configured. Docker and
+	// nsjail support it.
+	CPUSet string
 }
 
 // ToProviderConfig converts to provider.ResourceConfig.
 func (r ResourceConfig) ToProviderConfig() provider.ResourceConfig {
 	return provider.ResourceConfig{
-		MemoryMB: r.MemoryMB,
-		CPUs:     r.CPUs,
-		DiskMB:   r.DiskMB,
+		MemoryMB:  r.MemoryMB,
+		CPUs:      r.CPUs,
+		CPUMode:   r.CPUMode,
+		CPUShares: r.CPUShares,
+		DiskMB:    r.DiskMB,
+		MaxPids:   r.MaxPids,
+		CPUSet:    r.CPUSet,
 	}
 }
 
+// CPUMode selects how the CPU time of a sandbox is limited.
+type CPUMode = provider.CPUMode
+
+const (
+	// CPUModeQuota caps the sandbox at ResourceConfig.CPUs cores.
+	CPUModeQuota = provider.CPUModeQuota
+
+	// CPUModeShares weights the sandbox by ResourceConfig.CPUShares
+	// against other containers, so it may burst onto idle cores.
+	CPUModeShares = provider.CPUModeShares
+)
+
 // Logger interface for debug output.
 type Logger interface {
 	Debug(msg string, keysAndValues ...any)
@@ -257,6 +784,22 @@ type DockerConfig struct {
 	CertPath     string
 	RegistryAuth map[string]string
 	DefaultImage string
+
+	// DisableTimeoutWrapper skips the in-sandbox timeout backstop for
+	// images that do not ship coreutils timeout.
+	DisableTimeoutWrapper bool
+
+	// ValidateRetries is how many times Validate retries a failed daemon
+	// ping; ValidateRetryDelay is the wait between attempts (default 500ms).
+	ValidateRetries    int
+	ValidateRetryDelay time.Duration
+
+	// CaptureImage provides tcpdump for WithNetworkCapture (default
+	// nicolaka/netshoot).
+	CaptureImage string
+
+	// CPUSet pins every container to these host cores, such as "0-3".
+	CPUSet string
 }
 
 // VercelConfig configures Vercel Sandbox provider.
@@ -265,6 +808,30 @@ type VercelConfig struct {
 	TeamID    string
 	ProjectID string
 	Runtime   string // "node22" or "python313"
+
+	// RateLimit is the maximum API requests per second (default 10).
+	// A negative value disables throttling. Sandboxes using the same
+	// credentials share one limiter, so they must agree on RateLimit and
+	// RateBurst; a mismatch fails sandbox creation.
+	RateLimit float64
+
+	// RateBurst is the number of requests allowed in a burst (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a 429 response is retried (default 3).
+	MaxRetries int
+
+	// HTTPClient sends the API requests, for a proxy, custom TLS or tests
+	// (default a client with a 5 minute timeout). Rate limiting and retries
+	// wrap its transport.
+	HTTPClient *http.Client
+}
+
+// WithHTTPClient returns a copy of c that sends its API requests through
+// client.
+func (c VercelConfig) WithHTTPClient(client *http.Client) VercelConfig {
+	c.HTTPClient = client
+	return c
 }
 
 // E2BConfig configures E2B provider.
@@ -272,6 +839,70 @@ type E2BConfig struct {
 	APIKey   string
 	Template string
 	Timeout  time.Duration
+
+	// RateLimit is the maximum API requests per second (default 10).
+	// A negative value disables throttling. Sandboxes using the same
+	// credentials share one limiter, so they must agree on RateLimit and
+	// RateBurst; a mismatch fails sandbox creation.
+	RateLimit float64
+
+	// RateBurst is the number of requests allowed in a burst (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a 429 response is retried (default 3).
+	MaxRetries int
+
+	// HTTPClient sends the API requests, for a proxy, custom TLS or tests
+	// (default a client with a 5 minute timeout). Rate limiting and retries
+	// wrap its transport.
+	HTTPClient *http.Client
+}
+
+// WithHTTPClient returns a copy of c that sends its API requests through
+// client.
+func (c E2BConfig) WithHTTPClient(client *http.Client) E2BConfig {
+	c.HTTPClient = client
+	return c
+}
+
+// LambdaConfig configures AWS Lambda provider. Executions invoke a
+// pre-deployed runner function; see the lambda package for its payload.
+type LambdaConfig struct {
+	// Region defaults to AWS_REGION or AWS_DEFAULT_REGION.
+	Region string
+
+	// FunctionName is the runner's name or ARN (default "sindoq-runner").
+	FunctionName string
+
+	// Qualifier selects a function version or alias.
+	Qualifier string
+
+	// AccessKeyID, SecretAccessKey and SessionToken set static
+	// credentials. When empty, the AWS SDK's default credential chain is
+	// used, with Profile selecting a shared config profile.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Profile         string
+
+	// Languages the runner supports (default Python, JavaScript, Bash).
+	Languages []string
+
+	// MemoryMB is the memory configured on the function (default 10240).
+	MemoryMB int
+
+	// RateLimit is the maximum invocations per second (default 10).
+	// A negative value disables throttling. Sandboxes invoking the same
+	// function share one limiter, so they must agree on RateLimit and
+	// RateBurst; a mismatch fails sandbox creation.
+	RateLimit float64
+
+	// RateBurst is the number of invocations allowed in a burst (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a throttled invocation is retried
+	// (default 3).
+	MaxRetries int
 }
 
 // KubernetesConfig configures Kubernetes provider.
@@ -343,6 +974,14 @@ type GVisorConfig struct {
 
 	// Debug enables gVisor debug logging.
 	Debug bool
+
+	// DisableTimeoutWrapper skips the in-sandbox timeout backstop for
+	// images that do not ship coreutils timeout.
+	DisableTimeoutWrapper bool
+
+	// Fallback uses Docker's default runtime, with a warning, when the
+	// gVisor runtime is not configured, instead of failing.
+	Fallback bool
 }
 
 // NsjailConfig configures nsjail provider.
@@ -367,6 +1006,9 @@ type NsjailConfig struct {
 	// MaxCPUs limits CPU cores.
 	MaxCPUs uint32
 
+	// CPUSet pins every sandbox to these host cores, such as "0-3".
+	CPUSet string
+
 	// EnableNetwork allows network access.
 	EnableNetwork bool
 
@@ -398,23 +1040,109 @@ type ExecuteOption func(*ExecuteConfig)
 
 // ExecuteConfig holds execution configuration.
 type ExecuteConfig struct {
-	Language      string
-	Filename      string
-	Timeout       time.Duration
-	Env           map[string]string
-	WorkDir       string
-	Stdin         string
-	Files         map[string][]byte
-	KeepArtifacts bool
+	Language         string
+	Filename         string
+	Timeout          time.Duration
+	Env              map[string]string
+	WorkDir          string
+	Stdin            string
+	Files            map[string][]byte
+	KeepArtifacts    bool
+	Reproducible     bool
+	TrackFileChanges bool
+	AutoWrapMain     bool
+	Ulimits          map[string]executor.Ulimit
+
+	// MemoryMB lowers the memory limit for the run. It is set by a memory
+	// directive; see WithParseDirectives.
+	MemoryMB int
+
+	// Umask sets the file-creation mask for the run. See WithUmask.
+	Umask *os.FileMode
+
+	// MaxOutputRate caps streamed output in bytes per second. See
+	// WithMaxOutputRate.
+	MaxOutputRate int64
+
+	// StdoutLimit and StderrLimit cap each output stream in bytes. See
+	// WithStdoutLimit and WithStderrLimit.
+	StdoutLimit int64
+	StderrLimit int64
+
+	// SpillThreshold keeps output past this many bytes per stream in a
+	// temporary file instead of memory. See WithSpillToDisk.
+	SpillThreshold int64
+
+	// Timezone sets TZ for the run. See WithTimezone.
+	Timezone string
+
+	// ClockOffset shifts the program's wall clock. See WithClockOffset.
+	ClockOffset time.Duration
+
+	// CommandWrapper runs the program under another command. See
+	// WithCommandWrapper.
+	CommandWrapper []string
+
+	// Args are the program's command-line arguments. See WithArgs.
+	Args []string
+
+	// AllowReadPaths and AllowWritePaths are host paths mounted for this
+	// run only. See WithAllowReadPaths and WithAllowWritePaths.
+	AllowReadPaths  []string
+	AllowWritePaths []string
+
+	// EphemeralWorkdir runs the code in a fresh subdirectory of WorkDir
+	// that is removed afterwards. See WithEphemeralWorkdir.
+	EphemeralWorkdir bool
+
+	// NetworkCapture records the run's network traffic as a pcap. See
+	// WithNetworkCapture.
+	NetworkCapture bool
+
+	// Coverage runs the code under its coverage tool. See WithCoverage.
+	Coverage bool
+
+	// StreamFlushInterval coalesces streamed output produced within the
+	// interval into a single event. Zero emits every read.
+	StreamFlushInterval time.Duration
+
+	// StreamMiddleware filters and transforms streamed events, in order,
+	// before the handler sees them. See WithStreamMiddleware.
+	StreamMiddleware []StreamMiddleware
+
+	// RawStream delivers the provider's unprocessed output as StreamRaw
+	// events. See WithRawStream.
+	RawStream bool
+
+	// SkipLanguageCheck runs the code even if the provider does not list
+	// its language. See WithSkipLanguageCheck.
+	SkipLanguageCheck bool
+
+	// OutputLoopThreshold aborts the run once it prints the same line this
+	// many times in a row. Zero disables the check. See
+	// WithDetectOutputLoop.
+	OutputLoopThreshold int
+
+	// StdinScript answers the program's prompts on stdin. See
+	// WithStdinScript.
+	StdinScript []StdinRule
+
+	// StdinPromptTimeout is how long StdinScript waits for each prompt.
+	StdinPromptTimeout time.Duration
+
+	// ManifestOutputLimit is how much output ExecuteWithManifest keeps
+	// inline. See WithManifestOutputLimit.
+	ManifestOutputLimit int
 }
 
 // DefaultExecuteConfig returns default execution config.
 func DefaultExecuteConfig() *ExecuteConfig {
 	return &ExecuteConfig{
-		Timeout: 30 * time.Second,
-		WorkDir: "/workspace",
-		Env:     make(map[string]string),
-		Files:   make(map[string][]byte),
+		Timeout:            30 * time.Second,
+		WorkDir:            "/workspace",
+		Env:                make(map[string]string),
+		Files:              make(map[string][]byte),
+		StdinPromptTimeout: DefaultStdinPromptTimeout,
 	}
 }
 
@@ -453,7 +1181,9 @@ func WithStdin(input string) ExecuteOption {
 	}
 }
 
-// WithFiles adds files to the execution environment.
+// WithFiles adds files to the execution environment. Relative names are
+// written under the run's working directory; one that leaves it, such as
+// "../x", fails with ErrInvalidConfiguration.
 func WithFiles(files map[string][]byte) ExecuteOption {
 	return func(c *ExecuteConfig) {
 		c.Files = files
@@ -467,9 +1197,437 @@ func WithWorkDir(dir string) ExecuteOption {
 	}
 }
 
+// WithPersistentWorkdir runs the code directly in the working directory, so
+// files it writes are visible to later executions in the same sandbox. This
+// is the default.
+func WithPersistentWorkdir() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.EphemeralWorkdir = false
+	}
+}
+
+// WithEphemeralWorkdir runs the code in a fresh, empty subdirectory of the
+// working directory that is removed when the execution ends, so it neither
+// sees files left by earlier executions there nor leaves any behind. Files
+// written elsewhere, such as /tmp, still persist. Docker, gVisor, nsjail and
+// Wasmer support it; other providers ignore it and keep the working
+// directory persistent.
+func WithEphemeralWorkdir() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.EphemeralWorkdir = true
+	}
+}
+
 // WithKeepArtifacts preserves generated files after execution.
 func WithKeepArtifacts() ExecuteOption {
 	return func(c *ExecuteConfig) {
 		c.KeepArtifacts = true
 	}
 }
+
+// sandboxEnv returns the environment the sandbox is created with: secrets
+// layered over ProviderEnv.
+func (c *Config) sandboxEnv() map[string]string {
+	env := make(map[string]string, len(caTrustEnv)+len(c.ProviderEnv)+len(c.Secrets))
+	for k, v := range c.ProviderEnv {
+		env[k] = v
+	}
+	for k, v := range c.Secrets {
+		env[k] = v
+	}
+	return env
+}
+
+// execEnv returns the environment applied to every execution: sandboxEnv
+// layered over the CA trust variables, which point at the bundle in
+// workDir. The provider may only settle the working directory while
+// creating the sandbox, so the variables are left out of sandboxEnv.
+func (c *Config) execEnv(workDir string) map[string]string {
+	env := c.sandboxEnv()
+	if c.hasCACerts() {
+		for _, k := range caTrustEnv {
+			if _, ok := env[k]; !ok {
+				env[k] = caBundlePath(workDir)
+			}
+		}
+	}
+	return env
+}
+
+// secretValues returns the values to redact: Secrets plus the contents of
+// SecretFiles.
+func (c *Config) secretValues() map[string]string {
+	if len(c.SecretFiles) == 0 {
+		return c.Secrets
+	}
+	values := make(map[string]string, len(c.Secrets)+len(c.SecretFiles))
+	for k, v := range c.Secrets {
+		values[k] = v
+	}
+	for name, content := range c.SecretFiles {
+		values[executor.SecretsDir+"/"+name] = strings.TrimSpace(string(content))
+	}
+	return values
+}
+
+// toExecutionOptions converts the config into provider execution options.
+// baseEnv is the sandbox-wide environment; per-call Env takes precedence.
+func (c *ExecuteConfig) toExecutionOptions(language string, baseEnv map[string]string) *executor.ExecutionOptions {
+	env := c.Env
+	if c.Reproducible || c.Timezone != "" || len(baseEnv) > 0 {
+		env = make(map[string]string, len(baseEnv)+len(reproducibleEnv)+len(c.Env)+1)
+		for k, v := range baseEnv {
+			env[k] = v
+		}
+		if c.Reproducible {
+			for k, v := range reproducibleEnv {
+				env[k] = v
+			}
+		}
+		if c.Timezone != "" {
+			env["TZ"] = c.Timezone
+		}
+		for k, v := range c.Env {
+			env[k] = v
+		}
+	}
+
+	return &executor.ExecutionOptions{
+		Language:         language,
+		Filename:         c.Filename,
+		Timeout:          c.Timeout,
+		Env:              env,
+		WorkDir:          c.WorkDir,
+		EphemeralWorkDir: c.EphemeralWorkdir,
+		Stdin:            c.Stdin,
+		Files:            c.Files,
+		KeepArtifacts:    c.KeepArtifacts,
+		TrackFileChanges: c.TrackFileChanges,
+		NetworkCapture:   c.NetworkCapture,
+		Coverage:         c.Coverage,
+		Ulimits:          c.Ulimits,
+		MemoryMB:         c.MemoryMB,
+		Umask:            c.Umask,
+		MaxOutputRate:    c.MaxOutputRate,
+		StdoutLimit:      c.StdoutLimit,
+		StderrLimit:      c.StderrLimit,
+		ClockOffset:      c.ClockOffset,
+		CommandWrapper:   c.CommandWrapper,
+		Args:             c.Args,
+		AllowReadPaths:   c.AllowReadPaths,
+		AllowWritePaths:  c.AllowWritePaths,
+	}
+}
+
+// WithTrackFileChanges reports the files created, modified or deleted in the
+// working directory in ExecutionResult.FileChanges. Docker and gVisor use the
+// container changes API, which compares against the image, so a file that an
+// earlier execution created and this one modifies is not reported; nsjail and
+// Wasmer hash the workspace before and after the run. At most
+// executor.DefaultMaxTrackedFiles entries are reported.
+func WithTrackFileChanges() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.TrackFileChanges = true
+	}
+}
+
+// WithNetworkCapture records the sandbox's network traffic during the run
+// into ExecutionResult.NetworkCapture as a pcap, for analyzing what
+// untrusted code tried to contact. Docker runs tcpdump in a sidecar that
+// shares the sandbox's network namespace; the sidecar start adds roughly a
+// second per execution. If the run fails, for example by timing out, the
+// capture is still collected and returned in a *NetworkCaptureError.
+//
+// Only providers with SupportsNetworkCapture honor it; Execute fails with
+// ErrCapabilityNotSupported on others, and ExecuteStream rejects it.
+func WithNetworkCapture() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.NetworkCapture = true
+	}
+}
+
+// WithCoverage runs the code under its language's coverage tool and
+// reports line coverage in ExecutionResult.Coverage, for grading how much
+// of a submission its tests exercise. Python uses coverage.py, Go builds
+// with go run -cover (Go 1.20 or later) and JavaScript runs under c8; the
+// tool must be installed in the image, or Execute fails saying so. Other
+// languages fail. A program that crashes before writing coverage data gets
+// its result without Coverage.
+//
+// Only providers with SupportsCoverage (Docker, gVisor) honor it; Execute
+// fails with ErrCapabilityNotSupported on others, and ExecuteStream
+// rejects it.
+func WithCoverage() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.Coverage = true
+	}
+}
+
+// WithAutoWrapMain lets Go snippets run without boilerplate. Code without a
+// package clause is wrapped in package main and func main, keeping
+// top-level func and type declarations, and imports are added for the
+// standard packages it references. Full programs are left unchanged.
+func WithAutoWrapMain() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.AutoWrapMain = true
+	}
+}
+
+// WithSkipLanguageCheck runs the code even when the language is missing
+// from the provider's SupportedLanguages, for providers whose list is
+// incomplete, e.g. a custom image that adds a runtime.
+func WithSkipLanguageCheck() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.SkipLanguageCheck = true
+	}
+}
+
+// WithExecutionUlimits overrides WithUlimits for a single execution. Only
+// nsjail applies per-execution limits; container providers fix them when the
+// sandbox is created.
+func WithExecutionUlimits(limits map[string]executor.Ulimit) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.Ulimits = limits
+	}
+}
+
+// WithUmask sets the umask the code runs with, so files it creates get
+// predictable permissions: 0o022 gives 0644 files, 0o077 gives 0600. Only the
+// permission bits of mask are used. Docker, gVisor and nsjail apply it by
+// running the command under sh with the umask set; other providers ignore it.
+func WithUmask(mask os.FileMode) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		mask &= os.ModePerm
+		c.Umask = &mask
+	}
+}
+
+// WithTimezone runs the code with TZ set to tz, an IANA name such as
+// "Europe/Istanbul", so local times are computed in that zone. It overrides
+// the UTC set by WithReproducible; a TZ passed via WithEnv still wins. The
+// image needs tzdata for names other than UTC.
+func WithTimezone(tz string) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.Timezone = tz
+	}
+}
+
+// WithClockOffset shifts the wall clock the program sees by d, e.g. a year
+// ahead to test expiry logic. Docker applies it with libfaketime, which must
+// be installed in the image (the faketime package on Debian and Ubuntu);
+// only the run is shifted, not compilation, and monotonic clocks are not
+// faked. When the image lacks libfaketime, or on other providers, the
+// option is ignored and Execute logs a warning.
+func WithClockOffset(d time.Duration) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.ClockOffset = d
+	}
+}
+
+// WithCommandWrapper runs the program under wrapper, e.g.
+// []string{"valgrind", "--leak-check=full"} or []string{"/usr/bin/time", "-v"},
+// for profiling and tracing: the provider runs wrapper followed by the
+// language's run command and code file. Compilation is not wrapped. The
+// wrapper's binary must exist in the sandbox; it is checked before every
+// run. Only providers with SupportsCommandWrapper (Docker, gVisor, nsjail)
+// honor it; Execute and ExecuteStream fail with ErrCapabilityNotSupported
+// on others.
+func WithCommandWrapper(wrapper []string) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.CommandWrapper = wrapper
+	}
+}
+
+// WithArgs passes args to the program as its command-line arguments, after
+// the code file for interpreted languages (python3 main.py arg1 arg2) and
+// after the binary for compiled ones, so code reads them from sys.argv,
+// process.argv or os.Args. Each argument is one argv entry: arguments with
+// spaces or quotes are never re-split. Only providers with
+// SupportsProgramArgs (Docker, gVisor, nsjail, Firecracker, Wasmer) honor
+// it; Execute and ExecuteStream fail with ErrCapabilityNotSupported on
+// others.
+func WithArgs(args []string) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.Args = args
+	}
+}
+
+// WithAllowReadPaths mounts host paths read-only at the same paths for this
+// run only, so one execution can read /data/input while the next cannot.
+// Paths must be absolute and exist on the host; the root, ".." elements and
+// colons are rejected with ErrInvalidConfiguration. Only providers with
+// SupportsPathGrants (nsjail) honor it; Execute and ExecuteStream fail with
+// ErrCapabilityNotSupported on others.
+func WithAllowReadPaths(paths []string) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.AllowReadPaths = append(c.AllowReadPaths, paths...)
+	}
+}
+
+// WithAllowWritePaths mounts host paths read-write for this run only, with
+// the same rules as WithAllowReadPaths.
+func WithAllowWritePaths(paths []string) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.AllowWritePaths = append(c.AllowWritePaths, paths...)
+	}
+}
+
+// WithDetectOutputLoop aborts runs stuck printing the same line, such as
+// `while True: print("x")`, once it repeats DefaultOutputLoopThreshold times
+// in a row, instead of letting them run until the timeout. Execute returns
+// the output so far with OutputLoopDetected set; ExecuteStream returns
+// ErrOutputLoopDetected. Stdout and stderr are checked separately, and any
+// different line resets the count.
+//
+// With loop detection Execute streams the run, so the provider must
+// support streaming, and Execute fails with ErrInvalidConfiguration when it
+// is combined with WithTrackFileChanges, WithCoverage or WithNetworkCapture.
+func WithDetectOutputLoop() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.OutputLoopThreshold = DefaultOutputLoopThreshold
+	}
+}
+
+// WithOutputLoopThreshold enables WithDetectOutputLoop with a threshold of
+// n consecutive identical lines.
+func WithOutputLoopThreshold(n int) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.OutputLoopThreshold = n
+	}
+}
+
+// WithStdinScript drives a prompt-driven program by answering its prompts
+// on stdin: when the stdout printed since the previous answer matches the
+// next rule's MatchRegex, its Response is written to stdin. Rules are used
+// once each, in order, and stdin is closed after the last. If a prompt does
+// not appear within the prompt timeout (DefaultStdinPromptTimeout, see
+// WithStdinPromptTimeout), the run is aborted; if the program exits first,
+// Execute and ExecuteStream fail with ErrStdinPromptNotMatched too.
+//
+// WithStdinScript cannot be combined with WithStdin. Only providers with
+// SupportsInteractiveStdin (Docker, gVisor, nsjail) honor it. Execute
+// streams the run, so it fails with ErrInvalidConfiguration when the script
+// is combined with WithTrackFileChanges, WithCoverage or WithNetworkCapture.
+func WithStdinScript(rules []StdinRule) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StdinScript = rules
+	}
+}
+
+// WithStdinPromptTimeout sets how long WithStdinScript waits for each
+// prompt.
+func WithStdinPromptTimeout(d time.Duration) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StdinPromptTimeout = d
+	}
+}
+
+// WithMaxOutputRate throttles ExecuteStream to bytesPerSec bytes of stdout
+// and stderr combined. The provider reads the program's output no faster
+// than that, so a program that floods output is slowed down by its pipe
+// instead of swamping the handler. Zero or a negative value means
+// unlimited. Docker, gVisor, nsjail, Wasmer and Firecracker apply it; other
+// providers and Execute ignore it.
+func WithMaxOutputRate(bytesPerSec int64) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.MaxOutputRate = bytesPerSec
+	}
+}
+
+// WithStdoutLimit keeps at most n bytes of stdout, cut at a character
+// boundary, and sets StdoutTruncated on the result or the StreamComplete
+// event when output was dropped. The program keeps running; what it prints
+// past the limit is read and discarded. Docker, gVisor, nsjail, Wasmer and
+// Firecracker stop buffering at the limit; with other providers the output
+// is cut once it arrives. Zero or a negative value means unlimited.
+func WithStdoutLimit(n int64) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StdoutLimit = n
+	}
+}
+
+// WithStderrLimit keeps at most n bytes of stderr, independently of
+// stdout, as WithStdoutLimit does. It suits programs whose stdout is large
+// by design but whose stderr should only hold a few errors. ExecuteStream
+// counts compiler output against this limit too.
+func WithStderrLimit(n int64) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StderrLimit = n
+	}
+}
+
+// WithSpillToDisk keeps the whole output of Execute, however large, while
+// holding at most threshold bytes of each stream in memory: the rest spills
+// to a temporary file on the host. Stdout and Stderr of the result hold the
+// in-memory part; StdoutReader and StderrReader read everything, and the
+// caller must Close the result to remove the files. It runs through the
+// streaming path, so the provider must support streaming, and it cannot be
+// combined with WithTrackFileChanges, WithCoverage or WithNetworkCapture.
+// Output limits still apply first; this is for when the full output is
+// needed.
+func WithSpillToDisk(threshold int64) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.SpillThreshold = threshold
+	}
+}
+
+// WithStreamFlushInterval makes ExecuteStream merge stdout or stderr output
+// produced within d into one StreamEvent instead of one per provider read.
+// Output is delayed by at most d, and anything held is flushed before the
+// complete or error event. It has no effect on Execute.
+func WithStreamFlushInterval(d time.Duration) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StreamFlushInterval = d
+	}
+}
+
+// WithRawStream makes ExecuteStream also deliver the provider's output
+// as it came off the wire, in StreamRaw events, for callers that forward
+// it verbatim, such as a proxy to a web terminal, or demultiplex it
+// themselves. On Docker each event holds whole multiplexed frames, headers
+// included. The demultiplexed StreamStdout and StreamStderr events are
+// still delivered. Raw output cannot be redacted, so it fails with
+// ErrInvalidConfiguration in a sandbox with WithRedactPatterns or WithSecrets,
+// and it is not cut by output limits. Only Docker supports it; it has no
+// effect on Execute.
+func WithRawStream() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.RawStream = true
+	}
+}
+
+// WithStreamMiddleware passes every event of ExecuteStream through mw
+// before the handler sees it: mw returns the event to forward, which may be
+// modified or replaced, or false to drop it. Repeating the option chains
+// middleware in order. It sees events after redaction, output limits and
+// WithStreamFlushInterval, so it can filter, enrich or further redact what
+// the handler gets. ExecuteTo and ExecuteBatchEvents, which stream, apply
+// it too; Execute ignores it.
+func WithStreamMiddleware(mw StreamMiddleware) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.StreamMiddleware = append(c.StreamMiddleware, mw)
+	}
+}
+
+// WithReproducible pins the environment so repeated runs produce the same output.
+// It sets PYTHONHASHSEED, SOURCE_DATE_EPOCH, TZ and the C locale; variables passed
+// via WithEnv take precedence.
+//
+// Only environment-driven sources of variance are covered. Wall-clock time and
+// /dev/urandom cannot be pinned by any provider. Docker, gVisor, nsjail, Wasmer,
+// Firecracker and Vercel apply the variables to the executed process; E2B does
+// not forward per-execution environment variables, so the option has no effect there.
+func WithReproducible() ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.Reproducible = true
+	}
+}
+
+// reproducibleEnv holds the variables applied by WithReproducible.
+var reproducibleEnv = map[string]string{
+	"PYTHONHASHSEED":    "0",
+	"SOURCE_DATE_EPOCH": "0",
+	"TZ":                "UTC",
+	"LC_ALL":            "C.UTF-8",
+	"LANG":              "C.UTF-8",
+}
@@ -1,6 +1,10 @@
 package sindoq
 
 import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -26,8 +30,8 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Resources.CPUs != 1 {
 		t.Errorf("Resources.CPUs = %f, want 1", cfg.Resources.CPUs)
 	}
-	if cfg.Resources.DiskMB != 1024 {
-		t.Errorf("Resources.DiskMB = %d, want 1024", cfg.Resources.DiskMB)
+	if cfg.Resources.DiskMB != 0 {
+		t.Errorf("Resources.DiskMB = %d, want 0 (no limit)", cfg.Resources.DiskMB)
 	}
 }
 
@@ -58,6 +62,19 @@ func TestWithImage(t *testing.T) {
 	}
 }
 
+func TestWithPolyglotImage(t *testing.T) {
+	cfg := DefaultConfig()
+	WithRuntime("Python")(cfg)
+	WithPolyglotImage("nikolaik/python-nodejs:python3.12-nodejs22")(cfg)
+
+	if cfg.Image != "nikolaik/python-nodejs:python3.12-nodejs22" {
+		t.Errorf("Image = %q, want the polyglot image", cfg.Image)
+	}
+	if !cfg.Polyglot {
+		t.Error("Polyglot should be true")
+	}
+}
+
 func TestWithTimeout(t *testing.T) {
 	cfg := DefaultConfig()
 	WithTimeout(5 * time.Minute)(cfg)
@@ -67,6 +84,29 @@ func TestWithTimeout(t *testing.T) {
 	}
 }
 
+func TestWithMaxLifetime(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.MaxLifetime != 0 {
+		t.Errorf("default MaxLifetime = %v, want none", cfg.MaxLifetime)
+	}
+	WithMaxLifetime(time.Hour)(cfg)
+	if cfg.MaxLifetime != time.Hour {
+		t.Errorf("MaxLifetime = %v, want %v", cfg.MaxLifetime, time.Hour)
+	}
+}
+
+func TestWithParseDirectives(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.ParseDirectives {
+		t.Error("directives should not be parsed by default")
+	}
+	WithParseDirectives()(cfg)
+	WithDirectiveMaxTimeout(2 * time.Minute)(cfg)
+	if !cfg.ParseDirectives || cfg.DirectiveMaxTimeout != 2*time.Minute {
+		t.Errorf("ParseDirectives = %v, DirectiveMaxTimeout = %v", cfg.ParseDirectives, cfg.DirectiveMaxTimeout)
+	}
+}
+
 func TestWithResources(t *testing.T) {
 	cfg := DefaultConfig()
 	res := ResourceConfig{
@@ -87,6 +127,39 @@ func TestWithResources(t *testing.T) {
 	}
 }
 
+func TestWithAutoResourceLimits(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.AutoResourceLimits {
+		t.Error("AutoResourceLimits should be off by default")
+	}
+	WithAutoResourceLimits()(cfg)
+	if !cfg.AutoResourceLimits {
+		t.Error("AutoResourceLimits should be set")
+	}
+}
+
+func TestWithSandboxWorkDir(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.WorkDir != "" {
+		t.Errorf("WorkDir = %q, want the provider default", cfg.WorkDir)
+	}
+	WithSandboxWorkDir("/app")(cfg)
+	if cfg.WorkDir != "/app" {
+		t.Errorf("WorkDir = %q, want /app", cfg.WorkDir)
+	}
+}
+
+func TestWithSkipMemoryMinimum(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.SkipMemoryMinimum {
+		t.Error("SkipMemoryMinimum should be off by default")
+	}
+	WithSkipMemoryMinimum()(cfg)
+	if !cfg.SkipMemoryMinimum {
+		t.Error("SkipMemoryMinimum should be set")
+	}
+}
+
 func TestWithAutoDetect(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.AutoDetectLanguage = false
@@ -97,6 +170,43 @@ func TestWithAutoDetect(t *testing.T) {
 	}
 }
 
+func TestWithFallbackAndStrictLanguage(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.DefaultLanguage != "" || cfg.StrictLanguage {
+		t.Errorf("default config should neither fall back nor be strict, got %q, %v", cfg.DefaultLanguage, cfg.StrictLanguage)
+	}
+	WithFallbackLanguage("Python")(cfg)
+	WithStrictLanguage()(cfg)
+
+	if cfg.DefaultLanguage != "Python" {
+		t.Errorf("DefaultLanguage = %q, want Python", cfg.DefaultLanguage)
+	}
+	if !cfg.StrictLanguage {
+		t.Error("StrictLanguage should be true after WithStrictLanguage")
+	}
+}
+
+func TestWithAuditCode(t *testing.T) {
+	cfg := DefaultConfig()
+	WithAuditCode()(cfg)
+
+	if !cfg.AuditCode {
+		t.Error("AuditCode should be true after WithAuditCode")
+	}
+}
+
+func TestWithResultRetention(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.ResultRetention != DefaultResultRetention || cfg.ResultRetentionAge != DefaultResultRetentionAge {
+		t.Errorf("default retention = %d, %v", cfg.ResultRetention, cfg.ResultRetentionAge)
+	}
+
+	WithResultRetention(5, time.Minute)(cfg)
+	if cfg.ResultRetention != 5 || cfg.ResultRetentionAge != time.Minute {
+		t.Errorf("retention = %d, %v, want 5, 1m", cfg.ResultRetention, cfg.ResultRetentionAge)
+	}
+}
+
 func TestWithInternetAccess(t *testing.T) {
 	cfg := DefaultConfig()
 	WithInternetAccess()(cfg)
@@ -106,6 +216,102 @@ func TestWithInternetAccess(t *testing.T) {
 	}
 }
 
+func TestWithInterpreterReuse(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.InterpreterReuse {
+		t.Error("InterpreterReuse should be false by default")
+	}
+	WithInterpreterReuse()(cfg)
+
+	if !cfg.InterpreterReuse {
+		t.Error("InterpreterReuse should be true after WithInterpreterReuse")
+	}
+}
+
+func TestWithInterpreterPath(t *testing.T) {
+	cfg := DefaultConfig()
+	WithInterpreterPath("python", "/usr/local/bin/python3.11")(cfg)
+	WithInterpreterPath("node", "/opt/node/bin/node")(cfg)
+
+	if got := cfg.InterpreterPaths["Python"]; got != "/usr/local/bin/python3.11" {
+		t.Errorf("InterpreterPaths[Python] = %q, want the path under the canonical name", got)
+	}
+	if got := cfg.interpreterPath("JavaScript"); got != "/opt/node/bin/node" {
+		t.Errorf("interpreterPath(JavaScript) = %q", got)
+	}
+	if err := cfg.checkInterpreterPaths(); err != nil {
+		t.Errorf("checkInterpreterPaths() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		language, path string
+		want           error
+	}{
+		{"Rust", "/usr/local/bin/main", ErrInvalidConfiguration},
+		{"Python", "", ErrInvalidConfiguration},
+		{"COBOL", "/usr/bin/cobc", ErrLanguageNotSupported},
+	} {
+		cfg := DefaultConfig()
+		WithInterpreterPath(tt.language, tt.path)(cfg)
+		if err := cfg.checkInterpreterPaths(); !errors.Is(err, tt.want) {
+			t.Errorf("checkInterpreterPaths() for %s %q error = %v, want %v", tt.language, tt.path, err, tt.want)
+		}
+	}
+}
+
+func TestWithReadonlyRootfs(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.ReadonlyRootfs {
+		t.Error("ReadonlyRootfs should be false by default")
+	}
+	WithReadonlyRootfs()(cfg)
+
+	if !cfg.ReadonlyRootfs {
+		t.Error("ReadonlyRootfs should be true after WithReadonlyRootfs")
+	}
+}
+
+func TestWithPorts(t *testing.T) {
+	cfg := DefaultConfig()
+	WithPorts(8080)(cfg)
+	WithPorts(3000, 5432)(cfg)
+
+	want := []int{8080, 3000, 5432}
+	if !reflect.DeepEqual(cfg.Ports, want) {
+		t.Errorf("Ports = %v, want %v", cfg.Ports, want)
+	}
+}
+
+func TestWithCapabilities(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.CapDrop != nil || cfg.CapAdd != nil {
+		t.Errorf("capabilities should be unchanged by default, got drop %v add %v", cfg.CapDrop, cfg.CapAdd)
+	}
+	WithDropCapabilities(DropAll())(cfg)
+	WithAddCapabilities([]string{"NET_BIND_SERVICE"})(cfg)
+
+	if !reflect.DeepEqual(cfg.CapDrop, []string{"ALL"}) {
+		t.Errorf("CapDrop = %v, want [ALL]", cfg.CapDrop)
+	}
+	if !reflect.DeepEqual(cfg.CapAdd, []string{"NET_BIND_SERVICE"}) {
+		t.Errorf("CapAdd = %v, want [NET_BIND_SERVICE]", cfg.CapAdd)
+	}
+}
+
+func TestWithProviderEnvAndSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	WithProviderEnv(map[string]string{"LOG_LEVEL": "info", "TOKEN": "none"})(cfg)
+	WithSecrets(map[string]string{"TOKEN": "s3cr3t"})(cfg)
+
+	env := cfg.sandboxEnv()
+	if env["LOG_LEVEL"] != "info" {
+		t.Errorf("Env[LOG_LEVEL] = %q, want %q", env["LOG_LEVEL"], "info")
+	}
+	if env["TOKEN"] != "s3cr3t" {
+		t.Errorf("Env[TOKEN] = %q, want secrets to override provider env", env["TOKEN"])
+	}
+}
+
 func TestWithDockerConfig(t *testing.T) {
 	cfg := DefaultConfig()
 	dockerCfg := DockerConfig{
@@ -168,6 +374,25 @@ func TestWithE2BConfig(t *testing.T) {
 	}
 }
 
+func TestWithLambdaConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	WithLambdaConfig(LambdaConfig{
+		Region:       "eu-west-1",
+		FunctionName: "runner",
+	})(cfg)
+
+	if cfg.Provider != "lambda" {
+		t.Errorf("Provider = %q, want %q", cfg.Provider, "lambda")
+	}
+	lc, ok := cfg.ProviderConfig.(LambdaConfig)
+	if !ok {
+		t.Fatal("ProviderConfig should be LambdaConfig")
+	}
+	if lc.Region != "eu-west-1" || lc.FunctionName != "runner" {
+		t.Errorf("LambdaConfig = %+v", lc)
+	}
+}
+
 func TestWithKubernetesConfig(t *testing.T) {
 	cfg := DefaultConfig()
 	k8sCfg := KubernetesConfig{
@@ -206,11 +431,32 @@ func TestWithFirecrackerConfig(t *testing.T) {
 	}
 }
 
+func TestWithGVisorFallback(t *testing.T) {
+	cfg := DefaultConfig()
+	WithGVisorConfig(GVisorConfig{Platform: "kvm"})(cfg)
+	WithGVisorFallback()(cfg)
+
+	if cfg.Provider != "gvisor" {
+		t.Errorf("Provider = %q, want %q", cfg.Provider, "gvisor")
+	}
+	gc, ok := cfg.ProviderConfig.(GVisorConfig)
+	if !ok {
+		t.Fatal("ProviderConfig should be GVisorConfig")
+	}
+	if !gc.Fallback || gc.Platform != "kvm" {
+		t.Errorf("GVisorConfig = %+v, want Fallback with the platform kept", gc)
+	}
+}
+
 func TestResourceConfigToProviderConfig(t *testing.T) {
 	rc := ResourceConfig{
-		MemoryMB: 512,
-		CPUs:     2,
-		DiskMB:   1024,
+		MemoryMB:  512,
+		CPUs:      2,
+		CPUMode:   CPUModeShares,
+		CPUShares: 256,
+		DiskMB:    1024,
+		MaxPids:   64,
+		CPUSet:    "0-3",
 	}
 	pc := rc.ToProviderConfig()
 
@@ -220,9 +466,28 @@ func TestResourceConfigToProviderConfig(t *testing.T) {
 	if pc.CPUs != 2 {
 		t.Errorf("CPUs = %f, want 2", pc.CPUs)
 	}
+	if pc.CPUMode != CPUModeShares || pc.CPUShares != 256 {
+		t.Errorf("CPUMode = %d, CPUShares = %d, want shares of 256", pc.CPUMode, pc.CPUShares)
+	}
 	if pc.DiskMB != 1024 {
 		t.Errorf("DiskMB = %d, want 1024", pc.DiskMB)
 	}
+	if pc.MaxPids != 64 {
+		t.Errorf("MaxPids = %d, want 64", pc.MaxPids)
+	}
+	if pc.CPUSet != "0-3" {
+		t.Errorf("CPUSet = %q, want 0-3", pc.CPUSet)
+	}
+}
+
+func TestCPUSetUnsupported(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	_, err := Create(context.Background(), WithProvider("mock"), WithResources(ResourceConfig{CPUSet: "0"}))
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
 }
 
 func TestDefaultExecuteConfig(t *testing.T) {
@@ -301,6 +566,21 @@ func TestExecuteOptions(t *testing.T) {
 		}
 	})
 
+	t.Run("WithEphemeralWorkdir", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		if cfg.toExecutionOptions("Python", nil).EphemeralWorkDir {
+			t.Error("working directory should be persistent by default")
+		}
+		WithEphemeralWorkdir()(cfg)
+		if !cfg.toExecutionOptions("Python", nil).EphemeralWorkDir {
+			t.Error("EphemeralWorkDir should be passed to execution options")
+		}
+		WithPersistentWorkdir()(cfg)
+		if cfg.EphemeralWorkdir {
+			t.Error("WithPersistentWorkdir should override WithEphemeralWorkdir")
+		}
+	})
+
 	t.Run("WithKeepArtifacts", func(t *testing.T) {
 		cfg := DefaultExecuteConfig()
 		WithKeepArtifacts()(cfg)
@@ -308,6 +588,101 @@ func TestExecuteOptions(t *testing.T) {
 			t.Error("KeepArtifacts should be true")
 		}
 	})
+
+	t.Run("WithSkipLanguageCheck", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		WithSkipLanguageCheck()(cfg)
+		if !cfg.SkipLanguageCheck {
+			t.Error("SkipLanguageCheck should be true")
+		}
+	})
+
+	t.Run("WithDetectOutputLoop", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		if cfg.OutputLoopThreshold != 0 {
+			t.Errorf("OutputLoopThreshold = %d, want detection off by default", cfg.OutputLoopThreshold)
+		}
+		WithDetectOutputLoop()(cfg)
+		if cfg.OutputLoopThreshold != DefaultOutputLoopThreshold {
+			t.Errorf("OutputLoopThreshold = %d, want %d", cfg.OutputLoopThreshold, DefaultOutputLoopThreshold)
+		}
+		WithOutputLoopThreshold(500)(cfg)
+		if cfg.OutputLoopThreshold != 500 {
+			t.Errorf("OutputLoopThreshold = %d, want 500", cfg.OutputLoopThreshold)
+		}
+	})
+
+	t.Run("WithStdinScript", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		if cfg.StdinPromptTimeout != DefaultStdinPromptTimeout {
+			t.Errorf("StdinPromptTimeout = %v, want %v", cfg.StdinPromptTimeout, DefaultStdinPromptTimeout)
+		}
+		rules := []StdinRule{{MatchRegex: `Name:`, Response: "Alice\n"}}
+		WithStdinScript(rules)(cfg)
+		WithStdinPromptTimeout(time.Minute)(cfg)
+		if len(cfg.StdinScript) != 1 || cfg.StdinScript[0] != rules[0] || cfg.StdinPromptTimeout != time.Minute {
+			t.Errorf("StdinScript = %v, StdinPromptTimeout = %v", cfg.StdinScript, cfg.StdinPromptTimeout)
+		}
+	})
+
+	t.Run("WithTrackFileChanges", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		WithTrackFileChanges()(cfg)
+		if !cfg.TrackFileChanges {
+			t.Error("TrackFileChanges should be true")
+		}
+		if !cfg.toExecutionOptions("Python", nil).TrackFileChanges {
+			t.Error("TrackFileChanges should be passed to execution options")
+		}
+	})
+
+	t.Run("WithReproducible", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		WithReproducible()(cfg)
+		if !cfg.Reproducible {
+			t.Error("Reproducible should be true")
+		}
+		opts := cfg.toExecutionOptions("Python", nil)
+		if opts.Env["SOURCE_DATE_EPOCH"] != "0" {
+			t.Errorf("Env[SOURCE_DATE_EPOCH] = %q, want %q", opts.Env["SOURCE_DATE_EPOCH"], "0")
+		}
+	})
+
+	t.Run("WithTimezone", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		WithReproducible()(cfg)
+		WithTimezone("Asia/Tokyo")(cfg)
+		if tz := cfg.toExecutionOptions("Python", nil).Env["TZ"]; tz != "Asia/Tokyo" {
+			t.Errorf("Env[TZ] = %q, want %q", tz, "Asia/Tokyo")
+		}
+		WithEnv(map[string]string{"TZ": "UTC"})(cfg)
+		if tz := cfg.toExecutionOptions("Python", nil).Env["TZ"]; tz != "UTC" {
+			t.Errorf("Env[TZ] with WithEnv = %q, want %q", tz, "UTC")
+		}
+	})
+
+	t.Run("WithMaxOutputRate", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		WithMaxOutputRate(64 * 1024)(cfg)
+		if got := cfg.toExecutionOptions("Python", nil).MaxOutputRate; got != 64*1024 {
+			t.Errorf("MaxOutputRate = %d, want %d", got, 64*1024)
+		}
+	})
+
+	t.Run("WithUmask", func(t *testing.T) {
+		cfg := DefaultExecuteConfig()
+		if cfg.toExecutionOptions("Python", nil).Umask != nil {
+			t.Error("Umask should be unset by default")
+		}
+		WithUmask(os.ModeDir | 0o077)(cfg)
+		umask := cfg.toExecutionOptions("Python", nil).Umask
+		if umask == nil {
+			t.Fatal("Umask should be passed to execution options")
+		}
+		if *umask != 0o077 {
+			t.Errorf("Umask = %o, want 77", *umask)
+		}
+	})
 }
 
 func TestNopLogger(t *testing.T) {
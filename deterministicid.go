@@ -0,0 +1,49 @@
+package sindoq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// deterministicIDLabel is the label carrying the full configuration hash of
+// a sandbox created with WithDeterministicID.
+const deterministicIDLabel = "sindoq.config-hash"
+
+// WithDeterministicID gives the sandbox an ID derived from its
+// configuration, so identical configurations get identical IDs and Create
+// reattaches to a running sandbox with that ID instead of creating another,
+// as a content-addressed sandbox cache. The ID hashes everything the
+// provider is asked to create: provider, image, runtime, resources,
+// environment, network and the other sandbox options.
+//
+// The sandbox is shared, not copied. Files and processes left by one holder
+// are seen by the next, and Stop removes it for all of them, so a cache
+// should stop sandboxes only when evicting them. A sandbox found stopped is
+// replaced. The ID is a prefix of the hash; the full hash is kept on the
+// sandbox, and Create fails rather than reattach if the two differ. Only
+// providers with SupportsDeterministicID (Docker) honour it; on others
+// Create fails with ErrCapabilityNotSupported.
+func WithDeterministicID() Option {
+	return func(c *Config) {
+		c.DeterministicID = true
+	}
+}
+
+// deterministicID returns the ID of a sandbox created on providerName with
+// opts, and the full hash it is a prefix of.
+func deterministicID(providerName string, opts *provider.CreateOptions) (id, hash string, err error) {
+	data, err := json.Marshal(struct {
+		Provider string
+		Options  *provider.CreateOptions
+	}{providerName, opts})
+	if err != nil {
+		return "", "", fmt.Errorf("hash sandbox configuration: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash = hex.EncodeToString(sum[:])
+	return "sindoq-" + hash[:24], hash, nil
+}
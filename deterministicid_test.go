@@ -0,0 +1,94 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+func TestDeterministicID(t *testing.T) {
+	mp := &mockProvider{name: "mock", caps: &provider.Capabilities{SupportsDeterministicID: true}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	createID := func(opts ...Option) (id, hash string) {
+		t.Helper()
+		sb, err := Create(ctx, append(opts, WithProvider("mock"), WithDeterministicID())...)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		sb.Stop(ctx)
+		return mp.createOpts.ID, mp.createOpts.Labels[deterministicIDLabel]
+	}
+
+	id, hash := createID(WithImage("python:3.12-slim"), WithResources(ResourceConfig{MemoryMB: 256, CPUs: 1}))
+	if !strings.HasPrefix(id, "sindoq-") || !strings.HasPrefix(hash, strings.TrimPrefix(id, "sindoq-")) {
+		t.Errorf("ID %q is not a prefix of hash %q", id, hash)
+	}
+	if again, _ := createID(WithImage("python:3.12-slim"), WithResources(ResourceConfig{MemoryMB: 256, CPUs: 1})); again != id {
+		t.Errorf("identical config: ID = %q, want %q", again, id)
+	}
+	for name, opts := range map[string][]Option{
+		"image":    {WithImage("python:3.11-slim"), WithResources(ResourceConfig{MemoryMB: 256, CPUs: 1})},
+		"resource": {WithImage("python:3.12-slim"), WithResources(ResourceConfig{MemoryMB: 512, CPUs: 1})},
+		"network":  {WithImage("python:3.12-slim"), WithResources(ResourceConfig{MemoryMB: 256, CPUs: 1}), WithInternetAccess()},
+	} {
+		if other, _ := createID(opts...); other == id {
+			t.Errorf("different %s: ID = %q, same as the original", name, other)
+		}
+	}
+
+	if sb, err := Create(ctx, WithProvider("mock")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	} else {
+		sb.Stop(ctx)
+	}
+	if mp.createOpts.ID != "" {
+		t.Errorf("ID = %q without WithDeterministicID", mp.createOpts.ID)
+	}
+}
+
+func TestDeterministicIDUnsupported(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	_, err := Create(context.Background(), WithProvider("mock"), WithDeterministicID())
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
+
+func TestCreateReattachedSandbox(t *testing.T) {
+	instance := &mockInstance{id: "sindoq-abc", status: provider.StatusRunning, reattached: true}
+	mp := &mockProvider{name: "mock", instance: instance, caps: &provider.Capabilities{SupportsDeterministicID: true}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	// The first holder installed the CA bundle; without a file system a
+	// second install would fail.
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithDeterministicID(), WithCACert(testCACert(t, "reattach CA")))
+	if err != nil {
+		t.Fatalf("Create() error = %v, want the one-time setup skipped", err)
+	}
+	if sb.ID() != instance.id {
+		t.Errorf("ID = %q, want %q", sb.ID(), instance.id)
+	}
+
+	_, err = Create(ctx, WithProvider("mock"), WithDeterministicID(), WithFileLifetimePolicy(CleanPerExecution))
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Fatalf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if instance.stopped {
+		t.Error("a failed Create stopped the reattached sandbox other holders use")
+	}
+}
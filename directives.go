@@ -0,0 +1,194 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// directiveMarker starts the body of a directive comment, as in
+// "# sindoq: timeout=10s memory=256MB".
+const directiveMarker = "sindoq:"
+
+// directiveCommentPrefixes are the line comment markers a directive may
+// follow, covering the supported languages. Block comments are handled
+// separately.
+var directiveCommentPrefixes = []string{"//", "--", "#", ";", "%"}
+
+// codeDirectives are the settings of a directive comment. Zero fields were
+// not given.
+type codeDirectives struct {
+	Timeout   time.Duration
+	MemoryMB  int
+	Language  string
+	StdinFile string
+}
+
+// parseDirectives parses the directive comment of code, which must be its
+// first line, or the first after a shebang and blank lines. It returns nil
+// if code has none.
+func parseDirectives(code string) (*codeDirectives, error) {
+	body, ok := directiveBody(code)
+	if !ok {
+		return nil, nil
+	}
+
+	d := &codeDirectives{}
+	for _, field := range strings.Fields(body) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("directive %q: want key=value: %w", field, ErrInvalidConfiguration)
+		}
+		switch key {
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("directive timeout=%s: want a positive duration such as 10s: %w", value, ErrInvalidConfiguration)
+			}
+			d.Timeout = timeout
+		case "memory":
+			mb, err := parseMemoryMB(value)
+			if err != nil {
+				return nil, fmt.Errorf("directive memory=%s: %v: %w", value, err, ErrInvalidConfiguration)
+			}
+			d.MemoryMB = mb
+		case "language":
+			info, ok := langdetect.GetRuntimeInfo(value)
+			if !ok {
+				return nil, fmt.Errorf("directive language=%s: %w", value, ErrLanguageNotSupported)
+			}
+			d.Language = info.Language
+		case "stdin-file":
+			d.StdinFile = value
+		default:
+			return nil, fmt.Errorf("directive %q: unknown key %q: %w", field, key, ErrInvalidConfiguration)
+		}
+	}
+	return d, nil
+}
+
+// directiveBody returns what follows the directive marker in the leading
+// comment of code.
+func directiveBody(code string) (string, bool) {
+	var line string
+	for l := range strings.Lines(code) {
+		l = strings.TrimSpace(l)
+		if l == "" || strings.HasPrefix(l, "#!") {
+			continue
+		}
+		line = l
+		break
+	}
+
+	var comment string
+	if rest, ok := strings.CutPrefix(line, "/*"); ok {
+		comment, ok = strings.CutSuffix(rest, "*/")
+		if !ok {
+			return "", false
+		}
+	} else {
+		for _, prefix := range directiveCommentPrefixes {
+			if rest, ok := strings.CutPrefix(line, prefix); ok {
+				// Repeated markers, as in ";; sindoq:" or "## sindoq:".
+				comment = strings.TrimLeft(rest, prefix)
+				break
+			}
+		}
+	}
+
+	return strings.CutPrefix(strings.TrimSpace(comment), directiveMarker)
+}
+
+// parseMemoryMB parses a size such as "256MB", "256M", "1GB" or "512" (in
+// megabytes) into megabytes.
+func parseMemoryMB(s string) (int, error) {
+	upper := strings.ToUpper(s)
+	scale := 1.0
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{
+		{"GB", 1024}, {"G", 1024}, {"MB", 1}, {"M", 1}, {"KB", 1.0 / 1024}, {"K", 1.0 / 1024},
+	} {
+		if n, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			upper, scale = n, unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("want a positive size such as 256MB")
+	}
+	mb := int(n * scale)
+	if mb < 1 {
+		return 0, fmt.Errorf("less than 1MB")
+	}
+	return mb, nil
+}
+
+// applyDirectives sets execCfg from the directive comment of code, clamping
+// the timeout to DirectiveMaxTimeout (or the sandbox's default timeout) and
+// the memory to the sandbox's memory. The caller applies explicit options
+// afterwards, so they win.
+func (s *sandbox) applyDirectives(ctx context.Context, code string, execCfg *ExecuteConfig) error {
+	d, err := parseDirectives(code)
+	if err != nil || d == nil {
+		return err
+	}
+
+	if d.Timeout > 0 {
+		maxTimeout := s.config.DirectiveMaxTimeout
+		if maxTimeout <= 0 {
+			maxTimeout = s.config.DefaultTimeout
+		}
+		execCfg.Timeout = d.Timeout
+		if maxTimeout > 0 && d.Timeout > maxTimeout {
+			s.warnDirectiveClamp("timeout", d.Timeout, maxTimeout)
+			execCfg.Timeout = maxTimeout
+		}
+	}
+	if d.MemoryMB > 0 {
+		// An address space ulimit is no substitute: runtimes such as Go,
+		// Java and Node reserve far more virtual memory than they use.
+		if !s.capabilities.SupportsExecutionMemoryLimit {
+			return fmt.Errorf("directive memory: %w", ErrCapabilityNotSupported)
+		}
+		mb := d.MemoryMB
+		if maxMB := s.config.Resources.MemoryMB; maxMB > 0 && mb > maxMB {
+			s.warnDirectiveClamp("memory", mb, maxMB)
+			mb = maxMB
+		}
+		execCfg.MemoryMB = mb
+	}
+	if d.Language != "" {
+		execCfg.Language = d.Language
+	}
+	if d.StdinFile != "" {
+		fsys := s.instance.FileSystem()
+		if fsys == nil {
+			return fmt.Errorf("directive stdin-file: %w", ErrCapabilityNotSupported)
+		}
+		name := d.StdinFile
+		if !path.IsAbs(name) && execCfg.WorkDir != "" {
+			name = path.Join(execCfg.WorkDir, name)
+		}
+		data, err := fsys.Read(ctx, name)
+		if err != nil {
+			return fmt.Errorf("directive stdin-file=%s: %w", d.StdinFile, err)
+		}
+		execCfg.Stdin = string(data)
+	}
+	return nil
+}
+
+// warnDirectiveClamp logs that a directive asked for more than allowed.
+func (s *sandbox) warnDirectiveClamp(key string, requested, limit any) {
+	if s.config.Logger != nil {
+		s.config.Logger.Warn("directive clamped to the sandbox limit", "directive", key, "requested", requested, "limit", limit)
+	}
+}
@@ -0,0 +1,173 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    *codeDirectives
+		wantErr error
+	}{
+		{
+			name: "python",
+			code: "# sindoq: timeout=10s memory=256MB\nprint(input())\n",
+			want: &codeDirectives{Timeout: 10 * time.Second, MemoryMB: 256},
+		},
+		{
+			name: "c",
+			code: "// sindoq: language=c stdin-file=input.txt\n#include <stdio.h>\n",
+			want: &codeDirectives{Language: "C", StdinFile: "input.txt"},
+		},
+		{
+			name: "after shebang and blank lines",
+			code: "#!/usr/bin/env python3\n\n#sindoq: memory=1G\n",
+			want: &codeDirectives{MemoryMB: 1024},
+		},
+		{
+			name: "block comment",
+			code: "/* sindoq: timeout=500ms */\nint main() {}\n",
+			want: &codeDirectives{Timeout: 500 * time.Millisecond},
+		},
+		{
+			name: "lisp",
+			code: ";; sindoq: language=clojure\n(println 1)\n",
+			want: &codeDirectives{Language: "Clojure"},
+		},
+		{name: "plain comment", code: "# compute the answer\nprint(42)\n"},
+		{name: "not on the first line", code: "print(42)\n# sindoq: timeout=1s\n"},
+		{name: "no code", code: ""},
+		{name: "unknown key", code: "# sindoq: cpus=4\n", wantErr: ErrInvalidConfiguration},
+		{name: "bad timeout", code: "# sindoq: timeout=soon\n", wantErr: ErrInvalidConfiguration},
+		{name: "bad memory", code: "// sindoq: memory=-1MB\n", wantErr: ErrInvalidConfiguration},
+		{name: "no value", code: "# sindoq: timeout\n", wantErr: ErrInvalidConfiguration},
+		{name: "unknown language", code: "# sindoq: language=cobol\n", wantErr: ErrLanguageNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDirectives(tt.code)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("parseDirectives() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDirectives() error = %v", err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("parseDirectives() = %+v, want none", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Errorf("parseDirectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxParseDirectives(t *testing.T) {
+	inst := &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys: &memFileSystem{files: map[string][]byte{
+			provider.DefaultWorkDir + "/input.txt": []byte("3 4\n"),
+		}},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return &mockProvider{name: "mock", instance: inst, caps: &provider.Capabilities{SupportsExecutionMemoryLimit: true}}, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithParseDirectives(),
+		WithTimeout(time.Minute),
+		WithResources(ResourceConfig{MemoryMB: 512, CPUs: 1}),
+	)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	code := "# sindoq: timeout=10s memory=256MB language=python stdin-file=input.txt\na, b = input().split()\n"
+	if _, err := sb.Execute(ctx, code); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	opts := inst.lastOpts
+	if opts.Timeout != 10*time.Second || opts.Language != "Python" || opts.Stdin != "3 4\n" {
+		t.Errorf("options = timeout %v, language %q, stdin %q, want the directives", opts.Timeout, opts.Language, opts.Stdin)
+	}
+	if opts.MemoryMB != 256 {
+		t.Errorf("MemoryMB = %d, want 256", opts.MemoryMB)
+	}
+	if _, ok := opts.Ulimits["as"]; ok {
+		t.Errorf("as ulimit = %+v, want the memory directive left out of the ulimits", opts.Ulimits["as"])
+	}
+
+	// Requests above the sandbox's limits are clamped.
+	if _, err := sb.Execute(ctx, "// sindoq: timeout=1h memory=8GB\nconsole.log(1)\n", WithLanguage("JavaScript")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	opts = inst.lastOpts
+	if opts.Timeout != time.Minute || opts.MemoryMB != 512 {
+		t.Errorf("clamped options = timeout %v, MemoryMB %d, want the sandbox's limits", opts.Timeout, opts.MemoryMB)
+	}
+
+	// Explicit options win.
+	if _, err := sb.Execute(ctx, "# sindoq: timeout=10s language=shell\necho 1\n", WithExecutionTimeout(5*time.Second), WithLanguage("Python")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if opts := inst.lastOpts; opts.Timeout != 5*time.Second || opts.Language != "Python" {
+		t.Errorf("options = timeout %v, language %q, want the explicit ones", opts.Timeout, opts.Language)
+	}
+
+	if _, err := sb.Execute(ctx, "# sindoq: stdin-file=missing.txt\nprint(1)\n"); err == nil {
+		t.Error("Execute() with a missing stdin file should fail")
+	}
+	if _, err := sb.Execute(ctx, "# sindoq: gpus=1\nprint(1)\n"); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Execute() with an unknown directive error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxMemoryDirectiveUnsupported(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithParseDirectives())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if _, err := sb.Execute(ctx, "# sindoq: memory=256MB\nprint(1)\n", WithLanguage("Python")); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
+
+func TestSandboxDirectivesDisabled(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if _, err := sb.Execute(ctx, "# sindoq: gpus=1\nprint(1)\n", WithLanguage("Python")); err != nil {
+		t.Errorf("Execute() error = %v, want directives ignored without WithParseDirectives", err)
+	}
+}
@@ -4,6 +4,10 @@ package sindoq
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
 )
 
 // Sentinel errors for common conditions.
@@ -14,6 +18,14 @@ var (
 	// ErrSandboxStopped indicates sandbox is not running.
 	ErrSandboxStopped = errors.New("sandbox is stopped")
 
+	// ErrSandboxPaused indicates sandbox is paused and must be resumed
+	// before it can run anything.
+	ErrSandboxPaused = errors.New("sandbox is paused")
+
+	// ErrMaxLifetimeExceeded indicates an execution was canceled because
+	// the sandbox reached its maximum lifetime. See WithMaxLifetime.
+	ErrMaxLifetimeExceeded = errors.New("sandbox max lifetime exceeded")
+
 	// ErrExecutionTimeout indicates execution exceeded timeout.
 	ErrExecutionTimeout = errors.New("execution timeout")
 
@@ -23,6 +35,10 @@ var (
 	// ErrLanguageNotSupported indicates language isn't supported.
 	ErrLanguageNotSupported = errors.New("language not supported")
 
+	// ErrLanguageUnsupportedByProvider indicates the sandbox's provider
+	// has no runtime for the language. See LanguageUnsupportedError.
+	ErrLanguageUnsupportedByProvider = errors.New("language not supported by provider")
+
 	// ErrLanguageDetectionFailed indicates detection couldn't determine language.
 	ErrLanguageDetectionFailed = errors.New("language detection failed")
 
@@ -37,6 +53,43 @@ var (
 
 	// ErrProviderNotRegistered indicates provider is not registered.
 	ErrProviderNotRegistered = errors.New("provider not registered")
+
+	// ErrPipeStageFailed indicates a pipeline stage exited non-zero.
+	ErrPipeStageFailed = errors.New("pipe stage failed")
+
+	// ErrInvalidRequest indicates a malformed ExecuteRequest.
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrTestRunnerNotFound indicates RunTests' test runner is not
+	// installed in the sandbox.
+	ErrTestRunnerNotFound = errors.New("test runner not found")
+
+	// ErrOutputLoopDetected indicates a run was aborted for printing the
+	// same line over and over. See WithDetectOutputLoop.
+	ErrOutputLoopDetected = errors.New("output loop detected")
+
+	// ErrStdinPromptNotMatched indicates a prompt expected by a stdin
+	// script never appeared. See WithStdinScript.
+	ErrStdinPromptNotMatched = errors.New("stdin prompt not matched")
+
+	// ErrEmptyCode indicates code that is empty or only whitespace, which
+	// is refused before it reaches the provider.
+	ErrEmptyCode = errors.New("empty code")
+
+	// ErrProgramFailed indicates the program ran but did not succeed, e.g.
+	// exited non-zero. See Sandbox.Eval.
+	ErrProgramFailed = errors.New("program failed")
+
+	// ErrCapabilityNotSupported indicates an option the provider cannot honor.
+	ErrCapabilityNotSupported = errors.New("capability not supported by provider")
+
+	// ErrProviderClosing indicates work was refused because CloseProviders
+	// is shutting the provider down.
+	ErrProviderClosing = provider.ErrClosing
+
+	// ErrDiskLimitUnsupported indicates Resources.DiskMB was set but the
+	// provider or its storage driver cannot enforce it.
+	ErrDiskLimitUnsupported = provider.ErrDiskLimitUnsupported
 )
 
 // ProviderNotFoundError supplies suggestions when a provider is missing.
@@ -53,6 +106,40 @@ func (e *ProviderNotFoundError) Error() string {
 	return msg
 }
 
+// LanguageUnsupportedError is returned by Execute and ExecuteStream when the
+// provider does not list the language among its SupportedLanguages. It
+// matches ErrLanguageUnsupportedByProvider.
+type LanguageUnsupportedError struct {
+	Language  string
+	Provider  string
+	Supported []string // languages the provider supports
+	Providers []string // other registered providers that support Language
+}
+
+func (e *LanguageUnsupportedError) Error() string {
+	msg := fmt.Sprintf("language %q is not supported by provider %q (supported: %s)",
+		e.Language, e.Provider, strings.Join(e.Supported, ", "))
+	if len(e.Providers) > 0 {
+		msg += fmt.Sprintf("; try %s", strings.Join(e.Providers, ", "))
+	}
+	return msg
+}
+
+// Unwrap returns ErrLanguageUnsupportedByProvider.
+func (e *LanguageUnsupportedError) Unwrap() error {
+	return ErrLanguageUnsupportedByProvider
+}
+
+// HTTPProviderError reports an unexpected HTTP status from the E2B, Vercel
+// or AWS Lambda API. Use errors.As to branch on Status, e.g. 401 for a bad key, 404 for a
+// sandbox that no longer exists, 429 when rate limited and 5xx for outages.
+type HTTPProviderError = provider.HTTPProviderError
+
+// NetworkCaptureError is returned by Execute when a run with
+// WithNetworkCapture fails after the capture started, e.g. on timeout. Use
+// errors.As to get the traffic recorded before the failure.
+type NetworkCaptureError = executor.NetworkCaptureError
+
 // SandboxError wraps errors with context.
 type SandboxError struct {
 	Op        string // Operation that failed
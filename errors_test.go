@@ -12,14 +12,25 @@ func TestSentinelErrors(t *testing.T) {
 	}{
 		{"ErrSandboxNotFound", ErrSandboxNotFound},
 		{"ErrSandboxStopped", ErrSandboxStopped},
+		{"ErrSandboxPaused", ErrSandboxPaused},
 		{"ErrExecutionTimeout", ErrExecutionTimeout},
 		{"ErrProviderUnavailable", ErrProviderUnavailable},
 		{"ErrLanguageNotSupported", ErrLanguageNotSupported},
+		{"ErrLanguageUnsupportedByProvider", ErrLanguageUnsupportedByProvider},
 		{"ErrLanguageDetectionFailed", ErrLanguageDetectionFailed},
 		{"ErrResourceExhausted", ErrResourceExhausted},
 		{"ErrPermissionDenied", ErrPermissionDenied},
 		{"ErrInvalidConfiguration", ErrInvalidConfiguration},
 		{"ErrProviderNotRegistered", ErrProviderNotRegistered},
+		{"ErrPipeStageFailed", ErrPipeStageFailed},
+		{"ErrInvalidRequest", ErrInvalidRequest},
+		{"ErrCapabilityNotSupported", ErrCapabilityNotSupported},
+		{"ErrTestRunnerNotFound", ErrTestRunnerNotFound},
+		{"ErrOutputLoopDetected", ErrOutputLoopDetected},
+		{"ErrStdinPromptNotMatched", ErrStdinPromptNotMatched},
+		{"ErrEmptyCode", ErrEmptyCode},
+		{"ErrMaxLifetimeExceeded", ErrMaxLifetimeExceeded},
+		{"ErrProgramFailed", ErrProgramFailed},
 	}
 
 	for _, tt := range tests {
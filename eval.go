@@ -0,0 +1,48 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+)
+
+// Eval runs code and returns its stdout if it succeeded. Otherwise the
+// error wraps an ExecutionError, with the exit code, stdout and stderr,
+// around ErrProgramFailed:
+//
+//	out, err := sb.Eval(ctx, `print(6 * 7)`)
+//	var execErr *sindoq.ExecutionError
+//	if errors.As(err, &execErr) {
+//		log.Printf("exit %d: %s", execErr.ExitCode, execErr.Stderr)
+//	}
+//
+// Success is judged as by ExecutionResult.Success, so a language's own
+// exit code conventions apply. Errors that prevented the run, such as a
+// timeout, are returned unchanged.
+func (s *sandbox) Eval(ctx context.Context, code string, opts ...ExecuteOption) (string, error) {
+	result, err := s.Execute(ctx, code, opts...)
+	if err != nil {
+		return "", err
+	}
+	if result.Success() {
+		return result.Stdout, nil
+	}
+
+	cause := ErrProgramFailed
+	if result.Error != nil {
+		cause = fmt.Errorf("%w: %w", ErrProgramFailed, result.Error)
+	}
+	return "", NewError("eval", s.providerName, s.instance.ID(),
+		NewExecutionError(result.ExitCode, result.Stdout, result.Stderr, cause))
+}
+
+// Eval is a convenience function for one-shot evaluation. It creates a
+// sandbox, runs code with Sandbox.Eval, and cleans up.
+func Eval(ctx context.Context, code string, opts ...Option) (string, error) {
+	sb, err := Create(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	defer sb.Stop(context.Background())
+
+	return sb.Eval(ctx, code)
+}
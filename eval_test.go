@@ -0,0 +1,103 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestSandboxEval(t *testing.T) {
+	var got *executor.ExecutionOptions
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		got = opts
+		return &executor.ExecutionResult{ExitCode: 0, Stdout: "42\n", Stderr: "warning\n", Language: opts.Language}
+	})
+
+	out, err := sb.Eval(context.Background(), "print(42)", WithLanguage("Python"), WithExecutionTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if out != "42\n" {
+		t.Errorf("Eval() = %q, want stdout only", out)
+	}
+	if got.Language != "Python" || got.Timeout != 5*time.Second {
+		t.Errorf("options not applied: language %q, timeout %v", got.Language, got.Timeout)
+	}
+}
+
+func TestSandboxEvalFailure(t *testing.T) {
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{ExitCode: 2, Stdout: "partial\n", Stderr: "NameError: x\n", Language: opts.Language}
+	})
+
+	out, err := sb.Eval(context.Background(), "print(x)", WithLanguage("Python"))
+	if out != "" {
+		t.Errorf("Eval() = %q, want no output on failure", out)
+	}
+	if !errors.Is(err, ErrProgramFailed) {
+		t.Fatalf("Eval() error = %v, want ErrProgramFailed", err)
+	}
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Eval() error = %v, want an ExecutionError", err)
+	}
+	if execErr.ExitCode != 2 || execErr.Stdout != "partial\n" || execErr.Stderr != "NameError: x\n" {
+		t.Errorf("ExecutionError = %+v", execErr)
+	}
+	if !strings.Contains(err.Error(), "exit code 2") || !strings.Contains(err.Error(), "NameError") {
+		t.Errorf("Error() = %q, want the exit code and stderr", err.Error())
+	}
+}
+
+func TestSandboxEvalResultError(t *testing.T) {
+	cause := errors.New("killed by OOM")
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{ExitCode: 0, Stdout: "ok\n", Error: cause}
+	})
+
+	_, err := sb.Eval(context.Background(), "run()", WithLanguage("Python"))
+	if !errors.Is(err, ErrProgramFailed) || !errors.Is(err, cause) {
+		t.Errorf("Eval() error = %v, want ErrProgramFailed and the result error", err)
+	}
+}
+
+func TestSandboxEvalExecuteError(t *testing.T) {
+	sb := setupTestRunnerProvider(t, nil)
+	sb.Stop(context.Background())
+
+	_, err := sb.Eval(context.Background(), "print(1)", WithLanguage("Python"))
+	if !errors.Is(err, ErrSandboxStopped) || errors.Is(err, ErrProgramFailed) {
+		t.Errorf("Eval() error = %v, want ErrSandboxStopped unchanged", err)
+	}
+}
+
+func TestEval(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "eval-instance",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			return &executor.ExecutionResult{ExitCode: 0, Stdout: "hi\n"}
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	out, err := Eval(context.Background(), "print('hi')", WithProvider("mock"), WithRuntime("Python"))
+	if err != nil {
+		t.Fatalf("Eval() error = %v", err)
+	}
+	if out != "hi\n" {
+		t.Errorf("Eval() = %q", out)
+	}
+	if !mp.instance.stopped {
+		t.Error("Eval() should stop the sandbox")
+	}
+}
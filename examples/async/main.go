@@ -34,13 +34,13 @@ print(f"Result: {result}")
 `
 
 	fmt.Println("Starting async execution...")
-	resultChan, err := sb.ExecuteAsync(ctx, code)
+	execID, resultChan, err := sb.ExecuteAsync(ctx, code)
 	if err != nil {
 		log.Fatalf("Failed to start async execution: %v", err)
 	}
 
 	// Do other work while execution is running
-	fmt.Println("Execution started! Doing other work...")
+	fmt.Printf("Execution %s started! Doing other work...\n", execID)
 	for i := 0; i < 5; i++ {
 		fmt.Printf("  Other work tick %d...\n", i+1)
 		time.Sleep(500 * time.Millisecond)
@@ -46,10 +46,14 @@ print("This is stderr", file=sys.stderr)
 	// Stream handler receives events as they occur
 	err = sb.ExecuteStream(ctx, code, func(event *executor.StreamEvent) error {
 		switch event.Type {
+		case executor.StreamStart:
+			fmt.Printf("Running %s...\n", event.Language)
 		case executor.StreamStdout:
 			fmt.Print(event.Data)
 		case executor.StreamStderr:
 			fmt.Printf("[STDERR] %s", event.Data)
+		case executor.StreamCompileStdout, executor.StreamCompileStderr:
+			fmt.Printf("[COMPILE] %s", event.Data)
 		case executor.StreamComplete:
 			fmt.Printf("\n---\nExecution complete. Exit code: %d\n", event.ExitCode)
 		case executor.StreamError:
@@ -0,0 +1,189 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ExecuteTo runs code with streaming output written to stdout and stderr as
+// it arrives and returns the final result. Stdout and Stderr of the result
+// are empty since the output went to the writers. Writes are serialized, so
+// stdout and stderr may be the same writer.
+//
+// If a write fails, execution is aborted and the write error is returned.
+func (s *sandbox) ExecuteTo(ctx context.Context, code string, stdout, stderr io.Writer, opts ...ExecuteOption) (*executor.ExecutionResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &streamWriters{stdout: stdout, stderr: stderr, cancel: cancel}
+	start := time.Now()
+	err := s.ExecuteStream(ctx, code, w.handle, opts...)
+	if werr := w.err(); werr != nil {
+		return nil, werr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &executor.ExecutionResult{
+		ExitCode:        w.exitCode,
+		Signal:          w.signal,
+		Duration:        time.Since(start),
+		Language:        w.language,
+		StdoutTruncated: w.stdoutTruncated,
+		StderrTruncated: w.stderrTruncated,
+	}, nil
+}
+
+// streamWriters is the StreamHandler behind ExecuteTo.
+type streamWriters struct {
+	stdout, stderr io.Writer
+	cancel         context.CancelFunc
+
+	mu       sync.Mutex
+	exitCode int
+	signal   syscall.Signal
+	language string
+	writeErr error
+
+	stdoutTruncated, stderrTruncated bool
+}
+
+func (w *streamWriters) handle(e *executor.StreamEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Providers may keep delivering events after a failed write until the
+	// cancellation takes effect.
+	if w.writeErr != nil {
+		return w.writeErr
+	}
+
+	var dst io.Writer
+	switch e.Type {
+	case executor.StreamStdout:
+		dst = w.stdout
+	case executor.StreamStderr, executor.StreamCompileStdout, executor.StreamCompileStderr:
+		// Compiler diagnostics are not program output.
+		dst = w.stderr
+	case executor.StreamStart:
+		w.language = e.Language
+		return nil
+	case executor.StreamComplete:
+		w.exitCode, w.signal = e.ExitCode, e.Signal
+		w.language = e.Language
+		w.stdoutTruncated, w.stderrTruncated = e.StdoutTruncated, e.StderrTruncated
+		return nil
+	case executor.StreamError:
+		return e.Error
+	default:
+		return nil
+	}
+
+	if dst == nil || e.Data == "" {
+		return nil
+	}
+	if _, err := io.WriteString(dst, e.Data); err != nil {
+		w.writeErr = err
+		w.cancel()
+		return err
+	}
+	return nil
+}
+
+func (w *streamWriters) err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeErr
+}
+
+// streamedFeature names the option in c that Execute can only apply by
+// streaming the run, or returns "" if it can run directly.
+func (c *ExecuteConfig) streamedFeature() string {
+	switch {
+	case c.OutputLoopThreshold > 0:
+		return "output loop detection"
+	case len(c.StdinScript) > 0:
+		return "stdin script"
+	case c.SpillThreshold > 0:
+		return "spill to disk"
+	}
+	return ""
+}
+
+// executeOnlyOption names the option in c whose result only a direct
+// Execute returns, which a streamed run would lose, or returns "".
+func (c *ExecuteConfig) executeOnlyOption() string {
+	switch {
+	case c.TrackFileChanges:
+		return "file change tracking"
+	case c.Coverage:
+		return "coverage"
+	case c.NetworkCapture:
+		return "network capture"
+	}
+	return ""
+}
+
+// executeStreamed runs Execute through ExecuteStream, for options such as
+// feature that only the streaming path applies, and collects the output,
+// spilling each stream past spill bytes to disk when spill is positive. A
+// detected output loop is reported on the result rather than as an error.
+func (s *sandbox) executeStreamed(ctx context.Context, code, feature string, spill int64, opts []ExecuteOption) (*executor.ExecutionResult, error) {
+	if !s.capabilities.SupportsStreaming {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("%s: %w", feature, ErrCapabilityNotSupported))
+	}
+
+	// Middleware is for streamed events; Execute returns the output whole.
+	opts = append(slices.Clip(opts), func(c *ExecuteConfig) {
+		c.StreamMiddleware = nil
+	})
+
+	var stdout, stderr interface {
+		io.Writer
+		fmt.Stringer
+	} = &strings.Builder{}, &strings.Builder{}
+	var stdoutSpill, stderrSpill *executor.SpillBuffer
+	if spill > 0 {
+		stdoutSpill, stderrSpill = executor.NewSpillBuffer(spill), executor.NewSpillBuffer(spill)
+		stdout, stderr = stdoutSpill, stderrSpill
+	}
+	w := &streamWriters{stdout: stdout, stderr: stderr, cancel: func() {}}
+	start := time.Now()
+	err := s.ExecuteStream(ctx, code, w.handle, opts...)
+	loop := errors.Is(err, ErrOutputLoopDetected)
+	if err != nil && !loop {
+		if spill > 0 {
+			stdoutSpill.Close()
+			stderrSpill.Close()
+		}
+		return nil, err
+	}
+
+	result := &executor.ExecutionResult{
+		ExitCode:           w.exitCode,
+		Signal:             w.signal,
+		Stdout:             stdout.String(),
+		Stderr:             stderr.String(),
+		StdoutSpill:        stdoutSpill,
+		StderrSpill:        stderrSpill,
+		Duration:           time.Since(start),
+		Language:           w.language,
+		OutputLoopDetected: loop,
+		StdoutTruncated:    w.stdoutTruncated,
+		StderrTruncated:    w.stderrTruncated,
+	}
+	if loop {
+		result.ExitCode, result.Signal = -1, 0
+	}
+	return result, nil
+}
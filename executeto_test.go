@@ -0,0 +1,160 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// setupStreamProvider registers a mock whose streaming executions run stream.
+func setupStreamProvider(t *testing.T, stream func(ctx context.Context, handler executor.StreamHandler) error) func() {
+	t.Helper()
+	mp := &mockProvider{
+		name: "mock",
+		instance: &mockInstance{
+			id:         "test-instance-123",
+			status:     provider.StatusRunning,
+			streamFunc: stream,
+		},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	return func() { factory.Unregister("mock") }
+}
+
+func TestSandboxExecuteTo(t *testing.T) {
+	cleanup := setupStreamProvider(t, func(ctx context.Context, handler executor.StreamHandler) error {
+		handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "out 1\n"})
+		handler(&executor.StreamEvent{Type: executor.StreamStderr, Data: "err\n"})
+		handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "out 2\n"})
+		handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 3})
+		return nil
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	var stdout, stderr strings.Builder
+	result, err := sb.ExecuteTo(ctx, "print(1)", &stdout, &stderr, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("ExecuteTo() error = %v", err)
+	}
+	if got := stdout.String(); got != "out 1\nout 2\n" {
+		t.Errorf("stdout = %q, want %q", got, "out 1\nout 2\n")
+	}
+	if got := stderr.String(); got != "err\n" {
+		t.Errorf("stderr = %q, want %q", got, "err\n")
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	if result.Language != "Python" {
+		t.Errorf("Language = %q, want Python", result.Language)
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+func TestSandboxExecuteToWriterError(t *testing.T) {
+	events := 0
+	cleanup := setupStreamProvider(t, func(ctx context.Context, handler executor.StreamHandler) error {
+		// Ignore handler errors like providers that only stop on
+		// cancellation.
+		for ctx.Err() == nil {
+			events++
+			handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "x"})
+		}
+		return ctx.Err()
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	writeErr := errors.New("broken pipe")
+	var stderr strings.Builder
+	_, err = sb.ExecuteTo(ctx, "print(1)", failingWriter{writeErr}, &stderr, WithLanguage("Python"))
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("ExecuteTo() error = %v, want %v", err, writeErr)
+	}
+	if events != 1 {
+		t.Errorf("execution continued for %d events after the failed write", events-1)
+	}
+}
+
+func TestSandboxExecuteToSignal(t *testing.T) {
+	cleanup := setupStreamProvider(t, func(ctx context.Context, handler executor.StreamHandler) error {
+		return handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 139})
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.ExecuteTo(ctx, "print(1)", io.Discard, io.Discard, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("ExecuteTo() error = %v", err)
+	}
+	if result.Signal != syscall.SIGSEGV {
+		t.Errorf("Signal = %v, want SIGSEGV", result.Signal)
+	}
+}
+
+func TestSandboxExecuteStreamedRejectsExecuteOnlyOptions(t *testing.T) {
+	cleanup := setupStreamProvider(t, func(ctx context.Context, handler executor.StreamHandler) error {
+		t.Error("the run should be rejected before it streams")
+		return nil
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	streamed := map[string]ExecuteOption{
+		"output loop":   WithDetectOutputLoop(),
+		"stdin script":  WithStdinScript([]StdinRule{{MatchRegex: "name", Response: "x"}}),
+		"spill to disk": WithSpillToDisk(1024),
+	}
+	executeOnly := map[string]ExecuteOption{
+		"file changes":    WithTrackFileChanges(),
+		"coverage":        WithCoverage(),
+		"network capture": WithNetworkCapture(),
+	}
+	for streamedName, streamedOpt := range streamed {
+		for onlyName, onlyOpt := range executeOnly {
+			t.Run(streamedName+" with "+onlyName, func(t *testing.T) {
+				_, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), streamedOpt, onlyOpt)
+				if !errors.Is(err, ErrInvalidConfiguration) {
+					t.Errorf("Execute() error = %v, want ErrInvalidConfiguration", err)
+				}
+			})
+		}
+	}
+}
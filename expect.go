@@ -0,0 +1,373 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ExpectResult is the outcome of Sandbox.ExecuteExpect.
+type ExpectResult struct {
+	// Match reports whether the output matched the expected output under
+	// the configured normalization or comparator.
+	Match bool
+
+	// Diff is a unified diff from the expected to the actual output, or
+	// empty when they match. A custom comparator may leave it empty.
+	Diff string
+
+	// Result is the raw execution.
+	Result *executor.ExecutionResult
+}
+
+// ExpectOption configures ExecuteExpect.
+type ExpectOption func(*ExpectConfig)
+
+// ExpectConfig holds the output comparison settings of ExecuteExpect. The
+// zero value compares stdout exactly.
+type ExpectConfig struct {
+	// TrimTrailingSpace ignores whitespace at the end of lines and blank
+	// lines at the end of the output.
+	TrimTrailingSpace bool
+
+	// IgnoreCase compares lines case-insensitively.
+	IgnoreCase bool
+
+	// IgnoreBlankLines skips lines that contain only whitespace.
+	IgnoreBlankLines bool
+
+	// CombinedOutput compares stdout followed by stderr instead of stdout
+	// alone.
+	CombinedOutput bool
+
+	// FloatTolerance, when positive, lets numbers in the same position of
+	// a line differ by up to this absolute amount.
+	FloatTolerance float64
+
+	// Comparator, when set, replaces the comparison above. See
+	// WithComparator.
+	Comparator OutputComparator
+
+	// collapseSpace compares lines with runs of whitespace collapsed and
+	// leading whitespace removed.
+	collapseSpace bool
+
+	// ExecuteOptions apply to the execution.
+	ExecuteOptions []ExecuteOption
+}
+
+// WithTrimTrailingSpace ignores trailing whitespace on each line and blank
+// lines at the end of the output, including a missing final newline.
+func WithTrimTrailingSpace() ExpectOption {
+	return func(c *ExpectConfig) {
+		c.TrimTrailingSpace = true
+	}
+}
+
+// WithIgnoreCase compares output case-insensitively.
+func WithIgnoreCase() ExpectOption {
+	return func(c *ExpectConfig) {
+		c.IgnoreCase = true
+	}
+}
+
+// WithIgnoreBlankLines skips blank lines in both outputs.
+func WithIgnoreBlankLines() ExpectOption {
+	return func(c *ExpectConfig) {
+		c.IgnoreBlankLines = true
+	}
+}
+
+// WithCombinedOutput compares stdout followed by stderr. Providers return
+// the two streams separately, so their interleaving is not preserved.
+func WithCombinedOutput() ExpectOption {
+	return func(c *ExpectConfig) {
+		c.CombinedOutput = true
+	}
+}
+
+// WithFloatTolerance treats numbers as equal when they differ by at most
+// tol, so "0.30000000000000004" matches "0.3". Lines are then compared
+// field by field, separated by whitespace.
+func WithFloatTolerance(tol float64) ExpectOption {
+	return func(c *ExpectConfig) {
+		c.FloatTolerance = tol
+	}
+}
+
+// WithExecuteOptions passes execution options, such as WithLanguage or
+// WithStdin, to the run.
+func WithExecuteOptions(opts ...ExecuteOption) ExpectOption {
+	return func(c *ExpectConfig) {
+		c.ExecuteOptions = append(c.ExecuteOptions, opts...)
+	}
+}
+
+// ExecuteExpect runs code and compares its output with expected, for
+// autograders. A mismatch is reported in the result, with a diff, not as
+// an error; neither is a non-zero exit code, which is left to the caller
+// to check in Result.
+func (s *sandbox) ExecuteExpect(ctx context.Context, code, expected string, opts ...ExpectOption) (*ExpectResult, error) {
+	cfg := &ExpectConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.FloatTolerance < 0 || math.IsNaN(cfg.FloatTolerance) {
+		return nil, NewError("executeExpect", s.providerName, s.instance.ID(),
+			fmt.Errorf("float tolerance %v: %w", cfg.FloatTolerance, ErrInvalidConfiguration))
+	}
+
+	result, err := s.Execute(ctx, code, cfg.ExecuteOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := result.Stdout
+	if cfg.CombinedOutput {
+		actual += result.Stderr
+	}
+	match, diff := cfg.comparator().Compare(expected, actual)
+	return &ExpectResult{Match: match, Diff: diff, Result: result}, nil
+}
+
+// comparator returns the configured comparator, or the comparison set up
+// by the normalization options.
+func (c *ExpectConfig) comparator() OutputComparator {
+	if c.Comparator != nil {
+		return c.Comparator
+	}
+	return (*expectComparator)(c)
+}
+
+// outputLine is one line of compared output.
+type outputLine struct {
+	// text is the line as printed and key its normalized form.
+	text string
+	key  string
+
+	// num is the 1-based line number in the output.
+	num int
+
+	// noEOL is set on a last line without a trailing newline.
+	noEOL bool
+}
+
+// splitOutput splits output into lines normalized according to cfg.
+func splitOutput(output string, cfg *ExpectConfig) []outputLine {
+	if cfg.TrimTrailingSpace {
+		output = strings.TrimRight(output, " \t\r\n")
+	}
+	if output == "" {
+		return nil
+	}
+	noEOL := !strings.HasSuffix(output, "\n")
+	raw := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	lines := make([]outputLine, 0, len(raw))
+	for i, text := range raw {
+		key := text
+		if cfg.TrimTrailingSpace {
+			key = strings.TrimRight(key, " \t\r")
+		}
+		if cfg.collapseSpace {
+			key = strings.Join(strings.Fields(key), " ")
+		}
+		if cfg.IgnoreBlankLines && strings.TrimSpace(key) == "" {
+			continue
+		}
+		if cfg.IgnoreCase {
+			key = strings.ToLower(key)
+		}
+		lines = append(lines, outputLine{text: text, key: key, num: i + 1, noEOL: noEOL && i == len(raw)-1})
+	}
+	return lines
+}
+
+// compareOutput reports whether actual matches expected under cfg and, if
+// not, returns a unified diff between them.
+func compareOutput(expected, actual string, cfg *ExpectConfig) (bool, string) {
+	a := splitOutput(expected, cfg)
+	b := splitOutput(actual, cfg)
+	eq := func(x, y outputLine) bool {
+		if x.noEOL != y.noEOL {
+			return false
+		}
+		return x.key == y.key || cfg.FloatTolerance > 0 && fieldsWithin(x.key, y.key, cfg.FloatTolerance)
+	}
+
+	if len(a) == len(b) {
+		match := true
+		for i := range a {
+			if !eq(a[i], b[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true, ""
+		}
+	}
+	return false, unifiedDiff(a, b, eq)
+}
+
+// fieldsWithin reports whether x and y have the same whitespace-separated
+// fields, allowing numeric fields to differ by at most tol.
+func fieldsWithin(x, y string, tol float64) bool {
+	fx, fy := strings.Fields(x), strings.Fields(y)
+	if len(fx) != len(fy) {
+		return false
+	}
+	for i := range fx {
+		if fx[i] == fy[i] {
+			continue
+		}
+		vx, errx := strconv.ParseFloat(fx[i], 64)
+		vy, erry := strconv.ParseFloat(fy[i], 64)
+		if errx != nil || erry != nil || !(math.Abs(vx-vy) <= tol) {
+			return false
+		}
+	}
+	return true
+}
+
+// diffContext is how many unchanged lines surround each change in a diff.
+const diffContext = 3
+
+// maxDiffCells bounds the size of the table used to align outputs. Larger
+// outputs are diffed as a wholesale replacement.
+const maxDiffCells = 1 << 22
+
+// diffOp is one line of a diff: ' ' for a common line, '-' for a line only
+// in the expected output and '+' for one only in the actual output. ai and
+// bi are the indexes of the next expected and actual lines.
+type diffOp struct {
+	kind   byte
+	line   outputLine
+	ai, bi int
+}
+
+// diffOps aligns a and b along a longest common subsequence.
+func diffOps(a, b []outputLine, eq func(x, y outputLine) bool) []diffOp {
+	n, m := len(a), len(b)
+	var ops []diffOp
+	if (n+1)*(m+1) > maxDiffCells {
+		for i := range a {
+			ops = append(ops, diffOp{'-', a[i], i, 0})
+		}
+		for j := range b {
+			ops = append(ops, diffOp{'+', b[j], n, j})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if eq(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && eq(a[i], b[j]):
+			ops = append(ops, diffOp{' ', b[j], i, j})
+			i++
+			j++
+		case i < n && (j == m || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff formats the differences between a and b as a unified diff
+// with diffContext lines of context. Lines are printed as they appeared,
+// before normalization.
+func unifiedDiff(a, b []outputLine, eq func(x, y outputLine) bool) string {
+	ops := diffOps(a, b, eq)
+
+	var out strings.Builder
+	out.WriteString("--- expected\n+++ actual\n")
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are
+		// within twice the context of each other.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		end := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+		from := max(first-diffContext, start)
+		to := min(end+diffContext, len(ops))
+
+		writeHunk(&out, ops[from:to], a, b)
+		start = to
+	}
+	return out.String()
+}
+
+// writeHunk writes one hunk of a unified diff.
+func writeHunk(out *strings.Builder, ops []diffOp, a, b []outputLine) {
+	var aCount, bCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aCount++
+		}
+		if op.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(a, ops[0].ai, aCount), hunkRange(b, ops[0].bi, bCount))
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line.text)
+		out.WriteByte('\n')
+		if op.line.noEOL {
+			out.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the line range of a hunk side that starts at index
+// start of lines and spans count lines.
+func hunkRange(lines []outputLine, start, count int) string {
+	line := 0
+	switch {
+	case count > 0:
+		line = lines[start].num
+	case start > 0:
+		// An empty range names the line before it.
+		line = lines[start-1].num
+	}
+	if count == 1 {
+		return strconv.Itoa(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
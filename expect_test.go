@@ -0,0 +1,121 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestCompareOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		opts     []ExpectOption
+		want     bool
+	}{
+		{"exact", "1\n2\n", "1\n2\n", nil, true},
+		{"exact trailing space differs", "1\n2\n", "1 \n2\n", nil, false},
+		{"exact final newline differs", "1\n2\n", "1\n2", nil, false},
+		{"trim trailing space", "1\n2\n", "1  \n2\t\n\n", []ExpectOption{WithTrimTrailingSpace()}, true},
+		{"trim keeps leading space", "1\n2\n", " 1\n2\n", []ExpectOption{WithTrimTrailingSpace()}, false},
+		{"ignore case", "Hello\n", "HELLO\n", []ExpectOption{WithIgnoreCase()}, true},
+		{"ignore blank lines", "a\nb\n", "a\n\n  \nb\n", []ExpectOption{WithIgnoreBlankLines()}, true},
+		{"float tolerance", "sum 0.3\n", "sum 0.30000000000000004\n", []ExpectOption{WithFloatTolerance(1e-9)}, true},
+		{"float beyond tolerance", "0.3\n", "0.31\n", []ExpectOption{WithFloatTolerance(1e-3)}, false},
+		{"float tolerance needs numbers", "x\n", "y\n", []ExpectOption{WithFloatTolerance(1)}, false},
+		{"empty", "", "", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &ExpectConfig{}
+			for _, opt := range tt.opts {
+				opt(cfg)
+			}
+			match, diff := compareOutput(tt.expected, tt.actual, cfg)
+			if match != tt.want {
+				t.Errorf("match = %v, want %v\n%s", match, tt.want, diff)
+			}
+			if match != (diff == "") {
+				t.Errorf("diff = %q for match = %v", diff, match)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	expected := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	actual := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+	_, diff := compareOutput(expected, actual, &ExpectConfig{})
+	want := `--- expected
++++ actual
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestUnifiedDiffNoNewline(t *testing.T) {
+	_, diff := compareOutput("done\n", "done", &ExpectConfig{})
+	want := "--- expected\n+++ actual\n@@ -1 +1 @@\n-done\n+done\n\\ No newline at end of file\n"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestSandboxExecuteExpect(t *testing.T) {
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{ExitCode: 0, Stdout: "Hello, World!  \n\n", Stderr: "warning\n"}
+	})
+	ctx := context.Background()
+
+	er, err := sb.ExecuteExpect(ctx, `print("Hello, World!")`, "Hello, World!\n", WithExecuteOptions(WithLanguage("Python")))
+	if err != nil {
+		t.Fatalf("ExecuteExpect() error = %v", err)
+	}
+	if er.Match || er.Result.Stdout != "Hello, World!  \n\n" {
+		t.Errorf("exact comparison = %+v, want a mismatch", er)
+	}
+	want := "--- expected\n+++ actual\n@@ -1 +1,2 @@\n-Hello, World!\n+Hello, World!  \n+\n"
+	if er.Diff != want {
+		t.Errorf("Diff =\n%s\nwant\n%s", er.Diff, want)
+	}
+
+	er, err = sb.ExecuteExpect(ctx, `print("Hello, World!")`, "Hello, World!", WithTrimTrailingSpace())
+	if err != nil {
+		t.Fatalf("ExecuteExpect() error = %v", err)
+	}
+	if !er.Match || er.Diff != "" {
+		t.Errorf("normalized comparison = %+v, want a match", er)
+	}
+
+	er, err = sb.ExecuteExpect(ctx, `print("Hello, World!")`, "Hello, World!\nwarning", WithTrimTrailingSpace(), WithIgnoreBlankLines(), WithCombinedOutput())
+	if err != nil {
+		t.Fatalf("ExecuteExpect() error = %v", err)
+	}
+	if !er.Match {
+		t.Errorf("combined comparison diff:\n%s", er.Diff)
+	}
+
+	_, err = sb.ExecuteExpect(ctx, "", "", WithFloatTolerance(-1))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("negative tolerance error = %v, want ErrInvalidConfiguration", err)
+	}
+}
@@ -0,0 +1,100 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// FileLifetimePolicy decides what happens to the files in a sandbox's
+// working directory between executions.
+type FileLifetimePolicy int
+
+const (
+	// PersistAcrossExecutions keeps the files an execution leaves in the
+	// working directory for later executions. This is the default.
+	PersistAcrossExecutions FileLifetimePolicy = iota
+
+	// CleanPerExecution empties the working directory when an execution
+	// ends, so every execution starts from the files written through
+	// Files() since the last one and nothing else.
+	CleanPerExecution
+)
+
+// String returns the policy's name.
+func (p FileLifetimePolicy) String() string {
+	switch p {
+	case PersistAcrossExecutions:
+		return "persist-across-executions"
+	case CleanPerExecution:
+		return "clean-per-execution"
+	default:
+		return fmt.Sprintf("FileLifetimePolicy(%d)", int(p))
+	}
+}
+
+// WithFileLifetimePolicy sets what happens to the files in the working
+// directory between executions. The policy is applied by sindoq through the
+// sandbox's file system rather than by the provider, so it behaves the same
+// on every provider; creating a sandbox with CleanPerExecution on a provider
+// without a file system fails with ErrCapabilityNotSupported.
+//
+// Cleaning covers the sandbox's working directory only: files written
+// elsewhere, such as /tmp, persist, and so do the ".sindoq" files sindoq
+// keeps there itself. While executions overlap, the directory is cleaned
+// once the last of them ends.
+func WithFileLifetimePolicy(p FileLifetimePolicy) Option {
+	return func(c *Config) {
+		c.FileLifetime = p
+	}
+}
+
+// fileLifetime applies Config.FileLifetime to the executions of a sandbox.
+type fileLifetime struct {
+	mu      sync.Mutex
+	running int
+}
+
+// beginExecution marks an execution as running and returns the function
+// that ends it, cleaning the working directory under CleanPerExecution
+// once no other execution is running. Executions wait for a cleaning in
+// progress before they start.
+func (s *sandbox) beginExecution(ctx context.Context) func() {
+	if s.config.FileLifetime != CleanPerExecution {
+		return func() {}
+	}
+	s.files.mu.Lock()
+	s.files.running++
+	s.files.mu.Unlock()
+
+	return func() {
+		s.files.mu.Lock()
+		defer s.files.mu.Unlock()
+		s.files.running--
+		if s.files.running == 0 {
+			s.cleanWorkDir(context.WithoutCancel(ctx))
+		}
+	}
+}
+
+// cleanWorkDir removes everything in the working directory except sindoq's
+// own files.
+func (s *sandbox) cleanWorkDir(ctx context.Context) {
+	fsys := s.instance.FileSystem()
+	entries, err := fsys.List(ctx, s.workDir)
+	if err == nil {
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name, scratchDir) {
+				continue
+			}
+			if err = fsys.Delete(ctx, path.Join(s.workDir, e.Name)); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && s.config.Logger != nil {
+		s.config.Logger.Warn("failed to clean working directory", "workdir", s.workDir, "error", err)
+	}
+}
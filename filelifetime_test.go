@@ -0,0 +1,70 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// TestFileLifetimePolicy is repeated against real sandboxes by the Docker
+// and nsjail packages, with the same files and expectations.
+func TestFileLifetimePolicy(t *testing.T) {
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return &mockProvider{name: "mock", instance: &mockInstance{
+			id:         "test-instance-123",
+			status:     provider.StatusRunning,
+			fsys:       &memFileSystem{files: map[string][]byte{}},
+			execResult: &executor.ExecutionResult{Stdout: "1\n"},
+		}}, nil
+	})
+	defer factory.Unregister("mock")
+
+	for _, policy := range []FileLifetimePolicy{PersistAcrossExecutions, CleanPerExecution} {
+		t.Run(policy.String(), func(t *testing.T) {
+			ctx := context.Background()
+			sb, err := Create(ctx, WithProvider("mock"), WithRuntime("Python"), WithFileLifetimePolicy(policy))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			files := map[string]bool{
+				"/workspace/left.txt":           policy == PersistAcrossExecutions,
+				"/workspace/data/input.csv":     policy == PersistAcrossExecutions,
+				"/workspace/.sindoq/out.stdout": true,
+			}
+			for file := range files {
+				if err := sb.Files().Write(ctx, file, []byte("x")); err != nil {
+					t.Fatalf("Write(%s) error = %v", file, err)
+				}
+			}
+			sb.Execute(ctx, "print(1)", WithLanguage("Python"))
+
+			for file, want := range files {
+				if got, err := sb.Files().Exists(ctx, file); err != nil || got != want {
+					t.Errorf("%s exists = %v (error %v), want %v", file, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileLifetimePolicyWithoutFileSystem(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{id: "test-instance-123", status: provider.StatusRunning}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	if _, err := Create(ctx, WithProvider("mock"), WithFileLifetimePolicy(CleanPerExecution)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if !mp.instance.stopped {
+		t.Error("instance not stopped after the failed Create()")
+	}
+}
@@ -0,0 +1,169 @@
+package sindoq
+
+import (
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goStdImports maps package names used in snippets to their import paths.
+var goStdImports = map[string]string{
+	"bufio":    "bufio",
+	"bytes":    "bytes",
+	"errors":   "errors",
+	"filepath": "path/filepath",
+	"fmt":      "fmt",
+	"http":     "net/http",
+	"io":       "io",
+	"json":     "encoding/json",
+	"math":     "math",
+	"os":       "os",
+	"rand":     "math/rand",
+	"regexp":   "regexp",
+	"sort":     "sort",
+	"strconv":  "strconv",
+	"strings":  "strings",
+	"sync":     "sync",
+	"time":     "time",
+	"unicode":  "unicode",
+	"utf8":     "unicode/utf8",
+}
+
+// wrapGoMain turns a Go snippet into a runnable program. Code without a
+// package clause keeps its imports and top-level func and type
+// declarations, has its remaining statements moved into func main, and
+// gets imports for the standard packages it references but doesn't import.
+// Code that already has a package clause is returned unchanged.
+func wrapGoMain(code string) string {
+	src := []byte(code)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	type span struct {
+		start, end int
+		kind       token.Token
+	}
+	var (
+		decls    []span
+		imports  = make(map[string]bool)
+		used     = make(map[string]bool)
+		hasMain  bool
+		depth    int
+		declAt   = -1
+		declKind token.Token
+		prev     token.Token
+		prevLit  string
+	)
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		off := file.Offset(pos)
+
+		switch tok {
+		case token.PACKAGE:
+			if depth == 0 && declAt < 0 {
+				return code
+			}
+		case token.LBRACE, token.LPAREN, token.LBRACK:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACK:
+			depth--
+		case token.FUNC, token.TYPE, token.IMPORT:
+			// Only at the start of a top-level statement, so function
+			// literals such as "f := func() {}" stay in main.
+			if depth == 0 && declAt < 0 && (prev == token.SEMICOLON || prev == token.ILLEGAL) {
+				declAt, declKind = off, tok
+			}
+		case token.IDENT:
+			if declKind == token.FUNC && prev == token.FUNC && lit == "main" && depth == 0 {
+				hasMain = true
+			}
+		case token.STRING:
+			if declKind == token.IMPORT && declAt >= 0 {
+				if path, err := strconv.Unquote(lit); err == nil {
+					imports[path] = true
+				}
+			}
+		case token.PERIOD:
+			if prev == token.IDENT {
+				used[prevLit] = true
+			}
+		case token.SEMICOLON:
+			if depth == 0 && declAt >= 0 {
+				end := off
+				if lit == ";" {
+					end++
+				}
+				decls = append(decls, span{declAt, end, declKind})
+				declAt = -1
+				declKind = token.ILLEGAL
+			}
+		}
+		if tok != token.COMMENT {
+			prev, prevLit = tok, lit
+		}
+	}
+	if declAt >= 0 {
+		decls = append(decls, span{declAt, len(src), declKind})
+	}
+
+	// Everything outside the declarations is the body of main.
+	var importText, declText, body strings.Builder
+	last := 0
+	for _, d := range decls {
+		body.WriteString(code[last:d.start])
+		text := &declText
+		if d.kind == token.IMPORT {
+			text = &importText
+		}
+		text.WriteString(code[d.start:d.end])
+		text.WriteString("\n\n")
+		last = d.end
+	}
+	body.WriteString(code[last:])
+
+	var missing []string
+	for name, path := range goStdImports {
+		if used[name] && !imports[path] {
+			missing = append(missing, path)
+		}
+	}
+	sort.Strings(missing)
+
+	var out strings.Builder
+	out.WriteString("package main\n\n")
+	out.WriteString(importText.String())
+	if len(missing) > 0 {
+		out.WriteString("import (\n")
+		for _, path := range missing {
+			out.WriteString("\t" + strconv.Quote(path) + "\n")
+		}
+		out.WriteString(")\n\n")
+	}
+	out.WriteString(declText.String())
+
+	stmts := strings.TrimSpace(body.String())
+	if !hasMain {
+		out.WriteString("func main() {\n")
+		out.WriteString(stmts)
+		out.WriteString("\n}\n")
+	} else if stmts != "" {
+		// Leftover statements can't be placed; keep them so the compiler
+		// reports them rather than silently dropping code.
+		out.WriteString(stmts + "\n")
+	}
+
+	if formatted, err := format.Source([]byte(out.String())); err == nil {
+		return string(formatted)
+	}
+	return out.String()
+}
@@ -0,0 +1,138 @@
+package sindoq
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// parseGo parses src and returns its imports and top-level func names.
+func parseGo(t *testing.T, src string) (imports map[string]bool, funcs map[string]bool) {
+	t.Helper()
+
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", src, 0)
+	if err != nil {
+		t.Fatalf("wrapped code does not parse: %v\n%s", err, src)
+	}
+	if f.Name.Name != "main" {
+		t.Errorf("package = %s, want main", f.Name.Name)
+	}
+
+	imports = make(map[string]bool)
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		imports[path] = true
+	}
+	funcs = make(map[string]bool)
+	for _, obj := range f.Scope.Objects {
+		if obj.Kind.String() == "func" {
+			funcs[obj.Name] = true
+		}
+	}
+	return imports, funcs
+}
+
+func TestWrapGoMainBareStatements(t *testing.T) {
+	got := wrapGoMain(`x := strings.ToUpper("hi")
+fmt.Println(x)`)
+
+	imports, funcs := parseGo(t, got)
+	if !imports["fmt"] || !imports["strings"] {
+		t.Errorf("imports = %v, want fmt and strings\n%s", imports, got)
+	}
+	if !funcs["main"] {
+		t.Errorf("missing func main\n%s", got)
+	}
+}
+
+func TestWrapGoMainBareFunction(t *testing.T) {
+	got := wrapGoMain(`import "os"
+
+type point struct{ x, y int }
+
+func add(a, b int) int {
+	return a + b
+}
+
+f := func() int { return add(1, 2) }
+fmt.Println(f(), point{1, 2})
+os.Exit(0)`)
+
+	imports, funcs := parseGo(t, got)
+	if !imports["fmt"] || !imports["os"] {
+		t.Errorf("imports = %v, want fmt and os\n%s", imports, got)
+	}
+	if !funcs["add"] || !funcs["main"] {
+		t.Errorf("funcs = %v, want add and main\n%s", funcs, got)
+	}
+}
+
+func TestWrapGoMainFullProgram(t *testing.T) {
+	code := `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hi")
+}
+`
+	if got := wrapGoMain(code); got != code {
+		t.Errorf("wrapGoMain changed a full program:\n%s", got)
+	}
+}
+
+func TestWrapGoMainExistingMain(t *testing.T) {
+	got := wrapGoMain(`func main() {
+	fmt.Println("hi")
+}`)
+
+	imports, funcs := parseGo(t, got)
+	if !imports["fmt"] || !funcs["main"] {
+		t.Errorf("imports = %v, funcs = %v\n%s", imports, funcs, got)
+	}
+}
+
+func TestSandboxAutoWrapMain(t *testing.T) {
+	var ran []string
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			ran = append(ran, code)
+			return &executor.ExecutionResult{}
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	snippet := `fmt.Println("hi")`
+	sb.Execute(ctx, snippet, WithLanguage("Go"))
+	sb.Execute(ctx, snippet, WithLanguage("Go"), WithAutoWrapMain())
+	sb.Execute(ctx, `print("hi")`, WithLanguage("Python"), WithAutoWrapMain())
+
+	if ran[0] != snippet {
+		t.Errorf("code without WithAutoWrapMain = %q, want unchanged", ran[0])
+	}
+	if !strings.HasPrefix(ran[1], "package main") {
+		t.Errorf("code with WithAutoWrapMain = %q, want wrapped", ran[1])
+	}
+	if ran[2] != `print("hi")` {
+		t.Errorf("Python code = %q, want unchanged", ran[2])
+	}
+}
@@ -0,0 +1,232 @@
+// Package cgroup reads the memory and CPU limits that the cgroup of the
+// current process imposes, under cgroup v1 or v2.
+package cgroup
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Limits are the resources available to a cgroup. Zero means unlimited.
+type Limits struct {
+	// MemoryMB is the memory limit in megabytes.
+	MemoryMB int
+
+	// CPUs is the CPU quota in cores.
+	CPUs float64
+}
+
+// unlimitedMemory is the smallest cgroup v1 memory limit treated as no
+// limit. The kernel reports "unlimited" as the largest page-aligned int64.
+const unlimitedMemory = 1 << 60
+
+// Read returns the limits of the current process's cgroup. Hosts without
+// cgroups, such as macOS, report no limits.
+func Read() (Limits, error) {
+	return ReadFrom("/")
+}
+
+// ReadFrom is Read with /proc and /sys resolved under root.
+func ReadFrom(root string) (Limits, error) {
+	groups, err := readGroups(filepath.Join(root, "proc", "self", "cgroup"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Limits{}, nil
+	}
+	if err != nil {
+		return Limits{}, err
+	}
+
+	mount := filepath.Join(root, "sys", "fs", "cgroup")
+	if _, err := os.Stat(filepath.Join(mount, "cgroup.controllers")); err == nil {
+		return readV2(mount, groups[""])
+	}
+	return readV1(mount, groups)
+}
+
+// readGroups parses /proc/self/cgroup into the cgroup path of each
+// controller. The cgroup v2 hierarchy is keyed by "".
+func readGroups(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	groups := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[1] == "" {
+			groups[""] = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			groups[controller] = fields[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	return groups, nil
+}
+
+// readV2 reads memory.max and cpu.max of group and each of its ancestors,
+// keeping the tightest limits. Inside a container the group is often not
+// visible under its own path, in which case only the mount root is read.
+func readV2(mount, group string) (Limits, error) {
+	var limits Limits
+	for _, dir := range ancestors(mount, group) {
+		memory, err := readV2Memory(filepath.Join(dir, "memory.max"))
+		if err != nil {
+			return Limits{}, err
+		}
+		cpus, err := readV2CPU(filepath.Join(dir, "cpu.max"))
+		if err != nil {
+			return Limits{}, err
+		}
+		limits = tightest(limits, Limits{MemoryMB: memory, CPUs: cpus})
+	}
+	return limits, nil
+}
+
+func readV2Memory(name string) (int, error) {
+	value, err := readValue(name)
+	if err != nil || value == "" || value == "max" {
+		return 0, err
+	}
+	bytes, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return int(bytes / (1024 * 1024)), nil
+}
+
+func readV2CPU(name string) (float64, error) {
+	value, err := readValue(name)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	// "$MAX $PERIOD", where $MAX may be "max".
+	fields := strings.Fields(value)
+	if fields[0] == "max" {
+		return 0, nil
+	}
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("parse %s: unexpected %q", name, value)
+	}
+	return quota(name, fields[0], fields[1])
+}
+
+// readV1 reads the memory and cpu controllers, each mounted in its own
+// hierarchy.
+func readV1(mount string, groups map[string]string) (Limits, error) {
+	var limits Limits
+	if group, ok := groups["memory"]; ok {
+		for _, dir := range ancestors(filepath.Join(mount, "memory"), group) {
+			value, err := readValue(filepath.Join(dir, "memory.limit_in_bytes"))
+			if err != nil {
+				return Limits{}, err
+			}
+			if value == "" {
+				continue
+			}
+			bytes, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Limits{}, fmt.Errorf("parse memory.limit_in_bytes: %w", err)
+			}
+			if bytes < unlimitedMemory {
+				limits = tightest(limits, Limits{MemoryMB: int(bytes / (1024 * 1024))})
+			}
+		}
+	}
+	if group, ok := groups["cpu"]; ok {
+		dir := filepath.Join(mount, "cpu,cpuacct")
+		if _, err := os.Stat(dir); err != nil {
+			dir = filepath.Join(mount, "cpu")
+		}
+		for _, dir := range ancestors(dir, group) {
+			q, err := readValue(filepath.Join(dir, "cpu.cfs_quota_us"))
+			if err != nil {
+				return Limits{}, err
+			}
+			period, err := readValue(filepath.Join(dir, "cpu.cfs_period_us"))
+			if err != nil {
+				return Limits{}, err
+			}
+			if q == "" || period == "" || q == "-1" {
+				continue
+			}
+			cpus, err := quota("cpu.cfs_quota_us", q, period)
+			if err != nil {
+				return Limits{}, err
+			}
+			limits = tightest(limits, Limits{CPUs: cpus})
+		}
+	}
+	return limits, nil
+}
+
+// quota converts a CFS quota and period in microseconds to cores.
+func quota(name, q, period string) (float64, error) {
+	limit, err := strconv.ParseFloat(q, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, fmt.Errorf("parse %s: invalid period %q", name, period)
+	}
+	return limit / p, nil
+}
+
+// ancestors returns the directories of group and its ancestors under
+// mount, or just mount when group is not visible there.
+func ancestors(mount, group string) []string {
+	group = path.Clean("/" + group)
+	if _, err := os.Stat(filepath.Join(mount, filepath.FromSlash(group))); err != nil {
+		return []string{mount}
+	}
+	var dirs []string
+	for {
+		dirs = append(dirs, filepath.Join(mount, filepath.FromSlash(group)))
+		if group == "/" {
+			return dirs
+		}
+		group = path.Dir(group)
+	}
+}
+
+// readValue returns the trimmed content of a cgroup file, or "" if it
+// does not exist.
+func readValue(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// tightest returns the smaller of each limit, where zero is unlimited.
+func tightest(a, b Limits) Limits {
+	if b.MemoryMB > 0 && (a.MemoryMB == 0 || b.MemoryMB < a.MemoryMB) {
+		a.MemoryMB = b.MemoryMB
+	}
+	if b.CPUs > 0 && (a.CPUs == 0 || b.CPUs < a.CPUs) {
+		a.CPUs = b.CPUs
+	}
+	return a
+}
@@ -0,0 +1,127 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles creates files under root from a map of relative paths to
+// contents.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Limits
+	}{
+		{
+			name:  "no cgroups",
+			files: map[string]string{},
+		},
+		{
+			name: "v2 namespaced",
+			files: map[string]string{
+				"proc/self/cgroup":                 "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory pids\n",
+				"sys/fs/cgroup/memory.max":         "1073741824\n",
+				"sys/fs/cgroup/cpu.max":            "150000 100000\n",
+			},
+			want: Limits{MemoryMB: 1024, CPUs: 1.5},
+		},
+		{
+			name: "v2 unlimited",
+			files: map[string]string{
+				"proc/self/cgroup":                 "0::/\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory\n",
+				"sys/fs/cgroup/memory.max":         "max\n",
+				"sys/fs/cgroup/cpu.max":            "max 100000\n",
+			},
+		},
+		{
+			name: "v2 nested takes the tightest ancestor",
+			files: map[string]string{
+				"proc/self/cgroup":                           "0::/kubepods/pod1/app\n",
+				"sys/fs/cgroup/cgroup.controllers":           "cpu memory\n",
+				"sys/fs/cgroup/kubepods/memory.max":          "4294967296\n",
+				"sys/fs/cgroup/kubepods/pod1/memory.max":     "536870912\n",
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":        "200000 100000\n",
+				"sys/fs/cgroup/kubepods/pod1/app/memory.max": "max\n",
+				"sys/fs/cgroup/kubepods/pod1/app/cpu.max":    "50000 100000\n",
+			},
+			want: Limits{MemoryMB: 512, CPUs: 0.5},
+		},
+		{
+			name: "v1",
+			files: map[string]string{
+				"proc/self/cgroup": "12:memory:/docker/abc\n" +
+					"11:cpu,cpuacct:/docker/abc\n" +
+					"10:pids:/docker/abc\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes":  "268435456\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "200000\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+			want: Limits{MemoryMB: 256, CPUs: 2},
+		},
+		{
+			name: "v1 unlimited",
+			files: map[string]string{
+				"proc/self/cgroup": "12:memory:/\n" +
+					"11:cpu,cpuacct:/\n",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes":  "9223372036854771712\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":  "-1\n",
+				"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us": "100000\n",
+			},
+		},
+		{
+			name: "v1 separate cpu hierarchy",
+			files: map[string]string{
+				"proc/self/cgroup":                    "4:cpu:/\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":  "25000\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n",
+			},
+			want: Limits{CPUs: 0.25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			got, err := ReadFrom(root)
+			if err != nil {
+				t.Fatalf("ReadFrom() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFromInvalid(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"proc/self/cgroup":                 "0::/\n",
+		"sys/fs/cgroup/cgroup.controllers": "memory\n",
+		"sys/fs/cgroup/memory.max":         "lots\n",
+	})
+
+	if _, err := ReadFrom(root); err == nil {
+		t.Error("ReadFrom() should fail on an unparsable limit")
+	}
+}
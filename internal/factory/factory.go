@@ -38,7 +38,7 @@ func (f *SandboxFactory) CreateSandbox(ctx context.Context, providerName string,
 				msg += fmt.Sprintf("  - %s\n", p)
 			}
 		}
-		return nil, fmt.Errorf(msg)
+		return nil, fmt.Errorf("%s", msg)
 	}
 
 	p, err := f.registry.Get(providerName, providerConfig)
@@ -94,6 +94,12 @@ func (f *SandboxFactory) Close() error {
 	return f.registry.Close()
 }
 
+// CloseGraceful closes all providers, waiting up to ctx for in-flight
+// executions on providers that support it.
+func (f *SandboxFactory) CloseGraceful(ctx context.Context) error {
+	return f.registry.CloseGraceful(ctx)
+}
+
 // Global factory instance
 var globalFactory = NewDefaultFactory()
 
@@ -2,6 +2,7 @@
 package factory
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
@@ -74,6 +75,31 @@ func (r *Registry) Get(name string, config any) (provider.Provider, error) {
 	return p, nil
 }
 
+// ProbeCapabilities returns a provider's capabilities without caching a
+// provider: a cached one is used if present, otherwise one is built with a
+// nil config and closed again, so a later Get still sees the caller's
+// config.
+func (r *Registry) ProbeCapabilities(name string) (provider.Capabilities, error) {
+	r.mu.RLock()
+	p, cached := r.providers[name]
+	constructor, ok := r.constructors[name]
+	r.mu.RUnlock()
+
+	if cached {
+		return p.Capabilities(), nil
+	}
+	if !ok {
+		return provider.Capabilities{}, fmt.Errorf("provider %q not registered", name)
+	}
+
+	p, err := constructor(nil)
+	if err != nil {
+		return provider.Capabilities{}, fmt.Errorf("failed to create provider %q: %w", name, err)
+	}
+	defer p.Close()
+	return p.Capabilities(), nil
+}
+
 // GetConstructor retrieves a provider constructor.
 func (r *Registry) GetConstructor(name string) (ProviderConstructor, bool) {
 	r.mu.RLock()
@@ -118,6 +144,43 @@ func (r *Registry) Close() error {
 	return lastErr
 }
 
+// CloseGraceful closes all provider instances like Close, but lets
+// providers implementing provider.GracefulCloser finish in-flight
+// executions first. Providers are closed concurrently, all bounded by ctx;
+// those that cannot wait are closed immediately.
+func (r *Registry) CloseGraceful(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		wg      sync.WaitGroup
+		errMu   sync.Mutex
+		lastErr error
+	)
+	for name, p := range r.providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var err error
+			if gc, ok := p.(provider.GracefulCloser); ok {
+				err = gc.CloseGraceful(ctx)
+			} else {
+				err = p.Close()
+			}
+			if err != nil {
+				errMu.Lock()
+				lastErr = fmt.Errorf("failed to close provider %q: %w", name, err)
+				errMu.Unlock()
+			}
+		}()
+		delete(r.providers, name)
+	}
+	wg.Wait()
+
+	return lastErr
+}
+
 // DefaultRegistry is the global provider registry.
 var DefaultRegistry = NewRegistry()
 
@@ -136,6 +199,12 @@ func Get(name string, config any) (provider.Provider, error) {
 	return DefaultRegistry.Get(name, config)
 }
 
+// ProbeCapabilities returns a provider's capabilities from the default
+// registry without caching it.
+func ProbeCapabilities(name string) (provider.Capabilities, error) {
+	return DefaultRegistry.ProbeCapabilities(name)
+}
+
 // Available returns all available providers from the default registry.
 func Available() []string {
 	return DefaultRegistry.Available()
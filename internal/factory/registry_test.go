@@ -177,6 +177,42 @@ func TestRegistryGetConstructor(t *testing.T) {
 	}
 }
 
+func TestRegistryProbeCapabilities(t *testing.T) {
+	r := NewRegistry()
+
+	var built []*testProvider
+	r.Register("test", func(config any) (provider.Provider, error) {
+		p := &testProvider{name: "test"}
+		built = append(built, p)
+		return p, nil
+	})
+
+	caps, err := r.ProbeCapabilities("test")
+	if err != nil {
+		t.Fatalf("ProbeCapabilities() error = %v", err)
+	}
+	if len(caps.SupportedLanguages) != 1 || caps.SupportedLanguages[0] != "Python" {
+		t.Errorf("SupportedLanguages = %v", caps.SupportedLanguages)
+	}
+	if len(built) != 1 || !built[0].closed {
+		t.Error("probed provider should be closed")
+	}
+	if _, ok := r.providers["test"]; ok {
+		t.Error("probed provider should not be cached")
+	}
+
+	// A cached provider is used as is.
+	p, _ := r.Get("test", nil)
+	r.ProbeCapabilities("test")
+	if len(built) != 2 || p.(*testProvider).closed {
+		t.Error("ProbeCapabilities() should use the cached provider")
+	}
+
+	if _, err := r.ProbeCapabilities("nonexistent"); err == nil {
+		t.Error("ProbeCapabilities() should fail for unregistered provider")
+	}
+}
+
 func TestRegistryAvailable(t *testing.T) {
 	r := NewRegistry()
 
@@ -243,6 +279,57 @@ func TestRegistryClose(t *testing.T) {
 	}
 }
 
+// gracefulProvider records how it was closed.
+type gracefulProvider struct {
+	testProvider
+	closeCtx context.Context
+}
+
+func (p *gracefulProvider) CloseGraceful(ctx context.Context) error {
+	p.closeCtx = ctx
+	return nil
+}
+
+func TestRegistryCloseGraceful(t *testing.T) {
+	r := NewRegistry()
+
+	plain := &testProvider{name: "plain"}
+	graceful := &gracefulProvider{testProvider: testProvider{name: "graceful"}}
+
+	r.Register("plain", func(config any) (provider.Provider, error) {
+		return plain, nil
+	})
+	r.Register("graceful", func(config any) (provider.Provider, error) {
+		return graceful, nil
+	})
+	r.Get("plain", nil)
+	r.Get("graceful", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.CloseGraceful(ctx); err != nil {
+		t.Fatalf("CloseGraceful() error = %v", err)
+	}
+
+	if !plain.closed {
+		t.Error("providers without CloseGraceful should be closed")
+	}
+	if graceful.closeCtx != ctx {
+		t.Error("CloseGraceful should be used when the provider supports it")
+	}
+	if graceful.closed {
+		t.Error("Close should not be called on a gracefully closed provider")
+	}
+
+	// Closed providers are dropped, so the next Get builds a new one.
+	r.mu.RLock()
+	n := len(r.providers)
+	r.mu.RUnlock()
+	if n != 0 {
+		t.Errorf("%d providers cached after CloseGraceful, want 0", n)
+	}
+}
+
 func TestRegistryConcurrentAccess(t *testing.T) {
 	r := NewRegistry()
 
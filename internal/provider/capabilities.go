@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// CapabilityRequest describes what a job needs from a provider.
+// Zero values impose no requirement.
+type CapabilityRequest struct {
+	// Language is the programming language to run.
+	Language string
+
+	// MemoryMB is the memory the job needs in megabytes.
+	MemoryMB int
+
+	// CPUs is the CPU count the job needs.
+	CPUs int
+
+	// ExecutionTime is how long the job may run.
+	ExecutionTime time.Duration
+
+	// Streaming, Async, FileSystem, Network, GPU, Persistence,
+	// RangeDownload, NetworkCapture, TailFile, Archive, Pause,
+	// CommandWrapper, InteractiveStdin, Coverage, InterpreterPath,
+	// PathGrants, ImageEntrypoint, ProgramArgs, DeterministicID,
+	// SyscallFilter, RawStream, CPUSet and EphemeralWorkDir require the
+	// matching Supports* capability.
+	Streaming        bool
+	Async            bool
+	FileSystem       bool
+	Network          bool
+	GPU              bool
+	Persistence      bool
+	RangeDownload    bool
+	NetworkCapture   bool
+	TailFile         bool
+	Archive          bool
+	Pause            bool
+	CommandWrapper   bool
+	InteractiveStdin bool
+	Coverage         bool
+	InterpreterPath  bool
+	PathGrants       bool
+	ImageEntrypoint  bool
+	ProgramArgs      bool
+	DeterministicID  bool
+	SyscallFilter    bool
+	RawStream        bool
+	CPUSet           bool
+	EphemeralWorkDir bool
+}
+
+// Can reports whether the capabilities satisfy req, and lists the unmet
+// requirements when they don't. Zero limits in c are treated as unknown
+// and never fail a request, as is an empty SupportedLanguages list.
+func (c Capabilities) Can(req CapabilityRequest) (bool, []string) {
+	var unmet []string
+
+	if req.Language != "" && len(c.SupportedLanguages) > 0 && !c.SupportsLanguage(req.Language) {
+		unmet = append(unmet, fmt.Sprintf("language %q not supported", req.Language))
+	}
+	if req.MemoryMB > 0 && c.MaxMemoryMB > 0 && req.MemoryMB > c.MaxMemoryMB {
+		unmet = append(unmet, fmt.Sprintf("memory %dMB exceeds limit of %dMB", req.MemoryMB, c.MaxMemoryMB))
+	}
+	if req.CPUs > 0 && c.MaxCPUs > 0 && req.CPUs > c.MaxCPUs {
+		unmet = append(unmet, fmt.Sprintf("%d CPUs exceeds limit of %d", req.CPUs, c.MaxCPUs))
+	}
+	if req.ExecutionTime > 0 && c.MaxExecutionTime > 0 && req.ExecutionTime > c.MaxExecutionTime {
+		unmet = append(unmet, fmt.Sprintf("execution time %v exceeds limit of %v", req.ExecutionTime, c.MaxExecutionTime))
+	}
+
+	features := []struct {
+		name      string
+		required  bool
+		supported bool
+	}{
+		{"streaming", req.Streaming, c.SupportsStreaming},
+		{"async execution", req.Async, c.SupportsAsync},
+		{"file system", req.FileSystem, c.SupportsFileSystem},
+		{"network", req.Network, c.SupportsNetwork},
+		{"GPU", req.GPU, c.SupportsGPU},
+		{"persistence", req.Persistence, c.SupportsPersistence},
+		{"range downloads", req.RangeDownload, c.SupportsRangeDownload},
+		{"network capture", req.NetworkCapture, c.SupportsNetworkCapture},
+		{"file tailing", req.TailFile, c.SupportsTailFile},
+		{"filesystem archives", req.Archive, c.SupportsArchive},
+		{"pause", req.Pause, c.SupportsPause},
+		{"command wrappers", req.CommandWrapper, c.SupportsCommandWrapper},
+		{"interactive stdin", req.InteractiveStdin, c.SupportsInteractiveStdin},
+		{"coverage", req.Coverage, c.SupportsCoverage},
+		{"interpreter paths", req.InterpreterPath, c.SupportsInterpreterPath},
+		{"path grants", req.PathGrants, c.SupportsPathGrants},
+		{"image entrypoints", req.ImageEntrypoint, c.SupportsImageEntrypoint},
+		{"program arguments", req.ProgramArgs, c.SupportsProgramArgs},
+		{"deterministic IDs", req.DeterministicID, c.SupportsDeterministicID},
+		{"syscall filters", req.SyscallFilter, c.SupportsSyscallFilter},
+		{"raw streams", req.RawStream, c.SupportsRawStream},
+		{"CPU sets", req.CPUSet, c.SupportsCPUSet},
+		{"ephemeral workdirs", req.EphemeralWorkDir, c.SupportsEphemeralWorkDir},
+	}
+	for _, f := range features {
+		if f.required && !f.supported {
+			unmet = append(unmet, f.name+" not supported")
+		}
+	}
+
+	return len(unmet) == 0, unmet
+}
+
+// SupportsLanguage reports whether language is in SupportedLanguages,
+// ignoring case and treating aliases known to langdetect, such as "Bash"
+// and "Shell", as the same language.
+func (c Capabilities) SupportsLanguage(language string) bool {
+	canonical := func(l string) string {
+		if info, ok := langdetect.GetRuntimeInfo(l); ok {
+			return info.Language
+		}
+		return l
+	}
+	want := canonical(language)
+	for _, l := range c.SupportedLanguages {
+		if strings.EqualFold(canonical(l), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCapabilitiesCan(t *testing.T) {
+	caps := Capabilities{
+		SupportsStreaming:  true,
+		SupportedLanguages: []string{"Python", "Go"},
+		MaxMemoryMB:        1024,
+		MaxCPUs:            2,
+		MaxExecutionTime:   time.Minute,
+	}
+
+	tests := []struct {
+		name  string
+		req   CapabilityRequest
+		unmet []string
+	}{
+		{"satisfied", CapabilityRequest{Language: "python", Streaming: true, MemoryMB: 512}, nil},
+		{"language not supported", CapabilityRequest{Language: "Rust"}, []string{`language "Rust" not supported`}},
+		{"language alias", CapabilityRequest{Language: "py"}, nil},
+		{"memory too high", CapabilityRequest{MemoryMB: 2048}, []string{"memory 2048MB exceeds limit of 1024MB"}},
+		{"network unsupported", CapabilityRequest{Network: true}, []string{"network not supported"}},
+		{"network capture unsupported", CapabilityRequest{NetworkCapture: true}, []string{"network capture not supported"}},
+		{"file tailing unsupported", CapabilityRequest{TailFile: true}, []string{"file tailing not supported"}},
+		{"archive unsupported", CapabilityRequest{Archive: true}, []string{"filesystem archives not supported"}},
+		{"pause unsupported", CapabilityRequest{Pause: true}, []string{"pause not supported"}},
+		{"command wrapper unsupported", CapabilityRequest{CommandWrapper: true}, []string{"command wrappers not supported"}},
+		{"interactive stdin unsupported", CapabilityRequest{InteractiveStdin: true}, []string{"interactive stdin not supported"}},
+		{"coverage unsupported", CapabilityRequest{Coverage: true}, []string{"coverage not supported"}},
+		{"interpreter path unsupported", CapabilityRequest{InterpreterPath: true}, []string{"interpreter paths not supported"}},
+		{"path grants unsupported", CapabilityRequest{PathGrants: true}, []string{"path grants not supported"}},
+		{"image entrypoint unsupported", CapabilityRequest{ImageEntrypoint: true}, []string{"image entrypoints not supported"}},
+		{"program arguments unsupported", CapabilityRequest{ProgramArgs: true}, []string{"program arguments not supported"}},
+		{"deterministic ID unsupported", CapabilityRequest{DeterministicID: true}, []string{"deterministic IDs not supported"}},
+		{"syscall filter unsupported", CapabilityRequest{SyscallFilter: true}, []string{"syscall filters not supported"}},
+		{"raw stream unsupported", CapabilityRequest{RawStream: true}, []string{"raw streams not supported"}},
+		{"cpuset unsupported", CapabilityRequest{CPUSet: true}, []string{"CPU sets not supported"}},
+		{"ephemeral workdir unsupported", CapabilityRequest{EphemeralWorkDir: true}, []string{"ephemeral workdirs not supported"}},
+		{"several unmet", CapabilityRequest{CPUs: 4, ExecutionTime: time.Hour, GPU: true}, []string{
+			"4 CPUs exceeds limit of 2",
+			"execution time 1h0m0s exceeds limit of 1m0s",
+			"GPU not supported",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, unmet := caps.Can(tt.req)
+			if ok != (len(tt.unmet) == 0) {
+				t.Errorf("Can() ok = %v, want %v", ok, len(tt.unmet) == 0)
+			}
+			if !reflect.DeepEqual(unmet, tt.unmet) {
+				t.Errorf("Can() unmet = %q, want %q", unmet, tt.unmet)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesCanUnknownLimits(t *testing.T) {
+	ok, unmet := Capabilities{}.Can(CapabilityRequest{Language: "Rust", MemoryMB: 8192})
+	if !ok {
+		t.Errorf("Can() with unknown limits = %v, want satisfied", unmet)
+	}
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// CoverageDirName is the directory, under the working directory, that a
+// coverage run keeps its data and report in.
+const CoverageDirName = ".sindoq-coverage"
+
+// CoverageRun describes how to run code under its language's coverage
+// tool, for providers implementing ExecutionOptions.Coverage. Commands run
+// in the working directory with Env added to the environment.
+type CoverageRun struct {
+	// Tool names the coverage tool for errors.
+	Tool string
+
+	// Check exits zero when the tool is installed.
+	Check []string
+
+	// Run replaces the language's run command; the code file is appended.
+	Run []string
+
+	// Report writes ReportFile after the run. It is nil when Run writes
+	// the report itself.
+	Report []string
+
+	// ReportFile is the report the coverage is read from.
+	ReportFile string
+
+	Env map[string]string
+
+	percent func(report []byte) (float64, error)
+}
+
+// NewCoverageRun returns the coverage run for language, keeping its data
+// in dir, or an error if the language has no supported coverage tool.
+func NewCoverageRun(language, dir string) (*CoverageRun, error) {
+	switch language {
+	case "Python":
+		return &CoverageRun{
+			Tool:       "coverage.py",
+			Check:      []string{"python3", "-c", "import coverage"},
+			Run:        []string{"python3", "-m", "coverage", "run"},
+			Report:     []string{"python3", "-m", "coverage", "json", "-q", "-o", dir + "/coverage.json"},
+			ReportFile: dir + "/coverage.json",
+			Env:        map[string]string{"COVERAGE_FILE": dir + "/.coverage"},
+			percent:    coveragePyPercent,
+		}, nil
+	case "Go":
+		return &CoverageRun{
+			Tool:       "go 1.20 or later",
+			Check:      []string{"go", "tool", "-n", "covdata"},
+			Run:        []string{"go", "run", "-cover"},
+			Report:     []string{"go", "tool", "covdata", "textfmt", "-i=" + dir, "-o=" + dir + "/coverage.out"},
+			ReportFile: dir + "/coverage.out",
+			Env:        map[string]string{"GOCOVERDIR": dir},
+			percent:    goProfilePercent,
+		}, nil
+	case "JavaScript":
+		return &CoverageRun{
+			Tool:       "c8",
+			Check:      []string{"c8", "--version"},
+			Run:        []string{"c8", "--reporter=json-summary", "--reports-dir=" + dir, "--temp-directory=" + dir + "/tmp", "node"},
+			ReportFile: dir + "/coverage-summary.json",
+			percent:    c8Percent,
+		}, nil
+	}
+	return nil, fmt.Errorf("coverage is not supported for %s (Python, Go and JavaScript are)", language)
+}
+
+// MissingToolError is the error for an image without the coverage tool.
+func (c *CoverageRun) MissingToolError() error {
+	return fmt.Errorf("coverage: %s is not installed in the image", c.Tool)
+}
+
+// Coverage builds the result's coverage from the report.
+func (c *CoverageRun) Coverage(report []byte) (*executor.Coverage, error) {
+	percent, err := c.percent(report)
+	if err != nil {
+		return nil, fmt.Errorf("coverage: parse %s report: %w", c.Tool, err)
+	}
+	return &executor.Coverage{Percent: percent, Profile: report}, nil
+}
+
+// coveragePyPercent reads the total from a coverage.py JSON report.
+func coveragePyPercent(report []byte) (float64, error) {
+	var r struct {
+		Totals *struct {
+			PercentCovered float64 `json:"percent_covered"`
+		} `json:"totals"`
+	}
+	if err := json.Unmarshal(report, &r); err != nil {
+		return 0, err
+	}
+	if r.Totals == nil {
+		return 0, fmt.Errorf("no totals")
+	}
+	return r.Totals.PercentCovered, nil
+}
+
+// c8Percent reads the line total from an istanbul JSON summary.
+func c8Percent(report []byte) (float64, error) {
+	var r struct {
+		Total *struct {
+			Lines struct {
+				Pct float64 `json:"pct"`
+			} `json:"lines"`
+		} `json:"total"`
+	}
+	if err := json.Unmarshal(report, &r); err != nil {
+		return 0, err
+	}
+	if r.Total == nil {
+		return 0, fmt.Errorf("no total")
+	}
+	return r.Total.Lines.Pct, nil
+}
+
+// goProfilePercent computes the share of statements run from a Go cover
+// profile, as go tool cover -func does.
+func goProfilePercent(report []byte) (float64, error) {
+	// Blocks may repeat across counter files; a block ran if any says so.
+	type block struct {
+		stmts int
+		ran   bool
+	}
+	blocks := make(map[string]*block)
+
+	sc := bufio.NewScanner(bytes.NewReader(report))
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return 0, fmt.Errorf("malformed line %q", line)
+		}
+		stmts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("malformed line %q", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, fmt.Errorf("malformed line %q", line)
+		}
+		b := blocks[fields[0]]
+		if b == nil {
+			b = &block{stmts: stmts}
+			blocks[fields[0]] = b
+		}
+		b.ran = b.ran || count > 0
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	var total, ran int
+	for _, b := range blocks {
+		total += b.stmts
+		if b.ran {
+			ran += b.stmts
+		}
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return 100 * float64(ran) / float64(total), nil
+}
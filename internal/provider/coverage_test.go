@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewCoverageRun(t *testing.T) {
+	for _, language := range []string{"Python", "Go", "JavaScript"} {
+		cov, err := NewCoverageRun(language, "/workspace/"+CoverageDirName)
+		if err != nil {
+			t.Fatalf("NewCoverageRun(%s) error = %v", language, err)
+		}
+		if len(cov.Check) == 0 || len(cov.Run) == 0 || !strings.HasPrefix(cov.ReportFile, "/workspace/"+CoverageDirName+"/") {
+			t.Errorf("NewCoverageRun(%s) = %+v", language, cov)
+		}
+	}
+
+	if _, err := NewCoverageRun("Ruby", "/tmp/cov"); err == nil || !strings.Contains(err.Error(), "Ruby") {
+		t.Errorf("NewCoverageRun(Ruby) error = %v, want unsupported language", err)
+	}
+}
+
+func TestCoveragePercent(t *testing.T) {
+	tests := []struct {
+		language string
+		report   string
+		want     float64
+	}{
+		{"Python", `{"meta": {"version": "7.6.1"}, "files": {}, "totals": {"covered_lines": 3, "num_statements": 4, "percent_covered": 75.0}}`, 75},
+		{"JavaScript", `{"total": {"lines": {"total": 8, "covered": 6, "skipped": 0, "pct": 75}}}`, 75},
+		{"Go", "mode: set\n" +
+			"command-line-arguments/main.go:5.13,7.2 1 1\n" +
+			"command-line-arguments/main.go:9.13,10.12 2 0\n" +
+			"command-line-arguments/main.go:12.2,12.20 1 1\n" +
+			"command-line-arguments/main.go:9.13,10.12 2 1\n", 100},
+		{"Go", "mode: set\nmain.go:1.1,2.2 3 1\nmain.go:3.1,4.2 1 0\n", 75},
+		{"Go", "mode: set\n", 0},
+	}
+	for _, tt := range tests {
+		cov, _ := NewCoverageRun(tt.language, "/tmp/cov")
+		got, err := cov.Coverage([]byte(tt.report))
+		if err != nil {
+			t.Fatalf("Coverage(%s) error = %v", tt.language, err)
+		}
+		if got.Percent != tt.want || string(got.Profile) != tt.report {
+			t.Errorf("Coverage(%s) = %v%%, want %v%%", tt.language, got.Percent, tt.want)
+		}
+	}
+
+	for _, language := range []string{"Python", "JavaScript", "Go"} {
+		cov, _ := NewCoverageRun(language, "/tmp/cov")
+		if _, err := cov.Coverage([]byte("{}\nnot a report")); err == nil {
+			t.Errorf("Coverage(%s) of a malformed report should fail", language)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUSet parses a cpuset list such as "0-3,6" into its sorted,
+// de-duplicated core numbers.
+func ParseCPUSet(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid cpuset %q", s)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(hi)
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid cpuset %q", s)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	slices.Sort(cpus)
+	return slices.Compact(cpus), nil
+}
+
+// ResolveCPUSet returns the cores a sandbox is pinned to: the sandbox's
+// own set, which must lie within the provider's, or the provider's when
+// the sandbox sets none. Either may be empty, meaning all host cores.
+func ResolveCPUSet(providerSet, sandboxSet string) (string, error) {
+	if providerSet != "" {
+		if _, err := ParseCPUSet(providerSet); err != nil {
+			return "", err
+		}
+	}
+	if sandboxSet == "" {
+		return providerSet, nil
+	}
+	cpus, err := ParseCPUSet(sandboxSet)
+	if err != nil {
+		return "", err
+	}
+	if providerSet != "" {
+		allowed, _ := ParseCPUSet(providerSet)
+		for _, cpu := range cpus {
+			if _, found := slices.BinarySearch(allowed, cpu); !found {
+				return "", fmt.Errorf("cpuset %q is not within the provider's cpuset %q", sandboxSet, providerSet)
+			}
+		}
+	}
+	return sandboxSet, nil
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{"0", []int{0}, false},
+		{"0-3,6", []int{0, 1, 2, 3, 6}, false},
+		{"6, 2-3,2", []int{2, 3, 6}, false},
+		{"", nil, true},
+		{"3-1", nil, true},
+		{"-1", nil, true},
+		{"a", nil, true},
+		{"0,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCPUSet(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCPUSet(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseCPUSet(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCPUSet(t *testing.T) {
+	tests := []struct {
+		name              string
+		provider, sandbox string
+		want              string
+		wantErr           bool
+	}{
+		{"neither", "", "", "", false},
+		{"provider only", "0-3", "", "0-3", false},
+		{"sandbox only", "", "5", "5", false},
+		{"subset", "0-3", "1,3", "1,3", false},
+		{"not a subset", "0-3", "2-4", "", true},
+		{"invalid provider", "x", "", "", true},
+		{"invalid sandbox", "0-3", "3-2", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveCPUSet(tt.provider, tt.sandbox)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveCPUSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveCPUSet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
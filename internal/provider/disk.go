@@ -0,0 +1,20 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDiskLimitUnsupported is returned by Create when ResourceConfig.DiskMB
+// is set but the provider, or its host, cannot enforce it.
+var ErrDiskLimitUnsupported = errors.New("disk limit not enforceable")
+
+// RejectDiskLimit returns ErrDiskLimitUnsupported if resources set a disk
+// limit. Providers with no way to cap storage use it to fail fast instead
+// of ignoring DiskMB.
+func RejectDiskLimit(providerName string, resources ResourceConfig) error {
+	if resources.DiskMB > 0 {
+		return fmt.Errorf("%s provider: %w", providerName, ErrDiskLimitUnsupported)
+	}
+	return nil
+}
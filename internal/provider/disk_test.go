@@ -0,0 +1,15 @@
+package provider
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRejectDiskLimit(t *testing.T) {
+	if err := RejectDiskLimit("wasmer", ResourceConfig{}); err != nil {
+		t.Errorf("no disk limit should be accepted: %v", err)
+	}
+	if err := RejectDiskLimit("wasmer", ResourceConfig{DiskMB: 512}); !errors.Is(err, ErrDiskLimitUnsupported) {
+		t.Errorf("disk limit error = %v, want ErrDiskLimitUnsupported", err)
+	}
+}
@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/happyhackingspace/sindoq/internal/provider/dockerapi"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// ExportTar streams dir out of the container.
+func (d *dockerFS) ExportTar(ctx context.Context, dir string, w io.Writer) error {
+	return dockerapi.ExportTar(ctx, d.instance.client, d.instance.id, dir, w)
+}
+
+// ImportTar streams r into dir, creating it first.
+func (d *dockerFS) ImportTar(ctx context.Context, dir string, r io.Reader) error {
+	if err := d.MkDir(ctx, dir); err != nil {
+		return err
+	}
+	return dockerapi.ImportTar(ctx, d.instance.client, d.instance.id, dir, r)
+}
+
+var _ fs.Archiver = (*dockerFS)(nil)
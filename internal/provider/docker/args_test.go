@@ -0,0 +1,45 @@
+//go:build integration
+
+package docker_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
+)
+
+// TestWithArgs checks that program arguments reach interpreted and compiled
+// programs as distinct argv entries.
+func TestWithArgs(t *testing.T) {
+	args := []string{"one", "two words", `it's "quoted"`}
+	tests := []struct {
+		language string
+		code     string
+	}{
+		{"Python", "import sys\nfor arg in sys.argv[1:]:\n    print(arg)\n"},
+		{"JavaScript", "for (const arg of process.argv.slice(2)) console.log(arg);\n"},
+		{"Go", "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nfunc main() {\n\tfor _, arg := range os.Args[1:] {\n\t\tfmt.Println(arg)\n\t}\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			ctx := context.Background()
+			sb, err := sindoq.Create(ctx, sindoq.WithProvider("docker"), sindoq.WithRuntime(tt.language))
+			if err != nil {
+				t.Skipf("Docker not available: %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			result, err := sb.Execute(ctx, tt.code, sindoq.WithLanguage(tt.language), sindoq.WithArgs(args))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Split(strings.TrimSpace(result.Stdout), "\n"); !slices.Equal(got, args) {
+				t.Errorf("argv = %q, want %q (stderr %s)", got, args, result.Stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	// defaultCaptureImage provides tcpdump when Config.CaptureImage is unset.
+	defaultCaptureImage = "nicolaka/netshoot:v0.13"
+
+	// capturePath is where the sidecar writes the pcap.
+	capturePath = "/tmp/capture.pcap"
+
+	// captureReadyTimeout bounds the wait for tcpdump to start listening.
+	captureReadyTimeout = 10 * time.Second
+
+	// captureStopTimeout bounds stopping the sidecar and copying the pcap
+	// out, which runs even after the execution context has ended.
+	captureStopTimeout = 30 * time.Second
+)
+
+// startCapture runs tcpdump in a sidecar container that joins the
+// instance's network namespace, so it sees exactly the sandbox's traffic on
+// every interface (loopback included) without changing its network. It
+// returns once tcpdump is listening, with a func that stops the sidecar and
+// returns the pcap. The stop func must always be called; it ignores
+// cancellation of ctx so a timed-out run still yields its capture.
+func (i *Instance) startCapture(ctx context.Context) (func() ([]byte, error), error) {
+	imageName := i.config.CaptureImage
+	if imageName == "" {
+		imageName = defaultCaptureImage
+	}
+	if err := i.ensureImage(ctx, imageName); err != nil {
+		return nil, fmt.Errorf("network capture: %w", err)
+	}
+
+	resp, err := i.client.ContainerCreate(ctx, &container.Config{
+		Image: imageName,
+		// -U writes each packet as it arrives so nothing is lost to
+		// buffering if the sidecar has to be killed.
+		Cmd:    []string{"tcpdump", "-i", "any", "-U", "-w", capturePath},
+		Labels: map[string]string{"sindoq.capture-for": i.id},
+	}, &container.HostConfig{
+		NetworkMode: container.NetworkMode("container:" + i.id),
+		CapAdd:      []string{"NET_ADMIN", "NET_RAW"},
+	}, nil, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("network capture: create sidecar: %w", err)
+	}
+	id := resp.ID
+
+	stop := func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), captureStopTimeout)
+		defer cancel()
+		defer i.client.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+
+		// SIGINT makes tcpdump close the file cleanly.
+		waitC, errC := i.client.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+		if err := i.client.ContainerKill(ctx, id, "SIGINT"); err != nil && !strings.Contains(err.Error(), "is not running") {
+			return nil, fmt.Errorf("network capture: stop sidecar: %w", err)
+		}
+		select {
+		case <-waitC:
+		case err := <-errC:
+			return nil, fmt.Errorf("network capture: wait for sidecar: %w", err)
+		}
+
+		pcap, err := i.copyCapture(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("network capture: %w", err)
+		}
+		return pcap, nil
+	}
+
+	if err := i.client.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
+		stop()
+		return nil, fmt.Errorf("network capture: start sidecar: %w", err)
+	}
+	if err := i.waitCaptureReady(ctx, id); err != nil {
+		stop()
+		return nil, fmt.Errorf("network capture: %w", err)
+	}
+	return stop, nil
+}
+
+// waitCaptureReady polls the sidecar's logs until tcpdump reports that it
+// is listening, so traffic from the start of the run is not missed.
+func (i *Instance) waitCaptureReady(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, captureReadyTimeout)
+	defer cancel()
+
+	for {
+		logs, err := i.client.ContainerLogs(ctx, id, container.LogsOptions{ShowStderr: true})
+		if err != nil {
+			return fmt.Errorf("read sidecar logs: %w", err)
+		}
+		out, _ := io.ReadAll(logs)
+		logs.Close()
+		if strings.Contains(string(out), "listening on") {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("tcpdump did not start: %s", strings.TrimSpace(string(out)))
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// copyCapture reads the pcap out of the stopped sidecar.
+func (i *Instance) copyCapture(ctx context.Context, id string) ([]byte, error) {
+	reader, _, err := i.client.CopyFromContainer(ctx, id, capturePath)
+	if err != nil {
+		return nil, fmt.Errorf("copy pcap: %w", err)
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("read pcap: %w", err)
+	}
+	return io.ReadAll(tr)
+}
@@ -0,0 +1,62 @@
+//go:build integration
+
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// affinityProbe prints the cores the process may run on.
+const affinityProbe = `import os
+print(",".join(str(c) for c in sorted(os.sched_getaffinity(0))))`
+
+func TestProviderCPUSet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	cfg := DefaultConfig()
+	cfg.CPUSet = "0"
+	p, err := New(cfg)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+	if err := p.Validate(ctx); err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+
+	t.Run("pinned", func(t *testing.T) {
+		instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		defer instance.Stop(ctx)
+
+		result, err := instance.Execute(ctx, affinityProbe, &executor.ExecutionOptions{
+			Language: "Python",
+			Timeout:  30 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if got := strings.TrimSpace(result.Stdout); got != "0" {
+			t.Errorf("affinity = %q (stderr %q), want %q", got, result.Stderr, "0")
+		}
+	})
+
+	t.Run("outside provider cpuset", func(t *testing.T) {
+		_, err := p.Create(ctx, &provider.CreateOptions{
+			Runtime:   "Python",
+			Resources: provider.ResourceConfig{CPUSet: "0-1"},
+		})
+		if err == nil {
+			t.Fatal("Create() with a cpuset outside the provider's should fail")
+		}
+	})
+}
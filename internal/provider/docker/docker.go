@@ -4,19 +4,26 @@ package docker
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"path"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/provider/dockerapi"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 	"github.com/happyhackingspace/sindoq/pkg/langdetect"
@@ -41,8 +48,31 @@ type Config struct {
 	CertPath     string
 	RegistryAuth map[string]string
 	DefaultImage string
+
+	// DisableTimeoutWrapper skips wrapping commands with the coreutils
+	// timeout binary. Set it for images that do not ship timeout.
+	DisableTimeoutWrapper bool
+
+	// ValidateRetries is how many times Validate retries a failed ping,
+	// e.g. while the daemon is still starting. Zero pings once.
+	ValidateRetries int
+
+	// ValidateRetryDelay is the wait between ping attempts. Defaults to
+	// 500ms.
+	ValidateRetryDelay time.Duration
+
+	// CaptureImage provides tcpdump for network capture. Defaults to
+	// nicolaka/netshoot.
+	CaptureImage string
+
+	// CPUSet pins every container to these host cores, such as "0-3".
+	// A sandbox's ResourceConfig.CPUSet narrows it further.
+	CPUSet string
 }
 
+// defaultValidateRetryDelay is used when ValidateRetryDelay is unset.
+const defaultValidateRetryDelay = 500 * time.Millisecond
+
 // DefaultConfig returns default Docker configuration.
 func DefaultConfig() *Config {
 	return &Config{
@@ -97,17 +127,23 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		opts = provider.DefaultCreateOptions()
 	}
 
-	// Determine image
-	image := opts.Image
-	if image == "" {
-		// Try to get image from runtime
-		if opts.Runtime != "" {
-			if info, ok := langdetect.GetRuntimeInfo(opts.Runtime); ok {
-				image = info.DockerImage
-			}
-		}
-		if image == "" {
-			image = p.config.DefaultImage
+	if opts.Polyglot && opts.Image == "" {
+		return nil, fmt.Errorf("polyglot sandbox requires an image")
+	}
+
+	cpuset, err := provider.ResolveCPUSet(p.config.CPUSet, opts.Resources.CPUSet)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := p.resolveImage(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ID != "" {
+		if inst, err := p.reattach(ctx, image, opts); inst != nil || err != nil {
+			return inst, err
 		}
 	}
 
@@ -116,59 +152,205 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		return nil, fmt.Errorf("ensure image: %w", err)
 	}
 
-	// Build environment variables
-	env := make([]string, 0, len(opts.Environment))
-	for k, v := range opts.Environment {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-
 	// Container configuration
 	containerConfig := &container.Config{
 		Image:        image,
-		Env:          env,
+		Env:          provider.MergeEnv(opts.Environment, nil),
 		WorkingDir:   opts.WorkDir,
 		AttachStdout: true,
 		AttachStderr: true,
 		Tty:          false,
 		Labels:       opts.Labels,
 		// Keep container running
-		Entrypoint: []string{"tail", "-f", "/dev/null"},
+		Entrypoint: keepAliveCmd,
+	}
+	if opts.RespectEntrypoint {
+		containerConfig.Entrypoint, containerConfig.Cmd = nil, keepAliveCmd
 	}
 
 	// Host configuration
 	hostConfig := &container.HostConfig{
-		Resources: container.Resources{
-			Memory:   int64(opts.Resources.MemoryMB) * 1024 * 1024,
-			NanoCPUs: int64(opts.Resources.CPUs * 1e9),
-		},
+		Resources:  containerResources(opts.Resources),
 		AutoRemove: false,
+		StorageOpt: dockerapi.DiskLimitStorageOpt(opts.Resources.DiskMB),
+	}
+	hostConfig.CpusetCpus = cpuset
+	if opts.Resources.DiskMB > 0 && opts.ReadonlyRootfs {
+		// The workspace would live in volumes, outside the limited layer.
+		return nil, fmt.Errorf("%w: the workspace of a read-only root filesystem is not covered", provider.ErrDiskLimitUnsupported)
 	}
 
 	// Network mode
 	if !opts.InternetAccess {
+		if len(opts.Ports) > 0 {
+			return nil, fmt.Errorf("publishing ports requires internet access")
+		}
 		hostConfig.NetworkMode = "none"
 	}
+	if len(opts.Ports) > 0 {
+		containerConfig.ExposedPorts, hostConfig.PortBindings = portBindings(opts.Ports)
+	}
+
+	if len(opts.Ulimits) > 0 {
+		hostConfig.Ulimits = containerUlimits(opts.Ulimits)
+	}
+	hostConfig.CapDrop = opts.CapDrop
+	hostConfig.CapAdd = opts.CapAdd
+	if opts.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges")
+	}
+	if opts.Syscalls != nil {
+		if err := opts.Syscalls.Validate(); err != nil {
+			return nil, err
+		}
+		opt, err := seccompSecurityOpt(opts.Syscalls)
+		if err != nil {
+			return nil, fmt.Errorf("seccomp profile: %w", err)
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, opt)
+	}
+
+	// Read-only root with writable volumes where code and files are copied
+	if opts.ReadonlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Mounts = writableVolumes(opts.WorkDir, "/tmp")
+		hostConfig.Tmpfs = maps.Clone(provider.ReadonlyRootfsTmpfs)
+	}
+	if opts.SecretFiles {
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = make(map[string]string)
+		}
+		hostConfig.Tmpfs[executor.SecretsDir] = dockerapi.SecretsTmpfsOptions
+	}
 
 	// Create container
-	resp, err := p.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
+	resp, err := p.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, opts.ID)
 	if err != nil {
+		if diskErr := dockerapi.DiskLimitError(err); diskErr != nil {
+			return nil, diskErr
+		}
+		if opts.ID != "" {
+			// Another Create with the same ID may have won the race.
+			if inst, rerr := p.reattach(ctx, image, opts); inst != nil || rerr != nil {
+				return inst, rerr
+			}
+		}
 		return nil, fmt.Errorf("create container: %w", err)
 	}
 
 	// Start container
 	if err := p.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
 		// Clean up on failure
-		p.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		p.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
 		return nil, fmt.Errorf("start container: %w", err)
 	}
 
-	return &Instance{
-		id:      resp.ID,
+	id := resp.ID
+	if opts.ID != "" {
+		id = opts.ID
+	}
+	return p.newInstance(ctx, id, image, opts)
+}
+
+// newInstance returns the Instance for the container id it just started,
+// reading the entrypoint's environment and preparing the working
+// directory. The container is removed if that fails.
+func (p *Provider) newInstance(ctx context.Context, id, image string, opts *provider.CreateOptions) (*Instance, error) {
+	inst := p.instance(id, image, opts)
+
+	if opts.RespectEntrypoint {
+		env, err := inst.entrypointEnv(ctx)
+		if err != nil {
+			p.client.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true})
+			return nil, err
+		}
+		maps.Copy(env, opts.Environment)
+		inst.env = env
+	}
+
+	// Docker creates a missing WorkingDir as root, which the image's user
+	// may not be able to write.
+	if opts.WorkDir != "" {
+		dir, err := inst.prepareWorkDir(ctx, opts.WorkDir, opts.FallbackWorkDir)
+		if err != nil {
+			p.client.ContainerRemove(ctx, id, container.RemoveOptions{Force: true, RemoveVolumes: true})
+			return nil, err
+		}
+		inst.workDir = dir
+	}
+	return inst, nil
+}
+
+// instance returns the Instance for the running container id, as
+// configured by opts.
+func (p *Provider) instance(id, image string, opts *provider.CreateOptions) *Instance {
+	inst := &Instance{
+		id:      id,
 		client:  p.client,
 		config:  p.config,
 		workDir: opts.WorkDir,
 		timeout: opts.Timeout,
-	}, nil
+		env:     opts.Environment,
+		diskMB:  opts.Resources.DiskMB,
+		runtime: provider.NewResolvedRuntime(image, opts.Runtime),
+
+		internetAccess: opts.InternetAccess,
+
+		ensureImage: p.ensureImage,
+
+		reuseInterpreter: opts.ReuseInterpreter,
+		interpreters:     make(map[string]int),
+	}
+	if opts.SecretFiles {
+		inst.secretRefs = new(executor.SecretFileRefs)
+	}
+	if opts.Polyglot {
+		inst.runtimes = new(dockerapi.RuntimeChecker)
+	}
+	return inst
+}
+
+// containerResources converts r to container limits, with the CPU limited
+// by either a NanoCPUs quota or a CPUShares weight as r.CPUMode selects.
+func containerResources(r provider.ResourceConfig) container.Resources {
+	pidsLimit := r.PidsLimit()
+	resources := container.Resources{
+		Memory:    int64(r.MemoryMB) * 1024 * 1024,
+		PidsLimit: &pidsLimit,
+	}
+	if r.CPUMode == provider.CPUModeShares {
+		resources.CPUShares = int64(r.CPUShares)
+	} else {
+		resources.NanoCPUs = int64(r.CPUs * 1e9)
+	}
+	return resources
+}
+
+// containerUlimits converts ulimits to Docker's form, sorted by name.
+func containerUlimits(limits map[string]executor.Ulimit) []*container.Ulimit {
+	ulimits := make([]*container.Ulimit, 0, len(limits))
+	for name, l := range limits {
+		ulimits = append(ulimits, &container.Ulimit{Name: name, Soft: l.Soft, Hard: l.Hard})
+	}
+	sort.Slice(ulimits, func(i, j int) bool {
+		return ulimits[i].Name < ulimits[j].Name
+	})
+	return ulimits
+}
+
+// writableVolumes returns anonymous volume mounts for dirs. Volumes are used
+// rather than tmpfs because the archive API cannot copy files into tmpfs.
+func writableVolumes(dirs ...string) []mount.Mount {
+	mounts := make([]mount.Mount, 0, len(dirs))
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		mounts = append(mounts, mount.Mount{Type: mount.TypeVolume, Target: dir})
+	}
+	return mounts
 }
 
 // ensureImage pulls the image if it doesn't exist locally.
@@ -201,27 +383,84 @@ func (p *Provider) ensureImage(ctx context.Context, imageName string) error {
 	return err
 }
 
+// resolveImage picks the container image: opts.Image, else the image for
+// opts.Runtime, else the configured default.
+func (p *Provider) resolveImage(opts *provider.CreateOptions) (string, error) {
+	if opts.Image != "" {
+		return opts.Image, nil
+	}
+	if opts.Runtime != "" {
+		image, err := langdetect.ResolveImage(opts.Runtime)
+		if err != nil && !errors.Is(err, langdetect.ErrUnknownRuntime) {
+			return "", fmt.Errorf("resolve runtime: %w", err)
+		}
+		if image != "" {
+			return image, nil
+		}
+	}
+	return p.config.DefaultImage, nil
+}
+
 // Capabilities returns Docker provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		SupportsStreaming:  true,
-		SupportsAsync:      true,
-		SupportsFileSystem: true,
-		SupportsNetwork:    true,
-		SupportedLanguages: langdetect.SupportedLanguages(),
-		MaxExecutionTime:   30 * time.Minute,
-		MaxMemoryMB:        4096,
-		MaxCPUs:            4,
+		SupportsStreaming:        true,
+		SupportsAsync:            true,
+		SupportsFileSystem:       true,
+		SupportsNetwork:          true,
+		SupportsRangeDownload:    true,
+		SupportsNetworkCapture:   true,
+		SupportsTailFile:         true,
+		SupportsArchive:          true,
+		SupportsEphemeralWorkDir: true,
+		SupportsPause:            true,
+		SupportsCommandWrapper:   true,
+		SupportsInteractiveStdin: true,
+		SupportsCoverage:         true,
+		SupportsInterpreterPath:  true,
+		SupportsProgramArgs:      true,
+		SupportsImageEntrypoint:  true,
+		SupportsDeterministicID:  true,
+		SupportsSyscallFilter:    true,
+		SupportsCPUSet:           true,
+		SupportsRawStream:        true,
+		SupportsSecretFiles:      true,
+		SupportedLanguages:       langdetect.SupportedLanguages(),
+		MaxExecutionTime:         30 * time.Minute,
+		MaxMemoryMB:              4096,
+		MaxCPUs:                  4,
 	}
 }
 
 // Validate checks if Docker is available.
 func (p *Provider) Validate(ctx context.Context) error {
 	_, err := p.client.Ping(ctx)
-	if err != nil {
+	if err == nil {
+		return nil
+	}
+	if p.config.ValidateRetries <= 0 {
 		return fmt.Errorf("docker not available: %w", err)
 	}
-	return nil
+
+	delay := p.config.ValidateRetryDelay
+	if delay <= 0 {
+		delay = defaultValidateRetryDelay
+	}
+
+	for attempt := 0; attempt < p.config.ValidateRetries; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("docker not available: %w", err)
+		case <-timer.C:
+		}
+
+		if _, err = p.client.Ping(ctx); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("docker not available after %d attempts: %w", p.config.ValidateRetries+1, err)
 }
 
 // Close releases provider resources.
@@ -238,6 +477,55 @@ type Instance struct {
 	timeout time.Duration
 	mu      sync.RWMutex
 	stopped bool
+
+	// env is the environment the container was created with. Every exec
+	// sets it explicitly, overlaid with the execution's Env, so the
+	// precedence does not depend on the daemon.
+	env map[string]string
+
+	// runtime is the image and runtime resolved at creation.
+	runtime provider.ResolvedRuntime
+
+	// diskMB is the size limit of the writable layer; when set, each
+	// Execute reports the space used.
+	diskMB int
+
+	// secretRefs tracks which secret files overlapping executions hold.
+	// It is nil unless the secrets tmpfs is mounted.
+	secretRefs *executor.SecretFileRefs
+
+	// reattached is set when Create found the container already running
+	// under a deterministic ID.
+	reattached bool
+
+	// internetAccess is set when the container has a network. Runtimes
+	// with their own permission model are granted network access to match.
+	internetAccess bool
+
+	// ensureImage pulls images the instance needs after creation, such as
+	// the network capture sidecar.
+	ensureImage func(ctx context.Context, imageName string) error
+
+	// runtimes checks each language's runtime binary. It is nil unless
+	// the image has several runtimes.
+	runtimes *dockerapi.RuntimeChecker
+
+	// faketimePath is libfaketime's path in the container, found on the
+	// first execution with a clock offset. It is empty if the image has
+	// none.
+	faketimeMu      sync.Mutex
+	faketimeChecked bool
+	faketimePath    string
+
+	// network is shared by Network calls so published ports are remembered.
+	networkOnce sync.Once
+	network     *dockerNetwork
+
+	// reuseInterpreter routes supported languages to warm interpreter
+	// servers, keyed by language in interpreters with their pids.
+	reuseInterpreter bool
+	interpMu         sync.Mutex
+	interpreters     map[string]int
 }
 
 // ID returns the container ID.
@@ -263,6 +551,20 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	if !ok {
 		return nil, fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+
+	if err := i.runtimes.Check(ctx, i.runExec, runtimeInfo); err != nil {
+		return nil, err
+	}
+	if err := dockerapi.CheckCommandWrapper(ctx, i.runExec, opts.CommandWrapper); err != nil {
+		return nil, err
+	}
+
+	opts, removeWorkDir, err := i.ephemeralWorkDir(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeWorkDir()
 
 	// Write code to file
 	codeFilename := "main" + runtimeInfo.FileExt
@@ -275,24 +577,69 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		return nil, fmt.Errorf("write code file: %w", err)
 	}
 
-	// Write additional files
-	for path, content := range opts.Files {
-		if err := i.writeFile(ctx, path, content); err != nil {
-			return nil, fmt.Errorf("write file %s: %w", path, err)
+	if err := i.writeFiles(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	removeSecrets, err := dockerapi.WriteSecretFiles(ctx, i.runExec, i.secretRefs, opts.SecretFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer removeSecrets()
+
+	var cov *provider.CoverageRun
+	if opts.Coverage {
+		var removeCoverage func()
+		if cov, removeCoverage, err = dockerapi.PrepareCoverage(ctx, i.runExec, i.workDir, opts); err != nil {
+			return nil, err
 		}
+		defer removeCoverage()
 	}
 
 	// Build command
 	var cmd []string
-	if runtimeInfo.CompileCmd != nil {
+	if cov != nil {
+		cmd = append(slices.Clone(cov.Run), codePath)
+	} else if runtimeInfo.CompileCmd != nil {
 		// Compile step
-		compileCmd := append(runtimeInfo.CompileCmd, codePath)
+		compileCmd := provider.WrapUmask(append(runtimeInfo.CompileCmd, codePath), opts.Umask)
 		if _, err := i.runExec(ctx, compileCmd, opts); err != nil {
 			return nil, fmt.Errorf("compile: %w", err)
 		}
 		cmd = runtimeInfo.RunCommand
 	} else {
-		cmd = append(runtimeInfo.RunCommand, codePath)
+		cmd = append(provider.RunCommand(runtimeInfo, i.internetAccess, opts.WorkDir), codePath)
+	}
+	cmd = append(slices.Clip(cmd), opts.Args...)
+	cmd = provider.WrapCommand(cmd, opts.CommandWrapper)
+	if !i.config.DisableTimeoutWrapper {
+		cmd = provider.WrapTimeout(cmd, opts.Timeout)
+	}
+	cmd = provider.WrapUmask(cmd, opts.Umask)
+
+	// Only the run sees the shifted clock, not the compiler.
+	runOpts, clockShifted, err := i.clockOffsetOptions(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if cov != nil {
+		runOpts = dockerapi.CoverageOptions(cov, runOpts)
+	}
+
+	var before map[string]container.ChangeType
+	if opts.TrackFileChanges {
+		snap, err := dockerapi.ContainerChanges(ctx, i.client, i.id, opts.WorkDir)
+		if err != nil {
+			return nil, err
+		}
+		before = snap
+	}
+
+	var stopCapture func() ([]byte, error)
+	if opts.NetworkCapture {
+		if stopCapture, err = i.startCapture(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	// Set timeout
@@ -306,16 +653,63 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	start := time.Now()
 
 	// Run the code
-	result, err := i.runExec(execCtx, cmd, opts)
+	var result *executor.ExecutionResult
+	if cov == nil && i.canReuseInterpreter(runOpts) {
+		result, err = i.executeWarm(execCtx, runtimeInfo, codePath, runOpts)
+	} else {
+		result, err = i.runExec(execCtx, cmd, runOpts)
+	}
+	duration := time.Since(start)
+
+	var capture []byte
+	if stopCapture != nil {
+		var captureErr error
+		if capture, captureErr = stopCapture(); captureErr != nil && err == nil {
+			return nil, captureErr
+		}
+	}
+
 	if err != nil {
 		if execCtx.Err() == context.DeadlineExceeded {
-			return nil, fmt.Errorf("execution timed out after %v: %w\n\nTip: You can increase the timeout using the -timeout flag (e.g., -timeout 10m)", opts.Timeout, err)
+			err = fmt.Errorf("execution timed out after %v: %w\n\nTip: You can increase the timeout using the -timeout flag (e.g., -timeout 10m)", opts.Timeout, err)
+		}
+		if capture != nil {
+			err = &executor.NetworkCaptureError{Err: err, Capture: capture}
 		}
 		return nil, err
 	}
 
-	result.Duration = time.Since(start)
+	result.Duration = duration
 	result.Language = opts.Language
+	result.NetworkCapture = capture
+	if clockShifted {
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]any)
+		}
+		result.Metadata[executor.MetadataClockOffsetApplied] = true
+	}
+
+	if cov != nil {
+		// Programs that crash may leave no coverage data; their output
+		// matters more than the missing report.
+		if result.Coverage, err = dockerapi.CollectCoverage(ctx, i.runExec, i.FileSystem(), cov, opts); err != nil && result.ExitCode == 0 {
+			return nil, err
+		}
+	}
+
+	if opts.TrackFileChanges {
+		after, err := dockerapi.ContainerChanges(ctx, i.client, i.id, opts.WorkDir)
+		if err != nil {
+			return nil, err
+		}
+		result.SetFileChanges(dockerapi.DiffContainerChanges(before, after, executor.DefaultMaxTrackedFiles))
+	}
+
+	if i.diskMB > 0 {
+		if result.DiskUsedMB, err = dockerapi.DiskUsedMB(ctx, i.client, i.id); err != nil {
+			return nil, err
+		}
+	}
 
 	return result, nil
 }
@@ -328,21 +722,12 @@ func (i *Instance) runExec(ctx context.Context, cmd []string, opts *executor.Exe
 		AttachStdout: true,
 		AttachStderr: true,
 		AttachStdin:  opts.Stdin != "",
+		Env:          provider.MergeEnv(i.env, opts.Env),
 	}
 
-	// Add environment variables
-	for k, v := range opts.Env {
-		execConfig.Env = append(execConfig.Env, fmt.Sprintf("%s=%s", k, v))
-	}
-
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
-	if err != nil {
-		return nil, fmt.Errorf("create exec: %w", err)
-	}
-
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
+	execID, resp, err := i.startExec(ctx, execConfig)
 	if err != nil {
-		return nil, fmt.Errorf("attach exec: %w", err)
+		return nil, err
 	}
 	defer resp.Close()
 
@@ -356,42 +741,59 @@ func (i *Instance) runExec(ctx context.Context, cmd []string, opts *executor.Exe
 
 	// Read output using stdcopy to demultiplex stdout/stderr
 	var stdout, stderr bytes.Buffer
-	if _, err := stdcopy.StdCopy(&stdout, &stderr, resp.Reader); err != nil {
+	stdoutW := executor.NewLimitedWriter(&stdout, opts.StdoutLimit)
+	stderrW := executor.NewLimitedWriter(&stderr, opts.StderrLimit)
+	if _, err := stdcopy.StdCopy(stdoutW, stderrW, resp.Reader); err != nil {
 		return nil, fmt.Errorf("read output: %w", err)
 	}
 
 	// Get exit code
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
+	exitCode, err := i.execExitCode(ctx, execID)
 	if err != nil {
-		return nil, fmt.Errorf("inspect exec: %w", err)
+		return nil, err
 	}
 
 	return &executor.ExecutionResult{
-		ExitCode: inspectResp.ExitCode,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
+		ExitCode:        exitCode,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdoutW.Truncated(),
+		StderrTruncated: stderrW.Truncated(),
 	}, nil
 }
 
 // writeFile writes content to a file in the container.
-func (i *Instance) writeFile(ctx context.Context, path string, content []byte) error {
+func (i *Instance) writeFile(ctx context.Context, name string, content []byte) error {
 	// Use tar archive to copy file
 	var buf bytes.Buffer
 	tw := newTarWriter(&buf)
-	if err := tw.WriteFile(path, content); err != nil {
+	if err := tw.WriteFile(name, content); err != nil {
 		return err
 	}
 	tw.Close()
 
 	// Get directory
-	dir := path[:strings.LastIndex(path, "/")]
-	if dir == "" {
-		dir = "/"
-	}
+	dir := path.Dir(name)
 
 	return i.client.CopyToContainer(ctx, i.id, dir, &buf, container.CopyToContainerOptions{})
 }
 
+// writeFiles writes opts.Files, resolving relative names against the
+// run's working directory.
+func (i *Instance) writeFiles(ctx context.Context, opts *executor.ExecutionOptions) error {
+	if len(opts.Files) == 0 {
+		return nil
+	}
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = i.workDir
+	}
+	if err := dockerapi.WriteFiles(ctx, i.client, i.id, workDir, opts.Files); err != nil {
+		return fmt.Errorf("write files: %w", err)
+	}
+	return nil
+}
+
 // ExecuteStream runs code with streaming output.
 func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
 	i.mu.RLock()
@@ -410,6 +812,20 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if !ok {
 		return fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+
+	if err := i.runtimes.Check(ctx, i.runExec, runtimeInfo); err != nil {
+		return err
+	}
+	if err := dockerapi.CheckCommandWrapper(ctx, i.runExec, opts.CommandWrapper); err != nil {
+		return err
+	}
+
+	opts, removeWorkDir, err := i.ephemeralWorkDir(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer removeWorkDir()
 
 	// Write code to file
 	codeFilename := "main" + runtimeInfo.FileExt
@@ -421,17 +837,55 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if err := i.writeFile(ctx, codePath, []byte(code)); err != nil {
 		return fmt.Errorf("write code file: %w", err)
 	}
+	if err := i.writeFiles(ctx, opts); err != nil {
+		return err
+	}
+
+	removeSecrets, err := dockerapi.WriteSecretFiles(ctx, i.runExec, i.secretRefs, opts.SecretFiles)
+	if err != nil {
+		return err
+	}
+	defer removeSecrets()
 
 	// Build command
 	var cmd []string
 	if runtimeInfo.CompileCmd != nil {
-		compileCmd := append(runtimeInfo.CompileCmd, codePath)
-		if _, err := i.runExec(ctx, compileCmd, opts); err != nil {
+		// Compiler diagnostics stream as they happen, ahead of the run.
+		compileCmd := provider.WrapUmask(append(runtimeInfo.CompileCmd, codePath), opts.Umask)
+		compileConfig := container.ExecOptions{
+			Cmd:          compileCmd,
+			WorkingDir:   opts.WorkDir,
+			AttachStdout: true,
+			AttachStderr: true,
+			Env:          provider.MergeEnv(i.env, opts.Env),
+		}
+		exitCode, err := i.streamExec(ctx, compileConfig, nil, opts.MaxOutputRate, false, executor.StreamCompileStdout, executor.StreamCompileStderr, handler)
+		if err != nil {
 			return fmt.Errorf("compile: %w", err)
 		}
+		if exitCode != 0 {
+			handler(&executor.StreamEvent{
+				Type:      executor.StreamComplete,
+				ExitCode:  exitCode,
+				Language:  opts.Language,
+				Timestamp: time.Now(),
+			})
+			return nil
+		}
 		cmd = runtimeInfo.RunCommand
 	} else {
-		cmd = append(runtimeInfo.RunCommand, codePath)
+		cmd = append(provider.RunCommand(runtimeInfo, i.internetAccess, opts.WorkDir), codePath)
+	}
+	cmd = append(slices.Clip(cmd), opts.Args...)
+	cmd = provider.WrapCommand(cmd, opts.CommandWrapper)
+	if !i.config.DisableTimeoutWrapper {
+		cmd = provider.WrapTimeout(cmd, opts.Timeout)
+	}
+	cmd = provider.WrapUmask(cmd, opts.Umask)
+
+	runOpts, _, err := i.clockOffsetOptions(ctx, opts)
+	if err != nil {
+		return err
 	}
 
 	execConfig := container.ExecOptions{
@@ -439,82 +893,20 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		WorkingDir:   opts.WorkDir,
 		AttachStdout: true,
 		AttachStderr: true,
+		AttachStdin:  opts.StdinStream != nil,
+		Env:          provider.MergeEnv(i.env, runOpts.Env),
 	}
 
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
-	if err != nil {
-		return fmt.Errorf("create exec: %w", err)
-	}
-
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
-	if err != nil {
-		return fmt.Errorf("attach exec: %w", err)
-	}
-	defer resp.Close()
-
-	// Stream output
-	stdoutReader, stdoutWriter := io.Pipe()
-	stderrReader, stderrWriter := io.Pipe()
-
-	go func() {
-		stdcopy.StdCopy(stdoutWriter, stderrWriter, resp.Reader)
-		stdoutWriter.Close()
-		stderrWriter.Close()
-	}()
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	// Stream stdout
-	go func() {
-		defer wg.Done()
-		buf := make([]byte, 1024)
-		for {
-			n, err := stdoutReader.Read(buf)
-			if n > 0 {
-				handler(&executor.StreamEvent{
-					Type:      executor.StreamStdout,
-					Data:      string(buf[:n]),
-					Timestamp: time.Now(),
-				})
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-
-	// Stream stderr
-	go func() {
-		defer wg.Done()
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderrReader.Read(buf)
-			if n > 0 {
-				handler(&executor.StreamEvent{
-					Type:      executor.StreamStderr,
-					Data:      string(buf[:n]),
-					Timestamp: time.Now(),
-				})
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-
-	wg.Wait()
-
-	// Get exit code
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
+	exitCode, err := i.streamExec(ctx, execConfig, opts.StdinStream, opts.MaxOutputRate, opts.RawStream, executor.StreamStdout, executor.StreamStderr, handler)
 	if err != nil {
-		return fmt.Errorf("inspect exec: %w", err)
+		return err
 	}
 
 	// Send completion event
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
-		ExitCode:  inspectResp.ExitCode,
+		ExitCode:  exitCode,
+		Language:  opts.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -534,20 +926,17 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 
 	execConfig := container.ExecOptions{
 		Cmd:          fullCmd,
+		WorkingDir:   i.workDir,
 		AttachStdout: true,
 		AttachStderr: true,
-	}
-
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
-	if err != nil {
-		return nil, fmt.Errorf("create exec: %w", err)
+		Env:          provider.MergeEnv(i.env, nil),
 	}
 
 	start := time.Now()
 
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
+	execID, resp, err := i.startExec(ctx, execConfig)
 	if err != nil {
-		return nil, fmt.Errorf("attach exec: %w", err)
+		return nil, err
 	}
 	defer resp.Close()
 
@@ -556,13 +945,13 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 		return nil, fmt.Errorf("read output: %w", err)
 	}
 
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
+	exitCode, err := i.execExitCode(ctx, execID)
 	if err != nil {
-		return nil, fmt.Errorf("inspect exec: %w", err)
+		return nil, err
 	}
 
 	return &executor.CommandResult{
-		ExitCode: inspectResp.ExitCode,
+		ExitCode: exitCode,
 		Stdout:   stdout.String(),
 		Stderr:   stderr.String(),
 		Duration: time.Since(start),
@@ -578,9 +967,10 @@ func (i *Instance) FileSystem() fs.FileSystem {
 
 // Network returns the network handler.
 func (i *Instance) Network() provider.Network {
-	return &dockerNetwork{
-		instance: i,
-	}
+	i.networkOnce.Do(func() {
+		i.network = &dockerNetwork{instance: i}
+	})
+	return i.network
 }
 
 // Stop terminates the container.
@@ -602,7 +992,7 @@ func (i *Instance) Stop(ctx context.Context) error {
 	}
 
 	// Remove container
-	if err := i.client.ContainerRemove(ctx, i.id, container.RemoveOptions{Force: true}); err != nil {
+	if err := i.client.ContainerRemove(ctx, i.id, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
 		if !strings.Contains(err.Error(), "No such container") {
 			return fmt.Errorf("remove container: %w", err)
 		}
@@ -611,6 +1001,33 @@ func (i *Instance) Stop(ctx context.Context) error {
 	return nil
 }
 
+// RuntimeInfo returns the image and runtime resolved at creation.
+func (i *Instance) RuntimeInfo() provider.ResolvedRuntime {
+	return i.runtime
+}
+
+// WorkDir returns the working directory prepared at creation, which is the
+// fallback when CreateOptions.WorkDir could not be used.
+func (i *Instance) WorkDir() string {
+	return i.workDir
+}
+
+// Pause freezes every process in the container with the cgroup freezer.
+func (i *Instance) Pause(ctx context.Context) error {
+	if err := i.client.ContainerPause(ctx, i.id); err != nil {
+		return fmt.Errorf("pause container: %w", err)
+	}
+	return nil
+}
+
+// Resume unfreezes a paused container.
+func (i *Instance) Resume(ctx context.Context) error {
+	if err := i.client.ContainerUnpause(ctx, i.id); err != nil {
+		return fmt.Errorf("unpause container: %w", err)
+	}
+	return nil
+}
+
 // Status returns the current status.
 func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error) {
 	i.mu.RLock()
@@ -625,12 +1042,13 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 		return provider.StatusError, err
 	}
 
-	if info.State.Running {
-		return provider.StatusRunning, nil
-	}
+	// A paused container also reports Running.
 	if info.State.Paused {
 		return provider.StatusPaused, nil
 	}
+	if info.State.Running {
+		return provider.StatusRunning, nil
+	}
 
 	return provider.StatusStopped, nil
 }
@@ -638,3 +1056,6 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 // Ensure Provider implements the interface
 var _ provider.Provider = (*Provider)(nil)
 var _ provider.Instance = (*Instance)(nil)
+var _ provider.Pauser = (*Instance)(nil)
+var _ provider.RuntimeReporter = (*Instance)(nil)
+var _ provider.WorkDirReporter = (*Instance)(nil)
@@ -3,13 +3,21 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/happyhackingspace/sindoq/internal/provider"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
 )
 
 func TestDockerProviderIntegration(t *testing.T) {
@@ -317,6 +325,109 @@ print(os.environ.get("MY_VAR", "not set"))
 	}
 }
 
+func TestDockerProviderEnvPrecedence(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:     "Python",
+		Environment: map[string]string{"CREATE_VAR": "create", "SHARED_VAR": "create"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	code := `import os
+print(os.environ.get("CREATE_VAR"), os.environ.get("EXEC_VAR"), os.environ.get("SHARED_VAR"))`
+	opts := &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  30 * time.Second,
+		Env:      map[string]string{"EXEC_VAR": "exec", "SHARED_VAR": "exec"},
+	}
+	want := "create exec exec\n"
+
+	result, err := instance.Execute(ctx, code, opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != want {
+		t.Errorf("Execute Stdout = %q, want %q", result.Stdout, want)
+	}
+
+	var stdout strings.Builder
+	err = instance.ExecuteStream(ctx, code, opts, func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamStdout {
+			stdout.WriteString(e.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if stdout.String() != want {
+		t.Errorf("ExecuteStream Stdout = %q, want %q", stdout.String(), want)
+	}
+
+	cmd, err := instance.RunCommand(ctx, "sh", []string{"-c", "echo $CREATE_VAR $SHARED_VAR"})
+	if err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+	if cmd.Stdout != "create create\n" {
+		t.Errorf("RunCommand Stdout = %q, want %q", cmd.Stdout, "create create\n")
+	}
+}
+
+func TestDockerProviderTimezoneAndClockOffset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// A POSIX TZ string works without tzdata in the image.
+	result, err := instance.Execute(ctx, `import time
+print(time.strftime("%Z %z", time.localtime()))`, &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  30 * time.Second,
+		Env:      map[string]string{"TZ": "XYZ-3"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "XYZ +0300\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "XYZ +0300\n")
+	}
+
+	// The stock Python image has no libfaketime, so the offset is skipped.
+	result, err = instance.Execute(ctx, "print(1)", &executor.ExecutionOptions{
+		Language:    "Python",
+		Timeout:     30 * time.Second,
+		ClockOffset: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Execute() with ClockOffset error = %v", err)
+	}
+	if result.Metadata[executor.MetadataClockOffsetApplied] == true {
+		t.Error("clock offset should not be applied without libfaketime")
+	}
+}
+
 func TestDockerProviderWithStdin(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
@@ -352,3 +463,1333 @@ print(f"Got: {data}")
 		t.Errorf("Stdout = %q, want to contain stdin data", result.Stdout)
 	}
 }
+
+func TestDockerProviderPidsLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:   "Python",
+		Resources: provider.ResourceConfig{MemoryMB: 256, CPUs: 1, MaxPids: 32},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// A fork bomb must hit the pids limit and end within the timeout
+	// instead of exhausting the host.
+	start := time.Now()
+	result, err := instance.Execute(ctx, `
+import os
+while True:
+    try:
+        os.fork()
+    except OSError:
+        print("fork failed")
+        os._exit(1)
+`, &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  10 * time.Second,
+	})
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Fatalf("fork bomb ran for %v", elapsed)
+	}
+	if err == nil && result.ExitCode == 0 {
+		t.Error("fork bomb should fail")
+	}
+}
+
+func TestDockerProviderReadonlyRootfs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:        "Shell",
+		WorkDir:        "/workspace",
+		ReadonlyRootfs: true,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	opts := &executor.ExecutionOptions{
+		Language: "Shell",
+		WorkDir:  "/workspace",
+		Timeout:  30 * time.Second,
+	}
+
+	result, err := instance.Execute(ctx, "echo x > /etc/sindoq", opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode == 0 {
+		t.Error("writing to /etc should fail")
+	}
+
+	result, err = instance.Execute(ctx, "echo ok > out.txt && cat out.txt && echo tmp > /tmp/t", opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != "ok" {
+		t.Errorf("workdir write: ExitCode = %d, Stdout = %q, Stderr = %q", result.ExitCode, result.Stdout, result.Stderr)
+	}
+}
+
+func TestDockerProviderUlimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Python",
+		Ulimits: map[string]executor.Ulimit{"nofile": {Soft: 2048, Hard: 2048}},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	code := `import resource
+print(resource.getrlimit(resource.RLIMIT_NOFILE)[0])
+files = [open("/dev/null") for _ in range(1500)]
+print(len(files))`
+	result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d, Stderr: %s", result.ExitCode, result.Stderr)
+	}
+	if result.Stdout != "2048\n1500\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "2048\n1500\n")
+	}
+}
+
+func TestDockerProviderCapabilities(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	// Binding a low port is no test: Docker sets
+	// net.ipv4.ip_unprivileged_port_start=0 in every network namespace it
+	// creates. Giving a file away needs CAP_CHOWN.
+	code := `import os
+open("/tmp/owned", "w").close()
+try:
+    os.chown("/tmp/owned", 1000, 1000)
+    print("allowed")
+except PermissionError:
+    print("denied")`
+
+	tests := []struct {
+		name   string
+		capAdd []string
+		want   string
+	}{
+		{"drop all", nil, "denied\n"},
+		{"drop all, add CHOWN", []string{"CHOWN"}, "allowed\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := p.Create(ctx, &provider.CreateOptions{
+				Runtime: "Python",
+				CapDrop: []string{"ALL"},
+				CapAdd:  tt.capAdd,
+			})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer instance.Stop(ctx)
+
+			result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+				Language: "Python",
+				Timeout:  30 * time.Second,
+			})
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.ExitCode != 0 {
+				t.Fatalf("ExitCode = %d, Stderr: %s", result.ExitCode, result.Stderr)
+			}
+			if result.Stdout != tt.want {
+				t.Errorf("Stdout = %q, want %q", result.Stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerProviderCommandWrapper(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	// The Shell image is Alpine, whose busybox provides /usr/bin/time.
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Shell"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	result, err := instance.Execute(ctx, "echo hello", &executor.ExecutionOptions{
+		Language:       "Shell",
+		Timeout:        30 * time.Second,
+		CommandWrapper: []string{"/usr/bin/time"},
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "hello\n" {
+		t.Fatalf("result = %+v", result)
+	}
+	if !strings.Contains(result.Stderr, "real") {
+		t.Errorf("Stderr = %q, want the timing report", result.Stderr)
+	}
+
+	_, err = instance.Execute(ctx, "echo hello", &executor.ExecutionOptions{
+		Language:       "Shell",
+		Timeout:        30 * time.Second,
+		CommandWrapper: []string{"valgrind"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "valgrind not found") {
+		t.Errorf("Execute() error = %v, want the missing wrapper reported", err)
+	}
+}
+
+func TestDockerProviderDeno(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Deno"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	code := `await Deno.writeTextFile("out.txt", "hello");
+console.log(await Deno.readTextFile("out.txt"));`
+	result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{Language: "Deno", Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "hello\n" {
+		t.Fatalf("result = %+v, want the workdir readable and writable", result)
+	}
+
+	// Without internet access Deno itself refuses the connection.
+	result, err = instance.Execute(ctx, `await fetch("https://example.com");`, &executor.ExecutionOptions{Language: "Deno", Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "net access") {
+		t.Errorf("result = %+v, want net access denied", result)
+	}
+
+	result, err = instance.Execute(ctx, `console.log(await Deno.readTextFile("/etc/hostname"));`, &executor.ExecutionOptions{Language: "Deno", Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "read access") {
+		t.Errorf("result = %+v, want reads outside the workdir denied", result)
+	}
+}
+
+func TestDockerProviderInteractiveStdin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// Answers are sent only after each prompt has been printed.
+	stdinReader, stdinWriter := io.Pipe()
+	defer stdinWriter.Close()
+	answers := map[string]string{"Name: ": "Alice\n", "Age: ": "30\n"}
+
+	var stdout strings.Builder
+	code := `name = input("Name: ")
+age = input("Age: ")
+print(f"{name} is {age}")`
+	err = instance.ExecuteStream(ctx, code, &executor.ExecutionOptions{
+		Language:    "Python",
+		Timeout:     30 * time.Second,
+		StdinStream: stdinReader,
+	}, func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamStdout {
+			stdout.WriteString(e.Data)
+			for prompt, answer := range answers {
+				if strings.HasSuffix(e.Data, prompt) {
+					go stdinWriter.Write([]byte(answer))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if !strings.HasSuffix(stdout.String(), "Alice is 30\n") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
+func TestDockerProviderUmask(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	code := `import os, stat
+open("created.txt", "w").close()
+print(oct(stat.S_IMODE(os.stat("created.txt").st_mode)))`
+	for _, tt := range []struct {
+		umask os.FileMode
+		want  string
+	}{
+		{0o022, "0o644\n"},
+		{0o077, "0o600\n"},
+	} {
+		result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+			Language:         "Python",
+			Timeout:          30 * time.Second,
+			EphemeralWorkDir: true,
+			Umask:            &tt.umask,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("ExitCode = %d, Stderr: %s", result.ExitCode, result.Stderr)
+		}
+		if result.Stdout != tt.want {
+			t.Errorf("umask %04o: Stdout = %q, want %q", tt.umask, result.Stdout, tt.want)
+		}
+	}
+}
+
+func TestDockerProviderMaxOutputRate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// 256KB at 64KB/s takes about 3.5s after the first 32KB burst.
+	const rate = 64 * 1024
+	code := `import sys
+sys.stdout.write("x" * 256 * 1024)`
+	var received int
+	start := time.Now()
+	err = instance.ExecuteStream(ctx, code, &executor.ExecutionOptions{
+		Language:      "Python",
+		Timeout:       time.Minute,
+		MaxOutputRate: rate,
+	}, func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamStdout {
+			received += len(e.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if received != 256*1024 {
+		t.Errorf("received %d bytes, want %d", received, 256*1024)
+	}
+	if elapsed < 3*time.Second {
+		t.Errorf("output arrived in %v, want it throttled to about 3.5s", elapsed)
+	}
+}
+
+func TestDockerProviderWorkdirModes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Python",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	run := func(code string, ephemeral bool) string {
+		t.Helper()
+		result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+			Language:         "Python",
+			WorkDir:          "/workspace",
+			EphemeralWorkDir: ephemeral,
+			Timeout:          30 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("ExitCode = %d, Stderr: %s", result.ExitCode, result.Stderr)
+		}
+		return result.Stdout
+	}
+	write := `open("state.txt", "w").write("saved")`
+	read := `import os
+print(open("state.txt").read() if os.path.exists("state.txt") else "missing")`
+
+	for _, tt := range []struct {
+		name      string
+		ephemeral bool
+		want      string
+	}{
+		{"persistent", false, "saved\n"},
+		{"ephemeral", true, "missing\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			run(`import os
+if os.path.exists("state.txt"):
+    os.remove("state.txt")`, false)
+			run(write, tt.ephemeral)
+			if got := run(read, tt.ephemeral); got != tt.want {
+				t.Errorf("second run read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDockerProviderConcurrentCompiled compiles two C programs at once in
+// ephemeral workdirs, as a concurrent batch does, and checks that neither
+// runs the other's binary.
+func TestDockerProviderConcurrentCompiled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "C",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	words := []string{"first", "second"}
+	outputs := make([]string, len(words))
+	errs := make([]error, len(words))
+	var wg sync.WaitGroup
+	for n, word := range words {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := fmt.Sprintf("#include <stdio.h>\nint main(void) { puts(%q); return 0; }\n", word)
+			result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+				Language:         "C",
+				WorkDir:          "/workspace",
+				EphemeralWorkDir: true,
+				Timeout:          time.Minute,
+			})
+			if err != nil {
+				errs[n] = err
+				return
+			}
+			outputs[n] = result.Stdout
+		}()
+	}
+	wg.Wait()
+
+	for n, word := range words {
+		if errs[n] != nil {
+			t.Fatalf("Execute(%s) error = %v", word, errs[n])
+		}
+		if outputs[n] != word+"\n" {
+			t.Errorf("Execute(%s) Stdout = %q, want %q", word, outputs[n], word+"\n")
+		}
+	}
+}
+
+// TestDockerProviderRelativeFiles writes files the way RunTests does:
+// relative names, into an ephemeral workdir, for Execute and
+// ExecuteStream alike.
+func TestDockerProviderRelativeFiles(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	const code = `import os
+print(os.getcwd().startswith("/workspace/.run-"), open("solution.py").read(), open("data/input.txt").read())`
+	opts := &executor.ExecutionOptions{
+		Language:         "Python",
+		EphemeralWorkDir: true,
+		Timeout:          30 * time.Second,
+		Files: map[string][]byte{
+			"solution.py":    []byte("x = 1"),
+			"data/input.txt": []byte("42"),
+		},
+	}
+	const want = "True x = 1 42\n"
+
+	result, err := instance.Execute(ctx, code, opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != want {
+		t.Errorf("Execute() Stdout = %q (stderr %q), want %q", result.Stdout, result.Stderr, want)
+	}
+
+	var stdout strings.Builder
+	err = instance.ExecuteStream(ctx, code, opts, func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamStdout {
+			stdout.WriteString(e.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if stdout.String() != want {
+		t.Errorf("ExecuteStream() stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
+func TestDockerProviderPolyglot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Image:    "nikolaik/python-nodejs:python3.12-nodejs22-slim",
+		Polyglot: true,
+		WorkDir:  "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	run := func(language, code string) *executor.ExecutionResult {
+		t.Helper()
+		result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+			Language: language,
+			WorkDir:  "/workspace",
+			Timeout:  30 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Execute(%s) error = %v", language, err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("Execute(%s) ExitCode = %d, Stderr: %s", language, result.ExitCode, result.Stderr)
+		}
+		return result
+	}
+
+	// Python writes a file that Node then reads from the same container.
+	run("Python", `open("shared.txt", "w").write("from python")`)
+	result := run("JavaScript", `console.log(require("fs").readFileSync("shared.txt", "utf8"))`)
+	if result.Stdout != "from python\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "from python\n")
+	}
+
+	_, err = instance.Execute(ctx, `fn main() {}`, &executor.ExecutionOptions{
+		Language: "Rust",
+		WorkDir:  "/workspace",
+		Timeout:  30 * time.Second,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no Rust runtime") {
+		t.Errorf("Execute(Rust) error = %v, want missing runtime", err)
+	}
+}
+
+func TestDockerProviderNetworkCapture(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Python",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// Loopback traffic is enough to show up in the capture and needs no
+	// internet access.
+	code := `import socket
+srv = socket.socket()
+srv.bind(("127.0.0.1", 0))
+srv.listen()
+c = socket.create_connection(srv.getsockname())
+conn, _ = srv.accept()
+c.sendall(b"hello from the sandbox")
+print(conn.recv(64).decode())`
+
+	// pcapMagic is the start of a microsecond-resolution pcap file in
+	// either byte order.
+	isPcap := func(b []byte) bool {
+		return len(b) > 24 && (string(b[:4]) == "\xd4\xc3\xb2\xa1" || string(b[:4]) == "\xa1\xb2\xc3\xd4")
+	}
+
+	t.Run("completed run", func(t *testing.T) {
+		result, err := instance.Execute(ctx, code, &executor.ExecutionOptions{
+			Language:       "Python",
+			WorkDir:        "/workspace",
+			Timeout:        30 * time.Second,
+			NetworkCapture: true,
+		})
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if result.ExitCode != 0 {
+			t.Fatalf("ExitCode = %d, Stderr: %s", result.ExitCode, result.Stderr)
+		}
+		if !isPcap(result.NetworkCapture) {
+			t.Fatalf("NetworkCapture is not a pcap (%d bytes)", len(result.NetworkCapture))
+		}
+		if !strings.Contains(string(result.NetworkCapture), "hello from the sandbox") {
+			t.Error("NetworkCapture should contain the sent payload")
+		}
+	})
+
+	t.Run("timed out run", func(t *testing.T) {
+		_, err := instance.Execute(ctx, code+"\nimport time\ntime.sleep(60)", &executor.ExecutionOptions{
+			Language:       "Python",
+			WorkDir:        "/workspace",
+			Timeout:        3 * time.Second,
+			NetworkCapture: true,
+		})
+		var captureErr *executor.NetworkCaptureError
+		if !errors.As(err, &captureErr) {
+			t.Fatalf("Execute() error = %v, want *NetworkCaptureError", err)
+		}
+		if !isPcap(captureErr.Capture) {
+			t.Errorf("Capture is not a pcap (%d bytes)", len(captureErr.Capture))
+		}
+	})
+}
+
+func TestDockerProviderTailFile(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Python",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// Start tailing before the file exists.
+	tailCtx, stopTail := context.WithCancel(ctx)
+	defer stopTail()
+	chunks, err := instance.FileSystem().(fs.Tailer).Tail(tailCtx, "/workspace/progress.log")
+	if err != nil {
+		t.Fatalf("Tail() error = %v", err)
+	}
+
+	go instance.Execute(ctx, `import time
+with open("progress.log", "w") as f:
+    for i in range(3):
+        f.write(f"step {i}\n")
+        f.flush()
+        time.sleep(0.2)
+    f.write("done\n")`, &executor.ExecutionOptions{
+		Language: "Python",
+		WorkDir:  "/workspace",
+		Timeout:  30 * time.Second,
+	})
+
+	var got string
+	for !strings.Contains(got, "done\n") {
+		select {
+		case c, ok := <-chunks:
+			if !ok {
+				t.Fatalf("channel closed early, got %q", got)
+			}
+			got += string(c)
+		case <-time.After(30 * time.Second):
+			t.Fatalf("timed out waiting for output, got %q", got)
+		}
+	}
+	if want := "step 0\nstep 1\nstep 2\ndone\n"; got != want {
+		t.Errorf("tailed %q, want %q", got, want)
+	}
+
+	stopTail()
+	select {
+	case _, ok := <-chunks:
+		if ok {
+			t.Error("expected no more chunks after cancel")
+		}
+	case <-time.After(15 * time.Second):
+		t.Error("channel not closed after cancel")
+	}
+}
+
+func TestDockerProviderArchiveRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Python",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	result, err := instance.RunCommand(ctx, "sh", []string{"-c",
+		"mkdir -p /workspace/out && echo 42 > /workspace/out/result.txt && printf '#!/bin/sh\\n' > /workspace/run.sh && chmod 755 /workspace/run.sh"})
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("RunCommand() = %+v, %v", result, err)
+	}
+
+	archiver := instance.FileSystem().(fs.Archiver)
+	var snapshot bytes.Buffer
+	if err := archiver.ExportTar(ctx, "/workspace", &snapshot); err != nil {
+		t.Fatalf("ExportTar() error = %v", err)
+	}
+	if err := archiver.ImportTar(ctx, "/restored", &snapshot); err != nil {
+		t.Fatalf("ImportTar() error = %v", err)
+	}
+
+	result, err = instance.RunCommand(ctx, "sh", []string{"-c", "cat /restored/out/result.txt && stat -c %a /restored/run.sh"})
+	if err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+	if result.Stdout != "42\n755\n" {
+		t.Errorf("restored = %q, want the file and the mode", result.Stdout)
+	}
+}
+
+func TestDockerProviderImmediateOutput(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime: "Shell",
+		WorkDir: "/workspace",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	// The program prints and exits before a late attach could catch it.
+	for n := range 100 {
+		result, err := instance.RunCommand(ctx, "sh", []string{"-c", "echo out; echo err >&2; exit 7"})
+		if err != nil {
+			t.Fatalf("run %d: RunCommand() error = %v", n, err)
+		}
+		if result.Stdout != "out\n" || result.Stderr != "err\n" || result.ExitCode != 7 {
+			t.Fatalf("run %d: got %q, %q, exit %d", n, result.Stdout, result.Stderr, result.ExitCode)
+		}
+
+		var stdout strings.Builder
+		exitCode := -1
+		err = instance.ExecuteStream(ctx, "echo out\nexit 7", &executor.ExecutionOptions{
+			Language: "Shell",
+			WorkDir:  "/workspace",
+			Timeout:  30 * time.Second,
+		}, func(e *executor.StreamEvent) error {
+			switch e.Type {
+			case executor.StreamStdout:
+				stdout.WriteString(e.Data)
+			case executor.StreamComplete:
+				exitCode = e.ExitCode
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("run %d: ExecuteStream() error = %v", n, err)
+		}
+		if stdout.String() != "out\n" || exitCode != 7 {
+			t.Fatalf("run %d: streamed %q, exit %d", n, stdout.String(), exitCode)
+		}
+	}
+}
+
+func TestDockerProviderInterpreterReuse(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	tests := []struct {
+		language string
+		setup    string
+		check    string
+		want     string
+	}{
+		{"Python", "import os\ncounter = 1\nprint(os.environ['MY_VAR'])", "print(globals().get('counter'))", "None"},
+		{"JavaScript", "globalThis.counter = 1; console.log(process.env.MY_VAR)", "console.log(typeof counter)", "undefined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			instance, err := p.Create(ctx, &provider.CreateOptions{
+				Runtime:          tt.language,
+				ReuseInterpreter: true,
+			})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer instance.Stop(ctx)
+
+			opts := &executor.ExecutionOptions{
+				Language: tt.language,
+				Timeout:  30 * time.Second,
+				Env:      map[string]string{"MY_VAR": "test_value"},
+			}
+			result, err := instance.Execute(ctx, tt.setup, opts)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if !strings.Contains(result.Stdout, "test_value") {
+				t.Errorf("Stdout = %q, want to contain %q", result.Stdout, "test_value")
+			}
+
+			// Globals are reset between runs.
+			result, err = instance.Execute(ctx, tt.check, opts)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if strings.TrimSpace(result.Stdout) != tt.want {
+				t.Errorf("Stdout = %q, want %q", result.Stdout, tt.want)
+			}
+		})
+	}
+
+	t.Run("timeout restarts server", func(t *testing.T) {
+		instance, err := p.Create(ctx, &provider.CreateOptions{
+			Runtime:          "Python",
+			ReuseInterpreter: true,
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		defer instance.Stop(ctx)
+
+		_, err = instance.Execute(ctx, "while True: pass", &executor.ExecutionOptions{
+			Language: "Python",
+			Timeout:  2 * time.Second,
+		})
+		if err == nil {
+			t.Fatal("Execute() should time out")
+		}
+
+		result, err := instance.Execute(ctx, `print("alive")`, &executor.ExecutionOptions{
+			Language: "Python",
+			Timeout:  30 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("Execute() after timeout error = %v", err)
+		}
+		if !strings.Contains(result.Stdout, "alive") {
+			t.Errorf("Stdout = %q, want to contain %q", result.Stdout, "alive")
+		}
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		for language, code := range map[string]string{
+			"Python":     "import os\nprint('bye')\nos._exit(3)",
+			"JavaScript": "console.log('bye'); process.exit(3); console.log('after')",
+		} {
+			instance, err := p.Create(ctx, &provider.CreateOptions{
+				Runtime:          language,
+				ReuseInterpreter: true,
+			})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer instance.Stop(ctx)
+
+			opts := &executor.ExecutionOptions{Language: language, Timeout: 30 * time.Second}
+			result, err := instance.Execute(ctx, code, opts)
+			if err != nil {
+				t.Fatalf("%s: Execute() error = %v", language, err)
+			}
+			if result.ExitCode != 3 || result.Stdout != "bye\n" {
+				t.Errorf("%s: exit %d, stdout %q, want exit 3 after bye", language, result.ExitCode, result.Stdout)
+			}
+		}
+	})
+
+	t.Run("killed server fails fast", func(t *testing.T) {
+		instance, err := p.Create(ctx, &provider.CreateOptions{
+			Runtime:          "JavaScript",
+			ReuseInterpreter: true,
+		})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		defer instance.Stop(ctx)
+
+		opts := &executor.ExecutionOptions{Language: "JavaScript", Timeout: 30 * time.Second}
+		start := time.Now()
+		if _, err := instance.Execute(ctx, `process.kill(process.pid, "SIGKILL")`, opts); err == nil {
+			t.Error("Execute() should fail when the server dies")
+		}
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Execute() took %v, want it to fail without waiting for the timeout", elapsed)
+		}
+		result, err := instance.Execute(ctx, `console.log("alive")`, opts)
+		if err != nil || result.Stdout != "alive\n" {
+			t.Errorf("Execute() after the server died = %+v, %v, want a restarted server", result, err)
+		}
+	})
+}
+
+// BenchmarkDockerExecute compares a tight execute loop with and without
+// interpreter reuse.
+func BenchmarkDockerExecute(b *testing.B) {
+	ctx := context.Background()
+
+	p, err := New(nil)
+	if err != nil {
+		b.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	for _, reuse := range []bool{false, true} {
+		name := "fresh"
+		if reuse {
+			name = "reuse"
+		}
+		for _, language := range []string{"Python", "JavaScript"} {
+			b.Run(name+"/"+language, func(b *testing.B) {
+				instance, err := p.Create(ctx, &provider.CreateOptions{
+					Runtime:          language,
+					ReuseInterpreter: reuse,
+				})
+				if err != nil {
+					b.Fatalf("Create() error = %v", err)
+				}
+				defer instance.Stop(ctx)
+
+				code := `print(1)`
+				if language == "JavaScript" {
+					code = `console.log(1)`
+				}
+				opts := &executor.ExecutionOptions{Language: language, Timeout: 30 * time.Second}
+
+				// Warm up so the first run's server start is not measured.
+				if _, err := instance.Execute(ctx, code, opts); err != nil {
+					b.Fatalf("Execute() error = %v", err)
+				}
+
+				b.ResetTimer()
+				for n := 0; n < b.N; n++ {
+					if _, err := instance.Execute(ctx, code, opts); err != nil {
+						b.Fatalf("Execute() error = %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDockerProviderPause(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{Runtime: "Python"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	pauser := instance.(provider.Pauser)
+	if err := pauser.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if status, err := instance.Status(ctx); err != nil || status != provider.StatusPaused {
+		t.Errorf("Status() = %v, %v, want paused", status, err)
+	}
+
+	if err := pauser.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if status, err := instance.Status(ctx); err != nil || status != provider.StatusRunning {
+		t.Errorf("Status() = %v, %v, want running", status, err)
+	}
+
+	result, err := instance.Execute(ctx, `print("resumed")`, &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "resumed\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "resumed\n")
+	}
+}
+
+func TestDockerProviderDiskLimit(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:   "Python",
+		Resources: provider.ResourceConfig{MemoryMB: 512, CPUs: 1, DiskMB: 64},
+	})
+	if errors.Is(err, provider.ErrDiskLimitUnsupported) {
+		t.Skipf("storage driver cannot limit container size: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	result, err := instance.Execute(ctx, `
+with open("big.bin", "wb") as f:
+    for _ in range(128):
+        f.write(b"\0" * (1 << 20))
+`, &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode == 0 {
+		t.Error("writing 128MB should fail under a 64MB disk limit")
+	}
+	if result.DiskUsedMB == 0 || result.DiskUsedMB > 64 {
+		t.Errorf("DiskUsedMB = %d, want between 1 and 64", result.DiskUsedMB)
+	}
+}
+
+// TestDockerProviderJavaMemoryMinimum compiles a class under the memory that
+// Create raises the 512MB default to for Java.
+func TestDockerProviderJavaMemoryMinimum(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	info, _ := langdetect.GetRuntimeInfo("Java")
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:   "Java",
+		Resources: provider.ResourceConfig{MemoryMB: info.MinMemoryMB, CPUs: 1},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	result, err := instance.Execute(ctx, `
+public class Main {
+    public static void main(String[] args) {
+        System.out.println("compiled");
+    }
+}
+`, &executor.ExecutionOptions{
+		Language: "Java",
+		Timeout:  2 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != "compiled" {
+		t.Errorf("ExitCode = %d, Stdout = %q\nStderr: %s", result.ExitCode, result.Stdout, result.Stderr)
+	}
+}
+
+// TestDockerProviderWorkDirMinimalImage runs in a working directory that a
+// minimal image does not have.
+func TestDockerProviderWorkDirMinimalImage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	for _, dir := range []string{"/workspace", "/srv/sindoq/work"} {
+		instance, err := p.Create(ctx, &provider.CreateOptions{
+			Image:   "busybox:1.36",
+			WorkDir: dir,
+		})
+		if err != nil {
+			t.Fatalf("Create(%s) error = %v", dir, err)
+		}
+		defer instance.Stop(ctx)
+
+		if got := instance.(provider.WorkDirReporter).WorkDir(); got != dir {
+			t.Errorf("WorkDir() = %q, want %q", got, dir)
+		}
+		result, err := instance.RunCommand(ctx, "sh", []string{"-c", "touch probe && pwd"})
+		if err != nil {
+			t.Fatalf("RunCommand() error = %v", err)
+		}
+		if result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != dir {
+			t.Errorf("ExitCode = %d, Stdout = %q, want to write in %s\nStderr: %s", result.ExitCode, result.Stdout, dir, result.Stderr)
+		}
+		if err := instance.FileSystem().Write(ctx, dir+"/data.txt", []byte("ok")); err != nil {
+			t.Errorf("Write() error = %v", err)
+		}
+	}
+}
+
+// TestDockerProviderCompileStream streams a Rust compiler warning as a
+// compile event ahead of the program's output.
+func TestDockerProviderCompileStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	info, _ := langdetect.GetRuntimeInfo("Rust")
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:   "Rust",
+		Resources: provider.ResourceConfig{MemoryMB: info.MinMemoryMB, CPUs: 1},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	var events []*executor.StreamEvent
+	err = instance.ExecuteStream(ctx, `
+fn main() {
+    let unused = 1;
+    println!("ran");
+}
+`, &executor.ExecutionOptions{
+		Language: "Rust",
+		Timeout:  2 * time.Minute,
+	}, func(e *executor.StreamEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	warning, stdout := -1, -1
+	for n, e := range events {
+		switch {
+		case e.Type == executor.StreamCompileStderr && strings.Contains(e.Data, "unused variable") && warning < 0:
+			warning = n
+		case e.Type == executor.StreamStdout && strings.Contains(e.Data, "ran") && stdout < 0:
+			stdout = n
+		}
+	}
+	if warning < 0 {
+		t.Fatalf("no compile_stderr event with the warning in %d events", len(events))
+	}
+	if stdout < warning {
+		t.Errorf("run output at event %d, want it after the compile warning at %d", stdout, warning)
+	}
+}
+
+// TestDockerProviderPythonCoverage measures the coverage of a Python
+// snippet that skips one branch, after failing clearly without coverage.py.
+func TestDockerProviderPythonCoverage(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	p, err := New(nil)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer p.Close()
+
+	instance, err := p.Create(ctx, &provider.CreateOptions{
+		Runtime:        "Python",
+		InternetAccess: true,
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer instance.Stop(ctx)
+
+	code := `def grade(score):
+    if score >= 50:
+        return "pass"
+    return "fail"
+
+print(grade(80))
+`
+	opts := &executor.ExecutionOptions{
+		Language: "Python",
+		Timeout:  time.Minute,
+		Coverage: true,
+	}
+
+	if _, err := instance.Execute(ctx, code, opts); err == nil || !strings.Contains(err.Error(), "coverage.py is not installed") {
+		t.Fatalf("Execute() without coverage.py error = %v, want missing tool", err)
+	}
+
+	install, err := instance.RunCommand(ctx, "pip", []string{"install", "-q", "coverage"})
+	if err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+	if install.ExitCode != 0 {
+		t.Skipf("cannot install coverage.py: %s", install.Stderr)
+	}
+
+	result, err := instance.Execute(ctx, code, opts)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "pass\n" {
+		t.Errorf("Stdout = %q, want the program's output only", result.Stdout)
+	}
+	if result.Coverage == nil {
+		t.Fatal("Coverage not reported")
+	}
+	// Four of the five statements run.
+	if result.Coverage.Percent != 80 {
+		t.Errorf("Coverage.Percent = %v, want 80", result.Coverage.Percent)
+	}
+	if !strings.Contains(string(result.Coverage.Profile), "main.py") {
+		t.Errorf("Profile = %s, want the coverage.py report", result.Coverage.Profile)
+	}
+}
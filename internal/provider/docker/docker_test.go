@@ -0,0 +1,343 @@
+package docker
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// newPingServer returns a fake daemon whose /_ping fails the first
+// failures times.
+func newPingServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_ping") {
+			http.NotFound(w, r)
+			return
+		}
+		// Ping sends HEAD and falls back to GET, so count attempts by HEAD.
+		n := pings.Load()
+		if r.Method == http.MethodHead {
+			n = pings.Add(1)
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("API-Version", "1.43")
+		w.Write([]byte("OK"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &pings
+}
+
+func newTestProvider(t *testing.T, srv *httptest.Server, retries int) *Provider {
+	t.Helper()
+
+	p, err := New(&Config{
+		Host:               "tcp://" + strings.TrimPrefix(srv.URL, "http://"),
+		ValidateRetries:    retries,
+		ValidateRetryDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestValidateRetries(t *testing.T) {
+	srv, pings := newPingServer(t, 2)
+	p := newTestProvider(t, srv, 3)
+
+	if err := p.Validate(context.Background()); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := pings.Load(); got != 3 {
+		t.Errorf("pings = %d, want 3", got)
+	}
+}
+
+func TestValidateSingleAttempt(t *testing.T) {
+	srv, pings := newPingServer(t, 1)
+	p := newTestProvider(t, srv, 0)
+
+	if err := p.Validate(context.Background()); err == nil {
+		t.Fatal("Validate() should fail without retries")
+	}
+	if got := pings.Load(); got != 1 {
+		t.Errorf("pings = %d, want 1", got)
+	}
+}
+
+func TestValidateRespectsContext(t *testing.T) {
+	srv, _ := newPingServer(t, 1000)
+	p := newTestProvider(t, srv, 1000)
+	p.config.ValidateRetryDelay = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := p.Validate(ctx); err == nil {
+		t.Fatal("Validate() should fail when the context expires")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Validate() took %v, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestCanReuseInterpreter(t *testing.T) {
+	tests := []struct {
+		name  string
+		reuse bool
+		opts  executor.ExecutionOptions
+		want  bool
+	}{
+		{"disabled", false, executor.ExecutionOptions{Language: "Python"}, false},
+		{"python", true, executor.ExecutionOptions{Language: "Python"}, true},
+		{"python stdin", true, executor.ExecutionOptions{Language: "Python", Stdin: "x"}, true},
+		{"javascript", true, executor.ExecutionOptions{Language: "JavaScript"}, true},
+		{"javascript stdin", true, executor.ExecutionOptions{Language: "JavaScript", Stdin: "x"}, false},
+		{"unsupported", true, executor.ExecutionOptions{Language: "Go"}, false},
+		{"command wrapper", true, executor.ExecutionOptions{Language: "Python", CommandWrapper: []string{"strace"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Instance{reuseInterpreter: tt.reuse}
+			if got := i.canReuseInterpreter(&tt.opts); got != tt.want {
+				t.Errorf("canReuseInterpreter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerPort(t *testing.T) {
+	info := container.InspectResponse{
+		NetworkSettings: &container.NetworkSettings{
+			NetworkSettingsBase: container.NetworkSettingsBase{
+				Ports: nat.PortMap{
+					"8080/tcp": []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "49153"}},
+				},
+			},
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {IPAddress: "172.17.0.2"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		port    int
+		wantURL string
+	}{
+		{"bound", 8080, "http://localhost:49153"},
+		{"container ip", 3000, "http://172.17.0.2:3000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := containerPort(info, tt.port)
+			if err != nil {
+				t.Fatalf("containerPort() error = %v", err)
+			}
+			if got.PublicURL != tt.wantURL {
+				t.Errorf("PublicURL = %q, want %q", got.PublicURL, tt.wantURL)
+			}
+			if got.LocalPort != tt.port {
+				t.Errorf("LocalPort = %d, want %d", got.LocalPort, tt.port)
+			}
+		})
+	}
+
+	if _, err := containerPort(container.InspectResponse{}, 8080); err == nil {
+		t.Error("containerPort() without network settings should fail")
+	}
+}
+
+func TestPortBindings(t *testing.T) {
+	exposed, bindings := portBindings([]int{8080, 3000})
+	if len(exposed) != 2 || len(bindings) != 2 {
+		t.Fatalf("got %d exposed and %d bindings, want 2 each", len(exposed), len(bindings))
+	}
+	if b := bindings["8080/tcp"]; len(b) != 1 || b[0].HostIP != "127.0.0.1" || b[0].HostPort != "" {
+		t.Errorf("bindings[8080/tcp] = %+v, want one random loopback binding", b)
+	}
+}
+
+func TestWritableVolumes(t *testing.T) {
+	mounts := writableVolumes("/workspace", "/tmp", "", "/tmp")
+	if len(mounts) != 2 {
+		t.Fatalf("got %d mounts, want 2", len(mounts))
+	}
+	for i, want := range []string{"/workspace", "/tmp"} {
+		if mounts[i].Target != want || mounts[i].Type != "volume" || mounts[i].Source != "" {
+			t.Errorf("mounts[%d] = %+v, want anonymous volume at %s", i, mounts[i], want)
+		}
+	}
+}
+
+func TestContainerUlimits(t *testing.T) {
+	ulimits := containerUlimits(map[string]executor.Ulimit{
+		"stack":  {Soft: 8 << 20, Hard: -1},
+		"nofile": {Soft: 4096, Hard: 8192},
+	})
+	if len(ulimits) != 2 {
+		t.Fatalf("got %d ulimits, want 2", len(ulimits))
+	}
+	if u := ulimits[0]; u.Name != "nofile" || u.Soft != 4096 || u.Hard != 8192 {
+		t.Errorf("ulimits[0] = %+v, want nofile 4096/8192", u)
+	}
+	if u := ulimits[1]; u.Name != "stack" || u.Soft != 8<<20 || u.Hard != -1 {
+		t.Errorf("ulimits[1] = %+v, want stack 8MiB/unlimited", u)
+	}
+}
+
+func TestContainerResources(t *testing.T) {
+	quota := containerResources(provider.ResourceConfig{CPUs: 1.5, CPUShares: 512})
+	if quota.NanoCPUs != 1_500_000_000 || quota.CPUShares != 0 {
+		t.Errorf("quota mode NanoCPUs = %d, CPUShares = %d, want 1.5e9, 0", quota.NanoCPUs, quota.CPUShares)
+	}
+	shares := containerResources(provider.ResourceConfig{CPUs: 1.5, CPUMode: provider.CPUModeShares, CPUShares: 512})
+	if shares.NanoCPUs != 0 || shares.CPUShares != 512 {
+		t.Errorf("shares mode NanoCPUs = %d, CPUShares = %d, want 0, 512", shares.NanoCPUs, shares.CPUShares)
+	}
+}
+
+func TestParseEnviron(t *testing.T) {
+	got := parseEnviron([]byte("PATH=/usr/bin\x00GREETING=a=b\x00EMPTY=\x00\x00junk\x00"))
+	want := map[string]string{"PATH": "/usr/bin", "GREETING": "a=b", "EMPTY": ""}
+	if !maps.Equal(got, want) {
+		t.Errorf("parseEnviron() = %v, want %v", got, want)
+	}
+}
+
+func TestSeccompSecurityOpt(t *testing.T) {
+	got, err := seccompSecurityOpt(&provider.SyscallFilter{Deny: []string{"mount", "ptrace"}, DenySocketFamilies: []int{2}})
+	if err != nil {
+		t.Fatalf("seccompSecurityOpt() error = %v", err)
+	}
+	want := `seccomp={"defaultAction":"SCMP_ACT_ALLOW","syscalls":[` +
+		`{"names":["mount","ptrace"],"action":"SCMP_ACT_ERRNO","errnoRet":1},` +
+		`{"names":["socket"],"action":"SCMP_ACT_ERRNO","errnoRet":1,"args":[{"index":0,"value":2,"op":"SCMP_CMP_EQ"}]}]}`
+	if got != want {
+		t.Errorf("seccompSecurityOpt() = %s, want %s", got, want)
+	}
+
+	got, err = seccompSecurityOpt(&provider.SyscallFilter{DenyCloneFlags: 0x10020000, Unimplemented: []string{"clone3"}, FixedPersonality: true})
+	if err != nil {
+		t.Fatalf("seccompSecurityOpt() error = %v", err)
+	}
+	want = `seccomp={"defaultAction":"SCMP_ACT_ALLOW","syscalls":[` +
+		`{"names":["clone"],"action":"SCMP_ACT_ERRNO","errnoRet":1,"args":[{"index":0,"value":131072,"valueTwo":131072,"op":"SCMP_CMP_MASKED_EQ"}]},` +
+		`{"names":["clone"],"action":"SCMP_ACT_ERRNO","errnoRet":1,"args":[{"index":0,"value":268435456,"valueTwo":268435456,"op":"SCMP_CMP_MASKED_EQ"}]},` +
+		`{"names":["clone3"],"action":"SCMP_ACT_ERRNO","errnoRet":38},` +
+		`{"names":["personality"],"action":"SCMP_ACT_ERRNO","errnoRet":1,"args":[{"index":0,"value":4294967295,"op":"SCMP_CMP_NE"}]}]}`
+	if got != want {
+		t.Errorf("seccompSecurityOpt() = %s, want %s", got, want)
+	}
+}
+
+func TestResolveImage(t *testing.T) {
+	p := &Provider{config: DefaultConfig()}
+
+	tests := []struct {
+		name string
+		opts provider.CreateOptions
+		want string
+	}{
+		{"image overrides runtime", provider.CreateOptions{Image: "python:3.11-slim", Runtime: "Python@3.12"}, "python:3.11-slim"},
+		{"versioned runtime", provider.CreateOptions{Runtime: "Python@3.11"}, "python:3.11-slim"},
+		{"runtime default", provider.CreateOptions{Runtime: "Go"}, "golang:1.25-alpine"},
+		{"unknown runtime", provider.CreateOptions{Runtime: "Cobol"}, "python:3.12-slim"},
+		{"nothing set", provider.CreateOptions{}, "python:3.12-slim"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.resolveImage(&tt.opts)
+			if err != nil {
+				t.Fatalf("resolveImage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := p.resolveImage(&provider.CreateOptions{Runtime: "Python@2.7"}); err == nil {
+		t.Error("resolveImage() should reject unavailable versions")
+	}
+}
+
+// TestCreateConflictWaitsForStart checks that a Create losing the race for
+// a deterministic ID waits for the winner's container to start and
+// reattaches to it, rather than removing it while it is still created.
+func TestCreateConflictWaitsForStart(t *testing.T) {
+	var inspects atomic.Int32
+	var removed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.43")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/_ping"):
+			w.Write([]byte("OK"))
+		case strings.HasSuffix(r.URL.Path, "/containers/sindoq-race/json"):
+			// Missing for the first Create's check, then created by the
+			// winner, then started.
+			switch inspects.Add(1) {
+			case 1:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message":"No such container: sindoq-race"}`))
+			case 2:
+				w.Write([]byte(`{"Id":"sindoq-race","State":{"Status":"created"},"Config":{"Labels":{"hash":"abc"}}}`))
+			default:
+				w.Write([]byte(`{"Id":"sindoq-race","State":{"Status":"running","Running":true},"Config":{"Labels":{"hash":"abc"}}}`))
+			}
+		case strings.Contains(r.URL.Path, "/images/"):
+			w.Write([]byte(`{"Id":"sha256:abc"}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/create"):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Conflict. The container name \"/sindoq-race\" is already in use"}`))
+		case r.Method == http.MethodDelete:
+			removed.Store(true)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	p := newTestProvider(t, srv, 0)
+
+	inst, err := p.Create(context.Background(), &provider.CreateOptions{
+		ID:     "sindoq-race",
+		Labels: map[string]string{"hash": "abc"},
+		Image:  "python:3.12-slim",
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if removed.Load() {
+		t.Error("Create() removed the container another Create was starting")
+	}
+	if r, ok := inst.(provider.ReattachReporter); !ok || !r.Reattached() {
+		t.Error("Create() did not reattach to the started container")
+	}
+	if n := inspects.Load(); n < 3 {
+		t.Errorf("inspected %d times, want a wait until the container runs", n)
+	}
+}
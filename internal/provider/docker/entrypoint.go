@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// keepAliveCmd keeps the container running. It replaces the entrypoint, or
+// with CreateOptions.RespectEntrypoint is passed to it as arguments.
+var keepAliveCmd = []string{"tail", "-f", "/dev/null"}
+
+// entrypointEnvScript waits for the entrypoint to exec keepAliveCmd and
+// prints that process's environment. Execs start from the container's
+// configured environment, not from PID 1's, so this is how they get what
+// the entrypoint exported.
+const entrypointEnvScript = `i=0
+while [ "$i" -lt 100 ]; do
+	for p in /proc/[0-9]*; do
+		if [ "$(tr '\0' ' ' < "$p/cmdline" 2>/dev/null)" = "tail -f /dev/null " ]; then
+			cat "$p/environ" && exit 0
+		fi
+	done
+	sleep 0.1
+	i=$((i + 1))
+done
+echo "entrypoint did not run its arguments" >&2
+exit 1`
+
+// entrypointEnv returns the environment the image's entrypoint exported.
+func (i *Instance) entrypointEnv(ctx context.Context) (map[string]string, error) {
+	result, err := i.runExec(ctx, []string{"sh", "-c", entrypointEnvScript}, &executor.ExecutionOptions{WorkDir: "/"})
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read entrypoint environment: %w", err)
+	}
+	return parseEnviron([]byte(result.Stdout)), nil
+}
+
+// parseEnviron parses the NUL-separated KEY=value pairs of
+// /proc/<pid>/environ.
+func parseEnviron(data []byte) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range bytes.Split(data, []byte{0}) {
+		k, v, ok := strings.Cut(string(kv), "=")
+		if ok && k != "" {
+			env[k] = v
+		}
+	}
+	return env
+}
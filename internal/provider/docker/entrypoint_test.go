@@ -0,0 +1,87 @@
+//go:build integration
+
+package docker_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/build"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
+)
+
+const entrypointImage = "sindoq-test-entrypoint"
+
+// entrypointDockerfile builds an image whose entrypoint exports a variable
+// code depends on, as virtualenv activation scripts do.
+const entrypointDockerfile = `FROM alpine:3.20
+RUN printf '#!/bin/sh\nexport GREETING=hello\nexec "$@"\n' > /entry.sh && chmod +x /entry.sh
+ENTRYPOINT ["/entry.sh"]
+`
+
+// buildEntrypointImage builds entrypointImage, skipping the test when
+// Docker is not available, and removes the image when the test ends.
+func buildEntrypointImage(t *testing.T, ctx context.Context) {
+	t.Helper()
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	if _, err := cli.Ping(ctx); err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+
+	var buildCtx bytes.Buffer
+	tw := tar.NewWriter(&buildCtx)
+	tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0o644, Size: int64(len(entrypointDockerfile))})
+	tw.Write([]byte(entrypointDockerfile))
+	tw.Close()
+
+	resp, err := cli.ImageBuild(ctx, &buildCtx, build.ImageBuildOptions{Tags: []string{entrypointImage}, Remove: true})
+	if err != nil {
+		t.Fatalf("ImageBuild() error = %v", err)
+	}
+	out, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(out), `"errorDetail"`) {
+		t.Fatalf("ImageBuild() failed: %s", out)
+	}
+	t.Cleanup(func() {
+		cli.ImageRemove(context.WithoutCancel(ctx), entrypointImage, image.RemoveOptions{Force: true})
+	})
+}
+
+func TestRespectEntrypoint(t *testing.T) {
+	ctx := context.Background()
+	buildEntrypointImage(t, ctx)
+
+	for _, respect := range []bool{false, true} {
+		opts := []sindoq.Option{sindoq.WithProvider("docker"), sindoq.WithImage(entrypointImage)}
+		want := ""
+		if respect {
+			opts = append(opts, sindoq.WithRespectEntrypoint())
+			want = "hello"
+		}
+		sb, err := sindoq.Create(ctx, opts...)
+		if err != nil {
+			t.Fatalf("Create(respect=%v) error = %v", respect, err)
+		}
+		result, err := sb.RunCommand(ctx, "sh", "-c", "echo $GREETING")
+		sb.Stop(ctx)
+		if err != nil {
+			t.Fatalf("RunCommand(respect=%v) error = %v", respect, err)
+		}
+		if got := strings.TrimSpace(result.Stdout); got != want {
+			t.Errorf("GREETING with respect=%v = %q, want %q", respect, got, want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// execDaemon is a fake Docker API serving execs that print to stdout and
+// stderr and exit with exitCode. The first racyInspects inspects of each
+// exec report it still running, as a daemon that has closed the streams
+// but not yet recorded the exit does. exitFor, if set, picks the exit code
+// from the exec's command instead.
+type execDaemon struct {
+	stdout, stderr string
+	exitCode       int
+	racyInspects   int
+	exitFor        func(cmd []string) int
+
+	mu        sync.Mutex
+	next      int
+	inspects  map[string]int
+	exitCodes map[string]int
+}
+
+func (d *execDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("API-Version", "1.43")
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/_ping"):
+		w.Write([]byte("OK"))
+	case strings.HasSuffix(path, "/exec") && strings.Contains(path, "/containers/"):
+		var body struct{ Cmd []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		d.mu.Lock()
+		d.next++
+		id := fmt.Sprintf("exec-%d", d.next)
+		d.exitCodes[id] = d.exitCode
+		if d.exitFor != nil {
+			d.exitCodes[id] = d.exitFor(body.Cmd)
+		}
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Id": id})
+	case strings.HasSuffix(path, "/start"):
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+		stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte(d.stdout))
+		stdcopy.NewStdWriter(conn, stdcopy.Stderr).Write([]byte(d.stderr))
+	case strings.HasSuffix(path, "/json") && strings.Contains(path, "/exec/"):
+		id := strings.TrimSuffix(path[strings.Index(path, "/exec/")+len("/exec/"):], "/json")
+		d.mu.Lock()
+		d.inspects[id]++
+		running := d.inspects[id] <= d.racyInspects
+		exitCode := d.exitCodes[id]
+		d.mu.Unlock()
+		if running {
+			exitCode = 0
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ID": id, "Running": running, "ExitCode": exitCode})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newExecTestInstance(t *testing.T, d *execDaemon) *Instance {
+	t.Helper()
+
+	d.inspects = make(map[string]int)
+	d.exitCodes = make(map[string]int)
+	srv := httptest.NewServer(d)
+	t.Cleanup(srv.Close)
+	p, err := New(&Config{Host: "tcp://" + strings.TrimPrefix(srv.URL, "http://")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return &Instance{id: "test-container", client: p.client, config: p.config}
+}
+
+func TestRunCommandCapturesImmediateOutput(t *testing.T) {
+	d := &execDaemon{stdout: "hello\n", stderr: "warn\n", exitCode: 3, racyInspects: 2}
+	i := newExecTestInstance(t, d)
+
+	for n := range 50 {
+		result, err := i.RunCommand(context.Background(), "sh", []string{"-c", "echo hello; echo warn >&2; exit 3"})
+		if err != nil {
+			t.Fatalf("run %d: RunCommand() error = %v", n, err)
+		}
+		if result.Stdout != "hello\n" || result.Stderr != "warn\n" {
+			t.Fatalf("run %d: output = %q, %q, want all of it", n, result.Stdout, result.Stderr)
+		}
+		if result.ExitCode != 3 {
+			t.Fatalf("run %d: ExitCode = %d, want 3 once the daemon records the exit", n, result.ExitCode)
+		}
+	}
+}
+
+func TestExecExitCodeRespectsContext(t *testing.T) {
+	d := &execDaemon{racyInspects: 1 << 30}
+	i := newExecTestInstance(t, d)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := i.execExitCode(ctx, "exec-1"); err == nil {
+		t.Error("execExitCode() should fail once the context is done")
+	}
+}
+
+func TestPrepareWorkDir(t *testing.T) {
+	// The image's user may create nothing but the directories in writable.
+	writable := map[string]bool{"/tmp/sindoq": true, "/srv/app": true}
+	d := &execDaemon{exitFor: func(cmd []string) int {
+		if writable[cmd[len(cmd)-1]] {
+			return 0
+		}
+		return 1
+	}}
+	i := newExecTestInstance(t, d)
+	ctx := context.Background()
+
+	tests := []struct {
+		name, dir, fallback string
+		want                string
+		wantErr             bool
+	}{
+		{name: "writable", dir: "/srv/app", fallback: "/tmp/sindoq", want: "/srv/app"},
+		{name: "fallback", dir: "/workspace", fallback: "/tmp/sindoq", want: "/tmp/sindoq"},
+		{name: "no fallback", dir: "/workspace", wantErr: true},
+		{name: "fallback not writable", dir: "/workspace", fallback: "/opt/work", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := i.prepareWorkDir(ctx, tt.dir, tt.fallback)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prepareWorkDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("prepareWorkDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamExecEventTypes(t *testing.T) {
+	d := &execDaemon{stdout: "built\n", stderr: "warning: unused variable\n", exitCode: 2}
+	i := newExecTestInstance(t, d)
+
+	var mu sync.Mutex
+	got := make(map[executor.StreamEventType]string)
+	handler := func(ev *executor.StreamEvent) error {
+		mu.Lock()
+		got[ev.Type] += ev.Data
+		mu.Unlock()
+		return nil
+	}
+	config := container.ExecOptions{Cmd: []string{"rustc", "main.rs"}, AttachStdout: true, AttachStderr: true}
+	exitCode, err := i.streamExec(context.Background(), config, nil, 0, false, executor.StreamCompileStdout, executor.StreamCompileStderr, handler)
+	if err != nil {
+		t.Fatalf("streamExec() error = %v", err)
+	}
+	if exitCode != 2 {
+		t.Errorf("exit code = %d, want 2", exitCode)
+	}
+	if got[executor.StreamCompileStdout] != "built\n" || got[executor.StreamCompileStderr] != "warning: unused variable\n" {
+		t.Errorf("events = %q, want the output as compile events", got)
+	}
+	if _, ok := got[executor.StreamStderr]; ok {
+		t.Error("compile output should not arrive as run stderr")
+	}
+}
+
+func TestStreamExecRawStream(t *testing.T) {
+	d := &execDaemon{stdout: "hello\n", stderr: "oops\n"}
+	i := newExecTestInstance(t, d)
+
+	var mu sync.Mutex
+	got := make(map[executor.StreamEventType]string)
+	handler := func(ev *executor.StreamEvent) error {
+		mu.Lock()
+		got[ev.Type] += ev.Data
+		mu.Unlock()
+		return nil
+	}
+	config := container.ExecOptions{Cmd: []string{"python3", "main.py"}, AttachStdout: true, AttachStderr: true}
+	if _, err := i.streamExec(context.Background(), config, nil, 0, true, executor.StreamStdout, executor.StreamStderr, handler); err != nil {
+		t.Fatalf("streamExec() error = %v", err)
+	}
+
+	var want bytes.Buffer
+	stdcopy.NewStdWriter(&want, stdcopy.Stdout).Write([]byte("hello\n"))
+	stdcopy.NewStdWriter(&want, stdcopy.Stderr).Write([]byte("oops\n"))
+	if got[executor.StreamRaw] != want.String() {
+		t.Errorf("raw events = %q, want the frames %q", got[executor.StreamRaw], want.String())
+	}
+	if got[executor.StreamStdout] != "hello\n" || got[executor.StreamStderr] != "oops\n" {
+		t.Errorf("events = %q, want the demultiplexed output as well", got)
+	}
+}
+
+func TestCopyFrames(t *testing.T) {
+	var in bytes.Buffer
+	stdcopy.NewStdWriter(&in, stdcopy.Stdout).Write([]byte("out"))
+	stdcopy.NewStdWriter(&in, stdcopy.Stderr).Write([]byte("err"))
+	stdcopy.NewStdWriter(&in, stdcopy.Systemerr).Write([]byte("daemon failed"))
+
+	var stdout, stderr bytes.Buffer
+	var frames []string
+	err := copyFrames(&stdout, &stderr, &in, func(frame []byte) { frames = append(frames, string(frame)) })
+	if err == nil || !strings.Contains(err.Error(), "daemon failed") {
+		t.Errorf("copyFrames() error = %v, want the daemon's error", err)
+	}
+	if stdout.String() != "out" || stderr.String() != "err" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+	if len(frames) != 3 || frames[0] != "\x01\x00\x00\x00\x00\x00\x00\x03out" {
+		t.Errorf("frames = %q", frames)
+	}
+}
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// faketimePaths are where distributions install libfaketime.
+var faketimePaths = []string{
+	"/usr/lib/x86_64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/aarch64-linux-gnu/faketime/libfaketime.so.1",
+	"/usr/lib/faketime/libfaketime.so.1",
+	"/usr/local/lib/faketime/libfaketime.so.1",
+}
+
+// clockOffsetOptions returns opts with libfaketime preloaded so the program
+// sees the wall clock shifted by opts.ClockOffset. Monotonic clocks are left
+// alone so timeouts keep working. It reports false, returning opts
+// unchanged, when no offset is set or the image has no libfaketime.
+func (i *Instance) clockOffsetOptions(ctx context.Context, opts *executor.ExecutionOptions) (*executor.ExecutionOptions, bool, error) {
+	if opts.ClockOffset == 0 {
+		return opts, false, nil
+	}
+
+	lib, err := i.findFaketime(ctx)
+	if err != nil || lib == "" {
+		return opts, false, err
+	}
+
+	offset := strconv.FormatFloat(opts.ClockOffset.Seconds(), 'f', -1, 64)
+	if opts.ClockOffset > 0 {
+		offset = "+" + offset
+	}
+
+	shifted := *opts
+	shifted.Env = maps.Clone(opts.Env)
+	if shifted.Env == nil {
+		shifted.Env = make(map[string]string)
+	}
+	if preload := shifted.Env["LD_PRELOAD"]; preload != "" {
+		lib += ":" + preload
+	}
+	shifted.Env["LD_PRELOAD"] = lib
+	shifted.Env["FAKETIME"] = offset
+	shifted.Env["FAKETIME_DONT_FAKE_MONOTONIC"] = "1"
+	return &shifted, true, nil
+}
+
+// findFaketime returns the path of libfaketime in the container, or "" if
+// the image has none. The answer is cached.
+func (i *Instance) findFaketime(ctx context.Context) (string, error) {
+	i.faketimeMu.Lock()
+	defer i.faketimeMu.Unlock()
+
+	if i.faketimeChecked {
+		return i.faketimePath, nil
+	}
+
+	script := `for f in "$@"; do if [ -f "$f" ]; then echo "$f"; exit 0; fi; done`
+	result, err := i.runExec(ctx, append([]string{"sh", "-c", script, "sh"}, faketimePaths...), &executor.ExecutionOptions{})
+	if err != nil {
+		return "", fmt.Errorf("look for libfaketime: %w", err)
+	}
+	i.faketimePath = strings.TrimSpace(result.Stdout)
+	i.faketimeChecked = true
+	return i.faketimePath, nil
+}
@@ -8,10 +8,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 )
 
@@ -184,6 +186,40 @@ func (d *dockerFS) Download(ctx context.Context, remotePath string, writer io.Wr
 	return err
 }
 
+// DownloadRange streams a byte range of a file from the container.
+// The range is cut inside the container so only the requested bytes are
+// transferred; cancelling ctx aborts the transfer.
+func (d *dockerFS) DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error {
+	script := `tail -c +"$1" "$2"`
+	if length > 0 {
+		script += ` | head -c "$3"`
+	}
+
+	execID, resp, err := d.instance.startExec(ctx, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", script, "sh", strconv.FormatInt(offset+1, 10), path, strconv.FormatInt(length, 10)},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	var stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(writer, &stderr, resp.Reader); err != nil {
+		return fmt.Errorf("read output: %w", err)
+	}
+
+	exitCode, err := d.instance.execExitCode(ctx, execID)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("download range failed: %s", stderr.String())
+	}
+	return nil
+}
+
 // MkDir creates a directory.
 func (d *dockerFS) MkDir(ctx context.Context, path string) error {
 	result, err := d.instance.RunCommand(ctx, "mkdir", []string{"-p", path})
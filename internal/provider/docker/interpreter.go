@@ -0,0 +1,280 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// interpreterDir holds the server scripts and request/response FIFOs.
+const interpreterDir = "/tmp"
+
+// interpreterServers are the scripts run as long-lived interpreters. Each
+// reads a JSON request from the request FIFO, runs the file it names in a
+// fresh global scope, and writes a JSON response to the response FIFO.
+var interpreterServers = map[string]struct {
+	ext    string
+	script string
+}{
+	"Python":     {".py", pythonServer},
+	"JavaScript": {".js", nodeServer},
+}
+
+const pythonServer = `import contextlib, io, json, os, sys, traceback
+
+REQ, RESP = sys.argv[1], sys.argv[2]
+real_exit = os._exit
+
+def _exit(code):
+    raise SystemExit(code)
+
+while True:
+    with open(REQ) as f:
+        req = json.load(f)
+    out, err = io.StringIO(), io.StringIO()
+    env = dict(os.environ)
+    code = 0
+    try:
+        os._exit = _exit
+        os.environ.update(req.get("env") or {})
+        os.chdir(req.get("workdir") or "/")
+        sys.stdin = io.StringIO(req.get("stdin") or "")
+        path = req["path"]
+        with contextlib.redirect_stdout(out), contextlib.redirect_stderr(err):
+            try:
+                with open(path) as src:
+                    exec(compile(src.read(), path, "exec"), {"__name__": "__main__", "__file__": path})
+            except SystemExit as e:
+                if e.code is None or isinstance(e.code, int):
+                    code = e.code or 0
+                else:
+                    print(e.code, file=sys.stderr)
+                    code = 1
+            except BaseException as e:
+                traceback.print_exception(type(e), e, e.__traceback__.tb_next)
+                code = 1
+    finally:
+        os._exit = real_exit
+        os.environ.clear()
+        os.environ.update(env)
+    with open(RESP, "w") as f:
+        json.dump({"exit_code": code, "stdout": out.getvalue(), "stderr": err.getvalue()}, f)
+`
+
+const nodeServer = `const fs = require("fs"), vm = require("vm"), util = require("util"), { createRequire } = require("module");
+
+const [REQ, RESP] = process.argv.slice(2);
+
+// Code sees a process whose exit ends the run instead of the server.
+class Exit {
+  constructor(code) { this.code = Number(code ?? process.exitCode ?? 0) || 0; }
+}
+const proc = new Proxy(process, {
+  get(target, key) {
+    if (key === "exit") return (code) => { throw new Exit(code); };
+    const value = Reflect.get(target, key, target);
+    return typeof value === "function" ? value.bind(target) : value;
+  },
+});
+
+(async () => {
+  for (;;) {
+    const req = JSON.parse(fs.readFileSync(REQ, "utf8"));
+    let stdout = "", stderr = "", code = 0;
+    const write = (toStdout) => (...args) => {
+      const line = util.format(...args) + "\n";
+      if (toStdout) stdout += line; else stderr += line;
+    };
+    const env = { ...process.env };
+    try {
+      Object.assign(process.env, req.env || {});
+      process.chdir(req.workdir || "/");
+      const console = { log: write(true), info: write(true), debug: write(true), warn: write(false), error: write(false) };
+      const module = { exports: {} };
+      const context = vm.createContext({
+        console, process: proc, Buffer, module, exports: module.exports,
+        require: createRequire(req.path), __filename: req.path,
+        setTimeout, clearTimeout, setInterval, clearInterval, setImmediate, clearImmediate,
+        URL, TextEncoder, TextDecoder,
+      });
+      const value = vm.runInContext(fs.readFileSync(req.path, "utf8"), context, { filename: req.path });
+      if (value && typeof value.then === "function") await value;
+      code = Number(process.exitCode) || 0;
+    } catch (e) {
+      if (e instanceof Exit) {
+        code = e.code;
+      } else {
+        stderr += (e && e.stack ? e.stack : String(e)) + "\n";
+        code = 1;
+      }
+    } finally {
+      process.exitCode = undefined;
+      for (const k of Object.keys(process.env)) if (!(k in env)) delete process.env[k];
+      Object.assign(process.env, env);
+    }
+    fs.writeFileSync(RESP, JSON.stringify({ exit_code: code, stdout, stderr }));
+  }
+})();
+`
+
+// warmExecScript writes the request on stdin to the FIFO $1 and prints the
+// response read from the FIFO $2 of the server with pid $3. Opening a FIFO
+// blocks until its other end is opened, so a watcher opens both ends itself
+// once the server has died, releasing the cats; the missing response then
+// fails the run at once rather than at its timeout. The server is reparented
+// to the container's init, which does not reap it, so a dead server is a
+// zombie that kill -0 still finds; its state is read from /proc instead.
+const warmExecScript = `alive() {
+	s=$(cat "/proc/$1/stat" 2>/dev/null) || return 1
+	s=${s##*) }
+	[ "${s%% *}" != Z ]
+}
+exec 3<&0
+{ cat >"$1" <&3 && cat "$2"; } &
+r=$!
+{
+	while alive "$3"; do sleep 0.1; done
+	while alive "$r"; do
+		: <>"$1"
+		: <>"$2"
+		sleep 0.1
+	done
+} </dev/null >/dev/null 2>&1 &
+w=$!
+wait "$r"
+status=$?
+kill "$w" 2>/dev/null
+exit "$status"
+`
+
+// interpreterRequest is sent to an interpreter server.
+type interpreterRequest struct {
+	Path    string            `json:"path"`
+	WorkDir string            `json:"workdir"`
+	Env     map[string]string `json:"env,omitempty"`
+	Stdin   string            `json:"stdin,omitempty"`
+}
+
+// interpreterResponse is returned by an interpreter server.
+type interpreterResponse struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// canReuseInterpreter reports whether opts can be served by a warm
+// interpreter. Node's server has no stdin, so those runs start fresh.
+func (i *Instance) canReuseInterpreter(opts *executor.ExecutionOptions) bool {
+	// The server's umask and preloaded libraries are fixed when it starts,
+	// and it runs no command a wrapper could be put in front of. Nor does
+	// it run under an overridden interpreter, and code it runs inline sees
+	// the server's argv rather than program arguments.
+	if !i.reuseInterpreter || opts.Umask != nil || opts.ClockOffset != 0 || len(opts.CommandWrapper) > 0 || opts.Interpreter != "" || len(opts.Args) > 0 {
+		return false
+	}
+	if _, ok := interpreterServers[opts.Language]; !ok {
+		return false
+	}
+	return opts.Language != "JavaScript" || opts.Stdin == ""
+}
+
+// executeWarm runs the code file at codePath in the language's interpreter
+// server, starting the server first if needed. If ctx ends mid-run or the
+// server dies, the server is killed and restarted on the next call.
+func (i *Instance) executeWarm(ctx context.Context, runtimeInfo *langdetect.RuntimeInfo, codePath string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	i.interpMu.Lock()
+	defer i.interpMu.Unlock()
+
+	lang := runtimeInfo.Language
+	pid, ok := i.interpreters[lang]
+	if !ok {
+		var err error
+		if pid, err = i.startInterpreter(ctx, runtimeInfo); err != nil {
+			return nil, fmt.Errorf("start %s interpreter: %w", lang, err)
+		}
+		i.interpreters[lang] = pid
+	}
+
+	req, err := json.Marshal(interpreterRequest{
+		Path:    codePath,
+		WorkDir: opts.WorkDir,
+		Env:     opts.Env,
+		Stdin:   opts.Stdin,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reqPath, respPath := interpreterFIFOs(lang)
+	result, err := i.runExec(ctx, []string{"sh", "-c", warmExecScript, "sh", reqPath, respPath, strconv.Itoa(pid)}, &executor.ExecutionOptions{
+		WorkDir: opts.WorkDir,
+		Stdin:   string(req),
+	})
+
+	var resp interpreterResponse
+	if err == nil {
+		err = json.Unmarshal([]byte(result.Stdout), &resp)
+		if err != nil {
+			err = fmt.Errorf("%s interpreter exited: %s", lang, strings.TrimSpace(result.Stderr))
+		}
+	}
+	if err != nil {
+		i.stopInterpreter(lang, pid)
+		return nil, err
+	}
+
+	return &executor.ExecutionResult{
+		ExitCode: resp.ExitCode,
+		Stdout:   resp.Stdout,
+		Stderr:   resp.Stderr,
+	}, nil
+}
+
+// startInterpreter writes the server script, creates its FIFOs and starts
+// it in the background, returning its pid.
+func (i *Instance) startInterpreter(ctx context.Context, runtimeInfo *langdetect.RuntimeInfo) (int, error) {
+	server := interpreterServers[runtimeInfo.Language]
+	lang := strings.ToLower(runtimeInfo.Language)
+	script := fmt.Sprintf("%s/sindoq-%s-server%s", interpreterDir, lang, server.ext)
+	if err := i.writeFile(ctx, script, []byte(server.script)); err != nil {
+		return 0, err
+	}
+
+	reqPath, respPath := interpreterFIFOs(runtimeInfo.Language)
+	interp := strings.Join(runtimeInfo.RunCommand, " ")
+	start := fmt.Sprintf(`rm -f "$1" "$2" && mkfifo "$1" "$2" && %s "$3" "$1" "$2" >/dev/null 2>&1 </dev/null & echo $!`, interp)
+
+	result, err := i.runExec(ctx, []string{"sh", "-c", start, "sh", reqPath, respPath, script}, &executor.ExecutionOptions{WorkDir: "/"})
+	if err != nil {
+		return 0, err
+	}
+	if result.ExitCode != 0 {
+		return 0, fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(result.Stdout))
+	if err != nil {
+		return 0, fmt.Errorf("read pid: %w", err)
+	}
+	return pid, nil
+}
+
+// stopInterpreter kills a server so the next run starts a fresh one.
+func (i *Instance) stopInterpreter(lang string, pid int) {
+	delete(i.interpreters, lang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	i.runExec(ctx, []string{"kill", "-9", strconv.Itoa(pid)}, &executor.ExecutionOptions{WorkDir: "/"})
+}
+
+// interpreterFIFOs returns the request and response FIFO paths for lang.
+func interpreterFIFOs(lang string) (string, string) {
+	base := fmt.Sprintf("%s/sindoq-%s", interpreterDir, strings.ToLower(lang))
+	return base + ".req", base + ".resp"
+}
@@ -3,6 +3,11 @@ package docker
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/happyhackingspace/sindoq/internal/provider"
 )
@@ -10,27 +15,39 @@ import (
 // dockerNetwork implements provider.Network for Docker containers.
 type dockerNetwork struct {
 	instance *Instance
+	mu       sync.RWMutex
 	ports    map[int]*provider.PublishedPort
 }
 
-// PublishPort exposes a port publicly.
-// Note: Docker requires port mappings at container creation time.
-// This implementation provides a stub that returns an error.
+// PublishPort returns a host-reachable URL for a port in the container.
+// Docker only binds host ports at creation time, so ports listed in
+// CreateOptions.Ports are served from their loopback host port. Other ports
+// fall back to the container's bridge IP, which only Linux hosts can reach.
 func (n *dockerNetwork) PublishPort(ctx context.Context, port int) (*provider.PublishedPort, error) {
-	// Docker requires port mappings to be specified at container creation time.
-	// For dynamic port publishing, we would need to:
-	// 1. Stop the container
-	// 2. Commit it to an image
-	// 3. Recreate with new port mappings
-	// This is not practical for a sandbox environment.
-
-	// Return an error indicating this limitation
-	return nil, fmt.Errorf("Docker provider requires ports to be configured at sandbox creation time. " +
-		"Use CreateOptions.Metadata[\"ports\"] to specify port mappings")
+	info, err := n.instance.client.ContainerInspect(ctx, n.instance.id)
+	if err != nil {
+		return nil, fmt.Errorf("inspect container: %w", err)
+	}
+
+	published, err := containerPort(info, port)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ports == nil {
+		n.ports = make(map[int]*provider.PublishedPort)
+	}
+	n.ports[port] = published
+	return published, nil
 }
 
 // GetPublicURL returns the public URL for an exposed port.
 func (n *dockerNetwork) GetPublicURL(port int) (string, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	if n.ports == nil {
 		return "", fmt.Errorf("no ports published")
 	}
@@ -44,14 +61,13 @@ func (n *dockerNetwork) GetPublicURL(port int) (string, error) {
 }
 
 // ListPorts returns all published ports.
-// Note: This is a simplified implementation that returns cached ports.
 func (n *dockerNetwork) ListPorts(ctx context.Context) ([]*provider.PublishedPort, error) {
-	ports := make([]*provider.PublishedPort, 0)
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 
-	if n.ports != nil {
-		for _, p := range n.ports {
-			ports = append(ports, p)
-		}
+	ports := make([]*provider.PublishedPort, 0, len(n.ports))
+	for _, p := range n.ports {
+		ports = append(ports, p)
 	}
 
 	return ports, nil
@@ -63,5 +79,51 @@ func (n *dockerNetwork) UnpublishPort(ctx context.Context, port int) error {
 	return fmt.Errorf("Docker provider does not support dynamic port unpublishing")
 }
 
+// portBindings exposes ports and binds each to a random loopback host port.
+func portBindings(ports []int) (nat.PortSet, nat.PortMap) {
+	exposed := make(nat.PortSet, len(ports))
+	bindings := make(nat.PortMap, len(ports))
+	for _, port := range ports {
+		p := nat.Port(strconv.Itoa(port) + "/tcp")
+		exposed[p] = struct{}{}
+		bindings[p] = []nat.PortBinding{{HostIP: "127.0.0.1"}}
+	}
+	return exposed, bindings
+}
+
+// containerPort resolves the address of port from an inspected container,
+// preferring a host port binding over the container IP.
+func containerPort(info container.InspectResponse, port int) (*provider.PublishedPort, error) {
+	if info.NetworkSettings == nil {
+		return nil, fmt.Errorf("port %d not reachable: container has no network", port)
+	}
+
+	for _, b := range info.NetworkSettings.Ports[nat.Port(strconv.Itoa(port)+"/tcp")] {
+		hostPort, err := strconv.Atoi(b.HostPort)
+		if err != nil {
+			continue
+		}
+		return &provider.PublishedPort{
+			LocalPort:  port,
+			PublicPort: hostPort,
+			Protocol:   "tcp",
+			PublicURL:  fmt.Sprintf("http://localhost:%d", hostPort),
+		}, nil
+	}
+
+	for _, endpoint := range info.NetworkSettings.Networks {
+		if endpoint != nil && endpoint.IPAddress != "" {
+			return &provider.PublishedPort{
+				LocalPort:  port,
+				PublicPort: port,
+				Protocol:   "tcp",
+				PublicURL:  fmt.Sprintf("http://%s:%d", endpoint.IPAddress, port),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("port %d not reachable: publish it with CreateOptions.Ports", port)
+}
+
 // Ensure dockerNetwork implements provider.Network
 var _ provider.Network = (*dockerNetwork)(nil)
@@ -0,0 +1,56 @@
+package docker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Stream identifiers in the header of Docker's multiplexed frames.
+const (
+	frameStdout    = 1
+	frameStderr    = 2
+	frameSystemErr = 3
+)
+
+// frameHeaderLen is the size of a frame header: the stream byte, three
+// bytes of padding and the big-endian payload length.
+const frameHeaderLen = 8
+
+// copyFrames demultiplexes Docker's frame stream from r into stdout and
+// stderr like stdcopy.StdCopy, passing each whole frame, header included,
+// to raw before its payload is written. A system error frame ends the copy
+// with its message as the error.
+func copyFrames(stdout, stderr io.Writer, r io.Reader, raw func(frame []byte)) error {
+	header := make([]byte, frameHeaderLen)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		frame := make([]byte, frameHeaderLen+int(binary.BigEndian.Uint32(header[4:])))
+		copy(frame, header)
+		if _, err := io.ReadFull(r, frame[frameHeaderLen:]); err != nil {
+			return err
+		}
+		raw(frame)
+
+		payload := frame[frameHeaderLen:]
+		switch header[0] {
+		case frameStdout:
+			if _, err := stdout.Write(payload); err != nil {
+				return err
+			}
+		case frameStderr:
+			if _, err := stderr.Write(payload); err != nil {
+				return err
+			}
+		case frameSystemErr:
+			return fmt.Errorf("error from daemon in stream: %s", payload)
+		default:
+			return fmt.Errorf("unrecognized stream in frame header: %d", header[0])
+		}
+	}
+}
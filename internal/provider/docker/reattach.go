@@ -0,0 +1,97 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// reattachStartTimeout bounds how long reattach waits for a container that
+// another Create has created but not yet started.
+const reattachStartTimeout = 30 * time.Second
+
+// reattachPollInterval is how often reattach inspects such a container.
+const reattachPollInterval = 100 * time.Millisecond
+
+// reattach returns an Instance for the running container named opts.ID,
+// or nil if there is none. The container must carry every label in
+// opts.Labels, which is how two configurations that hash to the same ID
+// are told apart. An exited or dead container is removed, so Create
+// replaces it. One that is still being created, started or removed, as
+// when two Creates race on the ID, is waited for. A running one is shared
+// with its other holders: its workdir is not prepared again and a failure
+// never removes it.
+func (p *Provider) reattach(ctx context.Context, image string, opts *provider.CreateOptions) (*Instance, error) {
+	deadline := time.Now().Add(reattachStartTimeout)
+	for {
+		info, err := p.client.ContainerInspect(ctx, opts.ID)
+		if client.IsErrNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("inspect container %s: %w", opts.ID, err)
+		}
+		for k, v := range opts.Labels {
+			if info.Config == nil || info.Config.Labels[k] != v {
+				return nil, fmt.Errorf("container %s exists with a different configuration (label %s)", opts.ID, k)
+			}
+		}
+
+		var status container.ContainerState
+		if info.State != nil {
+			status = info.State.Status
+		}
+		switch status {
+		case container.StateRunning, container.StatePaused:
+			return p.reattachRunning(ctx, image, opts)
+		case container.StateExited, container.StateDead:
+			if err := p.client.ContainerRemove(ctx, opts.ID, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil && !client.IsErrNotFound(err) {
+				return nil, fmt.Errorf("remove stopped container %s: %w", opts.ID, err)
+			}
+			return nil, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("container %s is still %s", opts.ID, status)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(reattachPollInterval):
+		}
+	}
+}
+
+// reattachRunning returns the Instance for the running container opts.ID.
+func (p *Provider) reattachRunning(ctx context.Context, image string, opts *provider.CreateOptions) (*Instance, error) {
+	inst := p.instance(opts.ID, image, opts)
+	inst.reattached = true
+	if opts.RespectEntrypoint {
+		env, err := inst.entrypointEnv(ctx)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(env, opts.Environment)
+		inst.env = env
+	}
+	if opts.WorkDir != "" {
+		dir, err := inst.findWorkDir(ctx, opts.WorkDir, opts.FallbackWorkDir)
+		if err != nil {
+			return nil, err
+		}
+		inst.workDir = dir
+	}
+	return inst, nil
+}
+
+// Reattached reports whether the instance reattached to a running
+// container rather than creating it.
+func (i *Instance) Reattached() bool {
+	return i.reattached
+}
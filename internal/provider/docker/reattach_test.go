@@ -0,0 +1,46 @@
+//go:build integration
+
+package docker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
+)
+
+// TestDeterministicIDReattach checks that a second Create with the same
+// configuration reattaches to the first sandbox.
+func TestDeterministicIDReattach(t *testing.T) {
+	ctx := context.Background()
+	opts := []sindoq.Option{sindoq.WithProvider("docker"), sindoq.WithRuntime("Python"), sindoq.WithDeterministicID()}
+	first, err := sindoq.Create(ctx, opts...)
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer first.Stop(ctx)
+	if err := first.Files().Write(ctx, "/workspace/cached.txt", []byte("x")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	second, err := sindoq.Create(ctx, opts...)
+	if err != nil {
+		t.Fatalf("second Create() error = %v", err)
+	}
+	if second.ID() != first.ID() {
+		t.Errorf("second ID = %s, want %s", second.ID(), first.ID())
+	}
+	if ok, err := second.Files().Exists(ctx, "/workspace/cached.txt"); err != nil || !ok {
+		t.Errorf("file written through the first sandbox exists = %v (error %v), want true", ok, err)
+	}
+
+	other, err := sindoq.Create(ctx, append(opts, sindoq.WithInternetAccess())...)
+	if err != nil {
+		t.Fatalf("Create() with another configuration error = %v", err)
+	}
+	defer other.Stop(ctx)
+	if other.ID() == first.ID() {
+		t.Errorf("another configuration reused %s", first.ID())
+	}
+}
@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"encoding/json"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// seccompProfile is the subset of Docker's seccomp profile format that
+// a provider.SyscallFilter needs.
+type seccompProfile struct {
+	DefaultAction string           `json:"defaultAction"`
+	Syscalls      []seccompSyscall `json:"syscalls"`
+}
+
+type seccompSyscall struct {
+	Names    []string     `json:"names"`
+	Action   string       `json:"action"`
+	ErrnoRet int          `json:"errnoRet"`
+	Args     []seccompArg `json:"args,omitempty"`
+}
+
+type seccompArg struct {
+	Index    int    `json:"index"`
+	Value    uint64 `json:"value"`
+	ValueTwo uint64 `json:"valueTwo,omitempty"`
+	Op       string `json:"op"`
+}
+
+// errnoEPERM is what denied system calls return, and errnoENOSYS what
+// unimplemented ones do.
+const (
+	errnoEPERM  = 1
+	errnoENOSYS = 38
+)
+
+// personalityQuery is the personality(2) argument that only reads the
+// execution domain.
+const personalityQuery = 0xffffffff
+
+// seccompSecurityOpt renders f as a "seccomp=" security option. The
+// profile replaces Docker's default one, allowing everything f does not
+// deny. It lists no architectures, so system calls made through another
+// ABI, such as int 0x80 on x86_64, fail rather than bypass it.
+func seccompSecurityOpt(f *provider.SyscallFilter) (string, error) {
+	profile := seccompProfile{DefaultAction: "SCMP_ACT_ALLOW"}
+	if len(f.Deny) > 0 {
+		profile.Syscalls = append(profile.Syscalls, seccompSyscall{
+			Names:    f.Deny,
+			Action:   "SCMP_ACT_ERRNO",
+			ErrnoRet: errnoEPERM,
+		})
+	}
+	for _, family := range f.DenySocketFamilies {
+		profile.Syscalls = append(profile.Syscalls, seccompSyscall{
+			Names:    []string{"socket"},
+			Action:   "SCMP_ACT_ERRNO",
+			ErrnoRet: errnoEPERM,
+			Args:     []seccompArg{{Index: 0, Value: uint64(family), Op: "SCMP_CMP_EQ"}},
+		})
+	}
+	// A masked comparison matches one flag, so each gets its own rule.
+	for bit := uint64(1); bit != 0; bit <<= 1 {
+		if f.DenyCloneFlags&bit != 0 {
+			profile.Syscalls = append(profile.Syscalls, seccompSyscall{
+				Names:    []string{"clone"},
+				Action:   "SCMP_ACT_ERRNO",
+				ErrnoRet: errnoEPERM,
+				Args:     []seccompArg{{Index: 0, Value: bit, ValueTwo: bit, Op: "SCMP_CMP_MASKED_EQ"}},
+			})
+		}
+	}
+	if len(f.Unimplemented) > 0 {
+		profile.Syscalls = append(profile.Syscalls, seccompSyscall{
+			Names:    f.Unimplemented,
+			Action:   "SCMP_ACT_ERRNO",
+			ErrnoRet: errnoENOSYS,
+		})
+	}
+	if f.FixedPersonality {
+		profile.Syscalls = append(profile.Syscalls, seccompSyscall{
+			Names:    []string{"personality"},
+			Action:   "SCMP_ACT_ERRNO",
+			ErrnoRet: errnoEPERM,
+			Args:     []seccompArg{{Index: 0, Value: personalityQuery, Op: "SCMP_CMP_NE"}},
+		})
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return "", err
+	}
+	return "seccomp=" + string(data), nil
+}
@@ -0,0 +1,100 @@
+//go:build integration
+
+package docker_test
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
+)
+
+// networkProbe prints whether a TCP connection out of the sandbox was
+// blocked by the syscall filter, made, or failed for another reason.
+const networkProbe = `import socket
+try:
+    socket.create_connection(("1.1.1.1", 53), timeout=5).close()
+    print("connected")
+except PermissionError:
+    print("blocked")
+except OSError as e:
+    print("unreachable", e)
+`
+
+func TestSecurityProfileNetwork(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{sindoq.SecurityProfileComputeOnly, "blocked"},
+		{sindoq.SecurityProfileUntrustedMinimal, "blocked"},
+		{sindoq.SecurityProfileNetworkAllowed, "connected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			ctx := context.Background()
+			sb, err := sindoq.Create(ctx, sindoq.WithProvider("docker"), sindoq.WithRuntime("Python"), sindoq.WithSecurityProfile(tt.profile))
+			if err != nil {
+				t.Skipf("Docker not available: %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			result, err := sb.Execute(ctx, networkProbe, sindoq.WithLanguage("Python"))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			got := strings.TrimSpace(result.Stdout)
+			if tt.want == "connected" && strings.HasPrefix(got, "unreachable") {
+				t.Skipf("no internet access: %s", got)
+			}
+			if got != tt.want {
+				t.Errorf("probe printed %q (stderr %q), want %q", got, result.Stderr, tt.want)
+			}
+		})
+	}
+}
+
+// escapeProbe prints the errno of calls Docker's default seccomp profile
+// refuses, on x86_64.
+const escapeProbe = `import ctypes, os
+libc = ctypes.CDLL(None, use_errno=True)
+def errno(nr, *args):
+    if libc.syscall(nr, *args) == -1:
+        return ctypes.get_errno()
+    return 0
+print(" ".join(str(e) for e in [
+    errno(56, 0x10000000, 0, 0, 0, 0),  # clone(CLONE_NEWUSER)
+    errno(435, 0, 0),                   # clone3
+    errno(425, 1, 0),                   # io_uring_setup
+    errno(430, 0, 0),                   # fsopen
+    errno(303, 0, 0, 0, 0, 0),          # name_to_handle_at
+    errno(227, 0, 0),                   # clock_settime
+    errno(172, 3),                      # iopl
+    errno(312, 0, 0, 0, 0, 0),          # kcmp
+    errno(135, 8),                      # personality(PER_LINUX32 | ...)
+]))
+`
+
+func TestSecurityProfileDockerDefaultCalls(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("the probe uses x86_64 syscall numbers")
+	}
+	ctx := context.Background()
+	sb, err := sindoq.Create(ctx, sindoq.WithProvider("docker"), sindoq.WithRuntime("Python"), sindoq.WithSecurityProfile(sindoq.SecurityProfileNetworkAllowed))
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.Execute(ctx, escapeProbe, sindoq.WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	// EPERM for every call except clone3, which is ENOSYS.
+	if got, want := strings.TrimSpace(result.Stdout), "1 38 1 1 1 1 1 1 1"; got != want {
+		t.Errorf("probe printed %q (stderr %q), want %q", got, result.Stderr, want)
+	}
+}
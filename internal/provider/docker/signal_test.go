@@ -0,0 +1,31 @@
+//go:build integration
+
+package docker_test
+
+import (
+	"context"
+	"syscall"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/docker"
+)
+
+// TestExecuteSignal checks that a crashing program is reported as killed
+// by SIGSEGV rather than only by its exit code.
+func TestExecuteSignal(t *testing.T) {
+	ctx := context.Background()
+	sb, err := sindoq.Create(ctx, sindoq.WithProvider("docker"), sindoq.WithRuntime("Python"))
+	if err != nil {
+		t.Skipf("Docker not available: %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.Execute(ctx, "import ctypes\nctypes.string_at(0)\n", sindoq.WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Signal != syscall.SIGSEGV {
+		t.Errorf("Signal = %v (exit code %d), want SIGSEGV", result.Signal, result.ExitCode)
+	}
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// tailChunkSize is the largest chunk Tail sends at once.
+const tailChunkSize = 32 * 1024
+
+// Tail follows path with tail -F in the container, which retries until the
+// file exists and keeps following it across truncation and replacement.
+// When ctx ends the tail process is killed and the channel closed.
+func (d *dockerFS) Tail(ctx context.Context, path string) (<-chan []byte, error) {
+	i := d.instance
+
+	// The shell prints its pid before becoming tail so it can be killed.
+	_, resp, err := i.startExec(ctx, container.ExecOptions{
+		Cmd:          []string{"sh", "-c", `echo $$ && exec tail -c +1 -F -- "$1"`, "sh", path},
+		AttachStdout: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(pw, io.Discard, resp.Reader)
+		pw.CloseWithError(err)
+	}()
+
+	out := bufio.NewReaderSize(pr, tailChunkSize)
+	pid, err := out.ReadString('\n')
+	if err != nil {
+		resp.Close()
+		return nil, fmt.Errorf("start tail: %w", err)
+	}
+	pid = strings.TrimSpace(pid)
+
+	chunks := make(chan []byte)
+	go func() {
+		defer close(chunks)
+		defer resp.Close()
+
+		stop := context.AfterFunc(ctx, func() {
+			killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			i.runExec(killCtx, []string{"kill", pid}, &executor.ExecutionOptions{WorkDir: "/"})
+			// Unblock the read below.
+			resp.Close()
+		})
+		defer stop()
+
+		buf := make([]byte, tailChunkSize)
+		for {
+			n, err := out.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- bytes.Clone(buf[:n]):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+var _ fs.Tailer = (*dockerFS)(nil)
@@ -0,0 +1,75 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ExportTar streams path out of container id. The daemon names entries
+// after the base name of path, so they are renamed to be relative to it.
+// When path is a file, the archive holds just that file under its base
+// name.
+func ExportTar(ctx context.Context, cli client.ContainerAPIClient, id, path string, w io.Writer) error {
+	reader, _, err := cli.CopyFromContainer(ctx, id, path)
+	if err != nil {
+		return fmt.Errorf("copy from container: %w", err)
+	}
+	defer reader.Close()
+	return renameEntries(reader, w)
+}
+
+// renameEntries copies the archive the daemon wrote to w, with entry
+// names made relative to the copied path.
+func renameEntries(r io.Reader, w io.Writer) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read tar: %w", err)
+		}
+
+		base, name, found := strings.Cut(header.Name, "/")
+		switch {
+		case name != "":
+			header.Name = name
+		case found || header.Typeflag == tar.TypeDir:
+			// The copied directory itself.
+			continue
+		default:
+			// The copied path is a file.
+			header.Name = base
+		}
+		if header.Typeflag == tar.TypeLink {
+			if _, link, found := strings.Cut(header.Linkname, "/"); found {
+				header.Linkname = link
+			}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// ImportTar streams r into the existing directory dir of container id,
+// which the daemon extracts with the modes recorded in the archive.
+func ImportTar(ctx context.Context, cli client.ContainerAPIClient, id, dir string, r io.Reader) error {
+	if err := cli.CopyToContainer(ctx, id, dir, r, container.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("copy to container: %w", err)
+	}
+	return nil
+}
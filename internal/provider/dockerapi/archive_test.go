@@ -0,0 +1,66 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestRenameEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tar.Header
+		want    []string
+	}{
+		{
+			name: "directory",
+			entries: []tar.Header{
+				{Name: "workspace/", Typeflag: tar.TypeDir},
+				{Name: "workspace/main.py", Typeflag: tar.TypeReg},
+				{Name: "workspace/sub/", Typeflag: tar.TypeDir},
+				{Name: "workspace/sub/copy.py", Typeflag: tar.TypeLink, Linkname: "workspace/main.py"},
+			},
+			want: []string{"main.py", "sub/", "sub/copy.py -> main.py"},
+		},
+		{
+			name:    "file",
+			entries: []tar.Header{{Name: "main.py", Typeflag: tar.TypeReg}},
+			want:    []string{"main.py"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in, out bytes.Buffer
+			tw := tar.NewWriter(&in)
+			for _, h := range tt.entries {
+				tw.WriteHeader(&h)
+			}
+			tw.Close()
+
+			if err := renameEntries(&in, &out); err != nil {
+				t.Fatalf("renameEntries() error = %v", err)
+			}
+			var got []string
+			tr := tar.NewReader(&out)
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				name := header.Name
+				if header.Linkname != "" {
+					name += " -> " + header.Linkname
+				}
+				got = append(got, name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ContainerChanges returns the filesystem changes of container id below
+// dir, as reported by the Docker changes API relative to the image.
+func ContainerChanges(ctx context.Context, cli client.ContainerAPIClient, id, dir string) (map[string]container.ChangeType, error) {
+	changes, err := cli.ContainerDiff(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("container diff: %w", err)
+	}
+
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	result := make(map[string]container.ChangeType)
+	for _, c := range changes {
+		if strings.HasPrefix(c.Path, prefix) {
+			result[c.Path] = c.Kind
+		}
+	}
+	return result, nil
+}
+
+// DiffContainerChanges derives the changes made between two calls to
+// ContainerChanges. The changes API only reports how a path differs from
+// the image, so a path changed before the run and changed again in the
+// same way is invisible: editing a file that an earlier run created, the
+// common case in a persistent workdir, is not reported.
+func DiffContainerChanges(before, after map[string]container.ChangeType, limit int) ([]executor.FileChange, bool) {
+	var changes []executor.FileChange
+	for path, kind := range after {
+		if prev, ok := before[path]; ok && prev == kind {
+			continue
+		}
+		switch kind {
+		case container.ChangeAdd:
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileCreated})
+		case container.ChangeDelete:
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileDeleted})
+		default:
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileModified})
+		}
+	}
+	for path, kind := range before {
+		if _, ok := after[path]; ok {
+			continue
+		}
+		switch kind {
+		case container.ChangeAdd:
+			// The path is not in the image, so it was deleted.
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileDeleted})
+		case container.ChangeDelete:
+			// The path was recreated and matches the image again.
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileCreated})
+		default:
+			// The path matches the image again.
+			changes = append(changes, executor.FileChange{Path: path, Kind: executor.FileModified})
+		}
+	}
+	return executor.SortFileChanges(changes, limit)
+}
@@ -0,0 +1,89 @@
+package dockerapi
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// diffClient returns fixed changes from ContainerDiff.
+type diffClient struct {
+	client.ContainerAPIClient
+	changes []container.FilesystemChange
+}
+
+func (c *diffClient) ContainerDiff(ctx context.Context, id string) ([]container.FilesystemChange, error) {
+	return c.changes, nil
+}
+
+func TestContainerChanges(t *testing.T) {
+	cli := &diffClient{changes: []container.FilesystemChange{
+		{Path: "/workspace", Kind: container.ChangeModify},
+		{Path: "/workspace/out.txt", Kind: container.ChangeAdd},
+		{Path: "/workspace-old/a", Kind: container.ChangeAdd},
+		{Path: "/tmp/x", Kind: container.ChangeAdd},
+	}}
+	got, err := ContainerChanges(context.Background(), cli, "c1", "/workspace/")
+	if err != nil {
+		t.Fatalf("ContainerChanges() error = %v", err)
+	}
+	want := map[string]container.ChangeType{"/workspace/out.txt": container.ChangeAdd}
+	if !maps.Equal(got, want) {
+		t.Errorf("ContainerChanges() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffContainerChanges(t *testing.T) {
+	const path = "/workspace/out.txt"
+	tests := []struct {
+		name          string
+		before, after map[string]container.ChangeType
+		want          []executor.FileChange
+	}{
+		{
+			name:  "add",
+			after: map[string]container.ChangeType{path: container.ChangeAdd},
+			want:  []executor.FileChange{{Path: path, Kind: executor.FileCreated}},
+		},
+		{
+			name:   "delete added file",
+			before: map[string]container.ChangeType{path: container.ChangeAdd},
+			want:   []executor.FileChange{{Path: path, Kind: executor.FileDeleted}},
+		},
+		{
+			name:   "re-add added file",
+			before: map[string]container.ChangeType{path: container.ChangeAdd},
+			after:  map[string]container.ChangeType{path: container.ChangeAdd},
+		},
+		{
+			name:  "delete image file",
+			after: map[string]container.ChangeType{path: container.ChangeDelete},
+			want:  []executor.FileChange{{Path: path, Kind: executor.FileDeleted}},
+		},
+		{
+			name:   "re-add image file",
+			before: map[string]container.ChangeType{path: container.ChangeDelete},
+			after:  map[string]container.ChangeType{path: container.ChangeModify},
+			want:   []executor.FileChange{{Path: path, Kind: executor.FileModified}},
+		},
+		{
+			name:  "modify image file",
+			after: map[string]container.ChangeType{path: container.ChangeModify},
+			want:  []executor.FileChange{{Path: path, Kind: executor.FileModified}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := DiffContainerChanges(tt.before, tt.after, executor.DefaultMaxTrackedFiles)
+			if !slices.Equal(got, tt.want) || truncated {
+				t.Errorf("DiffContainerChanges() = %v, %v, want %v", got, truncated, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// PrepareCoverage checks, through exec, that the image has the coverage
+// tool for the language of opts and creates the directory for its data in
+// the run's workdir (defaultWorkDir when opts sets none), returning the
+// coverage run and a func that removes the directory.
+func PrepareCoverage(ctx context.Context, exec ExecFunc, defaultWorkDir string, opts *executor.ExecutionOptions) (*provider.CoverageRun, func(), error) {
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = defaultWorkDir
+	}
+	dir := workDir + "/" + provider.CoverageDirName
+	cov, err := provider.NewCoverageRun(opts.Language, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	check, err := exec(ctx, cov.Check, &executor.ExecutionOptions{WorkDir: workDir})
+	if err != nil {
+		return nil, nil, fmt.Errorf("check coverage tool: %w", err)
+	}
+	if check.ExitCode != 0 {
+		return nil, nil, cov.MissingToolError()
+	}
+
+	result, err := exec(ctx, []string{"mkdir", "-p", dir}, &executor.ExecutionOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("create coverage directory: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return nil, nil, fmt.Errorf("create coverage directory: %s", strings.TrimSpace(result.Stderr))
+	}
+	remove := func() {
+		exec(context.Background(), []string{"rm", "-rf", dir}, &executor.ExecutionOptions{})
+	}
+	return cov, remove, nil
+}
+
+// CoverageOptions returns a copy of opts with the environment of cov.
+func CoverageOptions(cov *provider.CoverageRun, opts *executor.ExecutionOptions) *executor.ExecutionOptions {
+	withEnv := *opts
+	withEnv.Env = maps.Clone(opts.Env)
+	if withEnv.Env == nil {
+		withEnv.Env = make(map[string]string)
+	}
+	maps.Copy(withEnv.Env, cov.Env)
+	return &withEnv
+}
+
+// CollectCoverage writes the report of a finished coverage run through
+// exec and reads the coverage from it out of fsys.
+func CollectCoverage(ctx context.Context, exec ExecFunc, fsys fs.FileSystem, cov *provider.CoverageRun, opts *executor.ExecutionOptions) (*executor.Coverage, error) {
+	if cov.Report != nil {
+		reportOpts := CoverageOptions(cov, &executor.ExecutionOptions{WorkDir: opts.WorkDir, Env: opts.Env})
+		result, err := exec(ctx, cov.Report, reportOpts)
+		if err != nil {
+			return nil, fmt.Errorf("coverage report: %w", err)
+		}
+		if result.ExitCode != 0 {
+			return nil, fmt.Errorf("coverage report: %s", strings.TrimSpace(result.Stderr))
+		}
+	}
+	report, err := fsys.Read(ctx, cov.ReportFile)
+	if err != nil {
+		return nil, fmt.Errorf("coverage report: %w", err)
+	}
+	return cov.Coverage(report)
+}
@@ -0,0 +1,45 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// DiskLimitStorageOpt returns the storage options that cap a container's
+// writable layer at mb megabytes, or nil for no limit.
+func DiskLimitStorageOpt(mb int) map[string]string {
+	if mb <= 0 {
+		return nil
+	}
+	return map[string]string{"size": fmt.Sprintf("%dM", mb)}
+}
+
+// DiskLimitError returns err wrapped in ErrDiskLimitUnsupported if it is the
+// daemon refusing a size storage option, and nil otherwise.
+func DiskLimitError(err error) error {
+	msg := strings.ToLower(err.Error())
+	if !strings.Contains(msg, "storage-opt") && !strings.Contains(msg, "storage opt") {
+		return nil
+	}
+	return fmt.Errorf("%w: the storage driver cannot limit container size "+
+		"(overlay2 needs xfs mounted with pquota; btrfs, zfs and devicemapper also work): %v",
+		provider.ErrDiskLimitUnsupported, err)
+}
+
+// DiskUsedMB returns the size of the writable layer of container id in
+// megabytes, rounded up.
+func DiskUsedMB(ctx context.Context, cli client.ContainerAPIClient, id string) (int64, error) {
+	info, _, err := cli.ContainerInspectWithRaw(ctx, id, true)
+	if err != nil {
+		return 0, fmt.Errorf("inspect container size: %w", err)
+	}
+	if info.SizeRw == nil {
+		return 0, nil
+	}
+	return (*info.SizeRw + 1<<20 - 1) >> 20, nil
+}
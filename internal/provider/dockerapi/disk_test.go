@@ -0,0 +1,28 @@
+package dockerapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+func TestDiskLimitStorageOpt(t *testing.T) {
+	if opt := DiskLimitStorageOpt(0); opt != nil {
+		t.Errorf("no limit: got %v, want nil", opt)
+	}
+	if opt := DiskLimitStorageOpt(512); opt["size"] != "512M" {
+		t.Errorf("512MB: got %v", opt)
+	}
+}
+
+func TestDiskLimitError(t *testing.T) {
+	daemonErr := errors.New("Error response from daemon: --storage-opt is supported only for overlay over xfs with 'pquota' mount option")
+	if err := DiskLimitError(daemonErr); !errors.Is(err, provider.ErrDiskLimitUnsupported) {
+		t.Errorf("DiskLimitError() = %v, want ErrDiskLimitUnsupported", err)
+	}
+
+	if err := DiskLimitError(errors.New("No such image: python:3.12-slim")); err != nil {
+		t.Errorf("unrelated error reported as a disk limit error: %v", err)
+	}
+}
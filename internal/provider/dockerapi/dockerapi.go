@@ -0,0 +1,14 @@
+// Package dockerapi holds the code shared by the Docker and gVisor
+// providers, which both run their sandboxes as containers through the
+// Docker Engine API.
+package dockerapi
+
+import (
+	"context"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ExecFunc runs cmd in a container and waits for it, as each provider's
+// runExec does with the container's environment.
+type ExecFunc func(ctx context.Context, cmd []string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error)
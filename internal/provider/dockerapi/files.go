@@ -0,0 +1,88 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// WriteFiles copies ExecutionOptions.Files into container id for a run in
+// workDir. Relative names land under workDir, with their subdirectories
+// created, and absolute names where they say; each target directory takes
+// one archive.
+func WriteFiles(ctx context.Context, cli client.ContainerAPIClient, id, workDir string, files map[string][]byte) error {
+	archives := make(map[string]map[string][]byte)
+	for name, content := range files {
+		target, err := provider.FilePath(workDir, name)
+		if err != nil {
+			return err
+		}
+		dir, rel := workDir, strings.TrimPrefix(target, strings.TrimSuffix(workDir, "/")+"/")
+		if rel == target {
+			// Outside workDir: copy into the file's own directory, which
+			// must exist.
+			dir, rel = path.Split(target)
+		}
+		if archives[dir] == nil {
+			archives[dir] = make(map[string][]byte)
+		}
+		archives[dir][rel] = content
+	}
+
+	for dir, entries := range archives {
+		var buf bytes.Buffer
+		if err := writeArchive(&buf, entries); err != nil {
+			return err
+		}
+		if err := cli.CopyToContainer(ctx, id, dir, &buf, container.CopyToContainerOptions{}); err != nil {
+			return fmt.Errorf("copy files to %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// writeArchive writes entries, named by slash-separated relative paths,
+// as a tar with an entry for each parent directory.
+func writeArchive(buf *bytes.Buffer, entries map[string][]byte) error {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	tw := tar.NewWriter(buf)
+	written := make(map[string]bool)
+	for _, name := range names {
+		var parents []string
+		for dir := path.Dir(name); dir != "." && !written[dir]; dir = path.Dir(dir) {
+			written[dir] = true
+			parents = append(parents, dir)
+		}
+		for _, dir := range slices.Backward(parents) {
+			if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: now}); err != nil {
+				return err
+			}
+		}
+
+		content := entries[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: now}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
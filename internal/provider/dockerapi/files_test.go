@@ -0,0 +1,55 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// copyRecorder records the archives CopyToContainer receives, by
+// destination directory.
+type copyRecorder struct {
+	client.ContainerAPIClient
+	copies map[string][]string
+}
+
+func (c *copyRecorder) CopyToContainer(ctx context.Context, id, dir string, r io.Reader, opts container.CopyToContainerOptions) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			return nil
+		}
+		c.copies[dir] = append(c.copies[dir], header.Name)
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	cli := &copyRecorder{copies: make(map[string][]string)}
+	err := WriteFiles(context.Background(), cli, "c1", "/workspace/.run-abc", map[string][]byte{
+		"solution.py":         []byte("x"),
+		"test_solution.py":    []byte("y"),
+		"data/sets/input.txt": []byte("z"),
+		"/etc/app.conf":       []byte("c"),
+	})
+	if err != nil {
+		t.Fatalf("WriteFiles() error = %v", err)
+	}
+	want := map[string][]string{
+		"/workspace/.run-abc": {"data/", "data/sets/", "data/sets/input.txt", "solution.py", "test_solution.py"},
+		"/etc/":               {"app.conf"},
+	}
+	if !maps.EqualFunc(cli.copies, want, slices.Equal) {
+		t.Errorf("copies = %q, want %q", cli.copies, want)
+	}
+
+	if err := WriteFiles(context.Background(), cli, "c1", "/workspace", map[string][]byte{"../escape.txt": nil}); err == nil {
+		t.Error("WriteFiles() should reject a name outside the working directory")
+	}
+}
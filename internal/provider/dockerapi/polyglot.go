@@ -0,0 +1,52 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// RuntimeChecker checks that a polyglot image has the binary that runs (or
+// compiles) each language, so a missing runtime fails clearly instead of
+// with a shell "not found". The answer is cached per language.
+type RuntimeChecker struct {
+	mu      sync.Mutex
+	checked map[string]error
+}
+
+// Check reports whether the image has runtimeInfo's binary, looking it up
+// through exec. A nil checker, for a single-runtime image, checks nothing.
+func (c *RuntimeChecker) Check(ctx context.Context, exec ExecFunc, runtimeInfo *langdetect.RuntimeInfo) error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lang := runtimeInfo.Language
+	if err, ok := c.checked[lang]; ok {
+		return err
+	}
+
+	bin := runtimeInfo.RunCommand[0]
+	if len(runtimeInfo.CompileCmd) > 0 {
+		bin = runtimeInfo.CompileCmd[0]
+	}
+	result, err := exec(ctx, []string{"sh", "-c", `command -v "$1"`, "sh", bin}, &executor.ExecutionOptions{})
+	if err != nil {
+		// Not cached: the exec itself failed, not the lookup.
+		return fmt.Errorf("check %s runtime: %w", lang, err)
+	}
+	if result.ExitCode != 0 {
+		err = fmt.Errorf("image has no %s runtime: %s not found", lang, bin)
+	}
+	if c.checked == nil {
+		c.checked = make(map[string]error)
+	}
+	c.checked[lang] = err
+	return err
+}
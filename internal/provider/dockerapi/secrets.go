@@ -0,0 +1,46 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// SecretsTmpfsOptions mounts the secrets tmpfs readable only by its owner.
+const SecretsTmpfsOptions = "rw,noexec,nosuid,mode=0700"
+
+// WriteSecretFiles writes files into the secrets tmpfs through exec and
+// returns a func that removes them once no overlapping execution still
+// holds them. refs is nil when the tmpfs is not mounted. Files are piped
+// through exec because the archive API cannot write into a tmpfs.
+func WriteSecretFiles(ctx context.Context, exec ExecFunc, refs *executor.SecretFileRefs, files map[string][]byte) (func(), error) {
+	if len(files) == 0 {
+		return func() {}, nil
+	}
+	if refs == nil {
+		return nil, fmt.Errorf("secret files require CreateOptions.SecretFiles")
+	}
+
+	write := func(name string, content []byte) error {
+		path := executor.SecretsDir + "/" + name
+		cmd := []string{"sh", "-c", `rm -f -- "$1" && umask 0377 && cat > "$1"`, "sh", path}
+		result, err := exec(ctx, cmd, &executor.ExecutionOptions{Stdin: string(content)})
+		if err == nil && result.ExitCode != 0 {
+			err = fmt.Errorf("exit code %d", result.ExitCode)
+		}
+		return err
+	}
+	remove := func(names []string) {
+		// Remove even when ctx was cancelled by a timeout.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		cmd := []string{"rm", "-f", "--"}
+		for _, name := range names {
+			cmd = append(cmd, executor.SecretsDir+"/"+name)
+		}
+		exec(ctx, cmd, &executor.ExecutionOptions{})
+	}
+	return refs.Acquire(files, write, remove)
+}
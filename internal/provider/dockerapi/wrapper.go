@@ -0,0 +1,25 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// CheckCommandWrapper reports, through exec, whether the image has the
+// binary of wrapper, so a missing profiler or tracer fails clearly instead
+// of with a shell "not found" exit code.
+func CheckCommandWrapper(ctx context.Context, exec ExecFunc, wrapper []string) error {
+	if len(wrapper) == 0 {
+		return nil
+	}
+	result, err := exec(ctx, []string{"sh", "-c", `command -v "$1"`, "sh", wrapper[0]}, &executor.ExecutionOptions{})
+	if err != nil {
+		return fmt.Errorf("check command wrapper: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("command wrapper %s not found in image", wrapper[0])
+	}
+	return nil
+}
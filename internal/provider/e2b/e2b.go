@@ -13,12 +13,17 @@ import (
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 )
 
 const (
-	baseURL = "https://api.e2b.dev"
+	defaultRateLimit  = 10
+	defaultRateBurst  = 20
+	defaultMaxRetries = 3
+
+	defaultBaseURL = "https://api.e2b.dev"
 )
 
 func init() {
@@ -36,12 +41,34 @@ type Config struct {
 	APIKey   string
 	Template string
 	Timeout  time.Duration
+
+	// RateLimit is the maximum requests per second sent to the API
+	// (default 10). A negative value disables throttling. Providers with
+	// the same API key share one limiter, and New fails if their RateLimit
+	// or RateBurst differ.
+	RateLimit float64
+
+	// RateBurst is the number of requests allowed in a burst (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a 429 response is retried (default 3).
+	// A negative value disables retries.
+	MaxRetries int
+
+	// BaseURL is the API endpoint (default https://api.e2b.dev).
+	BaseURL string
+
+	// HTTPClient sends the API requests, for a proxy, custom TLS or tests
+	// (default a client with a 5 minute timeout). Rate limiting and retries
+	// wrap its transport.
+	HTTPClient *http.Client
 }
 
 // Provider implements the E2B provider.
 type Provider struct {
-	config *Config
-	client *http.Client
+	config  *Config
+	client  *http.Client
+	limiter *ratelimit.Limiter
 }
 
 // New creates a new E2B provider.
@@ -58,14 +85,44 @@ func New(cfg *Config) (*Provider, error) {
 		cfg.Template = "base"
 	}
 
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = defaultRateLimit
+	}
+	if cfg.RateBurst == 0 {
+		cfg.RateBurst = defaultRateBurst
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit > 0 {
+		var err error
+		limiter, err = ratelimit.Shared("e2b:"+cfg.APIKey, cfg.RateLimit, cfg.RateBurst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Provider{
-		config: cfg,
-		client: &http.Client{
-			Timeout: 5 * time.Minute,
-		},
+		config:  cfg,
+		client:  ratelimit.Client(cfg.HTTPClient, 5*time.Minute, limiter, cfg.MaxRetries),
+		limiter: limiter,
 	}, nil
 }
 
+// QueueDepth returns the number of API requests waiting on the rate limiter.
+func (p *Provider) QueueDepth() int {
+	if p.limiter == nil {
+		return 0
+	}
+	return p.limiter.QueueDepth()
+}
+
 // Name returns the provider identifier.
 func (p *Provider) Name() string {
 	return "e2b"
@@ -76,6 +133,12 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 	if opts == nil {
 		opts = provider.DefaultCreateOptions()
 	}
+	if err := provider.RejectRuntimeVersion("e2b", opts.Runtime); err != nil {
+		return nil, err
+	}
+	if err := provider.RejectDiskLimit("e2b", opts.Resources); err != nil {
+		return nil, err
+	}
 
 	reqBody := map[string]any{
 		"templateId": p.config.Template,
@@ -90,7 +153,7 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/sandboxes", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/sandboxes", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -102,9 +165,8 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("create sandbox failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("create sandbox", resp, http.StatusOK, http.StatusCreated); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -132,21 +194,22 @@ func (p *Provider) setHeaders(req *http.Request) {
 // Capabilities returns E2B provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		SupportsStreaming:   true,
-		SupportsAsync:       true,
-		SupportsFileSystem:  true,
-		SupportsNetwork:     false,
-		SupportedLanguages:  []string{"Python", "JavaScript", "TypeScript", "R", "Java", "Bash"},
-		MaxExecutionTime:    24 * time.Hour,
-		MaxMemoryMB:         8192,
-		MaxCPUs:             4,
-		SupportsPersistence: true,
+		SupportsStreaming:     true,
+		SupportsAsync:         true,
+		SupportsFileSystem:    true,
+		SupportsNetwork:       false,
+		SupportedLanguages:    []string{"Python", "JavaScript", "TypeScript", "R", "Java", "Bash"},
+		MaxExecutionTime:      24 * time.Hour,
+		MaxMemoryMB:           8192,
+		MaxCPUs:               4,
+		SupportsPersistence:   true,
+		SupportsRangeDownload: true,
 	}
 }
 
 // Validate checks if E2B API is accessible.
 func (p *Provider) Validate(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/templates", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/templates", nil)
 	if err != nil {
 		return err
 	}
@@ -158,8 +221,8 @@ func (p *Provider) Validate(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return fmt.Errorf("invalid E2B API key")
+	if err := provider.CheckHTTPResponse("validate", resp); err != nil {
+		return err
 	}
 
 	return nil
@@ -214,7 +277,7 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/sandboxes/"+i.id+"/code/execution", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", i.provider.config.BaseURL+"/sandboxes/"+i.id+"/code/execution", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -226,6 +289,10 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("execute", resp); err != nil {
+		return nil, err
+	}
+
 	var result struct {
 		Stdout   string `json:"stdout"`
 		Stderr   string `json:"stderr"`
@@ -283,6 +350,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
 		ExitCode:  result.ExitCode,
+		Language:  result.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -307,7 +375,7 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/sandboxes/"+i.id+"/commands", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", i.provider.config.BaseURL+"/sandboxes/"+i.id+"/commands", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -319,6 +387,10 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("run command", resp); err != nil {
+		return nil, err
+	}
+
 	var result struct {
 		Stdout   string `json:"stdout"`
 		Stderr   string `json:"stderr"`
@@ -357,7 +429,7 @@ func (i *Instance) Stop(ctx context.Context) error {
 	i.stopped = true
 	i.mu.Unlock()
 
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/sandboxes/"+i.id, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", i.provider.config.BaseURL+"/sandboxes/"+i.id, nil)
 	if err != nil {
 		return err
 	}
@@ -369,7 +441,8 @@ func (i *Instance) Stop(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	return nil
+	// A sandbox that is already gone counts as stopped.
+	return provider.CheckHTTPResponse("stop sandbox", resp, http.StatusOK, http.StatusNoContent, http.StatusNotFound)
 }
 
 // Status returns the current status.
@@ -381,7 +454,7 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 	}
 	i.mu.RUnlock()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/sandboxes/"+i.id, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", i.provider.config.BaseURL+"/sandboxes/"+i.id, nil)
 	if err != nil {
 		return provider.StatusError, err
 	}
@@ -396,6 +469,9 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 	if resp.StatusCode == http.StatusNotFound {
 		return provider.StatusStopped, nil
 	}
+	if err := provider.CheckHTTPResponse("get sandbox", resp); err != nil {
+		return provider.StatusError, err
+	}
 
 	return provider.StatusRunning, nil
 }
@@ -406,7 +482,7 @@ type e2bFS struct {
 }
 
 func (f *e2bFS) Read(ctx context.Context, path string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/sandboxes/"+f.instance.id+"/files?path="+path, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", f.instance.provider.config.BaseURL+"/sandboxes/"+f.instance.id+"/files?path="+path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -418,6 +494,10 @@ func (f *e2bFS) Read(ctx context.Context, path string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("read file", resp); err != nil {
+		return nil, err
+	}
+
 	return io.ReadAll(resp.Body)
 }
 
@@ -432,7 +512,7 @@ func (f *e2bFS) Write(ctx context.Context, path string, data []byte) error {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/sandboxes/"+f.instance.id+"/files", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", f.instance.provider.config.BaseURL+"/sandboxes/"+f.instance.id+"/files", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -444,11 +524,11 @@ func (f *e2bFS) Write(ctx context.Context, path string, data []byte) error {
 	}
 	defer resp.Body.Close()
 
-	return nil
+	return provider.CheckHTTPResponse("write file", resp, http.StatusOK, http.StatusCreated, http.StatusNoContent)
 }
 
 func (f *e2bFS) Delete(ctx context.Context, path string) error {
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/sandboxes/"+f.instance.id+"/files?path="+path, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", f.instance.provider.config.BaseURL+"/sandboxes/"+f.instance.id+"/files?path="+path, nil)
 	if err != nil {
 		return err
 	}
@@ -460,17 +540,18 @@ func (f *e2bFS) Delete(ctx context.Context, path string) error {
 	}
 	defer resp.Body.Close()
 
-	return nil
+	return provider.CheckHTTPResponse("delete file", resp, http.StatusOK, http.StatusNoContent)
 }
 
 func (f *e2bFS) List(ctx context.Context, path string) ([]fs.FileInfo, error) {
-	_, err := f.instance.RunCommand(ctx, "ls", []string{"-la", path})
+	result, err := f.instance.RunCommand(ctx, fs.ListCommand(path), nil)
 	if err != nil {
 		return nil, err
 	}
-
-	// Parse ls output (simplified)
-	return []fs.FileInfo{}, nil
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("list directory failed: %s", result.Stderr)
+	}
+	return fs.ParseList(path, []byte(result.Stdout))
 }
 
 func (f *e2bFS) Exists(ctx context.Context, path string) (bool, error) {
@@ -506,6 +587,40 @@ func (f *e2bFS) Download(ctx context.Context, remotePath string, writer io.Write
 	return err
 }
 
+// DownloadRange fetches a byte range of a file using an HTTP Range request.
+// If the server ignores the range and returns the whole file, the
+// unwanted bytes are discarded client-side.
+func (f *e2bFS) DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", f.instance.provider.config.BaseURL+"/sandboxes/"+f.instance.id+"/files?path="+path, nil)
+	if err != nil {
+		return err
+	}
+	f.instance.provider.setHeaders(req)
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := f.instance.provider.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		_, err = io.Copy(writer, resp.Body)
+		return err
+	case http.StatusOK:
+		return fs.WriteRange(resp.Body, offset, length, writer)
+	case http.StatusRequestedRangeNotSatisfiable:
+		return nil
+	default:
+		return provider.CheckHTTPResponse("download range", resp)
+	}
+}
+
 func (f *e2bFS) MkDir(ctx context.Context, path string) error {
 	result, err := f.instance.RunCommand(ctx, "mkdir", []string{"-p", path})
 	if err != nil {
@@ -0,0 +1,153 @@
+package e2b
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// newTestInstance returns an instance whose API requests are answered by
+// handler.
+func newTestInstance(t *testing.T, handler http.HandlerFunc) *Instance {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	p, err := New(&Config{APIKey: "key", BaseURL: srv.URL, RateLimit: -1, MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return &Instance{id: "sbx-1", provider: p}
+}
+
+func TestExecuteHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"bad key", http.StatusUnauthorized},
+		{"sandbox gone", http.StatusNotFound},
+		{"rate limited", http.StatusTooManyRequests},
+		{"server error", http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"message":"nope"}`))
+			})
+
+			_, err := i.Execute(context.Background(), "print(1)", &executor.ExecutionOptions{Language: "Python"})
+			var httpErr *provider.HTTPProviderError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("Execute() error = %v, want *HTTPProviderError", err)
+			}
+			if httpErr.Status != tt.status || httpErr.Op != "execute" || httpErr.Body != `{"message":"nope"}` {
+				t.Errorf("error = %+v", httpErr)
+			}
+		})
+	}
+}
+
+func TestExecuteOK(t *testing.T) {
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sandboxes/sbx-1/code/execution" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"stdout":"1\n","exitCode":0}`))
+	})
+
+	result, err := i.Execute(context.Background(), "print(1)", &executor.ExecutionOptions{Language: "Python"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "1\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "1\n")
+	}
+}
+
+func TestStatusAndStop(t *testing.T) {
+	status := http.StatusNotFound
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+	ctx := context.Background()
+
+	if s, err := i.Status(ctx); err != nil || s != provider.StatusStopped {
+		t.Errorf("Status() = %v, %v, want stopped for 404", s, err)
+	}
+
+	status = http.StatusInternalServerError
+	if s, err := i.Status(ctx); err == nil || s != provider.StatusError {
+		t.Errorf("Status() = %v, %v, want error for 500", s, err)
+	}
+
+	status = http.StatusNotFound
+	if err := i.Stop(ctx); err != nil {
+		t.Errorf("Stop() of a sandbox that is gone error = %v", err)
+	}
+}
+
+// redirectTransport sends every request to a test server, whatever its URL.
+type redirectTransport struct {
+	target string
+	calls  int
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", t.target
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /sandboxes":
+			w.Write([]byte(`{"sandboxId":"sbx-1","clientId":"c-1"}`))
+		case "POST /sandboxes/sbx-1/code/execution":
+			w.Write([]byte(`{"stdout":"1\n","exitCode":0}`))
+		case "DELETE /sandboxes/sbx-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	transport := &redirectTransport{target: srv.Listener.Addr().String()}
+	p, err := New(&Config{APIKey: "key", RateLimit: -1, HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	inst, err := p.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if inst.ID() != "sbx-1" {
+		t.Errorf("ID() = %q, want sbx-1", inst.ID())
+	}
+	result, err := inst.Execute(ctx, "print(1)", &executor.ExecutionOptions{Language: "Python"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "1\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "1\n")
+	}
+	if err := inst.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if transport.calls != 3 {
+		t.Errorf("custom client sent %d requests, want 3", transport.calls)
+	}
+}
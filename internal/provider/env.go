@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MergeEnv returns base overlaid with override as KEY=value pairs sorted by
+// key. Container providers pass it the sandbox's CreateOptions.Environment
+// and an execution's Env, so variables set for the execution take
+// precedence over those set when the sandbox was created.
+func MergeEnv(base, override map[string]string) []string {
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, merged[k]))
+	}
+	return env
+}
@@ -0,0 +1,21 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeEnv(t *testing.T) {
+	got := MergeEnv(
+		map[string]string{"B": "create", "SHARED": "create"},
+		map[string]string{"A": "exec", "SHARED": "exec"},
+	)
+	want := []string{"A=exec", "B=create", "SHARED=exec"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeEnv = %v, want %v", got, want)
+	}
+
+	if got := MergeEnv(nil, nil); len(got) != 0 {
+		t.Errorf("MergeEnv(nil, nil) = %v, want empty", got)
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ValidateFileName checks an ExecutionOptions.Files name: an absolute
+// path, or a relative one that stays inside the working directory.
+func ValidateFileName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("empty file name")
+	}
+	if path.IsAbs(name) {
+		return nil
+	}
+	if clean := path.Clean(name); clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("file %q is outside the working directory", name)
+	}
+	return nil
+}
+
+// FilePath returns where the ExecutionOptions.Files entry name is written
+// in a sandbox running in workDir: an absolute name as it is, a relative
+// one joined onto workDir.
+func FilePath(workDir, name string) (string, error) {
+	if err := ValidateFileName(name); err != nil {
+		return "", err
+	}
+	if path.IsAbs(name) {
+		return path.Clean(name), nil
+	}
+	return path.Join(workDir, name), nil
+}
@@ -0,0 +1,32 @@
+package provider
+
+import "testing"
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"test_solution.py", "/workspace/test_solution.py", false},
+		{"data/input.txt", "/workspace/data/input.txt", false},
+		{"./a/../b.txt", "/workspace/b.txt", false},
+		{"/etc/app.conf", "/etc/app.conf", false},
+		{"/tmp/../x", "/x", false},
+		{"../escape.txt", "", true},
+		{"a/../../escape.txt", "", true},
+		{".", "", true},
+		{" ", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FilePath("/workspace", tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FilePath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FilePath(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package firecracker
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
@@ -20,6 +21,7 @@ import (
 	"github.com/firecracker-microvm/firecracker-go-sdk/client/models"
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 	"github.com/happyhackingspace/sindoq/pkg/langdetect"
@@ -87,6 +89,9 @@ type Provider struct {
 	config    *Config
 	instances map[string]*Instance
 	mu        sync.RWMutex
+
+	// inflight tracks executions so CloseGraceful can wait for them.
+	inflight provider.InFlight
 }
 
 // New creates a new Firecracker provider.
@@ -113,6 +118,21 @@ func (p *Provider) Name() string {
 
 // Create initializes a new Firecracker microVM sandbox.
 func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	ctx, done, err := p.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if opts != nil {
+		if err := provider.RejectRuntimeVersion("firecracker", opts.Runtime); err != nil {
+			return nil, err
+		}
+		if err := provider.RejectDiskLimit("firecracker", opts.Resources); err != nil {
+			return nil, err
+		}
+	}
+
 	id := fmt.Sprintf("fc-%d", time.Now().UnixNano())
 	socketPath := filepath.Join(p.config.SocketDir, id+".sock")
 
@@ -191,14 +211,18 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 // Capabilities returns Firecracker provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		SupportsStreaming:  true,
-		SupportsAsync:      true,
-		SupportsFileSystem: true,
-		SupportsNetwork:    p.config.EnableNetwork,
-		SupportedLanguages: langdetect.SupportedLanguages(),
-		MaxExecutionTime:   24 * time.Hour,
-		MaxMemoryMB:        int(p.config.MemSizeMiB),
-		MaxCPUs:            int(p.config.VCPUCount),
+		SupportsStreaming:       true,
+		SupportsAsync:           true,
+		SupportsFileSystem:      true,
+		SupportsNetwork:         p.config.EnableNetwork,
+		SupportsInterpreterPath: true,
+		SupportsProgramArgs:     true,
+		SupportedLanguages:      langdetect.SupportedLanguages(),
+		MaxExecutionTime:        24 * time.Hour,
+		MaxMemoryMB:             int(p.config.MemSizeMiB),
+		MaxCPUs:                 int(p.config.VCPUCount),
+		// Code is written to and run from /tmp in the guest.
+		DefaultWorkDir: "/tmp",
 	}
 }
 
@@ -229,11 +253,8 @@ func (p *Provider) Validate(ctx context.Context) error {
 
 // Close releases provider resources.
 func (p *Provider) Close() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	var lastErr error
-	for _, instance := range p.instances {
+	for _, instance := range provider.SnapshotInstances(&p.mu, p.instances) {
 		if err := instance.Stop(context.Background()); err != nil {
 			lastErr = err
 		}
@@ -241,7 +262,14 @@ func (p *Provider) Close() error {
 	return lastErr
 }
 
+// CloseGraceful refuses new sandboxes and executions, waits for running
+// ones, then closes the provider.
+func (p *Provider) CloseGraceful(ctx context.Context) error {
+	return p.inflight.CloseGraceful(ctx, p.Close)
+}
+
 var _ provider.Provider = (*Provider)(nil)
+var _ provider.GracefulCloser = (*Provider)(nil)
 
 // Instance represents a running Firecracker microVM.
 type Instance struct {
@@ -262,6 +290,12 @@ func (i *Instance) ID() string {
 
 // Execute runs code in the microVM via serial console or SSH.
 func (i *Instance) Execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -278,6 +312,7 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	if !ok {
 		return nil, fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
 
 	start := time.Now()
 
@@ -332,7 +367,10 @@ func (i *Instance) executeViaSSH(ctx context.Context, code string, runtimeInfo *
 		execCmd := strings.Join(runtimeInfo.RunCommand, " ")
 		runCmd = fmt.Sprintf("%s && %s", compileCmd, execCmd)
 	} else {
-		runCmd = strings.Join(append(runtimeInfo.RunCommand, "/tmp/"+codeFilename), " ")
+		runCmd = strings.Join(append(provider.RunCommand(runtimeInfo, i.config.EnableNetwork, "/tmp"), "/tmp/"+codeFilename), " ")
+	}
+	for _, arg := range opts.Args {
+		runCmd += " " + shellQuote(arg)
 	}
 
 	// Add stdin handling
@@ -349,12 +387,17 @@ func (i *Instance) executeViaSSH(ctx context.Context, code string, runtimeInfo *
 		runCmd = envPrefix + runCmd
 	}
 
+	// Compiled binaries land in the working directory, next to the code.
+	runCmd = "cd /tmp && " + runCmd
+
 	sshRunArgs := append(sshArgs, runCmd)
 	runExec := exec.CommandContext(ctx, "ssh", sshRunArgs...)
 
 	var stdout, stderr bytes.Buffer
-	runExec.Stdout = &stdout
-	runExec.Stderr = &stderr
+	stdoutW := executor.NewLimitedWriter(&stdout, opts.StdoutLimit)
+	stderrW := executor.NewLimitedWriter(&stderr, opts.StderrLimit)
+	runExec.Stdout = stdoutW
+	runExec.Stderr = stderrW
 
 	err := runExec.Run()
 	exitCode := 0
@@ -367,9 +410,11 @@ func (i *Instance) executeViaSSH(ctx context.Context, code string, runtimeInfo *
 	}
 
 	return &executor.ExecutionResult{
-		ExitCode: exitCode,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
+		ExitCode:        exitCode,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdoutW.Truncated(),
+		StderrTruncated: stderrW.Truncated(),
 	}, nil
 }
 
@@ -382,6 +427,12 @@ func (i *Instance) executeViaSerial(ctx context.Context, code string, runtimeInf
 
 // ExecuteStream runs code with streaming output.
 func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -398,6 +449,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if !ok {
 		return fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
 
 	if !i.config.EnableNetwork || i.config.SSHKeyPath == "" {
 		return fmt.Errorf("streaming requires network and SSH; enable network and configure SSHKeyPath")
@@ -429,9 +481,15 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		execCmd := strings.Join(runtimeInfo.RunCommand, " ")
 		runCmd = fmt.Sprintf("%s && %s", compileCmd, execCmd)
 	} else {
-		runCmd = strings.Join(append(runtimeInfo.RunCommand, "/tmp/"+codeFilename), " ")
+		runCmd = strings.Join(append(provider.RunCommand(runtimeInfo, i.config.EnableNetwork, "/tmp"), "/tmp/"+codeFilename), " ")
+	}
+	for _, arg := range opts.Args {
+		runCmd += " " + shellQuote(arg)
 	}
 
+	// Compiled binaries land in the working directory, next to the code.
+	runCmd = "cd /tmp && " + runCmd
+
 	sshRunArgs := append(sshArgs, runCmd)
 	runExec := exec.CommandContext(ctx, "ssh", sshRunArgs...)
 
@@ -448,6 +506,11 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		return fmt.Errorf("start command: %w", err)
 	}
 
+	// stdout and stderr share one output budget.
+	outputRate := ratelimit.NewByteRate(opts.MaxOutputRate)
+	stdout := outputRate.Reader(ctx, stdoutPipe)
+	stderr := outputRate.Reader(ctx, stderrPipe)
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -456,7 +519,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stdoutPipe.Read(buf)
+			n, err := stdout.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
 					Type:      executor.StreamStdout,
@@ -475,7 +538,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stderrPipe.Read(buf)
+			n, err := stderr.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
 					Type:      executor.StreamStderr,
@@ -501,6 +564,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
 		ExitCode:  exitCode,
+		Language:  opts.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -509,6 +573,12 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 
 // RunCommand executes a shell command in the VM.
 func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*executor.CommandResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -543,7 +613,7 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 	sshExec.Stdout = &stdout
 	sshExec.Stderr = &stderr
 
-	err := sshExec.Run()
+	err = sshExec.Run()
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -633,43 +703,29 @@ type firecrackerFS struct {
 	instance *Instance
 }
 
-func (f *firecrackerFS) Read(ctx context.Context, path string) ([]byte, error) {
-	if !f.instance.config.EnableNetwork || f.instance.config.SSHKeyPath == "" {
-		return nil, fmt.Errorf("filesystem operations require network and SSH")
-	}
-
-	sshArgs := []string{
+// sshCommand returns an ssh command running command in the VM. -T keeps
+// ssh from allocating a terminal, which would translate line endings.
+func (f *firecrackerFS) sshCommand(ctx context.Context, command string) *exec.Cmd {
+	return exec.CommandContext(ctx, "ssh",
+		"-T",
 		"-i", f.instance.config.SSHKeyPath,
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		fmt.Sprintf("root@%s", f.instance.config.VMIPAddress),
-		fmt.Sprintf("cat %s", path),
-	}
+		command,
+	)
+}
 
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("read file: %w", err)
+func (f *firecrackerFS) Read(ctx context.Context, path string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.Download(ctx, path, &buf); err != nil {
+		return nil, err
 	}
-	return output, nil
+	return buf.Bytes(), nil
 }
 
 func (f *firecrackerFS) Write(ctx context.Context, path string, data []byte) error {
-	if !f.instance.config.EnableNetwork || f.instance.config.SSHKeyPath == "" {
-		return fmt.Errorf("filesystem operations require network and SSH")
-	}
-
-	sshArgs := []string{
-		"-i", f.instance.config.SSHKeyPath,
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		fmt.Sprintf("root@%s", f.instance.config.VMIPAddress),
-		fmt.Sprintf("cat > %s", path),
-	}
-
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	cmd.Stdin = bytes.NewReader(data)
-	return cmd.Run()
+	return f.UploadReader(ctx, bytes.NewReader(data), path)
 }
 
 func (f *firecrackerFS) Delete(ctx context.Context, path string) error {
@@ -720,7 +776,7 @@ func (f *firecrackerFS) List(ctx context.Context, path string) ([]fs.FileInfo, e
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
 		fmt.Sprintf("root@%s", f.instance.config.VMIPAddress),
-		fmt.Sprintf("ls -la %s", path),
+		fs.ListCommand(path),
 	}
 
 	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
@@ -729,27 +785,7 @@ func (f *firecrackerFS) List(ctx context.Context, path string) ([]fs.FileInfo, e
 		return nil, fmt.Errorf("list directory: %w", err)
 	}
 
-	// Parse ls output (simplified)
-	var files []fs.FileInfo
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines[1:] { // Skip "total" line
-		if line == "" {
-			continue
-		}
-		fields := strings.Fields(line)
-		if len(fields) < 9 {
-			continue
-		}
-		name := fields[8]
-		if name == "." || name == ".." {
-			continue
-		}
-		files = append(files, fs.FileInfo{
-			Name:  name,
-			IsDir: fields[0][0] == 'd',
-		})
-	}
-	return files, nil
+	return fs.ParseList(path, output)
 }
 
 func (f *firecrackerFS) MkDir(ctx context.Context, path string) error {
@@ -804,22 +840,32 @@ func (f *firecrackerFS) Stat(ctx context.Context, path string) (*fs.FileInfo, er
 	}, nil
 }
 
+// UploadReader streams reader to remotePath. The content is sent base64
+// encoded and decoded in the VM, so binary data arrives byte for byte.
 func (f *firecrackerFS) UploadReader(ctx context.Context, reader io.Reader, remotePath string) error {
 	if !f.instance.config.EnableNetwork || f.instance.config.SSHKeyPath == "" {
 		return fmt.Errorf("filesystem operations require network and SSH")
 	}
 
-	sshArgs := []string{
-		"-i", f.instance.config.SSHKeyPath,
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
-		fmt.Sprintf("root@%s", f.instance.config.VMIPAddress),
-		fmt.Sprintf("cat > %s", remotePath),
-	}
+	pr, pw := io.Pipe()
+	go func() {
+		enc := base64.NewEncoder(base64.StdEncoding, pw)
+		_, err := io.Copy(enc, reader)
+		if err == nil {
+			err = enc.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
 
-	cmd := exec.CommandContext(ctx, "ssh", sshArgs...)
-	cmd.Stdin = reader
-	return cmd.Run()
+	var stderr bytes.Buffer
+	cmd := f.sshCommand(ctx, fmt.Sprintf("base64 -d > %s", shellQuote(remotePath)))
+	cmd.Stdin = pr
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("write file: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
 }
 
 func (f *firecrackerFS) Move(ctx context.Context, src, dst string) error {
@@ -857,31 +903,50 @@ func (f *firecrackerFS) Copy(ctx context.Context, src, dst string) error {
 }
 
 func (f *firecrackerFS) Upload(ctx context.Context, localPath, remotePath string) error {
-	if !f.instance.config.EnableNetwork || f.instance.config.SSHKeyPath == "" {
-		return fmt.Errorf("filesystem operations require network and SSH")
-	}
-
-	// Read local file
-	data, err := os.ReadFile(localPath)
+	file, err := os.Open(localPath)
 	if err != nil {
 		return fmt.Errorf("read local file: %w", err)
 	}
+	defer file.Close()
 
-	return f.Write(ctx, remotePath, data)
+	return f.UploadReader(ctx, file, remotePath)
 }
 
+// Download streams remotePath to writer. The VM sends it base64 encoded,
+// so binary data arrives byte for byte; output that does not decode, such
+// as from a connection cut mid-file, is an error rather than short data.
 func (f *firecrackerFS) Download(ctx context.Context, remotePath string, writer io.Writer) error {
 	if !f.instance.config.EnableNetwork || f.instance.config.SSHKeyPath == "" {
 		return fmt.Errorf("filesystem operations require network and SSH")
 	}
 
-	data, err := f.Read(ctx, remotePath)
+	var stderr bytes.Buffer
+	cmd := f.sshCommand(ctx, fmt.Sprintf("base64 < %s", shellQuote(remotePath)))
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return err
+		return fmt.Errorf("read file: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("read file: %w", err)
 	}
+	_, copyErr := io.Copy(writer, base64.NewDecoder(base64.StdEncoding, stdout))
+	if copyErr != nil {
+		// Drain the rest so ssh is not left blocked on a full pipe.
+		io.Copy(io.Discard, stdout)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("read file: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if copyErr != nil {
+		return fmt.Errorf("read file: %w", copyErr)
+	}
+	return nil
+}
 
-	_, err = writer.Write(data)
-	return err
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 var _ fs.FileSystem = (*firecrackerFS)(nil)
@@ -901,8 +966,8 @@ func (n *firecrackerNetwork) PublishPort(ctx context.Context, port int) (*provid
 		n.ports = make(map[int]*provider.PublishedPort)
 	}
 
-	// For Firecracker, port publishing is typically done via iptables rules on the host
-	// This is a simplified implementation
+	// The guest is reachable from the host over its TAP device, so the URL
+	// points straight at the VM IP without any host port forwarding.
 	published := &provider.PublishedPort{
 		LocalPort:  port,
 		PublicPort: port,
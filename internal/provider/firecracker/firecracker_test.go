@@ -0,0 +1,54 @@
+//go:build linux
+
+package firecracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeSSH puts an ssh on PATH that runs its last argument, the remote
+// command, with the local shell, standing in for a VM.
+func fakeSSH(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\nfor cmd; do :; done\nexec sh -c \"$cmd\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestFileSystemBinaryRoundTrip(t *testing.T) {
+	fakeSSH(t)
+	fsys := (&Instance{config: &Config{EnableNetwork: true, SSHKeyPath: "key", VMIPAddress: "172.16.0.2"}}).FileSystem()
+	ctx := context.Background()
+
+	// Random bytes cover NUL, CR, LF and invalid UTF-8, and the size
+	// spans several pipe buffers.
+	data := make([]byte, 1<<20+3)
+	rand.Read(data)
+	path := filepath.Join(t.TempDir(), "it's binary.bin")
+
+	if err := fsys.Write(ctx, path, data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if onDisk, _ := os.ReadFile(path); !bytes.Equal(onDisk, data) {
+		t.Fatalf("written file differs: %d bytes, want %d", len(onDisk), len(data))
+	}
+	got, err := fsys.Read(ctx, path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Read() = %d bytes not equal to the %d written", len(got), len(data))
+	}
+
+	if _, err := fsys.Read(ctx, path+".missing"); err == nil {
+		t.Error("Read() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package gvisor
+
+import (
+	"context"
+	"io"
+
+	"github.com/happyhackingspace/sindoq/internal/provider/dockerapi"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// ExportTar streams dir out of the container.
+func (g *gvisorFS) ExportTar(ctx context.Context, dir string, w io.Writer) error {
+	return dockerapi.ExportTar(ctx, g.instance.client, g.instance.id, dir, w)
+}
+
+// ImportTar streams r into dir, creating it first.
+func (g *gvisorFS) ImportTar(ctx context.Context, dir string, r io.Reader) error {
+	if err := g.MkDir(ctx, dir); err != nil {
+		return err
+	}
+	return dockerapi.ImportTar(ctx, g.instance.client, g.instance.id, dir, r)
+}
+
+var _ fs.Archiver = (*gvisorFS)(nil)
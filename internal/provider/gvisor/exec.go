@@ -0,0 +1,126 @@
+//go:build linux
+
+package gvisor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// execExitWait bounds how long execExitCode waits for the daemon to record
+// the exit of an exec whose output has ended.
+const execExitWait = 5 * time.Second
+
+// startExec creates an exec and starts it attached. ContainerExecAttach
+// sends the start request itself and hijacks its connection, so the
+// output streams are attached before the process runs and a program that
+// prints and exits at once loses nothing. Starting the exec with
+// ContainerExecStart and attaching afterwards would open a window in which
+// early output is dropped.
+func (i *Instance) startExec(ctx context.Context, config container.ExecOptions) (string, types.HijackedResponse, error) {
+	execID, err := i.client.ContainerExecCreate(ctx, i.id, config)
+	if err != nil {
+		return "", types.HijackedResponse{}, fmt.Errorf("create exec: %w", err)
+	}
+
+	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return "", types.HijackedResponse{}, fmt.Errorf("attach exec: %w", err)
+	}
+	return execID.ID, resp, nil
+}
+
+// execExitCode returns the exit code of an exec whose output has been read
+// to the end. The daemon can close the streams before it records the
+// exit, and an inspect in that window reports the exec as still running
+// with exit code 0, so a failing program would appear to succeed; the
+// inspect is repeated until the exec has finished.
+func (i *Instance) execExitCode(ctx context.Context, execID string) (int, error) {
+	deadline := time.Now().Add(execExitWait)
+	delay := time.Millisecond
+	for {
+		inspect, err := i.client.ContainerExecInspect(ctx, execID)
+		if err != nil {
+			return 0, fmt.Errorf("inspect exec: %w", err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("inspect exec: still running %v after its output ended", execExitWait)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("inspect exec: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, 50*time.Millisecond)
+	}
+}
+
+// streamExec runs an exec of config, passing its output to handler as
+// stdoutType and stderrType events as it arrives, and returns its exit
+// code. stdin, if set, is copied to the program and maxRate, if positive,
+// caps the output rate. Cancelling ctx closes the connection, which ends
+// the read loops, and the program fails its next write.
+func (i *Instance) streamExec(ctx context.Context, config container.ExecOptions, stdin io.Reader, maxRate int64, stdoutType, stderrType executor.StreamEventType, handler executor.StreamHandler) (int, error) {
+	execID, resp, err := i.startExec(ctx, config)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+	stop := context.AfterFunc(ctx, resp.Close)
+	defer stop()
+
+	if stdin != nil {
+		go func() {
+			io.Copy(resp.Conn, stdin)
+			resp.CloseWrite()
+		}()
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	go func() {
+		output := ratelimit.NewByteRate(maxRate).Reader(ctx, resp.Reader)
+		stdcopy.StdCopy(stdoutWriter, stderrWriter, output)
+		stdoutWriter.Close()
+		stderrWriter.Close()
+	}()
+
+	var wg sync.WaitGroup
+	forward := func(r io.Reader, eventType executor.StreamEventType) {
+		defer wg.Done()
+		buf := make([]byte, 1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				handler(&executor.StreamEvent{
+					Type:      eventType,
+					Data:      string(buf[:n]),
+					Timestamp: time.Now(),
+				})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	wg.Add(2)
+	go forward(stdoutReader, stdoutType)
+	go forward(stderrReader, stderrType)
+	wg.Wait()
+
+	return i.execExitCode(ctx, execID)
+}
@@ -9,20 +9,28 @@ package gvisor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"os/exec"
+	"path"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/provider/dockerapi"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 	"github.com/happyhackingspace/sindoq/pkg/langdetect"
@@ -61,6 +69,19 @@ type Config struct {
 
 	// Debug enables gVisor debug logging.
 	Debug bool
+
+	// DisableTimeoutWrapper skips wrapping commands with the coreutils
+	// timeout binary. Set it for images that do not ship timeout.
+	DisableTimeoutWrapper bool
+
+	// Fallback runs containers with Docker's default runtime, after a
+	// warning, when RuntimeName is not configured in Docker. Without it
+	// Create fails. The default runtime does not provide gVisor's
+	// isolation.
+	Fallback bool
+
+	// Warn reports a fallback to the default runtime (default: slog.Warn).
+	Warn func(msg string, keysAndValues ...any)
 }
 
 // DefaultConfig returns sensible defaults.
@@ -81,6 +102,10 @@ type Provider struct {
 	config *Config
 	client *client.Client
 	mu     sync.RWMutex
+
+	// runtimeChecked is set once RuntimeName has been found in Docker, so
+	// later creates skip the check.
+	runtimeChecked bool
 }
 
 // New creates a new gVisor provider.
@@ -119,17 +144,18 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		opts = provider.DefaultCreateOptions()
 	}
 
-	// Determine image
-	imageName := opts.Image
-	if imageName == "" {
-		if opts.Runtime != "" {
-			if info, ok := langdetect.GetRuntimeInfo(opts.Runtime); ok {
-				imageName = info.DockerImage
-			}
-		}
-		if imageName == "" {
-			imageName = p.config.DefaultImage
-		}
+	if opts.Polyglot && opts.Image == "" {
+		return nil, fmt.Errorf("polyglot sandbox requires an image")
+	}
+
+	runtimeName, err := p.containerRuntime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	imageName, err := p.resolveImage(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Pull image if needed
@@ -137,16 +163,10 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		return nil, fmt.Errorf("ensure image: %w", err)
 	}
 
-	// Build environment variables
-	env := make([]string, 0, len(opts.Environment))
-	for k, v := range opts.Environment {
-		env = append(env, fmt.Sprintf("%s=%s", k, v))
-	}
-
 	// Container configuration
 	containerConfig := &container.Config{
 		Image:        imageName,
-		Env:          env,
+		Env:          provider.MergeEnv(opts.Environment, nil),
 		WorkingDir:   opts.WorkDir,
 		AttachStdout: true,
 		AttachStderr: true,
@@ -157,38 +177,133 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 
 	// Host configuration with gVisor runtime
 	hostConfig := &container.HostConfig{
-		Runtime: p.config.RuntimeName,
-		Resources: container.Resources{
-			Memory:   int64(opts.Resources.MemoryMB) * 1024 * 1024,
-			NanoCPUs: int64(opts.Resources.CPUs * 1e9),
-		},
+		Runtime:    runtimeName,
+		Resources:  containerResources(opts.Resources),
 		AutoRemove: false,
+		StorageOpt: dockerapi.DiskLimitStorageOpt(opts.Resources.DiskMB),
+	}
+	if opts.Resources.DiskMB > 0 && opts.ReadonlyRootfs {
+		// The workspace would live in volumes, outside the limited layer.
+		return nil, fmt.Errorf("%w: the workspace of a read-only root filesystem is not covered", provider.ErrDiskLimitUnsupported)
 	}
 
 	// Network mode
 	if !opts.InternetAccess {
+		if len(opts.Ports) > 0 {
+			return nil, fmt.Errorf("publishing ports requires internet access")
+		}
 		hostConfig.NetworkMode = "none"
 	}
+	if len(opts.Ports) > 0 {
+		containerConfig.ExposedPorts, hostConfig.PortBindings = portBindings(opts.Ports)
+	}
+
+	if len(opts.Ulimits) > 0 {
+		hostConfig.Ulimits = containerUlimits(opts.Ulimits)
+	}
+	hostConfig.CapDrop = opts.CapDrop
+	hostConfig.CapAdd = opts.CapAdd
+
+	// Read-only root with writable volumes where code and files are copied
+	if opts.ReadonlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Mounts = writableVolumes(opts.WorkDir, "/tmp")
+		hostConfig.Tmpfs = maps.Clone(provider.ReadonlyRootfsTmpfs)
+	}
+	if opts.SecretFiles {
+		if hostConfig.Tmpfs == nil {
+			hostConfig.Tmpfs = make(map[string]string)
+		}
+		hostConfig.Tmpfs[executor.SecretsDir] = dockerapi.SecretsTmpfsOptions
+	}
 
 	// Create container
 	resp, err := p.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, "")
 	if err != nil {
+		if diskErr := dockerapi.DiskLimitError(err); diskErr != nil {
+			return nil, diskErr
+		}
 		return nil, fmt.Errorf("create container: %w", err)
 	}
 
 	// Start container
 	if err := p.client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		p.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true})
+		p.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
 		return nil, fmt.Errorf("start container: %w", err)
 	}
 
-	return &Instance{
+	inst := &Instance{
 		id:      resp.ID,
 		client:  p.client,
 		config:  p.config,
 		workDir: opts.WorkDir,
 		timeout: opts.Timeout,
-	}, nil
+		env:     opts.Environment,
+		diskMB:  opts.Resources.DiskMB,
+		runtime: provider.NewResolvedRuntime(imageName, opts.Runtime),
+
+		internetAccess: opts.InternetAccess,
+	}
+	if opts.SecretFiles {
+		inst.secretRefs = new(executor.SecretFileRefs)
+	}
+	if opts.Polyglot {
+		inst.runtimes = new(dockerapi.RuntimeChecker)
+	}
+
+	// Docker creates a missing WorkingDir as root, which the image's user
+	// may not be able to write.
+	if opts.WorkDir != "" {
+		dir, err := inst.prepareWorkDir(ctx, opts.WorkDir, opts.FallbackWorkDir)
+		if err != nil {
+			p.client.ContainerRemove(ctx, resp.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+			return nil, err
+		}
+		inst.workDir = dir
+	}
+	return inst, nil
+}
+
+// containerResources converts r to container limits.
+func containerResources(r provider.ResourceConfig) container.Resources {
+	pidsLimit := r.PidsLimit()
+	resources := container.Resources{
+		Memory:    int64(r.MemoryMB) * 1024 * 1024,
+		PidsLimit: &pidsLimit,
+	}
+	if r.CPUMode == provider.CPUModeShares {
+		resources.CPUShares = int64(r.CPUShares)
+	} else {
+		resources.NanoCPUs = int64(r.CPUs * 1e9)
+	}
+	return resources
+}
+
+// containerUlimits converts ulimits to Docker's form, sorted by name.
+func containerUlimits(limits map[string]executor.Ulimit) []*container.Ulimit {
+	ulimits := make([]*container.Ulimit, 0, len(limits))
+	for name, l := range limits {
+		ulimits = append(ulimits, &container.Ulimit{Name: name, Soft: l.Soft, Hard: l.Hard})
+	}
+	sort.Slice(ulimits, func(i, j int) bool {
+		return ulimits[i].Name < ulimits[j].Name
+	})
+	return ulimits
+}
+
+// writableVolumes returns anonymous volume mounts for dirs. Volumes are used
+// rather than tmpfs because the archive API cannot copy files into tmpfs.
+func writableVolumes(dirs ...string) []mount.Mount {
+	mounts := make([]mount.Mount, 0, len(dirs))
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		mounts = append(mounts, mount.Mount{Type: mount.TypeVolume, Target: dir})
+	}
+	return mounts
 }
 
 // ensureImage pulls the image if it doesn't exist locally.
@@ -208,17 +323,44 @@ func (p *Provider) ensureImage(ctx context.Context, imageName string) error {
 	return err
 }
 
+// resolveImage picks the container image: opts.Image, else the image for
+// opts.Runtime, else the configured default.
+func (p *Provider) resolveImage(opts *provider.CreateOptions) (string, error) {
+	if opts.Image != "" {
+		return opts.Image, nil
+	}
+	if opts.Runtime != "" {
+		image, err := langdetect.ResolveImage(opts.Runtime)
+		if err != nil && !errors.Is(err, langdetect.ErrUnknownRuntime) {
+			return "", fmt.Errorf("resolve runtime: %w", err)
+		}
+		if image != "" {
+			return image, nil
+		}
+	}
+	return p.config.DefaultImage, nil
+}
+
 // Capabilities returns gVisor provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		SupportsStreaming:  true,
-		SupportsAsync:      true,
-		SupportsFileSystem: true,
-		SupportsNetwork:    true,
-		SupportedLanguages: langdetect.SupportedLanguages(),
-		MaxExecutionTime:   30 * time.Minute,
-		MaxMemoryMB:        4096,
-		MaxCPUs:            4,
+		SupportsStreaming:        true,
+		SupportsAsync:            true,
+		SupportsFileSystem:       true,
+		SupportsNetwork:          true,
+		SupportsArchive:          true,
+		SupportsEphemeralWorkDir: true,
+		SupportsPause:            true,
+		SupportsCommandWrapper:   true,
+		SupportsInteractiveStdin: true,
+		SupportsCoverage:         true,
+		SupportsInterpreterPath:  true,
+		SupportsProgramArgs:      true,
+		SupportsSecretFiles:      true,
+		SupportedLanguages:       langdetect.SupportedLanguages(),
+		MaxExecutionTime:         30 * time.Minute,
+		MaxMemoryMB:              4096,
+		MaxCPUs:                  4,
 	}
 }
 
@@ -235,24 +377,71 @@ func (p *Provider) Validate(ctx context.Context) error {
 	}
 
 	// Check if runsc runtime is configured in Docker
-	info, err := p.client.Info(ctx)
+	return p.checkRuntime(ctx)
+}
+
+// checkRuntime returns an actionable error if RuntimeName is not configured
+// in Docker.
+func (p *Provider) checkRuntime(ctx context.Context) error {
+	configured, err := p.runtimeConfigured(ctx)
 	if err != nil {
-		return fmt.Errorf("get docker info: %w", err)
+		return err
+	}
+	if !configured {
+		return p.runtimeNotConfigured()
 	}
+	return nil
+}
 
-	runtimeFound := false
-	for name := range info.Runtimes {
-		if name == p.config.RuntimeName {
-			runtimeFound = true
-			break
-		}
+// runtimeNotConfigured explains how to register RuntimeName with Docker.
+func (p *Provider) runtimeNotConfigured() error {
+	return fmt.Errorf("gVisor runtime '%s' not configured in Docker. Add to /etc/docker/daemon.json: {\"runtimes\": {\"runsc\": {\"path\": \"/usr/local/bin/runsc\"}}}", p.config.RuntimeName)
+}
+
+// runtimeConfigured reports whether Docker knows RuntimeName. A positive
+// answer is remembered, so later creates skip the daemon round trip.
+func (p *Provider) runtimeConfigured(ctx context.Context) (bool, error) {
+	p.mu.RLock()
+	checked := p.runtimeChecked
+	p.mu.RUnlock()
+	if checked {
+		return true, nil
 	}
 
-	if !runtimeFound {
-		return fmt.Errorf("gVisor runtime '%s' not configured in Docker. Add to /etc/docker/daemon.json: {\"runtimes\": {\"runsc\": {\"path\": \"/usr/local/bin/runsc\"}}}", p.config.RuntimeName)
+	info, err := p.client.Info(ctx)
+	if err != nil {
+		return false, fmt.Errorf("get docker info: %w", err)
+	}
+	if _, ok := info.Runtimes[p.config.RuntimeName]; !ok {
+		return false, nil
 	}
 
-	return nil
+	p.mu.Lock()
+	p.runtimeChecked = true
+	p.mu.Unlock()
+	return true, nil
+}
+
+// containerRuntime returns the Docker runtime for new containers:
+// RuntimeName, or with Fallback set the default runtime ("") when
+// RuntimeName is not configured.
+func (p *Provider) containerRuntime(ctx context.Context) (string, error) {
+	configured, err := p.runtimeConfigured(ctx)
+	if err != nil {
+		return "", err
+	}
+	if configured {
+		return p.config.RuntimeName, nil
+	}
+	if !p.config.Fallback {
+		return "", p.runtimeNotConfigured()
+	}
+	warn := p.config.Warn
+	if warn == nil {
+		warn = slog.Warn
+	}
+	warn("gVisor runtime not configured in Docker; falling back to the default runtime without gVisor isolation", "runtime", p.config.RuntimeName)
+	return "", nil
 }
 
 // Close releases provider resources.
@@ -271,6 +460,34 @@ type Instance struct {
 	timeout time.Duration
 	mu      sync.RWMutex
 	stopped bool
+
+	// env is the environment the container was created with. Every exec
+	// sets it explicitly, overlaid with the execution's Env, so the
+	// precedence does not depend on the daemon.
+	env map[string]string
+
+	// runtime is the image and runtime resolved at creation.
+	runtime provider.ResolvedRuntime
+
+	// diskMB is the size limit of the writable layer; when set, each
+	// Execute reports the space used.
+	diskMB int
+
+	// secretRefs tracks which secret files overlapping executions hold.
+	// It is nil unless the secrets tmpfs is mounted.
+	secretRefs *executor.SecretFileRefs
+
+	// internetAccess is set when the container has a network. Runtimes
+	// with their own permission model are granted network access to match.
+	internetAccess bool
+
+	// runtimes checks each language's runtime binary. It is nil unless
+	// the image has several runtimes.
+	runtimes *dockerapi.RuntimeChecker
+
+	// network is shared by Network calls so published ports are remembered.
+	networkOnce sync.Once
+	network     *gvisorNetwork
 }
 
 // ID returns the container ID.
@@ -295,6 +512,20 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	if !ok {
 		return nil, fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+
+	if err := i.runtimes.Check(ctx, i.runExec, runtimeInfo); err != nil {
+		return nil, err
+	}
+	if err := dockerapi.CheckCommandWrapper(ctx, i.runExec, opts.CommandWrapper); err != nil {
+		return nil, err
+	}
+
+	opts, removeWorkDir, err := i.ephemeralWorkDir(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeWorkDir()
 
 	codeFilename := "main" + runtimeInfo.FileExt
 	codePath := opts.WorkDir + "/" + codeFilename
@@ -306,21 +537,51 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		return nil, fmt.Errorf("write code file: %w", err)
 	}
 
-	for path, content := range opts.Files {
-		if err := i.writeFile(ctx, path, content); err != nil {
-			return nil, fmt.Errorf("write file %s: %w", path, err)
+	if err := i.writeFiles(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	removeSecrets, err := dockerapi.WriteSecretFiles(ctx, i.runExec, i.secretRefs, opts.SecretFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer removeSecrets()
+
+	var cov *provider.CoverageRun
+	if opts.Coverage {
+		var removeCoverage func()
+		if cov, removeCoverage, err = dockerapi.PrepareCoverage(ctx, i.runExec, i.workDir, opts); err != nil {
+			return nil, err
 		}
+		defer removeCoverage()
 	}
 
 	var cmd []string
-	if runtimeInfo.CompileCmd != nil {
-		compileCmd := append(runtimeInfo.CompileCmd, codePath)
+	if cov != nil {
+		cmd = append(slices.Clone(cov.Run), codePath)
+	} else if runtimeInfo.CompileCmd != nil {
+		compileCmd := provider.WrapUmask(append(runtimeInfo.CompileCmd, codePath), opts.Umask)
 		if _, err := i.runExec(ctx, compileCmd, opts); err != nil {
 			return nil, fmt.Errorf("compile: %w", err)
 		}
 		cmd = runtimeInfo.RunCommand
 	} else {
-		cmd = append(runtimeInfo.RunCommand, codePath)
+		cmd = append(provider.RunCommand(runtimeInfo, i.internetAccess, opts.WorkDir), codePath)
+	}
+	cmd = append(slices.Clip(cmd), opts.Args...)
+	cmd = provider.WrapCommand(cmd, opts.CommandWrapper)
+	if !i.config.DisableTimeoutWrapper {
+		cmd = provider.WrapTimeout(cmd, opts.Timeout)
+	}
+	cmd = provider.WrapUmask(cmd, opts.Umask)
+
+	var before map[string]container.ChangeType
+	if opts.TrackFileChanges {
+		snap, err := dockerapi.ContainerChanges(ctx, i.client, i.id, opts.WorkDir)
+		if err != nil {
+			return nil, err
+		}
+		before = snap
 	}
 
 	execCtx := ctx
@@ -330,9 +591,14 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		defer cancel()
 	}
 
+	runOpts := opts
+	if cov != nil {
+		runOpts = dockerapi.CoverageOptions(cov, opts)
+	}
+
 	start := time.Now()
 
-	result, err := i.runExec(execCtx, cmd, opts)
+	result, err := i.runExec(execCtx, cmd, runOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -340,6 +606,26 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	result.Duration = time.Since(start)
 	result.Language = opts.Language
 
+	if cov != nil {
+		if result.Coverage, err = dockerapi.CollectCoverage(ctx, i.runExec, i.FileSystem(), cov, opts); err != nil && result.ExitCode == 0 {
+			return nil, err
+		}
+	}
+
+	if opts.TrackFileChanges {
+		after, err := dockerapi.ContainerChanges(ctx, i.client, i.id, opts.WorkDir)
+		if err != nil {
+			return nil, err
+		}
+		result.SetFileChanges(dockerapi.DiffContainerChanges(before, after, executor.DefaultMaxTrackedFiles))
+	}
+
+	if i.diskMB > 0 {
+		if result.DiskUsedMB, err = dockerapi.DiskUsedMB(ctx, i.client, i.id); err != nil {
+			return nil, err
+		}
+	}
+
 	return result, nil
 }
 
@@ -351,20 +637,12 @@ func (i *Instance) runExec(ctx context.Context, cmd []string, opts *executor.Exe
 		AttachStdout: true,
 		AttachStderr: true,
 		AttachStdin:  opts.Stdin != "",
+		Env:          provider.MergeEnv(i.env, opts.Env),
 	}
 
-	for k, v := range opts.Env {
-		execConfig.Env = append(execConfig.Env, fmt.Sprintf("%s=%s", k, v))
-	}
-
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
+	execID, resp, err := i.startExec(ctx, execConfig)
 	if err != nil {
-		return nil, fmt.Errorf("create exec: %w", err)
-	}
-
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("attach exec: %w", err)
+		return nil, err
 	}
 	defer resp.Close()
 
@@ -376,39 +654,56 @@ func (i *Instance) runExec(ctx context.Context, cmd []string, opts *executor.Exe
 	}
 
 	var stdout, stderr bytes.Buffer
-	if _, err := stdcopy.StdCopy(&stdout, &stderr, resp.Reader); err != nil {
+	stdoutW := executor.NewLimitedWriter(&stdout, opts.StdoutLimit)
+	stderrW := executor.NewLimitedWriter(&stderr, opts.StderrLimit)
+	if _, err := stdcopy.StdCopy(stdoutW, stderrW, resp.Reader); err != nil {
 		return nil, fmt.Errorf("read output: %w", err)
 	}
 
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
+	exitCode, err := i.execExitCode(ctx, execID)
 	if err != nil {
-		return nil, fmt.Errorf("inspect exec: %w", err)
+		return nil, err
 	}
 
 	return &executor.ExecutionResult{
-		ExitCode: inspectResp.ExitCode,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
+		ExitCode:        exitCode,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdoutW.Truncated(),
+		StderrTruncated: stderrW.Truncated(),
 	}, nil
 }
 
 // writeFile writes content to a file in the container.
-func (i *Instance) writeFile(ctx context.Context, path string, content []byte) error {
+func (i *Instance) writeFile(ctx context.Context, name string, content []byte) error {
 	var buf bytes.Buffer
 	tw := newTarWriter(&buf)
-	if err := tw.WriteFile(path, content); err != nil {
+	if err := tw.WriteFile(name, content); err != nil {
 		return err
 	}
 	tw.Close()
 
-	dir := path[:strings.LastIndex(path, "/")]
-	if dir == "" {
-		dir = "/"
-	}
+	dir := path.Dir(name)
 
 	return i.client.CopyToContainer(ctx, i.id, dir, &buf, container.CopyToContainerOptions{})
 }
 
+// writeFiles writes opts.Files, resolving relative names against the
+// run's working directory.
+func (i *Instance) writeFiles(ctx context.Context, opts *executor.ExecutionOptions) error {
+	if len(opts.Files) == 0 {
+		return nil
+	}
+	workDir := opts.WorkDir
+	if workDir == "" {
+		workDir = i.workDir
+	}
+	if err := dockerapi.WriteFiles(ctx, i.client, i.id, workDir, opts.Files); err != nil {
+		return fmt.Errorf("write files: %w", err)
+	}
+	return nil
+}
+
 // ExecuteStream runs code with streaming output.
 func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
 	i.mu.RLock()
@@ -426,6 +721,20 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if !ok {
 		return fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+
+	if err := i.runtimes.Check(ctx, i.runExec, runtimeInfo); err != nil {
+		return err
+	}
+	if err := dockerapi.CheckCommandWrapper(ctx, i.runExec, opts.CommandWrapper); err != nil {
+		return err
+	}
+
+	opts, removeWorkDir, err := i.ephemeralWorkDir(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer removeWorkDir()
 
 	codeFilename := "main" + runtimeInfo.FileExt
 	codePath := opts.WorkDir + "/" + codeFilename
@@ -436,94 +745,69 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if err := i.writeFile(ctx, codePath, []byte(code)); err != nil {
 		return fmt.Errorf("write code file: %w", err)
 	}
+	if err := i.writeFiles(ctx, opts); err != nil {
+		return err
+	}
+
+	removeSecrets, err := dockerapi.WriteSecretFiles(ctx, i.runExec, i.secretRefs, opts.SecretFiles)
+	if err != nil {
+		return err
+	}
+	defer removeSecrets()
 
 	var cmd []string
 	if runtimeInfo.CompileCmd != nil {
-		compileCmd := append(runtimeInfo.CompileCmd, codePath)
-		if _, err := i.runExec(ctx, compileCmd, opts); err != nil {
+		// Compiler diagnostics stream as they happen, ahead of the run.
+		compileCmd := provider.WrapUmask(append(runtimeInfo.CompileCmd, codePath), opts.Umask)
+		compileConfig := container.ExecOptions{
+			Cmd:          compileCmd,
+			WorkingDir:   opts.WorkDir,
+			AttachStdout: true,
+			AttachStderr: true,
+			Env:          provider.MergeEnv(i.env, opts.Env),
+		}
+		exitCode, err := i.streamExec(ctx, compileConfig, nil, opts.MaxOutputRate, executor.StreamCompileStdout, executor.StreamCompileStderr, handler)
+		if err != nil {
 			return fmt.Errorf("compile: %w", err)
 		}
+		if exitCode != 0 {
+			handler(&executor.StreamEvent{
+				Type:      executor.StreamComplete,
+				ExitCode:  exitCode,
+				Language:  opts.Language,
+				Timestamp: time.Now(),
+			})
+			return nil
+		}
 		cmd = runtimeInfo.RunCommand
 	} else {
-		cmd = append(runtimeInfo.RunCommand, codePath)
+		cmd = append(provider.RunCommand(runtimeInfo, i.internetAccess, opts.WorkDir), codePath)
 	}
+	cmd = append(slices.Clip(cmd), opts.Args...)
+	cmd = provider.WrapCommand(cmd, opts.CommandWrapper)
+	if !i.config.DisableTimeoutWrapper {
+		cmd = provider.WrapTimeout(cmd, opts.Timeout)
+	}
+	cmd = provider.WrapUmask(cmd, opts.Umask)
 
 	execConfig := container.ExecOptions{
 		Cmd:          cmd,
 		WorkingDir:   opts.WorkDir,
 		AttachStdout: true,
 		AttachStderr: true,
+		AttachStdin:  opts.StdinStream != nil,
+		Env:          provider.MergeEnv(i.env, opts.Env),
 	}
 
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
+	exitCode, err := i.streamExec(ctx, execConfig, opts.StdinStream, opts.MaxOutputRate, executor.StreamStdout, executor.StreamStderr, handler)
 	if err != nil {
-		return fmt.Errorf("create exec: %w", err)
-	}
-
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
-	if err != nil {
-		return fmt.Errorf("attach exec: %w", err)
-	}
-	defer resp.Close()
-
-	stdoutReader, stdoutWriter := io.Pipe()
-	stderrReader, stderrWriter := io.Pipe()
-
-	go func() {
-		stdcopy.StdCopy(stdoutWriter, stderrWriter, resp.Reader)
-		stdoutWriter.Close()
-		stderrWriter.Close()
-	}()
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		buf := make([]byte, 1024)
-		for {
-			n, err := stdoutReader.Read(buf)
-			if n > 0 {
-				handler(&executor.StreamEvent{
-					Type:      executor.StreamStdout,
-					Data:      string(buf[:n]),
-					Timestamp: time.Now(),
-				})
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-
-	go func() {
-		defer wg.Done()
-		buf := make([]byte, 1024)
-		for {
-			n, err := stderrReader.Read(buf)
-			if n > 0 {
-				handler(&executor.StreamEvent{
-					Type:      executor.StreamStderr,
-					Data:      string(buf[:n]),
-					Timestamp: time.Now(),
-				})
-			}
-			if err != nil {
-				break
-			}
-		}
-	}()
-
-	wg.Wait()
-
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
-	if err != nil {
-		return fmt.Errorf("inspect exec: %w", err)
+		return err
 	}
 
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
-		ExitCode:  inspectResp.ExitCode,
+		ExitCode:  exitCode,
+		Language:  opts.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -543,20 +827,17 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 
 	execConfig := container.ExecOptions{
 		Cmd:          fullCmd,
+		WorkingDir:   i.workDir,
 		AttachStdout: true,
 		AttachStderr: true,
-	}
-
-	execID, err := i.client.ContainerExecCreate(ctx, i.id, execConfig)
-	if err != nil {
-		return nil, fmt.Errorf("create exec: %w", err)
+		Env:          provider.MergeEnv(i.env, nil),
 	}
 
 	start := time.Now()
 
-	resp, err := i.client.ContainerExecAttach(ctx, execID.ID, container.ExecAttachOptions{})
+	execID, resp, err := i.startExec(ctx, execConfig)
 	if err != nil {
-		return nil, fmt.Errorf("attach exec: %w", err)
+		return nil, err
 	}
 	defer resp.Close()
 
@@ -565,13 +846,13 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 		return nil, fmt.Errorf("read output: %w", err)
 	}
 
-	inspectResp, err := i.client.ContainerExecInspect(ctx, execID.ID)
+	exitCode, err := i.execExitCode(ctx, execID)
 	if err != nil {
-		return nil, fmt.Errorf("inspect exec: %w", err)
+		return nil, err
 	}
 
 	return &executor.CommandResult{
-		ExitCode: inspectResp.ExitCode,
+		ExitCode: exitCode,
 		Stdout:   stdout.String(),
 		Stderr:   stderr.String(),
 		Duration: time.Since(start),
@@ -585,7 +866,10 @@ func (i *Instance) FileSystem() fs.FileSystem {
 
 // Network returns the network handler.
 func (i *Instance) Network() provider.Network {
-	return &gvisorNetwork{instance: i}
+	i.networkOnce.Do(func() {
+		i.network = &gvisorNetwork{instance: i}
+	})
+	return i.network
 }
 
 // Stop terminates the container.
@@ -604,7 +888,7 @@ func (i *Instance) Stop(ctx context.Context) error {
 		}
 	}
 
-	if err := i.client.ContainerRemove(ctx, i.id, container.RemoveOptions{Force: true}); err != nil {
+	if err := i.client.ContainerRemove(ctx, i.id, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
 		if !strings.Contains(err.Error(), "No such container") {
 			return fmt.Errorf("remove container: %w", err)
 		}
@@ -613,6 +897,33 @@ func (i *Instance) Stop(ctx context.Context) error {
 	return nil
 }
 
+// RuntimeInfo returns the image and runtime resolved at creation.
+func (i *Instance) RuntimeInfo() provider.ResolvedRuntime {
+	return i.runtime
+}
+
+// WorkDir returns the working directory prepared at creation, which is the
+// fallback when CreateOptions.WorkDir could not be used.
+func (i *Instance) WorkDir() string {
+	return i.workDir
+}
+
+// Pause freezes every process in the container with the cgroup freezer.
+func (i *Instance) Pause(ctx context.Context) error {
+	if err := i.client.ContainerPause(ctx, i.id); err != nil {
+		return fmt.Errorf("pause container: %w", err)
+	}
+	return nil
+}
+
+// Resume unfreezes a paused container.
+func (i *Instance) Resume(ctx context.Context) error {
+	if err := i.client.ContainerUnpause(ctx, i.id); err != nil {
+		return fmt.Errorf("unpause container: %w", err)
+	}
+	return nil
+}
+
 // Status returns the current status.
 func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error) {
 	i.mu.RLock()
@@ -627,14 +938,18 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 		return provider.StatusError, err
 	}
 
-	if info.State.Running {
-		return provider.StatusRunning, nil
-	}
+	// A paused container also reports Running.
 	if info.State.Paused {
 		return provider.StatusPaused, nil
 	}
+	if info.State.Running {
+		return provider.StatusRunning, nil
+	}
 
 	return provider.StatusStopped, nil
 }
 
 var _ provider.Instance = (*Instance)(nil)
+var _ provider.Pauser = (*Instance)(nil)
+var _ provider.RuntimeReporter = (*Instance)(nil)
+var _ provider.WorkDirReporter = (*Instance)(nil)
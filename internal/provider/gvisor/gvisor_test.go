@@ -0,0 +1,147 @@
+//go:build linux
+
+package gvisor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// fakeDaemon is a minimal Docker API that knows the runtimes in runtimes
+// and records the runtime of each created container. Execs succeed
+// without output.
+type fakeDaemon struct {
+	runtimes []string
+
+	mu       sync.Mutex
+	created  []string
+	infoHits int
+}
+
+func (d *fakeDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("API-Version", "1.43")
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/_ping"):
+		w.Write([]byte("OK"))
+	case strings.HasSuffix(path, "/info"):
+		d.mu.Lock()
+		d.infoHits++
+		d.mu.Unlock()
+		runtimes := make(map[string]any)
+		for _, name := range d.runtimes {
+			runtimes[name] = map[string]string{"path": name}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Runtimes": runtimes})
+	case strings.Contains(path, "/images/") && strings.HasSuffix(path, "/json"):
+		json.NewEncoder(w).Encode(map[string]any{"Id": "sha256:test"})
+	case strings.HasSuffix(path, "/containers/create"):
+		var body struct {
+			HostConfig container.HostConfig
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		d.mu.Lock()
+		d.created = append(d.created, body.HostConfig.Runtime)
+		d.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"Id": "test-container"})
+	case strings.Contains(path, "/containers/") && strings.HasSuffix(path, "/exec"):
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"Id": "test-exec"})
+	case strings.Contains(path, "/exec/") && strings.HasSuffix(path, "/start"):
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		buf.Flush()
+		conn.Close()
+	case strings.Contains(path, "/exec/") && strings.HasSuffix(path, "/json"):
+		json.NewEncoder(w).Encode(map[string]any{"ID": "test-exec", "Running": false, "ExitCode": 0})
+	case strings.HasSuffix(path, "/start"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		// Anything else would surface as a raw Docker error.
+		http.Error(w, `{"message":"unknown or invalid runtime name: runsc"}`, http.StatusBadRequest)
+	}
+}
+
+func newTestProvider(t *testing.T, d *fakeDaemon, cfg *Config) *Provider {
+	t.Helper()
+
+	srv := httptest.NewServer(d)
+	t.Cleanup(srv.Close)
+
+	cfg.DockerHost = "tcp://" + strings.TrimPrefix(srv.URL, "http://")
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func TestCreateRuntimeNotConfigured(t *testing.T) {
+	d := &fakeDaemon{runtimes: []string{"runc"}}
+	p := newTestProvider(t, d, DefaultConfig())
+
+	_, err := p.Create(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Create() should fail when runsc is not configured")
+	}
+	if !strings.Contains(err.Error(), "not configured in Docker") || !strings.Contains(err.Error(), "daemon.json") {
+		t.Errorf("Create() error = %v, want the daemon.json hint", err)
+	}
+	if len(d.created) != 0 {
+		t.Errorf("created %d containers, want none", len(d.created))
+	}
+}
+
+func TestCreateRuntimeFallback(t *testing.T) {
+	d := &fakeDaemon{runtimes: []string{"runc"}}
+	var warnings []string
+	cfg := DefaultConfig()
+	cfg.Fallback = true
+	cfg.Warn = func(msg string, keysAndValues ...any) {
+		warnings = append(warnings, msg)
+	}
+	p := newTestProvider(t, d, cfg)
+
+	inst, err := p.Create(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if inst.ID() != "test-container" {
+		t.Errorf("ID() = %q, want %q", inst.ID(), "test-container")
+	}
+	if len(d.created) != 1 || d.created[0] != "" {
+		t.Errorf("created runtimes = %q, want the default runtime", d.created)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %q, want one", warnings)
+	}
+}
+
+func TestCreateRuntimeConfigured(t *testing.T) {
+	d := &fakeDaemon{runtimes: []string{"runc", "runsc"}}
+	p := newTestProvider(t, d, DefaultConfig())
+
+	for range 2 {
+		if _, err := p.Create(context.Background(), nil); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if len(d.created) != 2 || d.created[0] != "runsc" || d.created[1] != "runsc" {
+		t.Errorf("created runtimes = %q, want runsc", d.created)
+	}
+	if d.infoHits != 1 {
+		t.Errorf("info requests = %d, want 1", d.infoHits)
+	}
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package gvisor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ephemeralWorkDir returns opts unchanged unless opts.EphemeralWorkDir is
+// set, in which case it creates a fresh subdirectory of the working
+// directory and returns a copy of opts that runs there, along with a func
+// that removes the subdirectory.
+func (i *Instance) ephemeralWorkDir(ctx context.Context, opts *executor.ExecutionOptions) (*executor.ExecutionOptions, func(), error) {
+	if !opts.EphemeralWorkDir {
+		return opts, func() {}, nil
+	}
+
+	parent := opts.WorkDir
+	if parent == "" {
+		parent = i.workDir
+	}
+	result, err := i.runExec(ctx, []string{"mktemp", "-d", parent + "/.run-XXXXXX"}, &executor.ExecutionOptions{})
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("exit code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("create ephemeral workdir: %w", err)
+	}
+	dir := strings.TrimSpace(result.Stdout)
+
+	remove := func() {
+		// Remove even when ctx was cancelled by a timeout.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		i.runExec(ctx, []string{"rm", "-rf", "--", dir}, &executor.ExecutionOptions{})
+	}
+
+	runOpts := *opts
+	runOpts.WorkDir = dir
+	return &runOpts, remove, nil
+}
+
+// prepareWorkDir creates dir as the container's user and checks that the
+// user can write to it. When that fails and fallback is set, it prepares
+// fallback instead. It returns the directory prepared.
+func (i *Instance) prepareWorkDir(ctx context.Context, dir, fallback string) (string, error) {
+	err := i.makeWorkDir(ctx, dir)
+	if err == nil {
+		return dir, nil
+	}
+	if fallback == "" || fallback == dir {
+		return "", err
+	}
+	if ferr := i.makeWorkDir(ctx, fallback); ferr != nil {
+		return "", fmt.Errorf("%w; fallback: %w", err, ferr)
+	}
+	return fallback, nil
+}
+
+// makeWorkDir runs mkdir -p for dir and fails unless it ends up writable.
+func (i *Instance) makeWorkDir(ctx context.Context, dir string) error {
+	result, err := i.runExec(ctx, []string{"sh", "-c", `mkdir -p -- "$1" && test -w "$1"`, "sh", dir}, &executor.ExecutionOptions{WorkDir: "/"})
+	if err == nil && result.ExitCode != 0 {
+		err = fmt.Errorf("not writable (exit code %d): %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("prepare workdir %s: %w", dir, err)
+	}
+	return nil
+}
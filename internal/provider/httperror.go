@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxHTTPErrorBody bounds how much of a response body HTTPProviderError keeps.
+const maxHTTPErrorBody = 4096
+
+// HTTPProviderError is returned by the API-backed providers (E2B, Vercel,
+// Lambda) when a request gets an unexpected HTTP status. Callers can use errors.As
+// and branch on Status: 401 for a bad key, 404 for a sandbox that is gone,
+// 429 for rate limiting (after retries) and 5xx for service failures.
+type HTTPProviderError struct {
+	// Op is the operation that failed, e.g. "create sandbox".
+	Op string
+
+	// Status is the HTTP status code.
+	Status int
+
+	// Body is the start of the response body.
+	Body string
+}
+
+// Error implements the error interface.
+func (e *HTTPProviderError) Error() string {
+	msg := fmt.Sprintf("%s failed: %d %s", e.Op, e.Status, http.StatusText(e.Status))
+	if e.Body != "" {
+		msg += " - " + e.Body
+	}
+	return msg
+}
+
+// CheckHTTPResponse returns nil if resp has one of the ok statuses, or
+// http.StatusOK when none are given. Otherwise it reads the start of the
+// body and returns an *HTTPProviderError for op.
+func CheckHTTPResponse(op string, resp *http.Response, ok ...int) error {
+	if len(ok) == 0 {
+		ok = []int{http.StatusOK}
+	}
+	if slices.Contains(ok, resp.StatusCode) {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHTTPErrorBody))
+	return &HTTPProviderError{
+		Op:     op,
+		Status: resp.StatusCode,
+		Body:   strings.TrimSpace(string(body)),
+	}
+}
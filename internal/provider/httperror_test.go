@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckHTTPResponse(t *testing.T) {
+	resp := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	if err := CheckHTTPResponse("get", resp(http.StatusOK, "")); err != nil {
+		t.Errorf("200 error = %v", err)
+	}
+	if err := CheckHTTPResponse("create", resp(http.StatusCreated, ""), http.StatusOK, http.StatusCreated); err != nil {
+		t.Errorf("201 with 201 allowed error = %v", err)
+	}
+
+	err := CheckHTTPResponse("create", resp(http.StatusTooManyRequests, strings.Repeat("x", 2*maxHTTPErrorBody)))
+	var httpErr *HTTPProviderError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("error = %v, want *HTTPProviderError", err)
+	}
+	if httpErr.Status != http.StatusTooManyRequests || len(httpErr.Body) != maxHTTPErrorBody {
+		t.Errorf("Status = %d, len(Body) = %d", httpErr.Status, len(httpErr.Body))
+	}
+	if !strings.HasPrefix(err.Error(), "create failed: 429 Too Many Requests - x") {
+		t.Errorf("Error() = %.50q", err.Error())
+	}
+}
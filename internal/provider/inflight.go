@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrClosing is returned for new work on a provider that is closing.
+var ErrClosing = errors.New("provider is closing")
+
+// GracefulCloser is implemented by providers that can let in-flight
+// executions finish before closing.
+type GracefulCloser interface {
+	// CloseGraceful stops accepting new sandboxes and executions, waits
+	// for outstanding executions to finish, then closes. If ctx ends first
+	// the remaining executions are aborted, the provider is closed anyway
+	// and ctx's error is returned.
+	CloseGraceful(ctx context.Context) error
+}
+
+// InFlight tracks the executions running on a provider so that
+// CloseGraceful can wait for them. The zero value is ready to use.
+type InFlight struct {
+	mu      sync.Mutex
+	closing bool
+	wg      sync.WaitGroup
+
+	// abort is cancelled when Drain gives up, cancelling every context
+	// handed out by Start.
+	abort       context.Context
+	cancelAbort context.CancelFunc
+}
+
+// Start registers a unit of work. It returns a context derived from ctx
+// that is cancelled if the provider is force-closed, and a func that must
+// be called when the work ends. After Drain it returns ErrClosing.
+func (f *InFlight) Start(ctx context.Context) (context.Context, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closing {
+		return nil, nil, ErrClosing
+	}
+	f.init()
+	f.wg.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(f.abort, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		f.wg.Done()
+	}, nil
+}
+
+// Drain makes Start refuse new work and waits until the outstanding work
+// is done. If ctx ends first it cancels the outstanding work and returns
+// ctx's error without waiting for it.
+func (f *InFlight) Drain(ctx context.Context) error {
+	f.mu.Lock()
+	f.closing = true
+	f.init()
+	f.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		f.cancelAbort()
+		return ctx.Err()
+	}
+}
+
+// CloseGraceful implements GracefulCloser for a provider: it drains f, then
+// calls stop to stop the provider's instances even if ctx ended first.
+// stop's error takes precedence over ctx's.
+func (f *InFlight) CloseGraceful(ctx context.Context, stop func() error) error {
+	drainErr := f.Drain(ctx)
+	if err := stop(); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+// SnapshotInstances returns the instances in m, read under mu. Stop removes
+// an instance from m under mu, so providers stop the snapshot, not m.
+func SnapshotInstances[K comparable, I any](mu *sync.RWMutex, m map[K]I) []I {
+	mu.RLock()
+	defer mu.RUnlock()
+	return slices.Collect(maps.Values(m))
+}
+
+// init creates the abort context. f.mu must be held.
+func (f *InFlight) init() {
+	if f.abort == nil {
+		f.abort, f.cancelAbort = context.WithCancel(context.Background())
+	}
+}
@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInFlightDrainWaits(t *testing.T) {
+	var f InFlight
+
+	_, done, err := f.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- f.Drain(context.Background()) }()
+
+	select {
+	case <-drained:
+		t.Fatal("Drain() returned while work was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, _, err := f.Start(context.Background()); !errors.Is(err, ErrClosing) {
+		t.Errorf("Start() while draining error = %v, want ErrClosing", err)
+	}
+
+	done()
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Drain() did not return after the work finished")
+	}
+}
+
+func TestInFlightDrainTimeoutAborts(t *testing.T) {
+	var f InFlight
+
+	workCtx, done, err := f.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := f.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Drain() error = %v, want DeadlineExceeded", err)
+	}
+
+	select {
+	case <-workCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("in-flight context was not cancelled when Drain gave up")
+	}
+}
+
+func TestInFlightDrainIdle(t *testing.T) {
+	var f InFlight
+	if err := f.Drain(context.Background()); err != nil {
+		t.Errorf("Drain() with no work error = %v", err)
+	}
+}
+
+func TestInFlightCloseGraceful(t *testing.T) {
+	var f InFlight
+	_, done, err := f.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	stopped := false
+	err = f.CloseGraceful(ctx, func() error {
+		stopped = true
+		return nil
+	})
+	done()
+	if !errors.Is(err, context.DeadlineExceeded) || !stopped {
+		t.Errorf("CloseGraceful() = %v, stopped %v, want DeadlineExceeded after stopping", err, stopped)
+	}
+
+	stopErr := errors.New("stop failed")
+	if err := f.CloseGraceful(context.Background(), func() error { return stopErr }); err != stopErr {
+		t.Errorf("CloseGraceful() = %v, want the stop error", err)
+	}
+}
@@ -0,0 +1,39 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// loadAWSConfig resolves the region and credentials the way the AWS SDKs
+// do. Static keys in cfg win; otherwise the default chain applies: the
+// AWS_* environment variables, the shared config and credentials files
+// (SSO, assume-role and credential_process profiles included), web
+// identity tokens, and ECS or EC2 instance roles. The returned provider
+// caches credentials and refreshes temporary ones before they expire.
+func loadAWSConfig(ctx context.Context, cfg *Config) (aws.Config, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	if cfg.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(cfg.Profile))
+	}
+	if cfg.AccessKeyID != "" || cfg.SecretAccessKey != "" {
+		if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+			return aws.Config{}, fmt.Errorf("lambda: both AccessKeyID and SecretAccessKey are required")
+		}
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken)))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("lambda: load AWS config: %w", err)
+	}
+	return awsCfg, nil
+}
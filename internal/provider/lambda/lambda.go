@@ -0,0 +1,533 @@
+// Package lambda provides the AWS Lambda provider for sindoq.
+//
+// The provider invokes a pre-deployed runner function synchronously. Each
+// invocation receives a JSON payload
+//
+//	{"code": "...", "language": "Python", "stdin": "...", "env": {...},
+//	 "files": {"data.txt": "<base64>"}, "timeout_ms": 30000}
+//
+// or, for RunCommand, {"command": "ls", "args": ["-l"]}, and must return
+//
+//	{"stdout": "...", "stderr": "...", "exit_code": 0, "error": ""}
+//
+// Lambda keeps no state between invocations, so a sandbox is only a handle
+// on the function: files do not persist and there is no file system or
+// network access from the host.
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+const (
+	defaultRateLimit  = 10
+	defaultRateBurst  = 20
+	defaultMaxRetries = 3
+
+	defaultFunctionName = "sindoq-runner"
+
+	// maxExecutionTime is Lambda's hard limit on a single invocation.
+	maxExecutionTime = 15 * time.Minute
+)
+
+// defaultLanguages are the languages the reference runner supports.
+var defaultLanguages = []string{"Python", "JavaScript", "Bash"}
+
+func init() {
+	factory.Register("lambda", func(config any) (provider.Provider, error) {
+		cfg, ok := config.(*Config)
+		if !ok && config != nil {
+			return nil, fmt.Errorf("invalid config type for lambda provider")
+		}
+		return New(cfg)
+	})
+}
+
+// Config holds AWS Lambda provider configuration.
+type Config struct {
+	// Region is the AWS region of the function (default: AWS_REGION,
+	// AWS_DEFAULT_REGION or the profile's region).
+	Region string
+
+	// FunctionName is the name or ARN of the runner function
+	// (default "sindoq-runner").
+	FunctionName string
+
+	// Qualifier selects a version or alias of the function (optional).
+	Qualifier string
+
+	// AccessKeyID, SecretAccessKey and SessionToken set static
+	// credentials. When empty, credentials come from the AWS SDK's
+	// default chain: the environment, the shared config and credentials
+	// files, web identity tokens, then ECS or EC2 instance roles.
+	// Temporary credentials are refreshed as they expire.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Profile is the shared config profile (default: AWS_PROFILE or
+	// "default"). SSO and assume-role profiles work too.
+	Profile string
+
+	// Languages lists the languages the deployed runner supports
+	// (default Python, JavaScript and Bash).
+	Languages []string
+
+	// MemoryMB is the memory configured on the function, reported in
+	// Capabilities (default 10240, Lambda's maximum).
+	MemoryMB int
+
+	// RateLimit is the maximum invocations per second (default 10).
+	// A negative value disables throttling. Providers invoking the same
+	// function share one limiter, and New fails if their RateLimit or
+	// RateBurst differ.
+	RateLimit float64
+
+	// RateBurst is the number of invocations allowed in a burst
+	// (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a throttled (429) invocation is
+	// retried (default 3). A negative value disables retries.
+	MaxRetries int
+
+	// Endpoint overrides the Lambda API endpoint
+	// (default https://lambda.<region>.amazonaws.com).
+	Endpoint string
+}
+
+// Provider implements the AWS Lambda provider.
+type Provider struct {
+	config  *Config
+	creds   aws.CredentialsProvider
+	signer  *v4.Signer
+	client  *http.Client
+	limiter *ratelimit.Limiter
+}
+
+// New creates a new Lambda provider.
+func New(cfg *Config) (*Provider, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	awsCfg, err := loadAWSConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Region == "" {
+		cfg.Region = awsCfg.Region
+	}
+	if cfg.Region == "" && cfg.Endpoint == "" {
+		return nil, fmt.Errorf("lambda region is required")
+	}
+
+	if cfg.FunctionName == "" {
+		cfg.FunctionName = defaultFunctionName
+	}
+	if len(cfg.Languages) == 0 {
+		cfg.Languages = defaultLanguages
+	}
+	if cfg.MemoryMB == 0 {
+		cfg.MemoryMB = 10240
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://lambda." + cfg.Region + ".amazonaws.com"
+	}
+
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = defaultRateLimit
+	}
+	if cfg.RateBurst == 0 {
+		cfg.RateBurst = defaultRateBurst
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit > 0 {
+		limiter, err = ratelimit.Shared("lambda:"+cfg.Region+":"+cfg.FunctionName, cfg.RateLimit, cfg.RateBurst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Provider{
+		config: cfg,
+		creds:  awsCfg.Credentials,
+		signer: v4.NewSigner(),
+		client: &http.Client{
+			// Leave room for a full-length invocation.
+			Timeout: maxExecutionTime + time.Minute,
+			Transport: &ratelimit.Transport{
+				Limiter:    limiter,
+				MaxRetries: cfg.MaxRetries,
+			},
+		},
+		limiter: limiter,
+	}, nil
+}
+
+// QueueDepth returns the number of invocations waiting on the rate limiter.
+func (p *Provider) QueueDepth() int {
+	if p.limiter == nil {
+		return 0
+	}
+	return p.limiter.QueueDepth()
+}
+
+// Name returns the provider identifier.
+func (p *Provider) Name() string {
+	return "lambda"
+}
+
+// Create returns a sandbox backed by the runner function. No AWS resources
+// are created; every execution is a separate invocation.
+func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	if opts == nil {
+		opts = provider.DefaultCreateOptions()
+	}
+	if err := provider.RejectRuntimeVersion("lambda", opts.Runtime); err != nil {
+		return nil, err
+	}
+	if err := provider.RejectDiskLimit("lambda", opts.Resources); err != nil {
+		return nil, err
+	}
+
+	return &Instance{
+		id:       fmt.Sprintf("lambda-%d", time.Now().UnixNano()),
+		provider: p,
+		env:      opts.Environment,
+	}, nil
+}
+
+// Capabilities returns Lambda provider capabilities.
+func (p *Provider) Capabilities() provider.Capabilities {
+	return provider.Capabilities{
+		SupportsStreaming:   false,
+		SupportsAsync:       true,
+		SupportsFileSystem:  false,
+		SupportsNetwork:     false,
+		SupportedLanguages:  p.config.Languages,
+		MaxExecutionTime:    maxExecutionTime,
+		MaxMemoryMB:         p.config.MemoryMB,
+		MaxCPUs:             6,
+		SupportsPersistence: false,
+	}
+}
+
+// Validate checks that the runner function exists and the credentials can
+// see it.
+func (p *Provider) Validate(ctx context.Context) error {
+	req, err := p.newRequest(ctx, "GET", p.functionPath(""), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("lambda API not accessible: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return provider.CheckHTTPResponse("get function", resp)
+}
+
+// Close releases provider resources.
+func (p *Provider) Close() error {
+	return nil
+}
+
+// functionPath returns the API path of the function, followed by suffix.
+func (p *Provider) functionPath(suffix string) string {
+	return "/2015-03-31/functions/" + uriEncode(p.config.FunctionName) + suffix
+}
+
+// newRequest builds a signed request for the Lambda API.
+func (p *Provider) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	u, err := url.Parse(p.config.Endpoint + path)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if p.config.Qualifier != "" {
+		u.RawQuery = "Qualifier=" + uriEncode(p.config.Qualifier)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	creds, err := p.creds.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lambda: retrieve credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := p.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), signingService, p.config.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sign request: %w", err)
+	}
+	return req, nil
+}
+
+// signingService is the service name in Lambda's signing scope.
+const signingService = "lambda"
+
+// uriEncode percent-encodes every byte except the unreserved characters
+// A-Z, a-z, 0-9, '-', '_', '.' and '~', as the Lambda API expects for
+// function names and ARNs in paths.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// runnerResult is the payload returned by the runner function.
+type runnerResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error"`
+}
+
+// invoke calls the runner function synchronously with payload.
+func (p *Provider) invoke(ctx context.Context, op string, payload any) (*runnerResult, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := p.newRequest(ctx, "POST", p.functionPath("/invocations"), body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if err := provider.CheckHTTPResponse(op, resp); err != nil {
+		return nil, err
+	}
+
+	// Errors raised by the function itself still come back as 200.
+	if kind := resp.Header.Get("X-Amz-Function-Error"); kind != "" {
+		var fnErr struct {
+			ErrorType    string `json:"errorType"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		json.NewDecoder(resp.Body).Decode(&fnErr)
+		return nil, fmt.Errorf("%s: runner function error (%s): %s: %s", op, kind, fnErr.ErrorType, fnErr.ErrorMessage)
+	}
+
+	var result runnerResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// Instance is a handle on the runner function.
+type Instance struct {
+	id       string
+	provider *Provider
+	env      map[string]string
+	mu       sync.RWMutex
+	stopped  bool
+}
+
+// ID returns the sandbox ID.
+func (i *Instance) ID() string {
+	return i.id
+}
+
+// Execute runs code in a single invocation of the runner function.
+func (i *Instance) Execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	i.mu.RLock()
+	if i.stopped {
+		i.mu.RUnlock()
+		return nil, fmt.Errorf("sandbox stopped")
+	}
+	i.mu.RUnlock()
+
+	if opts == nil {
+		opts = executor.DefaultExecutionOptions()
+	}
+	if opts.Timeout > maxExecutionTime {
+		return nil, fmt.Errorf("timeout %v exceeds lambda limit of %v", opts.Timeout, maxExecutionTime)
+	}
+
+	// Per-execution variables override sandbox defaults.
+	env := make(map[string]string, len(i.env)+len(opts.Env))
+	for k, v := range i.env {
+		env[k] = v
+	}
+	for k, v := range opts.Env {
+		env[k] = v
+	}
+
+	reqBody := map[string]any{
+		"code":     code,
+		"language": opts.Language,
+	}
+	if opts.Stdin != "" {
+		reqBody["stdin"] = opts.Stdin
+	}
+	if len(env) > 0 {
+		reqBody["env"] = env
+	}
+	if len(opts.Files) > 0 {
+		// []byte values are base64-encoded by encoding/json.
+		reqBody["files"] = opts.Files
+	}
+	if opts.Timeout > 0 {
+		reqBody["timeout_ms"] = opts.Timeout.Milliseconds()
+	}
+
+	start := time.Now()
+	result, err := i.provider.invoke(ctx, "execute", reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	execResult := &executor.ExecutionResult{
+		ExitCode: result.ExitCode,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		Duration: time.Since(start),
+		Language: opts.Language,
+	}
+	if result.Error != "" {
+		execResult.Error = fmt.Errorf("%s", result.Error)
+	}
+
+	return execResult, nil
+}
+
+// ExecuteStream runs code and replays its output as events once the
+// invocation returns; Lambda does not stream synchronous responses.
+func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
+	result, err := i.Execute(ctx, code, opts)
+	if err != nil {
+		handler(&executor.StreamEvent{
+			Type:      executor.StreamError,
+			Error:     err,
+			Timestamp: time.Now(),
+		})
+		return err
+	}
+
+	if result.Stdout != "" {
+		handler(&executor.StreamEvent{
+			Type:      executor.StreamStdout,
+			Data:      result.Stdout,
+			Timestamp: time.Now(),
+		})
+	}
+
+	if result.Stderr != "" {
+		handler(&executor.StreamEvent{
+			Type:      executor.StreamStderr,
+			Data:      result.Stderr,
+			Timestamp: time.Now(),
+		})
+	}
+
+	handler(&executor.StreamEvent{
+		Type:      executor.StreamComplete,
+		ExitCode:  result.ExitCode,
+		Language:  result.Language,
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
+
+// RunCommand executes a command in a single invocation of the runner
+// function.
+func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*executor.CommandResult, error) {
+	i.mu.RLock()
+	if i.stopped {
+		i.mu.RUnlock()
+		return nil, fmt.Errorf("sandbox stopped")
+	}
+	i.mu.RUnlock()
+
+	start := time.Now()
+	result, err := i.provider.invoke(ctx, "run command", map[string]any{
+		"command": cmd,
+		"args":    args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &executor.CommandResult{
+		ExitCode: result.ExitCode,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		Duration: time.Since(start),
+	}, nil
+}
+
+// FileSystem returns nil; invocations share no file system.
+func (i *Instance) FileSystem() fs.FileSystem {
+	return nil
+}
+
+// Network returns nil; the runner cannot serve ports.
+func (i *Instance) Network() provider.Network {
+	return nil
+}
+
+// Stop marks the sandbox stopped. There is nothing to tear down remotely.
+func (i *Instance) Stop(ctx context.Context) error {
+	i.mu.Lock()
+	i.stopped = true
+	i.mu.Unlock()
+	return nil
+}
+
+// Status returns the current status.
+func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if i.stopped {
+		return provider.StatusStopped, nil
+	}
+	return provider.StatusRunning, nil
+}
+
+var _ provider.Provider = (*Provider)(nil)
+var _ provider.Instance = (*Instance)(nil)
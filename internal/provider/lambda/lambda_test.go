@@ -0,0 +1,271 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// newTestInstance returns an instance whose invocations are answered by
+// handler.
+func newTestInstance(t *testing.T, handler http.HandlerFunc) *Instance {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	p, err := New(&Config{
+		Region:          "us-east-1",
+		FunctionName:    "runner",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        srv.URL,
+		RateLimit:       -1,
+		MaxRetries:      -1,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	inst, err := p.Create(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return inst.(*Instance)
+}
+
+func TestExecuteOK(t *testing.T) {
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2015-03-31/functions/runner/invocations" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("Authorization = %q", auth)
+		}
+
+		var payload struct {
+			Code      string            `json:"code"`
+			Language  string            `json:"language"`
+			Stdin     string            `json:"stdin"`
+			Files     map[string][]byte `json:"files"`
+			TimeoutMS int64             `json:"timeout_ms"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		if payload.Code != "print(input())" || payload.Language != "Python" || payload.Stdin != "hi" ||
+			string(payload.Files["data.txt"]) != "x" || payload.TimeoutMS != 30000 {
+			t.Errorf("payload = %+v", payload)
+		}
+		w.Write([]byte(`{"stdout":"hi\n","exit_code":0}`))
+	})
+
+	result, err := i.Execute(context.Background(), "print(input())", &executor.ExecutionOptions{
+		Language: "Python",
+		Stdin:    "hi",
+		Files:    map[string][]byte{"data.txt": []byte("x")},
+		Timeout:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "hi\n" || result.ExitCode != 0 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestExecuteFunctionError(t *testing.T) {
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amz-Function-Error", "Unhandled")
+		w.Write([]byte(`{"errorType":"Runtime.ExitError","errorMessage":"out of memory"}`))
+	})
+
+	_, err := i.Execute(context.Background(), "print(1)", &executor.ExecutionOptions{Language: "Python"})
+	if err == nil || !strings.Contains(err.Error(), "out of memory") {
+		t.Errorf("Execute() error = %v, want the function error", err)
+	}
+}
+
+func TestExecuteHTTPStatus(t *testing.T) {
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"denied"}`))
+	})
+
+	_, err := i.Execute(context.Background(), "print(1)", &executor.ExecutionOptions{Language: "Python"})
+	var httpErr *provider.HTTPProviderError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Execute() error = %v, want *HTTPProviderError", err)
+	}
+	if httpErr.Status != http.StatusForbidden || httpErr.Op != "execute" {
+		t.Errorf("error = %+v", httpErr)
+	}
+}
+
+func TestExecuteTimeoutLimit(t *testing.T) {
+	i := newTestInstance(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no invocation expected")
+	})
+
+	_, err := i.Execute(context.Background(), "print(1)", &executor.ExecutionOptions{Timeout: 20 * time.Minute})
+	if err == nil {
+		t.Error("Execute() should reject timeouts beyond 15 minutes")
+	}
+}
+
+func TestSignedRequest(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{"exit_code":0}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(&Config{
+		Region:          "eu-west-1",
+		FunctionName:    "arn:aws:lambda:eu-west-1:123:function:runner",
+		Qualifier:       "live",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+		Endpoint:        srv.URL,
+		RateLimit:       -1,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := p.invoke(context.Background(), "execute", map[string]string{}); err != nil {
+		t.Fatalf("invoke() error = %v", err)
+	}
+
+	if want := "/2015-03-31/functions/arn%3Aaws%3Alambda%3Aeu-west-1%3A123%3Afunction%3Arunner/invocations"; got.URL.EscapedPath() != want {
+		t.Errorf("path = %s, want %s", got.URL.EscapedPath(), want)
+	}
+	if got.URL.RawQuery != "Qualifier=live" {
+		t.Errorf("query = %q, want Qualifier=live", got.URL.RawQuery)
+	}
+	auth := got.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/lambda/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for eu-west-1 lambda", auth)
+	}
+	if got.Header.Get("X-Amz-Security-Token") != "token" || got.Header.Get("X-Amz-Date") == "" {
+		t.Errorf("headers = %v, want the session token and date", got.Header)
+	}
+}
+
+// isolateAWSConfig points the AWS SDK at files in a temporary directory
+// and clears the credentials in the environment.
+func isolateAWSConfig(t *testing.T, config, credentials string) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	credentialsPath := filepath.Join(dir, "credentials")
+	os.WriteFile(configPath, []byte(config), 0o600)
+	os.WriteFile(credentialsPath, []byte(credentials), 0o600)
+
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsPath)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestCredentialChain(t *testing.T) {
+	isolateAWSConfig(t, `[profile ci]
+region = ap-south-1
+`, `[default]
+aws_access_key_id = DEFAULTKEY
+aws_secret_access_key = defaultsecret
+
+[ci]
+aws_access_key_id=CIKEY
+aws_secret_access_key=cisecret
+aws_session_token=citoken
+`)
+	ctx := context.Background()
+	accessKey := func(cfg *Config) string {
+		t.Helper()
+		p, err := New(cfg)
+		if err != nil {
+			t.Fatalf("New(%+v) error = %v", cfg, err)
+		}
+		creds, err := p.creds.Retrieve(ctx)
+		if err != nil {
+			t.Fatalf("Retrieve() error = %v", err)
+		}
+		return creds.AccessKeyID
+	}
+
+	if got := accessKey(&Config{Region: "us-east-1"}); got != "DEFAULTKEY" {
+		t.Errorf("default profile key = %s, want DEFAULTKEY", got)
+	}
+
+	p, err := New(&Config{Profile: "ci"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if p.config.Region != "ap-south-1" {
+		t.Errorf("Region = %q, want the profile's ap-south-1", p.config.Region)
+	}
+
+	if _, err := New(&Config{Region: "us-east-1", Profile: "missing"}); err == nil {
+		t.Error("New() with a missing profile should fail")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+	if got := accessKey(&Config{Region: "us-east-1"}); got != "ENVKEY" {
+		t.Errorf("environment key = %s, want ENVKEY", got)
+	}
+	if got := accessKey(&Config{Region: "us-east-1", AccessKeyID: "CFGKEY", SecretAccessKey: "cfgsecret"}); got != "CFGKEY" {
+		t.Errorf("config key = %s, want CFGKEY", got)
+	}
+	if _, err := New(&Config{Region: "us-east-1", AccessKeyID: "CFGKEY"}); err == nil {
+		t.Error("New() with only an access key ID should fail")
+	}
+}
+
+// TestCredentialRefresh serves expired credentials from a
+// credential_process, so every invocation must fetch and sign with fresh
+// ones.
+func TestCredentialRefresh(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "creds.sh")
+	os.WriteFile(script, []byte(`#!/bin/sh
+n=$(($(cat "$0.count" 2>/dev/null || echo 0) + 1))
+echo $n > "$0.count"
+printf '{"Version":1,"AccessKeyId":"KEY%d","SecretAccessKey":"s","SessionToken":"t","Expiration":"2000-01-01T00:00:00Z"}' $n
+`), 0o755)
+	isolateAWSConfig(t, "[profile rotating]\ncredential_process = "+script+"\n", "")
+
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=")
+		key, _, _ := strings.Cut(auth, "/")
+		keys = append(keys, key)
+		w.Write([]byte(`{"exit_code":0}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(&Config{Region: "us-east-1", Profile: "rotating", Endpoint: srv.URL, RateLimit: -1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for range 2 {
+		if _, err := p.invoke(context.Background(), "execute", map[string]string{}); err != nil {
+			t.Fatalf("invoke() error = %v", err)
+		}
+	}
+	if strings.Join(keys, ",") != "KEY1,KEY2" {
+		t.Errorf("signing keys = %v, want KEY1,KEY2", keys)
+	}
+}
@@ -0,0 +1,167 @@
+//go:build linux
+
+package nsjail
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupMount is where the cgroup hierarchies are mounted.
+var cgroupMount = "/sys/fs/cgroup"
+
+// cpuAccount measures the CPU time of one run with a cgroup. nsjail puts
+// the jailed process in a cgroup of its own, which it removes on exit, so
+// the run gets a parent cgroup created here: its counters keep the usage
+// of the removed child. Under cgroup v1 nsjail only joins the cpu
+// controller when it applies a CPU quota, so without one nothing is
+// measured there. Under cgroup v2 the parent also carries the sandbox's
+// cpuset, which the child cannot widen.
+type cpuAccount struct {
+	// dir is the parent cgroup.
+	dir string
+
+	// v2 reports a unified (cgroup v2) hierarchy.
+	v2 bool
+
+	// args point nsjail at the parent.
+	args []string
+}
+
+// newCPUAccount creates the parent cgroup for a run named name. Without a
+// cpuset it returns nil when the host has no usable cgroup hierarchy or it
+// may not be written, as when sindoq does not run as root; the run then
+// falls back to the rusage of the nsjail process. A cpuset must be
+// enforced, so then any failure is returned instead.
+func newCPUAccount(name string, quota bool, cpuset string) (*cpuAccount, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMount, "cgroup.controllers")); err == nil {
+		dir := filepath.Join(cgroupMount, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			if cpuset != "" {
+				return nil, fmt.Errorf("pin to cpuset %q: %w", cpuset, err)
+			}
+			return nil, nil
+		}
+		a := &cpuAccount{dir: dir, v2: true, args: []string{"--use_cgroupv2", "--cgroupv2_mount", dir}}
+		if cpuset != "" {
+			if err := a.pin(cpuset); err != nil {
+				a.remove()
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	if cpuset != "" {
+		return nil, fmt.Errorf("pin to cpuset %q: %w", cpuset, errNoCPUSetController)
+	}
+
+	if !quota {
+		return nil, nil
+	}
+	mount := filepath.Join(cgroupMount, "cpu")
+	if _, err := os.Stat(filepath.Join(mount, "cpuacct.usage")); err != nil {
+		return nil, nil
+	}
+	dir := filepath.Join(mount, name)
+	if os.Mkdir(dir, 0755) != nil {
+		return nil, nil
+	}
+	return &cpuAccount{dir: dir, args: []string{"--cgroup_cpu_mount", mount, "--cgroup_cpu_parent", name}}, nil
+}
+
+// errNoCPUSetController reports a host where cpusets cannot be applied.
+var errNoCPUSetController = errors.New("cgroup v2 cpuset controller not available")
+
+// checkCPUSetController reports whether runs can be pinned to a cpuset:
+// the host must have a cgroup v2 hierarchy offering the cpuset
+// controller.
+func checkCPUSetController() error {
+	data, err := os.ReadFile(filepath.Join(cgroupMount, "cgroup.controllers"))
+	if err != nil || !slices.Contains(strings.Fields(string(data)), "cpuset") {
+		return errNoCPUSetController
+	}
+	return nil
+}
+
+// pin confines the parent cgroup, and so the run, to cpuset. The cpuset
+// controller is enabled for the children of the hierarchy root first,
+// which is a no-op where it already is.
+func (a *cpuAccount) pin(cpuset string) error {
+	control := filepath.Join(filepath.Dir(a.dir), "cgroup.subtree_control")
+	if err := os.WriteFile(control, []byte("+cpuset"), 0644); err != nil {
+		return fmt.Errorf("enable cpuset controller: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, "cpuset.cpus"), []byte(cpuset), 0644); err != nil {
+		return fmt.Errorf("pin to cpuset %q: %w", cpuset, err)
+	}
+	return nil
+}
+
+// usage returns the CPU time charged to the parent cgroup.
+func (a *cpuAccount) usage() (time.Duration, error) {
+	if a.v2 {
+		return readCPUStat(filepath.Join(a.dir, "cpu.stat"))
+	}
+	return readCPUAcctUsage(filepath.Join(a.dir, "cpuacct.usage"))
+}
+
+// remove deletes the parent cgroup. nsjail has removed its child by then.
+func (a *cpuAccount) remove() {
+	os.Remove(a.dir)
+}
+
+// readCPUStat reads usage_usec from a cgroup v2 cpu.stat file.
+func readCPUStat(name string) (time.Duration, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || key != "usage_usec" {
+			continue
+		}
+		usec, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse %s: %w", name, err)
+		}
+		return time.Duration(usec) * time.Microsecond, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("read %s: %w", name, err)
+	}
+	return 0, fmt.Errorf("%s has no usage_usec", name)
+}
+
+// readCPUAcctUsage reads a cgroup v1 cpuacct.usage file, in nanoseconds.
+func readCPUAcctUsage(name string) (time.Duration, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return 0, err
+	}
+	nsec, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return time.Duration(nsec), nil
+}
+
+// processCPUTime returns the user and system time of a finished process,
+// which includes the descendants it waited for: nsjail waits for the
+// jailed process, so this covers the run, nsjail's own setup included.
+func processCPUTime(state *os.ProcessState) time.Duration {
+	if state == nil {
+		return 0
+	}
+	return state.UserTime() + state.SystemTime()
+}
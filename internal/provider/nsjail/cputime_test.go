@@ -0,0 +1,144 @@
+//go:build linux
+
+package nsjail
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestCPUAccountV2(t *testing.T) {
+	mount := t.TempDir()
+	cgroupMount = mount
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpu memory pids\n"), 0644)
+
+	a, _ := newCPUAccount("run", false, "")
+	if a == nil || !a.v2 {
+		t.Fatalf("newCPUAccount() = %+v, want a v2 account", a)
+	}
+	if want := []string{"--use_cgroupv2", "--cgroupv2_mount", filepath.Join(mount, "run")}; !slices.Equal(a.args, want) {
+		t.Errorf("args = %q, want %q", a.args, want)
+	}
+	os.WriteFile(filepath.Join(a.dir, "cpu.stat"), []byte("usage_usec 1500000\nuser_usec 1200000\nsystem_usec 300000\n"), 0644)
+	if got, err := a.usage(); err != nil || got != 1500*time.Millisecond {
+		t.Errorf("usage() = %v, %v, want 1.5s", got, err)
+	}
+}
+
+func TestCPUAccountV1(t *testing.T) {
+	mount := t.TempDir()
+	cgroupMount = mount
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+	os.MkdirAll(filepath.Join(mount, "cpu"), 0755)
+	os.WriteFile(filepath.Join(mount, "cpu", "cpuacct.usage"), []byte("0\n"), 0644)
+
+	// nsjail only joins the cpu controller to apply a quota.
+	if a, _ := newCPUAccount("run", false, ""); a != nil {
+		t.Errorf("newCPUAccount() without a quota = %+v, want nil", a)
+	}
+
+	a, _ := newCPUAccount("run", true, "")
+	if a == nil || a.v2 {
+		t.Fatalf("newCPUAccount() = %+v, want a v1 account", a)
+	}
+	if want := []string{"--cgroup_cpu_mount", filepath.Join(mount, "cpu"), "--cgroup_cpu_parent", "run"}; !slices.Equal(a.args, want) {
+		t.Errorf("args = %q, want %q", a.args, want)
+	}
+	os.WriteFile(filepath.Join(a.dir, "cpuacct.usage"), []byte("250000000\n"), 0644)
+	if got, err := a.usage(); err != nil || got != 250*time.Millisecond {
+		t.Errorf("usage() = %v, %v, want 250ms", got, err)
+	}
+}
+
+func TestCPUAccountNoCgroups(t *testing.T) {
+	cgroupMount = t.TempDir()
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+	if a, _ := newCPUAccount("run", true, ""); a != nil {
+		t.Errorf("newCPUAccount() = %+v, want nil", a)
+	}
+}
+
+func TestCPUAccountCPUSet(t *testing.T) {
+	mount := t.TempDir()
+	cgroupMount = mount
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+
+	// A cpuset must be enforced, so a host without cgroup v2 fails.
+	if _, err := newCPUAccount("run", false, "0-1"); err == nil {
+		t.Error("newCPUAccount() without cgroup v2 should fail to pin")
+	}
+
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpuset cpu memory pids\n"), 0644)
+	a, err := newCPUAccount("run", false, "0-1")
+	if err != nil {
+		t.Fatalf("newCPUAccount() error = %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(mount, "cgroup.subtree_control")); string(data) != "+cpuset" {
+		t.Errorf("subtree_control = %q, want +cpuset", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(a.dir, "cpuset.cpus")); string(data) != "0-1" {
+		t.Errorf("cpuset.cpus = %q, want 0-1", data)
+	}
+}
+
+func TestCheckCPUSetController(t *testing.T) {
+	mount := t.TempDir()
+	cgroupMount = mount
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpu memory pids\n"), 0644)
+	if err := checkCPUSetController(); err == nil {
+		t.Error("checkCPUSetController() without cpuset = nil, want an error")
+	}
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpuset cpu memory pids\n"), 0644)
+	if err := checkCPUSetController(); err != nil {
+		t.Errorf("checkCPUSetController() error = %v", err)
+	}
+}
+
+func TestReadCPUStatMissingUsage(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cpu.stat")
+	os.WriteFile(name, []byte("user_usec 1\n"), 0644)
+	if _, err := readCPUStat(name); err == nil {
+		t.Error("readCPUStat() error = nil, want missing usage_usec")
+	}
+}
+
+// TestExecuteCPUTime runs a CPU-bound loop and checks that CPUTime is
+// measured apart from Duration: close to it on an idle machine, never
+// more than the time the loop had.
+func TestExecuteCPUTime(t *testing.T) {
+	if _, err := exec.LookPath("nsjail"); err != nil {
+		t.Skip("nsjail not installed")
+	}
+	p, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+	inst, err := p.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer inst.Stop(ctx)
+
+	code := "import time\nend = time.time() + 1\nwhile time.time() < end:\n    pass\n"
+	result, err := inst.Execute(ctx, code, &executor.ExecutionOptions{Language: "Python"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d, stderr %q", result.ExitCode, result.Stderr)
+	}
+	if result.CPUTime < result.Duration/2 || result.CPUTime > result.Duration+100*time.Millisecond {
+		t.Errorf("CPUTime = %v, want close to Duration %v", result.CPUTime, result.Duration)
+	}
+}
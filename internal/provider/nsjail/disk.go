@@ -0,0 +1,70 @@
+//go:build linux
+
+package nsjail
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
+
+// diskQuotaOptions enforces the sandbox's disk limit for one run. nsjail
+// has no storage quota, so the run gets an fsize rlimit no larger than the
+// space left, which stops any single write from crossing the limit. It
+// fails once the limit is used up.
+func (i *Instance) diskQuotaOptions(opts *executor.ExecutionOptions) (*executor.ExecutionOptions, error) {
+	if i.diskMB <= 0 {
+		return opts, nil
+	}
+
+	used, err := dirSize(i.sandboxDir)
+	if err != nil {
+		return nil, fmt.Errorf("measure disk usage: %w", err)
+	}
+	remaining := int64(i.diskMB)<<20 - used
+	if remaining <= 0 {
+		return nil, fmt.Errorf("disk limit of %dMB reached", i.diskMB)
+	}
+
+	limited := *opts
+	limited.Ulimits = maps.Clone(opts.Ulimits)
+	if limited.Ulimits == nil {
+		limited.Ulimits = make(map[string]executor.Ulimit)
+	}
+	if u, ok := limited.Ulimits["fsize"]; !ok || u.Soft < 0 || u.Soft > remaining {
+		limited.Ulimits["fsize"] = executor.Ulimit{Soft: remaining, Hard: remaining}
+	}
+	return &limited, nil
+}
+
+// diskUsedMB returns the sandbox's disk usage in megabytes, rounded up.
+func (i *Instance) diskUsedMB() (int64, error) {
+	used, err := dirSize(i.sandboxDir)
+	if err != nil {
+		return 0, fmt.Errorf("measure disk usage: %w", err)
+	}
+	return (used + 1<<20 - 1) >> 20, nil
+}
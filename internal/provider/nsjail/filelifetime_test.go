@@ -0,0 +1,46 @@
+//go:build linux
+
+package nsjail_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	_ "github.com/happyhackingspace/sindoq/internal/provider/nsjail"
+)
+
+// TestFileLifetimePolicy checks that nsjail keeps or cleans the working
+// directory as the root package's mock-backed test of the same name
+// expects.
+func TestFileLifetimePolicy(t *testing.T) {
+	for _, policy := range []sindoq.FileLifetimePolicy{sindoq.PersistAcrossExecutions, sindoq.CleanPerExecution} {
+		t.Run(policy.String(), func(t *testing.T) {
+			ctx := context.Background()
+			sb, err := sindoq.Create(ctx, sindoq.WithProvider("nsjail"), sindoq.WithRuntime("Python"), sindoq.WithFileLifetimePolicy(policy))
+			if err != nil {
+				t.Skipf("nsjail not available: %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			files := map[string]bool{
+				"/workspace/left.txt":           policy == sindoq.PersistAcrossExecutions,
+				"/workspace/data/input.csv":     policy == sindoq.PersistAcrossExecutions,
+				"/workspace/.sindoq/out.stdout": true,
+			}
+			for file := range files {
+				if err := sb.Files().Write(ctx, file, []byte("x")); err != nil {
+					t.Fatalf("Write(%s) error = %v", file, err)
+				}
+			}
+			// The policy applies whether or not the code ran.
+			sb.Execute(ctx, "print(1)", sindoq.WithLanguage("Python"))
+
+			for file, want := range files {
+				if got, err := sb.Files().Exists(ctx, file); err != nil || got != want {
+					t.Errorf("%s exists = %v (error %v), want %v", file, got, err, want)
+				}
+			}
+		})
+	}
+}
@@ -125,6 +125,26 @@ func (f *nsjailFS) Download(ctx context.Context, remotePath string, writer io.Wr
 	return err
 }
 
+// DownloadRange copies a byte range of a file from the workspace.
+func (f *nsjailFS) DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error {
+	file, err := os.Open(f.resolvePath(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return fs.WriteRange(file, offset, length, writer)
+}
+
+// ExportTar archives a directory of the workspace.
+func (f *nsjailFS) ExportTar(ctx context.Context, dir string, w io.Writer) error {
+	return fs.WriteTar(ctx, f.resolvePath(dir), w)
+}
+
+// ImportTar extracts an archive into a directory of the workspace.
+func (f *nsjailFS) ImportTar(ctx context.Context, dir string, r io.Reader) error {
+	return fs.ExtractTar(ctx, f.resolvePath(dir), r)
+}
+
 // MkDir creates a directory.
 func (f *nsjailFS) MkDir(ctx context.Context, path string) error {
 	fullPath := f.resolvePath(path)
@@ -212,4 +232,7 @@ func (f *nsjailFS) Watch(ctx context.Context, path string, events chan<- *fs.Wat
 	return fmt.Errorf("watch not implemented for nsjail provider")
 }
 
-var _ fs.FileSystem = (*nsjailFS)(nil)
+var (
+	_ fs.FileSystem = (*nsjailFS)(nil)
+	_ fs.Archiver   = (*nsjailFS)(nil)
+)
@@ -9,15 +9,21 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 	"github.com/happyhackingspace/sindoq/pkg/langdetect"
@@ -54,6 +60,12 @@ type Config struct {
 	// MaxCPUs limits CPU cores.
 	MaxCPUs uint32
 
+	// CPUSet pins every sandbox to these host cores, such as "0-3". A
+	// sandbox's ResourceConfig.CPUSet narrows it further. Each run gets a
+	// cgroup v2 cpuset, which needs a writable hierarchy with the cpuset
+	// controller, usually meaning root.
+	CPUSet string
+
 	// MaxPids limits the number of processes.
 	MaxPids uint32
 
@@ -111,6 +123,9 @@ type Provider struct {
 	config    *Config
 	instances map[string]*Instance
 	mu        sync.RWMutex
+
+	// inflight tracks executions so CloseGraceful can wait for them.
+	inflight provider.InFlight
 }
 
 // New creates a new nsjail provider.
@@ -132,9 +147,37 @@ func (p *Provider) Name() string {
 
 // Create initializes a new nsjail sandbox instance.
 func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	ctx, done, err := p.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	if opts == nil {
 		opts = provider.DefaultCreateOptions()
 	}
+	if err := provider.RejectRuntimeVersion("nsjail", opts.Runtime); err != nil {
+		return nil, err
+	}
+	if _, err := rlimitArgs(opts.Ulimits); err != nil {
+		return nil, err
+	}
+	var seccomp string
+	if opts.Syscalls != nil {
+		if err := opts.Syscalls.Validate(); err != nil {
+			return nil, err
+		}
+		seccomp = kafelPolicy(opts.Syscalls)
+	}
+	cpuset, err := provider.ResolveCPUSet(p.config.CPUSet, opts.Resources.CPUSet)
+	if err != nil {
+		return nil, err
+	}
+	if cpuset != "" {
+		if err := checkCPUSetController(); err != nil {
+			return nil, fmt.Errorf("pin to cpuset %q: %w", cpuset, err)
+		}
+	}
 
 	id := fmt.Sprintf("nsjail-%d", time.Now().UnixNano())
 
@@ -159,6 +202,11 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		config:     p.config,
 		timeout:    opts.Timeout,
 		env:        opts.Environment,
+		ulimits:    opts.Ulimits,
+		diskMB:     opts.Resources.DiskMB,
+		cpuMode:    opts.Resources.CPUMode,
+		seccomp:    seccomp,
+		cpuset:     cpuset,
 	}
 
 	p.mu.Lock()
@@ -171,14 +219,26 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 // Capabilities returns nsjail provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
-		SupportsStreaming:  true,
-		SupportsAsync:      true,
-		SupportsFileSystem: true,
-		SupportsNetwork:    p.config.EnableNetwork,
-		SupportedLanguages: langdetect.SupportedLanguages(),
-		MaxExecutionTime:   time.Duration(p.config.TimeLimit) * time.Second,
-		MaxMemoryMB:        int(p.config.MaxMemoryMB),
-		MaxCPUs:            int(p.config.MaxCPUs),
+		SupportsStreaming:            true,
+		SupportsAsync:                true,
+		SupportsFileSystem:           true,
+		SupportsNetwork:              p.config.EnableNetwork,
+		SupportsRangeDownload:        true,
+		SupportsArchive:              true,
+		SupportsEphemeralWorkDir:     true,
+		SupportsCommandWrapper:       true,
+		SupportsInterpreterPath:      true,
+		SupportsPathGrants:           true,
+		SupportsProgramArgs:          true,
+		SupportsInteractiveStdin:     true,
+		SupportsSyscallFilter:        true,
+		SupportsCPUSet:               true,
+		SupportsExecutionMemoryLimit: true,
+		SupportsSecretFiles:          true,
+		SupportedLanguages:           langdetect.SupportedLanguages(),
+		MaxExecutionTime:             time.Duration(p.config.TimeLimit) * time.Second,
+		MaxMemoryMB:                  int(p.config.MaxMemoryMB),
+		MaxCPUs:                      int(p.config.MaxCPUs),
 	}
 }
 
@@ -199,17 +259,21 @@ func (p *Provider) Validate(ctx context.Context) error {
 
 // Close releases provider resources.
 func (p *Provider) Close() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	for _, instance := range p.instances {
+	for _, instance := range provider.SnapshotInstances(&p.mu, p.instances) {
 		instance.Stop(context.Background())
 	}
 
 	return nil
 }
 
+// CloseGraceful refuses new sandboxes and executions, waits for running
+// ones, then closes the provider.
+func (p *Provider) CloseGraceful(ctx context.Context) error {
+	return p.inflight.CloseGraceful(ctx, p.Close)
+}
+
 var _ provider.Provider = (*Provider)(nil)
+var _ provider.GracefulCloser = (*Provider)(nil)
 
 // Instance represents an nsjail sandbox instance.
 type Instance struct {
@@ -220,6 +284,12 @@ type Instance struct {
 	config     *Config
 	timeout    time.Duration
 	env        map[string]string
+	ulimits    map[string]executor.Ulimit
+	diskMB     int
+	cpuMode    provider.CPUMode
+	seccomp    string
+	cpuset     string
+	secretRefs executor.SecretFileRefs
 	mu         sync.RWMutex
 	stopped    bool
 }
@@ -231,6 +301,12 @@ func (i *Instance) ID() string {
 
 // Execute runs code in the nsjail sandbox.
 func (i *Instance) Execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -246,17 +322,33 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	if !ok {
 		return nil, fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+	if _, err := rlimitArgs(opts.Ulimits); err != nil {
+		return nil, err
+	}
+	if err := checkCommandWrapper(opts.CommandWrapper); err != nil {
+		return nil, err
+	}
+	if err := checkPathGrants(opts); err != nil {
+		return nil, err
+	}
+
+	hostDir, jailDir, removeRunDir, err := i.runDir(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeRunDir()
 
 	// Write code to file
 	codeFilename := "main" + runtimeInfo.FileExt
-	codePath := filepath.Join(i.workDir, codeFilename)
+	codePath := filepath.Join(hostDir, codeFilename)
 	if err := os.WriteFile(codePath, []byte(code), 0644); err != nil {
 		return nil, fmt.Errorf("write code file: %w", err)
 	}
 
 	// Write additional files
 	for path, content := range opts.Files {
-		fullPath := filepath.Join(i.workDir, path)
+		fullPath := filepath.Join(hostDir, path)
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 			return nil, fmt.Errorf("create dir for %s: %w", path, err)
 		}
@@ -265,13 +357,29 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		}
 	}
 
+	removeSecrets, err := executor.WriteSecretFiles(&i.secretRefs, i.secretsDir(), opts.SecretFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer removeSecrets()
+
+	if opts, err = i.diskQuotaOptions(opts); err != nil {
+		return nil, err
+	}
+
 	// Build nsjail command
-	sandboxCodePath := "/workspace/" + codeFilename
+	sandboxCodePath := jailDir + "/" + codeFilename
 	var runCmd []string
 	if runtimeInfo.CompileCmd != nil {
 		// For compiled languages, compile first then run
-		compileCmd := i.buildNsjailCmd(append(runtimeInfo.CompileCmd, sandboxCodePath), opts)
-		compileExec := exec.CommandContext(ctx, compileCmd[0], compileCmd[1:]...)
+		compileCmd := i.buildNsjailCmd(append(runtimeInfo.CompileCmd, sandboxCodePath), jailDir, opts)
+		compileExec, account, err := i.command(ctx, compileCmd)
+		if err != nil {
+			return nil, err
+		}
+		if account != nil {
+			defer account.remove()
+		}
 		if output, err := compileExec.CombinedOutput(); err != nil {
 			return &executor.ExecutionResult{
 				ExitCode: 1,
@@ -279,9 +387,18 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 				Language: opts.Language,
 			}, nil
 		}
-		runCmd = i.buildNsjailCmd(runtimeInfo.RunCommand, opts)
+		runCmd = i.buildNsjailCmd(provider.WrapCommand(slices.Concat(runtimeInfo.RunCommand, opts.Args), opts.CommandWrapper), jailDir, opts)
 	} else {
-		runCmd = i.buildNsjailCmd(append(runtimeInfo.RunCommand, sandboxCodePath), opts)
+		runCmd = i.buildNsjailCmd(provider.WrapCommand(slices.Concat(provider.RunCommand(runtimeInfo, i.config.EnableNetwork, jailDir), []string{sandboxCodePath}, opts.Args), opts.CommandWrapper), jailDir, opts)
+	}
+
+	var before executor.FileSnapshot
+	if opts.TrackFileChanges {
+		snap, _, err := executor.SnapshotDir(hostDir, jailDir, executor.DefaultMaxTrackedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot workdir: %w", err)
+		}
+		before = snap
 	}
 
 	// Set timeout
@@ -292,24 +409,34 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		defer cancel()
 	}
 
-	start := time.Now()
+	// Measure CPU time with a cgroup where the host allows it
+	cmd, account, err := i.command(execCtx, runCmd)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		defer account.remove()
+	}
 
-	// Execute
-	cmd := exec.CommandContext(execCtx, runCmd[0], runCmd[1:]...)
+	start := time.Now()
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdoutW := executor.NewLimitedWriter(&stdout, opts.StdoutLimit)
+	stderrW := executor.NewLimitedWriter(&stderr, opts.StderrLimit)
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
 
 	if opts.Stdin != "" {
 		cmd.Stdin = strings.NewReader(opts.Stdin)
 	}
 
-	err := cmd.Run()
+	err = cmd.Run()
 	exitCode := 0
+	var signal syscall.Signal
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
+			signal = executor.ProcessSignal(exitErr.ProcessState)
 		} else if execCtx.Err() == context.DeadlineExceeded {
 			return nil, fmt.Errorf("execution timeout")
 		} else {
@@ -317,17 +444,134 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		}
 	}
 
-	return &executor.ExecutionResult{
-		ExitCode: exitCode,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		Duration: time.Since(start),
-		Language: opts.Language,
-	}, nil
+	result := &executor.ExecutionResult{
+		ExitCode:        exitCode,
+		Signal:          signal,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdoutW.Truncated(),
+		StderrTruncated: stderrW.Truncated(),
+		Duration:        time.Since(start),
+		CPUTime:         processCPUTime(cmd.ProcessState),
+		Language:        opts.Language,
+	}
+	if account != nil {
+		if cpu, err := account.usage(); err == nil {
+			result.CPUTime = cpu
+		}
+	}
+
+	if opts.TrackFileChanges {
+		after, truncated, err := executor.SnapshotDir(hostDir, jailDir, executor.DefaultMaxTrackedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot workdir: %w", err)
+		}
+		changes, dropped := executor.DiffSnapshots(before, after, executor.DefaultMaxTrackedFiles)
+		result.SetFileChanges(changes, truncated || dropped)
+	}
+
+	if i.diskMB > 0 {
+		if result.DiskUsedMB, err = i.diskUsedMB(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// nsjailRlimits maps ulimit names to nsjail flags and the number of
+// setrlimit units in one unit of the flag.
+var nsjailRlimits = map[string]struct {
+	flag string
+	unit int64
+}{
+	"as":       {"--rlimit_as", 1 << 20},
+	"core":     {"--rlimit_core", 1 << 20},
+	"cpu":      {"--rlimit_cpu", 1},
+	"fsize":    {"--rlimit_fsize", 1 << 20},
+	"nofile":   {"--rlimit_nofile", 1},
+	"nproc":    {"--rlimit_nproc", 1},
+	"stack":    {"--rlimit_stack", 1 << 20},
+	"memlock":  {"--rlimit_memlock", 1 << 10},
+	"rtprio":   {"--rlimit_rtprio", 1},
+	"msgqueue": {"--rlimit_msgqueue", 1},
 }
 
-// buildNsjailCmd builds the nsjail command with all options.
-func (i *Instance) buildNsjailCmd(innerCmd []string, opts *executor.ExecutionOptions) []string {
+// rlimitArgs converts ulimits to nsjail flags, sorted by name. nsjail sets
+// the soft and hard limit to the same value, so the soft limit is used;
+// sizes are rounded up to the flag's unit.
+func rlimitArgs(limits map[string]executor.Ulimit) ([]string, error) {
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		if _, ok := nsjailRlimits[name]; !ok {
+			return nil, fmt.Errorf("unsupported ulimit for nsjail: %s", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		r := nsjailRlimits[name]
+		value := "inf"
+		if soft := limits[name].Soft; soft >= 0 {
+			value = strconv.FormatInt((soft+r.unit-1)/r.unit, 10)
+		}
+		args = append(args, r.flag, value)
+	}
+	return args, nil
+}
+
+// secretsDir is the host directory bound to executor.SecretsDir. It sits
+// outside the workspace so secret files never show up as file changes.
+func (i *Instance) secretsDir() string {
+	return filepath.Join(i.sandboxDir, "secrets")
+}
+
+// runDir returns the host directory a run writes its code to and the
+// matching working directory inside the jail. With opts.EphemeralWorkDir it
+// creates a fresh subdirectory of the workspace, removed by the returned func.
+func (i *Instance) runDir(opts *executor.ExecutionOptions) (hostDir, jailDir string, remove func(), err error) {
+	if !opts.EphemeralWorkDir {
+		return i.workDir, "/workspace", func() {}, nil
+	}
+	dir, err := os.MkdirTemp(i.workDir, ".run-")
+	if err != nil {
+		return "", "", nil, fmt.Errorf("create ephemeral workdir: %w", err)
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", "", nil, fmt.Errorf("create ephemeral workdir: %w", err)
+	}
+	return dir, "/workspace/" + filepath.Base(dir), func() { os.RemoveAll(dir) }, nil
+}
+
+// command prepares args, a command from buildNsjailCmd, to run in a
+// cgroup of its own, which measures its CPU time and confines it to the
+// sandbox's cpuset. The account is nil when the host offers no cgroup;
+// the caller removes it once the command has exited.
+func (i *Instance) command(ctx context.Context, args []string) (*exec.Cmd, *cpuAccount, error) {
+	account, err := newCPUAccount(fmt.Sprintf("sindoq-%s-%d", i.id, time.Now().UnixNano()),
+		i.config.MaxCPUs > 0 && i.cpuMode != provider.CPUModeShares, i.cpuset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account != nil {
+		args = slices.Insert(slices.Clone(args), 1, account.args...)
+	}
+	return exec.CommandContext(ctx, args[0], args[1:]...), account, nil
+}
+
+// buildNsjailCmd builds the nsjail command with all options, running
+// innerCmd in workDir inside the jail.
+func (i *Instance) buildNsjailCmd(innerCmd []string, workDir string, opts *executor.ExecutionOptions) []string {
+	// A run may lower the cgroup's memory limit, but not the address
+	// space limit, which runtimes reserving large virtual ranges need.
+	memoryMB := i.config.MaxMemoryMB
+	if opts.MemoryMB > 0 && uint32(opts.MemoryMB) < memoryMB {
+		memoryMB = uint32(opts.MemoryMB)
+	}
+
 	args := []string{
 		i.config.NsjailPath,
 		"--mode", "o", // once mode
@@ -341,11 +585,19 @@ func (i *Instance) buildNsjailCmd(innerCmd []string, opts *executor.ExecutionOpt
 		"--rlimit_nofile", "64",
 		"--rlimit_nproc", fmt.Sprintf("%d", i.config.MaxPids),
 		"--cgroup_pids_max", fmt.Sprintf("%d", i.config.MaxPids),
-		"--cgroup_mem_max", fmt.Sprintf("%d", i.config.MaxMemoryMB*1024*1024),
+		"--cgroup_mem_max", fmt.Sprintf("%d", memoryMB*1024*1024),
 	}
 
-	// CPU limit
-	if i.config.MaxCPUs > 0 {
+	// Ulimits override the limits above; per-execution ones come last.
+	// Both were validated before the command was built.
+	sandboxLimits, _ := rlimitArgs(i.ulimits)
+	execLimits, _ := rlimitArgs(opts.Ulimits)
+	args = append(args, sandboxLimits...)
+	args = append(args, execLimits...)
+
+	// CPU limit. nsjail has no cgroup CPU weight, so under CPUModeShares
+	// the quota is dropped and the sandbox competes like any process.
+	if i.config.MaxCPUs > 0 && i.cpuMode != provider.CPUModeShares {
 		args = append(args, "--cgroup_cpu_ms_per_sec", fmt.Sprintf("%d", i.config.MaxCPUs*1000))
 	}
 
@@ -354,6 +606,11 @@ func (i *Instance) buildNsjailCmd(innerCmd []string, opts *executor.ExecutionOpt
 		args = append(args, "--disable_clone_newnet")
 	}
 
+	// Syscall filter. nsjail always sets no_new_privs.
+	if i.seccomp != "" {
+		args = append(args, "--seccomp_string", i.seccomp)
+	}
+
 	// Mount proc
 	if i.config.MountProc {
 		args = append(args, "--mount", "proc:/proc:proc")
@@ -378,35 +635,104 @@ func (i *Instance) buildNsjailCmd(innerCmd []string, opts *executor.ExecutionOpt
 		}
 	}
 
+	// Paths granted to this run only, checked before the command was
+	// built
+	for _, path := range opts.AllowReadPaths {
+		args = append(args, "--bindmount_ro", path)
+	}
+	for _, path := range opts.AllowWritePaths {
+		args = append(args, "--bindmount", path)
+	}
+
 	// Mount workspace
 	args = append(args, "--bindmount", fmt.Sprintf("%s:/workspace", i.workDir))
 
+	// Mount secret files read-only
+	if len(opts.SecretFiles) > 0 {
+		args = append(args, "--bindmount_ro", fmt.Sprintf("%s:%s", i.secretsDir(), executor.SecretsDir))
+	}
+
 	// Working directory
-	args = append(args, "--cwd", "/workspace")
+	args = append(args, "--cwd", workDir)
 
 	// Environment variables
-	for k, v := range opts.Env {
+	for k, v := range i.env {
 		args = append(args, "--env", fmt.Sprintf("%s=%s", k, v))
 	}
-	for k, v := range i.env {
+	// Per-execution variables come last so they override sandbox defaults
+	for k, v := range opts.Env {
 		args = append(args, "--env", fmt.Sprintf("%s=%s", k, v))
 	}
 
 	// Add PATH
-	args = append(args, "--env", "PATH=/usr/local/bin:/usr/bin:/bin")
+	args = append(args, "--env", "PATH="+jailPath)
 
 	// Quiet mode (less nsjail output)
 	args = append(args, "--really_quiet")
 
 	// Add the command separator and inner command
 	args = append(args, "--")
-	args = append(args, innerCmd...)
+	args = append(args, provider.WrapUmask(innerCmd, opts.Umask)...)
 
 	return args
 }
 
+// jailPath is the PATH of processes in the jail.
+const jailPath = "/usr/local/bin:/usr/bin:/bin"
+
+// checkCommandWrapper reports whether the binary of wrapper exists, looking
+// a bare name up in jailPath, so a missing profiler or tracer fails clearly.
+// Host paths are checked, as the jail mounts them.
+func checkCommandWrapper(wrapper []string) error {
+	if len(wrapper) == 0 {
+		return nil
+	}
+	bin := wrapper[0]
+	candidates := []string{bin}
+	if !strings.Contains(bin, "/") {
+		candidates = candidates[:0]
+		for _, dir := range filepath.SplitList(jailPath) {
+			candidates = append(candidates, filepath.Join(dir, bin))
+		}
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return nil
+		}
+	}
+	return fmt.Errorf("command wrapper %s not found", bin)
+}
+
+// checkPathGrants checks that the paths granted to a run are safe to
+// mount and exist on the host.
+func checkPathGrants(opts *executor.ExecutionOptions) error {
+	for _, grant := range []struct {
+		kind  string
+		paths []string
+	}{
+		{"read", opts.AllowReadPaths},
+		{"write", opts.AllowWritePaths},
+	} {
+		for _, path := range grant.paths {
+			if err := provider.ValidatePathGrant(path); err != nil {
+				return fmt.Errorf("allow %s: %w", grant.kind, err)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("allow %s: %w", grant.kind, err)
+			}
+		}
+	}
+	return nil
+}
+
 // ExecuteStream runs code with streaming output.
 func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -422,53 +748,130 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	if !ok {
 		return fmt.Errorf("unsupported language: %s", opts.Language)
 	}
+	runtimeInfo = provider.OverrideInterpreter(runtimeInfo, opts.Interpreter)
+	if _, err := rlimitArgs(opts.Ulimits); err != nil {
+		return err
+	}
+	if err := checkCommandWrapper(opts.CommandWrapper); err != nil {
+		return err
+	}
+	if err := checkPathGrants(opts); err != nil {
+		return err
+	}
+
+	hostDir, jailDir, removeRunDir, err := i.runDir(opts)
+	if err != nil {
+		return err
+	}
+	defer removeRunDir()
 
 	// Write code to file
 	codeFilename := "main" + runtimeInfo.FileExt
-	codePath := filepath.Join(i.workDir, codeFilename)
+	codePath := filepath.Join(hostDir, codeFilename)
 	if err := os.WriteFile(codePath, []byte(code), 0644); err != nil {
 		return fmt.Errorf("write code file: %w", err)
 	}
 
+	removeSecrets, err := executor.WriteSecretFiles(&i.secretRefs, i.secretsDir(), opts.SecretFiles)
+	if err != nil {
+		return err
+	}
+	defer removeSecrets()
+
+	if opts, err = i.diskQuotaOptions(opts); err != nil {
+		return err
+	}
+
 	// Build command
-	sandboxCodePath := "/workspace/" + codeFilename
+	sandboxCodePath := jailDir + "/" + codeFilename
 	var runCmd []string
 	if runtimeInfo.CompileCmd != nil {
-		compileCmd := i.buildNsjailCmd(append(runtimeInfo.CompileCmd, sandboxCodePath), opts)
-		compileExec := exec.CommandContext(ctx, compileCmd[0], compileCmd[1:]...)
-		if output, err := compileExec.CombinedOutput(); err != nil {
-			handler(&executor.StreamEvent{
-				Type:      executor.StreamStderr,
-				Data:      string(output),
-				Timestamp: time.Now(),
-			})
+		compileCmd := i.buildNsjailCmd(append(runtimeInfo.CompileCmd, sandboxCodePath), jailDir, opts)
+		// Compiler diagnostics stream as they happen, ahead of the run.
+		compileExec, account, err := i.command(ctx, compileCmd)
+		if err != nil {
+			return err
+		}
+		if account != nil {
+			defer account.remove()
+		}
+		exitCode, err := streamCommand(ctx, compileExec, nil, opts.MaxOutputRate, executor.StreamCompileStdout, executor.StreamCompileStderr, handler)
+		if err != nil {
+			return fmt.Errorf("compile: %w", err)
+		}
+		if exitCode != 0 {
 			handler(&executor.StreamEvent{
 				Type:      executor.StreamComplete,
-				ExitCode:  1,
+				ExitCode:  exitCode,
+				Language:  opts.Language,
 				Timestamp: time.Now(),
 			})
 			return nil
 		}
-		runCmd = i.buildNsjailCmd(runtimeInfo.RunCommand, opts)
+		runCmd = i.buildNsjailCmd(provider.WrapCommand(slices.Concat(runtimeInfo.RunCommand, opts.Args), opts.CommandWrapper), jailDir, opts)
 	} else {
-		runCmd = i.buildNsjailCmd(append(runtimeInfo.RunCommand, sandboxCodePath), opts)
+		runCmd = i.buildNsjailCmd(provider.WrapCommand(slices.Concat(provider.RunCommand(runtimeInfo, i.config.EnableNetwork, jailDir), []string{sandboxCodePath}, opts.Args), opts.CommandWrapper), jailDir, opts)
 	}
 
-	cmd := exec.CommandContext(ctx, runCmd[0], runCmd[1:]...)
+	cmd, account, err := i.command(ctx, runCmd)
+	if err != nil {
+		return err
+	}
+	if account != nil {
+		defer account.remove()
+	}
+
+	exitCode, err := streamCommand(ctx, cmd, opts.StdinStream, opts.MaxOutputRate, executor.StreamStdout, executor.StreamStderr, handler)
+	if err != nil {
+		return err
+	}
 
+	handler(&executor.StreamEvent{
+		Type:      executor.StreamComplete,
+		ExitCode:  exitCode,
+		Language:  opts.Language,
+		Timestamp: time.Now(),
+	})
+
+	return nil
+}
+
+// streamCommand runs cmd, passing its output to handler as stdoutType and
+// stderrType events as it arrives, and returns the exit code. stdin, when
+// not nil, is copied to the command's standard input.
+func streamCommand(ctx context.Context, cmd *exec.Cmd, stdin io.Reader, maxRate int64, stdoutType, stderrType executor.StreamEventType, handler executor.StreamHandler) (int, error) {
 	stdoutPipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("create stdout pipe: %w", err)
+		return 0, fmt.Errorf("create stdout pipe: %w", err)
 	}
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("create stderr pipe: %w", err)
+		return 0, fmt.Errorf("create stderr pipe: %w", err)
+	}
+	// A pipe rather than cmd.Stdin, since Wait would block on copying from
+	// a stream that only ends when the caller closes it.
+	var stdinPipe io.WriteCloser
+	if stdin != nil {
+		if stdinPipe, err = cmd.StdinPipe(); err != nil {
+			return 0, fmt.Errorf("create stdin pipe: %w", err)
+		}
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("start command: %w", err)
+		return 0, fmt.Errorf("start command: %w", err)
+	}
+	if stdinPipe != nil {
+		go func() {
+			io.Copy(stdinPipe, stdin)
+			stdinPipe.Close()
+		}()
 	}
 
+	// stdout and stderr share one output budget.
+	outputRate := ratelimit.NewByteRate(maxRate)
+	stdout := outputRate.Reader(ctx, stdoutPipe)
+	stderr := outputRate.Reader(ctx, stderrPipe)
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -477,10 +880,10 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stdoutPipe.Read(buf)
+			n, err := stdout.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
-					Type:      executor.StreamStdout,
+					Type:      stdoutType,
 					Data:      string(buf[:n]),
 					Timestamp: time.Now(),
 				})
@@ -496,10 +899,10 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stderrPipe.Read(buf)
+			n, err := stderr.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
-					Type:      executor.StreamStderr,
+					Type:      stderrType,
 					Data:      string(buf[:n]),
 					Timestamp: time.Now(),
 				})
@@ -518,18 +921,17 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 			exitCode = exitErr.ExitCode()
 		}
 	}
-
-	handler(&executor.StreamEvent{
-		Type:      executor.StreamComplete,
-		ExitCode:  exitCode,
-		Timestamp: time.Now(),
-	})
-
-	return nil
+	return exitCode, nil
 }
 
 // RunCommand executes a shell command in the sandbox.
 func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*executor.CommandResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -539,16 +941,27 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 
 	start := time.Now()
 
+	opts, err := i.diskQuotaOptions(executor.DefaultExecutionOptions())
+	if err != nil {
+		return nil, err
+	}
+
 	fullCmd := append([]string{cmd}, args...)
-	nsjailCmd := i.buildNsjailCmd(fullCmd, executor.DefaultExecutionOptions())
+	nsjailCmd := i.buildNsjailCmd(fullCmd, "/workspace", opts)
 
-	execCmd := exec.CommandContext(ctx, nsjailCmd[0], nsjailCmd[1:]...)
+	execCmd, account, err := i.command(ctx, nsjailCmd)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		defer account.remove()
+	}
 
 	var stdout, stderr bytes.Buffer
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
 
-	err := execCmd.Run()
+	err = execCmd.Run()
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -0,0 +1,344 @@
+//go:build linux
+
+package nsjail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestRlimitArgs(t *testing.T) {
+	args, err := rlimitArgs(map[string]executor.Ulimit{
+		"stack":  {Soft: 64 << 20, Hard: 64 << 20},
+		"nofile": {Soft: 4096, Hard: 8192},
+		"core":   {Soft: -1, Hard: -1},
+		"fsize":  {Soft: 1, Hard: 1},
+	})
+	if err != nil {
+		t.Fatalf("rlimitArgs() error = %v", err)
+	}
+
+	want := []string{
+		"--rlimit_core", "inf",
+		"--rlimit_fsize", "1",
+		"--rlimit_nofile", "4096",
+		"--rlimit_stack", "64",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("rlimitArgs() = %v, want %v", args, want)
+	}
+
+	if _, err := rlimitArgs(map[string]executor.Ulimit{"bogus": {}}); err == nil {
+		t.Error("rlimitArgs() should reject unknown ulimits")
+	}
+}
+
+func TestBuildNsjailCmdUlimits(t *testing.T) {
+	i := &Instance{
+		config:  DefaultConfig(),
+		workDir: "/tmp/ws",
+		ulimits: map[string]executor.Ulimit{"nofile": {Soft: 1024, Hard: 1024}},
+	}
+
+	args := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{
+		Ulimits: map[string]executor.Ulimit{"nofile": {Soft: 2048, Hard: 2048}},
+	})
+
+	// nsjail keeps the last value of a repeated flag, so the per-execution
+	// limit must come after the sandbox limit and the config default.
+	var values []string
+	for j, arg := range args {
+		if arg == "--rlimit_nofile" {
+			values = append(values, args[j+1])
+		}
+	}
+	if got := strings.Join(values, ","); got != "64,1024,2048" {
+		t.Errorf("--rlimit_nofile values = %s, want 64,1024,2048", got)
+	}
+}
+
+func TestBuildNsjailCmdCPUMode(t *testing.T) {
+	for mode, want := range map[provider.CPUMode]bool{provider.CPUModeQuota: true, provider.CPUModeShares: false} {
+		i := &Instance{config: DefaultConfig(), workDir: "/tmp/ws", cpuMode: mode}
+		args := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{})
+		if got := slices.Contains(args, "--cgroup_cpu_ms_per_sec"); got != want {
+			t.Errorf("mode %d: CPU quota set = %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestBuildNsjailCmdMemory(t *testing.T) {
+	i := &Instance{config: DefaultConfig(), workDir: "/tmp/ws"}
+	i.config.MaxMemoryMB = 512
+
+	// A run can lower the cgroup limit but not raise it.
+	for memoryMB, want := range map[int]string{0: "536870912", 256: "268435456", 1024: "536870912"} {
+		args := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{MemoryMB: memoryMB})
+		if n := slices.Index(args, "--cgroup_mem_max"); n < 0 || args[n+1] != want {
+			t.Errorf("MemoryMB %d: args = %q, want --cgroup_mem_max %s", memoryMB, args, want)
+		}
+	}
+}
+
+func TestKafelPolicy(t *testing.T) {
+	got := kafelPolicy(&provider.SyscallFilter{Deny: []string{"mount", "ptrace"}, DenySocketFamilies: []int{2, 10}})
+	want := "POLICY sindoq { ERRNO(1) { mount, ptrace, socket(domain, type, protocol) { domain == 2 || domain == 10 } } } USE sindoq DEFAULT ALLOW"
+	if got != want {
+		t.Errorf("kafelPolicy() = %q, want %q", got, want)
+	}
+	if got := kafelPolicy(&provider.SyscallFilter{}); got != "" {
+		t.Errorf("kafelPolicy() of an empty filter = %q, want none", got)
+	}
+
+	got = kafelPolicy(&provider.SyscallFilter{Deny: []string{"fsopen"}, DenyCloneFlags: 0x10000000, Unimplemented: []string{"clone3"}, FixedPersonality: true})
+	wantFull := "POLICY sindoq { ERRNO(1) { SYSCALL[430], clone(flags, stack, ptid, ctid, tls) { (flags & 0x10000000) != 0 }, " +
+		"personality(persona) { persona != 0xffffffff } }, ERRNO(38) { SYSCALL[435] } } USE sindoq DEFAULT ALLOW"
+	if got != wantFull {
+		t.Errorf("kafelPolicy() = %q, want %q", got, wantFull)
+	}
+
+	i := &Instance{config: DefaultConfig(), workDir: "/tmp/ws", seccomp: want}
+	args := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{})
+	if n := slices.Index(args, "--seccomp_string"); n < 0 || args[n+1] != want {
+		t.Errorf("args = %q, want --seccomp_string %q", args, want)
+	}
+}
+
+func TestCreateCPUSet(t *testing.T) {
+	mount := t.TempDir()
+	cgroupMount = mount
+	t.Cleanup(func() { cgroupMount = "/sys/fs/cgroup" })
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpuset cpu memory pids\n"), 0644)
+
+	cfg := DefaultConfig()
+	cfg.CPUSet = "0"
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	instance, err := p.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	cmd, account, err := instance.(*Instance).command(ctx, []string{"nsjail", "--mode", "o"})
+	if err != nil {
+		t.Fatalf("command() error = %v", err)
+	}
+	defer account.remove()
+	want := []string{"nsjail", "--use_cgroupv2", "--cgroupv2_mount", account.dir, "--mode", "o"}
+	if !slices.Equal(cmd.Args, want) {
+		t.Errorf("command args = %q, want %q", cmd.Args, want)
+	}
+	if data, _ := os.ReadFile(filepath.Join(account.dir, "cpuset.cpus")); string(data) != "0" {
+		t.Errorf("cpuset.cpus = %q, want 0", data)
+	}
+
+	opts := provider.DefaultCreateOptions()
+	opts.Resources.CPUSet = "0-1"
+	if _, err := p.Create(ctx, opts); err == nil {
+		t.Error("Create() with a cpuset outside the provider's should fail")
+	}
+
+	os.WriteFile(filepath.Join(mount, "cgroup.controllers"), []byte("cpu memory pids\n"), 0644)
+	if _, err := p.Create(ctx, nil); err == nil {
+		t.Error("Create() without a cpuset controller should fail")
+	}
+}
+
+func TestBuildNsjailCmdUmask(t *testing.T) {
+	i := &Instance{config: DefaultConfig(), workDir: "/tmp/ws"}
+
+	mask := os.FileMode(0o077)
+	args := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{Umask: &mask})
+
+	got := strings.Join(args[slices.Index(args, "--")+1:], " ")
+	if want := `sh -c umask 0077 && exec "$@" sh true`; got != want {
+		t.Errorf("inner command = %s, want %s", got, want)
+	}
+}
+
+func TestBuildNsjailCmdPathGrants(t *testing.T) {
+	i := &Instance{config: DefaultConfig(), workDir: "/tmp/ws"}
+
+	granted := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{
+		AllowReadPaths:  []string{"/data/input"},
+		AllowWritePaths: []string{"/data/output"},
+	})
+	mounts := strings.Join(granted[:slices.Index(granted, "--")], " ")
+	if !strings.Contains(mounts, "--bindmount_ro /data/input") || !strings.Contains(mounts, "--bindmount /data/output") {
+		t.Errorf("args = %v, want the granted paths mounted", granted)
+	}
+
+	// The grants belong to that run only.
+	next := i.buildNsjailCmd([]string{"true"}, "/workspace", &executor.ExecutionOptions{})
+	if slices.Contains(next, "/data/input") || slices.Contains(next, "/data/output") {
+		t.Errorf("args = %v, want no mounts for a run without grants", next)
+	}
+}
+
+func TestCheckPathGrants(t *testing.T) {
+	dir := t.TempDir()
+	if err := checkPathGrants(&executor.ExecutionOptions{AllowReadPaths: []string{dir}, AllowWritePaths: []string{dir}}); err != nil {
+		t.Errorf("checkPathGrants() = %v", err)
+	}
+	for _, opts := range []*executor.ExecutionOptions{
+		{AllowReadPaths: []string{filepath.Join(dir, "missing")}},
+		{AllowWritePaths: []string{dir + "/../" + filepath.Base(dir)}},
+		{AllowReadPaths: []string{"relative"}},
+	} {
+		if err := checkPathGrants(opts); err == nil {
+			t.Errorf("checkPathGrants(%v, %v) should fail", opts.AllowReadPaths, opts.AllowWritePaths)
+		}
+	}
+}
+
+func TestCheckCommandWrapper(t *testing.T) {
+	if err := checkCommandWrapper(nil); err != nil {
+		t.Errorf("checkCommandWrapper(nil) = %v", err)
+	}
+	if err := checkCommandWrapper([]string{"sh", "-x"}); err != nil {
+		t.Errorf("checkCommandWrapper(sh) = %v, want it found in the jail PATH", err)
+	}
+	if err := checkCommandWrapper([]string{"/bin/sh"}); err != nil {
+		t.Errorf("checkCommandWrapper(/bin/sh) = %v", err)
+	}
+	if err := checkCommandWrapper([]string{"sindoq-no-such-profiler"}); err == nil {
+		t.Error("checkCommandWrapper should reject a missing binary")
+	}
+}
+
+func TestDiskQuotaOptions(t *testing.T) {
+	dir := t.TempDir()
+	i := &Instance{sandboxDir: dir, diskMB: 1}
+
+	if err := os.WriteFile(filepath.Join(dir, "used"), make([]byte, 256<<10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := i.diskQuotaOptions(&executor.ExecutionOptions{})
+	if err != nil {
+		t.Fatalf("diskQuotaOptions() error = %v", err)
+	}
+	if got := opts.Ulimits["fsize"].Soft; got != 768<<10 {
+		t.Errorf("fsize = %d, want the remaining %d bytes", got, 768<<10)
+	}
+
+	// A tighter limit from the caller is kept.
+	opts, _ = i.diskQuotaOptions(&executor.ExecutionOptions{
+		Ulimits: map[string]executor.Ulimit{"fsize": {Soft: 1024, Hard: 1024}},
+	})
+	if got := opts.Ulimits["fsize"].Soft; got != 1024 {
+		t.Errorf("fsize = %d, want 1024", got)
+	}
+
+	if used, _ := i.diskUsedMB(); used != 1 {
+		t.Errorf("diskUsedMB() = %d, want 1", used)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "full"), make([]byte, 768<<10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i.diskQuotaOptions(&executor.ExecutionOptions{}); err == nil {
+		t.Error("diskQuotaOptions() should fail once the limit is used up")
+	}
+
+	unlimited := &Instance{sandboxDir: dir}
+	in := &executor.ExecutionOptions{}
+	if opts, _ := unlimited.diskQuotaOptions(in); opts != in || opts.Ulimits != nil {
+		t.Error("diskQuotaOptions() should leave options alone without a limit")
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	i := &Instance{workDir: t.TempDir()}
+
+	hostDir, jailDir, remove, err := i.runDir(&executor.ExecutionOptions{})
+	if err != nil {
+		t.Fatalf("runDir() error = %v", err)
+	}
+	remove()
+	if hostDir != i.workDir || jailDir != "/workspace" {
+		t.Errorf("persistent runDir() = %s, %s, want %s, /workspace", hostDir, jailDir, i.workDir)
+	}
+
+	hostDir, jailDir, remove, err = i.runDir(&executor.ExecutionOptions{EphemeralWorkDir: true})
+	if err != nil {
+		t.Fatalf("runDir() error = %v", err)
+	}
+	if filepath.Dir(hostDir) != i.workDir {
+		t.Errorf("ephemeral host dir %s is not inside %s", hostDir, i.workDir)
+	}
+	if want := "/workspace/" + filepath.Base(hostDir); jailDir != want {
+		t.Errorf("ephemeral jail dir = %s, want %s", jailDir, want)
+	}
+	if err := os.WriteFile(filepath.Join(hostDir, "state.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	remove()
+	if _, err := os.Stat(hostDir); !os.IsNotExist(err) {
+		t.Errorf("ephemeral dir still exists after remove: %v", err)
+	}
+}
+
+func TestProviderCloseGraceful(t *testing.T) {
+	p, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	instance, err := p.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sandboxDir := instance.(*Instance).sandboxDir
+
+	// Hold an execution open to check that CloseGraceful waits for it.
+	_, done, err := p.inflight.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	closed := make(chan error, 1)
+	go func() { closed <- p.CloseGraceful(ctx) }()
+
+	select {
+	case <-closed:
+		t.Fatal("CloseGraceful() returned while an execution was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := p.Create(ctx, nil); !errors.Is(err, provider.ErrClosing) {
+		t.Errorf("Create() while closing error = %v, want ErrClosing", err)
+	}
+	if _, err := instance.RunCommand(ctx, "true", nil); !errors.Is(err, provider.ErrClosing) {
+		t.Errorf("RunCommand() while closing error = %v, want ErrClosing", err)
+	}
+
+	done()
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatalf("CloseGraceful() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CloseGraceful() did not return after the execution finished")
+	}
+
+	if status, _ := instance.Status(ctx); status != provider.StatusStopped {
+		t.Errorf("Status() = %v, want stopped", status)
+	}
+	if _, err := os.Stat(sandboxDir); !os.IsNotExist(err) {
+		t.Errorf("sandbox dir should be removed, Stat() error = %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package nsjail
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// unifiedSyscalls numbers the system calls added after kafel's syscall
+// tables were last updated. Since Linux 5.1 new calls share one number on
+// every architecture, so kafel can name them as SYSCALL[n].
+var unifiedSyscalls = map[string]int{
+	"io_uring_setup":    425,
+	"io_uring_enter":    426,
+	"io_uring_register": 427,
+	"open_tree":         428,
+	"move_mount":        429,
+	"fsopen":            430,
+	"fsconfig":          431,
+	"fsmount":           432,
+	"fspick":            433,
+	"clone3":            435,
+	"mount_setattr":     442,
+	"quotactl_fd":       443,
+}
+
+// x86Syscalls exist on x86 only. kafel rejects a policy naming a call its
+// architecture lacks, and there is nothing to deny elsewhere.
+var x86Syscalls = []string{"ioperm", "iopl", "sysfs", "uselib", "ustat"}
+
+// kafelSyscalls renders names as kafel syscall references, dropping the
+// ones the host architecture does not have.
+func kafelSyscalls(names []string) []string {
+	x86 := runtime.GOARCH == "amd64" || runtime.GOARCH == "386"
+	var out []string
+	for _, name := range names {
+		switch nr, ok := unifiedSyscalls[name]; {
+		case ok:
+			out = append(out, fmt.Sprintf("SYSCALL[%d]", nr))
+		case !x86 && slices.Contains(x86Syscalls, name):
+		default:
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// kafelPolicy renders f in kafel, the policy language of nsjail's
+// --seccomp_string. Everything f does not deny is allowed; nsjail runs
+// without a seccomp policy otherwise.
+func kafelPolicy(f *provider.SyscallFilter) string {
+	rules := kafelSyscalls(f.Deny)
+	if len(f.DenySocketFamilies) > 0 {
+		conds := make([]string, len(f.DenySocketFamilies))
+		for n, family := range f.DenySocketFamilies {
+			conds[n] = fmt.Sprintf("domain == %d", family)
+		}
+		rules = append(rules, fmt.Sprintf("socket(domain, type, protocol) { %s }", strings.Join(conds, " || ")))
+	}
+	if f.DenyCloneFlags != 0 {
+		rules = append(rules, fmt.Sprintf("clone(flags, stack, ptid, ctid, tls) { (flags & %#x) != 0 }", f.DenyCloneFlags))
+	}
+	if f.FixedPersonality {
+		rules = append(rules, "personality(persona) { persona != 0xffffffff }")
+	}
+
+	var actions []string
+	if len(rules) > 0 {
+		actions = append(actions, fmt.Sprintf("ERRNO(1) { %s }", strings.Join(rules, ", ")))
+	}
+	if unimplemented := kafelSyscalls(f.Unimplemented); len(unimplemented) > 0 {
+		actions = append(actions, fmt.Sprintf("ERRNO(38) { %s }", strings.Join(unimplemented, ", ")))
+	}
+	if len(actions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("POLICY sindoq { %s } USE sindoq DEFAULT ALLOW", strings.Join(actions, ", "))
+}
@@ -99,20 +99,123 @@ type Capabilities struct {
 
 	// SupportsPersistence indicates if sandbox state can be persisted.
 	SupportsPersistence bool
+
+	// SupportsRangeDownload indicates if partial file downloads are
+	// served natively rather than by reading the whole file.
+	SupportsRangeDownload bool
+
+	// SupportsNetworkCapture indicates if Execute can record a pcap of
+	// the sandbox's traffic (ExecutionOptions.NetworkCapture).
+	SupportsNetworkCapture bool
+
+	// SupportsTailFile indicates if the file system can follow a file as
+	// it grows (fs.Tailer).
+	SupportsTailFile bool
+
+	// SupportsArchive indicates if the file system can export and import
+	// directory trees as tar streams (fs.Archiver).
+	SupportsArchive bool
+
+	// SupportsPause indicates if instances can be frozen and resumed
+	// (Pauser).
+	SupportsPause bool
+
+	// SupportsCommandWrapper indicates if Execute can run the program
+	// under ExecutionOptions.CommandWrapper.
+	SupportsCommandWrapper bool
+
+	// SupportsInteractiveStdin indicates if ExecuteStream feeds
+	// ExecutionOptions.StdinStream to the program while it runs.
+	SupportsInteractiveStdin bool
+
+	// SupportsCoverage indicates if Execute can report the code's line
+	// coverage (ExecutionOptions.Coverage).
+	SupportsCoverage bool
+
+	// SupportsInterpreterPath indicates if Execute runs the program with
+	// ExecutionOptions.Interpreter in place of the runtime's default.
+	SupportsInterpreterPath bool
+
+	// SupportsPathGrants indicates if Execute mounts
+	// ExecutionOptions.AllowReadPaths and AllowWritePaths for the run.
+	SupportsPathGrants bool
+
+	// SupportsProgramArgs indicates if Execute passes
+	// ExecutionOptions.Args to the program.
+	SupportsProgramArgs bool
+
+	// SupportsDeterministicID indicates if Create honours CreateOptions.ID
+	// and reattaches to an existing sandbox with that ID.
+	SupportsDeterministicID bool
+
+	// SupportsRawStream indicates if ExecuteStream delivers StreamRaw
+	// events for ExecutionOptions.RawStream.
+	SupportsRawStream bool
+
+	// SupportsExecutionMemoryLimit indicates if Execute applies
+	// ExecutionOptions.MemoryMB as a real memory limit for the run.
+	SupportsExecutionMemoryLimit bool
+
+	// SupportsSyscallFilter indicates if Create applies
+	// CreateOptions.Syscalls and NoNewPrivileges.
+	SupportsSyscallFilter bool
+
+	// SupportsCPUSet indicates if sandboxes can be pinned to host cores
+	// with ResourceConfig.CPUSet.
+	SupportsCPUSet bool
+
+	// SupportsEphemeralWorkDir indicates if executions can run in a
+	// fresh working directory (ExecutionOptions.EphemeralWorkDir).
+	SupportsEphemeralWorkDir bool
+
+	// SupportsImageEntrypoint indicates if the sandbox can run the image's
+	// entrypoint (CreateOptions.RespectEntrypoint).
+	SupportsImageEntrypoint bool
+
+	// SupportsSecretFiles indicates if executions receive
+	// ExecutionOptions.SecretFiles under executor.SecretsDir.
+	SupportsSecretFiles bool
+
+	// DefaultWorkDir is the working directory sandboxes start in when none
+	// is configured. Empty means DefaultWorkDir.
+	DefaultWorkDir string
 }
 
+// Working directories used when the caller does not choose one.
+const (
+	// DefaultWorkDir is where code runs and files land by default.
+	DefaultWorkDir = "/workspace"
+
+	// DefaultFallbackWorkDir replaces DefaultWorkDir on images whose user
+	// cannot create or write it.
+	DefaultFallbackWorkDir = "/tmp/sindoq"
+)
+
 // CreateOptions configures sandbox creation.
 type CreateOptions struct {
+	// ID, when set, is the instance ID to use instead of a generated one.
+	// Providers with SupportsDeterministicID reattach to a running sandbox
+	// that already has the ID and carries every label in Labels, and fail
+	// if one has the ID but not the labels.
+	ID string
+
 	// Image specifies the container/VM image.
 	Image string
 
 	// Runtime specifies the language runtime (e.g., "python3.12", "node22").
 	Runtime string
 
+	// Polyglot marks Image as providing runtimes for several languages.
+	// Each execution runs the command for its own language in that image,
+	// and providers that check for the runtime report a missing one
+	// clearly. Image must be set.
+	Polyglot bool
+
 	// Resources defines resource limits.
 	Resources ResourceConfig
 
-	// Environment variables.
+	// Environment variables for every process in the sandbox, including
+	// RunCommand. An execution's Env takes precedence on conflict.
 	Environment map[string]string
 
 	// Timeout for the sandbox lifetime.
@@ -121,12 +224,62 @@ type CreateOptions struct {
 	// Labels for tagging/identification.
 	Labels map[string]string
 
-	// WorkDir is the initial working directory.
+	// WorkDir is the initial working directory. Providers that run an
+	// image create it if missing.
 	WorkDir string
 
+	// FallbackWorkDir is used instead of WorkDir when WorkDir cannot be
+	// created or written, e.g. on a slim image whose user may not create
+	// /workspace. Empty makes that a creation error. Instances report the
+	// directory they chose through WorkDirReporter.
+	FallbackWorkDir string
+
 	// InternetAccess controls network access.
 	InternetAccess bool
 
+	// Ports lists sandbox ports that Network().PublishPort must reach from
+	// the host. Docker and gVisor can only bind them at creation time.
+	Ports []int
+
+	// Ulimits sets resource limits by name for every process in the
+	// sandbox, e.g. "nofile" or "stack".
+	Ulimits map[string]executor.Ulimit
+
+	// ReadonlyRootfs mounts the root filesystem read-only, leaving only
+	// WorkDir, /tmp and ReadonlyRootfsTmpfs writable.
+	ReadonlyRootfs bool
+
+	// CapDrop lists Linux capabilities to remove, without the "CAP_"
+	// prefix; "ALL" removes every capability.
+	CapDrop []string
+
+	// CapAdd lists Linux capabilities to grant. They are applied after
+	// CapDrop.
+	CapAdd []string
+
+	// Syscalls restricts the system calls code may make. Nil keeps the
+	// provider's default. Only providers with SupportsSyscallFilter honour
+	// it.
+	Syscalls *SyscallFilter
+
+	// NoNewPrivileges stops processes from gaining privileges through
+	// setuid binaries or file capabilities.
+	NoNewPrivileges bool
+
+	// SecretFiles mounts a tmpfs at executor.SecretsDir so executions can
+	// receive ExecutionOptions.SecretFiles. Docker and gVisor need it at
+	// creation time; host providers write them to their scratch directory.
+	SecretFiles bool
+
+	// ReuseInterpreter keeps a warm interpreter running between executions
+	// where the provider supports it. Other providers ignore it.
+	ReuseInterpreter bool
+
+	// RespectEntrypoint runs the image's entrypoint instead of replacing it,
+	// and gives executions the environment it exported. Only providers
+	// with SupportsImageEntrypoint honour it.
+	RespectEntrypoint bool
+
 	// Metadata is provider-specific configuration.
 	Metadata map[string]any
 }
@@ -137,14 +290,14 @@ func DefaultCreateOptions() *CreateOptions {
 		Resources: ResourceConfig{
 			MemoryMB: 512,
 			CPUs:     1,
-			DiskMB:   1024,
 		},
-		Environment:    make(map[string]string),
-		Timeout:        5 * time.Minute,
-		Labels:         make(map[string]string),
-		WorkDir:        "/workspace",
-		InternetAccess: false,
-		Metadata:       make(map[string]any),
+		Environment:     make(map[string]string),
+		Timeout:         5 * time.Minute,
+		Labels:          make(map[string]string),
+		WorkDir:         DefaultWorkDir,
+		FallbackWorkDir: DefaultFallbackWorkDir,
+		InternetAccess:  false,
+		Metadata:        make(map[string]any),
 	}
 }
 
@@ -153,14 +306,83 @@ type ResourceConfig struct {
 	// MemoryMB is the memory limit in megabytes.
 	MemoryMB int
 
-	// CPUs is the CPU limit (can be fractional).
+	// CPUs is the CPU limit (can be fractional). It is ignored under
+	// CPUModeShares.
 	CPUs float64
 
-	// DiskMB is the disk space limit in megabytes.
+	// CPUMode selects between a hard CPUs quota, the default, and a
+	// relative CPUShares weight.
+	CPUMode CPUMode
+
+	// CPUShares is the sandbox's CPU weight under CPUModeShares, relative
+	// to other containers on the host. Zero uses the Docker default of
+	// 1024.
+	CPUShares int
+
+	// DiskMB limits the storage the sandbox can write, in megabytes. Zero
+	// means no limit. Providers that cannot enforce a limit fail Create
+	// with ErrDiskLimitUnsupported rather than ignore it.
 	DiskMB int
+
+	// MaxPids limits the number of processes and threads. Zero uses
+	// DefaultMaxPids; a negative value removes the limit.
+	MaxPids int
+
+	// CPUSet pins the sandbox to host cores, such as "0-3,6". It must lie
+	// within the provider's own cpuset, if one is configured. Only
+	// providers with SupportsCPUSet honour it.
+	CPUSet string
+}
+
+// CPUMode selects how the CPU time of a sandbox is limited.
+type CPUMode int
+
+const (
+	// CPUModeQuota caps the sandbox at ResourceConfig.CPUs cores even
+	// when the host is idle, so run times do not depend on other load.
+	CPUModeQuota CPUMode = iota
+
+	// CPUModeShares gives the sandbox a weight, ResourceConfig.CPUShares,
+	// that only matters under contention: an otherwise idle host lets it
+	// burst across all cores, trading predictable run times for
+	// throughput.
+	CPUModeShares
+)
+
+// DefaultMaxPids is the process limit applied when ResourceConfig.MaxPids
+// is zero. It contains fork bombs while leaving room for threaded runtimes
+// such as the JVM.
+const DefaultMaxPids = 256
+
+// PidsLimit returns the container pids limit for r.
+func (r ResourceConfig) PidsLimit() int64 {
+	switch {
+	case r.MaxPids < 0:
+		return -1
+	case r.MaxPids == 0:
+		return DefaultMaxPids
+	default:
+		return int64(r.MaxPids)
+	}
+}
+
+// ReadonlyRootfsTmpfs maps the tmpfs mounts added to a read-only container
+// to their mount options. They give compilers and package managers a
+// writable home and cache, e.g. GOCACHE or the npm cache under /root.
+var ReadonlyRootfsTmpfs = map[string]string{
+	"/root":    "rw,exec,mode=0700",
+	"/var/tmp": "rw,exec,mode=1777",
 }
 
 // Network provides network operations for a sandbox.
+//
+// PublishPort returns a URL reachable from the host running sindoq:
+//   - Docker and gVisor serve ports listed in CreateOptions.Ports from
+//     http://localhost:<hostport>; other ports use the container IP, which
+//     only Linux hosts can reach.
+//   - Firecracker serves every port from the VM's TAP address.
+//   - Vercel returns the platform's public URL.
+//   - E2B, Wasmer, nsjail and Podman return a nil Network.
 type Network interface {
 	// PublishPort exposes a port publicly.
 	PublishPort(ctx context.Context, port int) (*PublishedPort, error)
@@ -211,3 +433,43 @@ type PoolableInstance interface {
 	// UseCount returns how many times the instance has been used.
 	UseCount() int
 }
+
+// Pauser is implemented by instances that can be frozen without losing
+// state. A paused instance uses no CPU; its processes, including running
+// executions, continue where they left off on Resume.
+type Pauser interface {
+	// Pause freezes every process in the instance.
+	Pause(ctx context.Context) error
+
+	// Resume unfreezes a paused instance.
+	Resume(ctx context.Context) error
+}
+
+// RuntimeReporter is implemented by instances that resolve an image or
+// runtime when they are created and can report the result.
+type RuntimeReporter interface {
+	// RuntimeInfo returns what the instance resolved at creation.
+	RuntimeInfo() ResolvedRuntime
+}
+
+// WorkDirReporter is implemented by instances that may run in a working
+// directory other than CreateOptions.WorkDir, e.g. its fallback.
+type WorkDirReporter interface {
+	// WorkDir returns the working directory executions run in by default.
+	WorkDir() string
+}
+
+// ReattachReporter is implemented by instances that may have been created
+// by reattaching to a running sandbox with the same CreateOptions.ID.
+type ReattachReporter interface {
+	// Reattached reports whether the instance shares a sandbox created
+	// earlier, which other holders may still be using.
+	Reattached() bool
+}
+
+// QueueReporter is implemented by providers that throttle outgoing API
+// requests and can report how many are waiting.
+type QueueReporter interface {
+	// QueueDepth returns the number of requests waiting to be sent.
+	QueueDepth() int
+}
@@ -0,0 +1,21 @@
+package provider
+
+import "testing"
+
+func TestResourceConfigPidsLimit(t *testing.T) {
+	tests := []struct {
+		maxPids int
+		want    int64
+	}{
+		{0, DefaultMaxPids},
+		{64, 64},
+		{-1, -1},
+	}
+
+	for _, tt := range tests {
+		r := ResourceConfig{MaxPids: tt.maxPids}
+		if got := r.PidsLimit(); got != tt.want {
+			t.Errorf("ResourceConfig{MaxPids: %d}.PidsLimit() = %d, want %d", tt.maxPids, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// RejectRuntimeVersion returns an error if spec pins a runtime version
+// (e.g., "Python@3.11"). Providers whose runtimes are fixed by the host,
+// rootfs or template use it to fail fast instead of ignoring the version.
+func RejectRuntimeVersion(providerName, spec string) error {
+	if _, version := langdetect.ParseRuntimeSpec(spec); version != "" {
+		return fmt.Errorf("%s provider does not support runtime versions: %s", providerName, spec)
+	}
+	return nil
+}
+
+// ResolvedRuntime describes the image and runtime an instance was created
+// with.
+type ResolvedRuntime struct {
+	// Image is the container image, or empty for providers without images.
+	Image string
+
+	// Language is the runtime spec's language, normalized (e.g. "Python").
+	// It is empty when no runtime was given.
+	Language string
+
+	// Version is the version pinned by the runtime spec, if any.
+	Version string
+
+	// RunCommand is the command that runs a program in Language, without
+	// the file argument.
+	RunCommand []string
+}
+
+// NewResolvedRuntime describes image together with a runtime spec such as
+// "Python@3.11". A language langdetect does not know is kept as given,
+// with no RunCommand.
+func NewResolvedRuntime(image, spec string) ResolvedRuntime {
+	language, version := langdetect.ParseRuntimeSpec(spec)
+	r := ResolvedRuntime{Image: image, Language: language, Version: version}
+	if info, ok := langdetect.GetRuntimeInfo(language); ok {
+		r.Language = info.Language
+		r.RunCommand = slices.Clone(info.RunCommand)
+	}
+	return r
+}
+
+// OverrideInterpreter returns a copy of info whose run command starts with
+// interpreter instead of the runtime's default, or info itself when
+// interpreter is empty.
+func OverrideInterpreter(info *langdetect.RuntimeInfo, interpreter string) *langdetect.RuntimeInfo {
+	if interpreter == "" || len(info.RunCommand) == 0 {
+		return info
+	}
+	overridden := *info
+	overridden.RunCommand = slices.Clone(info.RunCommand)
+	overridden.RunCommand[0] = interpreter
+	return &overridden
+}
+
+// ValidatePathGrant checks a path of ExecutionOptions.AllowReadPaths or
+// AllowWritePaths: it must be absolute, must not be the root, and must not
+// contain ".." elements or a colon, which bind mount syntax would read as
+// a separate target.
+func ValidatePathGrant(p string) error {
+	switch {
+	case !path.IsAbs(p):
+		return fmt.Errorf("path %q is not absolute", p)
+	case slices.Contains(strings.Split(p, "/"), ".."):
+		return fmt.Errorf("path %q contains ..", p)
+	case strings.Contains(p, ":"):
+		return fmt.Errorf("path %q contains a colon", p)
+	case path.Clean(p) == "/":
+		return fmt.Errorf("path %q grants the whole file system", p)
+	}
+	return nil
+}
+
+// RunCommand returns a copy of info's run command followed by the flags
+// that apply the sandbox's policy inside runtimes with a permission model
+// of their own. Deno gets read and write access to workDir, and network
+// access only when internetAccess is set, so the runtime refuses what the
+// sandbox would block anyway. Other runtimes' commands are unchanged.
+func RunCommand(info *langdetect.RuntimeInfo, internetAccess bool, workDir string) []string {
+	cmd := slices.Clone(info.RunCommand)
+	if info.Language != "Deno" {
+		return cmd
+	}
+	if internetAccess {
+		cmd = append(cmd, "--allow-net")
+	}
+	if workDir != "" {
+		cmd = append(cmd, "--allow-read="+workDir, "--allow-write="+workDir)
+	}
+	return cmd
+}
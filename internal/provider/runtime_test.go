@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+func TestRejectRuntimeVersion(t *testing.T) {
+	if err := RejectRuntimeVersion("nsjail", "Python"); err != nil {
+		t.Errorf("unversioned runtime should be accepted: %v", err)
+	}
+	if err := RejectRuntimeVersion("nsjail", ""); err != nil {
+		t.Errorf("empty runtime should be accepted: %v", err)
+	}
+	if err := RejectRuntimeVersion("nsjail", "Python@3.11"); err == nil {
+		t.Error("versioned runtime should be rejected")
+	}
+}
+
+func TestNewResolvedRuntime(t *testing.T) {
+	r := NewResolvedRuntime("python:3.11-slim", "python@3.11")
+	if r.Image != "python:3.11-slim" || r.Language != "Python" || r.Version != "3.11" {
+		t.Errorf("resolved = %+v", r)
+	}
+	if len(r.RunCommand) != 1 || r.RunCommand[0] != "python3" {
+		t.Errorf("RunCommand = %v, want [python3]", r.RunCommand)
+	}
+
+	r = NewResolvedRuntime("", "Cobol")
+	if r.Language != "Cobol" || r.RunCommand != nil {
+		t.Errorf("unknown language resolved to %+v", r)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	deno, _ := langdetect.GetRuntimeInfo("Deno")
+	got := RunCommand(deno, false, "/workspace")
+	want := []string{"deno", "run", "--allow-read=/workspace", "--allow-write=/workspace"}
+	if !slices.Equal(got, want) {
+		t.Errorf("RunCommand() = %v, want %v", got, want)
+	}
+
+	got = RunCommand(deno, true, "")
+	if want := []string{"deno", "run", "--allow-net"}; !slices.Equal(got, want) {
+		t.Errorf("RunCommand() with internet access = %v, want %v", got, want)
+	}
+
+	python, _ := langdetect.GetRuntimeInfo("Python")
+	got = RunCommand(python, true, "/workspace")
+	if !slices.Equal(got, python.RunCommand) {
+		t.Errorf("RunCommand() = %v, want Python's command unchanged", got)
+	}
+	got[0] = "changed"
+	if python.RunCommand[0] != "python3" {
+		t.Error("RunCommand() should return a copy")
+	}
+}
+
+func TestOverrideInterpreter(t *testing.T) {
+	deno, _ := langdetect.GetRuntimeInfo("Deno")
+	got := OverrideInterpreter(deno, "/opt/deno/bin/deno")
+	if want := []string{"/opt/deno/bin/deno", "run"}; !slices.Equal(got.RunCommand, want) {
+		t.Errorf("RunCommand = %v, want %v", got.RunCommand, want)
+	}
+	if deno.RunCommand[0] != "deno" {
+		t.Error("OverrideInterpreter() should not change the runtime")
+	}
+	if OverrideInterpreter(deno, "") != deno {
+		t.Error("OverrideInterpreter() without an interpreter should return the runtime")
+	}
+}
+
+func TestValidatePathGrant(t *testing.T) {
+	for _, p := range []string{"/data/input", "/srv/models/", "/data/..hidden"} {
+		if err := ValidatePathGrant(p); err != nil {
+			t.Errorf("ValidatePathGrant(%q) = %v", p, err)
+		}
+	}
+	for _, p := range []string{"data/input", "/data/../etc", "/..", "/", "//", "/data:/workspace", ""} {
+		if err := ValidatePathGrant(p); err == nil {
+			t.Errorf("ValidatePathGrant(%q) should fail", p)
+		}
+	}
+}
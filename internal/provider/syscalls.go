@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// SyscallFilter denies system calls on top of an otherwise permissive
+// seccomp policy. Denied calls fail with EPERM rather than killing the
+// process, so code sees an ordinary error.
+type SyscallFilter struct {
+	// Deny lists system calls by name, such as "mount".
+	Deny []string
+
+	// DenySocketFamilies lists address families, such as 2 for AF_INET,
+	// that socket(2) refuses to create.
+	DenySocketFamilies []int
+
+	// DenyCloneFlags makes clone(2) fail when any of these flags, such
+	// as CLONE_NEWUSER, is set.
+	DenyCloneFlags uint64
+
+	// Unimplemented lists system calls that fail with ENOSYS, as if the
+	// kernel predated them, so libc falls back to older ones. clone3 is
+	// refused this way because its flags cannot be filtered.
+	Unimplemented []string
+
+	// FixedPersonality lets personality(2) query the execution domain
+	// but not change it.
+	FixedPersonality bool
+}
+
+var syscallName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Validate checks that the filter names system calls and families only,
+// so it can be rendered into any policy language.
+func (f *SyscallFilter) Validate() error {
+	for _, name := range slices.Concat(f.Deny, f.Unimplemented) {
+		if !syscallName.MatchString(name) {
+			return fmt.Errorf("invalid syscall name %q", name)
+		}
+	}
+	for _, family := range f.DenySocketFamilies {
+		if family < 0 {
+			return fmt.Errorf("invalid socket family %d", family)
+		}
+	}
+	return nil
+}
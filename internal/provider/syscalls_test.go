@@ -0,0 +1,25 @@
+package provider
+
+import "testing"
+
+func TestSyscallFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  SyscallFilter
+		wantErr bool
+	}{
+		{"empty", SyscallFilter{}, false},
+		{"names and families", SyscallFilter{Deny: []string{"mount", "umount2"}, DenySocketFamilies: []int{2, 10}}, false},
+		{"policy injection", SyscallFilter{Deny: []string{"mount } USE x"}}, true},
+		{"upper case", SyscallFilter{Deny: []string{"MOUNT"}}, true},
+		{"unimplemented injection", SyscallFilter{Unimplemented: []string{"clone3 }"}}, true},
+		{"negative family", SyscallFilter{DenySocketFamilies: []int{-1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.filter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// TimeoutGrace is how long the in-sandbox timeout wrapper waits beyond the
+// execution timeout, so the caller's context normally fires first.
+const TimeoutGrace = 5 * time.Second
+
+// WrapTimeout prefixes cmd with the coreutils timeout command so the process
+// is killed inside the sandbox even if the host-side exec never returns.
+// It returns cmd unchanged when timeout is not positive.
+func WrapTimeout(cmd []string, timeout time.Duration) []string {
+	if timeout <= 0 || len(cmd) == 0 {
+		return cmd
+	}
+	seconds := int64(math.Ceil((timeout + TimeoutGrace).Seconds()))
+	wrapped := make([]string, 0, len(cmd)+2)
+	wrapped = append(wrapped, "timeout", strconv.FormatInt(seconds, 10))
+	return append(wrapped, cmd...)
+}
@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWrapTimeout(t *testing.T) {
+	cmd := []string{"python3", "/workspace/main.py"}
+
+	got := WrapTimeout(cmd, 30*time.Second)
+	want := []string{"timeout", "35", "python3", "/workspace/main.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WrapTimeout = %v, want %v", got, want)
+	}
+
+	got = WrapTimeout(cmd, 1500*time.Millisecond)
+	if got[1] != "7" {
+		t.Errorf("seconds = %q, want %q", got[1], "7")
+	}
+
+	if got := WrapTimeout(cmd, 0); !reflect.DeepEqual(got, cmd) {
+		t.Errorf("WrapTimeout with zero timeout = %v, want %v", got, cmd)
+	}
+}
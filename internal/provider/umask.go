@@ -0,0 +1,17 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+)
+
+// WrapUmask runs cmd under sh with the given umask so the files it creates
+// get predictable permissions. It returns cmd unchanged when umask is nil.
+func WrapUmask(cmd []string, umask *os.FileMode) []string {
+	if umask == nil || len(cmd) == 0 {
+		return cmd
+	}
+	wrapped := make([]string, 0, len(cmd)+4)
+	wrapped = append(wrapped, "sh", "-c", fmt.Sprintf(`umask %04o && exec "$@"`, *umask&os.ModePerm), "sh")
+	return append(wrapped, cmd...)
+}
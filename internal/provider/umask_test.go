@@ -0,0 +1,22 @@
+package provider
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestWrapUmask(t *testing.T) {
+	cmd := []string{"python3", "/workspace/main.py"}
+
+	mask := os.FileMode(0o27)
+	got := WrapUmask(cmd, &mask)
+	want := []string{"sh", "-c", `umask 0027 && exec "$@"`, "sh", "python3", "/workspace/main.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WrapUmask = %v, want %v", got, want)
+	}
+
+	if got := WrapUmask(cmd, nil); !reflect.DeepEqual(got, cmd) {
+		t.Errorf("WrapUmask with nil umask = %v, want %v", got, cmd)
+	}
+}
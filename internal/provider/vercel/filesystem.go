@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"path/filepath"
 
+	"github.com/happyhackingspace/sindoq/internal/provider"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 )
 
@@ -19,7 +20,7 @@ type vercelFS struct {
 
 // Read reads file contents.
 func (v *vercelFS) Read(ctx context.Context, path string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", v.instance.provider.config.BaseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -31,9 +32,8 @@ func (v *vercelFS) Read(ctx context.Context, path string) ([]byte, error) {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("read file failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("read file", resp); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -54,7 +54,7 @@ func (v *vercelFS) Write(ctx context.Context, path string, data []byte) error {
 
 // Delete removes a file or directory.
 func (v *vercelFS) Delete(ctx context.Context, path string) error {
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", v.instance.provider.config.BaseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path, nil)
 	if err != nil {
 		return err
 	}
@@ -66,9 +66,8 @@ func (v *vercelFS) Delete(ctx context.Context, path string) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("delete file failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("delete file", resp, http.StatusOK, http.StatusNoContent); err != nil {
+		return err
 	}
 
 	return nil
@@ -76,7 +75,7 @@ func (v *vercelFS) Delete(ctx context.Context, path string) error {
 
 // List lists files in a directory.
 func (v *vercelFS) List(ctx context.Context, path string) ([]fs.FileInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path+"&list=true", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", v.instance.provider.config.BaseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path+"&list=true", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -88,9 +87,8 @@ func (v *vercelFS) List(ctx context.Context, path string) ([]fs.FileInfo, error)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list files failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("list files", resp); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -130,7 +128,7 @@ func (v *vercelFS) Exists(ctx context.Context, path string) (bool, error) {
 
 // Stat returns file information.
 func (v *vercelFS) Stat(ctx context.Context, path string) (*fs.FileInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path+"&stat=true", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", v.instance.provider.config.BaseURL+"/v1/sandbox/"+v.instance.id+"/files?path="+path+"&stat=true", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -142,8 +140,8 @@ func (v *vercelFS) Stat(ctx context.Context, path string) (*fs.FileInfo, error)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("stat failed: %s", resp.Status)
+	if err := provider.CheckHTTPResponse("stat", resp); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -229,7 +227,7 @@ func (v *vercelFS) Move(ctx context.Context, src, dst string) error {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox/"+v.instance.id+"/files/move", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", v.instance.provider.config.BaseURL+"/v1/sandbox/"+v.instance.id+"/files/move", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 
 	"github.com/happyhackingspace/sindoq/internal/provider"
@@ -28,7 +27,7 @@ func (n *vercelNetwork) PublishPort(ctx context.Context, port int) (*provider.Pu
 		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox/"+n.instance.id+"/ports", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", n.instance.provider.config.BaseURL+"/v1/sandbox/"+n.instance.id+"/ports", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +39,8 @@ func (n *vercelNetwork) PublishPort(ctx context.Context, port int) (*provider.Pu
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("publish port failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("publish port", resp, http.StatusOK, http.StatusCreated); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -84,7 +82,7 @@ func (n *vercelNetwork) GetPublicURL(port int) (string, error) {
 
 // ListPorts returns all published ports.
 func (n *vercelNetwork) ListPorts(ctx context.Context) ([]*provider.PublishedPort, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/sandbox/"+n.instance.id+"/ports", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", n.instance.provider.config.BaseURL+"/v1/sandbox/"+n.instance.id+"/ports", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -130,7 +128,7 @@ func (n *vercelNetwork) ListPorts(ctx context.Context) ([]*provider.PublishedPor
 
 // UnpublishPort removes port exposure.
 func (n *vercelNetwork) UnpublishPort(ctx context.Context, port int) error {
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/v1/sandbox/"+n.instance.id+"/ports/"+fmt.Sprintf("%d", port), nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", n.instance.provider.config.BaseURL+"/v1/sandbox/"+n.instance.id+"/ports/"+fmt.Sprintf("%d", port), nil)
 	if err != nil {
 		return err
 	}
@@ -142,6 +140,10 @@ func (n *vercelNetwork) UnpublishPort(ctx context.Context, port int) error {
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("unpublish port", resp, http.StatusOK, http.StatusNoContent); err != nil {
+		return err
+	}
+
 	if n.ports != nil {
 		delete(n.ports, port)
 	}
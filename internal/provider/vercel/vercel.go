@@ -6,20 +6,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 	"github.com/happyhackingspace/sindoq/pkg/langdetect"
 )
 
 const (
-	baseURL = "https://api.vercel.com"
+	defaultRateLimit  = 10
+	defaultRateBurst  = 20
+	defaultMaxRetries = 3
+
+	defaultBaseURL = "https://api.vercel.com"
 )
 
 func init() {
@@ -39,12 +44,34 @@ type Config struct {
 	TeamID    string
 	ProjectID string
 	Runtime   string // "node22" or "python313"
+
+	// RateLimit is the maximum requests per second sent to the API
+	// (default 10). A negative value disables throttling. Providers with
+	// the same token share one limiter, and New fails if their RateLimit
+	// or RateBurst differ.
+	RateLimit float64
+
+	// RateBurst is the number of requests allowed in a burst (default 20).
+	RateBurst int
+
+	// MaxRetries is how many times a 429 response is retried (default 3).
+	// A negative value disables retries.
+	MaxRetries int
+
+	// BaseURL is the API endpoint (default https://api.vercel.com).
+	BaseURL string
+
+	// HTTPClient sends the API requests, for a proxy, custom TLS or tests
+	// (default a client with a 5 minute timeout). Rate limiting and retries
+	// wrap its transport.
+	HTTPClient *http.Client
 }
 
 // Provider implements the Vercel Sandbox provider.
 type Provider struct {
-	config *Config
-	client *http.Client
+	config  *Config
+	client  *http.Client
+	limiter *ratelimit.Limiter
 }
 
 // New creates a new Vercel Sandbox provider.
@@ -57,14 +84,44 @@ func New(cfg *Config) (*Provider, error) {
 		return nil, fmt.Errorf("vercel token is required")
 	}
 
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = defaultRateLimit
+	}
+	if cfg.RateBurst == 0 {
+		cfg.RateBurst = defaultRateBurst
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit > 0 {
+		var err error
+		limiter, err = ratelimit.Shared("vercel:"+cfg.Token, cfg.RateLimit, cfg.RateBurst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Provider{
-		config: cfg,
-		client: &http.Client{
-			Timeout: 5 * time.Minute,
-		},
+		config:  cfg,
+		client:  ratelimit.Client(cfg.HTTPClient, 5*time.Minute, limiter, cfg.MaxRetries),
+		limiter: limiter,
 	}, nil
 }
 
+// QueueDepth returns the number of API requests waiting on the rate limiter.
+func (p *Provider) QueueDepth() int {
+	if p.limiter == nil {
+		return 0
+	}
+	return p.limiter.QueueDepth()
+}
+
 // Name returns the provider identifier.
 func (p *Provider) Name() string {
 	return "vercel"
@@ -75,9 +132,19 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 	if opts == nil {
 		opts = provider.DefaultCreateOptions()
 	}
+	if err := provider.RejectDiskLimit("vercel", opts.Resources); err != nil {
+		return nil, err
+	}
 
 	// Determine runtime
 	runtime := p.config.Runtime
+	if language, version := langdetect.ParseRuntimeSpec(opts.Runtime); version != "" {
+		r, err := vercelRuntime(language, version)
+		if err != nil {
+			return nil, err
+		}
+		runtime = r
+	}
 	if runtime == "" {
 		runtime = "python313" // Default to Python
 	}
@@ -96,7 +163,7 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/v1/sandbox", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -109,9 +176,8 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("create sandbox failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("create sandbox", resp, http.StatusOK, http.StatusCreated); err != nil {
+		return nil, err
 	}
 
 	var result struct {
@@ -141,6 +207,30 @@ func (p *Provider) setHeaders(req *http.Request) {
 	}
 }
 
+// vercelRuntimes lists the runtime identifiers offered by Vercel Sandbox.
+var vercelRuntimes = map[string]bool{
+	"node22":    true,
+	"python313": true,
+}
+
+// vercelRuntime maps a language and version (e.g., "Python", "3.13") to a
+// Vercel runtime identifier (e.g., "python313").
+func vercelRuntime(language, version string) (string, error) {
+	var runtime string
+	if info, ok := langdetect.GetRuntimeInfo(language); ok {
+		switch info.Language {
+		case "JavaScript", "TypeScript":
+			runtime = "node" + version
+		case "Python":
+			runtime = "python" + strings.ReplaceAll(version, ".", "")
+		}
+	}
+	if !vercelRuntimes[runtime] {
+		return "", fmt.Errorf("vercel provider does not support runtime %s@%s", language, version)
+	}
+	return runtime, nil
+}
+
 // Capabilities returns Vercel Sandbox provider capabilities.
 func (p *Provider) Capabilities() provider.Capabilities {
 	return provider.Capabilities{
@@ -157,7 +247,7 @@ func (p *Provider) Capabilities() provider.Capabilities {
 
 // Validate checks if Vercel API is accessible.
 func (p *Provider) Validate(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/user", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/v1/user", nil)
 	if err != nil {
 		return err
 	}
@@ -169,8 +259,8 @@ func (p *Provider) Validate(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("vercel API returned: %s", resp.Status)
+	if err := provider.CheckHTTPResponse("validate", resp); err != nil {
+		return err
 	}
 
 	return nil
@@ -254,7 +344,7 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox/"+i.id+"/exec", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", i.provider.config.BaseURL+"/v1/sandbox/"+i.id+"/exec", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -266,6 +356,10 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("execute", resp); err != nil {
+		return nil, err
+	}
+
 	var result struct {
 		ExitCode int    `json:"exitCode"`
 		Stdout   string `json:"stdout"`
@@ -297,7 +391,7 @@ func (i *Instance) writeFile(ctx context.Context, path string, content []byte) e
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox/"+i.id+"/files", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", i.provider.config.BaseURL+"/v1/sandbox/"+i.id+"/files", bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
@@ -309,9 +403,8 @@ func (i *Instance) writeFile(ctx context.Context, path string, content []byte) e
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("write file failed: %s - %s", resp.Status, string(bodyBytes))
+	if err := provider.CheckHTTPResponse("write file", resp, http.StatusOK, http.StatusCreated); err != nil {
+		return err
 	}
 
 	return nil
@@ -350,6 +443,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
 		ExitCode:  result.ExitCode,
+		Language:  result.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -370,7 +464,7 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 
 	start := time.Now()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/sandbox/"+i.id+"/exec", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", i.provider.config.BaseURL+"/v1/sandbox/"+i.id+"/exec", bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -382,6 +476,10 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 	}
 	defer resp.Body.Close()
 
+	if err := provider.CheckHTTPResponse("run command", resp); err != nil {
+		return nil, err
+	}
+
 	var result struct {
 		ExitCode int    `json:"exitCode"`
 		Stdout   string `json:"stdout"`
@@ -420,7 +518,7 @@ func (i *Instance) Stop(ctx context.Context) error {
 	i.stopped = true
 	i.mu.Unlock()
 
-	req, err := http.NewRequestWithContext(ctx, "DELETE", baseURL+"/v1/sandbox/"+i.id, nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", i.provider.config.BaseURL+"/v1/sandbox/"+i.id, nil)
 	if err != nil {
 		return err
 	}
@@ -432,7 +530,8 @@ func (i *Instance) Stop(ctx context.Context) error {
 	}
 	defer resp.Body.Close()
 
-	return nil
+	// A sandbox that is already gone counts as stopped.
+	return provider.CheckHTTPResponse("stop sandbox", resp, http.StatusOK, http.StatusNoContent, http.StatusNotFound)
 }
 
 // Status returns the current status.
@@ -444,7 +543,7 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 	}
 	i.mu.RUnlock()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/v1/sandbox/"+i.id, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", i.provider.config.BaseURL+"/v1/sandbox/"+i.id, nil)
 	if err != nil {
 		return provider.StatusError, err
 	}
@@ -459,6 +558,9 @@ func (i *Instance) Status(ctx context.Context) (provider.InstanceStatus, error)
 	if resp.StatusCode == http.StatusNotFound {
 		return provider.StatusStopped, nil
 	}
+	if err := provider.CheckHTTPResponse("get sandbox", resp); err != nil {
+		return provider.StatusError, err
+	}
 
 	return provider.StatusRunning, nil
 }
@@ -0,0 +1,97 @@
+package vercel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestCreateHTTPStatus(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "denied", status)
+			}))
+			defer srv.Close()
+
+			p, err := New(&Config{Token: "token", BaseURL: srv.URL, RateLimit: -1, MaxRetries: -1})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			_, err = p.Create(context.Background(), nil)
+			var httpErr *provider.HTTPProviderError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("Create() error = %v, want *HTTPProviderError", err)
+			}
+			if httpErr.Status != status || httpErr.Op != "create sandbox" || httpErr.Body != "denied" {
+				t.Errorf("error = %+v", httpErr)
+			}
+		})
+	}
+}
+
+// redirectTransport sends every request to a test server, whatever its URL.
+type redirectTransport struct {
+	target string
+	calls  int
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", t.target
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/sandbox":
+			w.Write([]byte(`{"id":"sbx-1","runtime":"python313"}`))
+		case "POST /v1/sandbox/sbx-1/files":
+			w.Write([]byte(`{}`))
+		case "POST /v1/sandbox/sbx-1/exec":
+			w.Write([]byte(`{"stdout":"1\n","exitCode":0}`))
+		case "DELETE /v1/sandbox/sbx-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	transport := &redirectTransport{target: srv.Listener.Addr().String()}
+	p, err := New(&Config{Token: "token", RateLimit: -1, HTTPClient: &http.Client{Transport: transport}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx := context.Background()
+
+	inst, err := p.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if inst.ID() != "sbx-1" {
+		t.Errorf("ID() = %q, want sbx-1", inst.ID())
+	}
+	result, err := inst.Execute(ctx, "print(1)", &executor.ExecutionOptions{Language: "Python"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "1\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "1\n")
+	}
+	if err := inst.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if transport.calls < 3 {
+		t.Errorf("custom client sent %d requests, want at least 3", transport.calls)
+	}
+}
@@ -123,6 +123,26 @@ func (f *wasmerFS) Download(ctx context.Context, remotePath string, writer io.Wr
 	return err
 }
 
+// DownloadRange copies a byte range of a file from the workspace.
+func (f *wasmerFS) DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error {
+	file, err := os.Open(f.resolvePath(path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return fs.WriteRange(file, offset, length, writer)
+}
+
+// ExportTar archives a directory of the workspace.
+func (f *wasmerFS) ExportTar(ctx context.Context, dir string, w io.Writer) error {
+	return fs.WriteTar(ctx, f.resolvePath(dir), w)
+}
+
+// ImportTar extracts an archive into a directory of the workspace.
+func (f *wasmerFS) ImportTar(ctx context.Context, dir string, r io.Reader) error {
+	return fs.ExtractTar(ctx, f.resolvePath(dir), r)
+}
+
 // MkDir creates a directory.
 func (f *wasmerFS) MkDir(ctx context.Context, path string) error {
 	fullPath := f.resolvePath(path)
@@ -210,4 +230,7 @@ func (f *wasmerFS) Watch(ctx context.Context, path string, events chan<- *fs.Wat
 	return fmt.Errorf("watch not implemented for wasmer provider")
 }
 
-var _ fs.FileSystem = (*wasmerFS)(nil)
+var (
+	_ fs.FileSystem = (*wasmerFS)(nil)
+	_ fs.Archiver   = (*wasmerFS)(nil)
+)
@@ -16,6 +16,7 @@ import (
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/ratelimit"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 )
@@ -141,6 +142,9 @@ type Provider struct {
 	instances map[string]*Instance
 	mu        sync.RWMutex
 	runtimes  map[string]WasmRuntime
+
+	// inflight tracks executions so CloseGraceful can wait for them.
+	inflight provider.InFlight
 }
 
 // New creates a new Wasmer provider.
@@ -177,6 +181,12 @@ func (p *Provider) Name() string {
 
 // Create initializes a new Wasmer sandbox instance.
 func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	ctx, done, err := p.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// Check if wasmer is available
 	if err := p.Validate(ctx); err != nil {
 		return nil, err
@@ -185,6 +195,12 @@ func (p *Provider) Create(ctx context.Context, opts *provider.CreateOptions) (pr
 	if opts == nil {
 		opts = provider.DefaultCreateOptions()
 	}
+	if err := provider.RejectRuntimeVersion("wasmer", opts.Runtime); err != nil {
+		return nil, err
+	}
+	if err := provider.RejectDiskLimit("wasmer", opts.Resources); err != nil {
+		return nil, err
+	}
 
 	id := fmt.Sprintf("wasmer-%d", time.Now().UnixNano())
 
@@ -226,14 +242,18 @@ func (p *Provider) Capabilities() provider.Capabilities {
 	}
 
 	return provider.Capabilities{
-		SupportsStreaming:  true,
-		SupportsAsync:      true,
-		SupportsFileSystem: true,
-		SupportsNetwork:    p.config.EnableNetwork,
-		SupportedLanguages: languages,
-		MaxExecutionTime:   time.Duration(p.config.TimeLimit) * time.Second,
-		MaxMemoryMB:        int(p.config.MaxMemoryMB),
-		MaxCPUs:            1, // WASM is single-threaded
+		SupportsStreaming:        true,
+		SupportsProgramArgs:      true,
+		SupportsAsync:            true,
+		SupportsFileSystem:       true,
+		SupportsNetwork:          p.config.EnableNetwork,
+		SupportsRangeDownload:    true,
+		SupportsArchive:          true,
+		SupportsEphemeralWorkDir: true,
+		SupportedLanguages:       languages,
+		MaxExecutionTime:         time.Duration(p.config.TimeLimit) * time.Second,
+		MaxMemoryMB:              int(p.config.MaxMemoryMB),
+		MaxCPUs:                  1, // WASM is single-threaded
 	}
 }
 
@@ -254,16 +274,19 @@ func (p *Provider) Validate(ctx context.Context) error {
 
 // Close releases provider resources.
 func (p *Provider) Close() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	for _, instance := range p.instances {
+	for _, instance := range provider.SnapshotInstances(&p.mu, p.instances) {
 		instance.Stop(context.Background())
 	}
 
 	return nil
 }
 
+// CloseGraceful refuses new sandboxes and executions, waits for running
+// ones, then closes the provider.
+func (p *Provider) CloseGraceful(ctx context.Context) error {
+	return p.inflight.CloseGraceful(ctx, p.Close)
+}
+
 // InstallRuntime installs a WASM runtime package.
 func (p *Provider) InstallRuntime(ctx context.Context, packageName string) error {
 	cmd := exec.CommandContext(ctx, p.config.WasmerPath, "run", packageName, "--", "--help")
@@ -276,6 +299,7 @@ func (p *Provider) InstallRuntime(ctx context.Context, packageName string) error
 }
 
 var _ provider.Provider = (*Provider)(nil)
+var _ provider.GracefulCloser = (*Provider)(nil)
 
 // Instance represents a Wasmer sandbox instance.
 type Instance struct {
@@ -297,6 +321,12 @@ func (i *Instance) ID() string {
 
 // Execute runs code in the Wasmer sandbox.
 func (i *Instance) Execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -313,16 +343,22 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		return nil, fmt.Errorf("unsupported language for wasmer: %s (supported: Python, JavaScript, Lua, Ruby, PHP, Shell)", opts.Language)
 	}
 
+	runDir, removeRunDir, err := i.runDir(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer removeRunDir()
+
 	// Write code to file
 	codeFilename := "main" + runtime.FileExt
-	codePath := filepath.Join(i.workDir, codeFilename)
+	codePath := filepath.Join(runDir, codeFilename)
 	if err := os.WriteFile(codePath, []byte(code), 0644); err != nil {
 		return nil, fmt.Errorf("write code file: %w", err)
 	}
 
 	// Write additional files
 	for path, content := range opts.Files {
-		fullPath := filepath.Join(i.workDir, path)
+		fullPath := filepath.Join(runDir, path)
 		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 			return nil, fmt.Errorf("create dir for %s: %w", path, err)
 		}
@@ -332,7 +368,16 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 	}
 
 	// Build wasmer command
-	runCmd := i.buildWasmerCmd(runtime, codeFilename)
+	runCmd := append(i.buildWasmerCmd(runtime, codeFilename), opts.Args...)
+
+	var before executor.FileSnapshot
+	if opts.TrackFileChanges {
+		snap, _, err := executor.SnapshotDir(runDir, "/workspace", executor.DefaultMaxTrackedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot workdir: %w", err)
+		}
+		before = snap
+	}
 
 	// Set timeout
 	execCtx := ctx
@@ -346,11 +391,13 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 
 	// Execute
 	cmd := exec.CommandContext(execCtx, runCmd[0], runCmd[1:]...)
-	cmd.Dir = i.workDir
+	cmd.Dir = runDir
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdoutW := executor.NewLimitedWriter(&stdout, opts.StdoutLimit)
+	stderrW := executor.NewLimitedWriter(&stderr, opts.StderrLimit)
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
 
 	if opts.Stdin != "" {
 		cmd.Stdin = strings.NewReader(opts.Stdin)
@@ -358,14 +405,15 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 
 	// Set environment
 	cmd.Env = append(os.Environ(), fmt.Sprintf("WASMER_CACHE_DIR=%s", i.config.CacheDir))
-	for k, v := range opts.Env {
+	for k, v := range i.env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
-	for k, v := range i.env {
+	// Per-execution variables come last so they override sandbox defaults
+	for k, v := range opts.Env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	err := cmd.Run()
+	err = cmd.Run()
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -377,13 +425,40 @@ func (i *Instance) Execute(ctx context.Context, code string, opts *executor.Exec
 		}
 	}
 
-	return &executor.ExecutionResult{
-		ExitCode: exitCode,
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		Duration: time.Since(start),
-		Language: opts.Language,
-	}, nil
+	result := &executor.ExecutionResult{
+		ExitCode:        exitCode,
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdoutW.Truncated(),
+		StderrTruncated: stderrW.Truncated(),
+		Duration:        time.Since(start),
+		Language:        opts.Language,
+	}
+
+	if opts.TrackFileChanges {
+		after, truncated, err := executor.SnapshotDir(runDir, "/workspace", executor.DefaultMaxTrackedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot workdir: %w", err)
+		}
+		changes, dropped := executor.DiffSnapshots(before, after, executor.DefaultMaxTrackedFiles)
+		result.SetFileChanges(changes, truncated || dropped)
+	}
+
+	return result, nil
+}
+
+// runDir returns the host directory a run executes in. With
+// opts.EphemeralWorkDir it creates a fresh subdirectory of the workspace,
+// removed by the returned func; WASI then exposes only that subdirectory.
+func (i *Instance) runDir(opts *executor.ExecutionOptions) (string, func(), error) {
+	if !opts.EphemeralWorkDir {
+		return i.workDir, func() {}, nil
+	}
+	dir, err := os.MkdirTemp(i.workDir, ".run-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create ephemeral workdir: %w", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
 }
 
 // buildWasmerCmd builds the wasmer command with all options.
@@ -421,6 +496,12 @@ func (i *Instance) buildWasmerCmd(runtime WasmRuntime, codeFilename string) []st
 
 // ExecuteStream runs code with streaming output.
 func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -437,18 +518,24 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		return fmt.Errorf("unsupported language for wasmer: %s", opts.Language)
 	}
 
+	runDir, removeRunDir, err := i.runDir(opts)
+	if err != nil {
+		return err
+	}
+	defer removeRunDir()
+
 	// Write code to file
 	codeFilename := "main" + runtime.FileExt
-	codePath := filepath.Join(i.workDir, codeFilename)
+	codePath := filepath.Join(runDir, codeFilename)
 	if err := os.WriteFile(codePath, []byte(code), 0644); err != nil {
 		return fmt.Errorf("write code file: %w", err)
 	}
 
 	// Build command
-	runCmd := i.buildWasmerCmd(runtime, codeFilename)
+	runCmd := append(i.buildWasmerCmd(runtime, codeFilename), opts.Args...)
 
 	cmd := exec.CommandContext(ctx, runCmd[0], runCmd[1:]...)
-	cmd.Dir = i.workDir
+	cmd.Dir = runDir
 	cmd.Env = append(os.Environ(), fmt.Sprintf("WASMER_CACHE_DIR=%s", i.config.CacheDir))
 
 	stdoutPipe, err := cmd.StdoutPipe()
@@ -464,6 +551,11 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		return fmt.Errorf("start command: %w", err)
 	}
 
+	// stdout and stderr share one output budget.
+	outputRate := ratelimit.NewByteRate(opts.MaxOutputRate)
+	stdout := outputRate.Reader(ctx, stdoutPipe)
+	stderr := outputRate.Reader(ctx, stderrPipe)
+
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -472,7 +564,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stdoutPipe.Read(buf)
+			n, err := stdout.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
 					Type:      executor.StreamStdout,
@@ -491,7 +583,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 		defer wg.Done()
 		buf := make([]byte, 1024)
 		for {
-			n, err := stderrPipe.Read(buf)
+			n, err := stderr.Read(buf)
 			if n > 0 {
 				handler(&executor.StreamEvent{
 					Type:      executor.StreamStderr,
@@ -517,6 +609,7 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
 		ExitCode:  exitCode,
+		Language:  opts.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -525,6 +618,12 @@ func (i *Instance) ExecuteStream(ctx context.Context, code string, opts *executo
 
 // RunCommand executes a shell command in the sandbox.
 func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*executor.CommandResult, error) {
+	ctx, done, err := i.provider.inflight.Start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	i.mu.RLock()
 	if i.stopped {
 		i.mu.RUnlock()
@@ -555,7 +654,7 @@ func (i *Instance) RunCommand(ctx context.Context, cmd string, args []string) (*
 	execCmd.Stdout = &stdout
 	execCmd.Stderr = &stderr
 
-	err := execCmd.Run()
+	err = execCmd.Run()
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
@@ -0,0 +1,12 @@
+package provider
+
+import "slices"
+
+// WrapCommand prefixes cmd with wrapper, e.g. a profiler or tracer, so
+// wrapper runs the program. It returns cmd unchanged when wrapper is empty.
+func WrapCommand(cmd, wrapper []string) []string {
+	if len(wrapper) == 0 || len(cmd) == 0 {
+		return cmd
+	}
+	return append(slices.Clone(wrapper), cmd...)
+}
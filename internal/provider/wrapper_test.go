@@ -0,0 +1,24 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapCommand(t *testing.T) {
+	cmd := []string{"python3", "/workspace/main.py"}
+	wrapper := []string{"valgrind", "--leak-check=full"}
+
+	got := WrapCommand(cmd, wrapper)
+	want := []string{"valgrind", "--leak-check=full", "python3", "/workspace/main.py"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WrapCommand = %v, want %v", got, want)
+	}
+	if len(wrapper) != 2 {
+		t.Errorf("WrapCommand modified wrapper: %v", wrapper)
+	}
+
+	if got := WrapCommand(cmd, nil); !reflect.DeepEqual(got, cmd) {
+		t.Errorf("WrapCommand without wrapper = %v, want %v", got, cmd)
+	}
+}
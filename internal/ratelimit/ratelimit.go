@@ -0,0 +1,167 @@
+// Package ratelimit throttles outgoing requests to rate-limited provider APIs
+// and output read from sandboxes.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter is a token bucket that blocks callers until a request may be sent
+// and tracks how many callers are currently waiting.
+type Limiter struct {
+	limiter *rate.Limiter
+	rps     float64
+	burst   int
+	waiting atomic.Int64
+}
+
+// NewLimiter creates a limiter allowing rps requests per second with the
+// given burst.
+func NewLimiter(rps float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{limiter: rate.NewLimiter(rate.Limit(rps), burst), rps: rps, burst: burst}
+}
+
+// Wait blocks until a request may proceed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	return l.limiter.Wait(ctx)
+}
+
+// QueueDepth returns the number of callers waiting for a token.
+func (l *Limiter) QueueDepth() int {
+	return int(l.waiting.Load())
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = make(map[[sha256.Size]byte]*Limiter)
+)
+
+// Shared returns the limiter registered under key, creating it with rps and
+// burst on first use. Sandboxes of the same provider share one limiter, so
+// a later caller asking for a different rps or burst under the same key gets
+// an error rather than the first caller's settings. Keys usually hold an API
+// credential, so only their SHA-256 is kept.
+func Shared(key string, rps float64, burst int) (*Limiter, error) {
+	sum := sha256.Sum256([]byte(key))
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	if l, ok := shared[sum]; ok {
+		if l.rps != rps || l.burst != burst {
+			return nil, fmt.Errorf("rate limit %g/s burst %d conflicts with %g/s burst %d already in use for these credentials",
+				rps, burst, l.rps, l.burst)
+		}
+		return l, nil
+	}
+	l := NewLimiter(rps, burst)
+	shared[sum] = l
+	return l, nil
+}
+
+// Transport is an http.RoundTripper that waits on a Limiter before each
+// request and retries 429 Too Many Requests responses.
+type Transport struct {
+	// Base is the underlying transport (default: http.DefaultTransport).
+	Base http.RoundTripper
+
+	// Limiter throttles requests. A nil Limiter disables throttling.
+	Limiter *Limiter
+
+	// MaxRetries is how many times a 429 response is retried.
+	MaxRetries int
+}
+
+// Client returns an HTTP client sending requests through a Transport with
+// limiter and maxRetries. The client is a copy of base, whose own transport
+// becomes the Transport's Base, so a caller's proxy, TLS settings, timeout and
+// test servers are kept; a nil base is a client with timeout.
+func Client(base *http.Client, timeout time.Duration, limiter *Limiter, maxRetries int) *http.Client {
+	c := &http.Client{Timeout: timeout}
+	if base != nil {
+		copied := *base
+		c = &copied
+	}
+	c.Transport = &Transport{Base: c.Transport, Limiter: limiter, MaxRetries: maxRetries}
+	return c
+}
+
+// retryBaseDelay is the first backoff when a 429 has no Retry-After header.
+const retryBaseDelay = 500 * time.Millisecond
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if t.Limiter != nil {
+			if err := t.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		r := req
+		if attempt > 0 {
+			r = req.Clone(ctx)
+			if req.Body != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		canRetry := req.Body == nil || req.GetBody != nil
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= t.MaxRetries || !canRetry {
+			return resp, nil
+		}
+
+		delay := retryDelay(resp, attempt)
+		resp.Body.Close()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay honours a Retry-After header in seconds and otherwise backs off
+// exponentially.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return retryBaseDelay << attempt
+}
@@ -0,0 +1,183 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterQueueDepth(t *testing.T) {
+	l := NewLimiter(1, 1)
+	ctx := context.Background()
+
+	// Consume the only token so the next caller has to wait.
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("Wait error: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		l.Wait(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for l.QueueDepth() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := l.QueueDepth(); got != 1 {
+		t.Errorf("QueueDepth() = %d, want 1", got)
+	}
+
+	<-done
+	if got := l.QueueDepth(); got != 0 {
+		t.Errorf("QueueDepth() after wait = %d, want 0", got)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := NewLimiter(0.001, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Wait should fail when the context is done")
+	}
+}
+
+func TestShared(t *testing.T) {
+	a, err := Shared("test:shared", 5, 5)
+	if err != nil {
+		t.Fatalf("Shared error: %v", err)
+	}
+	b, err := Shared("test:shared", 5, 5)
+	if err != nil {
+		t.Fatalf("Shared error: %v", err)
+	}
+	if a != b {
+		t.Error("Shared should return the same limiter for the same key")
+	}
+	if other, _ := Shared("test:other", 5, 5); other == a {
+		t.Error("Shared should return distinct limiters for distinct keys")
+	}
+
+	sharedMu.Lock()
+	l := shared[sha256.Sum256([]byte("test:shared"))]
+	sharedMu.Unlock()
+	if l != a {
+		t.Error("Shared should register the limiter under the key's SHA-256")
+	}
+}
+
+func TestSharedMismatch(t *testing.T) {
+	if _, err := Shared("test:mismatch", 5, 5); err != nil {
+		t.Fatalf("Shared error: %v", err)
+	}
+	if _, err := Shared("test:mismatch", 100, 5); err == nil {
+		t.Error("Shared should fail for a different rps under the same key")
+	}
+	if _, err := Shared("test:mismatch", 5, 100); err == nil {
+		t.Error("Shared should fail for a different burst under the same key")
+	}
+}
+
+func TestTransportRetries429(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make([]byte, 4)
+		n, _ := r.Body.Read(body)
+		if string(body[:n]) != "ping" {
+			t.Errorf("request body = %q, want %q", body[:n], "ping")
+		}
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{Limiter: NewLimiter(1000, 10), MaxRetries: 3}}
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("ping"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want 200", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestTransportGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{MaxRetries: 1}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("StatusCode = %d, want 429", resp.StatusCode)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestClient(t *testing.T) {
+	if c := Client(nil, time.Minute, nil, 0); c.Timeout != time.Minute {
+		t.Errorf("Timeout = %v, want 1m", c.Timeout)
+	}
+
+	var calls atomic.Int32
+	base := &http.Client{
+		Timeout: time.Second,
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+	c := Client(base, time.Minute, nil, 0)
+	if c.Timeout != time.Second {
+		t.Errorf("Timeout = %v, want the base client's 1s", c.Timeout)
+	}
+	if _, ok := c.Transport.(*Transport); !ok {
+		t.Errorf("Transport = %T, want *Transport", c.Transport)
+	}
+	if _, ok := base.Transport.(*Transport); ok {
+		t.Error("Client modified the base client")
+	}
+
+	resp, err := c.Get("http://example.invalid/")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("base transport called %d times, want 1", calls.Load())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
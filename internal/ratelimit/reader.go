@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// maxByteBurst caps how many bytes a ByteRate lets through at once, so
+// high rates still deliver output in bounded chunks.
+const maxByteBurst = 32 * 1024
+
+// ByteRate is a token bucket over bytes. Readers wrapped by the same
+// ByteRate share its budget, so stdout and stderr together stay under it.
+type ByteRate struct {
+	limiter *rate.Limiter
+	burst   int
+}
+
+// NewByteRate returns a ByteRate allowing bytesPerSec bytes per second, or
+// nil when bytesPerSec is not positive. A nil ByteRate does not throttle.
+func NewByteRate(bytesPerSec int64) *ByteRate {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := int(min(bytesPerSec, maxByteBurst))
+	return &ByteRate{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSec), burst),
+		burst:   burst,
+	}
+}
+
+// Reader wraps r so that reads wait for the bytes they return. Because the
+// wait delays the next read, a writer on the other end of a pipe is slowed
+// down too. Reads fail with ctx's error once ctx is done.
+func (b *ByteRate) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &byteRateReader{ctx: ctx, r: r, rate: b}
+}
+
+type byteRateReader struct {
+	ctx  context.Context
+	r    io.Reader
+	rate *ByteRate
+}
+
+func (r *byteRateReader) Read(p []byte) (int, error) {
+	if len(p) > r.rate.burst {
+		p = p[:r.rate.burst]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.rate.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
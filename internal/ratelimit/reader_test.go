@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// zeroReader returns zero bytes forever, like a program printing as fast
+// as it can.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestByteRateReader(t *testing.T) {
+	const bytesPerSec = 200 * 1024
+	r := NewByteRate(bytesPerSec).Reader(context.Background(), zeroReader{})
+
+	// The first maxByteBurst bytes are free; the rest take half a second.
+	total := int64(maxByteBurst + bytesPerSec/2)
+	start := time.Now()
+	if _, err := io.CopyN(io.Discard, r, total); err != nil {
+		t.Fatalf("CopyN error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("reading %d bytes at %d B/s took %v, want about 500ms", total, bytesPerSec, elapsed)
+	}
+}
+
+func TestByteRateShared(t *testing.T) {
+	const bytesPerSec = 100 * 1024
+	b := NewByteRate(bytesPerSec)
+	ctx := context.Background()
+	stdout, stderr := b.Reader(ctx, zeroReader{}), b.Reader(ctx, zeroReader{})
+
+	// Two readers draw from one budget: 32KB free, then the other 96KB
+	// at 100KB/s between them.
+	start := time.Now()
+	done := make(chan error, 2)
+	for _, r := range []io.Reader{stdout, stderr} {
+		go func() {
+			_, err := io.CopyN(io.Discard, r, 64*1024)
+			done <- err
+		}()
+	}
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Fatalf("CopyN error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("shared readers took %v, want about 960ms", elapsed)
+	}
+}
+
+func TestByteRateReaderCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := NewByteRate(1).Reader(ctx, zeroReader{})
+
+	_, err := io.Copy(io.Discard, r)
+	if err == nil {
+		t.Error("reads should fail once the context is done")
+	}
+}
+
+func TestNewByteRateUnlimited(t *testing.T) {
+	src := strings.NewReader("output")
+	if r := NewByteRate(0).Reader(context.Background(), src); r != src {
+		t.Error("a zero rate should not wrap the reader")
+	}
+}
@@ -0,0 +1,210 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// Verdict is the outcome of one judge case.
+type Verdict string
+
+// Judge verdicts, named as online judges name them.
+const (
+	// VerdictAccepted means the program succeeded and its output matched.
+	VerdictAccepted Verdict = "AC"
+
+	// VerdictWrongAnswer means the program succeeded but its output did
+	// not match.
+	VerdictWrongAnswer Verdict = "WA"
+
+	// VerdictTimeLimitExceeded means the program ran past the case's time
+	// limit.
+	VerdictTimeLimitExceeded Verdict = "TLE"
+
+	// VerdictRuntimeError means the program failed, such as with a
+	// non-zero exit code or a compile error.
+	VerdictRuntimeError Verdict = "RE"
+)
+
+// JudgeCase is one input of Sandbox.Judge and the output it expects.
+type JudgeCase struct {
+	// Name identifies the case in results. It defaults to the case's
+	// 1-based position.
+	Name string
+
+	// Stdin is fed to the program.
+	Stdin string
+
+	// Expected is the stdout the program must print.
+	Expected string
+
+	// Timeout is the case's time limit. Zero uses the judge's time limit.
+	Timeout time.Duration
+}
+
+// JudgeCaseResult is the verdict of one case.
+type JudgeCaseResult struct {
+	Name    string
+	Verdict Verdict
+
+	// Diff is a unified diff from the expected to the actual output on a
+	// wrong answer.
+	Diff string
+
+	// Result is the raw execution. It is nil when the case timed out
+	// before the provider returned a result.
+	Result *executor.ExecutionResult
+}
+
+// JudgeResult is the outcome of Sandbox.Judge.
+type JudgeResult struct {
+	// Verdict is VerdictAccepted if every case was accepted, or else the
+	// verdict of the first case that was not.
+	Verdict Verdict
+
+	// Cases holds the verdicts in case order. With WithStopOnFailure it
+	// ends at the first case that was not accepted.
+	Cases []JudgeCaseResult
+
+	// Passed is the number of accepted cases.
+	Passed int
+}
+
+// JudgeOption configures Judge.
+type JudgeOption func(*JudgeConfig)
+
+// JudgeConfig holds the settings of Judge.
+type JudgeConfig struct {
+	// TimeLimit applies to cases without their own timeout. Zero uses the
+	// execution's default timeout.
+	TimeLimit time.Duration
+
+	// StopOnFailure stops at the first case that is not accepted.
+	StopOnFailure bool
+
+	// Expect configures the output comparison and the execution options,
+	// as for ExecuteExpect.
+	Expect ExpectConfig
+}
+
+// WithTimeLimit sets the time limit of cases without their own timeout.
+func WithTimeLimit(d time.Duration) JudgeOption {
+	return func(c *JudgeConfig) {
+		c.TimeLimit = d
+	}
+}
+
+// WithStopOnFailure stops judging at the first case that is not accepted,
+// leaving the rest unrun.
+func WithStopOnFailure() JudgeOption {
+	return func(c *JudgeConfig) {
+		c.StopOnFailure = true
+	}
+}
+
+// WithJudgeExpect configures the output comparison, such as with
+// WithTrimTrailingSpace, and passes WithExecuteOptions to every run.
+func WithJudgeExpect(opts ...ExpectOption) JudgeOption {
+	return func(c *JudgeConfig) {
+		for _, opt := range opts {
+			opt(&c.Expect)
+		}
+	}
+}
+
+// Judge runs code once per case, feeding it the case's stdin, and compares
+// its stdout with the case's expected output. Failing cases are verdicts,
+// not errors; an error means a run could not be judged, such as when the
+// sandbox is stopped or ctx is canceled.
+func (s *sandbox) Judge(ctx context.Context, code string, cases []JudgeCase, opts ...JudgeOption) (*JudgeResult, error) {
+	cfg := &JudgeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cases) == 0 {
+		return nil, NewError("judge", s.providerName, s.instance.ID(), fmt.Errorf("no cases: %w", ErrInvalidConfiguration))
+	}
+	if cfg.TimeLimit < 0 {
+		return nil, NewError("judge", s.providerName, s.instance.ID(),
+			fmt.Errorf("time limit %v: %w", cfg.TimeLimit, ErrInvalidConfiguration))
+	}
+	if tol := cfg.Expect.FloatTolerance; tol < 0 || math.IsNaN(tol) {
+		return nil, NewError("judge", s.providerName, s.instance.ID(),
+			fmt.Errorf("float tolerance %v: %w", tol, ErrInvalidConfiguration))
+	}
+
+	result := &JudgeResult{Verdict: VerdictAccepted}
+	for i, c := range cases {
+		if c.Name == "" {
+			c.Name = fmt.Sprint(i + 1)
+		}
+		cr, err := s.judgeCase(ctx, code, c, cfg)
+		if err != nil {
+			return nil, err
+		}
+		result.Cases = append(result.Cases, *cr)
+		if cr.Verdict == VerdictAccepted {
+			result.Passed++
+			continue
+		}
+		if result.Verdict == VerdictAccepted {
+			result.Verdict = cr.Verdict
+		}
+		if cfg.StopOnFailure {
+			break
+		}
+	}
+	return result, nil
+}
+
+// judgeCase runs code on one case and returns its verdict.
+func (s *sandbox) judgeCase(ctx context.Context, code string, c JudgeCase, cfg *JudgeConfig) (*JudgeCaseResult, error) {
+	limit := c.Timeout
+	if limit <= 0 {
+		limit = cfg.TimeLimit
+	}
+
+	opts := append(slices.Clip(cfg.Expect.ExecuteOptions), WithStdin(c.Stdin))
+	caseCtx := ctx
+	if limit > 0 {
+		opts = append(opts, WithExecutionTimeout(limit))
+		var cancel context.CancelFunc
+		caseCtx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+
+	cr := &JudgeCaseResult{Name: c.Name}
+	result, err := s.Execute(caseCtx, code, opts...)
+	if err != nil {
+		if ctx.Err() == nil && (caseCtx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrExecutionTimeout)) {
+			cr.Verdict = VerdictTimeLimitExceeded
+			return cr, nil
+		}
+		return nil, err
+	}
+	cr.Result = result
+
+	switch {
+	case limit > 0 && result.Duration > limit:
+		cr.Verdict = VerdictTimeLimitExceeded
+	case !result.Success():
+		cr.Verdict = VerdictRuntimeError
+	default:
+		actual := result.Stdout
+		if cfg.Expect.CombinedOutput {
+			actual += result.Stderr
+		}
+		match, diff := cfg.Expect.comparator().Compare(c.Expected, actual)
+		cr.Verdict, cr.Diff = VerdictWrongAnswer, diff
+		if match {
+			cr.Verdict = VerdictAccepted
+		}
+	}
+	return cr, nil
+}
@@ -0,0 +1,138 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// judgeExec doubles its input, except for "slow", which runs for the
+// time limit and then some, and "crash", which exits 1.
+func judgeExec(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+	switch in := strings.TrimSpace(opts.Stdin); in {
+	case "slow":
+		return &executor.ExecutionResult{Duration: opts.Timeout + time.Second}
+	case "crash":
+		return &executor.ExecutionResult{ExitCode: 1, Stderr: "Traceback (most recent call last):\n"}
+	default:
+		n, _ := strconv.Atoi(in)
+		return &executor.ExecutionResult{Stdout: fmt.Sprintf("%d\n", 2*n), Duration: 10 * time.Millisecond}
+	}
+}
+
+func TestSandboxJudge(t *testing.T) {
+	var timeouts []time.Duration
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		timeouts = append(timeouts, opts.Timeout)
+		return judgeExec(code, opts)
+	})
+	ctx := context.Background()
+
+	cases := []JudgeCase{
+		{Name: "double", Stdin: "2\n", Expected: "4\n"},
+		{Stdin: "3\n", Expected: "7\n"},
+		{Stdin: "slow\n", Timeout: 2 * time.Second},
+		{Stdin: "crash\n"},
+		{Stdin: "5\n", Expected: "10"},
+	}
+	jr, err := sb.Judge(ctx, "print(2 * int(input()))", cases,
+		WithTimeLimit(time.Second),
+		WithJudgeExpect(WithTrimTrailingSpace(), WithExecuteOptions(WithLanguage("Python"))),
+	)
+	if err != nil {
+		t.Fatalf("Judge() error = %v", err)
+	}
+
+	want := []struct {
+		name    string
+		verdict Verdict
+	}{
+		{"double", VerdictAccepted},
+		{"2", VerdictWrongAnswer},
+		{"3", VerdictTimeLimitExceeded},
+		{"4", VerdictRuntimeError},
+		{"5", VerdictAccepted},
+	}
+	if len(jr.Cases) != len(want) {
+		t.Fatalf("judged %d cases, want %d", len(jr.Cases), len(want))
+	}
+	for i, w := range want {
+		if c := jr.Cases[i]; c.Name != w.name || c.Verdict != w.verdict {
+			t.Errorf("case %d = %s %s, want %s %s", i, c.Name, c.Verdict, w.name, w.verdict)
+		}
+	}
+	if jr.Verdict != VerdictWrongAnswer || jr.Passed != 2 {
+		t.Errorf("result = %s with %d passed, want WA with 2 passed", jr.Verdict, jr.Passed)
+	}
+	if diff := jr.Cases[1].Diff; !strings.Contains(diff, "-7") || !strings.Contains(diff, "+6") {
+		t.Errorf("wrong answer diff =\n%s", diff)
+	}
+	if got := timeouts[:3]; got[0] != time.Second || got[2] != 2*time.Second {
+		t.Errorf("timeouts = %v, want the judge's limit and the case's own", got)
+	}
+}
+
+func TestSandboxJudgeStopOnFailure(t *testing.T) {
+	runs := 0
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		runs++
+		return judgeExec(code, opts)
+	})
+
+	jr, err := sb.Judge(context.Background(), "print(2 * int(input()))", []JudgeCase{
+		{Stdin: "1", Expected: "2\n"},
+		{Stdin: "crash"},
+		{Stdin: "3", Expected: "6\n"},
+	}, WithStopOnFailure(), WithJudgeExpect(WithExecuteOptions(WithLanguage("Python"))))
+	if err != nil {
+		t.Fatalf("Judge() error = %v", err)
+	}
+	if runs != 2 || len(jr.Cases) != 2 || jr.Verdict != VerdictRuntimeError {
+		t.Errorf("ran %d cases, result %s with %d cases, want to stop at the runtime error", runs, jr.Verdict, len(jr.Cases))
+	}
+}
+
+func TestSandboxJudgeTimeoutError(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:      "test-instance-123",
+		status:  provider.StatusRunning,
+		execErr: fmt.Errorf("execution timed out after 1s: %w", context.DeadlineExceeded),
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	jr, err := sb.Judge(ctx, "while True: pass", []JudgeCase{{Stdin: "1"}},
+		WithTimeLimit(time.Second), WithJudgeExpect(WithExecuteOptions(WithLanguage("Python"))))
+	if err != nil {
+		t.Fatalf("Judge() error = %v", err)
+	}
+	if c := jr.Cases[0]; c.Verdict != VerdictTimeLimitExceeded || c.Result != nil {
+		t.Errorf("case = %+v, want a time limit verdict", c)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := sb.Judge(canceled, "print(1)", []JudgeCase{{}}); err == nil {
+		t.Error("Judge() with a canceled context should fail")
+	}
+	if _, err := sb.Judge(ctx, "print(1)", nil); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Judge() without cases error = %v, want ErrInvalidConfiguration", err)
+	}
+}
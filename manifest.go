@@ -0,0 +1,246 @@
+package sindoq
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"slices"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/event"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ManifestVersion is the version of the ExecutionManifest JSON schema.
+const ManifestVersion = 1
+
+// DefaultManifestOutputLimit is the number of bytes of stdout and stderr
+// kept inline in an ExecutionManifest.
+const DefaultManifestOutputLimit = 64 << 10
+
+// scratchDir is where the sandbox keeps files of its own, such as output
+// saved by ExecuteWithManifest, relative to the working directory.
+const scratchDir = ".sindoq"
+
+// ExecutionManifest is a self-contained summary of one execution, written
+// by ExecuteWithManifest for storing or handing to other tools. Fields the
+// provider cannot report are left zero.
+type ExecutionManifest struct {
+	// Version is the schema version, ManifestVersion.
+	Version int `json:"version"`
+
+	// ID identifies the execution.
+	ID string `json:"id"`
+
+	// Time is when the execution started.
+	Time time.Time `json:"time"`
+
+	// Provider and SandboxID identify where the code ran.
+	Provider  string `json:"provider"`
+	SandboxID string `json:"sandbox_id"`
+
+	// Language is the language the code ran as.
+	Language string `json:"language"`
+
+	// ExitCode is the program's exit code.
+	ExitCode int `json:"exit_code"`
+
+	// Stdout and Stderr are the program's output.
+	Stdout ManifestOutput `json:"stdout"`
+	Stderr ManifestOutput `json:"stderr"`
+
+	// Artifacts are the files the run created or modified, and any
+	// artifacts the provider reported.
+	Artifacts []ManifestArtifact `json:"artifacts,omitempty"`
+
+	// Runtime describes the runtime the code ran on.
+	Runtime ManifestRuntime `json:"runtime"`
+
+	// Resources is what the run used.
+	Resources ManifestResources `json:"resources"`
+
+	// Timeline lists the execution events with their offset from Time.
+	Timeline []RecordedEvent `json:"timeline"`
+}
+
+// ManifestOutput is one output stream of an ExecutionManifest.
+type ManifestOutput struct {
+	// Data is the output, cut to the manifest's output limit.
+	Data string `json:"data"`
+
+	// Size is the length of the output in bytes before the manifest cut
+	// it.
+	Size int `json:"size"`
+
+	// Truncated reports whether Data is shorter than the program's output,
+	// because of the manifest's limit or a WithStdoutLimit or
+	// WithStderrLimit on the run.
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Path is the sandbox file holding the full output when it was
+	// truncated and could be saved.
+	Path string `json:"path,omitempty"`
+}
+
+// ManifestArtifact is a file produced by an execution.
+type ManifestArtifact struct {
+	// Path is the file path within the sandbox.
+	Path string `json:"path"`
+
+	// Kind is "created", "modified" or "deleted" for tracked file
+	// changes, or "artifact" for artifacts reported by the provider.
+	Kind string `json:"kind"`
+
+	// Size is the file size in bytes.
+	Size int64 `json:"size"`
+
+	// SHA256 is the hex-encoded SHA-256 of the content. It is empty when
+	// the content could not be read.
+	SHA256 string `json:"sha256,omitempty"`
+
+	// MIMEType is the content type, if the provider reported one.
+	MIMEType string `json:"mime_type,omitempty"`
+}
+
+// ManifestRuntime is the serializable form of the runtime an execution
+// used.
+type ManifestRuntime struct {
+	Image      string   `json:"image,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	RunCommand []string `json:"run_command,omitempty"`
+}
+
+// ManifestResources is what an execution used.
+type ManifestResources struct {
+	Duration   time.Duration `json:"duration"`
+	CPUTime    time.Duration `json:"cpu_time,omitempty"`
+	DiskUsedMB int64         `json:"disk_used_mb,omitempty"`
+}
+
+// WithManifestOutputLimit sets how many bytes of stdout and stderr
+// ExecuteWithManifest keeps inline. Zero uses DefaultManifestOutputLimit.
+func WithManifestOutputLimit(n int) ExecuteOption {
+	return func(c *ExecuteConfig) {
+		c.ManifestOutputLimit = n
+	}
+}
+
+// ExecuteWithManifest runs code and summarizes the run in an
+// ExecutionManifest that marshals cleanly to JSON. File changes are
+// tracked to list the run's artifacts, hashed through the sandbox's file
+// system. Output past the limit set by WithManifestOutputLimit is cut from
+// the manifest and saved in full under .sindoq in the working directory,
+// where the file system allows it.
+func (s *sandbox) ExecuteWithManifest(ctx context.Context, code string, opts ...ExecuteOption) (*ExecutionManifest, error) {
+	execCfg := DefaultExecuteConfig()
+	for _, opt := range opts {
+		opt(execCfg)
+	}
+	limit := execCfg.ManifestOutputLimit
+	if limit <= 0 {
+		limit = DefaultManifestOutputLimit
+	}
+
+	start := time.Now().UTC()
+	result, err := s.Execute(ctx, code, append(slices.Clip(opts), WithTrackFileChanges())...)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := s.RuntimeInfo()
+	m := &ExecutionManifest{
+		Version:   ManifestVersion,
+		ID:        newExecutionID(),
+		Time:      start,
+		Provider:  s.providerName,
+		SandboxID: s.instance.ID(),
+		Language:  result.Language,
+		ExitCode:  result.ExitCode,
+		Runtime: ManifestRuntime{
+			Image:      rt.Image,
+			Language:   rt.Language,
+			Version:    rt.Version,
+			RunCommand: rt.RunCommand,
+		},
+		Resources: ManifestResources{
+			Duration:   result.Duration,
+			CPUTime:    result.CPUTime,
+			DiskUsedMB: result.DiskUsedMB,
+		},
+		Timeline: []RecordedEvent{
+			{Type: event.EventExecutionStarted},
+			{Type: event.EventExecutionComplete, Offset: time.Since(start)},
+		},
+	}
+
+	m.Artifacts = s.manifestArtifacts(ctx, execCfg.WorkDir, result)
+
+	dir := path.Join(execCfg.WorkDir, scratchDir)
+	m.Stdout = s.manifestOutput(ctx, result.Stdout, limit, path.Join(dir, m.ID+".stdout"))
+	m.Stdout.Truncated = m.Stdout.Truncated || result.StdoutTruncated
+	m.Stderr = s.manifestOutput(ctx, result.Stderr, limit, path.Join(dir, m.ID+".stderr"))
+	m.Stderr.Truncated = m.Stderr.Truncated || result.StderrTruncated
+	for _, out := range []ManifestOutput{m.Stdout, m.Stderr} {
+		if out.Path != "" {
+			m.Timeline = append(m.Timeline, RecordedEvent{Type: event.EventFileWritten, Offset: time.Since(start)})
+		}
+	}
+	return m, nil
+}
+
+// manifestOutput cuts out to limit bytes, saving the full output to file
+// when it is cut.
+func (s *sandbox) manifestOutput(ctx context.Context, out string, limit int, file string) ManifestOutput {
+	mo := ManifestOutput{Size: len(out)}
+	if mo.Data, mo.Truncated = executor.CutOutput(out, int64(limit)); !mo.Truncated {
+		return mo
+	}
+
+	fsys := s.instance.FileSystem()
+	if fsys == nil {
+		return mo
+	}
+	if err := fsys.Write(ctx, file, []byte(out)); err != nil {
+		if s.config.Logger != nil {
+			s.config.Logger.Warn("failed to save manifest output", "path", file, "error", err)
+		}
+		return mo
+	}
+	mo.Path = file
+	return mo
+}
+
+// manifestArtifacts lists the files result created or modified with their
+// sizes and hashes, followed by the artifacts the provider reported.
+func (s *sandbox) manifestArtifacts(ctx context.Context, workDir string, result *executor.ExecutionResult) []ManifestArtifact {
+	var artifacts []ManifestArtifact
+	fsys := s.instance.FileSystem()
+	for _, fc := range result.FileChanges {
+		a := ManifestArtifact{Path: fc.Path, Kind: string(fc.Kind)}
+		if !path.IsAbs(a.Path) {
+			a.Path = path.Join(workDir, a.Path)
+		}
+		if fc.Kind != executor.FileDeleted && fsys != nil {
+			if data, err := fsys.Read(ctx, a.Path); err == nil {
+				a.Size, a.SHA256 = int64(len(data)), sha256Hex(data)
+			}
+		}
+		artifacts = append(artifacts, a)
+	}
+	for _, art := range result.Artifacts {
+		a := ManifestArtifact{Path: art.Path, Kind: "artifact", Size: art.Size, MIMEType: art.MIMEType}
+		if art.Data != nil {
+			a.Size, a.SHA256 = int64(len(art.Data)), sha256Hex(art.Data)
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts
+}
+
+// sha256Hex returns the hex-encoded SHA-256 of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package sindoq
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestSandboxExecuteWithManifest(t *testing.T) {
+	memfs := &memFileSystem{files: map[string][]byte{
+		"/workspace/out.txt": []byte("result\n"),
+	}}
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys:   memfs,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			if !opts.TrackFileChanges {
+				t.Error("ExecuteWithManifest should track file changes")
+			}
+			return &executor.ExecutionResult{
+				Stdout:      "héllo, world\n",
+				Stderr:      "warning\n",
+				Language:    opts.Language,
+				Duration:    150 * time.Millisecond,
+				DiskUsedMB:  3,
+				FileChanges: []executor.FileChange{{Path: "out.txt", Kind: executor.FileCreated}, {Path: "/workspace/old.txt", Kind: executor.FileDeleted}},
+			}
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	m, err := sb.ExecuteWithManifest(ctx, "print('héllo, world')", WithLanguage("Python"), WithManifestOutputLimit(2))
+	if err != nil {
+		t.Fatalf("ExecuteWithManifest() error = %v", err)
+	}
+
+	if m.Language != "Python" || m.Provider != "mock" || m.SandboxID != sb.ID() || m.Resources.Duration != 150*time.Millisecond || m.Resources.DiskUsedMB != 3 {
+		t.Errorf("manifest = %+v, want the run's language, provider and resources", m)
+	}
+	// "é" straddles the limit, so only "h" is kept.
+	if m.Stdout.Data != "h" || !m.Stdout.Truncated || m.Stdout.Size != len("héllo, world\n") {
+		t.Errorf("stdout = %+v, want a truncated copy", m.Stdout)
+	}
+	if full := string(memfs.files[m.Stdout.Path]); full != "héllo, world\n" || !strings.HasPrefix(m.Stdout.Path, "/workspace/.sindoq/") {
+		t.Errorf("full stdout at %q = %q, want it saved in the workdir", m.Stdout.Path, full)
+	}
+	want := []ManifestArtifact{
+		{Path: "/workspace/out.txt", Kind: "created", Size: 7, SHA256: sha256Hex([]byte("result\n"))},
+		{Path: "/workspace/old.txt", Kind: "deleted"},
+	}
+	if !reflect.DeepEqual(m.Artifacts, want) {
+		t.Errorf("artifacts = %+v, want %+v", m.Artifacts, want)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got ExecutionManifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(&got, m) {
+		t.Errorf("manifest after a JSON round trip = %+v, want %+v", got, *m)
+	}
+}
@@ -0,0 +1,116 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// MultiOption configures ExecuteMulti.
+type MultiOption func(*MultiConfig)
+
+// MultiConfig holds the settings of ExecuteMulti.
+type MultiConfig struct {
+	// SandboxOptions configure every sandbox. WithProvider is overridden
+	// by each provider in turn, and a With*Config option only applies to
+	// its own provider.
+	SandboxOptions []Option
+
+	// ExecuteOptions are passed to every run.
+	ExecuteOptions []ExecuteOption
+}
+
+// WithMultiSandboxOptions configures the sandbox created on every provider.
+func WithMultiSandboxOptions(opts ...Option) MultiOption {
+	return func(c *MultiConfig) {
+		c.SandboxOptions = append(c.SandboxOptions, opts...)
+	}
+}
+
+// WithMultiExecuteOptions passes opts to the run on every provider.
+func WithMultiExecuteOptions(opts ...ExecuteOption) MultiOption {
+	return func(c *MultiConfig) {
+		c.ExecuteOptions = append(c.ExecuteOptions, opts...)
+	}
+}
+
+// ExecuteMulti runs code on each of providers at once, in a sandbox of its
+// own, and returns the results keyed by provider for comparing how they
+// behave. Every provider has an entry: one that could not run the code has
+// a result with only Error set. A provider that does not list the language
+// set with WithLanguage is skipped without creating a sandbox, with an
+// Error matching ErrLanguageUnsupportedByProvider. Every sandbox is stopped
+// before ExecuteMulti returns. The error is only for invalid arguments.
+func ExecuteMulti(ctx context.Context, code string, providers []string, opts ...MultiOption) (map[string]*executor.ExecutionResult, error) {
+	cfg := &MultiConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(providers) == 0 {
+		return nil, NewError("executeMulti", "", "", fmt.Errorf("no providers: %w", ErrInvalidConfiguration))
+	}
+	for i, name := range providers {
+		if slices.Contains(providers[:i], name) {
+			return nil, NewError("executeMulti", name, "", fmt.Errorf("provider listed twice: %w", ErrInvalidConfiguration))
+		}
+	}
+
+	execCfg := DefaultExecuteConfig()
+	for _, opt := range cfg.ExecuteOptions {
+		opt(execCfg)
+	}
+	var language string
+	if !execCfg.SkipLanguageCheck {
+		language = execCfg.Language
+		if info, ok := langdetect.GetRuntimeInfo(language); ok {
+			language = info.Language
+		}
+	}
+
+	results := make(map[string]*executor.ExecutionResult, len(providers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := executeOn(ctx, name, code, language, cfg)
+			if err != nil {
+				result = &executor.ExecutionResult{Error: err}
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// executeOn runs code in a new sandbox on the named provider and stops the
+// sandbox.
+func executeOn(ctx context.Context, name, code, language string, cfg *MultiConfig) (*executor.ExecutionResult, error) {
+	// Providers that need a config to be built cannot be probed; Execute
+	// checks the language once the sandbox exists.
+	if caps, err := factory.ProbeCapabilities(name); err == nil && language != "" &&
+		len(caps.SupportedLanguages) > 0 && !caps.SupportsLanguage(language) {
+		return nil, NewError("executeMulti", name, "", &LanguageUnsupportedError{
+			Language:  language,
+			Provider:  name,
+			Supported: caps.SupportedLanguages,
+		})
+	}
+
+	sb, err := Create(ctx, append(slices.Clip(cfg.SandboxOptions), WithProvider(name))...)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.Stop(context.WithoutCancel(ctx))
+
+	return sb.Execute(ctx, code, cfg.ExecuteOptions...)
+}
@@ -0,0 +1,97 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/internal/provider/wasmer"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestExecuteMulti(t *testing.T) {
+	createErr := errors.New("daemon not running")
+	mocks := map[string]*mockProvider{
+		"mock-a":    {name: "mock-a", instance: &mockInstance{id: "a", status: provider.StatusRunning, execResult: &executor.ExecutionResult{Stdout: "0.30000000000000004\n"}}},
+		"mock-b":    {name: "mock-b", instance: &mockInstance{id: "b", status: provider.StatusRunning, execResult: &executor.ExecutionResult{Stdout: "0.3\n"}}},
+		"mock-fail": {name: "mock-fail", createErr: createErr},
+	}
+	for name, mp := range mocks {
+		factory.Register(name, func(config any) (provider.Provider, error) {
+			return mp, nil
+		})
+		defer factory.Unregister(name)
+	}
+	// Wasmer has no Go runtime, so it is skipped before its binary is needed.
+	wcfg := wasmer.DefaultConfig()
+	wcfg.CacheDir = t.TempDir()
+	if real, ok := factory.DefaultRegistry.GetConstructor("wasmer"); ok {
+		defer factory.Register("wasmer", real)
+	}
+	factory.Register("wasmer", func(config any) (provider.Provider, error) {
+		return wasmer.New(wcfg)
+	})
+	defer factory.Unregister("wasmer")
+
+	results, err := ExecuteMulti(context.Background(), "fmt.Println(0.1 + 0.2)", []string{"mock-a", "mock-b", "mock-fail", "wasmer"},
+		WithMultiExecuteOptions(WithLanguage("go")))
+	if err != nil {
+		t.Fatalf("ExecuteMulti() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got results for %d providers, want 4", len(results))
+	}
+
+	for name, want := range map[string]string{"mock-a": "0.30000000000000004\n", "mock-b": "0.3\n"} {
+		if r := results[name]; r.Error != nil || r.Stdout != want {
+			t.Errorf("%s result = %+v, want stdout %q", name, r, want)
+		}
+		if inst := mocks[name].instance; !inst.stopped || inst.lastOpts.Language != "go" {
+			t.Errorf("%s: stopped = %v, language = %q, want a stopped sandbox that ran Go", name, inst.stopped, inst.lastOpts.Language)
+		}
+	}
+	if r := results["mock-fail"]; !errors.Is(r.Error, createErr) {
+		t.Errorf("mock-fail error = %v, want the create error", r.Error)
+	}
+	var unsupported *LanguageUnsupportedError
+	if r := results["wasmer"]; !errors.As(r.Error, &unsupported) || unsupported.Language != "Go" {
+		t.Errorf("wasmer error = %v, want a skip for Go", r.Error)
+	}
+}
+
+func TestExecuteMultiProviderConfig(t *testing.T) {
+	mp := &mockProvider{name: "mock-a", instance: &mockInstance{id: "a", status: provider.StatusRunning}}
+	var configs []any
+	factory.Register("mock-a", func(config any) (provider.Provider, error) {
+		configs = append(configs, config)
+		return mp, nil
+	})
+	defer factory.Unregister("mock-a")
+
+	results, err := ExecuteMulti(context.Background(), "print(1)", []string{"mock-a"},
+		WithMultiSandboxOptions(WithDockerConfig(DockerConfig{Host: "tcp://docker.internal:2376"})),
+		WithMultiExecuteOptions(WithLanguage("Python")))
+	if err != nil {
+		t.Fatalf("ExecuteMulti() error = %v", err)
+	}
+	if r := results["mock-a"]; r.Error != nil {
+		t.Errorf("mock-a error = %v, want the Docker config left out", r.Error)
+	}
+	for _, config := range configs {
+		if config != nil {
+			t.Errorf("mock-a got config %#v, want the Docker config left out", config)
+		}
+	}
+}
+
+func TestExecuteMultiInvalid(t *testing.T) {
+	ctx := context.Background()
+	if _, err := ExecuteMulti(ctx, "print(1)", nil); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteMulti() without providers error = %v, want ErrInvalidConfiguration", err)
+	}
+	if _, err := ExecuteMulti(ctx, "print(1)", []string{"mock", "mock"}); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteMulti() with a repeated provider error = %v, want ErrInvalidConfiguration", err)
+	}
+}
@@ -0,0 +1,80 @@
+package sindoq
+
+import (
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// outputLimiter is a StreamHandler that cuts stdout and stderr off at their
+// own limits, dropping what follows, and reports the cuts on the
+// StreamComplete event. Compiler output counts against the stderr limit,
+// as ExecuteTo writes it to stderr.
+type outputLimiter struct {
+	next executor.StreamHandler
+
+	mu             sync.Mutex
+	stdout, stderr streamLimit
+}
+
+// streamLimit tracks one output stream against its limit.
+type streamLimit struct {
+	limit     int64
+	used      int64
+	truncated bool
+}
+
+func newOutputLimiter(stdoutLimit, stderrLimit int64, next executor.StreamHandler) *outputLimiter {
+	return &outputLimiter{
+		next:   next,
+		stdout: streamLimit{limit: stdoutLimit},
+		stderr: streamLimit{limit: stderrLimit},
+	}
+}
+
+func (l *outputLimiter) handle(e *executor.StreamEvent) error {
+	l.mu.Lock()
+	switch e.Type {
+	case executor.StreamStdout, executor.StreamStderr, executor.StreamCompileStdout, executor.StreamCompileStderr:
+		sl := &l.stderr
+		if e.Type == executor.StreamStdout {
+			sl = &l.stdout
+		}
+		data := sl.cut(e.Data)
+		if data == "" && e.Data != "" {
+			l.mu.Unlock()
+			return nil
+		}
+		e.Data = data
+	case executor.StreamComplete:
+		e.StdoutTruncated = e.StdoutTruncated || l.stdout.truncated
+		e.StderrTruncated = e.StderrTruncated || l.stderr.truncated
+	}
+	l.mu.Unlock()
+	return l.next(e)
+}
+
+// cut returns the part of data that fits in the stream's limit.
+func (s *streamLimit) cut(data string) string {
+	if s.limit <= 0 {
+		return data
+	}
+	if s.truncated || s.used >= s.limit {
+		s.truncated = s.truncated || data != ""
+		return ""
+	}
+	kept, cut := executor.CutOutput(data, s.limit-s.used)
+	s.used += int64(len(kept))
+	s.truncated = cut
+	return kept
+}
+
+// limitOutput cuts the output of a buffered result to the limits, for
+// providers that do not apply them while reading.
+func limitOutput(result *executor.ExecutionResult, stdoutLimit, stderrLimit int64) {
+	var cut bool
+	result.Stdout, cut = executor.CutOutput(result.Stdout, stdoutLimit)
+	result.StdoutTruncated = result.StdoutTruncated || cut
+	result.Stderr, cut = executor.CutOutput(result.Stderr, stderrLimit)
+	result.StderrTruncated = result.StderrTruncated || cut
+}
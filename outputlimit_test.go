@@ -0,0 +1,182 @@
+package sindoq
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestOutputLimiter(t *testing.T) {
+	var got []executor.StreamEvent
+	l := newOutputLimiter(6, 0, func(e *executor.StreamEvent) error {
+		got = append(got, *e)
+		return nil
+	})
+	for _, e := range []executor.StreamEvent{
+		{Type: executor.StreamStdout, Data: "abcd"},
+		{Type: executor.StreamStderr, Data: "error one\n"},
+		{Type: executor.StreamStdout, Data: "efgh"},
+		{Type: executor.StreamStdout, Data: "ijkl"},
+		{Type: executor.StreamStderr, Data: "error two\n"},
+		{Type: executor.StreamComplete},
+	} {
+		l.handle(&e)
+	}
+
+	var stdout, stderr strings.Builder
+	for _, e := range got {
+		switch e.Type {
+		case executor.StreamStdout:
+			stdout.WriteString(e.Data)
+		case executor.StreamStderr:
+			stderr.WriteString(e.Data)
+		}
+	}
+	if stdout.String() != "abcdef" || stderr.String() != "error one\nerror two\n" {
+		t.Errorf("stdout = %q, stderr = %q, want stdout cut at 6 bytes and stderr whole", stdout.String(), stderr.String())
+	}
+	if len(got) != 5 {
+		t.Errorf("forwarded %d events, want the fully dropped one skipped", len(got))
+	}
+	if c := got[len(got)-1]; !c.StdoutTruncated || c.StderrTruncated {
+		t.Errorf("complete event truncated stdout = %v, stderr = %v, want true, false", c.StdoutTruncated, c.StderrTruncated)
+	}
+}
+
+func TestOutputLimiterCompileOutput(t *testing.T) {
+	var stdout, stderr strings.Builder
+	var complete executor.StreamEvent
+	l := newOutputLimiter(0, 8, func(e *executor.StreamEvent) error {
+		switch e.Type {
+		case executor.StreamStdout:
+			stdout.WriteString(e.Data)
+		case executor.StreamStderr, executor.StreamCompileStdout, executor.StreamCompileStderr:
+			stderr.WriteString(e.Data)
+		case executor.StreamComplete:
+			complete = *e
+		}
+		return nil
+	})
+	for _, e := range []executor.StreamEvent{
+		{Type: executor.StreamCompileStdout, Data: "note\n"},
+		{Type: executor.StreamCompileStderr, Data: strings.Repeat("error\n", 1000)},
+		{Type: executor.StreamStdout, Data: "ok\n"},
+		{Type: executor.StreamComplete},
+	} {
+		l.handle(&e)
+	}
+
+	if stdout.String() != "ok\n" || stderr.String() != "note\nerr" {
+		t.Errorf("stdout = %q, stderr = %q, want compiler output cut at the stderr limit", stdout.String(), stderr.String())
+	}
+	if !complete.StderrTruncated || complete.StdoutTruncated {
+		t.Errorf("complete event truncated stdout = %v, stderr = %v, want false, true", complete.StdoutTruncated, complete.StderrTruncated)
+	}
+}
+
+func TestSandboxExecuteOutputLimits(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			if opts.StdoutLimit != 1000 || opts.StderrLimit != 10 {
+				t.Errorf("limits = %d, %d, want them passed to the provider", opts.StdoutLimit, opts.StderrLimit)
+			}
+			return &executor.ExecutionResult{
+				Stdout: strings.Repeat("data\n", 100),
+				Stderr: strings.Repeat("warning\n", 100),
+			}
+		},
+		streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+			for range 100 {
+				handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "data\n"})
+				handler(&executor.StreamEvent{Type: executor.StreamStderr, Data: "warning\n"})
+			}
+			return handler(&executor.StreamEvent{Type: executor.StreamComplete})
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	opts := []ExecuteOption{WithLanguage("Python"), WithStdoutLimit(1000), WithStderrLimit(10)}
+	result, err := sb.Execute(ctx, "print('data')", opts...)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(result.Stdout) != 500 || result.StdoutTruncated {
+		t.Errorf("stdout = %d bytes, truncated %v, want all 500 bytes", len(result.Stdout), result.StdoutTruncated)
+	}
+	if result.Stderr != "warning\nwa" || !result.StderrTruncated {
+		t.Errorf("stderr = %q, truncated %v, want it cut at 10 bytes", result.Stderr, result.StderrTruncated)
+	}
+
+	var stdout strings.Builder
+	var stderr strings.Builder
+	result, err = sb.ExecuteTo(ctx, "print('data')", &stdout, &stderr, opts...)
+	if err != nil {
+		t.Fatalf("ExecuteTo() error = %v", err)
+	}
+	if stdout.Len() != 500 || result.StdoutTruncated {
+		t.Errorf("streamed stdout = %d bytes, truncated %v, want all 500 bytes", stdout.Len(), result.StdoutTruncated)
+	}
+	if stderr.String() != "warning\nwa" || !result.StderrTruncated {
+		t.Errorf("streamed stderr = %q, truncated %v, want it cut at 10 bytes", stderr.String(), result.StderrTruncated)
+	}
+}
+
+func TestSandboxExecuteSpillToDisk(t *testing.T) {
+	line := strings.Repeat("x", 1023) + "\n"
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+			for range 1024 {
+				handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: line})
+			}
+			handler(&executor.StreamEvent{Type: executor.StreamStderr, Data: "done\n"})
+			return handler(&executor.StreamEvent{Type: executor.StreamComplete})
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.Execute(ctx, "print('x' * 1023)", WithLanguage("Python"), WithSpillToDisk(64<<10))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	defer result.Close()
+
+	if len(result.Stdout) != 64<<10 || !result.StdoutSpill.Spilled() {
+		t.Errorf("stdout in memory = %d bytes, spilled %v, want 64KiB and the rest on disk", len(result.Stdout), result.StdoutSpill.Spilled())
+	}
+	stdout, err := io.ReadAll(result.StdoutReader())
+	if err != nil || string(stdout) != strings.Repeat(line, 1024) {
+		t.Errorf("StdoutReader() read %d bytes, %v, want all 1MiB of output", len(stdout), err)
+	}
+	if result.Stderr != "done\n" || result.StderrSpill.Spilled() {
+		t.Errorf("stderr = %q, spilled %v, want it in memory", result.Stderr, result.StderrSpill.Spilled())
+	}
+}
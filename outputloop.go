@@ -0,0 +1,115 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// DefaultOutputLoopThreshold is how many identical lines in a row
+// WithDetectOutputLoop treats as a runaway loop. It is far above what
+// legitimately repetitive output, such as a grid of zeros or a log of
+// identical retries, produces.
+const DefaultOutputLoopThreshold = 10000
+
+// maxLoopLineLen bounds how much of a line the detector keeps. Longer
+// lines are compared by this prefix.
+const maxLoopLineLen = 1024
+
+// outputLoopDetector is a StreamHandler that counts identical consecutive
+// lines on stdout and stderr and cancels the run once either reaches the
+// threshold. Events after that are dropped.
+type outputLoopDetector struct {
+	threshold int
+	cancel    context.CancelFunc
+	next      executor.StreamHandler
+
+	mu      sync.Mutex
+	streams map[executor.StreamEventType]*lineRepeats
+	tripped bool
+}
+
+// lineRepeats tracks one output stream.
+type lineRepeats struct {
+	partial strings.Builder
+	last    string
+	count   int
+}
+
+func newOutputLoopDetector(threshold int, cancel context.CancelFunc, next executor.StreamHandler) *outputLoopDetector {
+	return &outputLoopDetector{
+		threshold: threshold,
+		cancel:    cancel,
+		next:      next,
+		streams:   make(map[executor.StreamEventType]*lineRepeats),
+	}
+}
+
+func (d *outputLoopDetector) handle(e *executor.StreamEvent) error {
+	d.mu.Lock()
+	if d.tripped {
+		d.mu.Unlock()
+		return nil
+	}
+	if e.Type == executor.StreamStdout || e.Type == executor.StreamStderr {
+		if d.scan(e.Type, e.Data) {
+			d.tripped = true
+			d.cancel()
+		}
+	}
+	d.mu.Unlock()
+	return d.next(e)
+}
+
+// scan feeds data from stream and reports whether a line has now repeated
+// threshold times.
+func (d *outputLoopDetector) scan(stream executor.StreamEventType, data string) bool {
+	r := d.streams[stream]
+	if r == nil {
+		r = &lineRepeats{}
+		d.streams[stream] = r
+	}
+	for {
+		i := strings.IndexByte(data, '\n')
+		if i < 0 {
+			r.add(data)
+			return false
+		}
+		r.add(data[:i])
+		data = data[i+1:]
+
+		line := r.partial.String()
+		r.partial.Reset()
+		if line == r.last && r.count > 0 {
+			r.count++
+		} else {
+			r.last, r.count = line, 1
+		}
+		if r.count >= d.threshold {
+			return true
+		}
+	}
+}
+
+// add appends to the current line up to maxLoopLineLen bytes.
+func (r *lineRepeats) add(s string) {
+	if room := maxLoopLineLen - r.partial.Len(); room < len(s) {
+		s = s[:max(room, 0)]
+	}
+	r.partial.WriteString(s)
+}
+
+// detected reports whether the threshold was reached.
+func (d *outputLoopDetector) detected() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tripped
+}
+
+// err describes the detected loop.
+func (d *outputLoopDetector) err() error {
+	return fmt.Errorf("%w: the same line was printed %d times in a row", ErrOutputLoopDetected, d.threshold)
+}
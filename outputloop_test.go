@@ -0,0 +1,157 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestOutputLoopDetector(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []executor.StreamEvent
+		want   bool
+	}{
+		{"below threshold", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx\nx\n"},
+		}, false},
+		{"threshold", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx\nx\nx\n"},
+		}, true},
+		{"split across chunks", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx"},
+			{Type: executor.StreamStdout, Data: "\nx\n"},
+			{Type: executor.StreamStdout, Data: "x\n"},
+		}, true},
+		{"reset by a different line", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx\nx\ny\nx\nx\nx\nx\n"},
+		}, false},
+		{"partial line does not count", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx\nx\nx"},
+		}, false},
+		{"streams are separate", []executor.StreamEvent{
+			{Type: executor.StreamStdout, Data: "x\nx\nx\n"},
+			{Type: executor.StreamStderr, Data: "x\nx\n"},
+		}, false},
+		{"blank lines", []executor.StreamEvent{
+			{Type: executor.StreamStderr, Data: "\n\n\n\n\n"},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancelled := false
+			var forwarded int
+			d := newOutputLoopDetector(5, func() { cancelled = true }, func(*executor.StreamEvent) error {
+				forwarded++
+				return nil
+			})
+			for i := range tt.events {
+				d.handle(&tt.events[i])
+			}
+			if d.detected() != tt.want || cancelled != tt.want {
+				t.Errorf("detected = %v, cancelled = %v, want %v", d.detected(), cancelled, tt.want)
+			}
+			if !tt.want && forwarded != len(tt.events) {
+				t.Errorf("forwarded %d of %d events", forwarded, len(tt.events))
+			}
+		})
+	}
+}
+
+func TestOutputLoopDetectorLongLines(t *testing.T) {
+	d := newOutputLoopDetector(3, func() {}, func(*executor.StreamEvent) error { return nil })
+
+	// Lines sharing a long prefix are compared by the prefix only.
+	prefix := strings.Repeat("a", maxLoopLineLen)
+	d.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: prefix + "1\n" + prefix + "2\n"})
+	if d.detected() {
+		t.Fatal("detected after two lines")
+	}
+	d.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: prefix + "3\n"})
+	if !d.detected() {
+		t.Error("lines beyond maxLoopLineLen should be compared by their prefix")
+	}
+
+	// Data after detection is dropped.
+	var forwarded bool
+	d.next = func(*executor.StreamEvent) error { forwarded = true; return nil }
+	d.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "more\n"})
+	if forwarded {
+		t.Error("events after detection should be dropped")
+	}
+}
+
+func setupLoopingProvider(t *testing.T, line string, limit int) Sandbox {
+	t.Helper()
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "loop-instance",
+		status: provider.StatusRunning,
+		streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+			for i := 0; limit == 0 || i < limit; i++ {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: line})
+			}
+			return handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 0})
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	t.Cleanup(func() { factory.Unregister("mock") })
+
+	sb, err := Create(context.Background(), WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { sb.Stop(context.Background()) })
+	return sb
+}
+
+func TestSandboxExecuteOutputLoop(t *testing.T) {
+	ctx := context.Background()
+	sb := setupLoopingProvider(t, "x\n", 0)
+
+	result, err := sb.Execute(ctx, `while True: print("x")`, WithLanguage("Python"), WithOutputLoopThreshold(100))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !result.OutputLoopDetected || result.Success() {
+		t.Errorf("result = %+v, want a failed run with OutputLoopDetected", result)
+	}
+	if n := strings.Count(result.Stdout, "x\n"); n != 100 {
+		t.Errorf("Stdout has %d lines, want the 100 up to detection", n)
+	}
+	if result.Language != "Python" {
+		t.Errorf("Language = %q, want Python", result.Language)
+	}
+
+	err = sb.ExecuteStream(ctx, `while True: print("x")`, func(*executor.StreamEvent) error { return nil },
+		WithLanguage("Python"), WithDetectOutputLoop())
+	if !errors.Is(err, ErrOutputLoopDetected) {
+		t.Errorf("ExecuteStream() error = %v, want ErrOutputLoopDetected", err)
+	}
+}
+
+func TestSandboxExecuteOutputLoopRepetitiveOutput(t *testing.T) {
+	sb := setupLoopingProvider(t, "0 0 0 0\n", 1000)
+
+	result, err := sb.Execute(context.Background(), `for _ in range(1000): print("0 0 0 0")`,
+		WithLanguage("Python"), WithDetectOutputLoop())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.OutputLoopDetected || !result.Success() {
+		t.Errorf("result = %+v, want bounded repetitive output to pass", result)
+	}
+	if n := strings.Count(result.Stdout, "\n"); n != 1000 {
+		t.Errorf("Stdout has %d lines, want 1000", n)
+	}
+}
@@ -0,0 +1,92 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// PipeStage is one step of a pipeline run by Sandbox.Pipe.
+type PipeStage struct {
+	// Language of the stage's code (auto-detected if empty).
+	Language string
+
+	// Code to execute.
+	Code string
+
+	// Options apply to this stage only.
+	Options []ExecuteOption
+}
+
+// PipeOption configures a pipeline.
+type PipeOption func(*PipeConfig)
+
+// PipeConfig holds pipeline configuration.
+type PipeConfig struct {
+	// ContinueOnFailure runs later stages even if a stage fails.
+	ContinueOnFailure bool
+
+	// OnStage is called with each stage's result as it completes.
+	OnStage func(stage int, result *executor.ExecutionResult)
+}
+
+// WithContinueOnFailure keeps the pipeline running after a stage fails.
+func WithContinueOnFailure() PipeOption {
+	return func(c *PipeConfig) {
+		c.ContinueOnFailure = true
+	}
+}
+
+// WithStageResults registers a callback receiving every stage's result,
+// including intermediate ones.
+func WithStageResults(fn func(stage int, result *executor.ExecutionResult)) PipeOption {
+	return func(c *PipeConfig) {
+		c.OnStage = fn
+	}
+}
+
+// Pipe runs stages in order, feeding each stage's stdout to the next stage's
+// stdin, and returns the last stage's result. By default the pipeline stops
+// at the first stage that fails, as judged by ExecutionResult.Success; that
+// stage's result is returned together with an ExecutionError wrapping
+// ErrPipeStageFailed.
+func (s *sandbox) Pipe(ctx context.Context, stages []PipeStage, opts ...PipeOption) (*executor.ExecutionResult, error) {
+	if len(stages) == 0 {
+		return nil, NewError("pipe", s.providerName, s.instance.ID(), fmt.Errorf("no stages: %w", ErrInvalidConfiguration))
+	}
+
+	cfg := &PipeConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var result *executor.ExecutionResult
+	for i, stage := range stages {
+		execOpts := make([]ExecuteOption, 0, len(stage.Options)+2)
+		if stage.Language != "" {
+			execOpts = append(execOpts, WithLanguage(stage.Language))
+		}
+		execOpts = append(execOpts, stage.Options...)
+		if result != nil {
+			execOpts = append(execOpts, WithStdin(result.Stdout))
+		}
+
+		var err error
+		result, err = s.Execute(ctx, stage.Code, execOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("pipe stage %d: %w", i, err)
+		}
+		if cfg.OnStage != nil {
+			cfg.OnStage(i, result)
+		}
+
+		if result.Failed() && !cfg.ContinueOnFailure {
+			stageErr := fmt.Errorf("stage %d: %w", i, ErrPipeStageFailed)
+			return result, NewError("pipe", s.providerName, s.instance.ID(),
+				NewExecutionError(result.ExitCode, result.Stdout, result.Stderr, stageErr))
+		}
+	}
+
+	return result, nil
+}
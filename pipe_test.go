@@ -0,0 +1,121 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// setupPipeProvider registers a mock whose instance runs a tiny interpreter:
+// Python prints fixed lines, Shell "sort" sorts stdin and "false" fails.
+func setupPipeProvider(t *testing.T) func() {
+	t.Helper()
+	mp := &mockProvider{
+		name: "mock",
+		instance: &mockInstance{
+			id:     "test-instance-123",
+			status: provider.StatusRunning,
+			execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+				switch {
+				case opts.Language == "Python":
+					return &executor.ExecutionResult{Stdout: "cherry\napple\nbanana\n"}
+				case code == "sort":
+					lines := strings.Fields(opts.Stdin)
+					sort.Strings(lines)
+					return &executor.ExecutionResult{Stdout: strings.Join(lines, "\n") + "\n"}
+				default:
+					return &executor.ExecutionResult{ExitCode: 1, Stderr: "failed"}
+				}
+			},
+		},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	return func() { factory.Unregister("mock") }
+}
+
+func TestSandboxPipe(t *testing.T) {
+	cleanup := setupPipeProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	var stages []*executor.ExecutionResult
+	result, err := sb.Pipe(ctx, []PipeStage{
+		{Language: "Python", Code: `for w in ["cherry", "apple", "banana"]: print(w)`},
+		{Language: "Shell", Code: "sort"},
+	}, WithStageResults(func(stage int, r *executor.ExecutionResult) {
+		stages = append(stages, r)
+	}))
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+
+	if result.Stdout != "apple\nbanana\ncherry\n" {
+		t.Errorf("Stdout = %q, want sorted output", result.Stdout)
+	}
+	if len(stages) != 2 {
+		t.Errorf("len(stages) = %d, want 2", len(stages))
+	}
+}
+
+func TestSandboxPipeStopsOnFailure(t *testing.T) {
+	cleanup := setupPipeProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	stages := []PipeStage{
+		{Language: "Shell", Code: "false"},
+		{Language: "Shell", Code: "sort"},
+	}
+
+	result, err := sb.Pipe(ctx, stages)
+	if !errors.Is(err, ErrPipeStageFailed) {
+		t.Fatalf("Pipe() error = %v, want ErrPipeStageFailed", err)
+	}
+	if result == nil || result.ExitCode != 1 {
+		t.Errorf("Pipe() should return the failing stage's result, got %v", result)
+	}
+
+	result, err = sb.Pipe(ctx, stages, WithContinueOnFailure())
+	if err != nil {
+		t.Fatalf("Pipe() with WithContinueOnFailure error = %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0 from last stage", result.ExitCode)
+	}
+}
+
+func TestSandboxPipeNoStages(t *testing.T) {
+	cleanup := setupPipeProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if _, err := sb.Pipe(ctx, nil); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Pipe(nil) error = %v, want ErrInvalidConfiguration", err)
+	}
+}
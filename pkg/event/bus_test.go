@@ -286,6 +286,8 @@ func TestEventTypes(t *testing.T) {
 		EventSandboxCreated,
 		EventSandboxStarted,
 		EventSandboxStopped,
+		EventSandboxPaused,
+		EventSandboxResumed,
 		EventSandboxError,
 		EventExecutionStarted,
 		EventExecutionComplete,
@@ -13,6 +13,8 @@ const (
 	EventSandboxCreated EventType = "sandbox.created"
 	EventSandboxStarted EventType = "sandbox.started"
 	EventSandboxStopped EventType = "sandbox.stopped"
+	EventSandboxPaused  EventType = "sandbox.paused"
+	EventSandboxResumed EventType = "sandbox.resumed"
 	EventSandboxError   EventType = "sandbox.error"
 
 	// Execution events
@@ -25,6 +27,10 @@ const (
 	EventOutputStdout EventType = "output.stdout"
 	EventOutputStderr EventType = "output.stderr"
 
+	// Setup events report progress of package installs and builds run
+	// before the code, with SetupProgressData.
+	EventSetupProgress EventType = "setup.progress"
+
 	// File events
 	EventFileWritten  EventType = "file.written"
 	EventFileRead     EventType = "file.read"
@@ -109,6 +115,17 @@ type Emitter interface {
 type ExecutionStartedData struct {
 	Language string
 	CodeSize int
+
+	// CodeHash is the hex-encoded SHA-256 of the code as executed.
+	CodeHash string
+
+	// Code is the code as executed, with secret values removed. It is only
+	// set when the sandbox was created with sindoq.WithAuditCode.
+	Code string
+
+	// Command is the command the language runtime runs the code with.
+	// Providers may wrap it, e.g. with a timeout.
+	Command []string
 }
 
 // ExecutionCompleteData contains data for execution.complete events.
@@ -130,6 +147,16 @@ type FileEventData struct {
 	Size int64
 }
 
+// SandboxStoppedData contains data for sandbox.stopped events of sandboxes
+// that stopped themselves. Stopping a sandbox with Stop emits no data.
+type SandboxStoppedData struct {
+	Reason string
+}
+
+// StopReasonMaxLifetime is the reason of a sandbox stopped for reaching
+// its maximum lifetime.
+const StopReasonMaxLifetime = "max lifetime"
+
 // PortEventData contains data for port events.
 type PortEventData struct {
 	Port      int
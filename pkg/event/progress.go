@@ -0,0 +1,109 @@
+package event
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SetupProgressData contains data for setup.progress events, parsed from a
+// line of package manager or build tool output by ParseSetupProgress.
+type SetupProgressData struct {
+	// Tool is the tool that printed the line: "npm", "pip" or "cargo",
+	// or empty for a line no parser recognised.
+	Tool string
+
+	// Step is what the tool is doing, such as "Collecting", "Compiling"
+	// or "Installed". Empty for unrecognised lines.
+	Step string
+
+	// Package and Version name the package the step is about, when the
+	// line names one.
+	Package string
+	Version string
+
+	// Current and Total count the step's progress, such as cargo's 45 of
+	// 120 crates built, when the tool reports them. Total alone counts
+	// packages, as in npm's "added 120 packages". Zero when unknown.
+	Current int
+	Total   int
+
+	// Line is the raw line, without its trailing newline.
+	Line string
+}
+
+// progressParser matches one kind of line and fills in data from it.
+type progressParser struct {
+	tool string
+	re   *regexp.Regexp
+	fill func(m []string, data *SetupProgressData)
+}
+
+var progressParsers = []progressParser{
+	// pip
+	{"pip", regexp.MustCompile(`^Collecting ([A-Za-z0-9._-]+)(?:\[[^\]]*\])?(?:==([^\s;]+))?`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package, d.Version = "Collecting", m[1], m[2]
+	}},
+	{"pip", regexp.MustCompile(`^\s*Downloading ([A-Za-z0-9_.]+?)-(\d[^-\s]*?)(?:-|\.tar\.gz|\.zip)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package, d.Version = "Downloading", m[1], m[2]
+	}},
+	{"pip", regexp.MustCompile(`^Requirement already satisfied: ([A-Za-z0-9._-]+)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package = "Satisfied", m[1]
+	}},
+	{"pip", regexp.MustCompile(`^Installing collected packages: (.+)$`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Total = "Installing", len(strings.Split(m[1], ","))
+	}},
+	{"pip", regexp.MustCompile(`^Successfully installed (.+)$`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Total = "Installed", len(strings.Fields(m[1]))
+	}},
+
+	// npm
+	{"npm", regexp.MustCompile(`^(?:(?:added|changed|removed) (\d+) packages?\b|up to date, )`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Total = "Installed", atoi(m[1])
+	}},
+	{"npm", regexp.MustCompile(`^npm (?:WARN|warn) deprecated ((?:@[^/\s]+/)?[^@\s]+)@([^:\s]+)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package, d.Version = "Deprecated", m[1], m[2]
+	}},
+	{"npm", regexp.MustCompile(`^npm (?:ERR!|error)`), func(m []string, d *SetupProgressData) {
+		d.Step = "Error"
+	}},
+	{"npm", regexp.MustCompile(`⸩ \S+ (reify|idealTree|build|preinstall|postinstall):((?:@[^/\s:]+/)?[^\s:]+)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package = m[1], m[2]
+	}},
+
+	// cargo
+	{"cargo", regexp.MustCompile(`^\s*(Compiling|Checking|Downloaded|Fresh) ([A-Za-z0-9_-]+) v(\S+)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Package, d.Version = m[1], m[2], m[3]
+	}},
+	{"cargo", regexp.MustCompile(`^\s*Building \[[ =>]*\] (\d+)/(\d+)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Current, d.Total = "Building", atoi(m[1]), atoi(m[2])
+	}},
+	{"cargo", regexp.MustCompile(`^\s*(Downloaded|Locking) (\d+) (?:crates|packages)`), func(m []string, d *SetupProgressData) {
+		d.Step, d.Total = m[1], atoi(m[2])
+	}},
+	{"cargo", regexp.MustCompile(`^\s*(Updating) crates\.io index|^\s*(Finished) .*target\(s\) in`), func(m []string, d *SetupProgressData) {
+		d.Step = m[1] + m[2]
+	}},
+}
+
+// ParseSetupProgress parses a line of npm, pip or cargo output into
+// progress data. Lines none of them recognise are returned with only Line
+// set, so callers can show them raw.
+func ParseSetupProgress(line string) *SetupProgressData {
+	line = strings.TrimRight(line, "\r\n")
+	data := &SetupProgressData{Line: line}
+	for _, p := range progressParsers {
+		if m := p.re.FindStringSubmatch(line); m != nil {
+			data.Tool = p.tool
+			p.fill(m, data)
+			break
+		}
+	}
+	return data
+}
+
+// atoi returns the number in s, or 0 if s is empty.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
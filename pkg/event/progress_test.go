@@ -0,0 +1,50 @@
+package event
+
+import (
+	"testing"
+)
+
+func TestParseSetupProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want SetupProgressData
+	}{
+		// pip
+		{"Collecting requests==2.31.0", SetupProgressData{Tool: "pip", Step: "Collecting", Package: "requests", Version: "2.31.0"}},
+		{"Collecting urllib3<3,>=1.21.1 (from requests)", SetupProgressData{Tool: "pip", Step: "Collecting", Package: "urllib3"}},
+		{"  Downloading requests-2.31.0-py3-none-any.whl (62 kB)", SetupProgressData{Tool: "pip", Step: "Downloading", Package: "requests", Version: "2.31.0"}},
+		{"  Downloading charset_normalizer-3.3.2.tar.gz (104 kB)", SetupProgressData{Tool: "pip", Step: "Downloading", Package: "charset_normalizer", Version: "3.3.2"}},
+		{"Requirement already satisfied: idna<4,>=2.5 in /usr/lib/python3/dist-packages", SetupProgressData{Tool: "pip", Step: "Satisfied", Package: "idna"}},
+		{"Installing collected packages: urllib3, idna, certifi, requests", SetupProgressData{Tool: "pip", Step: "Installing", Total: 4}},
+		{"Successfully installed certifi-2024.2.2 requests-2.31.0 urllib3-2.2.1", SetupProgressData{Tool: "pip", Step: "Installed", Total: 3}},
+
+		// npm
+		{"added 120 packages, and audited 121 packages in 3s", SetupProgressData{Tool: "npm", Step: "Installed", Total: 120}},
+		{"added 1 package in 512ms", SetupProgressData{Tool: "npm", Step: "Installed", Total: 1}},
+		{"up to date, audited 57 packages in 400ms", SetupProgressData{Tool: "npm", Step: "Installed"}},
+		{"npm WARN deprecated inflight@1.0.6: This module is not supported", SetupProgressData{Tool: "npm", Step: "Deprecated", Package: "inflight", Version: "1.0.6"}},
+		{"npm warn deprecated @babel/plugin-proposal-class-properties@7.18.6: merged", SetupProgressData{Tool: "npm", Step: "Deprecated", Package: "@babel/plugin-proposal-class-properties", Version: "7.18.6"}},
+		{"npm ERR! code ERESOLVE", SetupProgressData{Tool: "npm", Step: "Error"}},
+		{"⸨######⸩ ⠏ reify:lodash: timing reifyNode:node_modules/lodash Completed in 5ms", SetupProgressData{Tool: "npm", Step: "reify", Package: "lodash"}},
+
+		// cargo
+		{"   Compiling serde v1.0.200", SetupProgressData{Tool: "cargo", Step: "Compiling", Package: "serde", Version: "1.0.200"}},
+		{"  Downloaded tokio v1.37.0", SetupProgressData{Tool: "cargo", Step: "Downloaded", Package: "tokio", Version: "1.37.0"}},
+		{"  Downloaded 42 crates (4.1 MB) in 1.20s", SetupProgressData{Tool: "cargo", Step: "Downloaded", Total: 42}},
+		{"    Building [=======>     ] 45/120: serde, tokio", SetupProgressData{Tool: "cargo", Step: "Building", Current: 45, Total: 120}},
+		{"    Updating crates.io index", SetupProgressData{Tool: "cargo", Step: "Updating"}},
+		{"    Finished `release` profile [optimized] target(s) in 12.34s", SetupProgressData{Tool: "cargo", Step: "Finished"}},
+
+		// raw
+		{"hello world", SetupProgressData{}},
+		{"added a new feature", SetupProgressData{}},
+		{"", SetupProgressData{}},
+	}
+	for _, tt := range tests {
+		got := ParseSetupProgress(tt.line + "\n")
+		tt.want.Line = tt.line
+		if *got != tt.want {
+			t.Errorf("ParseSetupProgress(%q) = %+v, want %+v", tt.line, *got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package executor
+
+// NetworkCaptureError is returned when a run with NetworkCapture set fails
+// after the capture started, most often because it timed out. Capture
+// holds the traffic recorded up to that point.
+type NetworkCaptureError struct {
+	Err     error
+	Capture []byte
+}
+
+// Error implements the error interface.
+func (e *NetworkCaptureError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying execution error.
+func (e *NetworkCaptureError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultMaxTrackedFiles bounds the number of files snapshotted and
+// reported when tracking file changes.
+const DefaultMaxTrackedFiles = 1000
+
+// MetadataFileChangesTruncated is set in ExecutionResult.Metadata when the
+// file change list was cut off at DefaultMaxTrackedFiles.
+const MetadataFileChangesTruncated = "file_changes_truncated"
+
+// FileChangeKind describes how a file changed during execution.
+type FileChangeKind string
+
+const (
+	FileCreated  FileChangeKind = "created"
+	FileModified FileChangeKind = "modified"
+	FileDeleted  FileChangeKind = "deleted"
+)
+
+// FileChange records a file written or removed by an execution.
+type FileChange struct {
+	// Path is the file path within the sandbox.
+	Path string
+
+	// Kind is the type of change.
+	Kind FileChangeKind
+}
+
+// FileSnapshot maps sandbox paths to content hashes.
+type FileSnapshot map[string]string
+
+// SnapshotDir hashes the regular files under root, keying them by their
+// path relative to root joined onto prefix. It stops after limit files and
+// reports whether the snapshot was truncated. A missing root yields an
+// empty snapshot.
+func SnapshotDir(root, prefix string, limit int) (FileSnapshot, bool, error) {
+	snap := make(FileSnapshot)
+	truncated := false
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(snap) >= limit {
+			truncated = true
+			return fs.SkipAll
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		snap[path.Join(prefix, filepath.ToSlash(rel))] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return snap, truncated, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DiffSnapshots compares two snapshots and returns the changes sorted by
+// path, capped at limit entries. The boolean reports whether changes were
+// dropped.
+func DiffSnapshots(before, after FileSnapshot, limit int) ([]FileChange, bool) {
+	var changes []FileChange
+	for p, sum := range after {
+		prev, ok := before[p]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: p, Kind: FileCreated})
+		case prev != sum:
+			changes = append(changes, FileChange{Path: p, Kind: FileModified})
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			changes = append(changes, FileChange{Path: p, Kind: FileDeleted})
+		}
+	}
+	return SortFileChanges(changes, limit)
+}
+
+// SortFileChanges orders changes by path and caps them at limit entries.
+// The boolean reports whether changes were dropped.
+func SortFileChanges(changes []FileChange, limit int) ([]FileChange, bool) {
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	if len(changes) > limit {
+		return changes[:limit], true
+	}
+	return changes, false
+}
+
+// SetFileChanges stores changes on the result, flagging truncation in
+// Metadata.
+func (r *ExecutionResult) SetFileChanges(changes []FileChange, truncated bool) {
+	r.FileChanges = changes
+	if truncated {
+		if r.Metadata == nil {
+			r.Metadata = make(map[string]any)
+		}
+		r.Metadata[MetadataFileChangesTruncated] = true
+	}
+}
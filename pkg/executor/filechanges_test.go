@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshotDirAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("main.py", "print(1)")
+	write("data/keep.txt", "same")
+	write("old.txt", "gone soon")
+
+	before, truncated, err := SnapshotDir(dir, "/workspace", DefaultMaxTrackedFiles)
+	if err != nil {
+		t.Fatalf("SnapshotDir error: %v", err)
+	}
+	if truncated {
+		t.Error("snapshot should not be truncated")
+	}
+	if _, ok := before["/workspace/data/keep.txt"]; !ok {
+		t.Errorf("snapshot missing nested file: %v", before)
+	}
+
+	write("main.py", "print(2)")
+	write("out/result.json", "{}")
+	if err := os.Remove(filepath.Join(dir, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _, err := SnapshotDir(dir, "/workspace", DefaultMaxTrackedFiles)
+	if err != nil {
+		t.Fatalf("SnapshotDir error: %v", err)
+	}
+
+	changes, dropped := DiffSnapshots(before, after, DefaultMaxTrackedFiles)
+	if dropped {
+		t.Error("changes should not be dropped")
+	}
+	want := []FileChange{
+		{Path: "/workspace/main.py", Kind: FileModified},
+		{Path: "/workspace/old.txt", Kind: FileDeleted},
+		{Path: "/workspace/out/result.json", Kind: FileCreated},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("DiffSnapshots = %v, want %v", changes, want)
+	}
+}
+
+func TestSnapshotDirLimit(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snap, truncated, err := SnapshotDir(dir, "/workspace", 2)
+	if err != nil {
+		t.Fatalf("SnapshotDir error: %v", err)
+	}
+	if !truncated {
+		t.Error("snapshot should be truncated")
+	}
+	if len(snap) != 2 {
+		t.Errorf("len(snapshot) = %d, want 2", len(snap))
+	}
+}
+
+func TestSnapshotDirMissing(t *testing.T) {
+	snap, _, err := SnapshotDir(filepath.Join(t.TempDir(), "missing"), "/workspace", DefaultMaxTrackedFiles)
+	if err != nil {
+		t.Fatalf("SnapshotDir error: %v", err)
+	}
+	if len(snap) != 0 {
+		t.Errorf("len(snapshot) = %d, want 0", len(snap))
+	}
+}
+
+func TestSetFileChangesTruncated(t *testing.T) {
+	result := &ExecutionResult{}
+	result.SetFileChanges([]FileChange{{Path: "/workspace/a", Kind: FileCreated}}, true)
+
+	if len(result.FileChanges) != 1 {
+		t.Errorf("len(FileChanges) = %d, want 1", len(result.FileChanges))
+	}
+	if result.Metadata[MetadataFileChangesTruncated] != true {
+		t.Error("truncation should be flagged in Metadata")
+	}
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// LimitedWriter writes at most a limit of bytes to an underlying writer and
+// discards the rest, reporting every write as complete so that copying
+// into it, such as with exec.Cmd or stdcopy, runs to the end of the
+// output. The cut never splits a UTF-8 character within a write.
+type LimitedWriter struct {
+	w         io.Writer
+	remaining int64
+	limited   bool
+	truncated bool
+}
+
+// NewLimitedWriter returns a LimitedWriter that passes limit bytes to w.
+// Zero or a negative limit passes everything.
+func NewLimitedWriter(w io.Writer, limit int64) *LimitedWriter {
+	return &LimitedWriter{w: w, remaining: limit, limited: limit > 0}
+}
+
+// Write implements io.Writer.
+func (l *LimitedWriter) Write(p []byte) (int, error) {
+	if !l.limited {
+		return l.w.Write(p)
+	}
+	if l.truncated {
+		return len(p), nil
+	}
+	n := len(p)
+	if int64(n) > l.remaining {
+		n = runeBoundary(p, int(l.remaining))
+		l.truncated = true
+	}
+	if _, err := l.w.Write(p[:n]); err != nil {
+		return 0, err
+	}
+	l.remaining -= int64(n)
+	return len(p), nil
+}
+
+// Truncated reports whether output was discarded.
+func (l *LimitedWriter) Truncated() bool {
+	return l.truncated
+}
+
+// CutOutput cuts s to at most limit bytes without splitting a UTF-8
+// character and reports whether it cut anything. Zero or a negative limit
+// keeps s whole.
+func CutOutput(s string, limit int64) (string, bool) {
+	if limit <= 0 || int64(len(s)) <= limit {
+		return s, false
+	}
+	return s[:runeBoundary([]byte(s[:limit+1]), int(limit))], true
+}
+
+// runeBoundary backs n up to the start of the character p[n] belongs to.
+func runeBoundary(p []byte, n int) int {
+	for n > 0 && !utf8.RuneStart(p[n]) {
+		n--
+	}
+	return n
+}
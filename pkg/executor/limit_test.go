@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLimitedWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int64
+		writes    []string
+		want      string
+		truncated bool
+	}{
+		{"unlimited", 0, []string{"hello", " world"}, "hello world", false},
+		{"within limit", 11, []string{"hello", " world"}, "hello world", false},
+		{"cut in a write", 8, []string{"hello", " world"}, "hello wo", true},
+		{"cut at a write", 5, []string{"hello", " world"}, "hello", true},
+		{"character boundary", 2, []string{"hé"}, "h", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewLimitedWriter(&buf, tt.limit)
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+				}
+			}
+			if buf.String() != tt.want || w.Truncated() != tt.truncated {
+				t.Errorf("wrote %q, truncated %v, want %q, %v", buf.String(), w.Truncated(), tt.want, tt.truncated)
+			}
+		})
+	}
+}
+
+func TestCutOutput(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int64
+		want  string
+		cut   bool
+	}{
+		{"hello", 0, "hello", false},
+		{"hello", 5, "hello", false},
+		{"hello", 3, "hel", true},
+		{"héllo", 2, "h", true},
+		{"héllo", 3, "hé", true},
+	}
+
+	for _, tt := range tests {
+		if got, cut := CutOutput(tt.s, tt.limit); got != tt.want || cut != tt.cut {
+			t.Errorf("CutOutput(%q, %d) = %q, %v, want %q, %v", tt.s, tt.limit, got, cut, tt.want, tt.cut)
+		}
+	}
+}
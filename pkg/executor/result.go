@@ -2,14 +2,35 @@
 package executor
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
 )
 
+// summaryOutputLimit caps how many bytes of stdout/stderr String includes.
+const summaryOutputLimit = 256
+
+// MetadataClockOffsetApplied is set in ExecutionResult.Metadata when the
+// provider shifted the program's clock by ExecutionOptions.ClockOffset.
+const MetadataClockOffsetApplied = "clock_offset_applied"
+
 // ExecutionResult contains the outcome of code execution.
 type ExecutionResult struct {
 	// ExitCode is the process exit code (0 = success).
 	ExitCode int
 
+	// Signal is the signal that terminated the process, such as
+	// syscall.SIGSEGV for a crash or syscall.SIGKILL for the OOM killer, or
+	// 0 if it exited normally. Providers report it from the process state
+	// where they see it; otherwise it is derived from an ExitCode of
+	// 128+signal. See ExitSignal.
+	Signal syscall.Signal
+
 	// Stdout contains standard output.
 	Stdout string
 
@@ -19,12 +40,50 @@ type ExecutionResult struct {
 	// Duration is the execution time.
 	Duration time.Duration
 
+	// CPUTime is the CPU time the program used, user and system, across
+	// all its processes. Unlike Duration it does not grow while the program
+	// waits or competes for a CPU, so it suits benchmarking and billing. It
+	// is zero for providers that do not measure it.
+	CPUTime time.Duration
+
 	// Language is the detected programming language.
 	Language string
 
 	// Artifacts contains any generated files or outputs.
 	Artifacts []Artifact
 
+	// FileChanges lists files created, modified or deleted by the run.
+	// It is only populated when TrackFileChanges is set.
+	FileChanges []FileChange
+
+	// NetworkCapture is a pcap of the sandbox's network traffic during the
+	// run. It is only populated when NetworkCapture is set.
+	NetworkCapture []byte
+
+	// Coverage is the line coverage of the code. It is only populated when
+	// Coverage is set.
+	Coverage *Coverage
+
+	// OutputLoopDetected reports that the run was aborted for printing the
+	// same line too many times in a row. Output up to that point is kept.
+	OutputLoopDetected bool
+
+	// StdoutTruncated and StderrTruncated report that the stream was cut
+	// off at its limit. See ExecutionOptions.StdoutLimit.
+	StdoutTruncated bool
+	StderrTruncated bool
+
+	// StdoutSpill and StderrSpill hold the whole stream when output spills
+	// to disk past a threshold; Stdout and Stderr then hold only what
+	// stayed in memory. Read the streams with StdoutReader and
+	// StderrReader, and Close the result to remove the files.
+	StdoutSpill *SpillBuffer
+	StderrSpill *SpillBuffer
+
+	// DiskUsedMB is the storage the sandbox uses after the run, in
+	// megabytes. It is only populated by providers enforcing a disk limit.
+	DiskUsedMB int64
+
 	// Error contains any execution error.
 	Error error
 
@@ -32,9 +91,113 @@ type ExecutionResult struct {
 	Metadata map[string]any
 }
 
-// Success returns true if the execution completed successfully.
+// Coverage is the line coverage of an execution.
+type Coverage struct {
+	// Percent is the share of the code's lines (statements for Go) that
+	// ran, from 0 to 100.
+	Percent float64
+
+	// Profile is the coverage tool's report: coverage.py's JSON report for
+	// Python, a cover profile for Go and c8's JSON summary for JavaScript.
+	Profile []byte
+}
+
+// Success returns true if the execution completed successfully. The exit
+// code is judged by the language's langdetect.ExitCodeInterpreter, so for
+// example SQL runs that write to stderr fail even when they exit 0.
 func (r *ExecutionResult) Success() bool {
-	return r.ExitCode == 0 && r.Error == nil
+	if r.Error != nil {
+		return false
+	}
+	if info, ok := langdetect.GetRuntimeInfo(r.Language); ok {
+		return info.Succeeded(r.ExitCode, r.Stderr)
+	}
+	return r.ExitCode == 0
+}
+
+// StdoutReader returns a reader over the whole of stdout, including any
+// part spilled to disk.
+func (r *ExecutionResult) StdoutReader() io.ReadCloser {
+	if r.StdoutSpill != nil {
+		return r.StdoutSpill.Reader()
+	}
+	return io.NopCloser(strings.NewReader(r.Stdout))
+}
+
+// StderrReader returns a reader over the whole of stderr, including any
+// part spilled to disk.
+func (r *ExecutionResult) StderrReader() io.ReadCloser {
+	if r.StderrSpill != nil {
+		return r.StderrSpill.Reader()
+	}
+	return io.NopCloser(strings.NewReader(r.Stderr))
+}
+
+// Close removes the files output spilled to. It is a no-op for results
+// whose output stayed in memory.
+func (r *ExecutionResult) Close() error {
+	var err error
+	for _, spill := range []*SpillBuffer{r.StdoutSpill, r.StderrSpill} {
+		if spill != nil {
+			if cerr := spill.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// Failed returns true if the execution did not succeed or returned an error.
+func (r *ExecutionResult) Failed() bool {
+	return !r.Success()
+}
+
+// OutputString returns stdout followed by stderr, separated by a newline
+// when both are present.
+func (r *ExecutionResult) OutputString() string {
+	if r.Stdout == "" || r.Stderr == "" {
+		return r.Stdout + r.Stderr
+	}
+	sep := ""
+	if !strings.HasSuffix(r.Stdout, "\n") {
+		sep = "\n"
+	}
+	return r.Stdout + sep + r.Stderr
+}
+
+// String returns a concise one-line summary suitable for logging.
+// Output is truncated so large results do not flood logs.
+func (r *ExecutionResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "exit=%d duration=%s", r.ExitCode, r.Duration)
+	if r.CPUTime > 0 {
+		fmt.Fprintf(&b, " cpu=%s", r.CPUTime)
+	}
+	if r.Signal != 0 {
+		fmt.Fprintf(&b, " signal=%q", r.Signal.String())
+	}
+	if r.Language != "" {
+		fmt.Fprintf(&b, " language=%s", r.Language)
+	}
+	if r.Error != nil {
+		fmt.Fprintf(&b, " error=%q", r.Error.Error())
+	}
+	if r.Stdout != "" {
+		fmt.Fprintf(&b, " stdout=%s", truncateOutput(r.Stdout, summaryOutputLimit))
+	}
+	if r.Stderr != "" {
+		fmt.Fprintf(&b, " stderr=%s", truncateOutput(r.Stderr, summaryOutputLimit))
+	}
+	return b.String()
+}
+
+// truncateOutput quotes s, keeping at most limit bytes and noting how many
+// were dropped.
+func truncateOutput(s string, limit int) string {
+	if len(s) <= limit {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%q...(%d more bytes)", s[:limit], len(s)-limit)
 }
 
 // CommandResult contains the outcome of command execution.
@@ -86,20 +249,111 @@ type ExecutionOptions struct {
 	// Timeout for execution (default: 30s).
 	Timeout time.Duration
 
-	// Env provides environment variables.
+	// Env provides environment variables. They are layered over the
+	// sandbox's environment and win on conflict.
 	Env map[string]string
 
 	// WorkDir sets the working directory.
 	WorkDir string
 
+	// EphemeralWorkDir runs the code in a fresh subdirectory of WorkDir
+	// that is removed afterwards, so files written by the run are not seen
+	// by later runs. When false, the working directory persists across
+	// runs in the same sandbox.
+	EphemeralWorkDir bool
+
 	// Stdin provides input to the program.
 	Stdin string
 
-	// Files to create before execution.
+	// StdinStream is copied to the program's stdin while it runs, for
+	// input that depends on the program's output. It is read only by
+	// ExecuteStream, where the provider supports it
+	// (SupportsInteractiveStdin), and the program's stdin is closed when
+	// it reaches EOF.
+	StdinStream io.Reader
+
+	// Files to create before execution. Relative names are resolved
+	// against WorkDir and must stay inside it.
 	Files map[string][]byte
 
+	// SecretFiles are written to SecretsDir with mode 0400 before the run
+	// and removed after it. They are never recorded or tracked as changes.
+	SecretFiles map[string][]byte
+
 	// KeepArtifacts preserves generated files after execution.
 	KeepArtifacts bool
+
+	// TrackFileChanges reports files changed in WorkDir by the run.
+	TrackFileChanges bool
+
+	// NetworkCapture records the sandbox's network traffic during the run
+	// into ExecutionResult.NetworkCapture, where the provider supports it.
+	NetworkCapture bool
+
+	// Coverage runs the code under its language's coverage tool and
+	// reports ExecutionResult.Coverage, where the provider supports it.
+	Coverage bool
+
+	// Ulimits overrides resource limits by name (e.g. "nofile", "stack")
+	// for this run, where the provider supports it.
+	Ulimits map[string]Ulimit
+
+	// MemoryMB lowers the memory limit for this run, in megabytes, where
+	// the provider supports it. Zero keeps the sandbox's limit.
+	MemoryMB int
+
+	// Umask sets the file-creation mask for the run, where the provider
+	// supports it. Nil keeps the sandbox's default.
+	Umask *os.FileMode
+
+	// CommandWrapper is prepended to the run command, e.g. a profiler or
+	// tracer, where the provider supports it (SupportsCommandWrapper).
+	CommandWrapper []string
+
+	// Interpreter replaces the first element of the language's run
+	// command, e.g. "/usr/local/bin/python3.11", where the provider
+	// supports it (SupportsInterpreterPath).
+	Interpreter string
+
+	// Args are passed to the program as its command-line arguments, after
+	// the code file or compiled binary, where the provider supports it
+	// (SupportsProgramArgs). Each is one argv entry, never re-split.
+	Args []string
+
+	// AllowReadPaths and AllowWritePaths are host paths mounted read-only
+	// and read-write at the same paths for this run, where the provider
+	// supports it (SupportsPathGrants).
+	AllowReadPaths  []string
+	AllowWritePaths []string
+
+	// ClockOffset shifts the wall clock the program sees, where the
+	// provider supports it. Providers that apply it set
+	// MetadataClockOffsetApplied on the result.
+	ClockOffset time.Duration
+
+	// MaxOutputRate caps how many bytes per second of stdout and stderr
+	// combined a streaming run forwards, where the provider supports it.
+	// Zero means unlimited.
+	MaxOutputRate int64
+
+	// RawStream makes a streaming run also deliver its output as
+	// StreamRaw events, where the provider supports it
+	// (SupportsRawStream).
+	RawStream bool
+
+	// StdoutLimit and StderrLimit cap how many bytes of each stream a
+	// buffered run keeps, where the provider supports it. Output past the
+	// limit is read and discarded. Zero means unlimited.
+	StdoutLimit int64
+	StderrLimit int64
+}
+
+// Ulimit is a soft and hard resource limit, in the units of setrlimit(2):
+// bytes for sizes such as "stack", seconds for "cpu" and a count for
+// "nofile" or "nproc". A negative value means unlimited.
+type Ulimit struct {
+	Soft int64
+	Hard int64
 }
 
 // DefaultExecutionOptions returns sensible defaults.
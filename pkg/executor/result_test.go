@@ -2,6 +2,8 @@ package executor
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -32,6 +34,21 @@ func TestExecutionResultSuccess(t *testing.T) {
 			result:   ExecutionResult{ExitCode: 1, Error: errors.New("exec failed")},
 			expected: false,
 		},
+		{
+			name:     "sql success",
+			result:   ExecutionResult{ExitCode: 0, Language: "SQL", Stdout: "1\n"},
+			expected: true,
+		},
+		{
+			name:     "sql error exiting zero",
+			result:   ExecutionResult{ExitCode: 0, Language: "SQL", Stderr: "Parse error: no such table: t\n"},
+			expected: false,
+		},
+		{
+			name:     "python warning on stderr",
+			result:   ExecutionResult{ExitCode: 0, Language: "Python", Stderr: "DeprecationWarning\n"},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -39,10 +56,64 @@ func TestExecutionResultSuccess(t *testing.T) {
 			if got := tt.result.Success(); got != tt.expected {
 				t.Errorf("Success() = %v, want %v", got, tt.expected)
 			}
+			if got := tt.result.Failed(); got == tt.expected {
+				t.Errorf("Failed() = %v, want %v", got, !tt.expected)
+			}
 		})
 	}
 }
 
+func TestExecutionResultOutputString(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ExecutionResult
+		want   string
+	}{
+		{"stdout only", ExecutionResult{Stdout: "out\n"}, "out\n"},
+		{"stderr only", ExecutionResult{Stderr: "err\n"}, "err\n"},
+		{"both with newline", ExecutionResult{Stdout: "out\n", Stderr: "err\n"}, "out\nerr\n"},
+		{"both without newline", ExecutionResult{Stdout: "out", Stderr: "err"}, "out\nerr"},
+		{"empty", ExecutionResult{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.OutputString(); got != tt.want {
+				t.Errorf("OutputString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutionResultString(t *testing.T) {
+	result := &ExecutionResult{
+		ExitCode: 1,
+		Stdout:   "hello\n",
+		Stderr:   "boom",
+		Duration: 1500 * time.Millisecond,
+		Language: "Python",
+		Error:    errors.New("exec failed"),
+	}
+
+	want := `exit=1 duration=1.5s language=Python error="exec failed" stdout="hello\n" stderr="boom"`
+	if got := result.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestExecutionResultStringTruncates(t *testing.T) {
+	result := &ExecutionResult{Stdout: strings.Repeat("x", 1<<20)}
+
+	got := result.String()
+	if len(got) > 2*summaryOutputLimit {
+		t.Errorf("len(String()) = %d, want at most %d", len(got), 2*summaryOutputLimit)
+	}
+	wantSuffix := fmt.Sprintf("...(%d more bytes)", 1<<20-summaryOutputLimit)
+	if !strings.HasSuffix(got, wantSuffix) {
+		t.Errorf("String() should end with %q, got %q", wantSuffix, got[len(got)-40:])
+	}
+}
+
 func TestExecutionResultFields(t *testing.T) {
 	result := ExecutionResult{
 		ExitCode:  0,
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SecretsDir is where ExecutionOptions.SecretFiles appear inside the sandbox.
+const SecretsDir = "/run/secrets"
+
+// ValidSecretFileName reports whether name can be used as a key of
+// ExecutionOptions.SecretFiles. Names must be a single path element.
+func ValidSecretFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// SecretFileRefs reference-counts the secret files of overlapping runs that
+// share one SecretsDir, so a run finishing early does not remove files
+// another run is still reading. Runs of one sandbox deliver the same
+// contents, so a file that is already held is not rewritten.
+type SecretFileRefs struct {
+	mu   sync.Mutex
+	refs map[string]int
+}
+
+// Acquire takes a reference on each file, calling write for the ones no other
+// run holds. The returned func drops the references and calls remove with the
+// names no run holds any more; it must be called once the run ends, whatever
+// its outcome.
+func (r *SecretFileRefs) Acquire(files map[string][]byte, write func(name string, content []byte) error, remove func(names []string)) (func(), error) {
+	if len(files) == 0 {
+		return func() {}, nil
+	}
+	for name := range files {
+		if !ValidSecretFileName(name) {
+			return nil, fmt.Errorf("invalid secret file name %q", name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs == nil {
+		r.refs = make(map[string]int)
+	}
+
+	held := make([]string, 0, len(files))
+	release := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.release(held, remove)
+	}
+	for name, content := range files {
+		if r.refs[name] == 0 {
+			if err := write(name, content); err != nil {
+				r.release(held, remove)
+				return nil, fmt.Errorf("write secret file %s: %w", name, err)
+			}
+		}
+		r.refs[name]++
+		held = append(held, name)
+	}
+	return release, nil
+}
+
+// release drops a reference on each name and removes the unreferenced ones.
+// r.mu must be held.
+func (r *SecretFileRefs) release(names []string, remove func(names []string)) {
+	var unused []string
+	for _, name := range names {
+		if r.refs[name]--; r.refs[name] == 0 {
+			delete(r.refs, name)
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		remove(unused)
+	}
+}
+
+// WriteSecretFiles writes files into dir on the host for providers that bind
+// it to SecretsDir, taking references in refs. The directory is created with
+// mode 0700 and each file with mode 0400. The returned func removes the files
+// once no overlapping run holds them, and dir with the last one; it must be
+// called once the run ends, whatever its outcome.
+func WriteSecretFiles(refs *SecretFileRefs, dir string, files map[string][]byte) (func(), error) {
+	if len(files) == 0 {
+		return func() {}, nil
+	}
+
+	write := func(name string, content []byte) error {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, name), content, 0o400)
+	}
+	remove := func(names []string) {
+		for _, name := range names {
+			os.Remove(filepath.Join(dir, name))
+		}
+		// Only succeeds once the directory is empty.
+		os.Remove(dir)
+	}
+	return refs.Acquire(files, write, remove)
+}
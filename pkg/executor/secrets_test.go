@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSecretFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	remove, err := WriteSecretFiles(&SecretFileRefs{}, dir, map[string][]byte{
+		"token":   []byte("s3cr3t"),
+		"key.pem": []byte("-----BEGIN KEY-----"),
+	})
+	if err != nil {
+		t.Fatalf("WriteSecretFiles() error = %v", err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("dir mode = %o, want 700", perm)
+	}
+
+	for name, want := range map[string]string{"token": "s3cr3t", "key.pem": "-----BEGIN KEY-----"} {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o400 {
+			t.Errorf("%s mode = %o, want 400", name, perm)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	remove()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("secrets dir should be removed, stat error = %v", err)
+	}
+}
+
+func TestWriteSecretFilesInvalidName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	if _, err := WriteSecretFiles(&SecretFileRefs{}, dir, map[string][]byte{"../escape": []byte("x")}); err == nil {
+		t.Fatal("WriteSecretFiles() should reject names with path separators")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("secrets dir should be removed after a failure, stat error = %v", err)
+	}
+}
+
+func TestWriteSecretFilesOverlapping(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	files := map[string][]byte{"token": []byte("s3cr3t")}
+	var refs SecretFileRefs
+
+	first, err := WriteSecretFiles(&refs, dir, files)
+	if err != nil {
+		t.Fatalf("WriteSecretFiles() error = %v", err)
+	}
+	second, err := WriteSecretFiles(&refs, dir, files)
+	if err != nil {
+		t.Fatalf("WriteSecretFiles() error = %v", err)
+	}
+
+	first()
+	if got, err := os.ReadFile(filepath.Join(dir, "token")); err != nil || string(got) != "s3cr3t" {
+		t.Errorf("token after the first run ended = %q, %v; want it kept for the second", got, err)
+	}
+
+	second()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("secrets dir should be removed after the last run, stat error = %v", err)
+	}
+}
+
+func TestValidSecretFileName(t *testing.T) {
+	tests := map[string]bool{
+		"token":     true,
+		"api.key":   true,
+		"":          false,
+		".":         false,
+		"..":        false,
+		"a/b":       false,
+		`a\b`:       false,
+		"../passwd": false,
+	}
+	for name, want := range tests {
+		if got := ValidSecretFileName(name); got != want {
+			t.Errorf("ValidSecretFileName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"os"
+	"syscall"
+)
+
+// maxSignal is the highest signal number ExitSignal recognises, covering
+// the real-time signals of Linux.
+const maxSignal = 64
+
+// ExitSignal returns the signal that terminated a process reported with
+// exitCode, or 0 if the code reports a normal exit. Shells, Docker and
+// nsjail report death by signal N as exit code 128+N, so a program that
+// itself exits with such a code cannot be told apart from one killed by
+// the signal.
+func ExitSignal(exitCode int) syscall.Signal {
+	if exitCode <= 128 || exitCode > 128+maxSignal {
+		return 0
+	}
+	return syscall.Signal(exitCode - 128)
+}
+
+// ProcessSignal returns the signal that terminated a process run on the
+// host, whether it was signalled itself or reported a signal through its
+// exit code, as nsjail does for the program it runs. It returns 0 for a
+// normal exit.
+func ProcessSignal(state *os.ProcessState) syscall.Signal {
+	if state == nil {
+		return 0
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return ws.Signal()
+	}
+	return ExitSignal(state.ExitCode())
+}
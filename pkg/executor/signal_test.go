@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestExitSignal(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		want     syscall.Signal
+	}{
+		{0, 0},
+		{1, 0},
+		{128, 0},
+		{137, syscall.SIGKILL},
+		{139, syscall.SIGSEGV},
+		{143, syscall.SIGTERM},
+		{192, 64},
+		{193, 0},
+		{255, 0},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		if got := ExitSignal(tt.exitCode); got != tt.want {
+			t.Errorf("ExitSignal(%d) = %v, want %v", tt.exitCode, got, tt.want)
+		}
+	}
+}
+
+func TestProcessSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no POSIX signals on Windows")
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   syscall.Signal
+	}{
+		{"signalled", "kill -SEGV $$", syscall.SIGSEGV},
+		{"reported by exit code", "exit 137", syscall.SIGKILL},
+		{"normal exit", "exit 3", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exec.Command("sh", "-c", tt.script).Run()
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("Run() error = %v, want an exit error", err)
+			}
+			if got := ProcessSignal(exitErr.ProcessState); got != tt.want {
+				t.Errorf("ProcessSignal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := ProcessSignal(nil); got != 0 {
+		t.Errorf("ProcessSignal(nil) = %v, want 0", got)
+	}
+}
@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// SpillBuffer collects output in memory up to a threshold and writes the
+// rest to a temporary file on the host, so output of any size can be kept
+// whole without holding it all in RAM. Close removes the file.
+type SpillBuffer struct {
+	threshold int64
+	mem       bytes.Buffer
+	file      *os.File
+	size      int64
+	closed    bool
+}
+
+// NewSpillBuffer returns a SpillBuffer keeping up to threshold bytes in
+// memory.
+func NewSpillBuffer(threshold int64) *SpillBuffer {
+	return &SpillBuffer{threshold: threshold}
+}
+
+// Write implements io.Writer. The in-memory part ends at a UTF-8 character
+// boundary, so String never splits a character.
+func (b *SpillBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.file == nil {
+		keep := n
+		if int64(b.mem.Len()+n) > b.threshold {
+			keep = runeBoundary(p, int(b.threshold)-b.mem.Len())
+		}
+		b.mem.Write(p[:keep])
+		b.size += int64(keep)
+		if p = p[keep:]; len(p) == 0 {
+			return n, nil
+		}
+		f, err := os.CreateTemp("", "sindoq-spill-*")
+		if err != nil {
+			return n - len(p), err
+		}
+		b.file = f
+	}
+	written, err := b.file.Write(p)
+	b.size += int64(written)
+	return n - len(p) + written, err
+}
+
+// Len returns the number of bytes written.
+func (b *SpillBuffer) Len() int64 {
+	return b.size
+}
+
+// Spilled reports whether output went to disk.
+func (b *SpillBuffer) Spilled() bool {
+	return b.file != nil
+}
+
+// String returns the in-memory part of the output: all of it unless it
+// spilled.
+func (b *SpillBuffer) String() string {
+	return b.mem.String()
+}
+
+// Reader returns a reader over the whole output, memory first and then the
+// file. Several readers may be open at once; closing them is optional, and
+// they fail once the SpillBuffer is closed.
+func (b *SpillBuffer) Reader() io.ReadCloser {
+	mem := bytes.NewReader(b.mem.Bytes())
+	if b.file == nil {
+		return io.NopCloser(mem)
+	}
+	rest := io.NewSectionReader(b.file, 0, b.size-int64(b.mem.Len()))
+	return io.NopCloser(io.MultiReader(mem, rest))
+}
+
+// Close removes the file output spilled to, if any. Closing twice is a
+// no-op.
+func (b *SpillBuffer) Close() error {
+	if b.file == nil || b.closed {
+		return nil
+	}
+	b.closed = true
+	err := b.file.Close()
+	if rerr := os.Remove(b.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSpillBuffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int64
+		writes    []string
+		inMemory  string
+		spilled   bool
+	}{
+		{"within threshold", 11, []string{"hello", " world"}, "hello world", false},
+		{"spill in a write", 8, []string{"hello", " world"}, "hello wo", true},
+		{"spill at a write", 5, []string{"hello", " world"}, "hello", true},
+		{"character boundary", 2, []string{"hé!"}, "h", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewSpillBuffer(tt.threshold)
+			defer b.Close()
+			var all string
+			for _, s := range tt.writes {
+				if n, err := b.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write(%q) = %d, %v, want %d, nil", s, n, err, len(s))
+				}
+				all += s
+			}
+			if b.String() != tt.inMemory || b.Spilled() != tt.spilled || b.Len() != int64(len(all)) {
+				t.Errorf("in memory %q, spilled %v, len %d, want %q, %v, %d", b.String(), b.Spilled(), b.Len(), tt.inMemory, tt.spilled, len(all))
+			}
+			if got, err := io.ReadAll(b.Reader()); err != nil || string(got) != all {
+				t.Errorf("Reader() read %q, %v, want %q", got, err, all)
+			}
+		})
+	}
+}
+
+func TestExecutionResultClose(t *testing.T) {
+	spill := NewSpillBuffer(4)
+	spill.Write([]byte(strings.Repeat("x", 100)))
+	r := &ExecutionResult{Stdout: spill.String(), StdoutSpill: spill, Stderr: "warning"}
+	name := spill.file.Name()
+
+	if got, _ := io.ReadAll(r.StdoutReader()); len(got) != 100 {
+		t.Errorf("StdoutReader() read %d bytes, want 100", len(got))
+	}
+	if got, _ := io.ReadAll(r.StderrReader()); string(got) != "warning" {
+		t.Errorf("StderrReader() read %q, want the in-memory stderr", got)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("spill file still exists after Close(): %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package executor
 import (
 	"io"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -16,6 +17,18 @@ const (
 	// StreamStderr indicates standard error data.
 	StreamStderr StreamEventType = "stderr"
 
+	// StreamCompileStdout and StreamCompileStderr carry the output of a
+	// separate compile step, which precedes the run's StreamStdout and
+	// StreamStderr events.
+	StreamCompileStdout StreamEventType = "compile_stdout"
+	StreamCompileStderr StreamEventType = "compile_stderr"
+
+	// StreamRaw carries the provider's unprocessed output, such as Docker's
+	// multiplexed frames with their headers, when ExecutionOptions.RawStream
+	// is set. Each event's Data is one or more whole frames. StreamStdout
+	// and StreamStderr events still follow with the demultiplexed output.
+	StreamRaw StreamEventType = "raw"
+
 	// StreamStart indicates execution started.
 	StreamStart StreamEventType = "start"
 
@@ -37,11 +50,22 @@ type StreamEvent struct {
 	// Timestamp when the event occurred.
 	Timestamp time.Time
 
-	// ExitCode is set when Type is StreamComplete.
+	// ExitCode and Signal are set when Type is StreamComplete. See
+	// ExecutionResult.Signal.
 	ExitCode int
+	Signal   syscall.Signal
+
+	// Language is the language being run, set when Type is StreamStart or
+	// StreamComplete.
+	Language string
 
 	// Error is set when Type is StreamError.
 	Error error
+
+	// StdoutTruncated and StderrTruncated are set when Type is
+	// StreamComplete and the stream was cut off at its limit.
+	StdoutTruncated bool
+	StderrTruncated bool
 }
 
 // StreamHandler processes streaming events.
@@ -0,0 +1,209 @@
+package fs
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archiver is implemented by file systems that can transfer a directory
+// tree as a tar stream. Entry names are relative to the directory, file
+// modes are preserved, and file contents are streamed rather than
+// buffered.
+type Archiver interface {
+	// ExportTar writes the tree rooted at dir to w. When dir is a file,
+	// the archive holds just that file under its base name.
+	ExportTar(ctx context.Context, dir string, w io.Writer) error
+
+	// ImportTar extracts r into dir, creating it if needed. Existing
+	// files are overwritten.
+	ImportTar(ctx context.Context, dir string, r io.Reader) error
+}
+
+// WriteTar writes the tree rooted at the host directory root to w, with
+// entry names relative to root. Regular files, directories and symbolic
+// links are archived with their modes; other file types are skipped. When
+// root is a file, the archive holds just that file under its base name.
+func WriteTar(ctx context.Context, root string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	base := root
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		base = filepath.Dir(root)
+	}
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(base, name)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		var link string
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(name); err != nil {
+				return err
+			}
+		case !info.Mode().IsRegular() && !info.IsDir():
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// ExtractTar extracts r into the host directory root, restoring modes.
+// Entries are kept inside root, and an entry below a symbolic link, or a
+// directory entry naming one, is rejected rather than followed.
+func ExtractTar(ctx context.Context, root string, r io.Reader) error {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return err
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		name := path.Clean("/" + header.Name)
+		if name == "/" {
+			continue
+		}
+		target := filepath.Join(root, filepath.FromSlash(name))
+		// Files and links in the way are replaced, but a directory entry
+		// would be created or chmodded through one.
+		changed := filepath.Dir(target)
+		if header.Typeflag == tar.TypeDir {
+			changed = target
+		}
+		if err := checkNoSymlinks(root, changed); err != nil {
+			return fmt.Errorf("extract %q: %w", header.Name, err)
+		}
+		mode := fs.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := extractFile(target, mode, tr); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractFile writes the current tar entry to name with mode.
+func extractFile(name string, mode fs.FileMode, r io.Reader) error {
+	// Remove first so a symbolic link in the way is replaced, not followed.
+	os.Remove(name)
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// The umask may have cleared bits of mode.
+	return os.Chmod(name, mode)
+}
+
+// checkNoSymlinks returns an error if any existing path element of name
+// below root is a symbolic link.
+func checkNoSymlinks(root, name string) error {
+	rel, err := filepath.Rel(root, name)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	dir := root
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, elem)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symbolic link", filepath.ToSlash(rel))
+		}
+	}
+	return nil
+}
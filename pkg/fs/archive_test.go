@@ -0,0 +1,193 @@
+package fs
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTarRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	large := bytes.Repeat([]byte("0123456789abcdef"), 256*1024)
+	files := []struct {
+		name string
+		data []byte
+		mode os.FileMode
+	}{
+		{"main.py", []byte("print('hi')\n"), 0644},
+		{"run.sh", []byte("#!/bin/sh\necho hi\n"), 0755},
+		{"secret/key", []byte("k"), 0600},
+		{"data/big.bin", large, 0644},
+	}
+	for _, f := range files {
+		path := filepath.Join(src, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.data, f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(src, "empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("main.py", filepath.Join(src, "link.py")); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := WriteTar(ctx, src, &archive); err != nil {
+		t.Fatalf("WriteTar() error = %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "restored")
+	if err := ExtractTar(ctx, dst, &archive); err != nil {
+		t.Fatalf("ExtractTar() error = %v", err)
+	}
+
+	for _, f := range files {
+		path := filepath.Join(dst, filepath.FromSlash(f.name))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", f.name, err)
+		}
+		if !bytes.Equal(data, f.data) {
+			t.Errorf("%s: content differs", f.name)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != f.mode {
+			t.Errorf("%s: mode = %v, want %v", f.name, info.Mode().Perm(), f.mode)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dst, "empty")); err != nil || !info.IsDir() || info.Mode().Perm() != 0700 {
+		t.Errorf("empty directory not restored: %v, %v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "link.py")); err != nil || target != "main.py" {
+		t.Errorf("Readlink() = %q, %v, want main.py", target, err)
+	}
+}
+
+func TestWriteTarRelativeNames(t *testing.T) {
+	src := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := WriteTar(context.Background(), src, &archive); err != nil {
+		t.Fatalf("WriteTar() error = %v", err)
+	}
+	var names []string
+	tr := tar.NewReader(&archive)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	if strings.Join(names, ",") != "sub/,sub/a.txt" {
+		t.Errorf("names = %q, want [sub/ sub/a.txt]", names)
+	}
+}
+
+// writeArchive builds a tar from headers, giving regular files content.
+func writeArchive(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		content := ""
+		if h.Typeflag == tar.TypeReg {
+			content = "pwned"
+			h.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	return &buf
+}
+
+func TestExtractTarStaysInside(t *testing.T) {
+	ctx := context.Background()
+	parent := t.TempDir()
+	dst := filepath.Join(parent, "dst")
+
+	archive := writeArchive(t, &tar.Header{Name: "../escape.txt", Typeflag: tar.TypeReg, Mode: 0644})
+	if err := ExtractTar(ctx, dst, archive); err != nil {
+		t.Fatalf("ExtractTar() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape.txt")); err == nil {
+		t.Error("entry escaped the target directory")
+	}
+	if _, err := os.Stat(filepath.Join(dst, "escape.txt")); err != nil {
+		t.Errorf("entry should be extracted inside the target: %v", err)
+	}
+
+	outside := t.TempDir()
+	archive = writeArchive(t,
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0777},
+		&tar.Header{Name: "link/file.txt", Typeflag: tar.TypeReg, Mode: 0644},
+	)
+	if err := ExtractTar(ctx, dst, archive); err == nil {
+		t.Error("ExtractTar() should reject writing through a symbolic link")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "file.txt")); err == nil {
+		t.Error("entry was written outside the target directory")
+	}
+}
+
+func TestExtractTarDirectoryThroughSymlink(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.Chmod(outside, 0700); err != nil {
+		t.Fatal(err)
+	}
+	archive := writeArchive(t,
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0777},
+		&tar.Header{Name: "link/", Typeflag: tar.TypeDir, Mode: 0777},
+	)
+	if err := ExtractTar(context.Background(), t.TempDir(), archive); err == nil {
+		t.Error("ExtractTar() should reject a directory entry naming a symbolic link")
+	}
+	if info, err := os.Stat(outside); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("directory outside the target was changed: %v, %v", info, err)
+	}
+}
+
+func TestWriteTarFile(t *testing.T) {
+	ctx := context.Background()
+	src := filepath.Join(t.TempDir(), "main.py")
+	if err := os.WriteFile(src, []byte("print('hi')\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	if err := WriteTar(ctx, src, &archive); err != nil {
+		t.Fatalf("WriteTar() error = %v", err)
+	}
+	dst := t.TempDir()
+	if err := ExtractTar(ctx, dst, &archive); err != nil {
+		t.Fatalf("ExtractTar() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "main.py"))
+	if err != nil || string(data) != "print('hi')\n" {
+		t.Errorf("main.py = %q, %v, want the exported file", data, err)
+	}
+}
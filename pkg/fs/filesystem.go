@@ -2,7 +2,9 @@
 package fs
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"time"
 )
@@ -119,3 +121,69 @@ type WatchableFileSystem interface {
 	FileSystem
 	Watcher
 }
+
+// RangeDownloader is implemented by file systems that can fetch a byte range
+// of a file without transferring the whole file.
+type RangeDownloader interface {
+	// DownloadRange writes up to length bytes of the file starting at offset.
+	// A length of zero or less reads until the end of the file.
+	DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error
+}
+
+// Tailer is implemented by file systems that can follow a file as it grows.
+type Tailer interface {
+	// Tail sends the file's contents and then every appended chunk on the
+	// returned channel, waiting for the file if it does not exist yet. The
+	// channel is closed when ctx ends or the file can no longer be followed.
+	Tail(ctx context.Context, path string) (<-chan []byte, error)
+}
+
+// DownloadRange writes a byte range of a sandbox file to writer. It uses the
+// file system's native range support when available and otherwise falls back
+// to a full download, discarding bytes outside the requested range.
+// Interrupted downloads can be resumed by passing the number of bytes already
+// received as offset.
+func DownloadRange(ctx context.Context, fsys FileSystem, path string, offset, length int64, writer io.Writer) error {
+	if offset < 0 {
+		return fmt.Errorf("invalid offset %d", offset)
+	}
+	if rd, ok := fsys.(RangeDownloader); ok {
+		return rd.DownloadRange(ctx, path, offset, length, writer)
+	}
+
+	data, err := fsys.Read(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return WriteRange(bytes.NewReader(data), offset, length, writer)
+}
+
+// WriteRange copies the [offset, offset+length) range of r to writer.
+// A length of zero or less copies until EOF.
+func WriteRange(r io.Reader, offset, length int64, writer io.Writer) error {
+	if offset > 0 {
+		if seeker, ok := r.(io.Seeker); ok {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("seek: %w", err)
+			}
+		} else if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("skip: %w", err)
+		}
+	}
+
+	if length > 0 {
+		_, err := io.CopyN(writer, r, length)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	_, err := io.Copy(writer, r)
+	return err
+}
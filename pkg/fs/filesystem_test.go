@@ -1,8 +1,10 @@
 package fs
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"strings"
 	"testing"
 	"time"
 )
@@ -190,3 +192,86 @@ func TestWatchableFileSystemInterface(t *testing.T) {
 		t.Error("mockWatchableFileSystem should implement WatchableFileSystem")
 	}
 }
+
+// contentFileSystem serves a single file's content from memory.
+type contentFileSystem struct {
+	mockFileSystem
+	content []byte
+}
+
+func (m *contentFileSystem) Read(ctx context.Context, path string) ([]byte, error) {
+	return m.content, nil
+}
+
+// rangeFileSystem records native range requests.
+type rangeFileSystem struct {
+	contentFileSystem
+	offset, length int64
+}
+
+func (m *rangeFileSystem) DownloadRange(ctx context.Context, path string, offset, length int64, writer io.Writer) error {
+	m.offset, m.length = offset, length
+	_, err := writer.Write([]byte("native"))
+	return err
+}
+
+func TestDownloadRangeFallback(t *testing.T) {
+	fsys := &contentFileSystem{content: []byte("0123456789")}
+
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           string
+	}{
+		{"middle", 2, 3, "234"},
+		{"to end", 7, 0, "789"},
+		{"past end length", 8, 10, "89"},
+		{"offset past end", 20, 5, ""},
+		{"whole file", 0, 0, "0123456789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := DownloadRange(context.Background(), fsys, "/workspace/f", tt.offset, tt.length, &buf); err != nil {
+				t.Fatalf("DownloadRange error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("DownloadRange = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadRangeNative(t *testing.T) {
+	fsys := &rangeFileSystem{}
+
+	var buf bytes.Buffer
+	if err := DownloadRange(context.Background(), fsys, "/workspace/f", 5, 10, &buf); err != nil {
+		t.Fatalf("DownloadRange error: %v", err)
+	}
+	if buf.String() != "native" {
+		t.Errorf("DownloadRange = %q, want %q", buf.String(), "native")
+	}
+	if fsys.offset != 5 || fsys.length != 10 {
+		t.Errorf("range = (%d, %d), want (5, 10)", fsys.offset, fsys.length)
+	}
+}
+
+func TestDownloadRangeInvalidOffset(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DownloadRange(context.Background(), &contentFileSystem{}, "/workspace/f", -1, 0, &buf); err == nil {
+		t.Error("expected error for negative offset")
+	}
+}
+
+func TestWriteRangeNonSeeker(t *testing.T) {
+	var buf bytes.Buffer
+	r := io.LimitReader(strings.NewReader("abcdef"), 6)
+	if err := WriteRange(r, 1, 2, &buf); err != nil {
+		t.Fatalf("WriteRange error: %v", err)
+	}
+	if buf.String() != "bc" {
+		t.Errorf("WriteRange = %q, want %q", buf.String(), "bc")
+	}
+}
@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listFormat is the find -printf format read by ParseList: type, size,
+// modification time, octal permissions and name. The name comes last so it
+// may contain tabs, and records end in NUL so it may contain newlines.
+const listFormat = `%y\t%s\t%T@\t%m\t%f\0`
+
+// ListCommand returns a shell command that lists the entries of dir in the
+// format read by ParseList. Unlike ls, its output does not depend on the
+// locale and is unambiguous for any file name. It requires GNU find.
+func ListCommand(dir string) string {
+	return fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 -printf '%s'", shellQuote(dir), listFormat)
+}
+
+// ParseList parses the output of ListCommand(dir).
+func ParseList(dir string, output []byte) ([]FileInfo, error) {
+	var files []FileInfo
+	for _, record := range bytes.Split(output, []byte{0}) {
+		if len(bytes.TrimSpace(record)) == 0 {
+			continue
+		}
+		fields := strings.SplitN(string(record), "\t", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected list entry %q", record)
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse size of %q: %w", fields[4], err)
+		}
+		mtime, err := parseUnixTime(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("parse mtime of %q: %w", fields[4], err)
+		}
+		mode, err := strconv.ParseUint(fields[3], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse mode of %q: %w", fields[4], err)
+		}
+
+		files = append(files, FileInfo{
+			Name:    fields[4],
+			Path:    path.Join(dir, fields[4]),
+			Size:    size,
+			IsDir:   fields[0] == "d",
+			ModTime: mtime,
+			Mode:    uint32(mode),
+		})
+	}
+	return files, nil
+}
+
+// parseUnixTime parses seconds since the epoch with an optional fraction,
+// as printed by find's %T@, without the rounding of a float conversion.
+func parseUnixTime(s string) (time.Time, error) {
+	secs, frac, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nsec int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		frac += strings.Repeat("0", 9-len(frac))
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// shellQuote quotes s as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,114 @@
+package fs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseList(t *testing.T) {
+	output := "f\t5\t1700000000.5000000000\t644\tmy file.txt\x00" +
+		"d\t4096\t1700000000.0000000000\t755\tsub dir\x00" +
+		"f\t0\t1700000000.0000000000\t600\ttab\there\x00" +
+		"f\t3\t1700000000.0000000000\t644\tline\nbreak\x00" +
+		"f\t7\t1700000000.0000000000\t644\tnaïve 日本.txt\x00"
+
+	files, err := ParseList("/workspace", []byte(output))
+	if err != nil {
+		t.Fatalf("ParseList() error = %v", err)
+	}
+
+	want := []FileInfo{
+		{Name: "my file.txt", Path: "/workspace/my file.txt", Size: 5, Mode: 0644, ModTime: time.Unix(1700000000, 5e8)},
+		{Name: "sub dir", Path: "/workspace/sub dir", Size: 4096, IsDir: true, Mode: 0755, ModTime: time.Unix(1700000000, 0)},
+		{Name: "tab\there", Path: "/workspace/tab\there", Mode: 0600, ModTime: time.Unix(1700000000, 0)},
+		{Name: "line\nbreak", Path: "/workspace/line\nbreak", Size: 3, Mode: 0644, ModTime: time.Unix(1700000000, 0)},
+		{Name: "naïve 日本.txt", Path: "/workspace/naïve 日本.txt", Size: 7, Mode: 0644, ModTime: time.Unix(1700000000, 0)},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(files), len(want))
+	}
+	for i := range want {
+		if !files[i].ModTime.Equal(want[i].ModTime) {
+			t.Errorf("entry %d ModTime = %v, want %v", i, files[i].ModTime, want[i].ModTime)
+		}
+		files[i].ModTime = want[i].ModTime
+		if files[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, files[i], want[i])
+		}
+	}
+}
+
+func TestParseListEmpty(t *testing.T) {
+	files, err := ParseList("/workspace", nil)
+	if err != nil {
+		t.Fatalf("ParseList() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("got %d entries, want 0", len(files))
+	}
+}
+
+func TestParseListMalformed(t *testing.T) {
+	if _, err := ParseList("/workspace", []byte("total 8\n")); err == nil {
+		t.Error("ParseList() should reject ls-style output")
+	}
+}
+
+func TestListCommand(t *testing.T) {
+	if _, err := exec.LookPath("find"); err != nil {
+		t.Skip("find not available")
+	}
+
+	dir := filepath.Join(t.TempDir(), "it's here")
+	names := []string{"a b.txt", "tab\tname", "ünïcode-✓", "new\nline"}
+	if err := os.MkdirAll(filepath.Join(dir, "sub dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command("sh", "-c", ListCommand(dir))
+	cmd.Env = append(os.Environ(), "LC_ALL=de_DE.UTF-8")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Skipf("GNU find required: %v", err)
+	}
+
+	files, err := ParseList(dir, output)
+	if err != nil {
+		t.Fatalf("ParseList() error = %v", err)
+	}
+
+	got := make([]string, 0, len(files))
+	for _, f := range files {
+		got = append(got, f.Name)
+		if f.Path != filepath.Join(dir, f.Name) {
+			t.Errorf("Path = %q, want %q", f.Path, filepath.Join(dir, f.Name))
+		}
+		if f.IsDir != (f.Name == "sub dir") {
+			t.Errorf("%q IsDir = %v", f.Name, f.IsDir)
+		}
+		if !f.IsDir && f.Size != 4 {
+			t.Errorf("%q Size = %d, want 4", f.Name, f.Size)
+		}
+	}
+	want := append(names, "sub dir")
+	sort.Strings(got)
+	sort.Strings(want)
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %q, want %q", got, want)
+			break
+		}
+	}
+}
@@ -27,6 +27,16 @@ type DetectOptions struct {
 	// Filename hint (e.g., "main.py")
 	Filename string
 
+	// MIMEType hint (e.g., "text/x-python"). A known type ranks below the
+	// filename but above the content.
+	MIMEType string
+
+	// PriorLanguage is a weak guess (e.g., "Python") from upstream
+	// metadata. It breaks ties between disagreeing content-based
+	// strategies in its favor and is the answer of last resort, but never
+	// overrides a filename, MIME type or shebang.
+	PriorLanguage string
+
 	// UseContent enables content-based detection
 	UseContent bool
 
@@ -49,42 +59,79 @@ func DefaultDetectOptions() *DetectOptions {
 // DetectResult contains detection results.
 type DetectResult struct {
 	// Language is the detected language name.
-	Language string
+	Language string `json:"language"`
 
 	// Confidence is the detection confidence (0.0 to 1.0).
-	Confidence float64
+	Confidence float64 `json:"confidence"`
 
 	// Method indicates how the language was detected.
-	Method string
+	Method string `json:"method"`
+
+	// Signals holds the verdict of every strategy that produced one, in
+	// priority order.
+	Signals []Signal `json:"signals,omitempty"`
+
+	// Conflicts lists the signals that named a different language.
+	Conflicts []Signal `json:"conflicts,omitempty"`
+}
+
+// Signal is the verdict of a single detection strategy.
+type Signal struct {
+	// Method is the strategy name (e.g., "extension", "shebang", "heuristic").
+	Method string `json:"method"`
+
+	// Language is the language the strategy detected.
+	Language string `json:"language"`
+
+	// Confidence is the strategy's own confidence (0.0 to 1.0).
+	Confidence float64 `json:"confidence"`
 }
 
+// conflictPenalty scales how strongly a disagreeing signal lowers the
+// combined confidence.
+const conflictPenalty = 0.5
+
 // Detect identifies the programming language of code.
+// Every enabled strategy contributes a signal; the highest-priority signal
+// (filename, extension, MIME type, shebang, content, heuristic, prior)
+// picks the language and the signals are fused into a calibrated
+// confidence.
 func (d *Detector) Detect(code string, opts *DetectOptions) *DetectResult {
 	if opts == nil {
 		opts = DefaultDetectOptions()
 	}
 
+	var signals []Signal
+
 	// Strategy 1: Check filename/extension if provided
 	if opts.Filename != "" {
 		// Try exact filename match (e.g., Makefile, Dockerfile)
 		if lang, safe := enry.GetLanguageByFilename(opts.Filename); safe && lang != "" {
-			return &DetectResult{Language: lang, Confidence: 1.0, Method: "filename"}
+			signals = append(signals, Signal{Method: "filename", Language: lang, Confidence: 1.0})
+		} else if lang, safe := enry.GetLanguageByExtension(opts.Filename); safe && lang != "" {
+			// Try extension
+			signals = append(signals, Signal{Method: "extension", Language: lang, Confidence: 0.95})
 		}
+	}
 
-		// Try extension
-		if lang, safe := enry.GetLanguageByExtension(opts.Filename); safe && lang != "" {
-			return &DetectResult{Language: lang, Confidence: 0.95, Method: "extension"}
-		}
+	// Strategy 2: Check the MIME type
+	if lang := languageForMIMEType(opts.MIMEType); lang != "" {
+		signals = append(signals, Signal{Method: "mime", Language: lang, Confidence: 0.9})
 	}
 
-	// Strategy 2: Check shebang
+	// Strategies up to here are explicit metadata; the prior only breaks
+	// ties between the content-based ones that follow.
+	explicit := len(signals)
+	prior := canonicalLanguage(opts.PriorLanguage)
+
+	// Strategy 3: Check shebang
 	if opts.UseShebang && strings.HasPrefix(strings.TrimSpace(code), "#!") {
 		if lang, safe := enry.GetLanguageByShebang([]byte(code)); safe && lang != "" {
-			return &DetectResult{Language: lang, Confidence: 0.95, Method: "shebang"}
+			signals = append(signals, Signal{Method: "shebang", Language: lang, Confidence: 0.95})
 		}
 	}
 
-	// Strategy 3: Content-based detection using go-enry
+	// Strategy 4: Content-based detection using go-enry
 	if opts.UseContent {
 		filename := opts.Filename
 		if filename == "" {
@@ -94,29 +141,83 @@ func (d *Detector) Detect(code string, opts *DetectOptions) *DetectResult {
 		// Get all possible languages
 		languages := enry.GetLanguages(filename, []byte(code))
 		if len(languages) == 1 {
-			return &DetectResult{Language: languages[0], Confidence: 0.9, Method: "content"}
-		}
-		if len(languages) > 1 {
+			signals = append(signals, Signal{Method: "content", Language: languages[0], Confidence: 0.9})
+		} else if len(languages) > 1 {
 			// Use classifier to pick best match
-			lang := enry.GetLanguage(filename, []byte(code))
-			if lang != "" {
-				return &DetectResult{Language: lang, Confidence: 0.8, Method: "classifier"}
+			if lang := enry.GetLanguage(filename, []byte(code)); lang != "" {
+				signals = append(signals, Signal{Method: "classifier", Language: lang, Confidence: 0.8})
 			}
 		}
 	}
 
-	// Strategy 4: Heuristic patterns
+	// Strategy 5: Heuristic patterns
 	if opts.UseHeuristics {
-		if result := d.detectByPatterns(code); result != nil {
-			return result
+		if result := d.detectByPatterns(code, prior); result != nil {
+			signals = append(signals, Signal{Method: result.Method, Language: result.Language, Confidence: result.Confidence})
 		}
 	}
 
-	return &DetectResult{Language: "", Confidence: 0, Method: "unknown"}
+	// Strategy 6: The prior, weakest of all
+	if prior != "" {
+		preferPrior(signals[explicit:], prior)
+		signals = append(signals, Signal{Method: "prior", Language: prior, Confidence: priorConfidence})
+	}
+
+	if len(signals) == 0 {
+		return &DetectResult{Language: "", Confidence: 0, Method: "unknown"}
+	}
+	return fuseSignals(signals)
+}
+
+// priorConfidence is the weight of DetectOptions.PriorLanguage.
+const priorConfidence = 0.2
+
+// canonicalLanguage returns the name of a known language given its name or
+// an alias, or language unchanged.
+func canonicalLanguage(language string) string {
+	if info, ok := GetRuntimeInfo(language); ok {
+		return info.Language
+	}
+	return language
+}
+
+// preferPrior moves the first content-based signal naming prior to the
+// front when those signals disagree, so the prior breaks the tie.
+func preferPrior(signals []Signal, prior string) {
+	for i, sig := range signals {
+		if sig.Language == prior {
+			copy(signals[1:i+1], signals[:i])
+			signals[0] = sig
+			return
+		}
+	}
+}
+
+// fuseSignals picks the language of the first (highest-priority) signal and
+// combines all signals into one confidence. Agreeing signals are combined as
+// independent evidence (1 - Π(1-p)); each conflicting signal scales the
+// result by (1 - conflictPenalty*p).
+func fuseSignals(signals []Signal) *DetectResult {
+	best := signals[0]
+	result := &DetectResult{Language: best.Language, Method: best.Method, Signals: signals}
+
+	miss := 1.0
+	penalty := 1.0
+	for _, sig := range signals {
+		if sig.Language == best.Language {
+			miss *= 1 - sig.Confidence
+		} else {
+			penalty *= 1 - conflictPenalty*sig.Confidence
+			result.Conflicts = append(result.Conflicts, sig)
+		}
+	}
+	result.Confidence = (1 - miss) * penalty
+	return result
 }
 
 // detectByPatterns uses regex patterns for common language constructs.
-func (d *Detector) detectByPatterns(code string) *DetectResult {
+// When several languages share the best score, prior wins.
+func (d *Detector) detectByPatterns(code, prior string) *DetectResult {
 	patterns := map[string][]string{
 		"Python": {
 			`(?m)^import\s+\w+`,
@@ -197,6 +298,12 @@ func (d *Detector) detectByPatterns(code string) *DetectResult {
 			`:\s*(string|number|boolean|any)\b`,
 			`<[A-Z]\w*>`,
 		},
+		"Deno": {
+			`\bDeno\.\w+`,
+			`(?m)^import\s+.*\bfrom\s+["']https?://`,
+			`(?m)^import\s+["']https?://`,
+			`(?m)^import\s+.*\bfrom\s+["'](jsr|npm):`,
+		},
 		"Rust": {
 			`(?m)^fn\s+\w+`,
 			`\bfn\s+main\s*\(`,
@@ -317,6 +424,55 @@ func (d *Detector) detectByPatterns(code string) *DetectResult {
 			`(?m)^var\s+\w+`,
 			`println\s*\(`,
 		},
+		"Dart": {
+			`(?m)^import\s+'(dart|package):`,
+			`\bvoid\s+main\s*\([^)]*\)\s*(async\s*)?\{`,
+			`\b(final|late)\s+\w+(<[\w<>, ]*>)?\s+\w+\s*=`,
+			`\bfinal\s+\w+\s*=`,
+			`\b(List|Map|Set|Future|Stream)<\w+`,
+			`stdout\.write`,
+			`'[^'\n]*\$\{?\w+[^'\n]*'`,
+		},
+		"Zig": {
+			`const\s+std\s*=\s*@import\s*\(\s*"std"\s*\)`,
+			`@import\s*\(`,
+			`\bpub\s+fn\s+main\s*\(\s*\)\s*!?(void|u8)`,
+			`std\.(debug\.print|io\.getStdOut|heap\.)`,
+			`\btry\s+\w+`,
+			`\[\]const\s+u8`,
+			`\bcomptime\b`,
+			`\.\{\s*[\w.}]`,
+		},
+		"Nim": {
+			`(?m)^proc\s+\w+\*?\s*(\[[^\]]*\])?\s*\([^)]*\)\s*(:\s*[\w\[\]]+\s*)?=`,
+			`(?m)^(import|from)\s+(std/|strutils|sequtils|strformat|tables|os\b)`,
+			`(?m)^\s*echo\s+`,
+			`\bseq\[\w+\]`,
+			`\bwhen\s+isMainModule\b`,
+			`\{\.\w+.*\.\}`,
+			`\b\w+\s+in\s+\d+\s*\.\.<?\s*\w+`,
+			`(?m)^(let|var|const)\s+\w+\s*(:\s*\w+\s*)?=`,
+		},
+		"Julia": {
+			`(?m)^function\s+\w+(\{[^}]*\})?\s*\([^)]*\)\s*(::\s*\w+\s*)?$`,
+			`(?m)^end\s*$`,
+			`(?m)^using\s+\w+(\s*,\s*\w+)*\s*$`,
+			`::\s*(Int|Int64|Float64|String|Bool|Vector|Array|Dict)\b`,
+			`\bfor\s+\w+\s+(in|=|∈)\s+\d+:\w+`,
+			`(?m)^\s*@(show|time|assert|printf|test)\b`,
+			`"[^"]*\$\(?\w+`,
+		},
+		"OCaml": {
+			`(?m)^let\s+(rec\s+)?\w+(\s+\w+)*\s*=`,
+			`(?m)^let\s+\(\)\s*=`,
+			`\b(print_endline|print_string|print_int|Printf\.printf)\b`,
+			`\bmatch\s+.+\s+with\b`,
+			`(?m)^\s*\|\s*[\w\[\]:_ ]+->`,
+			`\b(List|Array|String)\.(iter|map|fold_left|length|concat)\b`,
+			`;;`,
+			`\bfun\s+\w+(\s+\w+)*\s*->`,
+			`(?m)\bin\s*$`,
+		},
 	}
 
 	scores := make(map[string]int)
@@ -338,6 +494,16 @@ func (d *Detector) detectByPatterns(code string) *DetectResult {
 			bestScore = score
 		}
 	}
+	if prior != "" && scores[prior] == bestScore {
+		bestLang = prior
+	}
+
+	// Deno code is also JavaScript or TypeScript, whose patterns it
+	// matches too, but its own markers appear nowhere else.
+	if scores["Deno"] > 0 {
+		bestLang = "Deno"
+		bestScore = scores["Deno"] + max(scores["JavaScript"], scores["TypeScript"])
+	}
 
 	if bestScore >= 1 {
 		confidence := float64(bestScore) / 5.0
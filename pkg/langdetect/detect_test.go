@@ -149,6 +149,159 @@ func (u *User) Greet() string {
 	}
 }
 
+func TestDetector_DetectAdditionalLanguages(t *testing.T) {
+	d := New()
+	opts := DefaultDetectOptions()
+
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name: "dart main with interpolation",
+			code: `import 'dart:io';
+
+void main() {
+  final name = 'World';
+  List<int> numbers = [1, 2, 3];
+  print('Hello, $name! ${numbers.length}');
+}`,
+			expected: "Dart",
+		},
+		{
+			name: "zig std import",
+			code: `const std = @import("std");
+
+pub fn main() !void {
+    const stdout = std.io.getStdOut().writer();
+    try stdout.print("Hello, {s}!\n", .{"World"});
+}`,
+			expected: "Zig",
+		},
+		{
+			name: "nim proc and echo",
+			code: `import strutils
+
+proc greet(name: string): string =
+  result = "Hello, " & name & "!"
+
+for i in 0..<3:
+  echo greet("World")`,
+			expected: "Nim",
+		},
+		{
+			name: "julia function end",
+			code: `using Printf
+
+function greet(name::String)
+    println("Hello, $name!")
+end
+
+for i in 1:3
+    greet("World")
+end`,
+			expected: "Julia",
+		},
+		{
+			name: "ocaml let and match",
+			code: `let rec fact n =
+  match n with
+  | 0 -> 1
+  | n -> n * fact (n - 1)
+
+let () =
+  print_endline "Hello, World!";
+  Printf.printf "%d\n" (fact 5)`,
+			expected: "OCaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := d.Detect(tt.code, opts)
+			if result.Language != tt.expected {
+				t.Errorf("Detect() = %v, want %v (method: %s, confidence: %f)", result.Language, tt.expected, result.Method, result.Confidence)
+			}
+		})
+	}
+}
+
+func TestDetector_DetectDeno(t *testing.T) {
+	d := New()
+	opts := DefaultDetectOptions()
+
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{
+			name: "url import",
+			code: `import { assertEquals } from "https://deno.land/std@0.224.0/assert/mod.ts";
+
+const sum = 1 + 2;
+assertEquals(sum, 3);
+console.log(sum);`,
+			expected: "Deno",
+		},
+		{
+			name:     "Deno namespace",
+			code:     "const text = await Deno.readTextFile(\"data.txt\");\nconsole.log(text.length);",
+			expected: "Deno",
+		},
+		{
+			name:     "jsr import",
+			code:     "import { parse } from \"jsr:@std/yaml\";\nconsole.log(parse(\"a: 1\"));",
+			expected: "Deno",
+		},
+		{
+			name:     "node fetch of a URL",
+			code:     "const res = await fetch(\"https://example.com\");\nconsole.log(res.status);",
+			expected: "JavaScript",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := d.Detect(tt.code, opts)
+			if result.Language != tt.expected {
+				t.Errorf("Detect() = %v, want %v (method: %s, confidence: %f)", result.Language, tt.expected, result.Method, result.Confidence)
+			}
+		})
+	}
+}
+
+// TestDetectByPatterns_NoCannibalization checks that the patterns of less
+// common languages do not outscore mainstream languages on typical code.
+func TestDetectByPatterns_NoCannibalization(t *testing.T) {
+	d := New()
+
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"def f(x):\n    return x + 1\n\nfor i in range(3):\n    print(f(i))", "Python"},
+		{"let count = 0;\nconst items = [1, 2, 3];\nitems.forEach(x => { count += x; });\nconsole.log(`total ${count}`);", "JavaScript"},
+		{"fn main() {\n    let mut v = vec![1, 2];\n    println!(\"{}\", v.len());\n}", "Rust"},
+		{"require 'json'\n\ndef greet(name)\n  puts \"Hello, #{name}\"\nend\n\n[1, 2].each do |x|\n  greet(x)\nend", "Ruby"},
+		{"#!/bin/bash\nfor f in *.txt; do\n  echo \"$f\"\ndone\nif [ -z \"$HOME\" ]; then\n  echo none\nfi", "Shell"},
+		{"fun main() {\n    val name = \"World\"\n    println(\"Hello, $name\")\n}", "Kotlin"},
+		{"object Main extends App {\n  val xs = List(1, 2, 3)\n  println(xs.map(_ * 2))\n}", "Scala"},
+		{"public class Main {\n    public static void main(String[] args) {\n        int x = 5;\n        System.out.println(x);\n    }\n}", "Java"},
+		{"#include <stdio.h>\n\nint main() {\n    int x = 1;\n    printf(\"%d\\n\", x);\n    return 0;\n}", "C"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			result := d.detectByPatterns(tt.code, "")
+			if result == nil || result.Language != tt.expected {
+				t.Errorf("detectByPatterns() = %+v, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestDetector_DetectWithOptions(t *testing.T) {
 	d := New()
 	tests := []struct {
@@ -181,6 +334,30 @@ func TestDetector_DetectWithOptions(t *testing.T) {
 			opts:     &DetectOptions{UseShebang: true},
 			expected: "Shell",
 		},
+		{
+			name:     "detect by mime type",
+			code:     "some code",
+			opts:     &DetectOptions{MIMEType: "text/x-ruby; charset=utf-8"},
+			expected: "Ruby",
+		},
+		{
+			name:     "mime type outranks content",
+			code:     "print('hello')",
+			opts:     &DetectOptions{MIMEType: "text/x-lua", UseHeuristics: true},
+			expected: "Lua",
+		},
+		{
+			name:     "filename outranks mime type",
+			code:     "some code",
+			opts:     &DetectOptions{Filename: "main.go", MIMEType: "text/x-python"},
+			expected: "Go",
+		},
+		{
+			name:     "unknown mime type is ignored",
+			code:     "#!/bin/bash\necho hello",
+			opts:     &DetectOptions{MIMEType: "text/plain", UseShebang: true},
+			expected: "Shell",
+		},
 	}
 
 	for _, tt := range tests {
@@ -193,6 +370,88 @@ func TestDetector_DetectWithOptions(t *testing.T) {
 	}
 }
 
+func TestDetector_DetectPrior(t *testing.T) {
+	d := New()
+	// Python, Ruby, Julia and Scala patterns score this snippet equally.
+	ambiguous := "def greet(name)\n  name\nend"
+
+	for _, prior := range []string{"Ruby", "Python", "Scala"} {
+		result := d.Detect(ambiguous, &DetectOptions{UseHeuristics: true, PriorLanguage: prior})
+		if result.Language != prior {
+			t.Errorf("Detect() with prior %s = %s, want %s", prior, result.Language, prior)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		opts     *DetectOptions
+		expected string
+		method   string
+	}{
+		{
+			name:     "alias is canonicalized",
+			code:     ambiguous,
+			opts:     &DetectOptions{UseHeuristics: true, PriorLanguage: "rb"},
+			expected: "Ruby",
+			method:   "heuristic",
+		},
+		{
+			name:     "prior does not override clear content",
+			code:     "fn main() {\n    println!(\"hi\");\n}",
+			opts:     &DetectOptions{UseHeuristics: true, PriorLanguage: "Python"},
+			expected: "Rust",
+			method:   "heuristic",
+		},
+		{
+			name:     "prior does not override a shebang",
+			code:     "#!/bin/bash\necho hello",
+			opts:     &DetectOptions{UseShebang: true, PriorLanguage: "Python"},
+			expected: "Shell",
+			method:   "shebang",
+		},
+		{
+			name:     "prior does not override a filename",
+			code:     ambiguous,
+			opts:     &DetectOptions{Filename: "greet.py", UseHeuristics: true, PriorLanguage: "Ruby"},
+			expected: "Python",
+			method:   "extension",
+		},
+		{
+			name:     "prior is the last resort",
+			code:     "some code",
+			opts:     &DetectOptions{PriorLanguage: "Python"},
+			expected: "Python",
+			method:   "prior",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := d.Detect(tt.code, tt.opts)
+			if result.Language != tt.expected || result.Method != tt.method {
+				t.Errorf("Detect() = %s (%s), want %s (%s)", result.Language, result.Method, tt.expected, tt.method)
+			}
+		})
+	}
+}
+
+func TestPreferPrior(t *testing.T) {
+	signals := []Signal{
+		{Method: "classifier", Language: "Ruby", Confidence: 0.8},
+		{Method: "heuristic", Language: "Python", Confidence: 0.4},
+	}
+	preferPrior(signals, "Python")
+
+	if signals[0].Language != "Python" || signals[1].Language != "Ruby" {
+		t.Errorf("signals = %+v, want the Python signal first", signals)
+	}
+	result := fuseSignals(append(signals, Signal{Method: "prior", Language: "Python", Confidence: priorConfidence}))
+	if result.Language != "Python" || len(result.Conflicts) != 1 {
+		t.Errorf("fuseSignals() = %+v", result)
+	}
+}
+
 func TestDetector_DetectFromFilename(t *testing.T) {
 	d := New()
 	// Note: go-enry may not recognize all extensions equally.
@@ -299,3 +558,53 @@ func TestDefaultDetectOptions(t *testing.T) {
 		t.Error("DefaultDetectOptions().UseHeuristics should be true")
 	}
 }
+
+func TestFuseSignals(t *testing.T) {
+	ext := Signal{Method: "extension", Language: "Python", Confidence: 0.95}
+
+	alone := fuseSignals([]Signal{ext})
+	if alone.Confidence != 0.95 {
+		t.Errorf("single signal confidence = %v, want 0.95", alone.Confidence)
+	}
+
+	agree := fuseSignals([]Signal{ext, {Method: "content", Language: "Python", Confidence: 0.9}})
+	if agree.Confidence <= alone.Confidence {
+		t.Errorf("agreeing confidence = %v, want > %v", agree.Confidence, alone.Confidence)
+	}
+	if len(agree.Conflicts) != 0 {
+		t.Errorf("agreeing signals reported conflicts: %v", agree.Conflicts)
+	}
+
+	conflict := fuseSignals([]Signal{ext, {Method: "content", Language: "Ruby", Confidence: 0.9}})
+	if conflict.Language != "Python" {
+		t.Errorf("Language = %q, want highest-priority signal %q", conflict.Language, "Python")
+	}
+	if conflict.Confidence >= alone.Confidence {
+		t.Errorf("conflicting confidence = %v, want < %v", conflict.Confidence, alone.Confidence)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Language != "Ruby" {
+		t.Errorf("Conflicts = %v, want the Ruby signal", conflict.Conflicts)
+	}
+	if len(conflict.Signals) != 2 {
+		t.Errorf("len(Signals) = %d, want 2", len(conflict.Signals))
+	}
+}
+
+func TestDetectRecordsSignals(t *testing.T) {
+	d := New()
+	result := d.Detect("import os\nprint(os.getcwd())\n", &DetectOptions{
+		Filename:      "main.py",
+		UseContent:    true,
+		UseHeuristics: true,
+	})
+
+	if result.Language != "Python" || result.Method != "extension" {
+		t.Errorf("Detect() = %s via %s, want Python via extension", result.Language, result.Method)
+	}
+	if len(result.Signals) < 2 {
+		t.Errorf("Signals = %v, want extension plus content or heuristic", result.Signals)
+	}
+	if result.Confidence <= 0.95 || result.Confidence > 1 {
+		t.Errorf("Confidence = %v, want in (0.95, 1]", result.Confidence)
+	}
+}
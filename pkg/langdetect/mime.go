@@ -0,0 +1,75 @@
+package langdetect
+
+import (
+	"mime"
+	"strings"
+)
+
+// mimeLanguages maps source MIME types, including the unregistered x-
+// types in common use, to languages.
+var mimeLanguages = map[string]string{
+	"text/x-python":             "Python",
+	"text/x-python3":            "Python",
+	"text/x-script.python":      "Python",
+	"application/x-python":      "Python",
+	"application/x-python-code": "Python",
+	"text/javascript":           "JavaScript",
+	"application/javascript":    "JavaScript",
+	"application/x-javascript":  "JavaScript",
+	"text/ecmascript":           "JavaScript",
+	"application/ecmascript":    "JavaScript",
+	"text/typescript":           "TypeScript",
+	"application/typescript":    "TypeScript",
+	"application/x-typescript":  "TypeScript",
+	"text/x-go":                 "Go",
+	"text/x-golang":             "Go",
+	"text/x-rust":               "Rust",
+	"text/rust":                 "Rust",
+	"text/x-java":               "Java",
+	"text/x-java-source":        "Java",
+	"text/x-c":                  "C",
+	"text/x-csrc":               "C",
+	"text/x-c++":                "C++",
+	"text/x-c++src":             "C++",
+	"text/x-csharp":             "C#",
+	"text/x-ruby":               "Ruby",
+	"application/x-ruby":        "Ruby",
+	"text/x-php":                "PHP",
+	"application/x-php":         "PHP",
+	"application/x-httpd-php":   "PHP",
+	"text/x-shellscript":        "Shell",
+	"text/x-sh":                 "Shell",
+	"application/x-sh":          "Shell",
+	"application/x-shellscript": "Shell",
+	"text/x-kotlin":             "Kotlin",
+	"text/x-swift":              "Swift",
+	"text/x-lua":                "Lua",
+	"text/x-perl":               "Perl",
+	"application/x-perl":        "Perl",
+	"text/x-r":                  "R",
+	"text/x-rsrc":               "R",
+	"text/x-scala":              "Scala",
+	"text/x-haskell":            "Haskell",
+	"text/x-elixir":             "Elixir",
+	"text/x-julia":              "Julia",
+	"text/x-ocaml":              "OCaml",
+	"text/x-zig":                "Zig",
+	"text/x-nim":                "Nim",
+	"application/dart":          "Dart",
+	"text/x-dart":               "Dart",
+	"application/sql":           "SQL",
+	"text/x-sql":                "SQL",
+}
+
+// languageForMIMEType returns the language of a source MIME type, ignoring
+// parameters such as charset, or "" for types that name no language.
+func languageForMIMEType(mimeType string) string {
+	if mimeType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(mimeType))
+	}
+	return mimeLanguages[mediaType]
+}
@@ -0,0 +1,225 @@
+package langdetect
+
+import (
+	"cmp"
+	"encoding/json"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/go-enry/go-enry/v2"
+)
+
+// manifestLanguages maps the base names of build manifests to the language
+// of the project they describe.
+var manifestLanguages = map[string]string{
+	"go.mod":           "Go",
+	"Cargo.toml":       "Rust",
+	"package.json":     "JavaScript",
+	"deno.json":        "Deno",
+	"deno.jsonc":       "Deno",
+	"pyproject.toml":   "Python",
+	"requirements.txt": "Python",
+	"setup.py":         "Python",
+	"Pipfile":          "Python",
+	"pom.xml":          "Java",
+	"build.gradle":     "Java",
+	"build.gradle.kts": "Kotlin",
+	"Gemfile":          "Ruby",
+	"composer.json":    "PHP",
+	"mix.exs":          "Elixir",
+	"pubspec.yaml":     "Dart",
+	"Package.swift":    "Swift",
+	"build.sbt":        "Scala",
+	"deps.edn":         "Clojure",
+	"project.clj":      "Clojure",
+	"stack.yaml":       "Haskell",
+	"build.zig":        "Zig",
+	"dune-project":     "OCaml",
+}
+
+// manifestConfidence is the weight of a build manifest, which names the
+// project's language more reliably than its sources.
+const manifestConfidence = 0.95
+
+// entrypointNames are the conventional entrypoint file names of languages,
+// most conventional first. Other languages use "main" and their extension.
+var entrypointNames = map[string][]string{
+	"Python":     {"__main__.py", "main.py", "app.py"},
+	"JavaScript": {"index.js", "main.js", "app.js", "server.js"},
+	"TypeScript": {"index.ts", "main.ts", "app.ts", "server.ts"},
+	"Deno":       {"main.ts", "mod.ts", "index.ts"},
+	"Java":       {"Main.java"},
+	"C++":        {"main.cpp", "main.cc"},
+	"PHP":        {"index.php", "main.php"},
+}
+
+// entrypointPatterns match the content of a file that defines a program's
+// entrypoint, for projects without a conventionally named one.
+var entrypointPatterns = map[string]*regexp.Regexp{
+	"Go":     regexp.MustCompile(`(?m)^package main\b[\s\S]*^func main\(\)`),
+	"Python": regexp.MustCompile(`(?m)^if __name__ == ["']__main__["']`),
+	"Java":   regexp.MustCompile(`static\s+void\s+main\s*\(`),
+	"Kotlin": regexp.MustCompile(`(?m)^fun main\(`),
+	"Rust":   regexp.MustCompile(`(?m)^fn main\(\)`),
+	"C":      regexp.MustCompile(`(?m)^int\s+main\s*\(`),
+	"C++":    regexp.MustCompile(`(?m)^int\s+main\s*\(`),
+}
+
+// DetectProject identifies the dominant language of a multi-file project,
+// keyed by path, and its entrypoint. A build manifest such as go.mod or
+// package.json names the language; without one, the language with the
+// most source bytes wins. Only languages with a runtime count, so data and
+// documentation files are ignored.
+//
+// The entrypoint is the shallowest conventionally named file (main.go,
+// __main__.py, index.js, the "main" of package.json), else a file that
+// defines a main function, else the only source file of the language. It
+// is empty when none of these single one out.
+func DetectProject(files map[string][]byte) (*DetectResult, string) {
+	// Sort by depth, then path, so results do not depend on map order.
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	slices.SortFunc(paths, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "/"), strings.Count(b, "/")), cmp.Compare(a, b))
+	})
+
+	weights := make(map[string]int)
+	var manifests []string
+	var total int
+	for _, p := range paths {
+		if lang, ok := manifestLanguages[path.Base(p)]; ok {
+			if lang == "JavaScript" && slices.Contains(paths, path.Join(path.Dir(p), "tsconfig.json")) {
+				lang = "TypeScript"
+			}
+			if !slices.Contains(manifests, lang) {
+				manifests = append(manifests, lang)
+			}
+			continue
+		}
+		if lang := sourceLanguage(p); lang != "" {
+			// Every file counts, even an empty one.
+			weights[lang] += len(files[p]) + 1
+			total += len(files[p]) + 1
+		}
+	}
+
+	// Languages by source weight, heaviest first.
+	langs := make([]string, 0, len(weights))
+	for lang := range weights {
+		langs = append(langs, lang)
+	}
+	slices.SortFunc(langs, func(a, b string) int {
+		return cmp.Or(cmp.Compare(weights[b], weights[a]), cmp.Compare(a, b))
+	})
+
+	var signals []Signal
+	if len(manifests) > 0 {
+		// Of several manifests, the one whose language has the most
+		// sources wins; the first found breaks ties.
+		slices.SortStableFunc(manifests, func(a, b string) int {
+			return cmp.Compare(projectWeight(weights, b), projectWeight(weights, a))
+		})
+		for _, lang := range manifests {
+			signals = append(signals, Signal{Method: "manifest", Language: lang, Confidence: manifestConfidence})
+		}
+	}
+	for _, lang := range langs {
+		share := float64(weights[lang]) / float64(total)
+		signals = append(signals, Signal{Method: "extension", Language: lang, Confidence: 0.9 * share})
+	}
+	if len(signals) == 0 {
+		return &DetectResult{Language: "", Confidence: 0, Method: "unknown"}, ""
+	}
+
+	result := fuseSignals(signals)
+	return result, projectEntrypoint(files, paths, result.Language)
+}
+
+// sourceLanguage returns the runtime language of a source file by its
+// extension, or "" for files that are not sources of a supported language.
+// Of the languages sharing an extension, such as TypeScript and XML for
+// ".ts", the first with a runtime is taken.
+func sourceLanguage(p string) string {
+	for _, lang := range enry.GetLanguagesByExtension(p, nil, nil) {
+		if info, ok := GetRuntimeInfo(lang); ok {
+			return info.Language
+		}
+	}
+	return ""
+}
+
+// projectWeight is the source weight of a project's language. Deno
+// projects are written in TypeScript or JavaScript.
+func projectWeight(weights map[string]int, lang string) int {
+	if lang == "Deno" {
+		return weights["TypeScript"] + weights["JavaScript"]
+	}
+	return weights[lang]
+}
+
+// projectEntrypoint picks the entrypoint of a project in lang from paths,
+// which are sorted shallowest first.
+func projectEntrypoint(files map[string][]byte, paths []string, lang string) string {
+	var sources []string
+	for _, p := range paths {
+		src := sourceLanguage(p)
+		if src == lang || lang == "Deno" && (src == "TypeScript" || src == "JavaScript") {
+			sources = append(sources, p)
+		}
+	}
+
+	if lang == "JavaScript" || lang == "TypeScript" {
+		if main := packageMain(files, paths); main != "" && slices.Contains(sources, main) {
+			return main
+		}
+	}
+
+	names := entrypointNames[lang]
+	if names == nil {
+		if info, ok := GetRuntimeInfo(lang); ok {
+			names = []string{"main" + info.FileExt}
+		}
+	}
+	for _, name := range names {
+		for _, p := range sources {
+			if path.Base(p) == name {
+				return p
+			}
+		}
+	}
+
+	if re := entrypointPatterns[lang]; re != nil {
+		for _, p := range sources {
+			if re.Match(files[p]) {
+				return p
+			}
+		}
+	}
+
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return ""
+}
+
+// packageMain returns the "main" file named by the shallowest package.json,
+// relative to the project, or "".
+func packageMain(files map[string][]byte, paths []string) string {
+	for _, p := range paths {
+		if path.Base(p) != "package.json" {
+			continue
+		}
+		var pkg struct {
+			Main string `json:"main"`
+		}
+		if json.Unmarshal(files[p], &pkg) != nil || pkg.Main == "" {
+			return ""
+		}
+		return path.Join(path.Dir(p), pkg.Main)
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package langdetect
+
+import "testing"
+
+func TestDetectProject(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		language   string
+		method     string
+		entrypoint string
+	}{
+		{
+			name: "go module",
+			files: map[string]string{
+				"go.mod":         "module example.com/app\n",
+				"cmd/app/run.go": "package main\n\nfunc main() {\n\trun()\n}\n",
+				"internal/a.go":  "package internal\n",
+				"README.md":      "# app\n",
+			},
+			language:   "Go",
+			method:     "manifest",
+			entrypoint: "cmd/app/run.go",
+		},
+		{
+			name: "go module with a frontend",
+			files: map[string]string{
+				"go.mod":           "module example.com/app\n",
+				"main.go":          "package main\n\nfunc main() {}\n// a long comment to outweigh the frontend\n",
+				"web/package.json": "{}",
+				"web/index.js":     "x()\n",
+			},
+			language:   "Go",
+			method:     "manifest",
+			entrypoint: "main.go",
+		},
+		{
+			name: "python package",
+			files: map[string]string{
+				"requirements.txt":  "requests\n",
+				"app/__main__.py":   "from app import cli\ncli.run()\n",
+				"app/cli.py":        "def run(): pass\n",
+				"scripts/deploy.sh": "#!/bin/sh\n",
+			},
+			language:   "Python",
+			method:     "manifest",
+			entrypoint: "app/__main__.py",
+		},
+		{
+			name: "package.json main",
+			files: map[string]string{
+				"package.json":  `{"name": "svc", "main": "src/server.js"}`,
+				"src/server.js": "require('./routes')\n",
+				"src/routes.js": "module.exports = {}\n",
+				"src/index.js":  "// unused\n",
+			},
+			language:   "JavaScript",
+			method:     "manifest",
+			entrypoint: "src/server.js",
+		},
+		{
+			name: "typescript",
+			files: map[string]string{
+				"package.json":  "{}",
+				"tsconfig.json": "{}",
+				"src/index.ts":  "console.log(1)\n",
+			},
+			language:   "TypeScript",
+			method:     "manifest",
+			entrypoint: "src/index.ts",
+		},
+		{
+			name: "no manifest, dominant language",
+			files: map[string]string{
+				"solver.py":  "import helpers\n\nif __name__ == '__main__':\n    helpers.solve()\n",
+				"helpers.py": "def solve():\n    print(42)\n",
+				"check.js":   "1\n",
+			},
+			language:   "Python",
+			method:     "extension",
+			entrypoint: "solver.py",
+		},
+		{
+			name: "java main class",
+			files: map[string]string{
+				"Solution.java": "public class Solution {\n    public static void main(String[] args) {}\n}\n",
+				"Graph.java":    "class Graph {}\n",
+			},
+			language:   "Java",
+			method:     "extension",
+			entrypoint: "Solution.java",
+		},
+		{
+			name: "no obvious entrypoint",
+			files: map[string]string{
+				"a.rb": "puts 1\n",
+				"b.rb": "puts 2\n",
+			},
+			language: "Ruby",
+			method:   "extension",
+		},
+		{
+			name:   "no sources",
+			files:  map[string]string{"data.csv": "a,b\n", "notes.md": "hi\n"},
+			method: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string][]byte, len(tt.files))
+			for p, content := range tt.files {
+				files[p] = []byte(content)
+			}
+			result, entrypoint := DetectProject(files)
+			if result.Language != tt.language || result.Method != tt.method {
+				t.Errorf("DetectProject() = %s by %s, want %s by %s", result.Language, result.Method, tt.language, tt.method)
+			}
+			if entrypoint != tt.entrypoint {
+				t.Errorf("entrypoint = %q, want %q", entrypoint, tt.entrypoint)
+			}
+			if tt.language != "" && (result.Confidence <= 0 || result.Confidence > 1) {
+				t.Errorf("Confidence = %v, want in (0, 1]", result.Confidence)
+			}
+		})
+	}
+}
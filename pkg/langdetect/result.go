@@ -0,0 +1,32 @@
+package langdetect
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// MarshalJSON encodes r in its canonical form: language, confidence,
+// method, signals and conflicts in that order, with each signal's method,
+// language and confidence. Empty signal lists are omitted whether nil or
+// not, so equal results always encode to the same bytes and can be used as
+// cache values or compared in API responses.
+func (r DetectResult) MarshalJSON() ([]byte, error) {
+	// detectResult drops the method so encoding does not recurse.
+	type detectResult DetectResult
+	return json.Marshal(detectResult(r))
+}
+
+// Equal reports whether r and other describe the same detection: the same
+// language, method and confidence, and the same signals and conflicts in
+// the same order. Nil and empty signal lists are equal. A nil result only
+// equals another nil result.
+func (r *DetectResult) Equal(other *DetectResult) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+	return r.Language == other.Language &&
+		r.Method == other.Method &&
+		r.Confidence == other.Confidence &&
+		slices.Equal(r.Signals, other.Signals) &&
+		slices.Equal(r.Conflicts, other.Conflicts)
+}
@@ -0,0 +1,119 @@
+package langdetect
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDetectResultJSONRoundTrip(t *testing.T) {
+	d := New()
+	inputs := []struct {
+		name string
+		code string
+		opts *DetectOptions
+	}{
+		{"content", "def main():\n    print('hi')\n", nil},
+		{"conflicting filename", "package main\n\nfunc main() {}\n", &DetectOptions{Filename: "main.py", UseContent: true, UseHeuristics: true}},
+		{"undetected", "", nil},
+	}
+
+	for _, in := range inputs {
+		t.Run(in.name, func(t *testing.T) {
+			want := d.Detect(in.code, in.opts)
+
+			data, err := json.Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got DetectResult
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("round trip = %+v, want %+v", got, *want)
+			}
+
+			again, err := json.Marshal(&got)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("re-encoded = %s, want %s", again, data)
+			}
+		})
+	}
+}
+
+func TestDetectResultMarshalJSON(t *testing.T) {
+	r := DetectResult{
+		Language:   "Python",
+		Confidence: 0.9,
+		Method:     "extension",
+		Signals:    []Signal{{Method: "extension", Language: "Python", Confidence: 0.95}},
+		Conflicts:  []Signal{},
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"language":"Python","confidence":0.9,"method":"extension","signals":[{"method":"extension","language":"Python","confidence":0.95}]}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	// Pointers and values encode the same way.
+	ptr, _ := json.Marshal(&r)
+	if string(ptr) != want {
+		t.Errorf("Marshal(&r) = %s, want %s", ptr, want)
+	}
+	if strings.Contains(string(data), "conflicts") {
+		t.Error("empty conflicts should be omitted")
+	}
+}
+
+func TestDetectResultEqual(t *testing.T) {
+	base := func() *DetectResult {
+		return &DetectResult{
+			Language:   "Go",
+			Confidence: 0.8,
+			Method:     "content",
+			Signals:    []Signal{{Method: "content", Language: "Go", Confidence: 0.8}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *DetectResult)
+		want   bool
+	}{
+		{"identical", func(r *DetectResult) {}, true},
+		{"empty conflicts equal nil", func(r *DetectResult) { r.Conflicts = []Signal{} }, true},
+		{"language", func(r *DetectResult) { r.Language = "Rust" }, false},
+		{"method", func(r *DetectResult) { r.Method = "heuristic" }, false},
+		{"confidence", func(r *DetectResult) { r.Confidence = 0.7 }, false},
+		{"signal confidence", func(r *DetectResult) { r.Signals[0].Confidence = 0.5 }, false},
+		{"extra conflict", func(r *DetectResult) {
+			r.Conflicts = []Signal{{Method: "extension", Language: "Python", Confidence: 0.95}}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base()
+			tt.modify(other)
+			if got := base().Equal(other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var nilResult *DetectResult
+	if !nilResult.Equal(nil) {
+		t.Error("nil results should be equal")
+	}
+	if base().Equal(nil) || nilResult.Equal(base()) {
+		t.Error("nil should not equal a result")
+	}
+}
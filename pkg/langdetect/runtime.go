@@ -2,6 +2,7 @@ package langdetect
 
 import (
 	"strings"
+	"time"
 )
 
 // RuntimeInfo maps languages to execution details.
@@ -21,111 +22,203 @@ type RuntimeInfo struct {
 	// RunCommand is the command to execute code (args after command).
 	RunCommand []string
 
-	// CompileCmd is the optional compile step (nil if interpreted).
+	// CompileCmd is the optional compile step (nil if interpreted). It
+	// writes its output relative to the working directory, so runs in
+	// separate workdirs never share a binary.
 	CompileCmd []string
 
+	// TypicalCompileTime is roughly how long a small program takes to
+	// build, whether in CompileCmd or inside RunCommand (e.g., "go run").
+	// Zero for languages that run source directly.
+	TypicalCompileTime time.Duration
+
+	// MinMemoryMB is the memory, in megabytes, a small program needs to
+	// build and run, for toolchains such as javac or rustc that need more
+	// than the default sandbox allows. Zero means the default suffices.
+	MinMemoryMB int
+
 	// DockerImage is the default Docker image for this language.
 	DockerImage string
 
+	// ImageTemplate builds a versioned Docker image, with "{version}"
+	// replaced by the requested version (e.g., "python:{version}-slim").
+	ImageTemplate string
+
+	// Versions lists the versions accepted in runtime specs like "Python@3.11".
+	Versions []string
+
 	// REPLMode indicates if bare expressions produce output.
 	REPLMode bool
+
+	// ExitCodeInterpreter decides whether a run logically succeeded, for
+	// runtimes whose exit codes do not reflect errors. Nil means exit == 0.
+	ExitCodeInterpreter ExitCodeInterpreter
+}
+
+// ExitCodeInterpreter reports whether a run that exited with exitCode and
+// wrote stderr succeeded.
+//
+// To normalize a custom runtime, set it on the runtime's entry in
+// DefaultRuntimes before executing code:
+//
+//	langdetect.DefaultRuntimes["MyLang"] = &langdetect.RuntimeInfo{
+//		Language:   "MyLang",
+//		RunCommand: []string{"mylang"},
+//		ExitCodeInterpreter: func(exitCode int, stderr string) bool {
+//			return exitCode == 0 || exitCode == 2 // 2 means warnings only
+//		},
+//	}
+type ExitCodeInterpreter func(exitCode int, stderr string) bool
+
+// Succeeded reports whether a run of this runtime succeeded, using
+// ExitCodeInterpreter when set.
+func (r *RuntimeInfo) Succeeded(exitCode int, stderr string) bool {
+	if r.ExitCodeInterpreter != nil {
+		return r.ExitCodeInterpreter(exitCode, stderr)
+	}
+	return exitCode == 0
+}
+
+// stderrFailure treats any stderr output as failure, for runtimes such as
+// sqlite3 that report errors but still exit 0.
+func stderrFailure(exitCode int, stderr string) bool {
+	return exitCode == 0 && strings.TrimSpace(stderr) == ""
 }
 
 // DefaultRuntimes provides default runtime configurations.
 var DefaultRuntimes = map[string]*RuntimeInfo{
 	"Python": {
-		Language:    "Python",
-		Aliases:     []string{"python", "python3", "py"},
-		Runtime:     "python3",
-		FileExt:     ".py",
-		RunCommand:  []string{"python3"},
-		DockerImage: "python:3.12-slim",
-		REPLMode:    false,
+		Language:      "Python",
+		Aliases:       []string{"python", "python3", "py"},
+		Runtime:       "python3",
+		FileExt:       ".py",
+		RunCommand:    []string{"python3"},
+		DockerImage:   "python:3.12-slim",
+		ImageTemplate: "python:{version}-slim",
+		Versions:      []string{"3.9", "3.10", "3.11", "3.12", "3.13"},
+		REPLMode:      false,
 	},
 	"Go": {
-		Language:    "Go",
-		Aliases:     []string{"go", "golang"},
-		Runtime:     "go",
-		FileExt:     ".go",
-		RunCommand:  []string{"go", "run"},
-		DockerImage: "golang:1.25-alpine",
-		REPLMode:    false,
+		Language:           "Go",
+		Aliases:            []string{"go", "golang"},
+		Runtime:            "go",
+		FileExt:            ".go",
+		RunCommand:         []string{"go", "run"},
+		TypicalCompileTime: 2 * time.Second,
+		DockerImage:        "golang:1.25-alpine",
+		ImageTemplate:      "golang:{version}-alpine",
+		Versions:           []string{"1.22", "1.23", "1.24", "1.25"},
+		REPLMode:           false,
 	},
 	"JavaScript": {
-		Language:    "JavaScript",
-		Aliases:     []string{"javascript", "js", "node", "nodejs"},
-		Runtime:     "node",
-		FileExt:     ".js",
-		RunCommand:  []string{"node"},
-		DockerImage: "node:22-slim",
-		REPLMode:    false,
+		Language:      "JavaScript",
+		Aliases:       []string{"javascript", "js", "node", "nodejs"},
+		Runtime:       "node",
+		FileExt:       ".js",
+		RunCommand:    []string{"node"},
+		DockerImage:   "node:22-slim",
+		ImageTemplate: "node:{version}-slim",
+		Versions:      []string{"18", "20", "22", "24"},
+		REPLMode:      false,
 	},
 	"TypeScript": {
-		Language:    "TypeScript",
-		Aliases:     []string{"typescript", "ts"},
-		Runtime:     "ts-node",
-		FileExt:     ".ts",
-		RunCommand:  []string{"npx", "ts-node"},
-		DockerImage: "node:22-slim",
-		REPLMode:    false,
+		Language:           "TypeScript",
+		Aliases:            []string{"typescript", "ts"},
+		Runtime:            "ts-node",
+		FileExt:            ".ts",
+		RunCommand:         []string{"npx", "ts-node"},
+		TypicalCompileTime: 2 * time.Second,
+		DockerImage:        "node:22-slim",
+		ImageTemplate:      "node:{version}-slim",
+		Versions:           []string{"18", "20", "22", "24"},
+		REPLMode:           false,
+	},
+	"Deno": {
+		Language:      "Deno",
+		Aliases:       []string{"deno"},
+		Runtime:       "deno",
+		FileExt:       ".ts",
+		RunCommand:    []string{"deno", "run"},
+		DockerImage:   "denoland/deno:alpine",
+		ImageTemplate: "denoland/deno:alpine-{version}",
+		Versions:      []string{"1.46.3", "2.0.6", "2.1.4"},
+		REPLMode:      false,
 	},
 	"Rust": {
-		Language:    "Rust",
-		Aliases:     []string{"rust", "rs"},
-		Runtime:     "rustc",
-		FileExt:     ".rs",
-		CompileCmd:  []string{"rustc", "-o", "/tmp/main"},
-		RunCommand:  []string{"/tmp/main"},
-		DockerImage: "rust:1.75-slim",
-		REPLMode:    false,
+		Language:           "Rust",
+		Aliases:            []string{"rust", "rs"},
+		Runtime:            "rustc",
+		FileExt:            ".rs",
+		CompileCmd:         []string{"rustc", "-o", "./main"},
+		RunCommand:         []string{"./main"},
+		TypicalCompileTime: 3 * time.Second,
+		MinMemoryMB:        1024,
+		DockerImage:        "rust:1.75-slim",
+		ImageTemplate:      "rust:{version}-slim",
+		Versions:           []string{"1.75", "1.80", "1.85"},
+		REPLMode:           false,
 	},
 	"Java": {
-		Language:    "Java",
-		Aliases:     []string{"java"},
-		Runtime:     "java",
-		FileExt:     ".java",
-		CompileCmd:  []string{"javac"},
-		RunCommand:  []string{"java"},
-		DockerImage: "eclipse-temurin:21-jdk",
-		REPLMode:    false,
+		Language:           "Java",
+		Aliases:            []string{"java"},
+		Runtime:            "java",
+		FileExt:            ".java",
+		CompileCmd:         []string{"javac"},
+		RunCommand:         []string{"java"},
+		TypicalCompileTime: 2 * time.Second,
+		MinMemoryMB:        1024,
+		DockerImage:        "eclipse-temurin:21-jdk",
+		ImageTemplate:      "eclipse-temurin:{version}-jdk",
+		Versions:           []string{"11", "17", "21"},
+		REPLMode:           false,
 	},
 	"C": {
-		Language:    "C",
-		Aliases:     []string{"c"},
-		Runtime:     "gcc",
-		FileExt:     ".c",
-		CompileCmd:  []string{"gcc", "-o", "/tmp/main"},
-		RunCommand:  []string{"/tmp/main"},
-		DockerImage: "gcc:14",
-		REPLMode:    false,
+		Language:           "C",
+		Aliases:            []string{"c"},
+		Runtime:            "gcc",
+		FileExt:            ".c",
+		CompileCmd:         []string{"gcc", "-o", "./main"},
+		RunCommand:         []string{"./main"},
+		TypicalCompileTime: 500 * time.Millisecond,
+		DockerImage:        "gcc:14",
+		ImageTemplate:      "gcc:{version}",
+		Versions:           []string{"12", "13", "14"},
+		REPLMode:           false,
 	},
 	"C++": {
-		Language:    "C++",
-		Aliases:     []string{"cpp", "c++", "cxx"},
-		Runtime:     "g++",
-		FileExt:     ".cpp",
-		CompileCmd:  []string{"g++", "-o", "/tmp/main"},
-		RunCommand:  []string{"/tmp/main"},
-		DockerImage: "gcc:14",
-		REPLMode:    false,
+		Language:           "C++",
+		Aliases:            []string{"cpp", "c++", "cxx"},
+		Runtime:            "g++",
+		FileExt:            ".cpp",
+		CompileCmd:         []string{"g++", "-o", "./main"},
+		RunCommand:         []string{"./main"},
+		TypicalCompileTime: time.Second,
+		DockerImage:        "gcc:14",
+		ImageTemplate:      "gcc:{version}",
+		Versions:           []string{"12", "13", "14"},
+		REPLMode:           false,
 	},
 	"Ruby": {
-		Language:    "Ruby",
-		Aliases:     []string{"ruby", "rb"},
-		Runtime:     "ruby",
-		FileExt:     ".rb",
-		RunCommand:  []string{"ruby"},
-		DockerImage: "ruby:3.3-slim",
-		REPLMode:    false,
+		Language:      "Ruby",
+		Aliases:       []string{"ruby", "rb"},
+		Runtime:       "ruby",
+		FileExt:       ".rb",
+		RunCommand:    []string{"ruby"},
+		DockerImage:   "ruby:3.3-slim",
+		ImageTemplate: "ruby:{version}-slim",
+		Versions:      []string{"3.1", "3.2", "3.3"},
+		REPLMode:      false,
 	},
 	"PHP": {
-		Language:    "PHP",
-		Aliases:     []string{"php"},
-		Runtime:     "php",
-		FileExt:     ".php",
-		RunCommand:  []string{"php"},
-		DockerImage: "php:8.3-cli",
-		REPLMode:    false,
+		Language:      "PHP",
+		Aliases:       []string{"php"},
+		Runtime:       "php",
+		FileExt:       ".php",
+		RunCommand:    []string{"php"},
+		DockerImage:   "php:8.3-cli",
+		ImageTemplate: "php:{version}-cli",
+		Versions:      []string{"8.1", "8.2", "8.3"},
+		REPLMode:      false,
 	},
 	"Shell": {
 		Language:    "Shell",
@@ -146,31 +239,37 @@ var DefaultRuntimes = map[string]*RuntimeInfo{
 		REPLMode:    false,
 	},
 	"Kotlin": {
-		Language:    "Kotlin",
-		Aliases:     []string{"kotlin", "kt"},
-		Runtime:     "kotlin",
-		FileExt:     ".kt",
-		RunCommand:  []string{"kotlin"},
-		DockerImage: "zenika/kotlin:1.9",
-		REPLMode:    false,
+		Language:           "Kotlin",
+		Aliases:            []string{"kotlin", "kt"},
+		Runtime:            "kotlin",
+		FileExt:            ".kt",
+		RunCommand:         []string{"kotlin"},
+		TypicalCompileTime: 5 * time.Second,
+		MinMemoryMB:        1536,
+		DockerImage:        "zenika/kotlin:1.9",
+		REPLMode:           false,
 	},
 	"Swift": {
-		Language:    "Swift",
-		Aliases:     []string{"swift"},
-		Runtime:     "swift",
-		FileExt:     ".swift",
-		RunCommand:  []string{"swift"},
-		DockerImage: "swift:5.9",
-		REPLMode:    false,
+		Language:           "Swift",
+		Aliases:            []string{"swift"},
+		Runtime:            "swift",
+		FileExt:            ".swift",
+		RunCommand:         []string{"swift"},
+		TypicalCompileTime: 3 * time.Second,
+		MinMemoryMB:        1024,
+		DockerImage:        "swift:5.9",
+		REPLMode:           false,
 	},
 	"Scala": {
-		Language:    "Scala",
-		Aliases:     []string{"scala"},
-		Runtime:     "scala",
-		FileExt:     ".scala",
-		RunCommand:  []string{"scala"},
-		DockerImage: "sbtscala/scala-sbt:eclipse-temurin-21.0.1_12_1.9.7_3.3.1",
-		REPLMode:    false,
+		Language:           "Scala",
+		Aliases:            []string{"scala"},
+		Runtime:            "scala",
+		FileExt:            ".scala",
+		RunCommand:         []string{"scala"},
+		TypicalCompileTime: 5 * time.Second,
+		MinMemoryMB:        1536,
+		DockerImage:        "sbtscala/scala-sbt:eclipse-temurin-21.0.1_12_1.9.7_3.3.1",
+		REPLMode:           false,
 	},
 	"Perl": {
 		Language:    "Perl",
@@ -191,13 +290,15 @@ var DefaultRuntimes = map[string]*RuntimeInfo{
 		REPLMode:    false,
 	},
 	"Haskell": {
-		Language:    "Haskell",
-		Aliases:     []string{"haskell", "hs"},
-		Runtime:     "runhaskell",
-		FileExt:     ".hs",
-		RunCommand:  []string{"runhaskell"},
-		DockerImage: "haskell:9.4",
-		REPLMode:    false,
+		Language:           "Haskell",
+		Aliases:            []string{"haskell", "hs"},
+		Runtime:            "runhaskell",
+		FileExt:            ".hs",
+		RunCommand:         []string{"runhaskell"},
+		TypicalCompileTime: time.Second,
+		MinMemoryMB:        1024,
+		DockerImage:        "haskell:9.4",
+		REPLMode:           false,
 	},
 	"Elixir": {
 		Language:    "Elixir",
@@ -214,9 +315,60 @@ var DefaultRuntimes = map[string]*RuntimeInfo{
 		Runtime:     "clojure",
 		FileExt:     ".clj",
 		RunCommand:  []string{"clojure"},
+		MinMemoryMB: 1024,
 		DockerImage: "clojure:tools-deps",
 		REPLMode:    false,
 	},
+	"Dart": {
+		Language:           "Dart",
+		Aliases:            []string{"dart"},
+		Runtime:            "dart",
+		FileExt:            ".dart",
+		RunCommand:         []string{"dart", "run"},
+		TypicalCompileTime: time.Second,
+		DockerImage:        "dart:3.5",
+		REPLMode:           false,
+	},
+	"Zig": {
+		Language:           "Zig",
+		Aliases:            []string{"zig"},
+		Runtime:            "zig",
+		FileExt:            ".zig",
+		CompileCmd:         []string{"zig", "build-exe", "-femit-bin=./main"},
+		RunCommand:         []string{"./main"},
+		TypicalCompileTime: 3 * time.Second,
+		DockerImage:        "euantorano/zig:0.13.0",
+		REPLMode:           false,
+	},
+	"Nim": {
+		Language:           "Nim",
+		Aliases:            []string{"nim"},
+		Runtime:            "nim",
+		FileExt:            ".nim",
+		CompileCmd:         []string{"nim", "c", "--hints:off", "--nimcache:.nimcache", "-o:./main"},
+		RunCommand:         []string{"./main"},
+		TypicalCompileTime: 2 * time.Second,
+		DockerImage:        "nimlang/nim:2.0.8",
+		REPLMode:           false,
+	},
+	"Julia": {
+		Language:    "Julia",
+		Aliases:     []string{"julia", "jl"},
+		Runtime:     "julia",
+		FileExt:     ".jl",
+		RunCommand:  []string{"julia"},
+		DockerImage: "julia:1.10",
+		REPLMode:    false,
+	},
+	"OCaml": {
+		Language:    "OCaml",
+		Aliases:     []string{"ocaml", "ml"},
+		Runtime:     "ocaml",
+		FileExt:     ".ml",
+		RunCommand:  []string{"ocaml"},
+		DockerImage: "ocaml/opam:debian-12-ocaml-5.2",
+		REPLMode:    false,
+	},
 	"SQL": {
 		Language:    "SQL",
 		Aliases:     []string{"sql"},
@@ -225,6 +377,8 @@ var DefaultRuntimes = map[string]*RuntimeInfo{
 		RunCommand:  []string{"sqlite3", ":memory:"},
 		DockerImage: "keinos/sqlite3:latest",
 		REPLMode:    false,
+
+		ExitCodeInterpreter: stderrFailure,
 	},
 }
 
@@ -290,6 +444,51 @@ func NeedsCompilation(language string) bool {
 	return false
 }
 
+// LanguageProfile summarizes how a language runs, for budgeting timeouts
+// before executing code.
+type LanguageProfile struct {
+	// Language is the canonical language name.
+	Language string
+
+	// NeedsCompilation reports a separate compile step before the run.
+	NeedsCompilation bool
+
+	// TypicalCompileTime is roughly how long a small program takes to
+	// build, including builds done by the run command itself.
+	TypicalCompileTime time.Duration
+
+	// IsInterpreted reports that source runs directly, with no build.
+	IsInterpreted bool
+
+	// MinMemoryMB is the memory a small program needs to build and run,
+	// or zero when the default sandbox memory suffices.
+	MinMemoryMB int
+
+	// REPLCapable reports that bare expressions produce output.
+	REPLCapable bool
+}
+
+// Profile returns the language profile of r.
+func (r *RuntimeInfo) Profile() *LanguageProfile {
+	return &LanguageProfile{
+		Language:           r.Language,
+		NeedsCompilation:   r.CompileCmd != nil,
+		TypicalCompileTime: r.TypicalCompileTime,
+		IsInterpreted:      r.CompileCmd == nil && r.TypicalCompileTime == 0,
+		MinMemoryMB:        r.MinMemoryMB,
+		REPLCapable:        r.REPLMode,
+	}
+}
+
+// GetLanguageProfile returns the profile of a language by name or alias.
+func GetLanguageProfile(language string) (*LanguageProfile, bool) {
+	info, ok := GetRuntimeInfo(language)
+	if !ok {
+		return nil, false
+	}
+	return info.Profile(), true
+}
+
 // SupportedLanguages returns all supported language names.
 func SupportedLanguages() []string {
 	languages := make([]string, 0, len(DefaultRuntimes))
@@ -1,6 +1,8 @@
 package langdetect
 
 import (
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -30,6 +32,12 @@ func TestGetRuntimeInfo(t *testing.T) {
 		{"Haskell", "runhaskell", true},
 		{"Elixir", "elixir", true},
 		{"Clojure", "clojure", true},
+		{"Dart", "dart", true},
+		{"Zig", "zig", true},
+		{"Nim", "nim", true},
+		{"Julia", "julia", true},
+		{"OCaml", "ocaml", true},
+		{"Deno", "deno", true},
 		{"Unknown", "", false},
 		{"NonExistent", "", false},
 	}
@@ -94,6 +102,7 @@ func TestGetDockerImage(t *testing.T) {
 		{"Rust", "rust:1.75-slim"},
 		{"Ruby", "ruby:3.3-slim"},
 		{"PHP", "php:8.3-cli"},
+		{"Deno", "denoland/deno:alpine"},
 		{"Unknown", ""},
 	}
 
@@ -125,6 +134,12 @@ func TestGetFileExtension(t *testing.T) {
 		{"Shell", ".sh"},
 		{"R", ".R"},
 		{"Swift", ".swift"},
+		{"Dart", ".dart"},
+		{"Zig", ".zig"},
+		{"Nim", ".nim"},
+		{"Julia", ".jl"},
+		{"OCaml", ".ml"},
+		{"Deno", ".ts"},
 		{"Unknown", ""},
 	}
 
@@ -171,6 +186,9 @@ func TestNeedsCompilation(t *testing.T) {
 		{"Java", true},
 		{"C", true},
 		{"C++", true},
+		{"Zig", true},
+		{"Nim", true},
+		{"Julia", false},
 		{"Unknown", false},
 	}
 
@@ -184,6 +202,109 @@ func TestNeedsCompilation(t *testing.T) {
 	}
 }
 
+func TestGetLanguageProfile(t *testing.T) {
+	tests := []struct {
+		language        string
+		wantCompile     bool
+		wantInterpreted bool
+		wantBuildTime   bool
+	}{
+		{"Python", false, true, false},
+		{"Go", false, false, true}, // go run builds before running
+		{"Rust", true, false, true},
+		{"cpp", true, false, true},
+		{"Ruby", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			p, ok := GetLanguageProfile(tt.language)
+			if !ok {
+				t.Fatalf("GetLanguageProfile(%q) not found", tt.language)
+			}
+			if p.NeedsCompilation != tt.wantCompile {
+				t.Errorf("NeedsCompilation = %v, want %v", p.NeedsCompilation, tt.wantCompile)
+			}
+			if p.IsInterpreted != tt.wantInterpreted {
+				t.Errorf("IsInterpreted = %v, want %v", p.IsInterpreted, tt.wantInterpreted)
+			}
+			if (p.TypicalCompileTime > 0) != tt.wantBuildTime {
+				t.Errorf("TypicalCompileTime = %v", p.TypicalCompileTime)
+			}
+		})
+	}
+
+	if _, ok := GetLanguageProfile("Unknown"); ok {
+		t.Error("GetLanguageProfile(Unknown) should not be found")
+	}
+}
+
+func TestCompiledLanguagesHaveCompileTime(t *testing.T) {
+	for name, info := range DefaultRuntimes {
+		if info.CompileCmd != nil && info.TypicalCompileTime == 0 {
+			t.Errorf("%s has a compile step but no TypicalCompileTime", name)
+		}
+	}
+}
+
+func TestCompiledOutputInWorkDir(t *testing.T) {
+	for name, info := range DefaultRuntimes {
+		for _, arg := range slices.Concat(info.CompileCmd, info.RunCommand) {
+			if strings.Contains(arg, "/tmp/") {
+				t.Errorf("%s uses the shared path %q; compiled output belongs in the working directory", name, arg)
+			}
+		}
+	}
+}
+
+func TestMinMemory(t *testing.T) {
+	for _, language := range []string{"Java", "Kotlin", "Scala", "Rust"} {
+		p, _ := GetLanguageProfile(language)
+		if p.MinMemoryMB <= 512 {
+			t.Errorf("%s MinMemoryMB = %d, want above the 512MB default", language, p.MinMemoryMB)
+		}
+	}
+	if p, _ := GetLanguageProfile("Python"); p.MinMemoryMB != 0 {
+		t.Errorf("Python MinMemoryMB = %d, want 0", p.MinMemoryMB)
+	}
+}
+
+func TestRuntimeInfoSucceeded(t *testing.T) {
+	python, _ := GetRuntimeInfo("Python")
+	sql, _ := GetRuntimeInfo("SQL")
+	custom := &RuntimeInfo{
+		Language: "MyLang",
+		ExitCodeInterpreter: func(exitCode int, stderr string) bool {
+			return exitCode == 0 || exitCode == 2
+		},
+	}
+
+	tests := []struct {
+		name     string
+		info     *RuntimeInfo
+		exitCode int
+		stderr   string
+		want     bool
+	}{
+		{"default zero", python, 0, "warning", true},
+		{"default non-zero", python, 1, "", false},
+		{"sql clean", sql, 0, "", true},
+		{"sql stderr", sql, 0, "Error: near line 1: syntax error\n", false},
+		{"sql whitespace stderr", sql, 0, "\n", true},
+		{"sql non-zero", sql, 1, "", false},
+		{"custom warning exit", custom, 2, "", true},
+		{"custom failure", custom, 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.Succeeded(tt.exitCode, tt.stderr); got != tt.want {
+				t.Errorf("Succeeded(%d, %q) = %v, want %v", tt.exitCode, tt.stderr, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRuntimeRegistry(t *testing.T) {
 	r := NewRuntimeRegistry()
 
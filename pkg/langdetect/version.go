@@ -0,0 +1,54 @@
+package langdetect
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrUnknownRuntime indicates the runtime spec names an unknown language.
+	ErrUnknownRuntime = errors.New("unknown runtime")
+
+	// ErrUnsupportedVersion indicates the requested runtime version is not available.
+	ErrUnsupportedVersion = errors.New("unsupported runtime version")
+)
+
+// ParseRuntimeSpec splits a runtime spec such as "Python@3.11" into its
+// language and version. The version is empty when none is given.
+func ParseRuntimeSpec(spec string) (language, version string) {
+	language, version, _ = strings.Cut(spec, "@")
+	return strings.TrimSpace(language), strings.TrimSpace(version)
+}
+
+// ResolveRuntime looks up the runtime info for a spec and validates the
+// requested version, if any, against RuntimeInfo.Versions.
+func ResolveRuntime(spec string) (*RuntimeInfo, string, error) {
+	language, version := ParseRuntimeSpec(spec)
+	info, ok := GetRuntimeInfo(language)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownRuntime, language)
+	}
+	if version == "" {
+		return info, "", nil
+	}
+	if info.ImageTemplate == "" || !slices.Contains(info.Versions, version) {
+		return nil, "", fmt.Errorf("%w: %s@%s (available: %s)",
+			ErrUnsupportedVersion, info.Language, version, strings.Join(info.Versions, ", "))
+	}
+	return info, version, nil
+}
+
+// ResolveImage returns the Docker image for a runtime spec. Specs without a
+// version resolve to the language's default image.
+func ResolveImage(spec string) (string, error) {
+	info, version, err := ResolveRuntime(spec)
+	if err != nil {
+		return "", err
+	}
+	if version == "" {
+		return info.DockerImage, nil
+	}
+	return strings.ReplaceAll(info.ImageTemplate, "{version}", version), nil
+}
@@ -0,0 +1,79 @@
+package langdetect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRuntimeSpec(t *testing.T) {
+	tests := []struct {
+		spec        string
+		wantLang    string
+		wantVersion string
+	}{
+		{"Python", "Python", ""},
+		{"Python@3.11", "Python", "3.11"},
+		{"Node@18", "Node", "18"},
+		{" Go @ 1.22 ", "Go", "1.22"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			lang, version := ParseRuntimeSpec(tt.spec)
+			if lang != tt.wantLang {
+				t.Errorf("language = %q, want %q", lang, tt.wantLang)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %q, want %q", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestResolveImage(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr error
+	}{
+		{"Python", "python:3.12-slim", nil},
+		{"Python@3.11", "python:3.11-slim", nil},
+		{"python3@3.13", "python:3.13-slim", nil},
+		{"Node@18", "node:18-slim", nil},
+		{"Go@1.22", "golang:1.22-alpine", nil},
+		{"Java@17", "eclipse-temurin:17-jdk", nil},
+		{"Python@2.7", "", ErrUnsupportedVersion},
+		{"Lua@5.1", "", ErrUnsupportedVersion},
+		{"Brainfuck@1", "", ErrUnknownRuntime},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ResolveImage(tt.spec)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ResolveImage(%q) error = %v, want %v", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveImage(%q) error: %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveImage(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionedRuntimesListVersions(t *testing.T) {
+	for lang, info := range DefaultRuntimes {
+		if info.ImageTemplate == "" {
+			continue
+		}
+		if len(info.Versions) == 0 {
+			t.Errorf("%s has an ImageTemplate but no Versions", lang)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package sindoq
+
+import (
+	"fmt"
+
+	"github.com/happyhackingspace/sindoq/internal/provider/docker"
+	"github.com/happyhackingspace/sindoq/internal/provider/e2b"
+	"github.com/happyhackingspace/sindoq/internal/provider/kubernetes"
+	"github.com/happyhackingspace/sindoq/internal/provider/lambda"
+	"github.com/happyhackingspace/sindoq/internal/provider/podman"
+	"github.com/happyhackingspace/sindoq/internal/provider/vercel"
+	"github.com/happyhackingspace/sindoq/internal/provider/wasmer"
+)
+
+// providerConfig converts the configuration set by a With*Config option
+// into the type the provider's factory expects, starting from the
+// provider's defaults so zero fields keep them. Any other value, such as
+// nil or a provider's own config, is returned unchanged.
+func (c *Config) providerConfig() (any, error) {
+	switch cfg := c.ProviderConfig.(type) {
+	case DockerConfig:
+		out := docker.DefaultConfig()
+		out.Host = cfg.Host
+		out.APIVersion = cfg.APIVersion
+		out.TLSVerify = cfg.TLSVerify
+		out.CertPath = cfg.CertPath
+		out.RegistryAuth = cfg.RegistryAuth
+		if cfg.DefaultImage != "" {
+			out.DefaultImage = cfg.DefaultImage
+		}
+		out.DisableTimeoutWrapper = cfg.DisableTimeoutWrapper
+		out.ValidateRetries = cfg.ValidateRetries
+		out.ValidateRetryDelay = cfg.ValidateRetryDelay
+		out.CaptureImage = cfg.CaptureImage
+		out.CPUSet = cfg.CPUSet
+		return out, nil
+
+	case VercelConfig:
+		return &vercel.Config{
+			Token:      cfg.Token,
+			TeamID:     cfg.TeamID,
+			ProjectID:  cfg.ProjectID,
+			Runtime:    cfg.Runtime,
+			RateLimit:  cfg.RateLimit,
+			RateBurst:  cfg.RateBurst,
+			MaxRetries: cfg.MaxRetries,
+			HTTPClient: cfg.HTTPClient,
+		}, nil
+
+	case E2BConfig:
+		return &e2b.Config{
+			APIKey:     cfg.APIKey,
+			Template:   cfg.Template,
+			Timeout:    cfg.Timeout,
+			RateLimit:  cfg.RateLimit,
+			RateBurst:  cfg.RateBurst,
+			MaxRetries: cfg.MaxRetries,
+			HTTPClient: cfg.HTTPClient,
+		}, nil
+
+	case LambdaConfig:
+		return &lambda.Config{
+			Region:          cfg.Region,
+			FunctionName:    cfg.FunctionName,
+			Qualifier:       cfg.Qualifier,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			SessionToken:    cfg.SessionToken,
+			Profile:         cfg.Profile,
+			Languages:       cfg.Languages,
+			MemoryMB:        cfg.MemoryMB,
+			RateLimit:       cfg.RateLimit,
+			RateBurst:       cfg.RateBurst,
+			MaxRetries:      cfg.MaxRetries,
+		}, nil
+
+	case KubernetesConfig:
+		if cfg.PodTemplate != "" {
+			return nil, fmt.Errorf("kubernetes pod templates are not supported: %w", ErrInvalidConfiguration)
+		}
+		out := &kubernetes.Config{
+			KubeConfig:     cfg.KubeConfig,
+			Namespace:      cfg.Namespace,
+			Image:          cfg.Image,
+			ServiceAccount: cfg.ServiceAccount,
+		}
+		if out.Namespace == "" {
+			out.Namespace = "default"
+		}
+		if out.Image == "" {
+			out.Image = "python:3.12-slim"
+		}
+		return out, nil
+
+	case PodmanConfig:
+		out := &podman.Config{
+			URI:          cfg.URI,
+			Identity:     cfg.Identity,
+			DefaultImage: "python:3.12-slim",
+		}
+		if out.URI == "" {
+			out.URI = "unix:///run/podman/podman.sock"
+		}
+		return out, nil
+
+	case WasmerConfig:
+		out := wasmer.DefaultConfig()
+		if cfg.WasmerPath != "" {
+			out.WasmerPath = cfg.WasmerPath
+		}
+		if cfg.CacheDir != "" {
+			out.CacheDir = cfg.CacheDir
+		}
+		if cfg.TimeLimit != 0 {
+			out.TimeLimit = cfg.TimeLimit
+		}
+		if cfg.MaxMemoryMB != 0 {
+			out.MaxMemoryMB = cfg.MaxMemoryMB
+		}
+		out.EnableNetwork = cfg.EnableNetwork
+		return out, nil
+	}
+	return c.platformProviderConfig()
+}
+
+// configuredProvider returns the provider whose With*Config option set
+// ProviderConfig, or "" for any other value.
+func (c *Config) configuredProvider() string {
+	switch c.ProviderConfig.(type) {
+	case DockerConfig:
+		return "docker"
+	case VercelConfig:
+		return "vercel"
+	case E2BConfig:
+		return "e2b"
+	case LambdaConfig:
+		return "lambda"
+	case KubernetesConfig:
+		return "kubernetes"
+	case PodmanConfig:
+		return "podman"
+	case WasmerConfig:
+		return "wasmer"
+	case GVisorConfig:
+		return "gvisor"
+	case FirecrackerConfig:
+		return "firecracker"
+	case NsjailConfig:
+		return "nsjail"
+	}
+	return ""
+}
+
+// setProvider selects the named provider. A ProviderConfig set for another
+// provider is dropped, so the named one starts from its defaults instead of
+// failing on a config of the wrong type.
+func (c *Config) setProvider(name string) {
+	if p := c.configuredProvider(); p != "" && p != name {
+		c.ProviderConfig = nil
+	}
+	c.Provider = name
+}
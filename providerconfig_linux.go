@@ -0,0 +1,98 @@
+//go:build linux
+
+package sindoq
+
+import (
+	"github.com/happyhackingspace/sindoq/internal/provider/firecracker"
+	"github.com/happyhackingspace/sindoq/internal/provider/gvisor"
+	"github.com/happyhackingspace/sindoq/internal/provider/nsjail"
+)
+
+// platformProviderConfig converts the configs of the Linux-only providers.
+func (c *Config) platformProviderConfig() (any, error) {
+	switch cfg := c.ProviderConfig.(type) {
+	case GVisorConfig:
+		out := gvisor.DefaultConfig()
+		if cfg.RuntimeName != "" {
+			out.RuntimeName = cfg.RuntimeName
+		}
+		if cfg.RuntimePath != "" {
+			out.RuntimePath = cfg.RuntimePath
+		}
+		if cfg.Platform != "" {
+			out.Platform = cfg.Platform
+		}
+		if cfg.Network != "" {
+			out.Network = cfg.Network
+		}
+		if cfg.DefaultImage != "" {
+			out.DefaultImage = cfg.DefaultImage
+		}
+		out.DockerHost = cfg.DockerHost
+		out.Debug = cfg.Debug
+		out.DisableTimeoutWrapper = cfg.DisableTimeoutWrapper
+		out.Fallback = cfg.Fallback
+		if c.Logger != nil {
+			out.Warn = c.Logger.Warn
+		}
+		return out, nil
+
+	case FirecrackerConfig:
+		out := firecracker.DefaultConfig()
+		if cfg.FirecrackerBinary != "" {
+			out.FirecrackerBinary = cfg.FirecrackerBinary
+		}
+		if cfg.KernelImagePath != "" {
+			out.KernelImagePath = cfg.KernelImagePath
+		}
+		if cfg.RootDrivePath != "" {
+			out.RootDrivePath = cfg.RootDrivePath
+		}
+		if cfg.VCPUCount != 0 {
+			out.VCPUCount = cfg.VCPUCount
+		}
+		if cfg.MemSizeMiB != 0 {
+			out.MemSizeMiB = cfg.MemSizeMiB
+		}
+		if cfg.SocketDir != "" {
+			out.SocketDir = cfg.SocketDir
+		}
+		if cfg.VMIPAddress != "" {
+			out.VMIPAddress = cfg.VMIPAddress
+		}
+		out.EnableNetwork = cfg.EnableNetwork
+		out.SSHKeyPath = cfg.SSHKeyPath
+		return out, nil
+
+	case NsjailConfig:
+		out := nsjail.DefaultConfig()
+		if cfg.NsjailPath != "" {
+			out.NsjailPath = cfg.NsjailPath
+		}
+		if cfg.Chroot != "" {
+			out.Chroot = cfg.Chroot
+		}
+		if cfg.User != 0 {
+			out.User = cfg.User
+		}
+		if cfg.Group != 0 {
+			out.Group = cfg.Group
+		}
+		if cfg.TimeLimit != 0 {
+			out.TimeLimit = cfg.TimeLimit
+		}
+		if cfg.MaxMemoryMB != 0 {
+			out.MaxMemoryMB = cfg.MaxMemoryMB
+		}
+		if cfg.MaxCPUs != 0 {
+			out.MaxCPUs = cfg.MaxCPUs
+		}
+		if cfg.ReadOnlyBindMounts != nil {
+			out.ReadOnlyBindMounts = cfg.ReadOnlyBindMounts
+		}
+		out.CPUSet = cfg.CPUSet
+		out.EnableNetwork = cfg.EnableNetwork
+		return out, nil
+	}
+	return c.ProviderConfig, nil
+}
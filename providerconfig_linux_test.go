@@ -0,0 +1,34 @@
+package sindoq
+
+import (
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/provider/firecracker"
+	"github.com/happyhackingspace/sindoq/internal/provider/gvisor"
+	"github.com/happyhackingspace/sindoq/internal/provider/nsjail"
+)
+
+func TestProviderConfigLinux(t *testing.T) {
+	logger := &warnLogger{}
+	gc := constructProvider(t, func(c *Config) {
+		WithLogger(logger)(c)
+		WithGVisorFallback()(c)
+	}).(*gvisor.Config)
+	if !gc.Fallback || gc.RuntimeName != "runsc" || gc.Warn == nil {
+		t.Fatalf("gvisor config = %+v, want the fallback, default runtime and logger", gc)
+	}
+	gc.Warn("falling back")
+	if len(logger.warns) != 1 {
+		t.Errorf("logger warnings = %q, want the gVisor warning", logger.warns)
+	}
+
+	nc := constructProvider(t, WithNsjailConfig(NsjailConfig{CPUSet: "0", TimeLimit: 10})).(*nsjail.Config)
+	if nc.CPUSet != "0" || nc.TimeLimit != 10 || nc.User != 65534 {
+		t.Errorf("nsjail config = %+v, want the cpuset, time limit and default user", nc)
+	}
+
+	fc := constructProvider(t, WithFirecrackerConfig(FirecrackerConfig{SocketDir: t.TempDir(), VCPUCount: 4})).(*firecracker.Config)
+	if fc.VCPUCount != 4 || fc.MemSizeMiB == 0 {
+		t.Errorf("firecracker config = %+v, want 4 vCPUs and the default memory", fc)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sindoq
+
+// platformProviderConfig returns the config unchanged: the Linux-only
+// providers are not registered on other platforms.
+func (c *Config) platformProviderConfig() (any, error) {
+	return c.ProviderConfig, nil
+}
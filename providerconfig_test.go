@@ -0,0 +1,223 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider/docker"
+	"github.com/happyhackingspace/sindoq/internal/provider/e2b"
+	"github.com/happyhackingspace/sindoq/internal/provider/kubernetes"
+	"github.com/happyhackingspace/sindoq/internal/provider/lambda"
+	"github.com/happyhackingspace/sindoq/internal/provider/podman"
+	"github.com/happyhackingspace/sindoq/internal/provider/vercel"
+	"github.com/happyhackingspace/sindoq/internal/provider/wasmer"
+)
+
+// constructProvider converts the config set by opt and builds the provider
+// with the registered factory, as Create does. It returns the converted
+// config.
+func constructProvider(t *testing.T, opt Option) any {
+	t.Helper()
+	cfg := DefaultConfig()
+	opt(cfg)
+
+	pc, err := cfg.providerConfig()
+	if err != nil {
+		t.Fatalf("providerConfig() error = %v", err)
+	}
+	constructor, ok := factory.DefaultRegistry.GetConstructor(cfg.Provider)
+	if !ok {
+		t.Fatalf("provider %q is not registered", cfg.Provider)
+	}
+	p, err := constructor(pc)
+	if err != nil {
+		t.Fatalf("%s factory error = %v", cfg.Provider, err)
+	}
+	p.Close()
+	return pc
+}
+
+func TestProviderConfigDocker(t *testing.T) {
+	pc := constructProvider(t, WithDockerConfig(DockerConfig{
+		DisableTimeoutWrapper: true,
+		ValidateRetries:       5,
+		ValidateRetryDelay:    time.Second,
+		CaptureImage:          "tcpdump:latest",
+		CPUSet:                "2-7",
+	}))
+	dc := pc.(*docker.Config)
+	if !dc.DisableTimeoutWrapper || dc.ValidateRetries != 5 || dc.ValidateRetryDelay != time.Second {
+		t.Errorf("docker config = %+v, want the timeout wrapper disabled and 5 retries 1s apart", dc)
+	}
+	if dc.CaptureImage != "tcpdump:latest" || dc.CPUSet != "2-7" {
+		t.Errorf("CaptureImage = %q, CPUSet = %q, want tcpdump:latest and 2-7", dc.CaptureImage, dc.CPUSet)
+	}
+	if dc.DefaultImage != docker.DefaultConfig().DefaultImage {
+		t.Errorf("DefaultImage = %q, want the provider default", dc.DefaultImage)
+	}
+}
+
+func TestProviderConfigAPIProviders(t *testing.T) {
+	client := &http.Client{}
+
+	ec := constructProvider(t, WithE2BConfig(E2BConfig{APIKey: "key", RateLimit: 2, HTTPClient: client})).(*e2b.Config)
+	if ec.APIKey != "key" || ec.RateLimit != 2 || ec.HTTPClient != client {
+		t.Errorf("e2b config = %+v, want the key, rate limit and client", ec)
+	}
+
+	vc := constructProvider(t, WithVercelConfig(VercelConfig{Token: "token", RateLimit: -1, HTTPClient: client})).(*vercel.Config)
+	if vc.Token != "token" || vc.RateLimit != -1 || vc.HTTPClient != client {
+		t.Errorf("vercel config = %+v, want the token, rate limit and client", vc)
+	}
+
+	lc := constructProvider(t, WithLambdaConfig(LambdaConfig{
+		Region:          "eu-west-1",
+		FunctionName:    "runner",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		MaxRetries:      7,
+	})).(*lambda.Config)
+	if lc.Region != "eu-west-1" || lc.FunctionName != "runner" || lc.MaxRetries != 7 {
+		t.Errorf("lambda config = %+v, want the region, function and retries", lc)
+	}
+}
+
+func TestProviderConfigOthers(t *testing.T) {
+	kc := constructProvider(t, WithKubernetesConfig(KubernetesConfig{ServiceAccount: "runner"})).(*kubernetes.Config)
+	if kc.ServiceAccount != "runner" || kc.Namespace != "default" || kc.Image == "" {
+		t.Errorf("kubernetes config = %+v, want the service account and defaults", kc)
+	}
+
+	pc := constructProvider(t, WithPodmanConfig(PodmanConfig{Identity: "id"})).(*podman.Config)
+	if pc.Identity != "id" || pc.URI == "" || pc.DefaultImage == "" {
+		t.Errorf("podman config = %+v, want the identity and defaults", pc)
+	}
+
+	wc := constructProvider(t, WithWasmerConfig(WasmerConfig{CacheDir: t.TempDir(), TimeLimit: 5})).(*wasmer.Config)
+	if wc.TimeLimit != 5 || wc.WasmerPath != "wasmer" {
+		t.Errorf("wasmer config = %+v, want a 5s limit and the default binary", wc)
+	}
+
+	cfg := DefaultConfig()
+	WithKubernetesConfig(KubernetesConfig{PodTemplate: "pod.yaml"})(cfg)
+	if _, err := cfg.providerConfig(); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("providerConfig() with a pod template error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+// freshProvider drops the cached provider name before and after the test,
+// so the factory builds it from the config the test passes.
+func freshProvider(t *testing.T, name string) {
+	t.Helper()
+	constructor, ok := factory.DefaultRegistry.GetConstructor(name)
+	if !ok {
+		t.Fatalf("provider %q is not registered", name)
+	}
+	reset := func() {
+		factory.Unregister(name)
+		factory.Register(name, constructor)
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// redirectTransport sends every request to a test server, whatever its URL.
+type redirectTransport struct {
+	target string
+	mu     sync.Mutex
+	calls  []string
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.calls = append(t.calls, req.Method+" "+req.URL.Path)
+	t.mu.Unlock()
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = "http", t.target
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestE2BConfigWithHTTPClient(t *testing.T) {
+	freshProvider(t, "e2b")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /sandboxes":
+			w.Write([]byte(`{"sandboxId":"sbx-1","clientId":"c-1"}`))
+		case "POST /sandboxes/sbx-1/code/execution":
+			w.Write([]byte(`{"stdout":"1\n","exitCode":0}`))
+		case "DELETE /sandboxes/sbx-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	transport := &redirectTransport{target: srv.Listener.Addr().String()}
+
+	ctx := context.Background()
+	cfg := E2BConfig{APIKey: "key", RateLimit: -1}.WithHTTPClient(&http.Client{Transport: transport})
+	sb, err := Create(ctx, WithE2BConfig(cfg))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	result, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "1\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "1\n")
+	}
+	if err := sb.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if !slices.Contains(transport.calls, "POST /sandboxes/sbx-1/code/execution") {
+		t.Errorf("custom client sent %q, want the execution", transport.calls)
+	}
+}
+
+func TestVercelConfigWithHTTPClient(t *testing.T) {
+	freshProvider(t, "vercel")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/sandbox":
+			w.Write([]byte(`{"id":"sbx-1","runtime":"python313"}`))
+		case "POST /v1/sandbox/sbx-1/files":
+			w.Write([]byte(`{}`))
+		case "POST /v1/sandbox/sbx-1/exec":
+			w.Write([]byte(`{"stdout":"1\n","exitCode":0}`))
+		case "DELETE /v1/sandbox/sbx-1":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	transport := &redirectTransport{target: srv.Listener.Addr().String()}
+
+	ctx := context.Background()
+	cfg := VercelConfig{Token: "token", RateLimit: -1}.WithHTTPClient(&http.Client{Transport: transport})
+	sb, err := Create(ctx, WithVercelConfig(cfg))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	result, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "1\n" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "1\n")
+	}
+	if err := sb.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if !slices.Contains(transport.calls, "POST /v1/sandbox/sbx-1/exec") {
+		t.Errorf("custom client sent %q, want the execution", transport.calls)
+	}
+}
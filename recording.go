@@ -0,0 +1,220 @@
+package sindoq
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/event"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ExecutionRecord is a serializable capture of one Execute call. Records
+// written by WithRecording can be loaded with ReadRecords and replayed with
+// testutil.NewReplayProvider.
+type ExecutionRecord struct {
+	// Time is when the execution started.
+	Time time.Time `json:"time"`
+
+	// Provider and SandboxID identify where the code ran.
+	Provider  string `json:"provider"`
+	SandboxID string `json:"sandbox_id"`
+
+	// Code is the executed source.
+	Code string `json:"code"`
+
+	// Language is the language the code ran as; Detected reports whether it
+	// was auto-detected rather than set with WithLanguage.
+	Language string `json:"language"`
+	Detected bool   `json:"detected"`
+
+	// Options are the options passed to the provider.
+	Options RecordedOptions `json:"options"`
+
+	// Result is the execution result; it is nil when Error is set.
+	Result *RecordedResult `json:"result,omitempty"`
+
+	// Error is the provider error message, if the execution failed.
+	Error string `json:"error,omitempty"`
+
+	// Timeline lists the execution events with their offset from Time.
+	Timeline []RecordedEvent `json:"timeline"`
+}
+
+// RecordedOptions is the serializable form of executor.ExecutionOptions.
+// Environment values are always redacted; file contents are redacted like
+// the output.
+type RecordedOptions struct {
+	Filename         string            `json:"filename,omitempty"`
+	Timeout          time.Duration     `json:"timeout"`
+	Env              map[string]string `json:"env,omitempty"`
+	WorkDir          string            `json:"work_dir,omitempty"`
+	Stdin            string            `json:"stdin,omitempty"`
+	Files            map[string][]byte `json:"files,omitempty"`
+	TrackFileChanges bool              `json:"track_file_changes,omitempty"`
+}
+
+// RecordedResult is the serializable form of executor.ExecutionResult.
+type RecordedResult struct {
+	ExitCode    int                   `json:"exit_code"`
+	Stdout      string                `json:"stdout"`
+	Stderr      string                `json:"stderr"`
+	Duration    time.Duration         `json:"duration"`
+	CPUTime     time.Duration         `json:"cpu_time,omitempty"`
+	Language    string                `json:"language"`
+	Artifacts   []executor.Artifact   `json:"artifacts,omitempty"`
+	FileChanges []executor.FileChange `json:"file_changes,omitempty"`
+	DiskUsedMB  int64                 `json:"disk_used_mb,omitempty"`
+	Error       string                `json:"error,omitempty"`
+	Metadata    map[string]any        `json:"metadata,omitempty"`
+}
+
+// RecordedEvent is one entry in an execution timeline.
+type RecordedEvent struct {
+	Type   event.EventType `json:"type"`
+	Offset time.Duration   `json:"offset"`
+}
+
+// ExecutionResult converts the recorded result back into an
+// executor.ExecutionResult.
+func (r *RecordedResult) ExecutionResult() *executor.ExecutionResult {
+	result := &executor.ExecutionResult{
+		ExitCode:    r.ExitCode,
+		Stdout:      r.Stdout,
+		Stderr:      r.Stderr,
+		Duration:    r.Duration,
+		CPUTime:     r.CPUTime,
+		Language:    r.Language,
+		Artifacts:   r.Artifacts,
+		FileChanges: r.FileChanges,
+		DiskUsedMB:  r.DiskUsedMB,
+		Metadata:    r.Metadata,
+	}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
+	}
+	return result
+}
+
+// ReadRecords decodes the JSON records written by WithRecording.
+func ReadRecords(r io.Reader) ([]ExecutionRecord, error) {
+	var records []ExecutionRecord
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var rec ExecutionRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return records, nil
+			}
+			return records, fmt.Errorf("read record %d: %w", len(records), err)
+		}
+		records = append(records, rec)
+	}
+}
+
+// recorder serializes execution records to a writer, one JSON object per
+// line.
+type recorder struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	secrets map[string]string
+	output  *outputRedactor
+}
+
+func newRecorder(w io.Writer, secrets map[string]string, output *outputRedactor) *recorder {
+	return &recorder{enc: json.NewEncoder(w), secrets: secrets, output: output}
+}
+
+// record redacts rec and writes it.
+func (r *recorder) record(rec *ExecutionRecord) error {
+	r.redact(rec)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(rec)
+}
+
+// redact replaces environment values with a placeholder and removes secret
+// values from the code, input, files, output and errors. File contents also
+// go through the output redaction patterns, as the output already has.
+func (r *recorder) redact(rec *ExecutionRecord) {
+	if len(rec.Options.Env) > 0 {
+		env := make(map[string]string, len(rec.Options.Env))
+		for k := range rec.Options.Env {
+			env[k] = redactedPlaceholder
+		}
+		rec.Options.Env = env
+	}
+	if len(rec.Options.Files) > 0 {
+		files := make(map[string][]byte, len(rec.Options.Files))
+		for name, content := range rec.Options.Files {
+			files[name] = []byte(r.output.redact(redactSecrets(string(content), r.secrets)))
+		}
+		rec.Options.Files = files
+	}
+
+	rec.Code = redactSecrets(rec.Code, r.secrets)
+	rec.Options.Stdin = redactSecrets(rec.Options.Stdin, r.secrets)
+	rec.Error = redactSecrets(rec.Error, r.secrets)
+	if res := rec.Result; res != nil {
+		res.Stdout = redactSecrets(res.Stdout, r.secrets)
+		res.Stderr = redactSecrets(res.Stderr, r.secrets)
+		res.Error = redactSecrets(res.Error, r.secrets)
+	}
+}
+
+// newExecutionRecord captures the inputs of an execution.
+func newExecutionRecord(s *sandbox, start time.Time, code, language string, detected bool, opts *executor.ExecutionOptions) *ExecutionRecord {
+	return &ExecutionRecord{
+		Time:      start,
+		Provider:  s.providerName,
+		SandboxID: s.instance.ID(),
+		Code:      code,
+		Language:  language,
+		Detected:  detected,
+		Options: RecordedOptions{
+			Filename:         opts.Filename,
+			Timeout:          opts.Timeout,
+			Env:              opts.Env,
+			WorkDir:          opts.WorkDir,
+			Stdin:            opts.Stdin,
+			Files:            opts.Files,
+			TrackFileChanges: opts.TrackFileChanges,
+		},
+	}
+}
+
+// event appends an event to rec's timeline at the current time.
+func (rec *ExecutionRecord) event(t event.EventType) {
+	rec.Timeline = append(rec.Timeline, RecordedEvent{Type: t, Offset: time.Since(rec.Time)})
+}
+
+// finish completes rec with the execution outcome.
+func (rec *ExecutionRecord) finish(result *executor.ExecutionResult, err error) {
+	if err != nil {
+		rec.Error = err.Error()
+		rec.event(event.EventExecutionError)
+		return
+	}
+
+	rec.Result = &RecordedResult{
+		ExitCode:    result.ExitCode,
+		Stdout:      result.Stdout,
+		Stderr:      result.Stderr,
+		Duration:    result.Duration,
+		CPUTime:     result.CPUTime,
+		Language:    result.Language,
+		Artifacts:   result.Artifacts,
+		FileChanges: result.FileChanges,
+		DiskUsedMB:  result.DiskUsedMB,
+		Metadata:    result.Metadata,
+	}
+	if result.Error != nil {
+		rec.Result.Error = result.Error.Error()
+	}
+	rec.event(event.EventExecutionComplete)
+}
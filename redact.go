@@ -0,0 +1,251 @@
+package sindoq
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// redactedPlaceholder replaces secret values in error messages.
+const redactedPlaceholder = "[REDACTED]"
+
+// redactedError hides secret values in an error message while keeping the
+// original error available to errors.Is and errors.As.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactSecrets replaces every secret value in s with a placeholder.
+// Longer values are replaced first so overlapping secrets are fully hidden.
+func redactSecrets(s string, secrets map[string]string) string {
+	values := make([]string, 0, len(secrets))
+	for _, v := range secrets {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, v := range values {
+		s = strings.ReplaceAll(s, v, redactedPlaceholder)
+	}
+	return s
+}
+
+// redactError returns err with secret values removed from its message.
+func redactError(err error, secrets map[string]string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+	msg := err.Error()
+	redacted := redactSecrets(msg, secrets)
+	if redacted == msg {
+		return err
+	}
+	return &redactedError{msg: redacted, err: err}
+}
+
+// redact removes the sandbox's secret values from err.
+func (s *sandbox) redact(err error) error {
+	return redactError(err, s.config.secretValues())
+}
+
+// outputPlaceholder replaces redacted matches in program output.
+const outputPlaceholder = "***"
+
+// streamLookback is the minimum number of trailing bytes held back from each
+// streamed chunk so matches split across chunks are still redacted.
+const streamLookback = 256
+
+// outputRedactor removes secret values and configured patterns from program
+// output.
+type outputRedactor struct {
+	re       *regexp.Regexp
+	lookback int
+}
+
+// newOutputRedactor compiles patterns and the secret values into a single
+// expression. It returns nil when there is nothing to redact.
+func newOutputRedactor(patterns []string, secrets map[string]string) (*outputRedactor, error) {
+	values := make([]string, 0, len(secrets))
+	for _, v := range secrets {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	// Longer values first so overlapping secrets are fully hidden.
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	lookback := streamLookback
+	alts := make([]string, 0, len(values)+len(patterns))
+	for _, v := range values {
+		alts = append(alts, regexp.QuoteMeta(v))
+		if len(v) > lookback {
+			lookback = len(v)
+		}
+	}
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("redact pattern %q: %v: %w", p, err, ErrInvalidConfiguration)
+		}
+		alts = append(alts, "(?:"+p+")")
+	}
+	if len(alts) == 0 {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(strings.Join(alts, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("redact patterns: %v: %w", err, ErrInvalidConfiguration)
+	}
+	return &outputRedactor{re: re, lookback: lookback}, nil
+}
+
+// redact replaces every match in s.
+func (r *outputRedactor) redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	return r.re.ReplaceAllLiteralString(s, outputPlaceholder)
+}
+
+// redactResult redacts the output of an execution result in place.
+func (r *outputRedactor) redactResult(result *executor.ExecutionResult) {
+	if r == nil || result == nil {
+		return
+	}
+	result.Stdout = r.redact(result.Stdout)
+	result.Stderr = r.redact(result.Stderr)
+}
+
+// redactCommand redacts the output of a command result in place.
+func (r *outputRedactor) redactCommand(result *executor.CommandResult) {
+	if r == nil || result == nil {
+		return
+	}
+	result.Stdout = r.redact(result.Stdout)
+	result.Stderr = r.redact(result.Stderr)
+}
+
+// streamRedactor redacts streamed output chunks before passing
+// them to the wrapped handler. The tail of each stream is held back until
+// more data arrives or the stream ends, so a match split across chunks is
+// still caught.
+type streamRedactor struct {
+	r       *outputRedactor
+	handler executor.StreamHandler
+	mu      sync.Mutex
+	pending map[executor.StreamEventType]*executor.StreamEvent
+}
+
+// redactedStreams are the event types whose data is redacted, in the order
+// they are flushed.
+var redactedStreams = []executor.StreamEventType{
+	executor.StreamCompileStdout,
+	executor.StreamCompileStderr,
+	executor.StreamStdout,
+	executor.StreamStderr,
+}
+
+func newStreamRedactor(r *outputRedactor, handler executor.StreamHandler) *streamRedactor {
+	return &streamRedactor{
+		r:       r,
+		handler: handler,
+		pending: make(map[executor.StreamEventType]*executor.StreamEvent),
+	}
+}
+
+// handle is the executor.StreamHandler passed to the provider.
+func (s *streamRedactor) handle(ev *executor.StreamEvent) error {
+	switch ev.Type {
+	case executor.StreamCompileStdout, executor.StreamCompileStderr:
+		return s.write(ev)
+	case executor.StreamStdout, executor.StreamStderr:
+		// The compile step has finished once the run produces output.
+		if err := s.flush(executor.StreamCompileStdout, executor.StreamCompileStderr); err != nil {
+			return err
+		}
+		return s.write(ev)
+	case executor.StreamComplete, executor.StreamError:
+		if err := s.flush(redactedStreams...); err != nil {
+			return err
+		}
+	}
+	return s.handler(ev)
+}
+
+// write buffers ev's data and emits the part that can no longer be
+// affected by future chunks.
+func (s *streamRedactor) write(ev *executor.StreamEvent) error {
+	s.mu.Lock()
+	buf := ev.Data
+	if p := s.pending[ev.Type]; p != nil {
+		buf = p.Data + buf
+	}
+
+	// Hold back the lookback window, extended to the start of any match
+	// that reaches into it.
+	hold := len(buf) - s.r.lookback
+	if hold < 0 {
+		hold = 0
+	}
+	for _, m := range s.r.re.FindAllStringIndex(buf, -1) {
+		if m[1] > hold && m[0] < hold {
+			hold = m[0]
+			break
+		}
+	}
+
+	if hold == len(buf) {
+		delete(s.pending, ev.Type)
+	} else {
+		held := *ev
+		held.Data = buf[hold:]
+		s.pending[ev.Type] = &held
+	}
+	s.mu.Unlock()
+
+	if hold == 0 {
+		return nil
+	}
+	out := *ev
+	out.Data = s.r.redact(buf[:hold])
+	return s.handler(&out)
+}
+
+// flush emits the output held for the given stream types, in order.
+func (s *streamRedactor) flush(types ...executor.StreamEventType) error {
+	s.mu.Lock()
+	var events []*executor.StreamEvent
+	for _, t := range types {
+		if p := s.pending[t]; p != nil {
+			p.Data = s.r.redact(p.Data)
+			events = append(events, p)
+			delete(s.pending, t)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		if err := s.handler(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
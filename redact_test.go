@@ -0,0 +1,199 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestOutputRedactor(t *testing.T) {
+	r, err := newOutputRedactor([]string{`ghp_[A-Za-z0-9]+`}, map[string]string{"TOKEN": "hunter2"})
+	if err != nil {
+		t.Fatalf("newOutputRedactor() error = %v", err)
+	}
+
+	got := r.redact("token ghp_abc123 pass hunter2 done")
+	if want := "token *** pass *** done"; got != want {
+		t.Errorf("redact() = %q, want %q", got, want)
+	}
+
+	if r, _ := newOutputRedactor(nil, map[string]string{"EMPTY": ""}); r != nil {
+		t.Error("newOutputRedactor() should return nil with nothing to redact")
+	}
+
+	if _, err := newOutputRedactor([]string{"("}, nil); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("invalid pattern error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestStreamRedactorSplitMatch(t *testing.T) {
+	r, err := newOutputRedactor(nil, map[string]string{"TOKEN": "sk-live-secret"})
+	if err != nil {
+		t.Fatalf("newOutputRedactor() error = %v", err)
+	}
+
+	var stdout, stderr strings.Builder
+	var order []executor.StreamEventType
+	sr := newStreamRedactor(r, func(ev *executor.StreamEvent) error {
+		order = append(order, ev.Type)
+		switch ev.Type {
+		case executor.StreamStdout:
+			stdout.WriteString(ev.Data)
+		case executor.StreamStderr:
+			stderr.WriteString(ev.Data)
+		}
+		return nil
+	})
+
+	long := strings.Repeat("x", 2*streamLookback)
+	chunks := []*executor.StreamEvent{
+		{Type: executor.StreamStdout, Data: long + "key=sk-li"},
+		{Type: executor.StreamStderr, Data: "warn sk-live-"},
+		{Type: executor.StreamStdout, Data: "ve-secret\n"},
+		{Type: executor.StreamStderr, Data: "secret\n"},
+		{Type: executor.StreamComplete},
+	}
+	for _, ev := range chunks {
+		if err := sr.handle(ev); err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+	}
+
+	if want := long + "key=***\n"; stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if want := "warn ***\n"; stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+	if last := order[len(order)-1]; last != executor.StreamComplete {
+		t.Errorf("last event = %s, want held output flushed before complete", last)
+	}
+}
+
+func TestStreamRedactorCompileOutput(t *testing.T) {
+	r, err := newOutputRedactor(nil, map[string]string{"TOKEN": "sk-live-secret"})
+	if err != nil {
+		t.Fatalf("newOutputRedactor() error = %v", err)
+	}
+
+	var got []string
+	sr := newStreamRedactor(r, func(ev *executor.StreamEvent) error {
+		got = append(got, string(ev.Type)+":"+ev.Data)
+		return nil
+	})
+	for _, ev := range []*executor.StreamEvent{
+		{Type: executor.StreamCompileStderr, Data: "warning: sk-live-"},
+		{Type: executor.StreamCompileStderr, Data: "secret\n"},
+		{Type: executor.StreamStdout, Data: "ran\n"},
+		{Type: executor.StreamComplete},
+	} {
+		if err := sr.handle(ev); err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+	}
+
+	want := []string{"compile_stderr:warning: ***\n", "stdout:ran\n", "complete:"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSandboxRedactsOutput(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		execResult: &executor.ExecutionResult{
+			Stdout: "Authorization: Bearer abc.def\n",
+			Stderr: "using key sk-live-123\n",
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"),
+		WithSecrets(map[string]string{"API_KEY": "sk-live-123"}),
+		WithRedactPatterns([]string{`Bearer \S+`}),
+	)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.Execute(ctx, `print(1)`, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "Authorization: ***\n" {
+		t.Errorf("Stdout = %q, want pattern redacted", result.Stdout)
+	}
+	if result.Stderr != "using key ***\n" {
+		t.Errorf("Stderr = %q, want secret redacted", result.Stderr)
+	}
+
+	if _, err := Create(ctx, WithProvider("mock"), WithRedactPatterns([]string{"[unclosed"})); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Create() with invalid pattern error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxSecretFiles(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		execResult: &executor.ExecutionResult{
+			Stdout: "token is ghp_secret\n",
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	files := map[string][]byte{"token": []byte("ghp_secret\n")}
+	if _, err := Create(ctx, WithProvider("mock"), WithSecretFiles(files)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Fatalf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if mp.createOpts != nil {
+		t.Error("provider asked to create a sandbox it cannot give secret files")
+	}
+
+	mp.caps = &provider.Capabilities{SupportsSecretFiles: true}
+	sb, err := Create(ctx, WithProvider("mock"), WithSecretFiles(files))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if !mp.createOpts.SecretFiles {
+		t.Error("CreateOptions.SecretFiles should be set")
+	}
+	if _, ok := mp.createOpts.Environment["token"]; ok {
+		t.Error("secret files should not be passed as environment variables")
+	}
+
+	result, err := sb.Execute(ctx, `print(open("/run/secrets/token").read())`, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.SecretFiles["token"]; string(got) != "ghp_secret\n" {
+		t.Errorf("ExecutionOptions.SecretFiles[token] = %q, want the file contents", got)
+	}
+	if result.Stdout != "token is ***\n" {
+		t.Errorf("Stdout = %q, want secret file contents redacted", result.Stdout)
+	}
+
+	for _, name := range []string{"", "../etc/passwd", "dir/token"} {
+		_, err := Create(ctx, WithProvider("mock"), WithSecretFiles(map[string][]byte{name: []byte("x")}))
+		if !errors.Is(err, ErrInvalidConfiguration) {
+			t.Errorf("Create() with secret file %q error = %v, want ErrInvalidConfiguration", name, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package sindoq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ExecuteRequest is a self-contained execution request, suited to driving
+// sindoq as a subprocess over a pipe. Its JSON form is:
+//
+//	{
+//	  "code":       "print(open('data.txt').read())",  // required
+//	  "language":   "Python",                          // optional, auto-detected
+//	  "filename":   "main.py",                         // optional detection hint
+//	  "files":      {"data.txt": "hello"},             // optional, path -> content
+//	  "env":        {"DEBUG": "1"},                    // optional
+//	  "stdin":      "input",                           // optional
+//	  "timeout_ms": 30000,                             // optional, > 0
+//	  "provider":   "docker"                           // optional
+//	}
+//
+// Relative "files" paths are written under the working directory and may
+// not leave it. Unknown fields are rejected.
+type ExecuteRequest struct {
+	Code      string            `json:"code"`
+	Language  string            `json:"language,omitempty"`
+	Filename  string            `json:"filename,omitempty"`
+	Files     map[string]string `json:"files,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Stdin     string            `json:"stdin,omitempty"`
+	TimeoutMs int64             `json:"timeout_ms,omitempty"`
+	Provider  string            `json:"provider,omitempty"`
+}
+
+// ExecuteResponse is the JSON form of an execution outcome written by
+// ExecuteToJSON. Error is set when the execution could not run; a program
+// that ran and failed reports a non-zero ExitCode instead.
+type ExecuteResponse struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	DurationMs int64  `json:"duration_ms"`
+	Language   string `json:"language"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ParseExecuteRequest decodes and validates a single JSON ExecuteRequest.
+func ParseExecuteRequest(r io.Reader) (*ExecuteRequest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var req ExecuteRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("empty input")
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: unexpected data after request object", ErrInvalidRequest)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// Validate checks the request for missing or invalid fields.
+func (r *ExecuteRequest) Validate() error {
+	var problems []string
+	if strings.TrimSpace(r.Code) == "" {
+		problems = append(problems, `"code" is required`)
+	}
+	if r.TimeoutMs < 0 {
+		problems = append(problems, `"timeout_ms" must be positive`)
+	}
+	for path := range r.Files {
+		if strings.TrimSpace(path) == "" {
+			problems = append(problems, `"files" has an empty path`)
+			break
+		}
+		if err := provider.ValidateFileName(path); err != nil {
+			problems = append(problems, fmt.Sprintf(`"files": %v`, err))
+			break
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Execute runs the request in a new sandbox. opts configure the sandbox;
+// the request's provider, if set, overrides WithProvider.
+func (r *ExecuteRequest) Execute(ctx context.Context, opts ...Option) (*executor.ExecutionResult, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	if r.Language != "" {
+		opts = append(opts, WithRuntime(r.Language))
+	}
+	if r.Provider != "" {
+		opts = append(opts, WithProvider(r.Provider))
+	}
+
+	sb, err := Create(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer sb.Stop(context.Background())
+
+	return sb.Execute(ctx, r.Code, r.executeOptions()...)
+}
+
+// executeOptions converts the request fields into execute options.
+func (r *ExecuteRequest) executeOptions() []ExecuteOption {
+	var opts []ExecuteOption
+	if r.Language != "" {
+		opts = append(opts, WithLanguage(r.Language))
+	}
+	if r.Filename != "" {
+		opts = append(opts, WithFilename(r.Filename))
+	}
+	if len(r.Files) > 0 {
+		files := make(map[string][]byte, len(r.Files))
+		for path, content := range r.Files {
+			files[path] = []byte(content)
+		}
+		opts = append(opts, WithFiles(files))
+	}
+	if len(r.Env) > 0 {
+		opts = append(opts, WithEnv(r.Env))
+	}
+	if r.Stdin != "" {
+		opts = append(opts, WithStdin(r.Stdin))
+	}
+	if r.TimeoutMs > 0 {
+		opts = append(opts, WithExecutionTimeout(time.Duration(r.TimeoutMs)*time.Millisecond))
+	}
+	return opts
+}
+
+// ExecuteFromJSON reads a JSON ExecuteRequest from r and runs it.
+// See ExecuteRequest for the format and ExecuteRequest.Execute for opts.
+func ExecuteFromJSON(ctx context.Context, r io.Reader, opts ...Option) (*executor.ExecutionResult, error) {
+	req, err := ParseExecuteRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	return req.Execute(ctx, opts...)
+}
+
+// ExecuteToJSON writes result and err to w as a single-line JSON
+// ExecuteResponse.
+func ExecuteToJSON(w io.Writer, result *executor.ExecutionResult, err error) error {
+	var resp ExecuteResponse
+	if result != nil {
+		resp = ExecuteResponse{
+			ExitCode:   result.ExitCode,
+			Stdout:     result.Stdout,
+			Stderr:     result.Stderr,
+			DurationMs: result.Duration.Milliseconds(),
+			Language:   result.Language,
+		}
+		if result.Error != nil {
+			resp.Error = result.Error.Error()
+		}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(resp); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
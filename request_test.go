@@ -0,0 +1,103 @@
+package sindoq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestParseExecuteRequestInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", "empty input"},
+		{"malformed", `{"code": `, "unexpected EOF"},
+		{"unknown field", `{"code": "x", "lang": "Go"}`, `unknown field "lang"`},
+		{"missing code", `{"language": "Go"}`, `"code" is required`},
+		{"negative timeout", `{"code": "x", "timeout_ms": -1}`, `"timeout_ms" must be positive`},
+		{"empty file path", `{"code": "x", "files": {"": "data"}}`, `"files" has an empty path`},
+		{"file outside workdir", `{"code": "x", "files": {"../../etc/cron.d/x": "data"}}`, `"files": file "../../etc/cron.d/x" is outside the working directory`},
+		{"trailing data", `{"code": "x"} {"code": "y"}`, "unexpected data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExecuteRequest(strings.NewReader(tt.input))
+			if !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("error = %v, want ErrInvalidRequest", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteFromJSON(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	input := `{
+		"code": "print(open('data.txt').read())",
+		"language": "Python",
+		"files": {"data.txt": "hello"},
+		"env": {"DEBUG": "1"},
+		"stdin": "input",
+		"timeout_ms": 1500,
+		"provider": "mock"
+	}`
+	result, err := ExecuteFromJSON(context.Background(), strings.NewReader(input), WithProvider("docker"))
+	if err != nil {
+		t.Fatalf("ExecuteFromJSON() error = %v", err)
+	}
+	if result.Stdout != "Hello, World!\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+
+	opts := mp.instance.lastOpts
+	if opts.Language != "Python" || opts.Stdin != "input" || opts.Timeout != 1500*time.Millisecond {
+		t.Errorf("options = %+v", opts)
+	}
+	if string(opts.Files["data.txt"]) != "hello" || opts.Env["DEBUG"] != "1" {
+		t.Errorf("files = %v, env = %v", opts.Files, opts.Env)
+	}
+	if mp.createOpts.Runtime != "Python" {
+		t.Errorf("Runtime = %q, want Python", mp.createOpts.Runtime)
+	}
+}
+
+func TestExecuteToJSON(t *testing.T) {
+	var buf bytes.Buffer
+	result := &executor.ExecutionResult{ExitCode: 2, Stdout: "out", Stderr: "err", Duration: 1500 * time.Millisecond, Language: "Go"}
+	if err := ExecuteToJSON(&buf, result, nil); err != nil {
+		t.Fatalf("ExecuteToJSON() error = %v", err)
+	}
+
+	want := `{"exit_code":2,"stdout":"out","stderr":"err","duration_ms":1500,"language":"Go"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("ExecuteToJSON() = %s, want %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	ExecuteToJSON(&buf, nil, ErrInvalidRequest)
+	var resp ExecuteResponse
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if resp.Error != "invalid request" {
+		t.Errorf("Error = %q, want %q", resp.Error, "invalid request")
+	}
+}
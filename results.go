@@ -0,0 +1,94 @@
+package sindoq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// Default retention of ExecuteAsync results.
+const (
+	DefaultResultRetention    = 100
+	DefaultResultRetentionAge = time.Hour
+)
+
+// retainedResult is a finished execution kept for GetResult.
+type retainedResult struct {
+	id       string
+	result   *executor.ExecutionResult
+	finished time.Time
+}
+
+// resultStore keeps the results of the most recent asynchronous executions,
+// oldest first. It holds at most max results, none older than maxAge.
+type resultStore struct {
+	mu      sync.Mutex
+	max     int
+	maxAge  time.Duration
+	results []retainedResult
+	now     func() time.Time
+}
+
+func newResultStore(max int, maxAge time.Duration) *resultStore {
+	return &resultStore{max: max, maxAge: maxAge, now: time.Now}
+}
+
+// put retains result under id, evicting the oldest results beyond the
+// count limit.
+func (r *resultStore) put(id string, result *executor.ExecutionResult) {
+	if r.max <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+	if len(r.results) >= r.max {
+		r.results = append(r.results[:0], r.results[len(r.results)-r.max+1:]...)
+	}
+	r.results = append(r.results, retainedResult{id: id, result: result, finished: r.now()})
+}
+
+// get returns the result retained under id.
+func (r *resultStore) get(id string) (*executor.ExecutionResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire()
+	for _, rr := range r.results {
+		if rr.id == id {
+			return rr.result, true
+		}
+	}
+	return nil, false
+}
+
+// expire drops results older than maxAge. The caller holds mu.
+func (r *resultStore) expire() {
+	if r.maxAge <= 0 {
+		return
+	}
+	cutoff := r.now().Add(-r.maxAge)
+	i := 0
+	for i < len(r.results) && r.results[i].finished.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		r.results = append(r.results[:0], r.results[i:]...)
+	}
+}
+
+// newExecutionID returns a random identifier for an asynchronous execution.
+func newExecutionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "exec-" + hex.EncodeToString(b)
+}
+
+// GetResult returns the result of the ExecuteAsync call that returned id.
+// It reports false while the execution is running and once the result has
+// been evicted; see WithResultRetention.
+func (s *sandbox) GetResult(id string) (*executor.ExecutionResult, bool) {
+	return s.results.get(id)
+}
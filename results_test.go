@@ -0,0 +1,50 @@
+package sindoq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestResultStoreEvictsByCount(t *testing.T) {
+	store := newResultStore(2, 0)
+	for _, id := range []string{"a", "b", "c"} {
+		store.put(id, &executor.ExecutionResult{Stdout: id})
+	}
+
+	if _, ok := store.get("a"); ok {
+		t.Error("oldest result should be evicted")
+	}
+	for _, id := range []string{"b", "c"} {
+		if r, ok := store.get(id); !ok || r.Stdout != id {
+			t.Errorf("get(%q) = %v, %v", id, r, ok)
+		}
+	}
+}
+
+func TestResultStoreEvictsByAge(t *testing.T) {
+	now := time.Now()
+	store := newResultStore(10, time.Minute)
+	store.now = func() time.Time { return now }
+
+	store.put("old", &executor.ExecutionResult{})
+	now = now.Add(45 * time.Second)
+	store.put("new", &executor.ExecutionResult{})
+	now = now.Add(30 * time.Second)
+
+	if _, ok := store.get("old"); ok {
+		t.Error("result older than maxAge should be evicted")
+	}
+	if _, ok := store.get("new"); !ok {
+		t.Error("recent result should be retained")
+	}
+}
+
+func TestResultStoreDisabled(t *testing.T) {
+	store := newResultStore(0, 0)
+	store.put("a", &executor.ExecutionResult{})
+	if _, ok := store.get("a"); ok {
+		t.Error("retention of zero should keep nothing")
+	}
+}
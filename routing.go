@@ -0,0 +1,75 @@
+package sindoq
+
+import (
+	"slices"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// WithProviderRouting picks the provider by language, so a platform can run
+// Python and JavaScript on a fast provider and untrusted C on a stronger
+// one:
+//
+//	sindoq.WithProviderRouting(map[string]string{
+//		"Python": "nsjail", "JavaScript": "nsjail",
+//		"C": "gvisor", "C++": "gvisor",
+//	})
+//
+// Languages may be given by name or alias. Those without a route use the
+// provider set with WithProvider. A config set with a With*Config option
+// only applies to its own provider; a sandbox routed elsewhere starts from
+// that provider's defaults.
+//
+// Create routes by the language of WithRuntime. The one-shot Execute and
+// ExecuteStream detect the code's language first, as a sandbox would, and
+// create the sandbox on the routed provider with that language as its
+// runtime. A sandbox keeps the provider it was created on, so its own
+// Execute does not reroute.
+func WithProviderRouting(routes map[string]string) Option {
+	return func(c *Config) {
+		if c.ProviderRouting == nil {
+			c.ProviderRouting = make(map[string]string, len(routes))
+		}
+		for language, name := range routes {
+			c.ProviderRouting[canonicalLanguage(language)] = name
+		}
+	}
+}
+
+// canonicalLanguage returns the canonical name of language, which may be an
+// alias or a runtime spec such as "Python@3.11", or language itself if it
+// is unknown.
+func canonicalLanguage(language string) string {
+	language, _ = langdetect.ParseRuntimeSpec(language)
+	if info, ok := langdetect.GetRuntimeInfo(language); ok {
+		return info.Language
+	}
+	return language
+}
+
+// routeProvider selects the route for language, if there is one.
+func (c *Config) routeProvider(language string) {
+	if name, ok := c.ProviderRouting[canonicalLanguage(language)]; ok && language != "" {
+		c.setProvider(name)
+	}
+}
+
+// routeCode prepares the one-shot execution of code under routing: it
+// detects the language, routes the sandbox by it and returns the options
+// to create the sandbox with and to run the code in that language. Without
+// routing, or when the runtime already decides the route, opts are
+// returned unchanged with no execute options.
+func routeCode(code string, opts []Option) ([]Option, []ExecuteOption, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.ProviderRouting) == 0 || cfg.Runtime != "" || !cfg.AutoDetectLanguage {
+		return opts, nil, nil
+	}
+	language, _ := cfg.detectLanguage(langdetect.New(), code, "", nil)
+	if language == "" {
+		return nil, nil, NewError("execute", cfg.Provider, "", ErrLanguageDetectionFailed)
+	}
+	return append(slices.Clip(opts), WithRuntime(language)), []ExecuteOption{WithLanguage(language)}, nil
+}
@@ -0,0 +1,121 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// registerRoutedProvider registers a mock provider named name supporting
+// languages whose executions print the provider's name.
+func registerRoutedProvider(t *testing.T, name string, languages ...string) *mockProvider {
+	t.Helper()
+	mp := &mockProvider{
+		name: name,
+		caps: &provider.Capabilities{SupportsStreaming: true, SupportedLanguages: languages},
+		instance: &mockInstance{
+			id:     name + "-instance",
+			status: provider.StatusRunning,
+			execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+				return &executor.ExecutionResult{Stdout: name}
+			},
+		},
+	}
+	factory.Register(name, func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	t.Cleanup(func() { factory.Unregister(name) })
+	return mp
+}
+
+func TestProviderRouting(t *testing.T) {
+	fast := registerRoutedProvider(t, "mock-fast", "Python")
+	strong := registerRoutedProvider(t, "mock-strong", "C")
+	routing := WithProviderRouting(map[string]string{"py": "mock-fast", "C": "mock-strong"})
+
+	tests := []struct {
+		name     string
+		code     string
+		provider *mockProvider
+		language string
+	}{
+		{"Python", "import sys\n\ndef main():\n    print(sys.argv)\n\nmain()\n", fast, "Python"},
+		{"C", "#include <stdio.h>\n\nint main(void) {\n    printf(\"hi\\n\");\n    return 0;\n}\n", strong, "C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Execute(context.Background(), tt.code, WithProvider("mock"), routing)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Stdout != tt.provider.name {
+				t.Errorf("ran on %s, want %s", result.Stdout, tt.provider.name)
+			}
+			if got := tt.provider.createOpts.Runtime; got != tt.language {
+				t.Errorf("Runtime = %q, want %q", got, tt.language)
+			}
+			if got := tt.provider.instance.lastOpts.Language; got != tt.language {
+				t.Errorf("Language = %q, want %q", got, tt.language)
+			}
+		})
+	}
+}
+
+func TestProviderRoutingCreate(t *testing.T) {
+	registerRoutedProvider(t, "mock-strong", "C")
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	routing := WithProviderRouting(map[string]string{"C": "mock-strong"})
+	for runtime, want := range map[string]string{"c": "mock-strong", "Python": "mock", "": "mock"} {
+		sb, err := Create(ctx, WithProvider("mock"), WithRuntime(runtime), routing)
+		if err != nil {
+			t.Fatalf("Create(%q) error = %v", runtime, err)
+		}
+		if got := sb.Provider(); got != want {
+			t.Errorf("Create(%q) provider = %s, want %s", runtime, got, want)
+		}
+		sb.Stop(ctx)
+	}
+}
+
+func TestProviderRoutingUndetectable(t *testing.T) {
+	registerRoutedProvider(t, "mock-fast", "Python")
+
+	_, err := Execute(context.Background(), "???", WithProvider("mock-fast"),
+		WithProviderRouting(map[string]string{"Python": "mock-fast"}), WithStrictLanguage())
+	if !errors.Is(err, ErrLanguageDetectionFailed) {
+		t.Errorf("Execute() error = %v, want ErrLanguageDetectionFailed", err)
+	}
+}
+
+func TestProviderRoutingProviderConfig(t *testing.T) {
+	mp := registerRoutedProvider(t, "mock-strong", "C")
+	var configs []any
+	factory.Register("mock-strong", func(config any) (provider.Provider, error) {
+		configs = append(configs, config)
+		return mp, nil
+	})
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithDockerConfig(DockerConfig{Host: "tcp://docker.internal:2376"}), WithRuntime("C"),
+		WithProviderRouting(map[string]string{"C": "mock-strong"}))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if got := sb.Provider(); got != "mock-strong" {
+		t.Errorf("provider = %s, want mock-strong", got)
+	}
+	for _, config := range configs {
+		if config != nil {
+			t.Errorf("routed provider got config %#v, want the Docker config left out", config)
+		}
+	}
+}
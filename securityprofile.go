@@ -0,0 +1,135 @@
+package sindoq
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+// Security profiles for WithSecurityProfile, from the most permissive to
+// the tightest.
+const (
+	// SecurityProfileNetworkAllowed drops every capability, forbids
+	// gaining privileges and denies the system calls that reach outside
+	// the sandbox: mounting, loading kernel modules or BPF programs,
+	// tracing and reading other processes' memory, entering or creating
+	// namespaces, the kernel keyring, io_uring, perf events, NUMA
+	// policy, setting the clock, port I/O, swap, reboot and handle-based
+	// opens. That is every call Docker's default seccomp profile refuses
+	// a process without capabilities. Internet access is on.
+	SecurityProfileNetworkAllowed = "network-allowed"
+
+	// SecurityProfileComputeOnly is network-allowed without internet
+	// access, and socket(2) refuses the AF_INET, AF_INET6 and AF_PACKET
+	// families, so code can compute and use local files and pipes but
+	// not the network, even in a sandbox with a network attached. Unix
+	// sockets still work.
+	SecurityProfileComputeOnly = "compute-only"
+
+	// SecurityProfileUntrustedMinimal is compute-only with a read-only
+	// root filesystem (see WithReadonlyRootfs), and socket(2) and
+	// socketpair(2) refused for every family.
+	SecurityProfileUntrustedMinimal = "untrusted-minimal"
+)
+
+// securityProfile is what a profile expands to.
+type securityProfile struct {
+	internet       bool
+	readonlyRootfs bool
+	syscalls       provider.SyscallFilter
+}
+
+// escapeSyscalls are the system calls every profile denies: those Docker's
+// default seccomp profile refuses without capabilities, plus ptrace and
+// process_vm_*, which it allows.
+var escapeSyscalls = []string{
+	"acct", "add_key", "bpf", "clock_adjtime", "clock_settime",
+	"delete_module", "finit_module", "fsconfig", "fsmount", "fsopen",
+	"fspick", "get_mempolicy", "init_module", "io_uring_enter",
+	"io_uring_register", "io_uring_setup", "ioperm", "iopl", "kcmp",
+	"kexec_file_load", "kexec_load", "keyctl", "lookup_dcookie", "mbind",
+	"mount", "mount_setattr", "move_mount", "move_pages",
+	"name_to_handle_at", "open_by_handle_at", "open_tree",
+	"perf_event_open", "pivot_root", "process_vm_readv",
+	"process_vm_writev", "ptrace", "quotactl", "quotactl_fd", "reboot",
+	"request_key", "set_mempolicy", "setns", "settimeofday", "swapoff",
+	"swapon", "sysfs", "syslog", "umount2", "unshare", "uselib",
+	"userfaultfd", "ustat", "vhangup",
+}
+
+// namespaceCloneFlags are CLONE_NEWNS, CLONE_NEWCGROUP, CLONE_NEWUTS,
+// CLONE_NEWIPC, CLONE_NEWUSER, CLONE_NEWPID and CLONE_NEWNET, which
+// clone(2) may not set.
+const namespaceCloneFlags = 0x7e020000
+
+// syscallFilter denies escapeSyscalls and extra, creating namespaces and
+// changing the execution domain. clone3 is unimplemented, as on Docker,
+// so threads and processes are created with clone, whose flags can be
+// checked.
+func syscallFilter(extra []string, families []int) provider.SyscallFilter {
+	return provider.SyscallFilter{
+		Deny:               slices.Concat(escapeSyscalls, extra),
+		DenySocketFamilies: families,
+		DenyCloneFlags:     namespaceCloneFlags,
+		Unimplemented:      []string{"clone3"},
+		FixedPersonality:   true,
+	}
+}
+
+// networkFamilies are AF_INET, AF_INET6 and AF_PACKET.
+var networkFamilies = []int{2, 10, 17}
+
+var securityProfiles = map[string]securityProfile{
+	SecurityProfileNetworkAllowed: {
+		internet: true,
+		syscalls: syscallFilter(nil, nil),
+	},
+	SecurityProfileComputeOnly: {
+		syscalls: syscallFilter(nil, networkFamilies),
+	},
+	SecurityProfileUntrustedMinimal: {
+		readonlyRootfs: true,
+		syscalls:       syscallFilter([]string{"socket", "socketpair"}, nil),
+	},
+}
+
+// WithSecurityProfile applies a named preset of seccomp, capability and
+// network settings, so untrusted code can be locked down without writing
+// a seccomp policy. See the SecurityProfile constants for exactly what
+// each one permits. Every profile drops all capabilities, which
+// WithAddCapabilities can grant back one by one; options after it may
+// also turn internet access or the read-only root back on or off.
+//
+// Docker and nsjail support profiles; on other providers Create fails
+// with ErrCapabilityNotSupported, as it does for an unknown name with
+// ErrInvalidConfiguration. On Docker the profile's seccomp policy replaces
+// Docker's default one, refusing everything the default does and more.
+// nsjail only has internet access when its EnableNetwork config is set,
+// whatever the profile.
+func WithSecurityProfile(name string) Option {
+	return func(c *Config) {
+		c.SecurityProfile = name
+		p, ok := securityProfiles[name]
+		if !ok {
+			return
+		}
+		c.CapDrop = append(c.CapDrop, DropAll()...)
+		c.InternetAccess = p.internet
+		if p.readonlyRootfs {
+			c.ReadonlyRootfs = true
+		}
+	}
+}
+
+// applySecurityProfile sets the syscall filter of the configured profile
+// on opts.
+func (c *Config) applySecurityProfile(opts *provider.CreateOptions) error {
+	p, ok := securityProfiles[c.SecurityProfile]
+	if !ok {
+		return fmt.Errorf("security profile %q: %w", c.SecurityProfile, ErrInvalidConfiguration)
+	}
+	opts.Syscalls = &p.syscalls
+	opts.NoNewPrivileges = true
+	return nil
+}
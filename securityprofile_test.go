@@ -0,0 +1,118 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+)
+
+func TestWithSecurityProfile(t *testing.T) {
+	mp := &mockProvider{name: "mock", caps: &provider.Capabilities{SupportsSyscallFilter: true}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	tests := []struct {
+		profile        string
+		internet       bool
+		readonlyRootfs bool
+		families       []int
+		denySocket     bool
+	}{
+		{SecurityProfileNetworkAllowed, true, false, nil, false},
+		{SecurityProfileComputeOnly, false, false, networkFamilies, false},
+		{SecurityProfileUntrustedMinimal, false, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			sb, err := Create(context.Background(), WithProvider("mock"), WithSecurityProfile(tt.profile))
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer sb.Stop(context.Background())
+
+			opts := mp.createOpts
+			if !slices.Equal(opts.CapDrop, []string{"ALL"}) || !opts.NoNewPrivileges {
+				t.Errorf("CapDrop = %v, NoNewPrivileges = %v, want every capability dropped and no new privileges", opts.CapDrop, opts.NoNewPrivileges)
+			}
+			if opts.InternetAccess != tt.internet || opts.ReadonlyRootfs != tt.readonlyRootfs {
+				t.Errorf("InternetAccess = %v, ReadonlyRootfs = %v, want %v, %v", opts.InternetAccess, opts.ReadonlyRootfs, tt.internet, tt.readonlyRootfs)
+			}
+			if opts.Syscalls == nil {
+				t.Fatal("Syscalls = nil, want a filter")
+			}
+			if !slices.Contains(opts.Syscalls.Deny, "mount") || !slices.Contains(opts.Syscalls.Deny, "ptrace") {
+				t.Errorf("Deny = %v, want mount and ptrace denied", opts.Syscalls.Deny)
+			}
+			if got := slices.Contains(opts.Syscalls.Deny, "socket"); got != tt.denySocket {
+				t.Errorf("socket denied = %v, want %v", got, tt.denySocket)
+			}
+			if !slices.Equal(opts.Syscalls.DenySocketFamilies, tt.families) {
+				t.Errorf("DenySocketFamilies = %v, want %v", opts.Syscalls.DenySocketFamilies, tt.families)
+			}
+			if err := opts.Syscalls.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+
+	// Later options still apply.
+	sb, err := Create(context.Background(), WithProvider("mock"), WithSecurityProfile(SecurityProfileComputeOnly),
+		WithAddCapabilities([]string{"NET_BIND_SERVICE"}), WithInternetAccess())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sb.Stop(context.Background())
+	if !mp.createOpts.InternetAccess || !slices.Equal(mp.createOpts.CapAdd, []string{"NET_BIND_SERVICE"}) {
+		t.Errorf("InternetAccess = %v, CapAdd = %v, want later options kept", mp.createOpts.InternetAccess, mp.createOpts.CapAdd)
+	}
+}
+
+func TestWithSecurityProfileErrors(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if _, err := Create(ctx, WithProvider("mock"), WithSecurityProfile("paranoid")); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("unknown profile: Create() error = %v, want ErrInvalidConfiguration", err)
+	}
+	if _, err := Create(ctx, WithProvider("mock"), WithSecurityProfile(SecurityProfileComputeOnly)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("unsupported provider: Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
+
+// TestSecurityProfilesCoverDockerDefault checks that every profile still
+// refuses the calls Docker's default seccomp profile blocks, as it
+// replaces that profile on Docker.
+func TestSecurityProfilesCoverDockerDefault(t *testing.T) {
+	const cloneNewUser = 0x10000000
+	denied := []string{
+		"io_uring_setup", "io_uring_enter", "io_uring_register",
+		"fsopen", "fsmount", "open_tree", "move_mount", "name_to_handle_at",
+		"settimeofday", "clock_settime", "iopl", "ioperm", "kcmp",
+	}
+	for name, p := range securityProfiles {
+		t.Run(name, func(t *testing.T) {
+			f := p.syscalls
+			for _, call := range denied {
+				if !slices.Contains(f.Deny, call) {
+					t.Errorf("%s is not denied", call)
+				}
+			}
+			if f.DenyCloneFlags&cloneNewUser == 0 {
+				t.Errorf("DenyCloneFlags = %#x, want CLONE_NEWUSER refused", f.DenyCloneFlags)
+			}
+			if !slices.Contains(f.Unimplemented, "clone3") {
+				t.Errorf("Unimplemented = %v, want clone3", f.Unimplemented)
+			}
+			if !f.FixedPersonality {
+				t.Error("FixedPersonality = false, want personality(2) restricted")
+			}
+		})
+	}
+}
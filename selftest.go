@@ -0,0 +1,127 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// selfTestOutput is what every self-test program prints.
+const selfTestOutput = "Hello, World!"
+
+// selfTestPrograms maps languages to a hello-world printing selfTestOutput.
+var selfTestPrograms = map[string]string{
+	"Python":     `print("Hello, World!")`,
+	"JavaScript": `console.log("Hello, World!");`,
+	"TypeScript": `const greeting: string = "Hello, World!";` + "\n" + `console.log(greeting);`,
+	"Deno":       `console.log("Hello, World!");`,
+	"Go":         "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"Hello, World!\")\n}\n",
+	"Rust":       "fn main() {\n    println!(\"Hello, World!\");\n}\n",
+	"Java":       "class Main {\n    public static void main(String[] args) {\n        System.out.println(\"Hello, World!\");\n    }\n}\n",
+	"C":          "#include <stdio.h>\n\nint main(void) {\n    puts(\"Hello, World!\");\n    return 0;\n}\n",
+	"C++":        "#include <iostream>\n\nint main() {\n    std::cout << \"Hello, World!\" << std::endl;\n    return 0;\n}\n",
+	"Ruby":       `puts "Hello, World!"`,
+	"PHP":        `<?php echo "Hello, World!\n";`,
+	"Shell":      `echo "Hello, World!"`,
+	"R":          `cat("Hello, World!\n")`,
+	"Kotlin":     "fun main() {\n    println(\"Hello, World!\")\n}\n",
+	"Swift":      `print("Hello, World!")`,
+	"Scala":      "object Main extends App {\n  println(\"Hello, World!\")\n}\n",
+	"Perl":       `print "Hello, World!\n";`,
+	"Lua":        `print("Hello, World!")`,
+	"Haskell":    `main = putStrLn "Hello, World!"`,
+	"Elixir":     `IO.puts("Hello, World!")`,
+	"Clojure":    `(println "Hello, World!")`,
+	"Dart":       "void main() {\n  print('Hello, World!');\n}\n",
+	"Zig":        "const std = @import(\"std\");\n\npub fn main() !void {\n    try std.io.getStdOut().writer().print(\"Hello, World!\\n\", .{});\n}\n",
+	"Nim":        `echo "Hello, World!"`,
+	"Julia":      `println("Hello, World!")`,
+	"OCaml":      `print_endline "Hello, World!";;`,
+	"SQL":        `SELECT 'Hello, World!';`,
+}
+
+// SelfTestResult is the outcome of SelfTest.
+type SelfTestResult struct {
+	Provider string
+	Language string
+
+	// Success reports whether the program ran and printed the expected
+	// output.
+	Success bool
+
+	// Expected is the output the program should print; Stdout, Stderr and
+	// ExitCode are what it did, for diagnosis. They are empty if the
+	// sandbox could not be created or the program not run.
+	Expected string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+
+	// CreateDuration and ExecuteDuration time creating the sandbox and
+	// running the program; Duration is the whole test, teardown included.
+	CreateDuration  time.Duration
+	ExecuteDuration time.Duration
+	Duration        time.Duration
+}
+
+// SelfTest checks that language works end to end on the provider: it
+// creates a sandbox for the language, runs a canonical hello-world, checks
+// its output and stops the sandbox. Services can run it at startup to find
+// a misconfigured image or missing runtime before the first user request
+// does. opts configure the sandbox, such as ProviderConfig.
+//
+// When the test fails the error says why, and the result, which is
+// returned either way, holds the timings and whatever output the program
+// produced. A language without a self-test program fails with
+// ErrLanguageNotSupported before any sandbox is created.
+func SelfTest(ctx context.Context, providerName, language string, opts ...Option) (*SelfTestResult, error) {
+	result := &SelfTestResult{Provider: providerName, Language: language, Expected: selfTestOutput}
+	info, ok := langdetect.GetRuntimeInfo(language)
+	if !ok {
+		return result, NewError("selfTest", providerName, "", fmt.Errorf("%s: %w", language, ErrLanguageNotSupported))
+	}
+	result.Language = info.Language
+	program, ok := selfTestPrograms[info.Language]
+	if !ok {
+		return result, NewError("selfTest", providerName, "", fmt.Errorf("no self-test program for %s: %w", info.Language, ErrLanguageNotSupported))
+	}
+
+	start := time.Now()
+	err := result.run(ctx, program, append(slices.Clip(opts), WithProvider(providerName), WithRuntime(info.Language)))
+	result.Duration = time.Since(start)
+	result.Success = err == nil
+	return result, err
+}
+
+// run creates the sandbox, runs program and checks its output.
+func (r *SelfTestResult) run(ctx context.Context, program string, opts []Option) error {
+	start := time.Now()
+	sb, err := Create(ctx, opts...)
+	r.CreateDuration = time.Since(start)
+	if err != nil {
+		return err
+	}
+	defer sb.Stop(context.WithoutCancel(ctx))
+
+	start = time.Now()
+	result, err := sb.Execute(ctx, program, WithLanguage(r.Language))
+	r.ExecuteDuration = time.Since(start)
+	if err != nil {
+		return err
+	}
+	r.Stdout, r.Stderr, r.ExitCode = result.Stdout, result.Stderr, result.ExitCode
+
+	if result.ExitCode != 0 {
+		return NewError("selfTest", r.Provider, sb.ID(), NewExecutionError(result.ExitCode, result.Stdout, result.Stderr,
+			errors.New("hello-world exited with an error")))
+	}
+	if got := strings.TrimSpace(result.Stdout); got != selfTestOutput {
+		return NewError("selfTest", r.Provider, sb.ID(), fmt.Errorf("hello-world printed %q, want %q", got, selfTestOutput))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+func TestSelfTest(t *testing.T) {
+	// The mock prints what a hello-world for Python or Go would, and
+	// fails like a missing runtime for anything else.
+	inst := &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			if opts.Language != "Python" && opts.Language != "Go" {
+				return &executor.ExecutionResult{ExitCode: 127, Stderr: "sh: 1: bash: not found\n"}
+			}
+			if strings.Contains(code, `"Hello, World!"`) {
+				return &executor.ExecutionResult{Stdout: "Hello, World!\n"}
+			}
+			return &executor.ExecutionResult{Stdout: "?\n"}
+		},
+	}
+	mp := &mockProvider{name: "mock", instance: inst}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+	ctx := context.Background()
+
+	for _, language := range []string{"python", "Go"} {
+		inst.stopped = false
+		result, err := SelfTest(ctx, "mock", language)
+		if err != nil {
+			t.Fatalf("SelfTest(%s) error = %v", language, err)
+		}
+		if !result.Success || result.Stdout != "Hello, World!\n" || result.Provider != "mock" || result.Duration < result.ExecuteDuration {
+			t.Errorf("SelfTest(%s) = %+v, want a success with the program's output", language, result)
+		}
+		if !inst.stopped || mp.createOpts.Runtime != result.Language {
+			t.Errorf("SelfTest(%s): stopped = %v, runtime = %q, want a stopped %s sandbox", language, inst.stopped, mp.createOpts.Runtime, result.Language)
+		}
+	}
+
+	result, err := SelfTest(ctx, "mock", "Shell")
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.ExitCode != 127 {
+		t.Errorf("SelfTest(Shell) error = %v, want an ExecutionError with exit code 127", err)
+	}
+	if result == nil || result.Success || result.Stderr != "sh: 1: bash: not found\n" {
+		t.Errorf("SelfTest(Shell) = %+v, want a failure with the program's stderr", result)
+	}
+
+	if result, err := SelfTest(ctx, "mock", "MyLang"); !errors.Is(err, ErrLanguageNotSupported) || result.Success {
+		t.Errorf("SelfTest(MyLang) = %+v, %v, want ErrLanguageNotSupported", result, err)
+	}
+
+	mp.createErr = errors.New("image not found")
+	if result, err := SelfTest(ctx, "mock", "Python"); !errors.Is(err, mp.createErr) || result.Success || result.Stdout != "" {
+		t.Errorf("SelfTest() with a failing Create = %+v, %v", result, err)
+	}
+}
+
+func TestSelfTestPrograms(t *testing.T) {
+	for language := range selfTestPrograms {
+		if info, ok := langdetect.GetRuntimeInfo(language); !ok || info.Language != language {
+			t.Errorf("self-test program for unknown language %q", language)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package sindoq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ServeRequest is one line of the Serve protocol. It extends
+// ExecuteRequest with routing fields:
+//
+//	{
+//	  "id":      "42",        // echoed on every response line for the request
+//	  "session": "notebook",  // optional, requests sharing it reuse a sandbox
+//	  "op":      "execute",   // "execute" (default) or "close"
+//	  "stream":  true,        // optional, send output as it arrives
+//	  "code":    "print(1)",  // and the other ExecuteRequest fields
+//	}
+//
+// A session's sandbox is created by its first request, from that request's
+// language and provider, and lives until a "close" request or the end of
+// input. Requests without a session run in a sandbox of their own.
+type ServeRequest struct {
+	ID      string `json:"id,omitempty"`
+	Session string `json:"session,omitempty"`
+	Op      string `json:"op,omitempty"`
+	Stream  bool   `json:"stream,omitempty"`
+	ExecuteRequest
+}
+
+// Serve protocol operations.
+const (
+	ServeOpExecute = "execute"
+	ServeOpClose   = "close"
+)
+
+// Serve response types. Every request gets exactly one ServeResult line,
+// after any output lines.
+const (
+	ServeStdout = "stdout"
+	ServeStderr = "stderr"
+	ServeResult = "result"
+)
+
+// ServeResponse is one line written by Serve. Output lines carry Data;
+// result lines carry the ExecuteResponse fields, with Error set when the
+// request was invalid or could not run.
+type ServeResponse struct {
+	ID      string `json:"id,omitempty"`
+	Session string `json:"session,omitempty"`
+	Type    string `json:"type"`
+	Data    string `json:"data,omitempty"`
+	*ExecuteResponse
+}
+
+// Serve reads newline-delimited JSON ServeRequests from r and writes
+// newline-delimited JSON ServeResponses to w, so that any language can
+// drive sindoq as a long-lived subprocess over pipes. opts configure every
+// sandbox Serve creates.
+//
+// Requests run concurrently, except that requests of one session run in
+// the order received. Responses of concurrent requests interleave; match
+// them by ID. At most 16 requests without a session run at once; reading
+// input waits for one of them to finish. At the end of input Serve waits
+// for running requests, stops all session sandboxes and returns the read
+// error, if any.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) error {
+	s := &server{
+		opts:      opts,
+		out:       w,
+		sessions:  make(map[string]*serveSession),
+		unsession: make(chan struct{}, serveMaxUnsessioned),
+	}
+	defer s.closeAll()
+	defer s.wg.Wait()
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			s.dispatch(ctx, line)
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serveMaxUnsessioned is how many requests without a session Serve runs
+// at once.
+const serveMaxUnsessioned = 16
+
+// server holds the state of one Serve call.
+type server struct {
+	opts []Option
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	// wg tracks request and session goroutines; unsession is a semaphore
+	// over requests without a session.
+	wg        sync.WaitGroup
+	unsession chan struct{}
+
+	mu       sync.Mutex
+	sessions map[string]*serveSession
+}
+
+// serveSession is a sandbox kept warm between requests. Its requests queue
+// in pending and run in order on one goroutine, which runs while the queue
+// is not empty and alone uses sb. sb is nil until the first request
+// creates it.
+type serveSession struct {
+	mu      sync.Mutex
+	pending []*ServeRequest
+	running bool
+
+	sb Sandbox
+}
+
+// dispatch parses line and hands it to a goroutine: the session's, so its
+// requests keep their order, or one of its own. It blocks only while the
+// requests without a session are at their limit.
+func (s *server) dispatch(ctx context.Context, line []byte) {
+	req, err := parseServeRequest(line)
+	if err != nil {
+		s.write(&ServeResponse{ID: req.ID, Session: req.Session, Type: ServeResult, ExecuteResponse: &ExecuteResponse{Error: err.Error()}})
+		return
+	}
+
+	if req.Session != "" {
+		s.enqueue(ctx, s.session(req.Session, req.Op == ServeOpClose), req)
+		return
+	}
+	s.unsession <- struct{}{}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.unsession }()
+		s.handle(ctx, req, nil)
+	}()
+}
+
+// enqueue queues req on sess, starting the session's goroutine if it is
+// idle.
+func (s *server) enqueue(ctx context.Context, sess *serveSession, req *ServeRequest) {
+	sess.mu.Lock()
+	sess.pending = append(sess.pending, req)
+	start := !sess.running
+	sess.running = true
+	sess.mu.Unlock()
+
+	if start {
+		s.wg.Add(1)
+		go s.run(ctx, sess)
+	}
+}
+
+// run handles the session's queued requests until the queue is empty.
+func (s *server) run(ctx context.Context, sess *serveSession) {
+	defer s.wg.Done()
+	for {
+		sess.mu.Lock()
+		if len(sess.pending) == 0 {
+			sess.running = false
+			sess.mu.Unlock()
+			return
+		}
+		req := sess.pending[0]
+		sess.pending = sess.pending[1:]
+		sess.mu.Unlock()
+
+		s.handle(ctx, req, sess)
+	}
+}
+
+// parseServeRequest decodes and validates a request line. The returned
+// request carries whatever ID and session could be decoded, for the error
+// response.
+func parseServeRequest(line []byte) (*ServeRequest, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+
+	req := &ServeRequest{}
+	if err := dec.Decode(req); err != nil {
+		return req, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	switch req.Op {
+	case "", ServeOpExecute:
+		return req, req.Validate()
+	case ServeOpClose:
+		if req.Session == "" {
+			return req, fmt.Errorf(`%w: "close" requires a "session"`, ErrInvalidRequest)
+		}
+		return req, nil
+	default:
+		return req, fmt.Errorf("%w: unknown op %q", ErrInvalidRequest, req.Op)
+	}
+}
+
+// session returns the named session, registering it if new. Closing
+// unregisters it, so later requests of that name start a new session.
+func (s *server) session(name string, closing bool) *serveSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[name]
+	if !ok {
+		sess = &serveSession{}
+		s.sessions[name] = sess
+	}
+	if closing {
+		delete(s.sessions, name)
+	}
+	return sess
+}
+
+// handle runs req, in sess if not nil, and writes its responses. It runs
+// on the session's goroutine.
+func (s *server) handle(ctx context.Context, req *ServeRequest, sess *serveSession) {
+	resp := &ServeResponse{ID: req.ID, Session: req.Session, Type: ServeResult, ExecuteResponse: &ExecuteResponse{}}
+
+	if req.Op == ServeOpClose {
+		if err := sess.close(); err != nil {
+			resp.Error = err.Error()
+		}
+		s.write(resp)
+		return
+	}
+
+	var sb Sandbox
+	if sess != nil {
+		sb = sess.sb
+	}
+	if sb == nil {
+		created, err := Create(ctx, slices.Concat(s.opts, req.createOptions())...)
+		if err != nil {
+			resp.Error = err.Error()
+			s.write(resp)
+			return
+		}
+		sb = created
+		if sess != nil {
+			sess.sb = sb
+		} else {
+			defer sb.Stop(context.Background())
+		}
+	}
+
+	result, err := s.execute(ctx, sb, req)
+	if result != nil {
+		resp.ExecuteResponse = &ExecuteResponse{
+			ExitCode:   result.ExitCode,
+			Stdout:     result.Stdout,
+			Stderr:     result.Stderr,
+			DurationMs: result.Duration.Milliseconds(),
+			Language:   result.Language,
+		}
+		if result.Error != nil {
+			resp.Error = result.Error.Error()
+		}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	s.write(resp)
+}
+
+// execute runs req in sb, writing output lines when it streams.
+func (s *server) execute(ctx context.Context, sb Sandbox, req *ServeRequest) (*executor.ExecutionResult, error) {
+	opts := req.executeOptions()
+	if !req.Stream {
+		return sb.Execute(ctx, req.Code, opts...)
+	}
+	stdout := &serveWriter{s: s, id: req.ID, session: req.Session, typ: ServeStdout}
+	stderr := &serveWriter{s: s, id: req.ID, session: req.Session, typ: ServeStderr}
+	return sb.ExecuteTo(ctx, req.Code, stdout, stderr, opts...)
+}
+
+// createOptions are the sandbox options the request selects.
+func (r *ServeRequest) createOptions() []Option {
+	var opts []Option
+	if r.Language != "" {
+		opts = append(opts, WithRuntime(r.Language))
+	}
+	if r.Provider != "" {
+		opts = append(opts, WithProvider(r.Provider))
+	}
+	return opts
+}
+
+// write encodes resp as one line. Lines of concurrent requests never
+// interleave.
+func (s *server) write(resp *ServeResponse) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(resp); err != nil {
+		return err
+	}
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	_, err := s.out.Write(buf.Bytes())
+	return err
+}
+
+// closeAll stops the sandboxes of the sessions still open. Session
+// goroutines must have finished.
+func (s *server) closeAll() {
+	s.mu.Lock()
+	sessions := s.sessions
+	s.sessions = nil
+	s.mu.Unlock()
+
+	for _, sess := range sessions {
+		sess.close()
+	}
+}
+
+// close stops the session's sandbox. It runs on the session's goroutine,
+// or once that has finished.
+func (sess *serveSession) close() error {
+	if sess.sb == nil {
+		return nil
+	}
+	sb := sess.sb
+	sess.sb = nil
+	return sb.Stop(context.Background())
+}
+
+// serveWriter turns streamed output into ServeResponse lines.
+type serveWriter struct {
+	s       *server
+	id      string
+	session string
+	typ     string
+}
+
+func (w *serveWriter) Write(p []byte) (int, error) {
+	if err := w.s.write(&ServeResponse{ID: w.id, Session: w.session, Type: w.typ, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
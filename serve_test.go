@@ -0,0 +1,268 @@
+package sindoq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// serveProvider creates a new mockInstance for every sandbox, so tests can
+// tell sessions apart.
+type serveProvider struct {
+	mockProvider
+
+	mu        sync.Mutex
+	instances []*mockInstance
+	execFunc  func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult
+}
+
+func (p *serveProvider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inst := &mockInstance{
+		id:       "serve-" + string(rune('a'+len(p.instances))),
+		status:   provider.StatusRunning,
+		execFunc: p.execFunc,
+	}
+	p.instances = append(p.instances, inst)
+	return inst, nil
+}
+
+// created returns the instances created so far.
+func (p *serveProvider) created() []*mockInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*mockInstance(nil), p.instances...)
+}
+
+func setupServeProvider(t *testing.T) *serveProvider {
+	t.Helper()
+
+	sp := &serveProvider{mockProvider: mockProvider{name: "mock"}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return sp, nil
+	})
+	t.Cleanup(func() { factory.Unregister("mock") })
+	return sp
+}
+
+// serve runs Serve over input and decodes the response lines.
+func serve(t *testing.T, input string) []ServeResponse {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := Serve(context.Background(), strings.NewReader(input), &out, WithProvider("mock")); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	var responses []ServeResponse
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		var resp ServeResponse
+		if err := json.Unmarshal(sc.Bytes(), &resp); err != nil {
+			t.Fatalf("response %q: %v", sc.Text(), err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// results returns the result responses keyed by request ID.
+func results(responses []ServeResponse) map[string]ServeResponse {
+	m := make(map[string]ServeResponse)
+	for _, resp := range responses {
+		if resp.Type == ServeResult {
+			m[resp.ID] = resp
+		}
+	}
+	return m
+}
+
+func TestServeSessions(t *testing.T) {
+	sp := setupServeProvider(t)
+	sp.execFunc = func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{Stdout: code + "\n", Language: opts.Language}
+	}
+
+	responses := serve(t, `{"id": "1", "session": "s", "code": "x = 1", "language": "Python"}
+{"id": "2", "session": "s", "code": "print(x)"}
+{"id": "3", "code": "print(2)", "language": "Python"}
+`)
+
+	got := results(responses)
+	if len(responses) != 3 || len(got) != 3 {
+		t.Fatalf("responses = %+v, want one result per request", responses)
+	}
+	if got["2"].Stdout != "print(x)\n" || got["2"].Session != "s" || got["2"].Error != "" {
+		t.Errorf("result 2 = %+v", got["2"])
+	}
+
+	instances := sp.created()
+	if len(instances) != 2 {
+		t.Fatalf("created %d sandboxes, want 2: one for the session, one for the request without", len(instances))
+	}
+	for _, inst := range instances {
+		if !inst.stopped {
+			t.Errorf("sandbox %s not stopped at the end of input", inst.id)
+		}
+	}
+}
+
+func TestServeClose(t *testing.T) {
+	sp := setupServeProvider(t)
+
+	responses := serve(t, `{"id": "1", "session": "s", "code": "a", "language": "Shell"}
+{"id": "2", "session": "s", "op": "close"}
+{"id": "3", "session": "s", "code": "b", "language": "Shell"}
+`)
+
+	got := results(responses)
+	for _, id := range []string{"1", "2", "3"} {
+		if got[id].Error != "" {
+			t.Errorf("result %s error = %q", id, got[id].Error)
+		}
+	}
+	instances := sp.created()
+	if len(instances) != 2 {
+		t.Fatalf("created %d sandboxes, want a new one after close", len(instances))
+	}
+	if !instances[0].stopped {
+		t.Error("closed session's sandbox should be stopped")
+	}
+}
+
+func TestServeStream(t *testing.T) {
+	setupServeProvider(t)
+
+	responses := serve(t, `{"id": "1", "code": "print('Hello')", "stream": true}`)
+	if len(responses) != 2 {
+		t.Fatalf("responses = %+v, want an output line and a result", responses)
+	}
+	if out := responses[0]; out.Type != ServeStdout || out.Data != "Hello" || out.ID != "1" || out.ExecuteResponse != nil {
+		t.Errorf("output = %+v", out)
+	}
+	if res := responses[1]; res.Type != ServeResult || res.ExecuteResponse == nil || res.Error != "" {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestServeInvalid(t *testing.T) {
+	setupServeProvider(t)
+
+	responses := serve(t, `{"id": "1", "code": `+"\n"+
+		`{"id": "2", "code": "x", "lang": "Go"}`+"\n"+
+		`{"id": "3", "op": "close"}`+"\n"+
+		`{"id": "4", "session": "s", "op": "restart"}`+"\n"+
+		`{"id": "5", "session": "s"}`+"\n"+
+		"\n"+
+		`{"id": "6", "code": "ok", "language": "Shell"}`)
+
+	got := results(responses)
+	for id, want := range map[string]string{
+		"2": `unknown field "lang"`,
+		"3": `"close" requires a "session"`,
+		"4": `unknown op "restart"`,
+		"5": `"code" is required`,
+	} {
+		if !strings.Contains(got[id].Error, want) {
+			t.Errorf("result %s error = %q, want it to mention %q", id, got[id].Error, want)
+		}
+	}
+	if got[""].Error == "" {
+		t.Error("malformed line should get an error result")
+	}
+	if got["6"].Error != "" || got["6"].Stdout == "" {
+		t.Errorf("valid request after invalid ones = %+v", got["6"].ExecuteResponse)
+	}
+}
+
+func TestServeConcurrent(t *testing.T) {
+	sp := setupServeProvider(t)
+	var started sync.WaitGroup
+	started.Add(2)
+	sp.execFunc = func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		started.Done()
+		done := make(chan struct{})
+		go func() { started.Wait(); close(done) }()
+		select {
+		case <-done:
+			return &executor.ExecutionResult{Stdout: code}
+		case <-time.After(5 * time.Second):
+			return &executor.ExecutionResult{ExitCode: 1, Stderr: "not run concurrently"}
+		}
+	}
+
+	got := results(serve(t, `{"id": "1", "session": "a", "code": "a", "language": "Shell"}
+{"id": "2", "session": "b", "code": "b", "language": "Shell"}
+`))
+	for _, id := range []string{"1", "2"} {
+		if got[id].ExitCode != 0 || got[id].Error != "" {
+			t.Errorf("result %s = %+v, want the sessions to run concurrently", id, got[id].ExecuteResponse)
+		}
+	}
+}
+
+func TestServeBusySession(t *testing.T) {
+	sp := setupServeProvider(t)
+	otherRan := make(chan struct{})
+	sp.execFunc = func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		switch code {
+		case "block":
+			// Queued requests of this session must not stop the reader
+			// from dispatching the other session's request.
+			select {
+			case <-otherRan:
+			case <-time.After(5 * time.Second):
+				return &executor.ExecutionResult{ExitCode: 1, Stderr: "other session held up"}
+			}
+		case "other":
+			close(otherRan)
+		}
+		return &executor.ExecutionResult{Stdout: code}
+	}
+
+	got := results(serve(t, `{"id": "1", "session": "a", "code": "block", "language": "Shell"}
+{"id": "2", "session": "a", "code": "next", "language": "Shell"}
+{"id": "3", "session": "b", "code": "other", "language": "Shell"}
+`))
+	for _, id := range []string{"1", "2", "3"} {
+		if got[id].ExitCode != 0 || got[id].Error != "" {
+			t.Errorf("result %s = %+v", id, got[id].ExecuteResponse)
+		}
+	}
+}
+
+func TestServeUnsessionedLimit(t *testing.T) {
+	sp := setupServeProvider(t)
+	var running, peak atomic.Int32
+	sp.execFunc = func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return &executor.ExecutionResult{Stdout: code}
+	}
+
+	var input strings.Builder
+	for i := range 3 * serveMaxUnsessioned {
+		fmt.Fprintf(&input, "{\"id\": \"%d\", \"code\": \"x\", \"language\": \"Shell\"}\n", i)
+	}
+	if got := results(serve(t, input.String())); len(got) != 3*serveMaxUnsessioned {
+		t.Errorf("got %d results, want %d", len(got), 3*serveMaxUnsessioned)
+	}
+	if p := peak.Load(); p > serveMaxUnsessioned {
+		t.Errorf("peak concurrency = %d, want at most %d", p, serveMaxUnsessioned)
+	}
+}
@@ -18,7 +18,15 @@ package sindoq
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,30 +47,96 @@ type Sandbox interface {
 	// Provider returns the name of the provider.
 	Provider() string
 
+	// RuntimeInfo returns the image, language and run command the sandbox
+	// resolved when it was created.
+	RuntimeInfo() ResolvedRuntime
+
 	// Execute runs code and returns the result.
 	// Blocks until execution completes.
 	Execute(ctx context.Context, code string, opts ...ExecuteOption) (*executor.ExecutionResult, error)
 
 	// ExecuteAsync runs code asynchronously and returns immediately.
-	// Results are delivered via the returned channel.
-	ExecuteAsync(ctx context.Context, code string, opts ...ExecuteOption) (<-chan *executor.ExecutionResult, error)
+	// Results are delivered via the returned channel and are also retained
+	// under the returned execution ID for GetResult.
+	ExecuteAsync(ctx context.Context, code string, opts ...ExecuteOption) (string, <-chan *executor.ExecutionResult, error)
+
+	// GetResult returns the result of the ExecuteAsync call that returned
+	// id, once it has finished and until it is evicted.
+	GetResult(id string) (*executor.ExecutionResult, bool)
 
 	// ExecuteStream runs code with streaming output.
 	// The handler receives output events as they occur.
 	ExecuteStream(ctx context.Context, code string, handler executor.StreamHandler, opts ...ExecuteOption) error
 
+	// ExecuteTo runs code writing output to stdout and stderr as it occurs
+	// and returns the final result, including the exit code.
+	ExecuteTo(ctx context.Context, code string, stdout, stderr io.Writer, opts ...ExecuteOption) (*executor.ExecutionResult, error)
+
+	// ExecuteBatchStream runs items concurrently and reports each result
+	// through cb as it finishes.
+	ExecuteBatchStream(ctx context.Context, items []BatchItem, concurrency int, cb func(index int, r *BatchResult)) error
+
+	// ExecuteBatchEvents runs items concurrently and streams their output
+	// as events labeled with the item index on one channel.
+	ExecuteBatchEvents(ctx context.Context, items []BatchItem, concurrency int) (<-chan LabeledStreamEvent, error)
+
+	// Pipe runs stages in order, feeding each stage's stdout to the next
+	// stage's stdin, and returns the final stage's result.
+	Pipe(ctx context.Context, stages []PipeStage, opts ...PipeOption) (*executor.ExecutionResult, error)
+
+	// RunTests runs a test suite against code with a registered test
+	// framework and returns the pass, fail and skip counts.
+	RunTests(ctx context.Context, framework string, code, tests string, opts ...ExecuteOption) (*TestResult, error)
+
+	// ExecuteExpect runs code and compares its output with expected,
+	// returning whether it matched and a diff when it did not.
+	ExecuteExpect(ctx context.Context, code, expected string, opts ...ExpectOption) (*ExpectResult, error)
+
+	// Judge runs code once per case with the case's stdin and returns a
+	// verdict per case: accepted, wrong answer, time limit or runtime error.
+	Judge(ctx context.Context, code string, cases []JudgeCase, opts ...JudgeOption) (*JudgeResult, error)
+
+	// ExecuteWithManifest runs code and returns a JSON-serializable summary
+	// of the run: exit code, output, artifacts, runtime and timeline.
+	ExecuteWithManifest(ctx context.Context, code string, opts ...ExecuteOption) (*ExecutionManifest, error)
+
+	// CheckSyntax parses code with its language's syntax checker without
+	// running it and returns whether it is valid with the parse errors.
+	CheckSyntax(ctx context.Context, code string, opts ...ExecuteOption) (*SyntaxResult, error)
+
+	// Eval runs code and returns its stdout, or an error carrying the
+	// exit code and stderr if it did not succeed.
+	Eval(ctx context.Context, code string, opts ...ExecuteOption) (string, error)
+
 	// RunCommand executes a shell command in the sandbox.
 	RunCommand(ctx context.Context, cmd string, args ...string) (*executor.CommandResult, error)
 
 	// Files returns the file system interface for this sandbox.
 	Files() fs.FileSystem
 
+	// TailFile streams a file's contents and then appended bytes until
+	// ctx ends, waiting for the file if it does not exist yet.
+	TailFile(ctx context.Context, path string) (<-chan []byte, error)
+
+	// ExportFilesystem writes the tree at path to w as a tar stream.
+	ExportFilesystem(ctx context.Context, path string, w io.Writer) error
+
+	// ImportFilesystem extracts a tar stream into the directory at path.
+	ImportFilesystem(ctx context.Context, path string, r io.Reader) error
+
 	// Network returns the network interface for this sandbox (may be nil).
 	Network() provider.Network
 
 	// Subscribe registers an event callback.
 	Subscribe(eventType event.EventType, handler event.EventHandler) (unsubscribe func())
 
+	// Pause freezes the sandbox. Running executions are suspended and new
+	// ones fail with ErrSandboxPaused until Resume is called.
+	Pause(ctx context.Context) error
+
+	// Resume unfreezes a paused sandbox.
+	Resume(ctx context.Context) error
+
 	// Stop terminates the sandbox and releases resources.
 	Stop(ctx context.Context) error
 
@@ -78,9 +152,36 @@ type sandbox struct {
 	eventBus     *event.Bus
 	mu           sync.RWMutex
 	stopped      bool
+	paused       bool
 	providerName string
+	recorder     *recorder
+	output       *outputRedactor
+	results      *resultStore
+
+	// capabilities gates options that need provider support, such as
+	// network capture.
+	capabilities provider.Capabilities
+
+	// runtime is what the instance resolved at creation.
+	runtime ResolvedRuntime
+
+	// workDir is where executions run unless WithWorkDir says otherwise.
+	workDir string
+
+	// files tracks running executions for Config.FileLifetime.
+	files fileLifetime
+
+	// lifetime is canceled with ErrMaxLifetimeExceeded when the sandbox
+	// reaches Config.MaxLifetime, and with it the executions in flight.
+	// It is nil without a maximum lifetime.
+	lifetime      context.Context
+	lifetimeTimer *time.Timer
 }
 
+// ResolvedRuntime describes the image and runtime a sandbox was created
+// with.
+type ResolvedRuntime = provider.ResolvedRuntime
+
 // Create creates a new sandbox with the given options.
 // This is the primary entry point for the SDK.
 func Create(ctx context.Context, opts ...Option) (Sandbox, error) {
@@ -88,8 +189,15 @@ func Create(ctx context.Context, opts ...Option) (Sandbox, error) {
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	cfg.routeProvider(cfg.Runtime)
 
-	return createSandbox(ctx, cfg)
+	ctx, span := startSpan(ctx, cfg.Tracer, "sindoq.create", AttrProvider.String(cfg.Provider))
+	sb, err := createSandbox(ctx, cfg)
+	if err == nil {
+		span.SetAttributes(AttrSandboxID.String(sb.ID()))
+	}
+	endSpan(span, err)
+	return sb, err
 }
 
 // MustCreate creates a sandbox or panics on error.
@@ -103,29 +211,168 @@ func MustCreate(ctx context.Context, opts ...Option) Sandbox {
 }
 
 func createSandbox(ctx context.Context, cfg *Config) (Sandbox, error) {
+	var caBundle []byte
+	if cfg.hasCACerts() {
+		bundle, err := cfg.caBundle()
+		if err != nil {
+			return nil, NewError("create", cfg.Provider, "", err)
+		}
+		caBundle = bundle
+	}
+
+	for name := range cfg.SecretFiles {
+		if !executor.ValidSecretFileName(name) {
+			return nil, NewError("create", cfg.Provider, "", fmt.Errorf("secret file name %q: %w", name, ErrInvalidConfiguration))
+		}
+	}
+
+	if err := cfg.checkInterpreterPaths(); err != nil {
+		return nil, NewError("create", cfg.Provider, "", err)
+	}
+
+	if cfg.Polyglot && cfg.Image == "" {
+		return nil, NewError("create", cfg.Provider, "", fmt.Errorf("polyglot sandbox requires an image: %w", ErrInvalidConfiguration))
+	}
+
+	if !cfg.SkipMemoryMinimum {
+		applyMemoryMinimum(cfg)
+	}
+	if cfg.AutoResourceLimits {
+		if err := applyAutoResourceLimits(cfg); err != nil {
+			return nil, NewError("create", cfg.Provider, "", fmt.Errorf("read cgroup limits: %w", err))
+		}
+	}
+
+	output, err := newOutputRedactor(cfg.RedactPatterns, cfg.secretValues())
+	if err != nil {
+		return nil, NewError("create", cfg.Provider, "", err)
+	}
+
+	providerConfig, err := cfg.providerConfig()
+	if err != nil {
+		return nil, NewError("create", cfg.Provider, "", err)
+	}
+	caps, capsErr := factory.GetGlobalFactory().GetCapabilities(cfg.Provider, providerConfig)
+
+	// Only a directory sindoq chose may be swapped for the fallback.
+	workDir, fallbackWorkDir := cfg.WorkDir, ""
+	if workDir == "" {
+		workDir, fallbackWorkDir = provider.DefaultWorkDir, provider.DefaultFallbackWorkDir
+		if capsErr == nil && caps.DefaultWorkDir != "" {
+			workDir = caps.DefaultWorkDir
+		}
+	}
+
 	// Create provider options
 	createOpts := &provider.CreateOptions{
-		Image:          cfg.Image,
-		Runtime:        cfg.Runtime,
-		Resources:      cfg.Resources.ToProviderConfig(),
-		Environment:    make(map[string]string),
-		Timeout:        cfg.DefaultTimeout,
-		WorkDir:        "/workspace",
-		InternetAccess: cfg.InternetAccess,
+		Image:             cfg.Image,
+		Runtime:           cfg.Runtime,
+		Polyglot:          cfg.Polyglot,
+		Resources:         cfg.Resources.ToProviderConfig(),
+		Environment:       cfg.sandboxEnv(),
+		Timeout:           cfg.DefaultTimeout,
+		WorkDir:           workDir,
+		FallbackWorkDir:   fallbackWorkDir,
+		InternetAccess:    cfg.InternetAccess,
+		Ports:             cfg.Ports,
+		Ulimits:           cfg.Ulimits,
+		ReadonlyRootfs:    cfg.ReadonlyRootfs,
+		CapDrop:           cfg.CapDrop,
+		CapAdd:            cfg.CapAdd,
+		SecretFiles:       len(cfg.SecretFiles) > 0,
+		ReuseInterpreter:  cfg.InterpreterReuse,
+		RespectEntrypoint: cfg.RespectEntrypoint,
+	}
+
+	if cfg.RespectEntrypoint && capsErr == nil && !caps.SupportsImageEntrypoint {
+		return nil, NewError("create", cfg.Provider, "", fmt.Errorf("image entrypoint: %w", ErrCapabilityNotSupported))
+	}
+
+	if len(cfg.SecretFiles) > 0 && capsErr == nil && !caps.SupportsSecretFiles {
+		return nil, NewError("create", cfg.Provider, "", fmt.Errorf("secret files: %w", ErrCapabilityNotSupported))
+	}
+
+	if cfg.Resources.CPUSet != "" && capsErr == nil && !caps.SupportsCPUSet {
+		return nil, NewError("create", cfg.Provider, "", fmt.Errorf("cpuset: %w", ErrCapabilityNotSupported))
+	}
+
+	if cfg.SecurityProfile != "" {
+		if err := cfg.applySecurityProfile(createOpts); err != nil {
+			return nil, NewError("create", cfg.Provider, "", err)
+		}
+		if capsErr == nil && !caps.SupportsSyscallFilter {
+			return nil, NewError("create", cfg.Provider, "", fmt.Errorf("security profile %q: %w", cfg.SecurityProfile, ErrCapabilityNotSupported))
+		}
+	}
+
+	if cfg.DeterministicID {
+		if capsErr == nil && !caps.SupportsDeterministicID {
+			return nil, NewError("create", cfg.Provider, "", fmt.Errorf("deterministic ID: %w", ErrCapabilityNotSupported))
+		}
+		id, hash, err := deterministicID(cfg.Provider, createOpts)
+		if err != nil {
+			return nil, NewError("create", cfg.Provider, "", err)
+		}
+		createOpts.ID = id
+		createOpts.Labels = map[string]string{deterministicIDLabel: hash}
 	}
 
 	// Create instance via factory
-	instance, err := factory.CreateSandbox(ctx, cfg.Provider, cfg.ProviderConfig, createOpts)
+	instance, err := factory.CreateSandbox(ctx, cfg.Provider, providerConfig, createOpts)
 	if err != nil {
 		return nil, NewError("create", cfg.Provider, "", err)
 	}
 
+	// A reattached sandbox was set up by its first holder and may still be
+	// in use, so it is neither set up again nor stopped on failure.
+	reattached := false
+	if r, ok := instance.(provider.ReattachReporter); ok {
+		reattached = r.Reattached()
+	}
+	abort := func(err error) (Sandbox, error) {
+		if !reattached {
+			instance.Stop(ctx)
+		}
+		return nil, NewError("create", cfg.Provider, instance.ID(), err)
+	}
+
+	if cfg.FileLifetime == CleanPerExecution && instance.FileSystem() == nil {
+		return abort(fmt.Errorf("%s: %w", cfg.FileLifetime, ErrCapabilityNotSupported))
+	}
+
+	// The instance may have settled on the fallback workdir.
+	if r, ok := instance.(provider.WorkDirReporter); ok {
+		workDir = r.WorkDir()
+	}
+
+	if caBundle != nil && !reattached {
+		if err := installCACerts(ctx, instance, workDir, caBundle); err != nil {
+			return abort(err)
+		}
+	}
+
 	sb := &sandbox{
 		instance:     instance,
 		config:       cfg,
 		detector:     langdetect.New(),
 		eventBus:     event.NewBus(),
 		providerName: cfg.Provider,
+		output:       output,
+	}
+	if capsErr == nil {
+		sb.capabilities = *caps
+	}
+	sb.workDir = workDir
+	// Providers that resolve an image report it; for the rest the
+	// configuration is all there is.
+	if r, ok := instance.(provider.RuntimeReporter); ok {
+		sb.runtime = r.RuntimeInfo()
+	} else {
+		sb.runtime = provider.NewResolvedRuntime(cfg.Image, cfg.Runtime)
+	}
+	sb.results = newResultStore(cfg.ResultRetention, cfg.ResultRetentionAge)
+	if cfg.Recording != nil {
+		sb.recorder = newRecorder(cfg.Recording, cfg.secretValues(), output)
 	}
 
 	// Register global event handler if provided
@@ -133,8 +380,19 @@ func createSandbox(ctx context.Context, cfg *Config) (Sandbox, error) {
 		sb.eventBus.SubscribeAll(cfg.EventHandler)
 	}
 
+	if cfg.MaxLifetime > 0 {
+		lifetime, expire := context.WithCancelCause(context.Background())
+		sb.lifetime = lifetime
+		sb.mu.Lock()
+		sb.lifetimeTimer = time.AfterFunc(cfg.MaxLifetime, func() {
+			expire(ErrMaxLifetimeExceeded)
+			sb.stop(context.Background(), event.StopReasonMaxLifetime)
+		})
+		sb.mu.Unlock()
+	}
+
 	// Emit creation event
-	sb.eventBus.Emit(event.NewEvent(event.EventSandboxCreated, instance.ID(), nil))
+	sb.eventBus.Emit(event.NewEvent(event.EventSandboxCreated, instance.ID(), sb.RuntimeInfo()))
 
 	return sb, nil
 }
@@ -149,73 +407,308 @@ func (s *sandbox) Provider() string {
 	return s.providerName
 }
 
-// Execute runs code and returns the result.
-func (s *sandbox) Execute(ctx context.Context, code string, opts ...ExecuteOption) (*executor.ExecutionResult, error) {
-	s.mu.RLock()
-	if s.stopped {
-		s.mu.RUnlock()
-		return nil, NewError("execute", s.providerName, s.instance.ID(), ErrSandboxStopped)
+// RuntimeInfo returns the image, language and run command resolved at
+// creation. For providers without images Image is empty and the rest comes
+// from WithRuntime.
+func (s *sandbox) RuntimeInfo() ResolvedRuntime {
+	r := s.runtime
+	r.RunCommand = slices.Clone(r.RunCommand)
+	return r
+}
+
+// checkLanguage fails with a LanguageUnsupportedError if the provider does
+// not list language, so the user gets a clear error rather than whatever
+// the provider makes of it. Providers without a language list, and
+// executions with WithSkipLanguageCheck, are not checked.
+func (s *sandbox) checkLanguage(op, language string, execCfg *ExecuteConfig) error {
+	if language == "" || execCfg.SkipLanguageCheck || len(s.capabilities.SupportedLanguages) == 0 ||
+		s.capabilities.SupportsLanguage(language) {
+		return nil
 	}
-	s.mu.RUnlock()
+	return NewError(op, s.providerName, s.instance.ID(), &LanguageUnsupportedError{
+		Language:  language,
+		Provider:  s.providerName,
+		Supported: s.capabilities.SupportedLanguages,
+		Providers: providersSupporting(language, s.providerName),
+	})
+}
 
-	// Build execution config
+// providersSupporting lists the registered providers, other than exclude,
+// that list language. Providers that cannot be built without a config are
+// left out.
+func providersSupporting(language, exclude string) []string {
+	names := factory.Available()
+	sort.Strings(names)
+
+	var supporting []string
+	for _, name := range names {
+		if name == exclude {
+			continue
+		}
+		caps, err := factory.ProbeCapabilities(name)
+		if err == nil && caps.SupportsLanguage(language) {
+			supporting = append(supporting, name)
+		}
+	}
+	return supporting
+}
+
+// resolveLanguage returns the language to run code in: the requested one,
+// else the detected one, else the fallback language unless
+// WithStrictLanguage is set. detected reports whether detection chose it.
+func (s *sandbox) resolveLanguage(op, code string, execCfg *ExecuteConfig) (language string, detected bool, err error) {
+	if execCfg.Language != "" || !s.config.AutoDetectLanguage {
+		return execCfg.Language, false, nil
+	}
+
+	language, detected = s.config.detectLanguage(s.detector, code, execCfg.Filename, execCfg.Files)
+	if language == "" {
+		return "", false, NewError(op, s.providerName, s.instance.ID(), ErrLanguageDetectionFailed)
+	}
+	return language, detected, nil
+}
+
+// detectLanguage detects the language of code, falling back to
+// DefaultLanguage unless StrictLanguage is set. detected reports whether
+// the language came from detection; the language is empty when neither
+// gives one.
+func (c *Config) detectLanguage(detector *langdetect.Detector, code, filename string, files map[string][]byte) (language string, detected bool) {
+	result := detector.Detect(code, &langdetect.DetectOptions{
+		Filename:      filename,
+		UseContent:    true,
+		UseShebang:    true,
+		UseHeuristics: true,
+	})
+	if len(files) > 0 {
+		// A project's manifest names its language whatever the snippet
+		// looks like; otherwise the stronger detection wins.
+		project, _ := langdetect.DetectProject(files)
+		if project.Language != "" && (project.Method == "manifest" || project.Confidence > result.Confidence) {
+			result = project
+		}
+	}
+	switch {
+	case result.Language != "":
+		return result.Language, true
+	case c.DefaultLanguage != "" && !c.StrictLanguage:
+		return c.DefaultLanguage, false
+	default:
+		return "", false
+	}
+}
+
+// executionStartedData describes an execution for the execution.started
+// event. The code itself is only included with WithAuditCode, with secret
+// values removed.
+func (s *sandbox) executionStartedData(code, language string, opts *executor.ExecutionOptions) *event.ExecutionStartedData {
+	sum := sha256.Sum256([]byte(code))
+	data := &event.ExecutionStartedData{
+		Language: language,
+		CodeSize: len(code),
+		CodeHash: hex.EncodeToString(sum[:]),
+	}
+	if s.config.AuditCode {
+		data.Code = redactSecrets(code, s.config.secretValues())
+	}
+
+	if info, ok := langdetect.GetRuntimeInfo(language); ok {
+		info = provider.OverrideInterpreter(info, opts.Interpreter)
+		data.Command = provider.RunCommand(info, s.config.InternetAccess, opts.WorkDir)
+		if info.CompileCmd == nil {
+			data.Command = append(data.Command, path.Join(opts.WorkDir, "main"+info.FileExt))
+		}
+		data.Command = append(data.Command, opts.Args...)
+		data.Command = provider.WrapCommand(data.Command, opts.CommandWrapper)
+	}
+	return data
+}
+
+// executeConfig applies opts over the defaults, running in the sandbox's
+// working directory, and over the directives of code if the sandbox
+// parses them.
+func (s *sandbox) executeConfig(ctx context.Context, op, code string, opts []ExecuteOption) (*ExecuteConfig, error) {
 	execCfg := DefaultExecuteConfig()
+	if s.workDir != "" {
+		execCfg.WorkDir = s.workDir
+	}
+	if s.config.ParseDirectives {
+		if err := s.applyDirectives(ctx, code, execCfg); err != nil {
+			return nil, NewError(op, s.providerName, s.instance.ID(), err)
+		}
+	}
 	for _, opt := range opts {
 		opt(execCfg)
 	}
+	return execCfg, nil
+}
 
-	// Detect language if not specified
-	language := execCfg.Language
-	if language == "" && s.config.AutoDetectLanguage {
-		result := s.detector.Detect(code, &langdetect.DetectOptions{
-			Filename:      execCfg.Filename,
-			UseContent:    true,
-			UseShebang:    true,
-			UseHeuristics: true,
-		})
-		if result.Language != "" {
-			language = result.Language
-		} else if s.config.DefaultLanguage != "" {
-			language = s.config.DefaultLanguage
-		} else {
-			return nil, NewError("execute", s.providerName, s.instance.ID(), ErrLanguageDetectionFailed)
+// checkPathGrants rejects paths granted to a run that the provider cannot
+// mount or that are unsafe to mount.
+func (s *sandbox) checkPathGrants(op string, execCfg *ExecuteConfig) error {
+	paths := slices.Concat(execCfg.AllowReadPaths, execCfg.AllowWritePaths)
+	if len(paths) == 0 {
+		return nil
+	}
+	if !s.capabilities.SupportsPathGrants {
+		return NewError(op, s.providerName, s.instance.ID(), fmt.Errorf("path grants: %w", ErrCapabilityNotSupported))
+	}
+	for _, path := range paths {
+		if err := provider.ValidatePathGrant(path); err != nil {
+			return NewError(op, s.providerName, s.instance.ID(), fmt.Errorf("path grant: %v: %w", err, ErrInvalidConfiguration))
+		}
+	}
+	return nil
+}
+
+// checkFiles rejects file names that would be written outside the run's
+// working directory. Relative names are resolved against it.
+func (s *sandbox) checkFiles(op string, execCfg *ExecuteConfig) error {
+	for name := range execCfg.Files {
+		if err := provider.ValidateFileName(name); err != nil {
+			return NewError(op, s.providerName, s.instance.ID(), fmt.Errorf("%v: %w", err, ErrInvalidConfiguration))
+		}
+	}
+	return nil
+}
+
+// checkCode fails with ErrEmptyCode for code with nothing but whitespace,
+// which would otherwise reach the provider and fail language detection or
+// exit confusingly. A comment alone is code and runs.
+func (s *sandbox) checkCode(op, code string) error {
+	if strings.TrimSpace(code) == "" {
+		return NewError(op, s.providerName, s.instance.ID(), ErrEmptyCode)
+	}
+	return nil
+}
+
+// Execute runs code and returns the result.
+func (s *sandbox) Execute(ctx context.Context, code string, opts ...ExecuteOption) (*executor.ExecutionResult, error) {
+	ctx, span := s.startSpan(ctx, "sindoq.execute")
+	result, err := s.execute(ctx, code, opts...)
+	setResultAttributes(span, result)
+	endSpan(span, err)
+	return result, err
+}
+
+func (s *sandbox) execute(ctx context.Context, code string, opts ...ExecuteOption) (*executor.ExecutionResult, error) {
+	if err := s.checkActive("execute"); err != nil {
+		return nil, err
+	}
+	if err := s.checkCode("execute", code); err != nil {
+		return nil, err
+	}
+	defer s.beginExecution(ctx)()
+
+	// Build execution config
+	execCfg, err := s.executeConfig(ctx, "execute", code, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if feature := execCfg.streamedFeature(); feature != "" {
+		if option := execCfg.executeOnlyOption(); option != "" {
+			return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("%s cannot be combined with %s: %w", option, feature, ErrInvalidConfiguration))
 		}
+		return s.executeStreamed(ctx, code, feature, execCfg.SpillThreshold, opts)
+	}
+
+	if execCfg.NetworkCapture && !s.capabilities.SupportsNetworkCapture {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("network capture: %w", ErrCapabilityNotSupported))
+	}
+	if len(execCfg.CommandWrapper) > 0 && !s.capabilities.SupportsCommandWrapper {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("command wrapper: %w", ErrCapabilityNotSupported))
+	}
+	if err := s.checkFiles("execute", execCfg); err != nil {
+		return nil, err
+	}
+	if err := s.checkPathGrants("execute", execCfg); err != nil {
+		return nil, err
+	}
+	if execCfg.Coverage && !s.capabilities.SupportsCoverage {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("coverage: %w", ErrCapabilityNotSupported))
+	}
+	if len(execCfg.Args) > 0 && !s.capabilities.SupportsProgramArgs {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("program arguments: %w", ErrCapabilityNotSupported))
+	}
+
+	start := time.Now()
+
+	// Detect language if not specified
+	language, detected, err := s.resolveLanguage("execute", code, execCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkLanguage("execute", language, execCfg); err != nil {
+		return nil, err
+	}
+
+	if execCfg.AutoWrapMain && language == "Go" {
+		code = wrapGoMain(code)
 	}
 
 	// Build execution options
-	execOpts := &executor.ExecutionOptions{
-		Language:      language,
-		Filename:      execCfg.Filename,
-		Timeout:       execCfg.Timeout,
-		Env:           execCfg.Env,
-		WorkDir:       execCfg.WorkDir,
-		Stdin:         execCfg.Stdin,
-		Files:         execCfg.Files,
-		KeepArtifacts: execCfg.KeepArtifacts,
+	execOpts := execCfg.toExecutionOptions(language, s.config.execEnv(s.workDir))
+	execOpts.SecretFiles = s.config.SecretFiles
+	execOpts.Interpreter = s.config.interpreterPath(language)
+	if execOpts.Interpreter != "" && !s.capabilities.SupportsInterpreterPath {
+		return nil, NewError("execute", s.providerName, s.instance.ID(), fmt.Errorf("interpreter path: %w", ErrCapabilityNotSupported))
+	}
+
+	var rec *ExecutionRecord
+	if s.recorder != nil {
+		rec = newExecutionRecord(s, start, code, language, detected, execOpts)
+		defer func() {
+			if err := s.recorder.record(rec); err != nil && s.config.Logger != nil {
+				s.config.Logger.Warn("failed to write execution record", "error", err)
+			}
+		}()
 	}
 
 	// Emit start event
-	s.eventBus.Emit(event.NewEvent(event.EventExecutionStarted, s.instance.ID(), &event.ExecutionStartedData{
-		Language: language,
-		CodeSize: len(code),
-	}))
+	s.eventBus.Emit(event.NewEvent(event.EventExecutionStarted, s.instance.ID(), s.executionStartedData(code, language, execOpts)))
 
-	start := time.Now()
+	execStart := time.Now()
+	if rec != nil {
+		rec.event(event.EventExecutionStarted)
+	}
 
 	// Execute
+	ctx, done := s.withLifetime(ctx)
+	defer done()
 	result, err := s.instance.Execute(ctx, code, execOpts)
+	if s.lifetimeExceeded(ctx) {
+		result, err = nil, ErrMaxLifetimeExceeded
+	}
 	if err != nil {
+		err = s.redact(err)
+		if rec != nil {
+			rec.finish(nil, err)
+		}
 		s.eventBus.Emit(event.NewErrorEvent(event.EventExecutionError, s.instance.ID(), err))
 		return nil, NewError("execute", s.providerName, s.instance.ID(), err)
 	}
 
+	if execOpts.ClockOffset != 0 && result.Metadata[executor.MetadataClockOffsetApplied] != true && s.config.Logger != nil {
+		s.config.Logger.Warn("clock offset ignored: provider or image does not support it", "provider", s.providerName, "offset", execOpts.ClockOffset)
+	}
+
 	// Set duration if not set by provider
 	if result.Duration == 0 {
-		result.Duration = time.Since(start)
+		result.Duration = time.Since(execStart)
+	}
+	if result.Signal == 0 {
+		result.Signal = executor.ExitSignal(result.ExitCode)
 	}
 
 	// Set language
 	result.Language = language
+	s.output.redactResult(result)
+	limitOutput(result, execCfg.StdoutLimit, execCfg.StderrLimit)
+
+	if rec != nil {
+		rec.finish(result, nil)
+	}
 
 	// Emit completion event
 	s.eventBus.Emit(event.NewEvent(event.EventExecutionComplete, s.instance.ID(), &event.ExecutionCompleteData{
@@ -228,14 +721,12 @@ func (s *sandbox) Execute(ctx context.Context, code string, opts ...ExecuteOptio
 }
 
 // ExecuteAsync runs code asynchronously and returns immediately.
-func (s *sandbox) ExecuteAsync(ctx context.Context, code string, opts ...ExecuteOption) (<-chan *executor.ExecutionResult, error) {
-	s.mu.RLock()
-	if s.stopped {
-		s.mu.RUnlock()
-		return nil, NewError("executeAsync", s.providerName, s.instance.ID(), ErrSandboxStopped)
+func (s *sandbox) ExecuteAsync(ctx context.Context, code string, opts ...ExecuteOption) (string, <-chan *executor.ExecutionResult, error) {
+	if err := s.checkActive("executeAsync"); err != nil {
+		return "", nil, err
 	}
-	s.mu.RUnlock()
 
+	id := newExecutionID()
 	results := make(chan *executor.ExecutionResult, 1)
 
 	go func() {
@@ -243,75 +734,202 @@ func (s *sandbox) ExecuteAsync(ctx context.Context, code string, opts ...Execute
 
 		result, err := s.Execute(ctx, code, opts...)
 		if err != nil {
-			results <- &executor.ExecutionResult{
+			result = &executor.ExecutionResult{
 				Error: err,
 			}
-			return
 		}
+		s.results.put(id, result)
 		results <- result
 	}()
 
-	return results, nil
+	return id, results, nil
 }
 
 // ExecuteStream runs code with streaming output.
 func (s *sandbox) ExecuteStream(ctx context.Context, code string, handler executor.StreamHandler, opts ...ExecuteOption) error {
-	s.mu.RLock()
-	if s.stopped {
-		s.mu.RUnlock()
-		return NewError("executeStream", s.providerName, s.instance.ID(), ErrSandboxStopped)
+	ctx, span := s.startSpan(ctx, "sindoq.executeStream")
+	if span.IsRecording() {
+		next := handler
+		handler = func(ev *executor.StreamEvent) error {
+			if ev.Type == executor.StreamComplete {
+				span.SetAttributes(AttrLanguage.String(ev.Language), AttrExitCode.Int(ev.ExitCode))
+			}
+			return next(ev)
+		}
 	}
-	s.mu.RUnlock()
+	err := s.executeStream(ctx, code, handler, opts...)
+	endSpan(span, err)
+	return err
+}
+
+func (s *sandbox) executeStream(ctx context.Context, code string, handler executor.StreamHandler, opts ...ExecuteOption) error {
+	if err := s.checkActive("executeStream"); err != nil {
+		return err
+	}
+	if err := s.checkCode("executeStream", code); err != nil {
+		return err
+	}
+	defer s.beginExecution(ctx)()
 
 	// Build execution config
-	execCfg := DefaultExecuteConfig()
-	for _, opt := range opts {
-		opt(execCfg)
+	execCfg, err := s.executeConfig(ctx, "executeStream", code, opts)
+	if err != nil {
+		return err
+	}
+
+	if execCfg.NetworkCapture {
+		return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("network capture is only returned by Execute: %w", ErrInvalidConfiguration))
+	}
+	if execCfg.Coverage {
+		return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("coverage is only returned by Execute: %w", ErrInvalidConfiguration))
+	}
+	if len(execCfg.CommandWrapper) > 0 && !s.capabilities.SupportsCommandWrapper {
+		return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("command wrapper: %w", ErrCapabilityNotSupported))
+	}
+	if err := s.checkFiles("executeStream", execCfg); err != nil {
+		return err
+	}
+	if err := s.checkPathGrants("executeStream", execCfg); err != nil {
+		return err
+	}
+	if len(execCfg.Args) > 0 && !s.capabilities.SupportsProgramArgs {
+		return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("program arguments: %w", ErrCapabilityNotSupported))
+	}
+	if execCfg.RawStream {
+		if !s.capabilities.SupportsRawStream {
+			return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("raw stream: %w", ErrCapabilityNotSupported))
+		}
+		if s.output != nil {
+			return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("raw stream cannot be redacted: %w", ErrInvalidConfiguration))
+		}
+	}
+	if len(execCfg.StdinScript) > 0 {
+		if execCfg.Stdin != "" {
+			return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("stdin and a stdin script are mutually exclusive: %w", ErrInvalidConfiguration))
+		}
+		if !s.capabilities.SupportsInteractiveStdin {
+			return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("stdin script: %w", ErrCapabilityNotSupported))
+		}
 	}
 
 	// Detect language
-	language := execCfg.Language
-	if language == "" && s.config.AutoDetectLanguage {
-		result := s.detector.Detect(code, &langdetect.DetectOptions{
-			Filename:      execCfg.Filename,
-			UseContent:    true,
-			UseShebang:    true,
-			UseHeuristics: true,
-		})
-		if result.Language != "" {
-			language = result.Language
-		} else if s.config.DefaultLanguage != "" {
-			language = s.config.DefaultLanguage
-		} else {
-			return NewError("executeStream", s.providerName, s.instance.ID(), ErrLanguageDetectionFailed)
+	language, _, err := s.resolveLanguage("executeStream", code, execCfg)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkLanguage("executeStream", language, execCfg); err != nil {
+		return err
+	}
+
+	if execCfg.AutoWrapMain && language == "Go" {
+		code = wrapGoMain(code)
+	}
+
+	execOpts := execCfg.toExecutionOptions(language, s.config.execEnv(s.workDir))
+	execOpts.SecretFiles = s.config.SecretFiles
+	execOpts.Interpreter = s.config.interpreterPath(language)
+	execOpts.RawStream = execCfg.RawStream
+	if execOpts.Interpreter != "" && !s.capabilities.SupportsInterpreterPath {
+		return NewError("executeStream", s.providerName, s.instance.ID(), fmt.Errorf("interpreter path: %w", ErrCapabilityNotSupported))
+	}
+
+	if len(execCfg.StreamMiddleware) > 0 {
+		handler = applyStreamMiddleware(execCfg.StreamMiddleware, handler)
+	}
+
+	var coalescer *streamCoalescer
+	if execCfg.StreamFlushInterval > 0 {
+		coalescer = newStreamCoalescer(execCfg.StreamFlushInterval, handler)
+		handler = coalescer.handle
+	}
+
+	// Cut each stream at its limit after redaction, so a secret straddling
+	// the limit is masked rather than half shown.
+	if execCfg.StdoutLimit > 0 || execCfg.StderrLimit > 0 {
+		handler = newOutputLimiter(execCfg.StdoutLimit, execCfg.StderrLimit, handler).handle
+	}
+
+	var redactor *streamRedactor
+	if s.output != nil {
+		redactor = newStreamRedactor(s.output, handler)
+		handler = redactor.handle
+	}
+
+	// Report the chosen language and the terminating signal on completion
+	// for providers that leave them unset.
+	next := handler
+	handler = func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamComplete {
+			if e.Language == "" {
+				e.Language = language
+			}
+			if e.Signal == 0 {
+				e.Signal = executor.ExitSignal(e.ExitCode)
+			}
 		}
+		return next(e)
+	}
+
+	// Watch the provider's raw output for runaway loops.
+	var loops *outputLoopDetector
+	if execCfg.OutputLoopThreshold > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		loops = newOutputLoopDetector(execCfg.OutputLoopThreshold, cancel, handler)
+		handler = loops.handle
 	}
 
-	execOpts := &executor.ExecutionOptions{
-		Language:      language,
-		Filename:      execCfg.Filename,
-		Timeout:       execCfg.Timeout,
-		Env:           execCfg.Env,
-		WorkDir:       execCfg.WorkDir,
-		Stdin:         execCfg.Stdin,
-		Files:         execCfg.Files,
-		KeepArtifacts: execCfg.KeepArtifacts,
+	// Answer prompts as they appear on the provider's raw output.
+	var script *stdinScript
+	if len(execCfg.StdinScript) > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		if script, err = newStdinScript(execCfg.StdinScript, execCfg.StdinPromptTimeout, cancel, handler); err != nil {
+			return NewError("executeStream", s.providerName, s.instance.ID(), err)
+		}
+		handler = script.handle
+		execOpts.StdinStream = script.stdin
 	}
 
 	// Emit start event
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamStart,
+		Language:  language,
 		Timestamp: time.Now(),
 	})
 
-	s.eventBus.Emit(event.NewEvent(event.EventExecutionStarted, s.instance.ID(), &event.ExecutionStartedData{
-		Language: language,
-		CodeSize: len(code),
-	}))
+	s.eventBus.Emit(event.NewEvent(event.EventExecutionStarted, s.instance.ID(), s.executionStartedData(code, language, execOpts)))
 
 	// Execute with streaming
-	err := s.instance.ExecuteStream(ctx, code, execOpts, handler)
+	ctx, done := s.withLifetime(ctx)
+	defer done()
+	if script != nil {
+		script.start()
+	}
+	err = s.instance.ExecuteStream(ctx, code, execOpts, handler)
+	if script != nil {
+		err = script.finish(err)
+	}
+	if s.lifetimeExceeded(ctx) {
+		err = ErrMaxLifetimeExceeded
+	}
+	if redactor != nil {
+		// Emit output held back by a provider that ended without a
+		// complete or error event.
+		redactor.flush(redactedStreams...)
+	}
+	if coalescer != nil {
+		coalescer.flush()
+	}
+	if loops != nil && loops.detected() {
+		// The provider's own error, if any, is from the cancellation.
+		err = loops.err()
+	}
 	if err != nil {
+		err = s.redact(err)
 		s.eventBus.Emit(event.NewErrorEvent(event.EventExecutionError, s.instance.ID(), err))
 		return NewError("executeStream", s.providerName, s.instance.ID(), err)
 	}
@@ -321,14 +939,16 @@ func (s *sandbox) ExecuteStream(ctx context.Context, code string, handler execut
 
 // RunCommand executes a shell command in the sandbox.
 func (s *sandbox) RunCommand(ctx context.Context, cmd string, args ...string) (*executor.CommandResult, error) {
-	s.mu.RLock()
-	if s.stopped {
-		s.mu.RUnlock()
-		return nil, NewError("runCommand", s.providerName, s.instance.ID(), ErrSandboxStopped)
+	if err := s.checkActive("runCommand"); err != nil {
+		return nil, err
 	}
-	s.mu.RUnlock()
 
-	return s.instance.RunCommand(ctx, cmd, args)
+	result, err := s.instance.RunCommand(ctx, cmd, args)
+	if err != nil {
+		return nil, s.redact(err)
+	}
+	s.output.redactCommand(result)
+	return result, nil
 }
 
 // Files returns the file system interface for this sandbox.
@@ -346,23 +966,127 @@ func (s *sandbox) Subscribe(eventType event.EventType, handler event.EventHandle
 	return s.eventBus.Subscribe(eventType, handler)
 }
 
+// checkActive returns ErrSandboxStopped or ErrSandboxPaused, wrapped for
+// op, if the sandbox cannot accept new work.
+func (s *sandbox) checkActive(op string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stopped {
+		return NewError(op, s.providerName, s.instance.ID(), ErrSandboxStopped)
+	}
+	if s.paused {
+		return NewError(op, s.providerName, s.instance.ID(), ErrSandboxPaused)
+	}
+	return nil
+}
+
+// Pause freezes the sandbox. Only providers with SupportsPause (Docker,
+// gVisor) implement it; others return ErrCapabilityNotSupported. Pausing
+// a paused sandbox is a no-op.
+func (s *sandbox) Pause(ctx context.Context) error {
+	return s.setPaused(ctx, "pause", true)
+}
+
+// Resume unfreezes a paused sandbox. Resuming a running sandbox is a
+// no-op.
+func (s *sandbox) Resume(ctx context.Context) error {
+	return s.setPaused(ctx, "resume", false)
+}
+
+// setPaused pauses or resumes the instance. The lock is held throughout so
+// a concurrent Stop or opposite call waits for the provider to finish.
+func (s *sandbox) setPaused(ctx context.Context, op string, paused bool) error {
+	pauser, ok := s.instance.(provider.Pauser)
+	if !ok {
+		return NewError(op, s.providerName, s.instance.ID(), fmt.Errorf("pause: %w", ErrCapabilityNotSupported))
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return NewError(op, s.providerName, s.instance.ID(), ErrSandboxStopped)
+	}
+	if s.paused == paused {
+		s.mu.Unlock()
+		return nil
+	}
+
+	var err error
+	eventType := event.EventSandboxPaused
+	if paused {
+		err = pauser.Pause(ctx)
+	} else {
+		err = pauser.Resume(ctx)
+		eventType = event.EventSandboxResumed
+	}
+	if err == nil {
+		s.paused = paused
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.eventBus.Emit(event.NewErrorEvent(event.EventSandboxError, s.instance.ID(), err))
+		return NewError(op, s.providerName, s.instance.ID(), err)
+	}
+	s.eventBus.Emit(event.NewEvent(eventType, s.instance.ID(), nil))
+	return nil
+}
+
+// withLifetime returns ctx, canceled too when the sandbox reaches its
+// maximum lifetime, and a func releasing it.
+func (s *sandbox) withLifetime(ctx context.Context) (context.Context, func()) {
+	if s.lifetime == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(s.lifetime, func() { cancel(context.Cause(s.lifetime)) })
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// lifetimeExceeded reports whether ctx, from withLifetime, was canceled
+// by the sandbox reaching its maximum lifetime.
+func (s *sandbox) lifetimeExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrMaxLifetimeExceeded)
+}
+
 // Stop terminates the sandbox and releases resources.
 func (s *sandbox) Stop(ctx context.Context) error {
+	return s.stop(ctx, "")
+}
+
+// stop stops the sandbox, reporting reason in the stopped event if the
+// sandbox stops itself.
+func (s *sandbox) stop(ctx context.Context, reason string) (err error) {
+	ctx, span := s.startSpan(ctx, "sindoq.stop")
+	if reason != "" {
+		span.SetAttributes(AttrStopReason.String(reason))
+	}
+	defer func() { endSpan(span, err) }()
+
 	s.mu.Lock()
 	if s.stopped {
 		s.mu.Unlock()
 		return nil
 	}
 	s.stopped = true
+	if s.lifetimeTimer != nil {
+		s.lifetimeTimer.Stop()
+	}
 	s.mu.Unlock()
 
-	err := s.instance.Stop(ctx)
-	if err != nil {
+	if err := s.instance.Stop(ctx); err != nil {
 		s.eventBus.Emit(event.NewErrorEvent(event.EventSandboxError, s.instance.ID(), err))
 		return NewError("stop", s.providerName, s.instance.ID(), err)
 	}
 
-	s.eventBus.Emit(event.NewEvent(event.EventSandboxStopped, s.instance.ID(), nil))
+	var data any
+	if reason != "" {
+		data = &event.SandboxStoppedData{Reason: reason}
+	}
+	s.eventBus.Emit(event.NewEvent(event.EventSandboxStopped, s.instance.ID(), data))
 	return nil
 }
 
@@ -381,24 +1105,32 @@ func (s *sandbox) Status(ctx context.Context) (provider.InstanceStatus, error) {
 // Execute is a convenience function for one-shot execution.
 // Creates a sandbox, runs code, and cleans up.
 func Execute(ctx context.Context, code string, opts ...Option) (*executor.ExecutionResult, error) {
+	opts, execOpts, err := routeCode(code, opts)
+	if err != nil {
+		return nil, err
+	}
 	sb, err := Create(ctx, opts...)
 	if err != nil {
 		return nil, err
 	}
 	defer sb.Stop(context.Background())
 
-	return sb.Execute(ctx, code)
+	return sb.Execute(ctx, code, execOpts...)
 }
 
 // ExecuteStream is a convenience function for streaming execution.
 func ExecuteStream(ctx context.Context, code string, handler executor.StreamHandler, opts ...Option) error {
+	opts, execOpts, err := routeCode(code, opts)
+	if err != nil {
+		return err
+	}
 	sb, err := Create(ctx, opts...)
 	if err != nil {
 		return err
 	}
 	defer sb.Stop(context.Background())
 
-	return sb.ExecuteStream(ctx, code, handler)
+	return sb.ExecuteStream(ctx, code, handler, execOpts...)
 }
 
 // ListProviders returns available provider names.
@@ -412,6 +1144,50 @@ func ProviderCapabilities(providerName string) (*provider.Capabilities, error) {
 	return fac.GetCapabilities(providerName, nil)
 }
 
+// CapabilityRequest describes what a job needs from a provider.
+type CapabilityRequest = provider.CapabilityRequest
+
+// CanRun reports whether a provider can run a job with the given
+// requirements, and lists the unmet ones when it can't.
+func CanRun(providerName string, req CapabilityRequest) (bool, []string, error) {
+	caps, err := ProviderCapabilities(providerName)
+	if err != nil {
+		return false, nil, err
+	}
+	ok, unmet := caps.Can(req)
+	return ok, unmet, nil
+}
+
+// CloseProviders shuts down every provider the SDK has created, for clean
+// server shutdown. nsjail, Wasmer and Firecracker refuse new sandboxes and
+// executions with ErrProviderClosing, then wait for running executions to
+// finish before stopping their sandboxes; other providers close at once.
+// When ctx ends, the remaining executions are aborted, the providers are
+// closed anyway and ctx's error is returned.
+func CloseProviders(ctx context.Context) error {
+	return factory.GetGlobalFactory().CloseGraceful(ctx)
+}
+
+// ValidateProvider checks whether a provider is usable on this machine,
+// e.g. that its daemon is reachable or its binaries are installed.
+func ValidateProvider(ctx context.Context, providerName string) error {
+	return factory.GetGlobalFactory().ValidateProvider(ctx, providerName, nil)
+}
+
+// ProviderQueueDepth returns how many API requests are waiting on the
+// provider's rate limiter. It returns 0 for providers that don't throttle
+// or haven't been created yet.
+func ProviderQueueDepth(providerName string) int {
+	p, err := factory.GetGlobalFactory().GetProvider(providerName, nil)
+	if err != nil {
+		return 0
+	}
+	if qr, ok := p.(provider.QueueReporter); ok {
+		return qr.QueueDepth()
+	}
+	return 0
+}
+
 // DetectLanguage detects the programming language of code.
 func DetectLanguage(code string, filename string) *langdetect.DetectResult {
 	return langdetect.Full(code, filename)
@@ -426,3 +1202,10 @@ func SupportedLanguages() []string {
 func GetRuntimeInfo(language string) (*langdetect.RuntimeInfo, bool) {
 	return langdetect.GetRuntimeInfo(language)
 }
+
+// LanguageProfile reports whether a language compiles and how long that
+// typically takes, so callers can budget timeouts, e.g. adding
+// TypicalCompileTime to the time the program itself needs.
+func LanguageProfile(language string) (*langdetect.LanguageProfile, bool) {
+	return langdetect.GetLanguageProfile(language)
+}
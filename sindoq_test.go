@@ -1,27 +1,38 @@
 package sindoq
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/happyhackingspace/sindoq/internal/factory"
 	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/event"
 	"github.com/happyhackingspace/sindoq/pkg/executor"
 	"github.com/happyhackingspace/sindoq/pkg/fs"
 )
 
 // mockProvider implements provider.Provider for testing
 type mockProvider struct {
-	name      string
-	createErr error
-	instance  *mockInstance
+	name       string
+	createErr  error
+	instance   *mockInstance
+	createOpts *provider.CreateOptions
+	caps       *provider.Capabilities
 }
 
 func (p *mockProvider) Name() string { return p.name }
 
 func (p *mockProvider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	p.createOpts = opts
 	if p.createErr != nil {
 		return nil, p.createErr
 	}
@@ -35,9 +46,12 @@ func (p *mockProvider) Create(ctx context.Context, opts *provider.CreateOptions)
 }
 
 func (p *mockProvider) Capabilities() provider.Capabilities {
+	if p.caps != nil {
+		return *p.caps
+	}
 	return provider.Capabilities{
 		SupportsStreaming:  true,
-		SupportedLanguages: []string{"Python", "JavaScript", "Go"},
+		SupportedLanguages: []string{"Python", "JavaScript", "Go", "Shell"},
 	}
 }
 
@@ -52,6 +66,13 @@ type mockInstance struct {
 	execErr    error
 	stopErr    error
 	stopped    bool
+	lastOpts   *executor.ExecutionOptions
+	execFunc   func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult
+	streamFunc func(ctx context.Context, handler executor.StreamHandler) error
+	cmdFunc    func(cmd string, args []string) *executor.CommandResult
+	fsys       fs.FileSystem
+	reattached bool
+	mu         sync.Mutex
 }
 
 func (i *mockInstance) ID() string       { return i.id }
@@ -61,9 +82,15 @@ func (i *mockInstance) Status(ctx context.Context) (provider.InstanceStatus, err
 }
 
 func (i *mockInstance) Execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	i.mu.Lock()
+	i.lastOpts = opts
+	i.mu.Unlock()
 	if i.execErr != nil {
 		return nil, i.execErr
 	}
+	if i.execFunc != nil {
+		return i.execFunc(code, opts), nil
+	}
 	if i.execResult != nil {
 		return i.execResult, nil
 	}
@@ -76,16 +103,24 @@ func (i *mockInstance) Execute(ctx context.Context, code string, opts *executor.
 }
 
 func (i *mockInstance) ExecuteStream(ctx context.Context, code string, opts *executor.ExecutionOptions, handler executor.StreamHandler) error {
+	i.lastOpts = opts
+	if i.streamFunc != nil {
+		return i.streamFunc(ctx, handler)
+	}
 	handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "Hello"})
 	handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 0})
 	return nil
 }
 
 func (i *mockInstance) RunCommand(ctx context.Context, cmd string, args []string) (*executor.CommandResult, error) {
+	if i.cmdFunc != nil {
+		return i.cmdFunc(cmd, args), nil
+	}
 	return &executor.CommandResult{ExitCode: 0, Stdout: "ok"}, nil
 }
 
-func (i *mockInstance) FileSystem() fs.FileSystem { return nil }
+func (i *mockInstance) FileSystem() fs.FileSystem { return i.fsys }
+func (i *mockInstance) Reattached() bool          { return i.reattached }
 func (i *mockInstance) Network() provider.Network { return nil }
 
 func (i *mockInstance) Stop(ctx context.Context) error {
@@ -171,6 +206,33 @@ func TestCreateUnregisteredProvider(t *testing.T) {
 	}
 }
 
+func TestCreatePolyglot(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithPolyglotImage("nikolaik/python-nodejs:python3.12-nodejs22"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if !mp.createOpts.Polyglot {
+		t.Error("Polyglot should be passed to create options")
+	}
+	if mp.createOpts.Image != "nikolaik/python-nodejs:python3.12-nodejs22" {
+		t.Errorf("Image = %q", mp.createOpts.Image)
+	}
+
+	_, err = Create(ctx, WithProvider("mock"), WithPolyglotImage(""))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Create() without image error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
 func TestMustCreate(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
@@ -257,7 +319,7 @@ main()`
 	}
 }
 
-func TestSandboxExecuteAfterStop(t *testing.T) {
+func TestSandboxExecuteAutoDetectProject(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
@@ -266,19 +328,23 @@ func TestSandboxExecuteAfterStop(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
 	}
+	defer sb.Stop(ctx)
 
-	sb.Stop(ctx)
-
-	_, err = sb.Execute(ctx, `print("Hello")`, WithLanguage("Python"))
-	if err == nil {
-		t.Error("Execute() should fail after Stop()")
+	// The snippet alone reads as Python; go.mod says otherwise.
+	files := map[string][]byte{
+		"go.mod":      []byte("module example.com/app\n\ngo 1.22\n"),
+		"util/sum.go": []byte("package util\n\nfunc Sum(a, b int) int { return a + b }\n"),
 	}
-	if !errors.Is(err, ErrSandboxStopped) {
-		t.Errorf("error should be ErrSandboxStopped, got %v", err)
+	result, err := sb.Execute(ctx, "print(util.Sum(1, 2))", WithFiles(files))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Language != "Go" {
+		t.Errorf("Language = %q, want Go from the project's files", result.Language)
 	}
 }
 
-func TestSandboxExecuteAsync(t *testing.T) {
+func TestSandboxExecuteFilesOutsideWorkDir(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
@@ -289,24 +355,80 @@ func TestSandboxExecuteAsync(t *testing.T) {
 	}
 	defer sb.Stop(ctx)
 
-	results, err := sb.ExecuteAsync(ctx, `print("Hello")`, WithLanguage("Python"))
-	if err != nil {
-		t.Fatalf("ExecuteAsync() error = %v", err)
+	files := map[string][]byte{"../escape.py": []byte("x = 1")}
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithFiles(files)); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Execute() error = %v, want ErrInvalidConfiguration", err)
 	}
-
-	result := <-results
-	if result == nil {
-		t.Fatal("result should not be nil")
+	err = sb.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python"), WithFiles(files))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteStream() error = %v, want ErrInvalidConfiguration", err)
 	}
-	if result.ExitCode != 0 {
-		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithFiles(map[string][]byte{"data/in.txt": nil})); err != nil {
+		t.Errorf("Execute() with a relative file error = %v", err)
 	}
 }
 
-func TestSandboxExecuteStream(t *testing.T) {
+func TestSandboxExecuteUndetectedLanguage(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
+	// Too short for any detection signal.
+	const code = "x"
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{"no fallback", nil, "", true},
+		{"fallback", []Option{WithFallbackLanguage("Python")}, "Python", false},
+		{"strict", []Option{WithStrictLanguage()}, "", true},
+		{"strict overrides fallback", []Option{WithFallbackLanguage("Python"), WithStrictLanguage()}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sb, err := Create(ctx, append([]Option{WithProvider("mock")}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			result, err := sb.Execute(ctx, code)
+			if tt.wantErr {
+				if !errors.Is(err, ErrLanguageDetectionFailed) {
+					t.Errorf("Execute() error = %v, want ErrLanguageDetectionFailed", err)
+				}
+				streamErr := sb.ExecuteStream(ctx, code, func(*executor.StreamEvent) error { return nil })
+				if !errors.Is(streamErr, ErrLanguageDetectionFailed) {
+					t.Errorf("ExecuteStream() error = %v, want ErrLanguageDetectionFailed", streamErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Language != tt.want {
+				t.Errorf("Language = %q, want %q", result.Language, tt.want)
+			}
+
+			// A detectable snippet ignores the fallback.
+			result, err = sb.Execute(ctx, "console.log('hi');")
+			if err != nil || result.Language != "JavaScript" {
+				t.Errorf("Execute() = %v, %v, want JavaScript", result, err)
+			}
+		})
+	}
+}
+
+func TestSandboxExecuteReproducible(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
 	ctx := context.Background()
 	sb, err := Create(ctx, WithProvider("mock"))
 	if err != nil {
@@ -314,71 +436,106 @@ func TestSandboxExecuteStream(t *testing.T) {
 	}
 	defer sb.Stop(ctx)
 
-	var events []*executor.StreamEvent
-	err = sb.ExecuteStream(ctx, `print("Hello")`, func(e *executor.StreamEvent) error {
-		events = append(events, e)
-		return nil
-	}, WithLanguage("Python"))
-	if err != nil {
-		t.Fatalf("ExecuteStream() error = %v", err)
+	code := `print({"b": 1, "a": 2})`
+
+	var envs []map[string]string
+	for i := 0; i < 2; i++ {
+		if _, err := sb.Execute(ctx, code, WithLanguage("Python"), WithReproducible()); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		envs = append(envs, mp.instance.lastOpts.Env)
 	}
 
-	if len(events) == 0 {
-		t.Error("should receive stream events")
+	for _, env := range envs {
+		if env["PYTHONHASHSEED"] != "0" {
+			t.Errorf("PYTHONHASHSEED = %q, want %q", env["PYTHONHASHSEED"], "0")
+		}
+		if env["TZ"] != "UTC" {
+			t.Errorf("TZ = %q, want %q", env["TZ"], "UTC")
+		}
+	}
+
+	// Identical environments across runs keep hash-dependent dict ordering stable
+	for k, v := range envs[0] {
+		if envs[1][k] != v {
+			t.Errorf("Env[%s] differs between runs: %q vs %q", k, v, envs[1][k])
+		}
+	}
+
+	// Explicit environment variables take precedence
+	if _, err := sb.Execute(ctx, code, WithLanguage("Python"), WithEnv(map[string]string{"TZ": "Europe/Istanbul"}), WithReproducible()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if tz := mp.instance.lastOpts.Env["TZ"]; tz != "Europe/Istanbul" {
+		t.Errorf("TZ = %q, want %q", tz, "Europe/Istanbul")
 	}
 }
 
-func TestSandboxRunCommand(t *testing.T) {
-	cleanup := setupMockProvider(t)
-	defer cleanup()
+func TestSandboxProviderEnvAndSecrets(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
 
 	ctx := context.Background()
-	sb, err := Create(ctx, WithProvider("mock"))
+	sb, err := Create(ctx, WithProvider("mock"),
+		WithProviderEnv(map[string]string{"HTTP_PROXY": "http://proxy:3128", "API_KEY": "placeholder"}),
+		WithSecrets(map[string]string{"API_KEY": "sk-live-123"}),
+	)
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
 	}
 	defer sb.Stop(ctx)
 
-	result, err := sb.RunCommand(ctx, "ls", "-la")
-	if err != nil {
-		t.Fatalf("RunCommand() error = %v", err)
+	if got := mp.createOpts.Environment["HTTP_PROXY"]; got != "http://proxy:3128" {
+		t.Errorf("CreateOptions.Environment[HTTP_PROXY] = %q, want %q", got, "http://proxy:3128")
 	}
 
-	if result.ExitCode != 0 {
-		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	if _, err := sb.Execute(ctx, `print(1)`, WithLanguage("Python"), WithEnv(map[string]string{"HTTP_PROXY": ""})); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	env := mp.instance.lastOpts.Env
+	if env["API_KEY"] != "sk-live-123" {
+		t.Errorf("Env[API_KEY] = %q, want secret to override provider env", env["API_KEY"])
+	}
+	if env["HTTP_PROXY"] != "" {
+		t.Errorf("Env[HTTP_PROXY] = %q, want per-call WithEnv to take precedence", env["HTTP_PROXY"])
 	}
 }
 
-func TestSandboxStatus(t *testing.T) {
-	cleanup := setupMockProvider(t)
-	defer cleanup()
+func TestSandboxRedactsSecrets(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
 
 	ctx := context.Background()
-	sb, err := Create(ctx, WithProvider("mock"))
+	sb, err := Create(ctx, WithProvider("mock"), WithSecrets(map[string]string{"API_KEY": "sk-live-123"}))
 	if err != nil {
 		t.Fatalf("Create() error = %v", err)
 	}
+	defer sb.Stop(ctx)
 
-	status, err := sb.Status(ctx)
-	if err != nil {
-		t.Fatalf("Status() error = %v", err)
+	mp.instance.execErr = fmt.Errorf("exec failed with API_KEY=sk-live-123: %w", ErrPermissionDenied)
+
+	_, err = sb.Execute(ctx, `print(1)`, WithLanguage("Python"))
+	if err == nil {
+		t.Fatal("expected error")
 	}
-	if status != provider.StatusRunning {
-		t.Errorf("Status() = %v, want %v", status, provider.StatusRunning)
+	if strings.Contains(err.Error(), "sk-live-123") {
+		t.Errorf("error leaks secret: %v", err)
 	}
-
-	sb.Stop(ctx)
-
-	status, err = sb.Status(ctx)
-	if err != nil {
-		t.Fatalf("Status() error = %v", err)
+	if !strings.Contains(err.Error(), "API_KEY=[REDACTED]") {
+		t.Errorf("error = %v, want redacted secret", err)
 	}
-	if status != provider.StatusStopped {
-		t.Errorf("Status() after stop = %v, want %v", status, provider.StatusStopped)
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Error("redacted error should still match ErrPermissionDenied")
 	}
 }
 
-func TestSandboxStopIdempotent(t *testing.T) {
+func TestSandboxExecuteAfterStop(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
@@ -388,95 +545,1061 @@ func TestSandboxStopIdempotent(t *testing.T) {
 		t.Fatalf("Create() error = %v", err)
 	}
 
-	// First stop
-	err = sb.Stop(ctx)
-	if err != nil {
-		t.Fatalf("Stop() error = %v", err)
+	sb.Stop(ctx)
+
+	_, err = sb.Execute(ctx, `print("Hello")`, WithLanguage("Python"))
+	if err == nil {
+		t.Error("Execute() should fail after Stop()")
 	}
+	if !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("error should be ErrSandboxStopped, got %v", err)
+	}
+}
 
-	// Second stop should be idempotent
-	err = sb.Stop(ctx)
-	if err != nil {
-		t.Fatalf("second Stop() error = %v", err)
+func TestSandboxExecuteEmptyCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		wantErr  bool
+	}{
+		{name: "empty", code: "", wantErr: true},
+		{name: "whitespace", code: " \n\t\r\n ", wantErr: true},
+		{name: "empty with language", code: "", language: "Python", wantErr: true},
+		{name: "shell comment", code: "# nothing to do", language: "Shell"},
+		{name: "python comment", code: "# TODO\n", language: "Python"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var runs int
+			sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+				runs++
+				return &executor.ExecutionResult{}
+			})
+			var opts []ExecuteOption
+			if tt.language != "" {
+				opts = append(opts, WithLanguage(tt.language))
+			}
+
+			_, err := sb.Execute(context.Background(), tt.code, opts...)
+			streamErr := sb.ExecuteStream(context.Background(), tt.code, func(*executor.StreamEvent) error { return nil }, opts...)
+			if !tt.wantErr {
+				if err != nil || streamErr != nil {
+					t.Fatalf("Execute() error = %v, ExecuteStream() error = %v", err, streamErr)
+				}
+				if runs != 1 {
+					t.Errorf("provider ran %d times, want 1", runs)
+				}
+				return
+			}
+			if !errors.Is(err, ErrEmptyCode) || !errors.Is(streamErr, ErrEmptyCode) {
+				t.Errorf("Execute() error = %v, ExecuteStream() error = %v, want ErrEmptyCode", err, streamErr)
+			}
+			if runs != 0 {
+				t.Errorf("provider ran %d times for empty code", runs)
+			}
+		})
 	}
 }
 
-func TestExecuteConvenience(t *testing.T) {
-	cleanup := setupMockProvider(t)
-	defer cleanup()
+// pausableInstance is a mockInstance that implements provider.Pauser.
+type pausableInstance struct {
+	*mockInstance
+	pauses, resumes int
+}
+
+func (i *pausableInstance) Pause(ctx context.Context) error {
+	i.pauses++
+	i.status = provider.StatusPaused
+	return nil
+}
+
+func (i *pausableInstance) Resume(ctx context.Context) error {
+	i.resumes++
+	i.status = provider.StatusRunning
+	return nil
+}
+
+// pausableProvider creates a pausableInstance.
+type pausableProvider struct {
+	*mockProvider
+	instance *pausableInstance
+}
+
+func (p *pausableProvider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	return p.instance, nil
+}
+
+func TestSandboxPauseResume(t *testing.T) {
+	inst := &pausableInstance{mockInstance: &mockInstance{id: "pausable", status: provider.StatusRunning}}
+	mp := &pausableProvider{mockProvider: &mockProvider{name: "mock"}, instance: inst}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
 
 	ctx := context.Background()
-	// Use recognizable Python code for auto-detection
-	code := `import json
-def main():
-    print(json.dumps({"hello": "world"}))
-main()`
-	result, err := Execute(ctx, code, WithProvider("mock"))
+	sb, err := Create(ctx, WithProvider("mock"))
 	if err != nil {
-		t.Fatalf("Execute() error = %v", err)
+		t.Fatalf("Create() error = %v", err)
 	}
+	defer sb.Stop(ctx)
 
-	if result.ExitCode != 0 {
-		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	if err := sb.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := sb.Pause(ctx); err != nil {
+		t.Fatalf("second Pause() error = %v", err)
+	}
+	if inst.pauses != 1 {
+		t.Errorf("provider paused %d times, want 1", inst.pauses)
+	}
+	if status, _ := sb.Status(ctx); status != provider.StatusPaused {
+		t.Errorf("Status() = %v, want paused", status)
 	}
-}
 
-func TestExecuteStreamConvenience(t *testing.T) {
-	cleanup := setupMockProvider(t)
-	defer cleanup()
+	if _, err := sb.Execute(ctx, `print("Hello")`, WithLanguage("Python")); !errors.Is(err, ErrSandboxPaused) {
+		t.Errorf("Execute() while paused error = %v, want ErrSandboxPaused", err)
+	}
+	if _, err := sb.RunCommand(ctx, "echo"); !errors.Is(err, ErrSandboxPaused) {
+		t.Errorf("RunCommand() while paused error = %v, want ErrSandboxPaused", err)
+	}
 
-	ctx := context.Background()
-	// Use recognizable Python code for auto-detection
-	code := `import json
-def main():
-    print(json.dumps({"hello": "world"}))
-main()`
-	var output string
-	err := ExecuteStream(ctx, code, func(e *executor.StreamEvent) error {
-		if e.Type == executor.StreamStdout {
-			output += e.Data
-		}
-		return nil
-	}, WithProvider("mock"))
+	if err := sb.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if inst.resumes != 1 {
+		t.Errorf("provider resumed %d times, want 1", inst.resumes)
+	}
+	result, err := sb.Execute(ctx, `print("Hello")`, WithLanguage("Python"))
 	if err != nil {
-		t.Fatalf("ExecuteStream() error = %v", err)
+		t.Fatalf("Execute() after Resume() error = %v", err)
+	}
+	if result.Stdout != "Hello, World!\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
 	}
 
-	if output == "" {
-		t.Error("should receive output")
+	sb.Stop(ctx)
+	if err := sb.Pause(ctx); !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("Pause() after Stop() error = %v, want ErrSandboxStopped", err)
 	}
 }
 
-func TestListProviders(t *testing.T) {
+func TestSandboxPauseUnsupported(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
-	providers := ListProviders()
-	found := false
-	for _, p := range providers {
-		if p == "mock" {
-			found = true
-			break
-		}
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
 	}
-	if !found {
-		t.Error("ListProviders() should include mock provider")
+	defer sb.Stop(ctx)
+
+	if err := sb.Pause(ctx); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Pause() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if _, err := sb.Execute(ctx, `print("Hello")`, WithLanguage("Python")); err != nil {
+		t.Errorf("Execute() after failed Pause() error = %v", err)
 	}
 }
 
-func TestProviderCapabilities(t *testing.T) {
+// resolvingInstance is a mockInstance that reports a resolved runtime.
+type resolvingInstance struct {
+	*mockInstance
+	runtime provider.ResolvedRuntime
+}
+
+func (i *resolvingInstance) RuntimeInfo() provider.ResolvedRuntime { return i.runtime }
+
+// resolvingProvider creates a resolvingInstance.
+type resolvingProvider struct {
+	*mockProvider
+	instance *resolvingInstance
+}
+
+func (p *resolvingProvider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	return p.instance, nil
+}
+
+func TestSandboxRuntimeInfo(t *testing.T) {
 	cleanup := setupMockProvider(t)
 	defer cleanup()
 
-	caps, err := ProviderCapabilities("mock")
+	ctx := context.Background()
+	created := make(chan *event.Event, 1)
+	sb, err := Create(ctx,
+		WithProvider("mock"),
+		WithRuntime("Python@3.12"),
+		WithImage("python:3.11-slim"),
+		WithEventHandler(func(e *event.Event) {
+			if e.Type == event.EventSandboxCreated {
+				created <- e
+			}
+		}),
+	)
 	if err != nil {
-		t.Fatalf("ProviderCapabilities() error = %v", err)
+		t.Fatalf("Create() error = %v", err)
 	}
+	defer sb.Stop(ctx)
 
-	if !caps.SupportsStreaming {
-		t.Error("SupportsStreaming should be true")
+	info := sb.RuntimeInfo()
+	if info.Image != "python:3.11-slim" {
+		t.Errorf("Image = %q, want WithImage to override WithRuntime", info.Image)
+	}
+	if info.Language != "Python" || info.Version != "3.12" || !slices.Equal(info.RunCommand, []string{"python3"}) {
+		t.Errorf("RuntimeInfo() = %+v", info)
 	}
-}
+
+	select {
+	case e := <-created:
+		if got, ok := e.Data.(ResolvedRuntime); !ok || got.Image != "python:3.11-slim" {
+			t.Errorf("creation event data = %#v, want the resolved runtime", e.Data)
+		}
+	case <-time.After(time.Second):
+		t.Error("no creation event")
+	}
+}
+
+func TestSandboxRuntimeInfoFromProvider(t *testing.T) {
+	want := provider.ResolvedRuntime{Image: "python:3.11-slim", Language: "Python", Version: "3.11", RunCommand: []string{"python3"}}
+	mp := &resolvingProvider{
+		mockProvider: &mockProvider{name: "mock"},
+		instance:     &resolvingInstance{mockInstance: &mockInstance{id: "resolving"}, runtime: want},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithRuntime("Python@3.11"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	info := sb.RuntimeInfo()
+	if info.Image != want.Image || info.Version != want.Version {
+		t.Errorf("RuntimeInfo() = %+v, want %+v", info, want)
+	}
+
+	// The result is a copy.
+	info.RunCommand[0] = "python2"
+	if sb.RuntimeInfo().RunCommand[0] != "python3" {
+		t.Error("RuntimeInfo() should not share RunCommand with the sandbox")
+	}
+}
+
+// workDirInstance is a mockInstance that reports the working directory it
+// chose, as Docker does after falling back.
+type workDirInstance struct {
+	*mockInstance
+	dir string
+}
+
+func (i *workDirInstance) WorkDir() string { return i.dir }
+
+// workDirProvider records the create options and returns instance.
+type workDirProvider struct {
+	*mockProvider
+	instance provider.Instance
+}
+
+func (p *workDirProvider) Create(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+	p.createOpts = opts
+	return p.instance, nil
+}
+
+func TestCreateWorkDir(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		caps         *provider.Capabilities
+		reported     string
+		execOpts     []ExecuteOption
+		wantWorkDir  string
+		wantFallback string
+		wantExecDir  string
+	}{
+		{
+			name:         "default",
+			wantWorkDir:  "/workspace",
+			wantFallback: "/tmp/sindoq",
+			wantExecDir:  "/workspace",
+		},
+		{
+			name:         "provider default",
+			caps:         &provider.Capabilities{DefaultWorkDir: "/tmp"},
+			wantWorkDir:  "/tmp",
+			wantFallback: "/tmp/sindoq",
+			wantExecDir:  "/tmp",
+		},
+		{
+			name:        "configured",
+			opts:        []Option{WithSandboxWorkDir("/app")},
+			wantWorkDir: "/app",
+			wantExecDir: "/app",
+		},
+		{
+			name:        "execution override",
+			opts:        []Option{WithSandboxWorkDir("/app")},
+			execOpts:    []ExecuteOption{WithWorkDir("/data")},
+			wantWorkDir: "/app",
+			wantExecDir: "/data",
+		},
+		{
+			name:         "fallback reported",
+			reported:     "/tmp/sindoq",
+			wantWorkDir:  "/workspace",
+			wantFallback: "/tmp/sindoq",
+			wantExecDir:  "/tmp/sindoq",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := &mockInstance{id: "workdir", status: provider.StatusRunning}
+			mp := &workDirProvider{mockProvider: &mockProvider{name: "mock", caps: tt.caps}, instance: inst}
+			if tt.reported != "" {
+				mp.instance = &workDirInstance{mockInstance: inst, dir: tt.reported}
+			}
+			factory.Register("mock", func(config any) (provider.Provider, error) {
+				return mp, nil
+			})
+			defer factory.Unregister("mock")
+
+			ctx := context.Background()
+			sb, err := Create(ctx, append([]Option{WithProvider("mock")}, tt.opts...)...)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			defer sb.Stop(ctx)
+
+			if mp.createOpts.WorkDir != tt.wantWorkDir || mp.createOpts.FallbackWorkDir != tt.wantFallback {
+				t.Errorf("CreateOptions WorkDir = %q, FallbackWorkDir = %q, want %q and %q",
+					mp.createOpts.WorkDir, mp.createOpts.FallbackWorkDir, tt.wantWorkDir, tt.wantFallback)
+			}
+
+			if _, err := sb.Execute(ctx, "pwd", append([]ExecuteOption{WithLanguage("Shell")}, tt.execOpts...)...); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if inst.lastOpts.WorkDir != tt.wantExecDir {
+				t.Errorf("execution WorkDir = %q, want %q", inst.lastOpts.WorkDir, tt.wantExecDir)
+			}
+		})
+	}
+}
+
+func TestSandboxExecuteLanguageUnsupported(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+	factory.Register("mock-rust", func(config any) (provider.Provider, error) {
+		return &mockProvider{name: "mock-rust", caps: &provider.Capabilities{SupportedLanguages: []string{"Rust"}}}, nil
+	})
+	defer factory.Unregister("mock-rust")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	_, err = sb.Execute(ctx, `fn main() {}`, WithLanguage("Rust"))
+	if !errors.Is(err, ErrLanguageUnsupportedByProvider) {
+		t.Fatalf("Execute() error = %v, want ErrLanguageUnsupportedByProvider", err)
+	}
+	var langErr *LanguageUnsupportedError
+	if !errors.As(err, &langErr) {
+		t.Fatalf("Execute() error = %v, want *LanguageUnsupportedError", err)
+	}
+	if langErr.Language != "Rust" || langErr.Provider != "mock" || !slices.Contains(langErr.Providers, "mock-rust") {
+		t.Errorf("error = %+v", langErr)
+	}
+	if !strings.Contains(err.Error(), "mock-rust") {
+		t.Errorf("error message %q should suggest mock-rust", err)
+	}
+
+	err = sb.ExecuteStream(ctx, `fn main() {}`, func(*executor.StreamEvent) error { return nil }, WithLanguage("Rust"))
+	if !errors.Is(err, ErrLanguageUnsupportedByProvider) {
+		t.Errorf("ExecuteStream() error = %v, want ErrLanguageUnsupportedByProvider", err)
+	}
+
+	if _, err := sb.Execute(ctx, `fn main() {}`, WithLanguage("Rust"), WithSkipLanguageCheck()); err != nil {
+		t.Errorf("Execute() with WithSkipLanguageCheck error = %v", err)
+	}
+	if _, err := sb.Execute(ctx, `print(1)`, WithLanguage("py")); err != nil {
+		t.Errorf("Execute() with a language alias error = %v", err)
+	}
+}
+
+func TestSandboxExecuteAsync(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	id, results, err := sb.ExecuteAsync(ctx, `print("Hello")`, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("ExecuteAsync() error = %v", err)
+	}
+	if id == "" {
+		t.Error("ExecuteAsync() should return an execution ID")
+	}
+
+	result := <-results
+	if result == nil {
+		t.Fatal("result should not be nil")
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+
+	if got, ok := sb.GetResult(id); !ok || got != result {
+		t.Errorf("GetResult() = %v, %v, want the delivered result", got, ok)
+	}
+	if _, ok := sb.GetResult("exec-unknown"); ok {
+		t.Error("GetResult() should not find unknown IDs")
+	}
+}
+
+func TestSandboxExecuteStream(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	var events []*executor.StreamEvent
+	err = sb.ExecuteStream(ctx, `print("Hello")`, func(e *executor.StreamEvent) error {
+		events = append(events, e)
+		return nil
+	}, WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	if len(events) == 0 {
+		t.Error("should receive stream events")
+	}
+}
+
+func TestSandboxExecuteStreamLanguage(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	var events []*executor.StreamEvent
+	err = sb.ExecuteStream(ctx, "def main():\n    print(\"Hello\")\n\nmain()", func(e *executor.StreamEvent) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	if len(events) == 0 || events[0].Type != executor.StreamStart {
+		t.Fatalf("first event should be %q, got %+v", executor.StreamStart, events)
+	}
+	if events[0].Language != "Python" {
+		t.Errorf("start event Language = %q, want Python", events[0].Language)
+	}
+	last := events[len(events)-1]
+	if last.Type != executor.StreamComplete || last.Language != "Python" {
+		t.Errorf("last event = %+v, want %q with Language Python", last, executor.StreamComplete)
+	}
+}
+
+func TestSandboxExecuteNetworkCapture(t *testing.T) {
+	pcap := []byte{0xd4, 0xc3, 0xb2, 0xa1}
+	mi := &mockInstance{
+		id:     "capture-instance",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			result := &executor.ExecutionResult{Language: opts.Language}
+			if opts.NetworkCapture {
+				result.NetworkCapture = pcap
+			}
+			return result
+		},
+	}
+	mp := &mockProvider{name: "mock", instance: mi}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	_, err = sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithNetworkCapture())
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsNetworkCapture: true}
+	sb2, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	result, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python"), WithNetworkCapture())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !bytes.Equal(result.NetworkCapture, pcap) {
+		t.Errorf("NetworkCapture = %x, want %x", result.NetworkCapture, pcap)
+	}
+
+	err = sb2.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithNetworkCapture())
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteStream() error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxExecuteCoverage(t *testing.T) {
+	mi := &mockInstance{
+		id:     "coverage-instance",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			result := &executor.ExecutionResult{Language: opts.Language}
+			if opts.Coverage {
+				result.Coverage = &executor.Coverage{Percent: 75}
+			}
+			return result
+		},
+	}
+	mp := &mockProvider{name: "mock", instance: mi}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	_, err = sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithCoverage())
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsCoverage: true}
+	sb2, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	result, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python"), WithCoverage())
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Coverage == nil || result.Coverage.Percent != 75 {
+		t.Errorf("Coverage = %+v, want the provider's", result.Coverage)
+	}
+
+	err = sb2.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithCoverage())
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteStream() error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxExecuteCommandWrapper(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	wrapper := WithCommandWrapper([]string{"/usr/bin/time", "-v"})
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), wrapper); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+	err = sb.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python"), wrapper)
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExecuteStream() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsCommandWrapper: true}
+	sb2, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	if _, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python"), wrapper); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.CommandWrapper; !slices.Equal(got, []string{"/usr/bin/time", "-v"}) {
+		t.Errorf("CommandWrapper = %v", got)
+	}
+}
+
+func TestSandboxExecuteInterpreterPath(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	interpreter := WithInterpreterPath("Python", "/usr/local/bin/python3.11")
+	sb, err := Create(ctx, WithProvider("mock"), interpreter)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python")); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+	err = sb.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python"))
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExecuteStream() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsInterpreterPath: true}
+	started := make(chan *event.ExecutionStartedData, 2)
+	sb2, err := Create(ctx, WithProvider("mock"), interpreter, WithEventHandler(func(e *event.Event) {
+		if e.Type == event.EventExecutionStarted {
+			started <- e.Data.(*event.ExecutionStartedData)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	if _, err := sb2.Execute(ctx, "print(1)", WithLanguage("python"), WithWorkDir("/work")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.Interpreter; got != "/usr/local/bin/python3.11" {
+		t.Errorf("Interpreter = %q", got)
+	}
+	select {
+	case data := <-started:
+		if want := []string{"/usr/local/bin/python3.11", "/work/main.py"}; !slices.Equal(data.Command, want) {
+			t.Errorf("Command = %v, want %v", data.Command, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no execution.started event")
+	}
+
+	// Other languages keep their default interpreter.
+	if _, err := sb2.Execute(ctx, "console.log(1)", WithLanguage("JavaScript")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.Interpreter; got != "" {
+		t.Errorf("Interpreter for JavaScript = %q, want none", got)
+	}
+
+	if _, err := Create(ctx, WithProvider("mock"), WithInterpreterPath("C", "/usr/bin/tcc")); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("Create() with a compiled language error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxExecutePathGrants(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	grant := WithAllowReadPaths([]string{"/data/input"})
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), grant); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+	err = sb.ExecuteStream(ctx, "print(1)", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python"), grant)
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExecuteStream() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsPathGrants: true}
+	sb2, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	if _, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python"), grant, WithAllowWritePaths([]string{"/data/output"})); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	opts := mp.instance.lastOpts
+	if !slices.Equal(opts.AllowReadPaths, []string{"/data/input"}) || !slices.Equal(opts.AllowWritePaths, []string{"/data/output"}) {
+		t.Errorf("grants = %v and %v", opts.AllowReadPaths, opts.AllowWritePaths)
+	}
+
+	if _, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if opts := mp.instance.lastOpts; len(opts.AllowReadPaths) != 0 || len(opts.AllowWritePaths) != 0 {
+		t.Errorf("grants carried over to the next run: %v and %v", opts.AllowReadPaths, opts.AllowWritePaths)
+	}
+
+	for _, path := range []string{"/data/../etc", "/", "/data:/workspace", "data"} {
+		_, err := sb2.Execute(ctx, "print(1)", WithLanguage("Python"), WithAllowReadPaths([]string{path}))
+		if !errors.Is(err, ErrInvalidConfiguration) {
+			t.Errorf("Execute() granting %q error = %v, want ErrInvalidConfiguration", path, err)
+		}
+	}
+}
+
+// warnLogger records Warn messages.
+type warnLogger struct {
+	NopLogger
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *warnLogger) Warn(msg string, keysAndValues ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, msg)
+}
+
+func TestSandboxExecuteClockOffset(t *testing.T) {
+	applied := false
+	mi := &mockInstance{
+		id:     "clock-instance",
+		status: provider.StatusRunning,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			result := &executor.ExecutionResult{Language: opts.Language}
+			if applied {
+				result.Metadata = map[string]any{executor.MetadataClockOffsetApplied: true}
+			}
+			return result
+		},
+	}
+	mp := &mockProvider{name: "mock", instance: mi}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	logger := &warnLogger{}
+	sb, err := Create(ctx, WithProvider("mock"), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithClockOffset(time.Hour)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if mi.lastOpts.ClockOffset != time.Hour {
+		t.Errorf("ClockOffset = %v, want %v", mi.lastOpts.ClockOffset, time.Hour)
+	}
+	if len(logger.warns) != 1 {
+		t.Errorf("warnings = %v, want one for the ignored offset", logger.warns)
+	}
+
+	applied = true
+	if _, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"), WithClockOffset(time.Hour)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(logger.warns) != 1 {
+		t.Errorf("warnings = %v, want none for an applied offset", logger.warns)
+	}
+}
+
+func TestSandboxRunCommand(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	result, err := sb.RunCommand(ctx, "ls", "-la")
+	if err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+}
+
+func TestSandboxStatus(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	status, err := sb.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status != provider.StatusRunning {
+		t.Errorf("Status() = %v, want %v", status, provider.StatusRunning)
+	}
+
+	sb.Stop(ctx)
+
+	status, err = sb.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status != provider.StatusStopped {
+		t.Errorf("Status() after stop = %v, want %v", status, provider.StatusStopped)
+	}
+}
+
+func TestSandboxStopIdempotent(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// First stop
+	err = sb.Stop(ctx)
+	if err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	// Second stop should be idempotent
+	err = sb.Stop(ctx)
+	if err != nil {
+		t.Fatalf("second Stop() error = %v", err)
+	}
+}
+
+func TestSandboxMaxLifetime(t *testing.T) {
+	inst := &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return &mockProvider{name: "mock", instance: inst}, nil
+	})
+	defer factory.Unregister("mock")
+
+	stopped := make(chan *event.Event, 1)
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithMaxLifetime(50*time.Millisecond), WithEventHandler(func(e *event.Event) {
+		if e.Type == event.EventSandboxStopped {
+			stopped <- e
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The execution runs until the deadline cancels it.
+	err = sb.ExecuteStream(ctx, "sleep 60", func(*executor.StreamEvent) error { return nil }, WithLanguage("Shell"))
+	if !errors.Is(err, ErrMaxLifetimeExceeded) {
+		t.Fatalf("in-flight ExecuteStream() error = %v, want ErrMaxLifetimeExceeded", err)
+	}
+
+	select {
+	case e := <-stopped:
+		data, ok := e.Data.(*event.SandboxStoppedData)
+		if !ok || data.Reason != event.StopReasonMaxLifetime {
+			t.Errorf("stopped event data = %#v, want reason %q", e.Data, event.StopReasonMaxLifetime)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no stopped event at the deadline")
+	}
+	if !inst.stopped {
+		t.Error("instance should be stopped at the deadline")
+	}
+
+	if _, err := sb.Execute(ctx, "echo hi", WithLanguage("Shell")); !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("Execute() after the deadline error = %v, want ErrSandboxStopped", err)
+	}
+}
+
+func TestSandboxMaxLifetimeStopBefore(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	var events atomic.Int32
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"), WithMaxLifetime(20*time.Millisecond), WithEventHandler(func(e *event.Event) {
+		if e.Type == event.EventSandboxStopped {
+			if e.Data != nil {
+				t.Errorf("Stop() event data = %#v, want none", e.Data)
+			}
+			events.Add(1)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := sb.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := events.Load(); n != 1 {
+		t.Errorf("got %d stopped events, want only the one from Stop", n)
+	}
+}
+
+func TestExecuteConvenience(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	// Use recognizable Python code for auto-detection
+	code := `import json
+def main():
+    print(json.dumps({"hello": "world"}))
+main()`
+	result, err := Execute(ctx, code, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if result.ExitCode != 0 {
+		t.Errorf("ExitCode = %d, want 0", result.ExitCode)
+	}
+}
+
+func TestExecuteStreamConvenience(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	// Use recognizable Python code for auto-detection
+	code := `import json
+def main():
+    print(json.dumps({"hello": "world"}))
+main()`
+	var output string
+	err := ExecuteStream(ctx, code, func(e *executor.StreamEvent) error {
+		if e.Type == executor.StreamStdout {
+			output += e.Data
+		}
+		return nil
+	}, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	if output == "" {
+		t.Error("should receive output")
+	}
+}
+
+func TestValidateProvider(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	if err := ValidateProvider(ctx, "mock"); err != nil {
+		t.Errorf("ValidateProvider(mock) error = %v", err)
+	}
+	if err := ValidateProvider(ctx, "nonexistent"); err == nil {
+		t.Error("ValidateProvider(nonexistent) should fail")
+	}
+}
+
+func TestCanRun(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ok, unmet, err := CanRun("mock", CapabilityRequest{Language: "Go", Streaming: true})
+	if err != nil || !ok || len(unmet) != 0 {
+		t.Errorf("CanRun(mock, Go) = %v, %v, %v; want true", ok, unmet, err)
+	}
+
+	ok, unmet, err = CanRun("mock", CapabilityRequest{Language: "Rust"})
+	if err != nil || ok || len(unmet) != 1 {
+		t.Errorf("CanRun(mock, Rust) = %v, %v, %v; want one unmet requirement", ok, unmet, err)
+	}
+
+	if _, _, err := CanRun("nonexistent", CapabilityRequest{}); err == nil {
+		t.Error("CanRun(nonexistent) should fail")
+	}
+}
+
+func TestListProviders(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	providers := ListProviders()
+	found := false
+	for _, p := range providers {
+		if p == "mock" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("ListProviders() should include mock provider")
+	}
+}
+
+func TestProviderCapabilities(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	caps, err := ProviderCapabilities("mock")
+	if err != nil {
+		t.Fatalf("ProviderCapabilities() error = %v", err)
+	}
+
+	if !caps.SupportsStreaming {
+		t.Error("SupportsStreaming should be true")
+	}
+}
 
 func TestDetectLanguage(t *testing.T) {
 	tests := []struct {
@@ -517,6 +1640,20 @@ func TestSupportedLanguages(t *testing.T) {
 	}
 }
 
+func TestLanguageProfile(t *testing.T) {
+	p, ok := LanguageProfile("Rust")
+	if !ok {
+		t.Fatal("LanguageProfile(Rust) should succeed")
+	}
+	if !p.NeedsCompilation || p.IsInterpreted || p.TypicalCompileTime <= 0 {
+		t.Errorf("LanguageProfile(Rust) = %+v, want a compiled language", p)
+	}
+
+	if _, ok := LanguageProfile("Unknown"); ok {
+		t.Error("LanguageProfile(Unknown) should fail")
+	}
+}
+
 func TestGetRuntimeInfo(t *testing.T) {
 	info, ok := GetRuntimeInfo("Python")
 	if !ok {
@@ -530,3 +1667,155 @@ func TestGetRuntimeInfo(t *testing.T) {
 		t.Error("DockerImage should not be empty")
 	}
 }
+
+func TestSandboxExecutionStartedData(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	run := func(code string, opts ...Option) *event.ExecutionStartedData {
+		t.Helper()
+		ctx := context.Background()
+		started := make(chan *event.ExecutionStartedData, 1)
+		opts = append(opts, WithProvider("mock"), WithEventHandler(func(e *event.Event) {
+			if e.Type == event.EventExecutionStarted {
+				started <- e.Data.(*event.ExecutionStartedData)
+			}
+		}))
+		sb, err := Create(ctx, opts...)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		defer sb.Stop(ctx)
+
+		if _, err := sb.Execute(ctx, code, WithLanguage("Python"), WithWorkDir("/work")); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		select {
+		case data := <-started:
+			return data
+		case <-time.After(time.Second):
+			t.Fatal("no execution.started event")
+			return nil
+		}
+	}
+
+	first := run("print('hello')")
+	second := run("print('hello')")
+	if first.CodeHash == "" || first.CodeHash != second.CodeHash {
+		t.Errorf("CodeHash = %q and %q, want a stable hash for identical code", first.CodeHash, second.CodeHash)
+	}
+	if other := run("print('bye')"); other.CodeHash == first.CodeHash {
+		t.Error("different code should hash differently")
+	}
+	if first.Code != "" {
+		t.Errorf("Code = %q, want it omitted without WithAuditCode", first.Code)
+	}
+	if !slices.Equal(first.Command, []string{"python3", "/work/main.py"}) {
+		t.Errorf("Command = %v", first.Command)
+	}
+
+	audited := run("print('hello')", WithAuditCode())
+	if audited.Code != "print('hello')" || audited.CodeHash != first.CodeHash {
+		t.Errorf("audited data = %+v", audited)
+	}
+}
+
+func TestCreateWithRespectEntrypoint(t *testing.T) {
+	mp := &mockProvider{name: "mock"}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	if _, err := Create(ctx, WithProvider("mock"), WithRespectEntrypoint()); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Fatalf("Create() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if mp.createOpts != nil {
+		t.Error("provider asked to create a sandbox it cannot honour")
+	}
+
+	mp.caps = &provider.Capabilities{SupportsImageEntrypoint: true}
+	sb, err := Create(ctx, WithProvider("mock"), WithRespectEntrypoint())
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+	if !mp.createOpts.RespectEntrypoint {
+		t.Error("RespectEntrypoint not passed to the provider")
+	}
+}
+
+func TestSandboxExecuteWithArgs(t *testing.T) {
+	mp := &mockProvider{name: "mock", instance: &mockInstance{id: "test-instance-123", status: provider.StatusRunning}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	args := []string{"one", "two words"}
+	if _, err := sb.Execute(ctx, "import sys; print(sys.argv)", WithLanguage("Python"), WithArgs(args)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() error = %v, want ErrCapabilityNotSupported", err)
+	}
+	if err := sb.ExecuteStream(ctx, "import sys; print(sys.argv)", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python"), WithArgs(args)); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExecuteStream() error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsProgramArgs: true, SupportedLanguages: []string{"Python"}}
+	sb, err = Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+	if _, err := sb.Execute(ctx, "import sys; print(sys.argv)", WithLanguage("Python"), WithArgs(args)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if got := mp.instance.lastOpts.Args; !slices.Equal(got, args) {
+		t.Errorf("Args = %q, want %q", got, args)
+	}
+}
+
+func TestSandboxExecuteSignal(t *testing.T) {
+	inst := &mockInstance{id: "test-instance-123", status: provider.StatusRunning}
+	mp := &mockProvider{name: "mock", instance: inst}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	tests := []struct {
+		name   string
+		result executor.ExecutionResult
+		want   syscall.Signal
+	}{
+		{"segfault", executor.ExecutionResult{ExitCode: 139}, syscall.SIGSEGV},
+		{"normal exit", executor.ExecutionResult{ExitCode: 1}, 0},
+		{"reported by provider", executor.ExecutionResult{ExitCode: -1, Signal: syscall.SIGKILL}, syscall.SIGKILL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst.execResult = &tt.result
+			result, err := sb.Execute(ctx, "print(1)", WithLanguage("Python"))
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if result.Signal != tt.want {
+				t.Errorf("Signal = %v, want %v", result.Signal, tt.want)
+			}
+		})
+	}
+}
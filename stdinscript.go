@@ -0,0 +1,165 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// StdinRule answers one prompt of an interactive program. See
+// WithStdinScript.
+type StdinRule struct {
+	// MatchRegex is matched against the stdout printed since the previous
+	// rule matched, e.g. `Name:\s*$`.
+	MatchRegex string
+
+	// Response is written to stdin when MatchRegex matches. It usually
+	// ends with "\n".
+	Response string
+}
+
+// DefaultStdinPromptTimeout is how long WithStdinScript waits for the
+// next prompt.
+const DefaultStdinPromptTimeout = 10 * time.Second
+
+// maxPromptBuffer bounds how much unmatched stdout a stdin script keeps.
+// Prompts are matched against the most recent output.
+const maxPromptBuffer = 64 * 1024
+
+// stdinScript is a StreamHandler that watches stdout and feeds the
+// program's stdin the response of each rule as its prompt appears. Rules
+// fire once each, in order, and stdin is closed after the last one. If the
+// next prompt does not appear within timeout, the run is cancelled.
+type stdinScript struct {
+	rules   []StdinRule
+	regexps []*regexp.Regexp
+	timeout time.Duration
+	cancel  context.CancelFunc
+	next    executor.StreamHandler
+
+	// stdin is the provider's StdinStream. Responses are written by a
+	// goroutine so a slow reader cannot stall the output handler.
+	stdin     *io.PipeReader
+	responses chan string
+
+	mu       sync.Mutex
+	output   string
+	step     int
+	timer    *time.Timer
+	timedOut bool
+	finished bool
+}
+
+func newStdinScript(rules []StdinRule, timeout time.Duration, cancel context.CancelFunc, next executor.StreamHandler) (*stdinScript, error) {
+	s := &stdinScript{
+		rules:     rules,
+		timeout:   timeout,
+		cancel:    cancel,
+		next:      next,
+		responses: make(chan string, len(rules)),
+	}
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.MatchRegex)
+		if err != nil {
+			return nil, fmt.Errorf("stdin script pattern %q: %w: %v", rule.MatchRegex, ErrInvalidConfiguration, err)
+		}
+		s.regexps = append(s.regexps, re)
+	}
+
+	pr, pw := io.Pipe()
+	s.stdin = pr
+	go func() {
+		defer pw.Close()
+		for response := range s.responses {
+			if _, err := pw.Write([]byte(response)); err != nil {
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// start begins waiting for the first prompt.
+func (s *stdinScript) start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timer = time.AfterFunc(s.timeout, s.expire)
+}
+
+func (s *stdinScript) handle(e *executor.StreamEvent) error {
+	if e.Type == executor.StreamStdout {
+		s.mu.Lock()
+		s.scan(e.Data)
+		s.mu.Unlock()
+	}
+	return s.next(e)
+}
+
+// scan adds data to the unmatched output and answers every prompt it now
+// contains. The caller holds mu.
+func (s *stdinScript) scan(data string) {
+	if s.finished || s.step == len(s.rules) {
+		return
+	}
+	s.output += data
+	if len(s.output) > maxPromptBuffer {
+		s.output = s.output[len(s.output)-maxPromptBuffer:]
+	}
+	for s.step < len(s.rules) {
+		loc := s.regexps[s.step].FindStringIndex(s.output)
+		if loc == nil {
+			return
+		}
+		s.responses <- s.rules[s.step].Response
+		s.output = s.output[loc[1]:]
+		s.step++
+		s.timer.Stop()
+		if s.step == len(s.rules) {
+			close(s.responses)
+			return
+		}
+		s.timer = time.AfterFunc(s.timeout, s.expire)
+	}
+}
+
+// expire cancels the run if a prompt is still awaited.
+func (s *stdinScript) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.finished || s.step == len(s.rules) {
+		return
+	}
+	s.timedOut = true
+	s.cancel()
+}
+
+// finish stops the script once the run has ended and reports a prompt
+// that never appeared. err is the provider's error, which takes precedence
+// unless the script itself cancelled the run.
+func (s *stdinScript) finish(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = true
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if s.step < len(s.rules) {
+		close(s.responses)
+	}
+	s.stdin.Close()
+
+	switch {
+	case s.timedOut:
+		return fmt.Errorf("%w: no output matched %q within %v", ErrStdinPromptNotMatched, s.rules[s.step].MatchRegex, s.timeout)
+	case err != nil:
+		return err
+	case s.step < len(s.rules):
+		return fmt.Errorf("%w: the program exited before printing %q", ErrStdinPromptNotMatched, s.rules[s.step].MatchRegex)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package sindoq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// setupPromptingProvider returns a sandbox whose program prints each prompt
+// and reads a line of stdin after it, like Python's input(), then prints
+// the answers.
+func setupPromptingProvider(t *testing.T, prompts ...string) Sandbox {
+	t.Helper()
+	inst := &mockInstance{id: "prompt-instance", status: provider.StatusRunning}
+	inst.streamFunc = func(ctx context.Context, handler executor.StreamHandler) error {
+		stdin := bufio.NewReader(inst.lastOpts.StdinStream)
+		var answers string
+		for _, prompt := range prompts {
+			handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: prompt})
+			// Like a provider killing the program, give up on the read
+			// when ctx ends.
+			lines := make(chan string, 1)
+			go func() {
+				line, err := stdin.ReadString('\n')
+				if err != nil {
+					close(lines)
+					return
+				}
+				lines <- line
+			}()
+			var line string
+			var ok bool
+			select {
+			case line, ok = <-lines:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				handler(&executor.StreamEvent{Type: executor.StreamStderr, Data: "EOFError\n"})
+				return handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 1})
+			}
+			answers += line
+		}
+		handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: answers})
+		return handler(&executor.StreamEvent{Type: executor.StreamComplete, ExitCode: 0})
+	}
+	mp := &mockProvider{name: "mock", instance: inst, caps: &provider.Capabilities{
+		SupportsStreaming:        true,
+		SupportsInteractiveStdin: true,
+		SupportedLanguages:       []string{"Python"},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	t.Cleanup(func() { factory.Unregister("mock") })
+
+	sb, err := Create(context.Background(), WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { sb.Stop(context.Background()) })
+	return sb
+}
+
+func TestSandboxExecuteStdinScript(t *testing.T) {
+	sb := setupPromptingProvider(t, "Name: ", "Age: ")
+
+	result, err := sb.Execute(context.Background(), `name = input("Name: "); age = input("Age: ")`,
+		WithLanguage("Python"),
+		WithStdinScript([]StdinRule{
+			{MatchRegex: `Name:\s*$`, Response: "Alice\n"},
+			{MatchRegex: `Age:\s*$`, Response: "30\n"},
+		}))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.ExitCode != 0 || result.Stdout != "Name: Age: Alice\n30\n" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestSandboxExecuteStdinScriptRepeatedPrompt(t *testing.T) {
+	sb := setupPromptingProvider(t, "> ", "> ")
+
+	// Each rule answers one prompt, so a repeated prompt needs a rule per
+	// occurrence.
+	result, err := sb.Execute(context.Background(), `input()`, WithLanguage("Python"),
+		WithStdinScript([]StdinRule{
+			{MatchRegex: `> $`, Response: "first\n"},
+			{MatchRegex: `> $`, Response: "second\n"},
+		}))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if result.Stdout != "> > first\nsecond\n" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+}
+
+func TestSandboxExecuteStdinScriptPromptTimeout(t *testing.T) {
+	sb := setupPromptingProvider(t, "Name: ", "Password: ")
+
+	start := time.Now()
+	_, err := sb.Execute(context.Background(), `input()`, WithLanguage("Python"),
+		WithStdinPromptTimeout(50*time.Millisecond),
+		WithStdinScript([]StdinRule{
+			{MatchRegex: `Name:`, Response: "Alice\n"},
+			{MatchRegex: `Age:`, Response: "30\n"},
+		}))
+	if !errors.Is(err, ErrStdinPromptNotMatched) {
+		t.Fatalf("Execute() error = %v, want ErrStdinPromptNotMatched", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %v, want it aborted at the prompt timeout", elapsed)
+	}
+}
+
+func TestSandboxExecuteStdinScriptProgramExits(t *testing.T) {
+	sb := setupPromptingProvider(t, "Name: ")
+
+	err := sb.ExecuteStream(context.Background(), `input()`, func(*executor.StreamEvent) error { return nil },
+		WithLanguage("Python"),
+		WithStdinScript([]StdinRule{
+			{MatchRegex: `Name:`, Response: "Alice\n"},
+			{MatchRegex: `Age:`, Response: "30\n"},
+		}))
+	if !errors.Is(err, ErrStdinPromptNotMatched) {
+		t.Errorf("ExecuteStream() error = %v, want ErrStdinPromptNotMatched", err)
+	}
+}
+
+func TestSandboxExecuteStdinScriptInvalid(t *testing.T) {
+	sb := setupPromptingProvider(t, "Name: ")
+	ctx := context.Background()
+
+	_, err := sb.Execute(ctx, `input()`, WithLanguage("Python"), WithStdinScript([]StdinRule{{MatchRegex: `(`}}))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("invalid pattern error = %v, want ErrInvalidConfiguration", err)
+	}
+
+	_, err = sb.Execute(ctx, `input()`, WithLanguage("Python"), WithStdin("x\n"),
+		WithStdinScript([]StdinRule{{MatchRegex: `Name:`, Response: "Alice\n"}}))
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("stdin with a script error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestSandboxExecuteStdinScriptUnsupported(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	_, err = sb.Execute(ctx, `input()`, WithLanguage("Python"), WithStdinScript([]StdinRule{{MatchRegex: `x`}}))
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("Execute() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
@@ -0,0 +1,113 @@
+package sindoq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// StreamMiddleware filters or transforms a streamed event. It returns the
+// event to forward and true, or false to drop the event.
+type StreamMiddleware func(*executor.StreamEvent) (*executor.StreamEvent, bool)
+
+// applyStreamMiddleware returns a handler that passes each event through
+// middleware in order before handler. An event dropped, or replaced with
+// nil, is not forwarded.
+func applyStreamMiddleware(middleware []StreamMiddleware, handler executor.StreamHandler) executor.StreamHandler {
+	return func(ev *executor.StreamEvent) error {
+		for _, mw := range middleware {
+			var keep bool
+			if ev, keep = mw(ev); !keep || ev == nil {
+				return nil
+			}
+		}
+		return handler(ev)
+	}
+}
+
+// streamCoalescer merges output events of one type produced within an
+// interval into a single event. Output is held for at most interval after
+// its first chunk, and a change of stream type or any other event flushes
+// it first so ordering is preserved.
+type streamCoalescer struct {
+	interval time.Duration
+	handler  executor.StreamHandler
+
+	// mu serializes handler calls between the provider and the timer.
+	mu      sync.Mutex
+	pending *executor.StreamEvent
+	timer   *time.Timer
+	err     error
+}
+
+func newStreamCoalescer(interval time.Duration, handler executor.StreamHandler) *streamCoalescer {
+	return &streamCoalescer{
+		interval: interval,
+		handler:  handler,
+	}
+}
+
+// handle is the executor.StreamHandler passed to the provider.
+func (s *streamCoalescer) handle(ev *executor.StreamEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Surface an error returned by the handler during a timed flush.
+	if s.err != nil {
+		return s.err
+	}
+
+	switch ev.Type {
+	case executor.StreamStdout, executor.StreamStderr, executor.StreamCompileStdout, executor.StreamCompileStderr:
+		if s.pending != nil && s.pending.Type != ev.Type {
+			if err := s.emit(); err != nil {
+				return err
+			}
+		}
+		if s.pending != nil {
+			s.pending.Data += ev.Data
+			return nil
+		}
+		held := *ev
+		s.pending = &held
+		s.timer = time.AfterFunc(s.interval, func() { s.tick(&held) })
+		return nil
+	}
+
+	if err := s.emit(); err != nil {
+		return err
+	}
+	return s.handler(ev)
+}
+
+// tick flushes held if it is still pending when its interval elapses.
+func (s *streamCoalescer) tick(held *executor.StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending != held {
+		return
+	}
+	if err := s.emit(); err != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// flush emits all held output.
+func (s *streamCoalescer) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.emit()
+}
+
+// emit passes the pending event to the handler. s.mu must be held.
+func (s *streamCoalescer) emit() error {
+	if s.pending == nil {
+		return nil
+	}
+	s.timer.Stop()
+	ev := s.pending
+	s.pending = nil
+	return s.handler(ev)
+}
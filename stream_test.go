@@ -0,0 +1,212 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestStreamCoalescerReducesEvents(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []*executor.StreamEvent
+	)
+	c := newStreamCoalescer(50*time.Millisecond, func(ev *executor.StreamEvent) error {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+		return nil
+	})
+
+	const chunks = 1000
+	for i := 0; i < chunks; i++ {
+		if err := c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "x"}); err != nil {
+			t.Fatalf("handle() error = %v", err)
+		}
+	}
+	if err := c.handle(&executor.StreamEvent{Type: executor.StreamComplete}); err != nil {
+		t.Fatalf("handle() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	var out strings.Builder
+	outputEvents := 0
+	for _, ev := range events {
+		if ev.Type == executor.StreamStdout {
+			outputEvents++
+			out.WriteString(ev.Data)
+		}
+	}
+	if out.Len() != chunks {
+		t.Errorf("got %d bytes, want %d", out.Len(), chunks)
+	}
+	if outputEvents == 0 || outputEvents >= chunks/10 {
+		t.Errorf("got %d output events for %d chunks, want coalescing", outputEvents, chunks)
+	}
+	if last := events[len(events)-1]; last.Type != executor.StreamComplete {
+		t.Errorf("last event = %q, want complete", last.Type)
+	}
+}
+
+func TestStreamCoalescerFlushesAfterInterval(t *testing.T) {
+	got := make(chan *executor.StreamEvent, 1)
+	c := newStreamCoalescer(10*time.Millisecond, func(ev *executor.StreamEvent) error {
+		got <- ev
+		return nil
+	})
+
+	c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "a"})
+	c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "b"})
+
+	select {
+	case ev := <-got:
+		if ev.Data != "ab" {
+			t.Errorf("Data = %q, want %q", ev.Data, "ab")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("held output was not flushed after the interval")
+	}
+}
+
+func TestStreamCoalescerPreservesOrder(t *testing.T) {
+	var events []*executor.StreamEvent
+	c := newStreamCoalescer(time.Hour, func(ev *executor.StreamEvent) error {
+		events = append(events, ev)
+		return nil
+	})
+
+	c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "1"})
+	c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "2"})
+	c.handle(&executor.StreamEvent{Type: executor.StreamStderr, Data: "3"})
+	c.handle(&executor.StreamEvent{Type: executor.StreamStdout, Data: "4"})
+	c.flush()
+
+	want := []string{"stdout:12", "stderr:3", "stdout:4"}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, ev := range events {
+		if got := string(ev.Type) + ":" + ev.Data; got != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestSandboxExecuteStreamMiddleware(t *testing.T) {
+	cleanup := setupStreamProvider(t, func(ctx context.Context, handler executor.StreamHandler) error {
+		handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "out\n"})
+		handler(&executor.StreamEvent{Type: executor.StreamStderr, Data: "noise\n"})
+		return handler(&executor.StreamEvent{Type: executor.StreamComplete})
+	})
+	defer cleanup()
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	dropStderr := func(e *executor.StreamEvent) (*executor.StreamEvent, bool) {
+		return e, e.Type != executor.StreamStderr
+	}
+	// Chained after dropStderr, so it never sees the stderr event.
+	var seen []executor.StreamEventType
+	tag := func(e *executor.StreamEvent) (*executor.StreamEvent, bool) {
+		seen = append(seen, e.Type)
+		if e.Type == executor.StreamStdout {
+			tagged := *e
+			tagged.Data = "[req-1] " + e.Data
+			return &tagged, true
+		}
+		return e, true
+	}
+
+	var got []string
+	err = sb.ExecuteStream(ctx, "print('out')", func(e *executor.StreamEvent) error {
+		got = append(got, string(e.Type)+":"+e.Data)
+		return nil
+	}, WithLanguage("Python"), WithStreamMiddleware(dropStderr), WithStreamMiddleware(tag))
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+
+	want := []string{"start:", "stdout:[req-1] out\n", "complete:"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("handler got %q, want %q", got, want)
+	}
+	if len(seen) != 3 {
+		t.Errorf("second middleware saw %v, want the 3 events the first kept", seen)
+	}
+}
+
+func TestSandboxExecuteStreamRaw(t *testing.T) {
+	frame := "\x01\x00\x00\x00\x00\x00\x00\x06hello\n"
+	mi := &mockInstance{
+		id:     "raw-instance",
+		status: provider.StatusRunning,
+		streamFunc: func(ctx context.Context, handler executor.StreamHandler) error {
+			handler(&executor.StreamEvent{Type: executor.StreamRaw, Data: frame})
+			handler(&executor.StreamEvent{Type: executor.StreamStdout, Data: "hello\n"})
+			return handler(&executor.StreamEvent{Type: executor.StreamComplete})
+		},
+	}
+	mp := &mockProvider{name: "mock", instance: mi}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	discard := func(*executor.StreamEvent) error { return nil }
+
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+	if err := sb.ExecuteStream(ctx, "print('hello')", discard, WithLanguage("Python"), WithRawStream()); !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("ExecuteStream() without capability error = %v, want ErrCapabilityNotSupported", err)
+	}
+
+	mp.caps = &provider.Capabilities{SupportsRawStream: true}
+	sb2, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb2.Stop(ctx)
+
+	var got []string
+	err = sb2.ExecuteStream(ctx, "print('hello')", func(e *executor.StreamEvent) error {
+		got = append(got, string(e.Type)+":"+e.Data)
+		return nil
+	}, WithLanguage("Python"), WithRawStream())
+	if err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if !mi.lastOpts.RawStream {
+		t.Error("RawStream not passed to the provider")
+	}
+	want := []string{"start:", "raw:" + frame, "stdout:hello\n", "complete:"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	redacted, err := Create(ctx, WithProvider("mock"), WithRedactPatterns([]string{`sk-\w+`}))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer redacted.Stop(ctx)
+	if err := redacted.ExecuteStream(ctx, "print('hello')", discard, WithLanguage("Python"), WithRawStream()); !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("ExecuteStream() with redaction error = %v, want ErrInvalidConfiguration", err)
+	}
+}
@@ -0,0 +1,184 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/happyhackingspace/sindoq/pkg/langdetect"
+)
+
+// SyntaxError is one parse error reported by a syntax check.
+type SyntaxError struct {
+	// Line and Column are 1-based. Column is zero when the checker does
+	// not report one.
+	Line   int
+	Column int
+
+	// Message is the checker's description of the error.
+	Message string
+}
+
+// SyntaxResult is the outcome of Sandbox.CheckSyntax.
+type SyntaxResult struct {
+	// Valid reports whether the code parsed.
+	Valid bool
+
+	// Language is the language the code was checked as.
+	Language string
+
+	// Errors are the parse errors the checker reported, in its order.
+	// It may be empty for invalid code whose checker output could not be
+	// parsed; Output still holds it.
+	Errors []SyntaxError
+
+	// Output is the checker's output, with file paths relative to the
+	// checked file's directory.
+	Output string
+}
+
+// syntaxChecker is a syntax-only check for a language.
+type syntaxChecker struct {
+	// command checks the file appended to it, exiting non-zero on a
+	// syntax error.
+	command []string
+
+	// pattern matches one error in the command's output, with the named
+	// groups line, msg and optionally col.
+	pattern *regexp.Regexp
+}
+
+// syntaxCheckers maps languages to their syntax-only checks.
+var syntaxCheckers = map[string]syntaxChecker{
+	"Python": {
+		command: []string{"python3", "-m", "py_compile"},
+		pattern: regexp.MustCompile(`(?m)line (?P<line>\d+)\n(?:.*\n)*?(?P<msg>\w*Error: .*)$`),
+	},
+	"JavaScript": {
+		command: []string{"node", "--check"},
+		pattern: regexp.MustCompile(`(?m):(?P<line>\d+)\n(?:.*\n)*?(?P<msg>SyntaxError: .*)$`),
+	},
+	"TypeScript": {
+		command: []string{"npx", "--no-install", "tsc", "--noEmit", "--pretty", "false"},
+		pattern: regexp.MustCompile(`(?m)\((?P<line>\d+),(?P<col>\d+)\): error (?P<msg>.*)$`),
+	},
+	"Go": {
+		command: []string{"gofmt", "-e", "-l"},
+		pattern: regexp.MustCompile(`(?m):(?P<line>\d+):(?P<col>\d+): (?P<msg>.*)$`),
+	},
+	"Ruby": {
+		command: []string{"ruby", "-c"},
+		pattern: regexp.MustCompile(`(?m)^[^:\n]+:(?P<line>\d+): (?P<msg>.*)$`),
+	},
+	"PHP": {
+		command: []string{"php", "-l"},
+		pattern: regexp.MustCompile(`(?m)(?P<msg>Parse error: .*?) in \S+ on line (?P<line>\d+)`),
+	},
+	"Shell": {
+		command: []string{"bash", "-n"},
+		pattern: regexp.MustCompile(`(?m)line (?P<line>\d+): (?P<msg>.*)$`),
+	},
+	"Perl": {
+		command: []string{"perl", "-c"},
+		pattern: regexp.MustCompile(`(?m)^(?P<msg>.*) at \S+ line (?P<line>\d+)`),
+	},
+}
+
+// CheckSyntax parses code with its language's syntax checker in the
+// sandbox, without running it, and reports whether it is valid with the
+// checker's errors. It is much cheaper than Execute, which suits editor
+// integrations. The language comes from WithLanguage or detection, as for
+// Execute; languages without a checker return errors.ErrUnsupported, as
+// does an image without the checker installed. Invalid code is a result,
+// not an error.
+func (s *sandbox) CheckSyntax(ctx context.Context, code string, opts ...ExecuteOption) (*SyntaxResult, error) {
+	if err := s.checkActive("checkSyntax"); err != nil {
+		return nil, err
+	}
+	if err := s.checkCode("checkSyntax", code); err != nil {
+		return nil, err
+	}
+	execCfg, err := s.executeConfig(ctx, "checkSyntax", code, opts)
+	if err != nil {
+		return nil, err
+	}
+	language, _, err := s.resolveLanguage("checkSyntax", code, execCfg)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := langdetect.GetRuntimeInfo(language)
+	if !ok {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(), fmt.Errorf("%s: %w", language, ErrLanguageNotSupported))
+	}
+	checker, ok := syntaxCheckers[info.Language]
+	if !ok {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(),
+			fmt.Errorf("no syntax check for %s: %w", info.Language, errors.ErrUnsupported))
+	}
+	fsys := s.instance.FileSystem()
+	if fsys == nil {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(), fmt.Errorf("file system: %w", ErrCapabilityNotSupported))
+	}
+
+	if execCfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, execCfg.Timeout)
+		defer cancel()
+	}
+
+	// Check a copy of the code in a directory of its own, so the checker's
+	// by-products, such as __pycache__, go with it.
+	dir := path.Join(execCfg.WorkDir, scratchDir, newExecutionID())
+	file := path.Join(dir, "main"+info.FileExt)
+	if err := fsys.Write(ctx, file, []byte(code)); err != nil {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(), fmt.Errorf("write code: %w", err))
+	}
+	defer fsys.Delete(context.WithoutCancel(ctx), dir)
+
+	args := append(slices.Clone(checker.command[1:]), file)
+	cr, err := s.RunCommand(ctx, checker.command[0], args...)
+	if err != nil {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(), err)
+	}
+	if cr.ExitCode == 126 || cr.ExitCode == runnerNotFoundExitCode {
+		return nil, NewError("checkSyntax", s.providerName, s.instance.ID(),
+			NewExecutionError(cr.ExitCode, cr.Stdout, cr.Stderr,
+				fmt.Errorf("%s is not installed: %w", checker.command[0], errors.ErrUnsupported)))
+	}
+
+	result := &SyntaxResult{Valid: cr.ExitCode == 0, Language: info.Language}
+	if !result.Valid {
+		result.Output = strings.ReplaceAll(strings.TrimSpace(cr.Stderr+cr.Stdout), dir+"/", "")
+		result.Errors = parseSyntaxErrors(checker.pattern, result.Output)
+	}
+	return result, nil
+}
+
+// parseSyntaxErrors extracts the errors matched by pattern from out with
+// their spacing normalized, dropping duplicates, such as PHP reporting one
+// error on both streams.
+func parseSyntaxErrors(pattern *regexp.Regexp, out string) []SyntaxError {
+	var errs []SyntaxError
+	for _, m := range pattern.FindAllStringSubmatch(out, -1) {
+		var e SyntaxError
+		for i, name := range pattern.SubexpNames() {
+			switch name {
+			case "line":
+				e.Line, _ = strconv.Atoi(m[i])
+			case "col":
+				e.Column, _ = strconv.Atoi(m[i])
+			case "msg":
+				e.Message = strings.Join(strings.Fields(m[i]), " ")
+			}
+		}
+		if !slices.Contains(errs, e) {
+			errs = append(errs, e)
+		}
+	}
+	return errs
+}
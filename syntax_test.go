@@ -0,0 +1,140 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// syntaxCheck imitates py_compile and node --check on the files in memfs,
+// failing code with an unclosed parenthesis as they do.
+func syntaxCheck(memfs *memFileSystem) func(cmd string, args []string) *executor.CommandResult {
+	return func(cmd string, args []string) *executor.CommandResult {
+		file := args[len(args)-1]
+		code, ok := memfs.files[file]
+		if !ok {
+			return &executor.CommandResult{ExitCode: 2, Stderr: "no such file\n"}
+		}
+		if cmd != "python3" && cmd != "node" {
+			return &executor.CommandResult{ExitCode: 127, Stderr: cmd + ": not found\n"}
+		}
+		if !strings.Contains(string(code), "print(\n") && !strings.Contains(string(code), "log(\n") {
+			return &executor.CommandResult{}
+		}
+		switch cmd {
+		case "python3":
+			return &executor.CommandResult{ExitCode: 1, Stderr: "  File \"" + file + "\", line 1\n    print(\n         ^\nSyntaxError: '(' was never closed\n"}
+		default:
+			return &executor.CommandResult{ExitCode: 1, Stderr: file + ":1\nconsole.log(\n           ^\n\nSyntaxError: missing ) after argument list\n    at wrapSafe (node:internal/modules/cjs/loader:1469:18)\n"}
+		}
+	}
+}
+
+func TestSandboxCheckSyntax(t *testing.T) {
+	memfs := &memFileSystem{files: map[string][]byte{}}
+	var commands []string
+	check := syntaxCheck(memfs)
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys:   memfs,
+		execFunc: func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+			t.Error("CheckSyntax should not run the code")
+			return &executor.ExecutionResult{}
+		},
+		cmdFunc: func(cmd string, args []string) *executor.CommandResult {
+			commands = append(commands, cmd+" "+strings.Join(args[:len(args)-1], " ")+" "+path.Base(args[len(args)-1]))
+			return check(cmd, args)
+		},
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("mock")
+
+	ctx := context.Background()
+	sb, err := Create(ctx, WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		command  string
+		want     *SyntaxResult
+	}{
+		{"valid Python", "python", "print('hi')\n", "python3 -m py_compile main.py", &SyntaxResult{Valid: true, Language: "Python"}},
+		{"invalid Python", "Python", "print(\n", "python3 -m py_compile main.py", &SyntaxResult{
+			Language: "Python",
+			Errors:   []SyntaxError{{Line: 1, Message: "SyntaxError: '(' was never closed"}},
+			Output:   "File \"main.py\", line 1\n    print(\n         ^\nSyntaxError: '(' was never closed",
+		}},
+		{"valid JavaScript", "JavaScript", "console.log('hi')\n", "node --check main.js", &SyntaxResult{Valid: true, Language: "JavaScript"}},
+		{"invalid JavaScript", "js", "console.log(\n", "node --check main.js", &SyntaxResult{
+			Language: "JavaScript",
+			Errors:   []SyntaxError{{Line: 1, Message: "SyntaxError: missing ) after argument list"}},
+			Output:   "main.js:1\nconsole.log(\n           ^\n\nSyntaxError: missing ) after argument list\n    at wrapSafe (node:internal/modules/cjs/loader:1469:18)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands = nil
+			got, err := sb.CheckSyntax(ctx, tt.code, WithLanguage(tt.language))
+			if err != nil {
+				t.Fatalf("CheckSyntax() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckSyntax() = %+v, want %+v", got, tt.want)
+			}
+			if len(commands) != 1 || commands[0] != tt.command {
+				t.Errorf("ran %q, want %q", commands, tt.command)
+			}
+			if len(memfs.files) != 0 {
+				t.Errorf("files left behind: %v", memfs.files)
+			}
+		})
+	}
+
+	if _, err := sb.CheckSyntax(ctx, "fn main() {}", WithLanguage("Rust")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CheckSyntax() for Rust error = %v, want errors.ErrUnsupported", err)
+	}
+	if _, err := sb.CheckSyntax(ctx, "puts 1", WithLanguage("Ruby")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("CheckSyntax() without ruby installed error = %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestParseSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		language string
+		output   string
+		want     []SyntaxError
+	}{
+		{"Go", "main.go:3:1: expected '}', found 'EOF'\n", []SyntaxError{{Line: 3, Column: 1, Message: "expected '}', found 'EOF'"}}},
+		{"TypeScript", "main.ts(1,13): error TS1005: ')' expected.\n", []SyntaxError{{Line: 1, Column: 13, Message: "TS1005: ')' expected."}}},
+		{"Ruby", "main.rb:2: syntax error, unexpected end-of-input\n", []SyntaxError{{Line: 2, Message: "syntax error, unexpected end-of-input"}}},
+		{"PHP", "PHP Parse error:  syntax error, unexpected end of file in main.php on line 3\nParse error: syntax error, unexpected end of file in main.php on line 3\nErrors parsing main.php\n",
+			[]SyntaxError{{Line: 3, Message: "Parse error: syntax error, unexpected end of file"}}},
+		{"Shell", "main.sh: line 4: syntax error: unexpected end of file\n", []SyntaxError{{Line: 4, Message: "syntax error: unexpected end of file"}}},
+		{"Perl", "syntax error at main.pl line 1, near \"print(\"\nmain.pl had compilation errors.\n", []SyntaxError{{Line: 1, Message: "syntax error"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			got := parseSyntaxErrors(syntaxCheckers[tt.language].pattern, tt.output)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSyntaxErrors() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package sindoq
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// TailFile follows a file inside the sandbox, sending its current contents
+// and then every appended chunk on the returned channel. A file that does
+// not exist yet is waited for, so TailFile can be started before the
+// program that writes it:
+//
+//	chunks, _ := sb.TailFile(ctx, "/workspace/out.log")
+//	_, results, _ := sb.ExecuteAsync(ctx, code)
+//	for chunk := range chunks { ... }
+//
+// The channel is closed when ctx ends. Only providers with
+// SupportsTailFile (Docker) implement it; others return
+// ErrCapabilityNotSupported.
+func (s *sandbox) TailFile(ctx context.Context, path string) (<-chan []byte, error) {
+	if err := s.checkActive("tailFile"); err != nil {
+		return nil, err
+	}
+
+	tailer, ok := s.instance.FileSystem().(fs.Tailer)
+	if !ok {
+		return nil, NewError("tailFile", s.providerName, s.instance.ID(), fmt.Errorf("file tailing: %w", ErrCapabilityNotSupported))
+	}
+	chunks, err := tailer.Tail(ctx, path)
+	if err != nil {
+		return nil, NewError("tailFile", s.providerName, s.instance.ID(), s.redact(err))
+	}
+	return chunks, nil
+}
@@ -0,0 +1,95 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/fs"
+)
+
+// tailFileSystem replays fixed chunks for any tailed path.
+type tailFileSystem struct {
+	fs.FileSystem
+	chunks []string
+	path   string
+}
+
+func (t *tailFileSystem) Tail(ctx context.Context, path string) (<-chan []byte, error) {
+	t.path = path
+	ch := make(chan []byte)
+	go func() {
+		defer close(ch)
+		for _, c := range t.chunks {
+			select {
+			case ch <- []byte(c):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func setupTailProvider(t *testing.T, fsys fs.FileSystem) Sandbox {
+	t.Helper()
+
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:     "test-instance-123",
+		status: provider.StatusRunning,
+		fsys:   fsys,
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	t.Cleanup(func() { factory.Unregister("mock") })
+
+	sb, err := Create(context.Background(), WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { sb.Stop(context.Background()) })
+	return sb
+}
+
+func TestSandboxTailFile(t *testing.T) {
+	fsys := &tailFileSystem{chunks: []string{"line 1\n", "line 2\n"}}
+	sb := setupTailProvider(t, fsys)
+
+	chunks, err := sb.TailFile(context.Background(), "/workspace/out.log")
+	if err != nil {
+		t.Fatalf("TailFile() error = %v", err)
+	}
+	var got string
+	for c := range chunks {
+		got += string(c)
+	}
+
+	if got != "line 1\nline 2\n" {
+		t.Errorf("tailed %q, want both lines", got)
+	}
+	if fsys.path != "/workspace/out.log" {
+		t.Errorf("tailed path = %q", fsys.path)
+	}
+}
+
+func TestSandboxTailFileUnsupported(t *testing.T) {
+	sb := setupTailProvider(t, &memFileSystem{files: map[string][]byte{}})
+
+	_, err := sb.TailFile(context.Background(), "/workspace/out.log")
+	if !errors.Is(err, ErrCapabilityNotSupported) {
+		t.Errorf("TailFile() error = %v, want ErrCapabilityNotSupported", err)
+	}
+}
+
+func TestSandboxTailFileAfterStop(t *testing.T) {
+	sb := setupTailProvider(t, &tailFileSystem{})
+	sb.Stop(context.Background())
+
+	_, err := sb.TailFile(context.Background(), "/workspace/out.log")
+	if !errors.Is(err, ErrSandboxStopped) {
+		t.Errorf("TailFile() error = %v, want ErrSandboxStopped", err)
+	}
+}
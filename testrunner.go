@@ -0,0 +1,374 @@
+package sindoq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// TestStatus is the outcome of one test case.
+type TestStatus string
+
+const (
+	TestPassed  TestStatus = "passed"
+	TestFailed  TestStatus = "failed"
+	TestSkipped TestStatus = "skipped"
+)
+
+// TestCase is the outcome of one test.
+type TestCase struct {
+	// Name identifies the test, e.g. "test_solution.test_add" or
+	// "TestAdd/negative".
+	Name string
+
+	Status   TestStatus
+	Duration time.Duration
+
+	// Message is the failure or skip reason reported by the runner.
+	Message string
+}
+
+// TestResult summarizes a test run by Sandbox.RunTests.
+type TestResult struct {
+	Passed  int
+	Failed  int
+	Skipped int
+	Cases   []TestCase
+
+	// Result is the raw execution. Stderr holds the runner's
+	// human-readable output.
+	Result *executor.ExecutionResult
+}
+
+// String returns the counts, e.g. "3 passed, 1 failed, 0 skipped".
+func (r *TestResult) String() string {
+	return fmt.Sprintf("%d passed, %d failed, %d skipped", r.Passed, r.Failed, r.Skipped)
+}
+
+// Success reports whether no test failed.
+func (r *TestResult) Success() bool {
+	return r.Failed == 0
+}
+
+// add records c and updates the counts.
+func (r *TestResult) add(c TestCase) {
+	switch c.Status {
+	case TestPassed:
+		r.Passed++
+	case TestFailed:
+		r.Failed++
+	case TestSkipped:
+		r.Skipped++
+	}
+	r.Cases = append(r.Cases, c)
+}
+
+// TestFramework runs a test suite inside a sandbox. The driver is ordinary
+// code in Language, so it runs wherever that language does; it invokes the
+// runner and writes a machine-readable report to stdout, leaving the
+// runner's own output on stderr.
+type TestFramework struct {
+	// Language the driver is written in.
+	Language string
+
+	// Driver invokes the test runner. It must exit with code 127 when the
+	// runner is not installed.
+	Driver string
+
+	// Files returns the files to write next to the driver for the code
+	// under test and its tests.
+	Files func(code, tests string) map[string][]byte
+
+	// Parse converts the driver's stdout into a TestResult.
+	Parse func(report []byte) (*TestResult, error)
+}
+
+// runnerNotFoundExitCode is the exit code drivers use when the runner is
+// missing, matching the shell's "command not found".
+const runnerNotFoundExitCode = 127
+
+var (
+	testFrameworksMu sync.RWMutex
+	testFrameworks   = map[string]TestFramework{
+		"pytest": pytestFramework,
+		"go":     goTestFramework,
+	}
+)
+
+// RegisterTestFramework makes a test framework available to RunTests under
+// name, replacing any framework already registered with that name.
+func RegisterTestFramework(name string, fw TestFramework) {
+	testFrameworksMu.Lock()
+	defer testFrameworksMu.Unlock()
+	testFrameworks[name] = fw
+}
+
+// TestFrameworks returns the registered test framework names, sorted.
+func TestFrameworks() []string {
+	testFrameworksMu.RLock()
+	defer testFrameworksMu.RUnlock()
+	names := make([]string, 0, len(testFrameworks))
+	for name := range testFrameworks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunTests runs tests against code with the named framework ("pytest" or
+// "go", or any registered with RegisterTestFramework) and returns the
+// parsed counts and cases. The run uses an ephemeral workdir unless opts
+// say otherwise. Failing tests are not an error; a missing runner returns
+// ErrTestRunnerNotFound.
+func (s *sandbox) RunTests(ctx context.Context, framework string, code, tests string, opts ...ExecuteOption) (*TestResult, error) {
+	testFrameworksMu.RLock()
+	fw, ok := testFrameworks[framework]
+	testFrameworksMu.RUnlock()
+	if !ok {
+		return nil, NewError("runTests", s.providerName, s.instance.ID(),
+			fmt.Errorf("unknown test framework %q: %w", framework, ErrInvalidConfiguration))
+	}
+
+	execOpts := make([]ExecuteOption, 0, len(opts)+3)
+	execOpts = append(execOpts, WithLanguage(fw.Language), WithEphemeralWorkdir(), WithFiles(fw.Files(code, tests)))
+	execOpts = append(execOpts, opts...)
+
+	result, err := s.Execute(ctx, fw.Driver, execOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode == runnerNotFoundExitCode {
+		return nil, NewError("runTests", s.providerName, s.instance.ID(),
+			NewExecutionError(result.ExitCode, result.Stdout, result.Stderr,
+				fmt.Errorf("%s: %w", framework, ErrTestRunnerNotFound)))
+	}
+
+	tr, err := fw.Parse([]byte(result.Stdout))
+	if err != nil {
+		return nil, NewError("runTests", s.providerName, s.instance.ID(),
+			NewExecutionError(result.ExitCode, result.Stdout, result.Stderr,
+				fmt.Errorf("parse %s report: %w", framework, err)))
+	}
+	tr.Result = result
+	return tr, nil
+}
+
+// pytestFramework runs test_solution.py, which imports the code under test
+// from the solution module, and reports JUnit XML.
+var pytestFramework = TestFramework{
+	Language: "Python",
+	Driver: `import importlib.util, subprocess, sys
+
+if importlib.util.find_spec("pytest") is None:
+    print("pytest is not installed", file=sys.stderr)
+    sys.exit(127)
+
+subprocess.run(
+    [sys.executable, "-m", "pytest", "-p", "no:cacheprovider",
+     "--junitxml=.sindoq-report.xml", "test_solution.py"],
+    stdout=sys.stderr,
+)
+with open(".sindoq-report.xml") as f:
+    sys.stdout.write(f.read())
+`,
+	Files: func(code, tests string) map[string][]byte {
+		return map[string][]byte{
+			"solution.py":      []byte(code),
+			"test_solution.py": []byte(tests),
+		}
+	},
+	Parse: parseJUnit,
+}
+
+// goTestFramework runs solution_test.go against solution.go in a module
+// named solution and reports go test -json. The two files must declare the
+// same package. The driver is excluded from the package by its build
+// constraint, which go run ignores for named files.
+var goTestFramework = TestFramework{
+	Language: "Go",
+	Driver: `//go:build ignore
+
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+func main() {
+	cmd := exec.Command("go", "test", "-json", ".")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Run()
+}
+`,
+	Files: func(code, tests string) map[string][]byte {
+		return map[string][]byte{
+			"go.mod":           []byte("module solution\n\ngo 1.21\n"),
+			"solution.go":      []byte(code),
+			"solution_test.go": []byte(tests),
+		}
+	},
+	Parse: parseGoTestJSON,
+}
+
+// junitSuite is a JUnit <testsuite>; reports may nest suites under
+// <testsuites>.
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []struct {
+		Name      string  `xml:"name,attr"`
+		ClassName string  `xml:"classname,attr"`
+		Time      float64 `xml:"time,attr"`
+		Failure   *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"failure"`
+		Error *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"error"`
+		Skipped *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"skipped"`
+	} `xml:"testcase"`
+}
+
+// parseJUnit parses a JUnit XML report. Errors count as failures.
+func parseJUnit(report []byte) (*TestResult, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(report, &root); err != nil {
+		return nil, err
+	}
+
+	tr := &TestResult{}
+	var walk func(s *junitSuite)
+	walk = func(s *junitSuite) {
+		for _, c := range s.Cases {
+			tc := TestCase{
+				Name:     c.Name,
+				Status:   TestPassed,
+				Duration: time.Duration(c.Time * float64(time.Second)),
+			}
+			if c.ClassName != "" {
+				tc.Name = c.ClassName + "." + c.Name
+			}
+			switch {
+			case c.Failure != nil:
+				tc.Status, tc.Message = TestFailed, c.Failure.Message
+			case c.Error != nil:
+				tc.Status, tc.Message = TestFailed, c.Error.Message
+			case c.Skipped != nil:
+				tc.Status, tc.Message = TestSkipped, c.Skipped.Message
+			}
+			tr.add(tc)
+		}
+		for i := range s.Suites {
+			walk(&s.Suites[i])
+		}
+	}
+	walk(&root)
+	return tr, nil
+}
+
+// goTestStatuses maps go test -json actions to test statuses.
+var goTestStatuses = map[string]TestStatus{
+	"pass": TestPassed,
+	"fail": TestFailed,
+	"skip": TestSkipped,
+}
+
+// goTestEvent is one line of go test -json output.
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// parseGoTestJSON parses go test -json output. A package that fails
+// without a failing test, such as one that does not compile, is reported
+// as a failed case named after the package.
+func parseGoTestJSON(report []byte) (*TestResult, error) {
+	tr := &TestResult{}
+	output := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	seen := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(report))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		seen = true
+
+		key := ev.Package + "\x00" + ev.Test
+		switch ev.Action {
+		case "output", "build-output":
+			b := output[key]
+			if b == nil {
+				b = &strings.Builder{}
+				output[key] = b
+			}
+			b.WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			status := goTestStatuses[ev.Action]
+			if ev.Test == "" {
+				if status == TestFailed && !failedTests[ev.Package] {
+					tr.add(TestCase{
+						Name:     ev.Package,
+						Status:   TestFailed,
+						Duration: time.Duration(ev.Elapsed * float64(time.Second)),
+						Message:  goTestMessage(output[key]),
+					})
+				}
+				continue
+			}
+			tc := TestCase{
+				Name:     ev.Test,
+				Status:   status,
+				Duration: time.Duration(ev.Elapsed * float64(time.Second)),
+			}
+			if status != TestPassed {
+				tc.Message = goTestMessage(output[key])
+				failedTests[ev.Package] = failedTests[ev.Package] || status == TestFailed
+			}
+			tr.add(tc)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !seen {
+		return nil, fmt.Errorf("no go test events in output")
+	}
+	return tr, nil
+}
+
+// goTestMessage returns a test's output without go test's own status
+// lines.
+func goTestMessage(b *strings.Builder) string {
+	if b == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") ||
+			trimmed == "FAIL" || trimmed == "PASS" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "ok ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
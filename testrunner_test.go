@@ -0,0 +1,201 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+const pytestReport = `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest" errors="1" failures="1" skipped="1" tests="5" time="0.05">
+<testcase classname="test_solution" name="test_add" time="0.001"/>
+<testcase classname="test_solution" name="test_sub" time="0.002"><failure message="assert 1 == 2">traceback</failure></testcase>
+<testcase classname="test_solution" name="test_fixture" time="0.000"><error message="fixture 'db' not found"/></testcase>
+<testcase classname="test_solution" name="test_later" time="0.000"><skipped type="pytest.skip" message="not yet"/></testcase>
+<testcase classname="test_solution" name="test_mul" time="0.500"/>
+</testsuite></testsuites>`
+
+func TestParseJUnit(t *testing.T) {
+	tr, err := parseJUnit([]byte(pytestReport))
+	if err != nil {
+		t.Fatalf("parseJUnit() error = %v", err)
+	}
+	if tr.Passed != 2 || tr.Failed != 2 || tr.Skipped != 1 {
+		t.Errorf("counts = %s, want 2 passed, 2 failed, 1 skipped", tr)
+	}
+	if len(tr.Cases) != 5 {
+		t.Fatalf("got %d cases, want 5", len(tr.Cases))
+	}
+
+	sub := tr.Cases[1]
+	if sub.Name != "test_solution.test_sub" || sub.Status != TestFailed || sub.Message != "assert 1 == 2" {
+		t.Errorf("failed case = %+v", sub)
+	}
+	if tr.Cases[2].Status != TestFailed {
+		t.Errorf("errored case status = %s, want failed", tr.Cases[2].Status)
+	}
+	if tr.Cases[3].Status != TestSkipped || tr.Cases[3].Message != "not yet" {
+		t.Errorf("skipped case = %+v", tr.Cases[3])
+	}
+	if tr.Cases[4].Duration != 500*time.Millisecond {
+		t.Errorf("Duration = %v, want 500ms", tr.Cases[4].Duration)
+	}
+
+	if _, err := parseJUnit([]byte("not xml")); err == nil {
+		t.Error("parseJUnit() should reject malformed reports")
+	}
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	report := `{"Action":"start","Package":"solution"}
+{"Action":"run","Package":"solution","Test":"TestAdd"}
+{"Action":"output","Package":"solution","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"output","Package":"solution","Test":"TestAdd","Output":"--- PASS: TestAdd (0.00s)\n"}
+{"Action":"pass","Package":"solution","Test":"TestAdd","Elapsed":0.01}
+{"Action":"run","Package":"solution","Test":"TestSub"}
+{"Action":"output","Package":"solution","Test":"TestSub","Output":"    solution_test.go:12: Sub(3, 1) = 1, want 2\n"}
+{"Action":"output","Package":"solution","Test":"TestSub","Output":"--- FAIL: TestSub (0.00s)\n"}
+{"Action":"fail","Package":"solution","Test":"TestSub","Elapsed":0}
+{"Action":"skip","Package":"solution","Test":"TestSlow","Elapsed":0}
+{"Action":"fail","Package":"solution","Elapsed":0.2}
+`
+	tr, err := parseGoTestJSON([]byte(report))
+	if err != nil {
+		t.Fatalf("parseGoTestJSON() error = %v", err)
+	}
+	if tr.Passed != 1 || tr.Failed != 1 || tr.Skipped != 1 || len(tr.Cases) != 3 {
+		t.Fatalf("result = %s with %d cases, want 1 of each", tr, len(tr.Cases))
+	}
+	if msg := tr.Cases[1].Message; msg != "    solution_test.go:12: Sub(3, 1) = 1, want 2" {
+		t.Errorf("failure message = %q", msg)
+	}
+}
+
+func TestParseGoTestJSONBuildFailure(t *testing.T) {
+	report := `{"Action":"start","Package":"solution"}
+{"Action":"output","Package":"solution","Output":"# solution\n"}
+{"Action":"output","Package":"solution","Output":"./solution.go:3:1: syntax error\n"}
+{"Action":"output","Package":"solution","Output":"FAIL\tsolution [build failed]\n"}
+{"Action":"fail","Package":"solution","Elapsed":0}
+`
+	tr, err := parseGoTestJSON([]byte(report))
+	if err != nil {
+		t.Fatalf("parseGoTestJSON() error = %v", err)
+	}
+	if tr.Failed != 1 || tr.Cases[0].Name != "solution" {
+		t.Fatalf("result = %+v, want the package reported as failed", tr)
+	}
+	if msg := tr.Cases[0].Message; msg != "# solution\n./solution.go:3:1: syntax error" {
+		t.Errorf("message = %q", msg)
+	}
+
+	if _, err := parseGoTestJSON([]byte("go: command not found\n")); err == nil {
+		t.Error("parseGoTestJSON() should reject output without events")
+	}
+}
+
+func setupTestRunnerProvider(t *testing.T, execFunc func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult) Sandbox {
+	t.Helper()
+
+	mp := &mockProvider{name: "mock", instance: &mockInstance{
+		id:       "test-instance-123",
+		status:   provider.StatusRunning,
+		execFunc: execFunc,
+	}}
+	factory.Register("mock", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	t.Cleanup(func() { factory.Unregister("mock") })
+
+	sb, err := Create(context.Background(), WithProvider("mock"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	t.Cleanup(func() { sb.Stop(context.Background()) })
+	return sb
+}
+
+func TestSandboxRunTests(t *testing.T) {
+	var got *executor.ExecutionOptions
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		got = opts
+		return &executor.ExecutionResult{ExitCode: 0, Stdout: pytestReport, Stderr: "5 tests ran"}
+	})
+
+	tr, err := sb.RunTests(context.Background(), "pytest", "def add(a, b): return a + b", "from solution import add")
+	if err != nil {
+		t.Fatalf("RunTests() error = %v", err)
+	}
+	if tr.Passed != 2 || tr.Failed != 2 || tr.Skipped != 1 || tr.Success() {
+		t.Errorf("result = %s, success = %v", tr, tr.Success())
+	}
+	if tr.Result.Stderr != "5 tests ran" {
+		t.Errorf("Result.Stderr = %q", tr.Result.Stderr)
+	}
+
+	if got.Language != "Python" || !got.EphemeralWorkDir {
+		t.Errorf("options = %+v, want an ephemeral Python run", got)
+	}
+	if string(got.Files["solution.py"]) != "def add(a, b): return a + b" || string(got.Files["test_solution.py"]) != "from solution import add" {
+		t.Errorf("Files = %v", got.Files)
+	}
+}
+
+func TestSandboxRunTestsRunnerNotFound(t *testing.T) {
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{ExitCode: 127, Stderr: "pytest is not installed"}
+	})
+
+	_, err := sb.RunTests(context.Background(), "pytest", "", "")
+	if !errors.Is(err, ErrTestRunnerNotFound) {
+		t.Errorf("RunTests() error = %v, want ErrTestRunnerNotFound", err)
+	}
+}
+
+func TestSandboxRunTestsUnknownFramework(t *testing.T) {
+	sb := setupTestRunnerProvider(t, nil)
+
+	_, err := sb.RunTests(context.Background(), "rspec", "", "")
+	if !errors.Is(err, ErrInvalidConfiguration) {
+		t.Errorf("RunTests() error = %v, want ErrInvalidConfiguration", err)
+	}
+}
+
+func TestRegisterTestFramework(t *testing.T) {
+	RegisterTestFramework("custom", TestFramework{
+		Language: "Python",
+		Driver:   "print('ok')",
+		Files:    func(code, tests string) map[string][]byte { return nil },
+		Parse: func(report []byte) (*TestResult, error) {
+			tr := &TestResult{}
+			tr.add(TestCase{Name: string(report), Status: TestPassed})
+			return tr, nil
+		},
+	})
+	t.Cleanup(func() {
+		testFrameworksMu.Lock()
+		delete(testFrameworks, "custom")
+		testFrameworksMu.Unlock()
+	})
+
+	names := TestFrameworks()
+	if len(names) != 3 || names[0] != "custom" || names[1] != "go" || names[2] != "pytest" {
+		t.Errorf("TestFrameworks() = %v", names)
+	}
+
+	sb := setupTestRunnerProvider(t, func(code string, opts *executor.ExecutionOptions) *executor.ExecutionResult {
+		return &executor.ExecutionResult{Stdout: "case"}
+	})
+	tr, err := sb.RunTests(context.Background(), "custom", "", "")
+	if err != nil {
+		t.Fatalf("RunTests() error = %v", err)
+	}
+	if tr.Passed != 1 || tr.Cases[0].Name != "case" {
+		t.Errorf("result = %+v", tr)
+	}
+}
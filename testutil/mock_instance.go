@@ -149,6 +149,7 @@ func (i *MockInstance) ExecuteStream(ctx context.Context, code string, opts *exe
 	handler(&executor.StreamEvent{
 		Type:      executor.StreamComplete,
 		ExitCode:  result.ExitCode,
+		Language:  result.Language,
 		Timestamp: time.Now(),
 	})
 
@@ -0,0 +1,67 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/happyhackingspace/sindoq"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// ErrNoRecording is returned by replay instances when no unused record
+// matches an execution.
+var ErrNoRecording = errors.New("no recorded execution matches")
+
+// NewReplayProvider returns a mock provider whose instances answer Execute
+// calls from recorded executions, such as those written by
+// sindoq.WithRecording and loaded with sindoq.ReadRecords. Each call
+// consumes the first unused record with the same code and language; the
+// records are shared by all instances of the provider.
+func NewReplayProvider(records []sindoq.ExecutionRecord) *MockProvider {
+	p := NewMockProvider("replay")
+	r := &replayer{records: records, used: make([]bool, len(records))}
+
+	p.OnCreate = func(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+		instance := NewMockInstance(p)
+		instance.OnExecute = r.execute
+
+		p.mu.Lock()
+		p.instances[instance.ID()] = instance
+		p.mu.Unlock()
+		return instance, nil
+	}
+	return p
+}
+
+// replayer hands out recorded results in order.
+type replayer struct {
+	mu      sync.Mutex
+	records []sindoq.ExecutionRecord
+	used    []bool
+}
+
+func (r *replayer) execute(ctx context.Context, code string, opts *executor.ExecutionOptions) (*executor.ExecutionResult, error) {
+	var language string
+	if opts != nil {
+		language = opts.Language
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, rec := range r.records {
+		if r.used[i] || rec.Code != code || rec.Language != language {
+			continue
+		}
+		r.used[i] = true
+
+		if rec.Result == nil {
+			return nil, errors.New(rec.Error)
+		}
+		return rec.Result.ExecutionResult(), nil
+	}
+	return nil, fmt.Errorf("%w: %s code (%d bytes)", ErrNoRecording, language, len(code))
+}
@@ -0,0 +1,123 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/happyhackingspace/sindoq"
+	"github.com/happyhackingspace/sindoq/internal/factory"
+	"github.com/happyhackingspace/sindoq/internal/provider"
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	mp := NewMockProvider("record-test")
+	mp.OnCreate = func(ctx context.Context, opts *provider.CreateOptions) (provider.Instance, error) {
+		instance := NewMockInstance(mp)
+		instance.SetExecuteResult("token=s3cret\n", "warning\n", 3)
+		return instance, nil
+	}
+	factory.Register("record-test", func(config any) (provider.Provider, error) {
+		return mp, nil
+	})
+	defer factory.Unregister("record-test")
+
+	ctx := context.Background()
+	var buf bytes.Buffer
+	sb, err := sindoq.Create(ctx,
+		sindoq.WithProvider("record-test"),
+		sindoq.WithRecording(&buf),
+		sindoq.WithSecrets(map[string]string{"TOKEN": "s3cret"}),
+	)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer sb.Stop(ctx)
+
+	code := `import os; print("token=" + os.environ["TOKEN"])`
+	if _, err := sb.Execute(ctx, code,
+		sindoq.WithLanguage("Python"),
+		sindoq.WithEnv(map[string]string{"DEBUG": "verbose"}),
+		sindoq.WithFiles(map[string][]byte{"config.ini": []byte("token=s3cret\n")}),
+	); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if strings.Contains(buf.String(), "s3cret") || strings.Contains(buf.String(), "verbose") {
+		t.Errorf("record leaks secrets or env values: %s", buf.String())
+	}
+
+	records, err := sindoq.ReadRecords(&buf)
+	if err != nil {
+		t.Fatalf("ReadRecords() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	rec := records[0]
+	if rec.Provider != "record-test" || rec.Language != "Python" || rec.Detected {
+		t.Errorf("record = %+v, want provider record-test, language Python, not detected", rec)
+	}
+	if rec.Options.Env["DEBUG"] != "[REDACTED]" {
+		t.Errorf("Options.Env[DEBUG] = %q, want redacted", rec.Options.Env["DEBUG"])
+	}
+	if got := string(rec.Options.Files["config.ini"]); got != "token=[REDACTED]\n" {
+		t.Errorf("Options.Files[config.ini] = %q, want the secret redacted", got)
+	}
+	if len(rec.Timeline) != 2 {
+		t.Errorf("Timeline has %d events, want 2", len(rec.Timeline))
+	}
+
+	rp := NewReplayProvider(records)
+	factory.Register("replay-test", func(config any) (provider.Provider, error) {
+		return rp, nil
+	})
+	defer factory.Unregister("replay-test")
+
+	replay, err := sindoq.Create(ctx, sindoq.WithProvider("replay-test"))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	defer replay.Stop(ctx)
+
+	result, err := replay.Execute(ctx, code, sindoq.WithLanguage("Python"))
+	if err != nil {
+		t.Fatalf("replay Execute() error = %v", err)
+	}
+	if result.ExitCode != 3 || result.Stdout != "token=***\n" || result.Stderr != "warning\n" {
+		t.Errorf("replayed result = %+v", result)
+	}
+
+	// Each record is replayed once.
+	if _, err := replay.Execute(ctx, code, sindoq.WithLanguage("Python")); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("second replay error = %v, want ErrNoRecording", err)
+	}
+}
+
+func TestReplayRecordedError(t *testing.T) {
+	rp := NewReplayProvider([]sindoq.ExecutionRecord{
+		{Code: "boom", Language: "Go", Error: "compile failed"},
+	})
+
+	ctx := context.Background()
+	instance, err := rp.Create(ctx, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, err = instance.Execute(ctx, "boom", nil)
+	if !errors.Is(err, ErrNoRecording) {
+		t.Errorf("language mismatch error = %v, want ErrNoRecording", err)
+	}
+
+	_, err = instance.Execute(ctx, "boom", &executor.ExecutionOptions{Language: "Go"})
+	if err == nil || err.Error() != "compile failed" {
+		t.Errorf("Execute() error = %v, want recorded error", err)
+	}
+	if got := len(rp.Instances()); got != 1 {
+		t.Errorf("Instances() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,57 @@
+package sindoq
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// Span attribute keys set by WithTracer.
+const (
+	AttrProvider   = attribute.Key("sindoq.provider")
+	AttrSandboxID  = attribute.Key("sindoq.sandbox.id")
+	AttrLanguage   = attribute.Key("sindoq.language")
+	AttrExitCode   = attribute.Key("sindoq.exit_code")
+	AttrDurationMS = attribute.Key("sindoq.duration_ms")
+	AttrStopReason = attribute.Key("sindoq.stop_reason")
+)
+
+// startSpan starts a span named name under the span in ctx, or returns ctx
+// and a no-op span when tracer is nil.
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// startSpan starts a span for an operation on the sandbox.
+func (s *sandbox) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return startSpan(ctx, s.config.Tracer, name, AttrProvider.String(s.providerName), AttrSandboxID.String(s.instance.ID()))
+}
+
+// setResultAttributes records the outcome of an execution on span.
+func setResultAttributes(span trace.Span, result *executor.ExecutionResult) {
+	if result == nil {
+		return
+	}
+	span.SetAttributes(
+		AttrLanguage.String(result.Language),
+		AttrExitCode.Int(result.ExitCode),
+		AttrDurationMS.Int64(result.Duration.Milliseconds()),
+	)
+}
@@ -0,0 +1,126 @@
+package sindoq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/happyhackingspace/sindoq/pkg/executor"
+)
+
+// testTracer records the spans it starts.
+type testTracer struct {
+	noop.Tracer
+
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+// testSpan records what is set on it.
+type testSpan struct {
+	noop.Span
+
+	name   string
+	parent *testSpan
+	attrs  map[attribute.Key]attribute.Value
+	status codes.Code
+	errs   []error
+	ended  bool
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	parent, _ := trace.SpanFromContext(ctx).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attrs: make(map[attribute.Key]attribute.Value)}
+	cfg := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(cfg.Attributes()...)
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// named returns the spans named name.
+func (t *testTracer) named(name string) []*testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []*testSpan
+	for _, s := range t.spans {
+		if s.name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func (s *testSpan) IsRecording() bool                             { return true }
+func (s *testSpan) End(...trace.SpanEndOption)                    { s.ended = true }
+func (s *testSpan) SetStatus(code codes.Code, _ string)           { s.status = code }
+func (s *testSpan) RecordError(err error, _ ...trace.EventOption) { s.errs = append(s.errs, err) }
+
+func (s *testSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	cleanup := setupMockProvider(t)
+	defer cleanup()
+
+	tracer := &testTracer{}
+	ctx, root := tracer.Start(context.Background(), "request")
+
+	sb, err := Create(ctx, WithProvider("mock"), WithTracer(tracer))
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := sb.Execute(ctx, "print('hi')", WithLanguage("Python")); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if _, err := sb.Execute(ctx, "  "); !errors.Is(err, ErrEmptyCode) {
+		t.Fatalf("Execute() error = %v, want ErrEmptyCode", err)
+	}
+	if err := sb.ExecuteStream(ctx, "print('hi')", func(*executor.StreamEvent) error { return nil }, WithLanguage("Python")); err != nil {
+		t.Fatalf("ExecuteStream() error = %v", err)
+	}
+	if err := sb.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	for name, want := range map[string]int{"sindoq.create": 1, "sindoq.execute": 2, "sindoq.executeStream": 1, "sindoq.stop": 1} {
+		spans := tracer.named(name)
+		if len(spans) != want {
+			t.Fatalf("%d %s spans, want %d", len(spans), name, want)
+		}
+		for _, s := range spans {
+			if s.parent != root || !s.ended {
+				t.Errorf("%s span: parent %v, ended %v, want an ended child of the caller's span", name, s.parent, s.ended)
+			}
+			if s.attrs[AttrProvider].AsString() != "mock" || s.attrs[AttrSandboxID].AsString() != sb.ID() {
+				t.Errorf("%s span attributes = %v, want the provider and sandbox ID", name, s.attrs)
+			}
+		}
+	}
+
+	executes := tracer.named("sindoq.execute")
+	ok, failed := executes[0], executes[1]
+	if ok.attrs[AttrLanguage].AsString() != "Python" || ok.attrs[AttrExitCode].AsInt64() != 0 || ok.attrs[AttrDurationMS].AsInt64() != 100 {
+		t.Errorf("execute span attributes = %v, want the result's language, exit code and duration", ok.attrs)
+	}
+	if ok.status == codes.Error || len(ok.errs) != 0 {
+		t.Error("successful execute span should not record an error")
+	}
+	if failed.status != codes.Error || len(failed.errs) != 1 || !errors.Is(failed.errs[0], ErrEmptyCode) {
+		t.Errorf("failed execute span: status %v, errors %v, want the error recorded", failed.status, failed.errs)
+	}
+	if stream := tracer.named("sindoq.executeStream")[0]; stream.attrs[AttrLanguage].AsString() != "Python" {
+		t.Errorf("executeStream span attributes = %v, want the language", stream.attrs)
+	}
+}